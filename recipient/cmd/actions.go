@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/infodiode/shared/actionlog"
+)
+
+// defaultActionsLimit и maxActionsLimit размер выборки /audit
+const (
+	defaultActionsLimit = 100
+	maxActionsLimit     = 10000
+)
+
+// parseActionQuery разбирает параметры выборки журнала действий: since (RFC3339),
+// actor, action (начало действия), test_id и limit
+func parseActionQuery(query url.Values) (actionlog.Query, error) {
+	filter := actionlog.Query{
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		TestID: query.Get("test_id"),
+		Limit:  defaultActionsLimit,
+	}
+
+	if value := query.Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("некорректное значение since (RFC3339)")
+		}
+		filter.Since = since
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxActionsLimit {
+			return filter, fmt.Errorf("некорректное значение limit (1-%d)", maxActionsLimit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/infodiode/recipient/config"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
+)
+
+// clusterClient запрашивает статистику остальных экземпляров recipient,
+// принимающих сообщения одного топика через общую подписку
+type clusterClient struct {
+	peers  []string
+	client *http.Client
+}
+
+// newClusterClient создает клиента для экземпляров из cluster.peers
+func newClusterClient(cfg *config.ClusterConfig) (*clusterClient, error) {
+	peers := make([]string, 0, len(cfg.Peers))
+	for _, peer := range cfg.Peers {
+		peers = append(peers, strings.TrimRight(peer, "/"))
+	}
+
+	client, err := utils.HTTPClient(cfg.Timeout, cfg.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clusterClient{
+		peers:  peers,
+		client: client,
+	}, nil
+}
+
+// fetchAll выполняет запрос path ко всем экземплярам параллельно и декодирует ответы
+// в targets (по одному на экземпляр в порядке cluster.peers)
+func (c *clusterClient) fetchAll(ctx context.Context, path string, targets []interface{}) []error {
+	errs := make([]error, len(c.peers))
+
+	var wg sync.WaitGroup
+	for i, peer := range c.peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = c.getJSON(ctx, peer+path, targets[i])
+		}()
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// getJSON запрашивает address и декодирует JSON ответ
+func (c *clusterClient) getJSON(ctx context.Context, address string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errClusterNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("статус ответа %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// errClusterNotFound экземпляр не получал сообщений запрошенного теста
+var errClusterNotFound = errors.New("не найдено")
+
+// Stats собирает статистику экземпляров; local - статистика текущего экземпляра
+func (c *clusterClient) Stats(ctx context.Context, local statsResponse) clusterStatsResponse {
+	response := clusterStatsResponse{
+		Instances: []clusterInstanceStats{{Instance: local.Service.Instance, Stats: &local}},
+	}
+
+	stats := make([]statsResponse, len(c.peers))
+	targets := make([]interface{}, len(c.peers))
+	for i := range stats {
+		targets[i] = &stats[i]
+	}
+
+	errs := c.fetchAll(ctx, "/stats", targets)
+	for i, peer := range c.peers {
+		instance := clusterInstanceStats{Instance: peer, URL: peer}
+		if errs[i] != nil {
+			instance.Error = errs[i].Error()
+		} else {
+			instance.Stats = &stats[i]
+			instance.Instance = stats[i].Service.Instance
+		}
+		response.Instances = append(response.Instances, instance)
+	}
+
+	var processors []processorStats
+	var consumers []consumerStats
+	for _, instance := range response.Instances {
+		if instance.Stats == nil {
+			response.Unavailable++
+			continue
+		}
+		response.Available++
+		processors = append(processors, instance.Stats.Processor)
+		consumers = append(consumers, instance.Stats.Consumer)
+	}
+	response.Processor = mergeProcessorStats(processors)
+	response.Consumer = mergeConsumerStats(consumers)
+
+	return response
+}
+
+// SessionReport объединяет отчеты экземпляров о полноте доставки сообщений теста.
+// local - отчет текущего экземпляра (nil, если он не получал сообщений теста)
+func (c *clusterClient) SessionReport(ctx context.Context, testID string, localInstance string, local *models.SessionReport) (clusterSessionResponse, bool) {
+	response := clusterSessionResponse{
+		Instances: []clusterInstanceSession{{Instance: localInstance, Report: local}},
+	}
+
+	reports := make([]models.SessionReport, len(c.peers))
+	targets := make([]interface{}, len(c.peers))
+	for i := range reports {
+		targets[i] = &reports[i]
+	}
+
+	errs := c.fetchAll(ctx, "/sessions/"+url.PathEscape(testID), targets)
+	for i, peer := range c.peers {
+		instance := clusterInstanceSession{Instance: peer, URL: peer}
+		switch {
+		case errors.Is(errs[i], errClusterNotFound):
+		case errs[i] != nil:
+			instance.Error = errs[i].Error()
+		default:
+			instance.Report = &reports[i]
+		}
+		response.Instances = append(response.Instances, instance)
+	}
+
+	var received []*models.SessionReport
+	for _, instance := range response.Instances {
+		if instance.Report != nil {
+			received = append(received, instance.Report)
+		}
+	}
+	if len(received) == 0 {
+		return response, false
+	}
+
+	response.Report = mergeSessionReports(testID, received)
+	return response, true
+}
+
+// mergeProcessorStats суммирует статистику обработчиков экземпляров; средние значения
+// взвешиваются по количеству обработанных сообщений
+func mergeProcessorStats(stats []processorStats) processorStats {
+	var total processorStats
+	var latencyWeight float64
+	for _, s := range stats {
+		total.MessagesReceived += s.MessagesReceived
+		total.MessagesProcessed += s.MessagesProcessed
+		total.MessagesValid += s.MessagesValid
+		total.MessagesInvalid += s.MessagesInvalid
+		total.ChecksumErrors += s.ChecksumErrors
+		total.ProcessingErrors += s.ProcessingErrors
+		total.PayloadErrors += s.PayloadErrors
+		total.IntegrityErrors += s.IntegrityErrors
+		total.MessagesStale += s.MessagesStale
+		total.TotalBytesReceived += s.TotalBytesReceived
+		total.Throughput += s.Throughput
+		total.MessageLog.Written += s.MessageLog.Written
+		total.MessageLog.SampledOut += s.MessageLog.SampledOut
+		total.MessageLog.Dropped += s.MessageLog.Dropped
+
+		if s.MessagesProcessed > 0 {
+			if latencyWeight == 0 || s.MinLatency < total.MinLatency {
+				total.MinLatency = s.MinLatency
+			}
+			total.MaxLatency = max(total.MaxLatency, s.MaxLatency)
+			total.AvgLatency += s.AvgLatency * float64(s.MessagesProcessed)
+			latencyWeight += float64(s.MessagesProcessed)
+		}
+		total.FirstMessageTime = earliest(total.FirstMessageTime, s.FirstMessageTime)
+		if s.LastMessageTime.After(total.LastMessageTime) {
+			total.LastMessageTime = s.LastMessageTime
+		}
+	}
+
+	if latencyWeight > 0 {
+		total.AvgLatency /= latencyWeight
+	}
+	if total.MessagesReceived > 0 {
+		total.AvgMessageSize = total.TotalBytesReceived / total.MessagesReceived
+	}
+	return total
+}
+
+// mergeConsumerStats суммирует статистику MQTT consumer экземпляров
+func mergeConsumerStats(stats []consumerStats) clusterConsumerStats {
+	var total clusterConsumerStats
+	for _, s := range stats {
+		total.MessagesReceived += s.MessagesReceived
+		total.BytesReceived += s.BytesReceived
+		total.Errors += s.Errors
+		total.ReconnectCount += s.ReconnectCount
+		total.Throttled += s.Throttled
+		total.Retained += s.Retained
+		total.Duplicates += s.Duplicates
+		if s.Connected {
+			total.Connected++
+		}
+	}
+	return total
+}
+
+// mergeSessionReports объединяет отчеты экземпляров по тесту. Общая подписка доставляет
+// каждое сообщение одному экземпляру, поэтому уникальные номера суммируются; повтор одного
+// номера на разных экземплярах не обнаруживается, а диапазоны пропусков не формируются;
+// джиттер приема берется по экземпляру с наибольшим 95-м перцентилем, задержка по участкам
+// усредняется с учетом количества измерений, перцентиль и максимум берутся наибольшие
+func mergeSessionReports(testID string, reports []*models.SessionReport) *models.SessionReport {
+	merged := &models.SessionReport{TestID: testID, MissingRanges: []models.SequenceRange{}}
+	for _, r := range reports {
+		if merged.Tenant == "" && merged.RunLabel == "" {
+			merged.Tenant, merged.RunLabel = r.Tenant, r.RunLabel
+		}
+		merged.Received += r.Received
+		merged.Unique += r.Unique
+		merged.Duplicates += r.Duplicates
+		merged.RetryDuplicates += r.RetryDuplicates
+		merged.OutOfOrder += r.OutOfOrder
+		merged.Stale += r.Stale
+		merged.MaxSequence = max(merged.MaxSequence, r.MaxSequence)
+		merged.FirstSeen = earliest(merged.FirstSeen, r.FirstSeen)
+		if r.LastSeen.After(merged.LastSeen) {
+			merged.LastSeen = r.LastSeen
+		}
+		// Интервалы прихода на разных экземплярах не сопоставимы: берем худший джиттер
+		if r.Jitter != nil && (merged.Jitter == nil || r.Jitter.P95Ms > merged.Jitter.P95Ms) {
+			merged.Jitter = r.Jitter
+		}
+		if r.Latency != nil {
+			if merged.Latency == nil {
+				merged.Latency = &models.LatencyBreakdown{}
+			}
+			merged.Latency.Delivery = mergeHopLatency(merged.Latency.Delivery, r.Latency.Delivery)
+			merged.Latency.Processing = mergeHopLatency(merged.Latency.Processing, r.Latency.Processing)
+		}
+	}
+
+	merged.Missing = max(merged.MaxSequence-merged.Unique, 0)
+	return merged
+}
+
+// mergeHopLatency объединяет статистику участка пути двух экземпляров
+func mergeHopLatency(a, b *models.HopLatency) *models.HopLatency {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	samples := a.Samples + b.Samples
+	return &models.HopLatency{
+		Samples: samples,
+		AvgMs:   (a.AvgMs*float64(a.Samples) + b.AvgMs*float64(b.Samples)) / float64(samples),
+		P95Ms:   max(a.P95Ms, b.P95Ms),
+		MaxMs:   max(a.MaxMs, b.MaxMs),
+	}
+}
+
+// earliest возвращает более раннее из непустых значений времени
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
@@ -2,19 +2,38 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/infodiode/recipient/config"
+	"github.com/infodiode/recipient/internal/archive"
 	"github.com/infodiode/recipient/internal/broker"
+	"github.com/infodiode/recipient/internal/demux"
+	"github.com/infodiode/recipient/internal/files"
 	"github.com/infodiode/recipient/internal/processor"
+	"github.com/infodiode/recipient/internal/quic"
+	"github.com/infodiode/recipient/internal/serial"
+	"github.com/infodiode/recipient/internal/slo"
+	"github.com/infodiode/recipient/internal/store"
 	"github.com/infodiode/recipient/internal/tcp"
+	"github.com/infodiode/recipient/internal/throughput"
+	"github.com/infodiode/recipient/internal/validator"
+	"github.com/infodiode/shared/actionlog"
+	"github.com/infodiode/shared/correlation"
+	"github.com/infodiode/shared/logging"
 	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/supervisor"
+	"github.com/infodiode/shared/utils"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -24,19 +43,27 @@ var (
 	// Version информация о версии (устанавливается при сборке)
 	Version   = "dev"
 	BuildTime = "unknown"
+	GitCommit = "" // Пусто - коммит определяется по данным сборки go build
 )
 
+// messageLatencyHelp описание гистограммы задержки доставки
+const messageLatencyHelp = "Message delivery latency from send_time to receipt in milliseconds"
+
 func main() {
+	startTime := time.Now()
+
 	// Парсинг флагов командной строки
 	var (
 		configPath  = flag.String("config", "config.yaml", "путь к файлу конфигурации")
 		showVersion = flag.Bool("version", false, "показать версию и выйти")
+		replayPath  = flag.String("replay", "", "воспроизвести архив принятых кадров (файл или директория) через обработчик и выйти")
+		replayOut   = flag.String("replay-output", "", "файл для результата воспроизведения (по умолчанию stdout)")
 	)
 	flag.Parse()
 
 	// Показываем версию если запрошено
 	if *showVersion {
-		fmt.Printf("Recipient Service\nVersion: %s\nBuild time: %s\n", Version, BuildTime)
+		fmt.Printf("Recipient Service\nVersion: %s\nBuild time: %s\nCommit: %s\n", Version, BuildTime, gitCommit())
 		os.Exit(0)
 	}
 
@@ -48,13 +75,28 @@ func main() {
 	}
 
 	// Инициализируем логгер
-	logger, err := initLogger(cfg)
+	logger, logLevel, err := initLogger(cfg)
 	if err != nil {
 		fmt.Printf("Ошибка инициализации логгера: %v\n", err)
 		os.Exit(1)
 	}
 	defer logger.Sync()
 
+	// Режим воспроизведения архива
+	if *replayPath != "" {
+		out := os.Stdout
+		if *replayOut != "" {
+			if out, err = os.Create(*replayOut); err != nil {
+				logger.Fatal("Ошибка создания файла результата", zap.Error(err))
+			}
+			defer out.Close()
+		}
+		if err := runReplay(*replayPath, cfg.Validation, logger, out); err != nil {
+			logger.Fatal("Ошибка воспроизведения архива", zap.Error(err))
+		}
+		return
+	}
+
 	// Логируем информацию о запуске
 	logger.Info("Запуск Recipient сервиса",
 		zap.String("version", Version),
@@ -63,18 +105,166 @@ func main() {
 
 	// Создаем обработчик сообщений
 	msgProcessor := processor.NewMessageProcessor(logger)
+	msgProcessor.SetMessageTTL(cfg.Processing.MessageTTL)
+	msgProcessor.SetMonotonicLatency(cfg.Processing.MonotonicLatency)
+	msgProcessor.SetValidation(cfg.Validation.ValidationProfile(), cfg.Validation.Rules())
+	if cfg.MessageLog.Enabled {
+		msgProcessor.SetMessageLog(processor.NewMessageLogger(logger, processor.MessageLogConfig{
+			QueueSize:   cfg.MessageLog.QueueSize,
+			SampleEvery: cfg.MessageLog.SampleEvery,
+			FilePath:    cfg.Logger.Messages.FilePath,
+			MaxSize:     cfg.Logger.Messages.MaxSize,
+			MaxBackups:  cfg.Logger.Messages.MaxBackups,
+			MaxAge:      cfg.Logger.Messages.MaxAge,
+			Compress:    cfg.Logger.Messages.Compress,
+		}))
+	} else {
+		msgProcessor.SetMessageLog(nil)
+	}
+	if err := msgProcessor.Start(); err != nil {
+		logger.Fatal("Ошибка запуска обработчика сообщений", zap.Error(err))
+	}
+
+	// Счетчики, накопленные предыдущими процессами (если включено). Сохраняются
+	// периодически и при остановке - после остановки приема по всем каналам
+	saveLifetime := func() {}
+	if cfg.Processing.StatsFile != "" {
+		if err := msgProcessor.RestoreLifetime(cfg.Processing.StatsFile); err != nil {
+			logger.Fatal("Ошибка загрузки накопленной статистики", zap.Error(err))
+		}
+		saveLifetime = func() {
+			if err := msgProcessor.SaveLifetime(cfg.Processing.StatsFile); err != nil {
+				logger.Error("Ошибка сохранения накопленной статистики", zap.Error(err))
+			}
+		}
+		defer saveLifetime()
+
+		if interval := cfg.Processing.StatsSaveInterval; interval > 0 {
+			saveTicker := time.NewTicker(interval)
+			defer saveTicker.Stop()
+			go func() {
+				for range saveTicker.C {
+					saveLifetime()
+				}
+			}()
+		}
+	}
+
+	// Открываем архив принятых кадров (если включен); закрывается после остановки приема
+	var archiver *archive.Writer
+	if cfg.Archive.Enabled {
+		archiver, err = archive.NewWriter(archive.Config{
+			Directory:   cfg.Archive.Directory,
+			MaxFileSize: int64(cfg.Archive.MaxFileSize) * 1024 * 1024,
+			MaxFiles:    cfg.Archive.MaxFiles,
+		}, logger)
+		if err != nil {
+			logger.Fatal("Ошибка открытия архива кадров", zap.Error(err))
+		}
+		defer func() {
+			if err := archiver.Close(); err != nil {
+				logger.Error("Ошибка закрытия архива кадров", zap.Error(err))
+			}
+		}()
+	}
+
+	// Открываем журнал корреляции полученных сообщений (если включен); закрывается после остановки приема
+	var correlationLog *correlation.Writer
+	if cfg.Correlation.Enabled {
+		correlationLog, err = correlation.NewWriter(correlation.Config{
+			Directory:   cfg.Correlation.Directory,
+			Prefix:      correlation.PrefixReceived + "-" + time.Now().Format("20060102-150405"),
+			MaxFileSize: int64(cfg.Correlation.MaxFileSize) * 1024 * 1024,
+		})
+		if err != nil {
+			logger.Fatal("Ошибка открытия журнала корреляции", zap.Error(err))
+		}
+		msgProcessor.SetCorrelation(correlationLog)
+		defer func() {
+			if err := correlationLog.Close(); err != nil {
+				logger.Error("Ошибка закрытия журнала корреляции", zap.Error(err))
+			}
+		}()
+	}
+
+	// Открываем хранилище результатов (если включено); закрывается после остановки приема
+	var resultStore *store.Store
+	if cfg.Store.Enabled {
+		resultStore, err = store.Open(store.Config{
+			Path:          cfg.Store.Path,
+			QueueSize:     cfg.Store.QueueSize,
+			ValidMessages: cfg.Store.ValidMessages,
+			Retention:     cfg.Store.Retention,
+
+			BatchSize:       cfg.Store.BatchSize,
+			FlushInterval:   cfg.Store.FlushInterval,
+			MaxPending:      cfg.Store.MaxPending,
+			ShutdownTimeout: cfg.Store.ShutdownTimeout,
+		}, logger, msgProcessor.GetSessionReports)
+		if err != nil {
+			logger.Fatal("Ошибка открытия хранилища результатов", zap.Error(err))
+		}
+		msgProcessor.SetStore(resultStore)
+		defer func() {
+			if err := resultStore.Close(); err != nil {
+				logger.Error("Ошибка закрытия хранилища результатов", zap.Error(err))
+			}
+		}()
+	}
+
+	// Создаем сборщик файлов теста передачи файлов (если включен)
+	var fileAssembler *files.Assembler
+	if cfg.Files.Enabled {
+		fileAssembler, err = files.NewAssembler(files.Config{
+			Directory:   cfg.Files.Directory,
+			MaxFileSize: int64(cfg.Files.MaxFileSize) * 1024 * 1024,
+		}, logger)
+		if err != nil {
+			logger.Fatal("Ошибка создания сборщика файлов", zap.Error(err))
+		}
+		msgProcessor.SetFiles(fileAssembler)
+		defer fileAssembler.Close()
+	}
+
+	// Запускаем запись проверенных записей payload в файлы оборудования (если включена);
+	// закрывается после остановки приема, незавершенные файлы переименовываются
+	var demuxWriter *demux.Writer
+	if cfg.Demux.Enabled {
+		demuxWriter, err = demux.NewWriter(demux.Config{
+			Directory:      cfg.Demux.Directory,
+			Format:         cfg.Demux.Format,
+			MaxFileSize:    int64(cfg.Demux.MaxFileSize) * 1024 * 1024,
+			RotateInterval: cfg.Demux.RotateInterval,
+			MaxOpenFiles:   cfg.Demux.MaxOpenFiles,
+			QueueSize:      cfg.Demux.QueueSize,
+		}, logger)
+		if err != nil {
+			logger.Fatal("Ошибка запуска записи файлов оборудования", zap.Error(err))
+		}
+		msgProcessor.SetDemux(demuxWriter)
+		defer demuxWriter.Close()
+	}
 
 	// Создаем обработчик для MQTT consumer
 	messageHandler := func(msg *models.Message) error {
 		return msgProcessor.ProcessMessage(msg)
 	}
 
+	// Учет пробных сообщений sender для /healthz (если включен)
+	var probeMonitor *broker.ProbeMonitor
+	if cfg.HealthProbe.Enabled {
+		probeMonitor = broker.NewProbeMonitor(&cfg.HealthProbe, logger)
+	}
+
 	// Создаем MQTT consumer
-	consumer, err := broker.NewMQTTConsumer(&cfg.MQTT, logger, messageHandler)
+	consumer, err := broker.NewMQTTConsumer(&cfg.MQTT, logger, messageHandler, archiver, probeMonitor)
 	if err != nil {
 		logger.Fatal("Ошибка создания MQTT consumer", zap.Error(err))
 	}
 	defer consumer.Close()
+	consumer.SetDecodeObserver(msgProcessor.ObserveDecode)
+	consumer.SetOrderObserver(msgProcessor.ObserveOrder)
+	consumer.SetReceiveObserver(msgProcessor.ObserveReceive)
 
 	// Запускаем consumer
 	if err := consumer.Start(); err != nil {
@@ -84,16 +274,23 @@ func main() {
 	// Создаем и запускаем TCP сервер (если включен)
 	var tcpServer *tcp.TCPServer
 	if cfg.TCP.Enabled {
+		// Сети проверены при загрузке конфигурации
+		allowedNetworks, _ := cfg.TCP.Networks()
 		tcpConfig := &tcp.Config{
 			Address:         cfg.TCP.Address,
 			MaxConnections:  cfg.TCP.MaxConnections,
 			ReadTimeout:     cfg.TCP.ReadTimeout,
+			DrainTimeout:    cfg.TCP.DrainTimeout,
 			WriteTimeout:    cfg.TCP.WriteTimeout,
 			KeepAlive:       cfg.TCP.KeepAlive,
 			KeepAlivePeriod: cfg.TCP.KeepAlivePeriod,
+			PingInterval:    cfg.TCP.PingInterval,
+			PingTimeout:     cfg.TCP.PingTimeout,
+			AllowedNetworks: allowedNetworks,
+			Socket:          cfg.TCP.SocketOptions(),
 		}
 
-		tcpServer, err = tcp.NewTCPServer(tcpConfig, logger, msgProcessor)
+		tcpServer, err = tcp.NewTCPServer(tcpConfig, logger, msgProcessor, archiver)
 		if err != nil {
 			logger.Error("Ошибка создания TCP сервера", zap.Error(err))
 		} else {
@@ -111,17 +308,148 @@ func main() {
 		}
 	}
 
+	// Создаем и запускаем QUIC сервер (если включен)
+	var quicServer *quic.QUICServer
+	if cfg.QUIC.Enabled {
+		quicServer, err = quic.NewQUICServer(&quic.Config{
+			Address:         cfg.QUIC.Address,
+			CertFile:        cfg.QUIC.CertFile,
+			KeyFile:         cfg.QUIC.KeyFile,
+			MaxStreams:      cfg.QUIC.MaxStreams,
+			ZeroRTT:         cfg.QUIC.ZeroRTT,
+			KeepAlivePeriod: cfg.QUIC.KeepAlivePeriod,
+			IdleTimeout:     cfg.QUIC.IdleTimeout,
+		}, logger, msgProcessor, archiver)
+		if err != nil {
+			logger.Error("Ошибка создания QUIC сервера", zap.Error(err))
+		} else {
+			if err := quicServer.Start(); err != nil {
+				logger.Error("Ошибка запуска QUIC сервера", zap.Error(err))
+			}
+
+			defer func() {
+				if err := quicServer.Stop(); err != nil {
+					logger.Error("Ошибка остановки QUIC сервера", zap.Error(err))
+				}
+			}()
+		}
+	}
+
+	// Создаем и запускаем NATS JetStream consumer (если включен)
+	var natsConsumer *broker.NATSConsumer
+	if cfg.NATS.Enabled {
+		natsConsumer, err = broker.NewNATSConsumer(&cfg.NATS, logger, messageHandler, archiver)
+		if err != nil {
+			logger.Error("Ошибка создания NATS consumer", zap.Error(err))
+		} else {
+			natsConsumer.SetDecodeObserver(msgProcessor.ObserveDecode)
+			natsConsumer.SetOrderObserver(msgProcessor.ObserveOrder)
+			natsConsumer.SetReceiveObserver(msgProcessor.ObserveReceive)
+			if err := natsConsumer.Start(); err != nil {
+				logger.Error("Ошибка запуска NATS consumer", zap.Error(err))
+			}
+			defer natsConsumer.Close()
+		}
+	}
+
+	// Создаем и запускаем прием через последовательный порт (если включен)
+	var serialReceiver *serial.Receiver
+	if cfg.Serial.Enabled {
+		serialReceiver, err = serial.NewReceiver(&serial.Config{
+			Port:           cfg.Serial.PortConfig(),
+			MaxFrameSize:   cfg.Serial.MaxFrameSize,
+			FrameTimeout:   cfg.Serial.FrameTimeout,
+			ReopenInterval: cfg.Serial.ReopenInterval,
+		}, logger, msgProcessor, archiver)
+		if err != nil {
+			logger.Error("Ошибка создания приема через последовательный порт", zap.Error(err))
+		} else {
+			if err := serialReceiver.Start(); err != nil {
+				logger.Error("Ошибка запуска приема через последовательный порт", zap.Error(err))
+			}
+
+			defer func() {
+				if err := serialReceiver.Stop(); err != nil {
+					logger.Error("Ошибка остановки приема через последовательный порт", zap.Error(err))
+				}
+			}()
+		}
+	}
+
+	// Публикация сводок о принятых сообщениях в канал аудита (если включена)
+	var auditPublisher *broker.AuditPublisher
+	if cfg.Audit.Enabled {
+		auditPublisher = broker.NewAuditPublisher(&cfg.Audit, cfg.Service.Instance, logger, msgProcessor.GetAuditDigests)
+		auditPublisher.Start()
+		defer auditPublisher.Close()
+	}
+
+	// Контроль пропускной способности и отставания обработки от приема (если включен)
+	var throughputMonitor *throughput.Monitor
+	if cfg.SlowConsumer.Enabled {
+		throughputMonitor = throughput.NewMonitor(throughput.Config{
+			Window:         cfg.SlowConsumer.Window,
+			MaxLag:         cfg.SlowConsumer.MaxLag,
+			MaxQueueDepth:  cfg.SlowConsumer.MaxQueueDepth,
+			WebhookURL:     cfg.SlowConsumer.WebhookURL,
+			WebhookTimeout: cfg.SlowConsumer.WebhookTimeout,
+		}, cfg.Service.Instance, logger,
+			func() int64 { return msgProcessor.GetStats().MessagesProcessed },
+			func() int64 { return consumer.GetStats().InFlight })
+		msgProcessor.SetLagObserver(throughputMonitor.ObserveLag)
+		consumer.SetLagObserver(throughputMonitor.ObserveLag)
+		throughputMonitor.Start()
+		defer throughputMonitor.Close()
+	}
+
+	// Оценка целей по задержке доставки (если включена)
+	var sloMonitor *slo.Monitor
+	if cfg.SLO.Enabled {
+		objectives := make([]slo.Objective, 0, len(cfg.SLO.Objectives))
+		for _, objective := range cfg.SLO.Objectives {
+			objectives = append(objectives, slo.Objective(objective))
+		}
+		sloMonitor = slo.NewMonitor(slo.Config{
+			Objectives:     objectives,
+			WebhookURL:     cfg.SLO.WebhookURL,
+			WebhookTimeout: cfg.SLO.WebhookTimeout,
+		}, cfg.Service.Instance, logger)
+		msgProcessor.SetLatencyObserver(sloMonitor.ObserveLatency)
+		sloMonitor.Start()
+		defer sloMonitor.Close()
+	}
+
+	// Журнал действий API (если задан файл)
+	var actions *actionlog.Log
+	if cfg.ActionLog.File != "" {
+		actions, err = actionlog.Open(cfg.ActionLog.File)
+		if err != nil {
+			logger.Fatal("Ошибка открытия журнала действий", zap.Error(err))
+		}
+		defer actions.Close()
+
+		if status := actions.Status(); !status.ChainValid {
+			logger.Warn("Нарушена цепочка записей журнала действий",
+				zap.String("file", status.File),
+				zap.String("reason", status.ChainError))
+		}
+		recordAction(logger, actions, "service.start", map[string]string{"version": Version, "commit": gitCommit()}, nil)
+		defer recordAction(logger, actions, "service.stop", nil, nil)
+	}
+
 	// Запускаем HTTP сервер для метрик и health checks
 	mux := http.NewServeMux()
 
-	// Health check endpoint
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	// Проверки состояния сервиса (/health)
+	healthStatus := func() models.HealthStatus {
 		status := models.HealthStatus{
-			Status:    "healthy",
-			Service:   "recipient",
-			Version:   Version,
-			Timestamp: time.Now(),
-			Checks:    []models.Check{},
+			Status:        "healthy",
+			Service:       cfg.Service.Name,
+			Version:       Version,
+			Timestamp:     time.Now(),
+			Checks:        []models.Check{},
+			BuildTime:     BuildTime,
+			UptimeSeconds: time.Since(startTime).Seconds(),
 		}
 
 		// Проверка подключения к MQTT
@@ -130,14 +458,90 @@ func main() {
 			Status:    "healthy",
 		}
 
-		if !consumer.IsConnected() {
+		if ready, reason := mqttReadiness(consumer); !ready {
 			mqttCheck.Status = "unhealthy"
-			mqttCheck.Message = "MQTT broker disconnected"
+			mqttCheck.Message = reason
 			status.Status = "unhealthy"
 		}
 
 		status.Checks = append(status.Checks, mqttCheck)
 
+		// Проверка подключения к NATS (если включен)
+		if natsConsumer != nil {
+			natsCheck := models.Check{
+				Component: "nats",
+				Status:    "healthy",
+			}
+
+			if !natsConsumer.IsConnected() {
+				natsCheck.Status = "unhealthy"
+				natsCheck.Message = "NATS server disconnected"
+				status.Status = "unhealthy"
+			}
+
+			status.Checks = append(status.Checks, natsCheck)
+		}
+
+		// Проверка TCP сервера (если включен)
+		if cfg.TCP.Enabled {
+			tcpCheck := models.Check{
+				Component: "tcp",
+				Status:    "healthy",
+			}
+
+			if tcpServer == nil || !tcpServer.IsRunning() {
+				tcpCheck.Status = "unhealthy"
+				tcpCheck.Message = fmt.Sprintf("TCP server not listening on %s", cfg.TCP.Address)
+				status.Status = "unhealthy"
+			} else {
+				tcpStats := tcpServer.GetStats()
+				tcpCheck.Message = fmt.Sprintf("Connections: %d, Messages: %d",
+					tcpStats.ConnectionsActive, tcpStats.MessagesReceived)
+			}
+
+			status.Checks = append(status.Checks, tcpCheck)
+		}
+
+		// Проверка QUIC сервера (если включен)
+		if cfg.QUIC.Enabled {
+			quicCheck := models.Check{
+				Component: "quic",
+				Status:    "healthy",
+			}
+
+			if quicServer == nil || !quicServer.IsRunning() {
+				quicCheck.Status = "unhealthy"
+				quicCheck.Message = fmt.Sprintf("QUIC server not listening on %s", cfg.QUIC.Address)
+				status.Status = "unhealthy"
+			} else {
+				quicStats := quicServer.GetStats()
+				quicCheck.Message = fmt.Sprintf("Connections: %d, Streams: %d, Messages: %d",
+					quicStats.ConnectionsActive, quicStats.StreamsActive, quicStats.MessagesReceived)
+			}
+
+			status.Checks = append(status.Checks, quicCheck)
+		}
+
+		// Проверка последовательного порта (если включен)
+		if cfg.Serial.Enabled {
+			serialCheck := models.Check{
+				Component: "serial",
+				Status:    "healthy",
+			}
+
+			if serialReceiver == nil || !serialReceiver.IsOpen() {
+				serialCheck.Status = "unhealthy"
+				serialCheck.Message = fmt.Sprintf("serial port %s not open", cfg.Serial.Device)
+				status.Status = "unhealthy"
+			} else {
+				serialStats := serialReceiver.GetStats()
+				serialCheck.Message = fmt.Sprintf("Frames: %d, CRC errors: %d",
+					serialStats.FramesReceived, serialStats.CRCErrors)
+			}
+
+			status.Checks = append(status.Checks, serialCheck)
+		}
+
 		// Проверка обработчика
 		stats := msgProcessor.GetStats()
 		processorCheck := models.Check{
@@ -148,31 +552,91 @@ func main() {
 		}
 		status.Checks = append(status.Checks, processorCheck)
 
-		w.Header().Set("Content-Type", "application/json")
-		if status.Status == "healthy" {
-			w.WriteHeader(http.StatusOK)
-		} else {
-			w.WriteHeader(http.StatusServiceUnavailable)
+		return status
+	}
+	writeHealth := func(w http.ResponseWriter, status models.HealthStatus) {
+		code := http.StatusOK
+		if status.Status != "healthy" {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, logger, code, status)
+	}
+
+	// Health check endpoint
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, healthStatus())
+	})
+
+	// Проверка состояния с проверкой пути от sender: кроме проверок /health пробное
+	// сообщение sender должно быть получено не раньше health_probe.max_age назад
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if probeMonitor == nil {
+			writeJSON(w, logger, http.StatusNotFound, errorResponse{Error: "проверка пробных сообщений отключена (health_probe.enabled)"})
+			return
+		}
+
+		status := healthStatus()
+		probeCheck := probeMonitor.Check()
+		if probeCheck.Status != "healthy" {
+			status.Status = "unhealthy"
 		}
+		status.Checks = append(status.Checks, probeCheck)
 
-		// Простой JSON вывод без внешних зависимостей
-		fmt.Fprintf(w, `{"status":"%s","service":"%s","version":"%s"}`,
-			status.Status, status.Service, status.Version)
+		writeHealth(w, status)
 	})
 
-	// Ready check endpoint
+	// Ready check endpoint (готовность принимать трафик по всем включенным каналам)
 	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
-		if consumer.IsConnected() {
-			w.WriteHeader(http.StatusOK)
-			fmt.Fprint(w, `{"status":"ready"}`)
-		} else {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			fmt.Fprint(w, `{"status":"not ready"}`)
+		mqttReady, mqttReason := mqttReadiness(consumer)
+		checks := []models.Check{
+			readinessCheck("mqtt", mqttReady, mqttReason),
+			readinessCheck("processor", msgProcessor.IsRunning(), "processor not started"),
+		}
+		if cfg.TCP.Enabled {
+			checks = append(checks, readinessCheck("tcp",
+				tcpServer != nil && tcpServer.IsRunning(),
+				fmt.Sprintf("TCP server not listening on %s", cfg.TCP.Address)))
+		}
+		if cfg.QUIC.Enabled {
+			checks = append(checks, readinessCheck("quic",
+				quicServer != nil && quicServer.IsRunning(),
+				fmt.Sprintf("QUIC server not listening on %s", cfg.QUIC.Address)))
+		}
+		if cfg.NATS.Enabled {
+			checks = append(checks, readinessCheck("nats",
+				natsConsumer != nil && natsConsumer.IsConnected(),
+				"NATS server disconnected"))
+		}
+		if cfg.Serial.Enabled {
+			checks = append(checks, readinessCheck("serial",
+				serialReceiver != nil && serialReceiver.IsOpen(),
+				fmt.Sprintf("serial port %s not open", cfg.Serial.Device)))
+		}
+
+		response := readyResponse{Status: "ready", Checks: checks}
+
+		for _, check := range checks {
+			if check.Status != "ready" {
+				response.Status = "not ready"
+			}
+		}
+
+		code := http.StatusOK
+		if response.Status != "ready" {
+			code = http.StatusServiceUnavailable
 		}
+		writeJSON(w, logger, code, response)
 	})
 
-	// Metrics endpoint
+	// Metrics endpoint (metrics.enabled изменяется без перезапуска)
+	var metricsEnabled atomic.Bool
+	metricsEnabled.Store(cfg.Metrics.Enabled)
 	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if !metricsEnabled.Load() {
+			writeJSON(w, logger, http.StatusNotFound, errorResponse{Error: "экспорт метрик отключен"})
+			return
+		}
+
 		stats := msgProcessor.GetStats()
 		consumerStats := consumer.GetStats()
 
@@ -191,16 +655,60 @@ func main() {
 		fmt.Fprintf(w, "# TYPE messages_valid_total counter\n")
 		fmt.Fprintf(w, "messages_valid_total %d\n", stats.MessagesValid)
 
-		fmt.Fprintf(w, "\n# HELP checksum_errors_total Total number of checksum errors\n")
+		fmt.Fprintf(w, "\n# HELP checksum_errors_total Total number of payload checksum errors\n")
 		fmt.Fprintf(w, "# TYPE checksum_errors_total counter\n")
 		fmt.Fprintf(w, "checksum_errors_total %d\n", stats.ChecksumErrors)
 
-		fmt.Fprintf(w, "\n# HELP message_latency_ms Message processing latency in milliseconds\n")
-		fmt.Fprintf(w, "# TYPE message_latency_ms summary\n")
-		fmt.Fprintf(w, "message_latency_ms{quantile=\"0.5\"} %.2f\n", stats.AvgLatency)
-		fmt.Fprintf(w, "message_latency_ms{quantile=\"0.95\"} %.2f\n", stats.MaxLatency)
-		fmt.Fprintf(w, "message_latency_ms_sum %.2f\n", stats.AvgLatency*float64(stats.MessagesProcessed))
-		fmt.Fprintf(w, "message_latency_ms_count %d\n", stats.MessagesProcessed)
+		fmt.Fprintf(w, "\n# HELP payload_errors_total Total number of messages with unparsable payload\n")
+		fmt.Fprintf(w, "# TYPE payload_errors_total counter\n")
+		fmt.Fprintf(w, "payload_errors_total %d\n", stats.PayloadErrors)
+
+		fmt.Fprintf(w, "\n# HELP integrity_errors_total Total number of payload records violating validation rules\n")
+		fmt.Fprintf(w, "# TYPE integrity_errors_total counter\n")
+		fmt.Fprintf(w, "integrity_errors_total %d\n", stats.IntegrityErrors)
+
+		fmt.Fprintf(w, "\n# HELP messages_stale_total Total number of messages received after their TTL\n")
+		fmt.Fprintf(w, "# TYPE messages_stale_total counter\n")
+		fmt.Fprintf(w, "messages_stale_total %d\n", stats.MessagesStale)
+
+		fmt.Fprintf(w, "\n# HELP message_log_dropped_total Total number of message log entries dropped because the log queue was full\n")
+		fmt.Fprintf(w, "# TYPE message_log_dropped_total counter\n")
+		fmt.Fprintf(w, "message_log_dropped_total %d\n", stats.MessageLog.Dropped)
+
+		fmt.Fprintf(w, "\n# HELP message_log_queue_depth Message log entries waiting to be written\n")
+		fmt.Fprintf(w, "# TYPE message_log_queue_depth gauge\n")
+		fmt.Fprintf(w, "message_log_queue_depth %d\n", stats.MessageLog.Queued)
+
+		fmt.Fprintf(w, "\n# HELP message_log_write_errors_total Total number of message log entries that failed to be written to the message log file\n")
+		fmt.Fprintf(w, "# TYPE message_log_write_errors_total counter\n")
+		fmt.Fprintf(w, "message_log_write_errors_total %d\n", stats.MessageLog.WriteErrors)
+
+		if resultStore != nil {
+			storeStats := resultStore.Stats()
+
+			fmt.Fprintf(w, "\n# HELP store_lag_seconds Age of the oldest result record not yet written to the store\n")
+			fmt.Fprintf(w, "# TYPE store_lag_seconds gauge\n")
+			fmt.Fprintf(w, "store_lag_seconds %.3f\n", storeStats.LagSeconds)
+
+			fmt.Fprintf(w, "\n# HELP store_pending_records Result records accepted but not yet written to the store\n")
+			fmt.Fprintf(w, "# TYPE store_pending_records gauge\n")
+			fmt.Fprintf(w, "store_pending_records %d\n", storeStats.Pending+int64(storeStats.Queued))
+
+			fmt.Fprintf(w, "\n# HELP store_write_failures_total Total number of failed store write transactions\n")
+			fmt.Fprintf(w, "# TYPE store_write_failures_total counter\n")
+			fmt.Fprintf(w, "store_write_failures_total %d\n", storeStats.WriteFailures)
+
+			fmt.Fprintf(w, "\n# HELP store_records_dropped_total Total number of result records dropped because the store queue was full\n")
+			fmt.Fprintf(w, "# TYPE store_records_dropped_total counter\n")
+			fmt.Fprintf(w, "store_records_dropped_total %d\n", storeStats.MessagesDropped)
+		}
+
+		msgProcessor.LatencyHistogram().WriteText(w, "message_latency_ms", messageLatencyHelp)
+		msgProcessor.WritePipelineMetrics(w)
+		msgProcessor.WriteTenantMetrics(w)
+		msgProcessor.WriteOrderingMetrics(w)
+		msgProcessor.WriteBandwidthMetrics(w)
+		msgProcessor.WriteLifetimeMetrics(w)
 
 		fmt.Fprintf(w, "\n# HELP throughput_messages_per_sec Current message throughput\n")
 		fmt.Fprintf(w, "# TYPE throughput_messages_per_sec gauge\n")
@@ -213,63 +721,619 @@ func main() {
 		} else {
 			fmt.Fprintf(w, "mqtt_connected 0\n")
 		}
+
+		fmt.Fprintf(w, "\n# HELP mqtt_subscribed MQTT subscription status (all topics subscribed)\n")
+		fmt.Fprintf(w, "# TYPE mqtt_subscribed gauge\n")
+		if consumerStats.Subscribed {
+			fmt.Fprintf(w, "mqtt_subscribed 1\n")
+		} else {
+			fmt.Fprintf(w, "mqtt_subscribed 0\n")
+		}
+
+		fmt.Fprintf(w, "\n# HELP mqtt_subscribe_errors_total Total number of failed subscribe attempts\n")
+		fmt.Fprintf(w, "# TYPE mqtt_subscribe_errors_total counter\n")
+		fmt.Fprintf(w, "mqtt_subscribe_errors_total %d\n", consumerStats.SubscribeErrors)
+
+		fmt.Fprintf(w, "\n# HELP mqtt_retained_received_total Total number of retained messages delivered on subscribe\n")
+		fmt.Fprintf(w, "# TYPE mqtt_retained_received_total counter\n")
+		fmt.Fprintf(w, "mqtt_retained_received_total %d\n", consumerStats.Retained)
+
+		fmt.Fprintf(w, "\n# HELP mqtt_duplicate_received_total Total number of redelivered messages with DUP flag\n")
+		fmt.Fprintf(w, "# TYPE mqtt_duplicate_received_total counter\n")
+		fmt.Fprintf(w, "mqtt_duplicate_received_total %d\n", consumerStats.Duplicates)
+
+		fmt.Fprintf(w, "\n# HELP mqtt_will_received_total Total number of last will messages received\n")
+		fmt.Fprintf(w, "# TYPE mqtt_will_received_total counter\n")
+		fmt.Fprintf(w, "mqtt_will_received_total %d\n", consumerStats.Will)
+
+		fmt.Fprintf(w, "\n# HELP mqtt_batches_received_total Total number of message batches received as a single MQTT message\n")
+		fmt.Fprintf(w, "# TYPE mqtt_batches_received_total counter\n")
+		fmt.Fprintf(w, "mqtt_batches_received_total %d\n", consumerStats.Batches)
+
+		client := consumerStats.Client
+		fmt.Fprintf(w, "\n# HELP mqtt_store_outbound_messages Number of unacknowledged outbound packets in the MQTT client store\n")
+		fmt.Fprintf(w, "# TYPE mqtt_store_outbound_messages gauge\n")
+		fmt.Fprintf(w, "mqtt_store_outbound_messages{store=\"%s\"} %d\n", client.Store.Type, client.Store.Outbound)
+
+		fmt.Fprintf(w, "\n# HELP mqtt_store_inbound_messages Number of incomplete inbound QoS 2 messages in the MQTT client store\n")
+		fmt.Fprintf(w, "# TYPE mqtt_store_inbound_messages gauge\n")
+		fmt.Fprintf(w, "mqtt_store_inbound_messages{store=\"%s\"} %d\n", client.Store.Type, client.Store.Inbound)
+
+		fmt.Fprintf(w, "\n# HELP mqtt_session_resumes_total Total number of reconnects with incomplete messages in the client store\n")
+		fmt.Fprintf(w, "# TYPE mqtt_session_resumes_total counter\n")
+		fmt.Fprintf(w, "mqtt_session_resumes_total %d\n", client.Resumes)
+
+		if probeMonitor != nil {
+			probeStats := probeMonitor.Stats()
+
+			fmt.Fprintf(w, "\n# HELP health_probes_received_total Total number of sender health probes received\n")
+			fmt.Fprintf(w, "# TYPE health_probes_received_total counter\n")
+			fmt.Fprintf(w, "health_probes_received_total %d\n", probeStats.Received)
+
+			fmt.Fprintf(w, "\n# HELP health_probes_missed_total Total number of sender health probes missing by sequence\n")
+			fmt.Fprintf(w, "# TYPE health_probes_missed_total counter\n")
+			fmt.Fprintf(w, "health_probes_missed_total %d\n", probeStats.Missed)
+
+			if probeStats.LastReceived != nil {
+				fmt.Fprintf(w, "\n# HELP health_probe_age_seconds Time since the last sender health probe was received\n")
+				fmt.Fprintf(w, "# TYPE health_probe_age_seconds gauge\n")
+				fmt.Fprintf(w, "health_probe_age_seconds %.3f\n", probeStats.AgeSeconds)
+			}
+		}
+
+		if demuxWriter != nil {
+			demuxStats := demuxWriter.Stats()
+
+			fmt.Fprintf(w, "\n# HELP demux_records_written_total Total number of validated payload records written to per-equipment files\n")
+			fmt.Fprintf(w, "# TYPE demux_records_written_total counter\n")
+			fmt.Fprintf(w, "demux_records_written_total %d\n", demuxStats.RecordsWritten)
+
+			fmt.Fprintf(w, "\n# HELP demux_dropped_records_total Total number of payload records dropped because the per-equipment file queue was full\n")
+			fmt.Fprintf(w, "# TYPE demux_dropped_records_total counter\n")
+			fmt.Fprintf(w, "demux_dropped_records_total %d\n", demuxStats.Dropped)
+
+			fmt.Fprintf(w, "\n# HELP demux_files_completed_total Total number of completed per-equipment files\n")
+			fmt.Fprintf(w, "# TYPE demux_files_completed_total counter\n")
+			fmt.Fprintf(w, "demux_files_completed_total %d\n", demuxStats.FilesCompleted)
+		}
+
+		if natsConsumer != nil {
+			natsStats := natsConsumer.GetStats()
+
+			fmt.Fprintf(w, "\n# HELP nats_messages_received_total Total number of messages received from NATS JetStream\n")
+			fmt.Fprintf(w, "# TYPE nats_messages_received_total counter\n")
+			fmt.Fprintf(w, "nats_messages_received_total %d\n", natsStats.MessagesReceived)
+
+			fmt.Fprintf(w, "\n# HELP nats_connected NATS connection status\n")
+			fmt.Fprintf(w, "# TYPE nats_connected gauge\n")
+			if natsStats.Connected {
+				fmt.Fprintf(w, "nats_connected 1\n")
+			} else {
+				fmt.Fprintf(w, "nats_connected 0\n")
+			}
+		}
+
+		if tcpServer != nil {
+			tcpStats := tcpServer.GetStats()
+
+			fmt.Fprintf(w, "\n# HELP tcp_raw_bytes_received_total Total number of raw filler frame bytes received over TCP (wire saturation test)\n")
+			fmt.Fprintf(w, "# TYPE tcp_raw_bytes_received_total counter\n")
+			fmt.Fprintf(w, "tcp_raw_bytes_received_total %d\n", tcpStats.RawBytesReceived)
+
+			fmt.Fprintf(w, "\n# HELP tcp_frame_checksum_errors_total Total number of TCP frames with mismatched frame checksum (payload checksum errors are counted in checksum_errors_total)\n")
+			fmt.Fprintf(w, "# TYPE tcp_frame_checksum_errors_total counter\n")
+			fmt.Fprintf(w, "tcp_frame_checksum_errors_total %d\n", tcpStats.FrameChecksumErrors)
+		}
+
+		if quicServer != nil {
+			quicStats := quicServer.GetStats()
+
+			fmt.Fprintf(w, "\n# HELP quic_messages_received_total Total number of messages received over QUIC\n")
+			fmt.Fprintf(w, "# TYPE quic_messages_received_total counter\n")
+			fmt.Fprintf(w, "quic_messages_received_total %d\n", quicStats.MessagesReceived)
+
+			fmt.Fprintf(w, "\n# HELP quic_connections_active Number of active QUIC connections\n")
+			fmt.Fprintf(w, "# TYPE quic_connections_active gauge\n")
+			fmt.Fprintf(w, "quic_connections_active %d\n", quicStats.ConnectionsActive)
+
+			fmt.Fprintf(w, "\n# HELP quic_streams_active Number of active QUIC streams\n")
+			fmt.Fprintf(w, "# TYPE quic_streams_active gauge\n")
+			fmt.Fprintf(w, "quic_streams_active %d\n", quicStats.StreamsActive)
+
+			fmt.Fprintf(w, "\n# HELP quic_zero_rtt_accepted_total Total number of QUIC connections with accepted 0-RTT data\n")
+			fmt.Fprintf(w, "# TYPE quic_zero_rtt_accepted_total counter\n")
+			fmt.Fprintf(w, "quic_zero_rtt_accepted_total %d\n", quicStats.ZeroRTT)
+		}
+
+		if auditPublisher != nil {
+			fmt.Fprintf(w, "\n# HELP audit_digests_published_total Total number of digests published to audit channel\n")
+			fmt.Fprintf(w, "# TYPE audit_digests_published_total counter\n")
+			fmt.Fprintf(w, "audit_digests_published_total %d\n", auditPublisher.Stats().Published)
+		}
+
+		if throughputMonitor != nil {
+			throughputStats := throughputMonitor.Stats()
+
+			fmt.Fprintf(w, "\n# HELP receive_throughput_window Messages per second over sliding window\n")
+			fmt.Fprintf(w, "# TYPE receive_throughput_window gauge\n")
+			fmt.Fprintf(w, "receive_throughput_window %.2f\n", throughputStats.Throughput)
+
+			fmt.Fprintf(w, "\n# HELP receive_throughput_high_watermark Highest sliding window throughput\n")
+			fmt.Fprintf(w, "# TYPE receive_throughput_high_watermark gauge\n")
+			fmt.Fprintf(w, "receive_throughput_high_watermark %.2f\n", throughputStats.HighWatermark)
+
+			fmt.Fprintf(w, "\n# HELP receive_throughput_low_watermark Lowest non-zero full window throughput\n")
+			fmt.Fprintf(w, "# TYPE receive_throughput_low_watermark gauge\n")
+			fmt.Fprintf(w, "receive_throughput_low_watermark %.2f\n", throughputStats.LowWatermark)
+
+			fmt.Fprintf(w, "\n# HELP receive_lag_max_ms Max receive-to-processed lag over last second\n")
+			fmt.Fprintf(w, "# TYPE receive_lag_max_ms gauge\n")
+			fmt.Fprintf(w, "receive_lag_max_ms %.3f\n", throughputStats.LagMs)
+
+			fmt.Fprintf(w, "\n# HELP slow_consumer_active Processing is falling behind receive\n")
+			fmt.Fprintf(w, "# TYPE slow_consumer_active gauge\n")
+			if throughputStats.FallingBehind {
+				fmt.Fprintf(w, "slow_consumer_active 1\n")
+			} else {
+				fmt.Fprintf(w, "slow_consumer_active 0\n")
+			}
+
+			fmt.Fprintf(w, "\n# HELP slow_consumer_events_total Total number of falling behind events\n")
+			fmt.Fprintf(w, "# TYPE slow_consumer_events_total counter\n")
+			fmt.Fprintf(w, "slow_consumer_events_total %d\n", throughputStats.Events)
+		}
+
+		if sloMonitor != nil {
+			sloStats := sloMonitor.Stats()
+
+			fmt.Fprintf(w, "\n# HELP slo_burn_rate Latency error budget burn rate by objective and window\n")
+			fmt.Fprintf(w, "# TYPE slo_burn_rate gauge\n")
+			for _, objective := range sloStats.Objectives {
+				fmt.Fprintf(w, "slo_burn_rate{objective=\"%s\",window=\"long\"} %.4f\n", objective.Name, objective.Long.BurnRate)
+				fmt.Fprintf(w, "slo_burn_rate{objective=\"%s\",window=\"short\"} %.4f\n", objective.Name, objective.Short.BurnRate)
+			}
+
+			fmt.Fprintf(w, "\n# HELP slo_error_ratio Share of messages over latency threshold in long window\n")
+			fmt.Fprintf(w, "# TYPE slo_error_ratio gauge\n")
+			for _, objective := range sloStats.Objectives {
+				fmt.Fprintf(w, "slo_error_ratio{objective=\"%s\"} %.6f\n", objective.Name, objective.Long.ErrorRatio)
+			}
+
+			fmt.Fprintf(w, "\n# HELP slo_violating Objective burn rate is over threshold in both windows\n")
+			fmt.Fprintf(w, "# TYPE slo_violating gauge\n")
+			for _, objective := range sloStats.Objectives {
+				violating := 0
+				if objective.Violating {
+					violating = 1
+				}
+				fmt.Fprintf(w, "slo_violating{objective=\"%s\"} %d\n", objective.Name, violating)
+			}
+
+			fmt.Fprintf(w, "\n# HELP slo_violations_total Total number of objective violations\n")
+			fmt.Fprintf(w, "# TYPE slo_violations_total counter\n")
+			for _, objective := range sloStats.Objectives {
+				fmt.Fprintf(w, "slo_violations_total{objective=\"%s\"} %d\n", objective.Name, objective.Violations)
+			}
+		}
+
+		if serialReceiver != nil {
+			serialStats := serialReceiver.GetStats()
+
+			fmt.Fprintf(w, "\n# HELP serial_frames_received_total Total number of valid frames received from serial port\n")
+			fmt.Fprintf(w, "# TYPE serial_frames_received_total counter\n")
+			fmt.Fprintf(w, "serial_frames_received_total %d\n", serialStats.FramesReceived)
+
+			fmt.Fprintf(w, "\n# HELP serial_crc_errors_total Total number of serial frames dropped on CRC mismatch\n")
+			fmt.Fprintf(w, "# TYPE serial_crc_errors_total counter\n")
+			fmt.Fprintf(w, "serial_crc_errors_total %d\n", serialStats.CRCErrors)
+
+			fmt.Fprintf(w, "\n# HELP serial_port_open Serial port status\n")
+			fmt.Fprintf(w, "# TYPE serial_port_open gauge\n")
+			if serialStats.Open {
+				fmt.Fprintf(w, "serial_port_open 1\n")
+			} else {
+				fmt.Fprintf(w, "serial_port_open 0\n")
+			}
+		}
+
+		utils.WriteRuntimeMetrics(w)
+	})
+
+	// Гистограмма задержек в формате protobuf: классические и нативные корзины Prometheus
+	mux.HandleFunc("GET /metrics/native", func(w http.ResponseWriter, r *http.Request) {
+		if !metricsEnabled.Load() {
+			writeJSON(w, logger, http.StatusNotFound, errorResponse{Error: "экспорт метрик отключен"})
+			return
+		}
+
+		w.Header().Set("Content-Type", utils.ProtoContentType)
+		if err := msgProcessor.LatencyHistogram().WriteProto(w, "message_latency_ms", messageLatencyHelp); err != nil {
+			logger.Warn("Ошибка вывода гистограммы задержек", zap.Error(err))
+			return
+		}
+		if err := msgProcessor.WritePipelineProto(w); err != nil {
+			logger.Warn("Ошибка вывода гистограмм этапов обработки", zap.Error(err))
+		}
 	})
 
+	// Профилирование (metrics.debug): CPU, heap, горутины, блокировки
+	if cfg.Metrics.Debug {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		logger.Warn("Включены обработчики профилирования /debug/pprof/")
+	}
+
 	// Stats endpoint (JSON формат статистики)
+	currentStats := func() statsResponse {
+		response := statsResponse{
+			Service:   newServiceInfo(cfg.Service, startTime),
+			Processor: newProcessorStats(msgProcessor.GetStats()),
+			Consumer:  newConsumerStats(consumer.GetStats()),
+			Tenants:   msgProcessor.GetTenantStats(),
+			Ordering:  msgProcessor.GetOrderingStats(),
+			Bandwidth: msgProcessor.GetBandwidthStats(),
+		}
+		if lifetime, ok := msgProcessor.GetLifetimeStats(); ok {
+			response.Lifetime = &lifetime
+		}
+		if tcpServer != nil {
+			tcpStats := tcpServer.GetStats()
+			response.TCP = &tcpStats
+		}
+		if quicServer != nil {
+			quicStats := quicServer.GetStats()
+			response.QUIC = &quicStats
+		}
+		if natsConsumer != nil {
+			natsStats := newConsumerStats(natsConsumer.GetStats())
+			response.NATS = &natsStats
+		}
+		if serialReceiver != nil {
+			serialStats := serialReceiver.GetStats()
+			response.Serial = &serialStats
+		}
+		if archiver != nil {
+			archiveStats := archiver.Stats()
+			response.Archive = &archiveStats
+		}
+		if demuxWriter != nil {
+			demuxStats := demuxWriter.Stats()
+			response.Demux = &demuxStats
+		}
+		if correlationLog != nil {
+			correlationStats := correlationLog.Stats()
+			response.Correlation = &correlationStats
+		}
+		if resultStore != nil {
+			storeStats := resultStore.Stats()
+			response.Store = &storeStats
+		}
+		if auditPublisher != nil {
+			auditStats := auditPublisher.Stats()
+			response.Audit = &auditStats
+		}
+		if probeMonitor != nil {
+			probeStats := probeMonitor.Stats()
+			response.HealthProbe = &probeStats
+		}
+		if throughputMonitor != nil {
+			throughputStats := throughputMonitor.Stats()
+			response.Throughput = &throughputStats
+		}
+		if sloMonitor != nil {
+			sloStats := sloMonitor.Stats()
+			response.SLO = &sloStats
+		}
+		return response
+	}
+
 	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
-		stats := msgProcessor.GetStats()
-		consumerStats := consumer.GetStats()
+		window, err := utils.ParseStatsWindow(r.URL.Query().Get("window"))
+		if err != nil {
+			writeJSON(w, logger, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		response := currentStats()
+		if window > 0 {
+			windowStats := msgProcessor.GetWindowStats(window)
+			response.Window = &windowStats
+		}
+		writeJSON(w, logger, http.StatusOK, response)
+	})
+
+	// Сведения о сборке и конфигурации для проверки совместимости с sender
+	version := newVersionInfo(cfg)
+	mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, logger, http.StatusOK, version)
+	})
+
+	// Описание API (OpenAPI 3) для клиентов и интеграционных проверок
+	document, err := openAPIDocument(Version)
+	if err != nil {
+		logger.Fatal("Ошибка формирования описания API", zap.Error(err))
+	}
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, logger, http.StatusOK, json.RawMessage(document))
+	})
+
+	// Distribution endpoint (top-N распределение записей по оборудованию и индикаторам)
+	mux.HandleFunc("/stats/distribution", func(w http.ResponseWriter, r *http.Request) {
+		top := 10
+		if value := r.URL.Query().Get("top"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				writeJSON(w, logger, http.StatusBadRequest, errorResponse{Error: "некорректное значение top"})
+				return
+			}
+			top = n
+		}
+
+		writeJSON(w, logger, http.StatusOK, msgProcessor.GetDistribution(top))
+	})
+
+	// Sessions endpoints (полнота доставки сообщений по тестам sender)
+	mux.HandleFunc("/sessions", func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseSessionFilter(r.URL.Query())
+		if err != nil {
+			writeJSON(w, logger, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
 
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `{
-			"processor": {
-				"messages_received": %d,
-				"messages_processed": %d,
-				"messages_valid": %d,
-				"messages_invalid": %d,
-				"checksum_errors": %d,
-				"processing_errors": %d,
-				"total_bytes_received": %d,
-				"avg_message_size": %d,
-				"min_latency_ms": %.2f,
-				"max_latency_ms": %.2f,
-				"avg_latency_ms": %.2f,
-				"throughput_msg_per_sec": %.2f
-			},
-			"consumer": {
-				"messages_received": %d,
-				"bytes_received": %d,
-				"errors": %d,
-				"reconnect_count": %d,
-				"connected": %t,
-				"uptime_seconds": %.0f
+		reports := filterSessions(msgProcessor.GetSessionReports(), filter)
+		if resultStore != nil {
+			stored, err := resultStore.Sessions(filter)
+			if err != nil {
+				writeJSON(w, logger, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+				return
 			}
-		}`,
-			stats.MessagesReceived,
-			stats.MessagesProcessed,
-			stats.MessagesValid,
-			stats.MessagesInvalid,
-			stats.ChecksumErrors,
-			stats.ProcessingErrors,
-			stats.TotalBytesReceived,
-			stats.AvgMessageSize,
-			stats.MinLatency,
-			stats.MaxLatency,
-			stats.AvgLatency,
-			stats.Throughput,
-			consumerStats.MessagesReceived,
-			consumerStats.BytesReceived,
-			consumerStats.Errors,
-			consumerStats.ReconnectCount,
-			consumerStats.Connected,
-			consumerStats.Uptime.Seconds())
+			reports = mergeStoredSessions(reports, stored)
+		}
+
+		writeJSON(w, logger, http.StatusOK, reports)
+	})
+
+	mux.HandleFunc("/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		report, ok := msgProcessor.GetSessionReport(id)
+		if !ok && resultStore != nil {
+			// Тест мог завершиться до перезапуска сервиса или быть вытеснен из памяти
+			var err error
+			if report, ok, err = resultStore.Session(id); err != nil {
+				writeJSON(w, logger, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+				return
+			}
+		}
+		if !ok {
+			writeJSON(w, logger, http.StatusNotFound, errorResponse{Error: "сообщения теста не получены"})
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, report)
+	})
+
+	mux.HandleFunc("GET /sessions/{id}/timeline", func(w http.ResponseWriter, r *http.Request) {
+		points, ok := msgProcessor.GetSessionTimeline(r.PathValue("id"))
+		if !ok {
+			writeJSON(w, logger, http.StatusNotFound, errorResponse{Error: "сообщения теста не получены"})
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, points)
+	})
+
+	mux.HandleFunc("GET /sessions/{id}/messages", func(w http.ResponseWriter, r *http.Request) {
+		if resultStore == nil {
+			writeJSON(w, logger, http.StatusNotFound, errorResponse{Error: "хранилище результатов отключено"})
+			return
+		}
+
+		filter, err := parseMessageFilter(r.URL.Query())
+		if err != nil {
+			writeJSON(w, logger, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		id := r.PathValue("id")
+		messages, err := resultStore.Messages(id, filter)
+		if err != nil {
+			writeJSON(w, logger, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, sessionMessagesResponse{
+			TestID:   id,
+			Count:    len(messages),
+			Messages: messages,
+		})
+	})
+
+	// Files endpoints (прием файлов теста передачи файлов)
+	mux.HandleFunc("GET /files", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, logger, http.StatusOK, fileAssembler.Reports())
+	})
+
+	mux.HandleFunc("GET /files/{id}", func(w http.ResponseWriter, r *http.Request) {
+		report, ok := fileAssembler.Report(r.PathValue("id"))
+		if !ok {
+			writeJSON(w, logger, http.StatusNotFound, errorResponse{Error: "передача файла не найдена"})
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, report)
+	})
+
+	// Cluster endpoints (статистика нескольких экземпляров с общей подпиской MQTT)
+	cluster, err := newClusterClient(&cfg.Cluster)
+	if err != nil {
+		logger.Fatal("Ошибка создания клиента экземпляров cluster.peers", zap.Error(err))
+	}
+
+	mux.HandleFunc("GET /cluster/stats", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, logger, http.StatusOK, cluster.Stats(r.Context(), currentStats()))
+	})
+
+	mux.HandleFunc("GET /cluster/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		local, _ := msgProcessor.GetSessionReport(id)
+
+		response, ok := cluster.SessionReport(r.Context(), id, cfg.Service.Instance, local)
+		if !ok {
+			writeJSON(w, logger, http.StatusNotFound, errorResponse{Error: "сообщения теста не получены ни одним экземпляром"})
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, response)
+	})
+
+	// Активные TCP подключения (какой экземпляр sender передает данные и с ошибками)
+	mux.HandleFunc("GET /tcp/connections", func(w http.ResponseWriter, r *http.Request) {
+		if tcpServer == nil {
+			writeJSON(w, logger, http.StatusNotFound, errorResponse{Error: "TCP сервер отключен"})
+			return
+		}
+
+		connections := tcpServer.Connections()
+		writeJSON(w, logger, http.StatusOK, tcpConnectionsResponse{
+			Count:       len(connections),
+			Connections: connections,
+		})
+	})
+
+	// Цели по задержке доставки и их нарушения
+	mux.HandleFunc("GET /slo", func(w http.ResponseWriter, r *http.Request) {
+		if sloMonitor == nil {
+			writeJSON(w, logger, http.StatusNotFound, errorResponse{Error: "оценка целей отключена (slo.enabled)"})
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, sloMonitor.Stats())
+	})
+
+	mux.HandleFunc("GET /slo/violations", func(w http.ResponseWriter, r *http.Request) {
+		if sloMonitor == nil {
+			writeJSON(w, logger, http.StatusNotFound, errorResponse{Error: "оценка целей отключена (slo.enabled)"})
+			return
+		}
+
+		query := r.URL.Query()
+		violations := sloMonitor.Violations(query.Get("objective"), query.Get("active") == "true")
+		writeJSON(w, logger, http.StatusOK, sloViolationsResponse{Count: len(violations), Violations: violations})
+	})
+
+	// Сброс статистики обработчика, consumer, TCP и QUIC серверов и приема через последовательный порт
+	// (например, между прогонами тестов)
+	mux.HandleFunc("POST /admin/reset-stats", func(w http.ResponseWriter, r *http.Request) {
+		msgProcessor.ResetStats()
+		consumer.ResetStats()
+		if natsConsumer != nil {
+			natsConsumer.ResetStats()
+		}
+		if tcpServer != nil {
+			tcpServer.ResetStats()
+		}
+		if quicServer != nil {
+			quicServer.ResetStats()
+		}
+		if serialReceiver != nil {
+			serialReceiver.ResetStats()
+		}
+		if throughputMonitor != nil {
+			throughputMonitor.ResetWatermarks()
+		}
+		if sloMonitor != nil {
+			sloMonitor.Reset()
+		}
+		logger.Info("Статистика сброшена по запросу", zap.String("remote_addr", r.RemoteAddr))
+
+		writeJSON(w, logger, http.StatusOK, currentStats())
+	})
+
+	// Профиль проверки сообщений; переключение действует до перезапуска или
+	// перечитывания раздела validation конфигурации
+	mux.HandleFunc("GET /admin/validation", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, logger, http.StatusOK, newValidationResponse(msgProcessor.Profile(), msgProcessor.ValidationRules()))
+	})
+
+	mux.HandleFunc("PUT /admin/validation", func(w http.ResponseWriter, r *http.Request) {
+		var req validationRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+			writeJSON(w, logger, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("некорректный запрос: %v", err)})
+			return
+		}
+		profile, err := validator.ParseProfile(req.Profile)
+		if err != nil {
+			writeJSON(w, logger, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		previous := msgProcessor.Profile()
+		msgProcessor.SetProfile(profile)
+		logger.Info("Профиль проверки изменен по запросу",
+			zap.String("previous", string(previous)),
+			zap.String("profile", string(profile)),
+			zap.String("remote_addr", r.RemoteAddr))
+
+		writeJSON(w, logger, http.StatusOK, newValidationResponse(profile, msgProcessor.ValidationRules()))
+	})
+
+	// Повторная подписка MQTT (брокер доставляет сохраненные retained сообщения)
+	mux.HandleFunc("POST /mqtt/resubscribe", func(w http.ResponseWriter, r *http.Request) {
+		if err := consumer.Resubscribe(); err != nil {
+			writeJSON(w, logger, http.StatusServiceUnavailable, errorResponse{Error: err.Error()})
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, newConsumerStats(consumer.GetStats()))
+	})
+
+	// Журнал действий API с отбором по since (RFC3339), actor, action, test_id и limit
+	mux.HandleFunc("GET /audit", func(w http.ResponseWriter, r *http.Request) {
+		if actions == nil {
+			writeJSON(w, logger, http.StatusNotFound, errorResponse{Error: "журнал действий не ведется (action_log.file)"})
+			return
+		}
+
+		query, err := parseActionQuery(r.URL.Query())
+		if err != nil {
+			writeJSON(w, logger, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		events, err := actions.Query(query)
+		if err != nil {
+			writeJSON(w, logger, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, actionLogResponse{Log: actions.Status(), Events: events})
 	})
 
 	httpServer := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.Metrics.Port),
-		Handler:      mux,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		Addr:              net.JoinHostPort(cfg.Metrics.Host, strconv.Itoa(cfg.Metrics.Port)),
+		Handler:           actionlog.Handler(actions, logger, mux),
+		ReadTimeout:       cfg.Metrics.ReadTimeout,
+		ReadHeaderTimeout: cfg.Metrics.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Metrics.WriteTimeout,
+		IdleTimeout:       cfg.Metrics.IdleTimeout,
+	}
+
+	// Применение изменений конфигурации без перезапуска (изменение файла или SIGHUP)
+	reloader := &configReloader{
+		current:        *cfg,
+		logger:         logger,
+		logLevel:       logLevel,
+		consumer:       consumer,
+		processor:      msgProcessor,
+		metricsEnabled: &metricsEnabled,
+		actions:        actions,
+	}
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if err := config.Watch(watchCtx, *configPath, reloader.apply); err != nil {
+		logger.Warn("Отслеживание изменений конфигурации недоступно", zap.Error(err))
 	}
 
 	// Канал для graceful shutdown
@@ -279,12 +1343,19 @@ func main() {
 	// Канал для ошибок
 	errChan := make(chan error, 1)
 
+	// Уведомления systemd и работа службой Windows
+	service, err := supervisor.Start("infodiode-recipient", shutdown, errChan)
+	if err != nil {
+		logger.Fatal("Ошибка подключения к супервизору", zap.Error(err))
+	}
+
 	// Запускаем HTTP сервер
 	go func() {
 		logger.Info("Запуск HTTP сервера для метрик",
-			zap.Int("port", cfg.Metrics.Port))
+			zap.String("addr", httpServer.Addr),
+			zap.Bool("tls", cfg.Metrics.CertFile != ""))
 
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := utils.ListenAndServe(httpServer, cfg.Metrics.CertFile, cfg.Metrics.KeyFile); err != nil && err != http.ErrServerClosed {
 			errChan <- fmt.Errorf("ошибка HTTP сервера: %w", err)
 		}
 	}()
@@ -303,16 +1374,25 @@ func main() {
 		}
 	}()
 
+	if err := service.Ready(); err != nil {
+		logger.Warn("Ошибка уведомления systemd о готовности", zap.Error(err))
+	}
+	if interval := service.Watchdog(msgProcessor.IsRunning); interval > 0 {
+		logger.Info("Watchdog systemd включен", zap.Duration("interval", interval))
+	}
+
 	// Ожидаем сигнал завершения или ошибку
+	var exitErr error
 	select {
 	case sig := <-shutdown:
 		logger.Info("Получен сигнал завершения", zap.String("signal", sig.String()))
-	case err := <-errChan:
-		logger.Error("Критическая ошибка", zap.Error(err))
+	case exitErr = <-errChan:
+		logger.Error("Критическая ошибка", zap.Error(exitErr))
 	}
 
 	// Graceful shutdown
 	logger.Info("Начало graceful shutdown...")
+	service.Stopping()
 	statsTicker.Stop()
 
 	// Создаем контекст с таймаутом для shutdown
@@ -345,34 +1425,27 @@ func main() {
 		zap.Float64("средняя_задержка_ms", finalStats.AvgLatency))
 
 	logger.Info("Recipient сервис остановлен")
+	service.Stopped(exitErr)
+
+	// Ненулевой код завершения после критической ошибки, чтобы супервизор перезапустил сервис
+	if exitErr != nil {
+		saveLifetime()
+		logger.Sync()
+		os.Exit(1)
+	}
 }
 
-// initLogger инициализирует логгер
-func initLogger(cfg *config.Config) (*zap.Logger, error) {
+// initLogger инициализирует логгер. Возвращаемый уровень общий для выводов без
+// собственного уровня и может меняться без пересоздания логгера
+func initLogger(cfg *config.Config) (*zap.Logger, zap.AtomicLevel, error) {
 	// Парсим уровень логирования
-	level, err := zapcore.ParseLevel(cfg.Logger.Level)
+	parsed, err := logging.ParseLevel(cfg.Logger.Level)
 	if err != nil {
-		return nil, fmt.Errorf("неверный уровень логирования: %w", err)
+		return nil, zap.AtomicLevel{}, fmt.Errorf("неверный уровень логирования: %w", err)
 	}
+	level := zap.NewAtomicLevelAt(parsed)
 
-	// Создаем encoder config
-	encoderConfig := zapcore.EncoderConfig{
-		TimeKey:        "timestamp",
-		LevelKey:       "level",
-		NameKey:        "logger",
-		CallerKey:      "caller",
-		FunctionKey:    zapcore.OmitKey,
-		MessageKey:     "message",
-		StacktraceKey:  "stacktrace",
-		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeLevel:    zapcore.LowercaseLevelEncoder,
-		EncodeTime:     zapcore.ISO8601TimeEncoder,
-		EncodeDuration: zapcore.SecondsDurationEncoder,
-		EncodeCaller:   zapcore.ShortCallerEncoder,
-	}
-
-	// Создаем JSON encoder для файла
-	jsonEncoder := zapcore.NewJSONEncoder(encoderConfig)
+	encoderConfig := logging.EncoderConfig()
 
 	// Создаем cores
 	var cores []zapcore.Core
@@ -388,30 +1461,78 @@ func initLogger(cfg *config.Config) (*zap.Logger, error) {
 			LocalTime:  true,
 		}
 
-		fileCore := zapcore.NewCore(
-			jsonEncoder,
-			zapcore.AddSync(fileWriter),
-			level,
-		)
+		fileCore, err := newLogCore(cfg.Logger.Format, encoderConfig, zapcore.AddSync(fileWriter), level, cfg.Logger.FileLevel)
+		if err != nil {
+			return nil, level, fmt.Errorf("ошибка создания файлового вывода: %w", err)
+		}
 		cores = append(cores, fileCore)
 	}
 
 	// Консольный core
 	if cfg.Logger.Console {
-		consoleEncoder := zapcore.NewConsoleEncoder(encoderConfig)
-		consoleCore := zapcore.NewCore(
-			consoleEncoder,
-			zapcore.AddSync(os.Stdout),
-			level,
-		)
+		consoleCore, err := newLogCore(cfg.Logger.ConsoleFormat, encoderConfig, zapcore.AddSync(os.Stdout), level, cfg.Logger.ConsoleLevel)
+		if err != nil {
+			return nil, level, fmt.Errorf("ошибка создания консольного вывода: %w", err)
+		}
 		cores = append(cores, consoleCore)
 	}
 
+	// Вывод на сервер syslog
+	if cfg.Logger.Syslog.Enabled {
+		enabler, err := logging.SinkLevel(level, cfg.Logger.Syslog.Level)
+		if err != nil {
+			return nil, level, fmt.Errorf("неверный уровень syslog: %w", err)
+		}
+		syslogCore, err := logging.NewSyslogCore(cfg.Logger.Syslog.SinkConfig(), enabler)
+		if err != nil {
+			return nil, level, fmt.Errorf("ошибка создания вывода syslog: %w", err)
+		}
+		cores = append(cores, syslogCore)
+	}
+
 	// Создаем tee core
 	core := zapcore.NewTee(cores...)
 
 	// Создаем логгер
 	logger := zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
 
-	return logger, nil
+	return logger, level, nil
+}
+
+// newLogCore создает вывод формата format в writer с уровнем sinkLevel или общим уровнем
+func newLogCore(format string, encoderConfig zapcore.EncoderConfig, writer zapcore.WriteSyncer,
+	level zap.AtomicLevel, sinkLevel string) (zapcore.Core, error) {
+	encoder, err := logging.NewEncoder(format, encoderConfig)
+	if err != nil {
+		return nil, err
+	}
+	enabler, err := logging.SinkLevel(level, sinkLevel)
+	if err != nil {
+		return nil, err
+	}
+	return zapcore.NewCore(encoder, writer, enabler), nil
+}
+
+// mqttReadiness проверяет подключение к MQTT брокеру и подписку на топики;
+// возвращает причину неготовности
+func mqttReadiness(consumer *broker.MQTTConsumer) (bool, string) {
+	if !consumer.IsConnected() {
+		return false, "MQTT broker disconnected"
+	}
+	if !consumer.IsSubscribed() {
+		stats := consumer.GetStats()
+		if stats.LastSubscribeErr != "" {
+			return false, "MQTT subscription failed: " + stats.LastSubscribeErr
+		}
+		return false, "MQTT subscription pending"
+	}
+	return true, ""
+}
+
+// readinessCheck формирует результат проверки готовности компонента
+func readinessCheck(component string, ready bool, reason string) models.Check {
+	if ready {
+		return models.Check{Component: component, Status: "ready"}
+	}
+	return models.Check{Component: component, Status: "not ready", Message: reason}
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/infodiode/recipient/internal/processor"
+	"github.com/infodiode/recipient/internal/slo"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/openapi"
+	"github.com/infodiode/shared/utils"
+)
+
+// openAPIDocument формирует документ OpenAPI API recipient версии version
+func openAPIDocument(version string) ([]byte, error) {
+	doc := openapi.New(openapi.Info{
+		Title:       "infodiode recipient",
+		Version:     version,
+		Description: "HTTP API приемника: статистика приема, полнота доставки и управление проверкой сообщений",
+	},
+		openapi.Tag{Name: "service", Description: "Состояние и метрики"},
+		openapi.Tag{Name: "sessions", Description: "Полнота доставки сообщений тестов"},
+		openapi.Tag{Name: "cluster", Description: "Статистика нескольких экземпляров"},
+		openapi.Tag{Name: "admin", Description: "Управление приемом"},
+	)
+
+	window := openapi.Param{Name: "window", Description: "Окно показателей: 1s..5m или all"}
+	sessionFilter := []openapi.Param{
+		{Name: "tenant", Description: "Команда"},
+		{Name: "run_label", Description: "Метка прогона"},
+	}
+
+	doc.Add(
+		openapi.Route{Method: http.MethodGet, Path: "/health", Tag: "service", Summary: "Состояние recipient",
+			Response: models.HealthStatus{}, Errors: []int{http.StatusServiceUnavailable}},
+		openapi.Route{Method: http.MethodGet, Path: "/healthz", Tag: "service", Summary: "Состояние recipient с проверкой получения пробных сообщений sender",
+			Response: models.HealthStatus{}, Errors: []int{http.StatusNotFound, http.StatusServiceUnavailable}},
+		openapi.Route{Method: http.MethodGet, Path: "/ready", Tag: "service", Summary: "Готовность принимать трафик по всем включенным каналам",
+			Response: readyResponse{}},
+		openapi.Route{Method: http.MethodGet, Path: "/version", Tag: "service", Summary: "Сведения о сборке и конфигурации",
+			Response: models.VersionInfo{}},
+		openapi.Route{Method: http.MethodGet, Path: "/openapi.json", Tag: "service", Summary: "Описание API (OpenAPI 3)",
+			Response: json.RawMessage{}},
+		openapi.Route{Method: http.MethodGet, Path: "/metrics", Tag: "service", Summary: "Метрики Prometheus",
+			ContentType: "text/plain", Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodGet, Path: "/metrics/native", Tag: "service", Summary: "Гистограммы задержки и этапов обработки (native histogram)",
+			ContentType: utils.ProtoContentType, Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodGet, Path: "/stats", Tag: "service", Summary: "Статистика приема",
+			Query: []openapi.Param{window}, Response: statsResponse{}, Errors: []int{http.StatusBadRequest}},
+		openapi.Route{Method: http.MethodGet, Path: "/stats/distribution", Tag: "service", Summary: "Распределение записей по оборудованию и индикаторам",
+			Query:    []openapi.Param{{Name: "top", Type: "integer", Description: "Количество позиций (по умолчанию 10)"}},
+			Response: processor.DistributionSnapshot{}, Errors: []int{http.StatusBadRequest}},
+		openapi.Route{Method: http.MethodGet, Path: "/tcp/connections", Tag: "service", Summary: "Активные TCP подключения",
+			Response: tcpConnectionsResponse{}, Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodGet, Path: "/slo", Tag: "service", Summary: "Цели по задержке доставки",
+			Response: slo.Stats{}, Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodGet, Path: "/slo/violations", Tag: "service", Summary: "Нарушения целей по задержке",
+			Query: []openapi.Param{
+				{Name: "objective", Description: "Цель"},
+				{Name: "active", Type: "boolean", Description: "Только продолжающиеся нарушения"},
+			},
+			Response: sloViolationsResponse{}, Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodGet, Path: "/audit", Tag: "service", Summary: "Журнал действий API",
+			Query: []openapi.Param{
+				{Name: "since", Description: "Записи начиная с момента (RFC3339)"},
+				{Name: "actor", Description: "Инициатор действия"},
+				{Name: "action", Description: "Начало имени действия"},
+				{Name: "test_id", Description: "Идентификатор теста"},
+				{Name: "limit", Type: "integer", Description: "Количество записей (по умолчанию 100)"},
+			},
+			Response: actionLogResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError}},
+
+		openapi.Route{Method: http.MethodGet, Path: "/sessions", Tag: "sessions", Summary: "Отчеты тестов",
+			Query: sessionFilter, Response: []*models.SessionReport{}, Errors: []int{http.StatusBadRequest, http.StatusInternalServerError}},
+		openapi.Route{Method: http.MethodGet, Path: "/sessions/{id}", Tag: "sessions", Summary: "Отчет о полноте доставки сообщений теста",
+			Response: models.SessionReport{}, Errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		openapi.Route{Method: http.MethodGet, Path: "/sessions/{id}/timeline", Tag: "sessions", Summary: "Посекундная динамика приема сообщений теста",
+			Response: []models.ReceivePoint{}, Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodGet, Path: "/sessions/{id}/messages", Tag: "sessions", Summary: "Сообщения теста из хранилища результатов",
+			Query: []openapi.Param{
+				{Name: "invalid", Type: "boolean", Description: "Только непрошедшие проверку"},
+				{Name: "stale", Type: "boolean", Description: "Только устаревшие"},
+				{Name: "after", Type: "integer", Description: "Сообщения после номера"},
+				{Name: "limit", Type: "integer", Description: "Количество сообщений"},
+			},
+			Response: sessionMessagesResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError}},
+		openapi.Route{Method: http.MethodGet, Path: "/files", Tag: "sessions", Summary: "Отчеты о приеме файлов",
+			Response: []*models.FileTransferReport{}},
+		openapi.Route{Method: http.MethodGet, Path: "/files/{id}", Tag: "sessions", Summary: "Отчет о приеме файла",
+			Response: models.FileTransferReport{}, Errors: []int{http.StatusNotFound}},
+
+		openapi.Route{Method: http.MethodGet, Path: "/cluster/stats", Tag: "cluster", Summary: "Статистика экземпляров и суммарная",
+			Response: clusterStatsResponse{}},
+		openapi.Route{Method: http.MethodGet, Path: "/cluster/sessions/{id}", Tag: "cluster", Summary: "Объединенный отчет экземпляров по тесту",
+			Response: clusterSessionResponse{}, Errors: []int{http.StatusNotFound}},
+
+		openapi.Route{Method: http.MethodPost, Path: "/admin/reset-stats", Tag: "admin", Summary: "Сброс статистики",
+			Response: statsResponse{}},
+		openapi.Route{Method: http.MethodGet, Path: "/admin/validation", Tag: "admin", Summary: "Профиль проверки сообщений",
+			Response: validationResponse{}},
+		openapi.Route{Method: http.MethodPut, Path: "/admin/validation", Tag: "admin", Summary: "Смена профиля проверки сообщений",
+			Request: validationRequest{}, Response: validationResponse{}, Errors: []int{http.StatusBadRequest}},
+		openapi.Route{Method: http.MethodPost, Path: "/mqtt/resubscribe", Tag: "admin", Summary: "Повторная подписка MQTT (доставка retained сообщений)",
+			Response: consumerStats{}, Errors: []int{http.StatusServiceUnavailable}},
+	)
+
+	return doc.JSON()
+}
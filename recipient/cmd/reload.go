@@ -0,0 +1,123 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"github.com/infodiode/recipient/config"
+	"github.com/infodiode/recipient/internal/broker"
+	"github.com/infodiode/recipient/internal/processor"
+	"github.com/infodiode/shared/actionlog"
+	"github.com/infodiode/shared/configwatch"
+	"github.com/infodiode/shared/logging"
+	"go.uber.org/zap"
+)
+
+// configReloader применяет изменения конфигурации без перезапуска сервиса.
+// Без перезапуска меняются уровень логирования, окно обработки MQTT
+// (max_inflight), срок актуальности сообщений, правила проверки payload и включение
+// экспорта метрик; об остальных изменениях пишется в лог
+type configReloader struct {
+	current        config.Config
+	logger         *zap.Logger
+	logLevel       zap.AtomicLevel
+	consumer       *broker.MQTTConsumer
+	processor      *processor.MessageProcessor
+	metricsEnabled *atomic.Bool
+	actions        *actionlog.Log // Журнал действий, nil если не ведется
+}
+
+// apply применяет перечитанную конфигурацию
+func (r *configReloader) apply(next *config.Config, err error) {
+	if err != nil {
+		r.logger.Error("Ошибка перечитывания конфигурации, используется текущая", zap.Error(err))
+		recordAction(r.logger, r.actions, "config.reload", nil, err)
+		return
+	}
+
+	var applied []string
+
+	if next.Logger.Level != r.current.Logger.Level {
+		if level, err := logging.ParseLevel(next.Logger.Level); err != nil {
+			r.logger.Error("Ошибка изменения уровня логирования", zap.Error(err))
+		} else {
+			r.logLevel.SetLevel(level)
+			r.logger.Info("Уровень логирования изменен",
+				zap.String("old", r.current.Logger.Level),
+				zap.String("new", next.Logger.Level))
+			r.current.Logger.Level = next.Logger.Level
+			applied = append(applied, "logger.level")
+		}
+	}
+
+	if next.MQTT.MaxInflight != r.current.MQTT.MaxInflight {
+		r.consumer.SetMaxInflight(next.MQTT.MaxInflight)
+		r.logger.Info("Окно обработки MQTT изменено",
+			zap.Int("old", r.current.MQTT.MaxInflight),
+			zap.Int("new", next.MQTT.MaxInflight))
+		r.current.MQTT.MaxInflight = next.MQTT.MaxInflight
+		applied = append(applied, "mqtt.max_inflight")
+	}
+
+	if next.Processing.MessageTTL != r.current.Processing.MessageTTL {
+		r.processor.SetMessageTTL(next.Processing.MessageTTL)
+		r.logger.Info("Срок актуальности сообщений изменен",
+			zap.Duration("old", r.current.Processing.MessageTTL),
+			zap.Duration("new", next.Processing.MessageTTL))
+		r.current.Processing.MessageTTL = next.Processing.MessageTTL
+		applied = append(applied, "processing.message_ttl")
+	}
+
+	if next.Processing.MonotonicLatency != r.current.Processing.MonotonicLatency {
+		r.processor.SetMonotonicLatency(next.Processing.MonotonicLatency)
+		r.logger.Info("Режим задержки по монотонным часам изменен",
+			zap.Bool("enabled", next.Processing.MonotonicLatency))
+		r.current.Processing.MonotonicLatency = next.Processing.MonotonicLatency
+		applied = append(applied, "processing.monotonic_latency")
+	}
+
+	if !reflect.DeepEqual(next.Validation, r.current.Validation) {
+		r.processor.SetValidation(next.Validation.ValidationProfile(), next.Validation.Rules())
+		r.logger.Info("Правила проверки payload изменены",
+			zap.String("profile", string(next.Validation.ValidationProfile())),
+			zap.Any("rules", next.Validation.Rules()))
+		r.current.Validation = next.Validation
+		applied = append(applied, "validation")
+	}
+
+	if next.Metrics.Enabled != r.current.Metrics.Enabled {
+		r.metricsEnabled.Store(next.Metrics.Enabled)
+		r.logger.Info("Экспорт метрик изменен", zap.Bool("enabled", next.Metrics.Enabled))
+		r.current.Metrics.Enabled = next.Metrics.Enabled
+		applied = append(applied, "metrics.enabled")
+	}
+
+	sections := configwatch.ChangedSections(&r.current, next)
+	if len(sections) > 0 {
+		r.logger.Warn("Изменения конфигурации вступят в силу после перезапуска сервиса",
+			zap.Strings("sections", sections))
+	}
+
+	if len(applied) > 0 || len(sections) > 0 {
+		details := map[string]string{}
+		if len(applied) > 0 {
+			details["applied"] = strings.Join(applied, ",")
+		}
+		if len(sections) > 0 {
+			details["pending_restart"] = strings.Join(sections, ",")
+		}
+		recordAction(r.logger, r.actions, "config.reload", details, nil)
+	}
+}
+
+// recordAction записывает в журнал действие сервиса, выполненное без запроса API
+func recordAction(logger *zap.Logger, actions *actionlog.Log, action string, details map[string]string, err error) {
+	event := actionlog.Event{Actor: actionlog.ActorSystem, Action: action, Details: details}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	if err := actions.Record(event); err != nil {
+		logger.Error("Ошибка записи действия в журнал", zap.String("action", action), zap.Error(err))
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/infodiode/recipient/config"
+	"github.com/infodiode/recipient/internal/archive"
+	"github.com/infodiode/recipient/internal/processor"
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// replayResponse результат воспроизведения архива
+type replayResponse struct {
+	Archive      string                  `json:"archive"`
+	Frames       int64                   `json:"frames"`
+	DecodeErrors int64                   `json:"decode_errors"`
+	Processor    processorStats          `json:"processor"`
+	Sessions     []*models.SessionReport `json:"sessions"`
+}
+
+// runReplay пропускает кадры архива через обработчик сообщений с исходным временем
+// получения и выводит итоговую статистику и отчеты по сессиям в формате JSON.
+// Используется для повторной проверки прошлых прогонов новой версией валидатора
+// или с другими правилами проверки payload
+func runReplay(path string, validation config.ValidationConfig, logger *zap.Logger, out io.Writer) error {
+	msgProcessor := processor.NewMessageProcessor(logger)
+	msgProcessor.SetValidation(validation.ValidationProfile(), validation.Rules())
+	if err := msgProcessor.Start(); err != nil {
+		return fmt.Errorf("ошибка запуска обработчика сообщений: %w", err)
+	}
+	defer msgProcessor.Stop()
+
+	result := replayResponse{Archive: path}
+
+	err := archive.Replay(path, func(record *archive.Record) error {
+		result.Frames++
+
+		switch record.Kind {
+		case archive.KindBatch:
+			var batch models.MessageBatch
+			if err := json.Unmarshal(record.Data, &batch); err != nil {
+				result.DecodeErrors++
+				logger.Warn("Ошибка десериализации пакета из архива",
+					zap.String("source", record.Source.String()),
+					zap.Error(err))
+				return nil
+			}
+			for _, message := range batch.Messages {
+				if message != nil {
+					msgProcessor.ProcessReceivedMessage(message, -1, record.ReceivedAt)
+				}
+			}
+		default:
+			var message models.Message
+			if err := json.Unmarshal(record.Data, &message); err != nil {
+				result.DecodeErrors++
+				logger.Warn("Ошибка десериализации сообщения из архива",
+					zap.String("source", record.Source.String()),
+					zap.Error(err))
+				return nil
+			}
+			msgProcessor.ProcessReceivedMessage(&message, len(record.Data), record.ReceivedAt)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка чтения архива: %w", err)
+	}
+
+	result.Processor = newProcessorStats(msgProcessor.GetStats())
+	result.Sessions = msgProcessor.GetSessionReports()
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
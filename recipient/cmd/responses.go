@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/infodiode/recipient/config"
+	"github.com/infodiode/recipient/internal/archive"
+	"github.com/infodiode/recipient/internal/broker"
+	"github.com/infodiode/recipient/internal/demux"
+	"github.com/infodiode/recipient/internal/processor"
+	"github.com/infodiode/recipient/internal/quic"
+	"github.com/infodiode/recipient/internal/serial"
+	"github.com/infodiode/recipient/internal/slo"
+	"github.com/infodiode/recipient/internal/store"
+	"github.com/infodiode/recipient/internal/tcp"
+	"github.com/infodiode/recipient/internal/throughput"
+	"github.com/infodiode/recipient/internal/validator"
+	"github.com/infodiode/shared/actionlog"
+	"github.com/infodiode/shared/binrec"
+	"github.com/infodiode/shared/correlation"
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// statsResponse ответ /stats
+type statsResponse struct {
+	Service     serviceInfo                 `json:"service"`
+	Processor   processorStats              `json:"processor"`          // С запуска процесса или сброса статистики
+	Lifetime    *processor.LifetimeStats    `json:"lifetime,omitempty"` // За все запуски (при processing.stats_file)
+	Window      *models.WindowStats         `json:"window,omitempty"`   // За последнее окно (/stats?window=1m)
+	Consumer    consumerStats               `json:"consumer"`
+	Tenants     []processor.TenantSnapshot  `json:"tenants,omitempty"` // Прием по командам и прогонам
+	Ordering    processor.OrderingSnapshot  `json:"ordering"`          // Проверка порядка сообщений в потоках
+	Bandwidth   processor.BandwidthSnapshot `json:"bandwidth"`         // Объем и скорость приема по протоколам
+	TCP         *tcp.StatsSnapshot          `json:"tcp,omitempty"`
+	QUIC        *quic.StatsSnapshot         `json:"quic,omitempty"`
+	NATS        *consumerStats              `json:"nats,omitempty"`
+	Serial      *serial.StatsSnapshot       `json:"serial,omitempty"`
+	Archive     *archive.Stats              `json:"archive,omitempty"`
+	Demux       *demux.Stats                `json:"demux,omitempty"`
+	Correlation *correlation.Stats          `json:"correlation,omitempty"`
+	Store       *store.Stats                `json:"store,omitempty"`
+	Audit       *broker.AuditStats          `json:"audit,omitempty"`
+	HealthProbe *broker.ProbeStats          `json:"health_probe,omitempty"`
+	Throughput  *throughput.Stats           `json:"throughput,omitempty"`
+	SLO         *slo.Stats                  `json:"slo,omitempty"`
+}
+
+// readyResponse ответ /ready
+type readyResponse struct {
+	Status string         `json:"status"`
+	Checks []models.Check `json:"checks"`
+}
+
+// sessionMessagesResponse ответ /sessions/{id}/messages
+type sessionMessagesResponse struct {
+	TestID   string                `json:"test_id"`
+	Count    int                   `json:"count"`
+	Messages []store.MessageRecord `json:"messages"`
+}
+
+// clusterStatsResponse ответ /cluster/stats: статистика экземпляров и суммарная по всем доступным
+type clusterStatsResponse struct {
+	Available   int                    `json:"available"`
+	Unavailable int                    `json:"unavailable"`
+	Processor   processorStats         `json:"processor"`
+	Consumer    clusterConsumerStats   `json:"consumer"`
+	Instances   []clusterInstanceStats `json:"instances"`
+}
+
+// clusterInstanceStats статистика одного экземпляра; для недоступного экземпляра - ошибка запроса
+type clusterInstanceStats struct {
+	Instance string         `json:"instance"`
+	URL      string         `json:"url,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Stats    *statsResponse `json:"stats,omitempty"`
+}
+
+// clusterConsumerStats суммарная статистика MQTT consumer экземпляров
+type clusterConsumerStats struct {
+	MessagesReceived int64 `json:"messages_received"`
+	BytesReceived    int64 `json:"bytes_received"`
+	Errors           int64 `json:"errors"`
+	ReconnectCount   int32 `json:"reconnect_count"`
+	Connected        int   `json:"connected"`
+	Throttled        int64 `json:"throttled"`
+	Retained         int64 `json:"retained"`
+	Duplicates       int64 `json:"duplicate_flagged"`
+}
+
+// clusterSessionResponse ответ /cluster/sessions/{id}: объединенный отчет и отчеты экземпляров
+type clusterSessionResponse struct {
+	Report    *models.SessionReport    `json:"report"`
+	Instances []clusterInstanceSession `json:"instances"`
+}
+
+// clusterInstanceSession отчет экземпляра по тесту; nil, если экземпляр не получал сообщений теста
+type clusterInstanceSession struct {
+	Instance string                `json:"instance"`
+	URL      string                `json:"url,omitempty"`
+	Error    string                `json:"error,omitempty"`
+	Report   *models.SessionReport `json:"report,omitempty"`
+}
+
+// tcpConnectionsResponse ответ /tcp/connections
+type tcpConnectionsResponse struct {
+	Count       int                  `json:"count"`
+	Connections []tcp.ConnectionInfo `json:"connections"`
+}
+
+// validationResponse ответ /admin/validation
+type validationResponse struct {
+	Profile     validator.Profile `json:"profile"`
+	Checksum    bool              `json:"checksum"`
+	Payload     bool              `json:"payload"`
+	Integrity   bool              `json:"integrity"`
+	IndicatorID rangeResponse     `json:"indicator_id"`
+	EquipmentID rangeResponse     `json:"equipment_id"`
+	ValueLength int               `json:"value_length"`
+	Binary      *binrec.Layout    `json:"binary,omitempty"` // Раскладка двоичных записей, если задана
+}
+
+// rangeResponse допустимый диапазон значений
+type rangeResponse struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// validationRequest запрос PUT /admin/validation
+type validationRequest struct {
+	Profile string `json:"profile"`
+}
+
+// actionLogResponse ответ /audit
+type actionLogResponse struct {
+	Log    actionlog.Status  `json:"log"`
+	Events []actionlog.Event `json:"events"`
+}
+
+// sloViolationsResponse ответ /slo/violations
+type sloViolationsResponse struct {
+	Count      int             `json:"count"`
+	Violations []slo.Violation `json:"violations"`
+}
+
+// errorResponse ответ с описанием ошибки запроса
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// serviceInfo версия и время работы сервиса
+type serviceInfo struct {
+	Name          string    `json:"name"`
+	Instance      string    `json:"instance"`
+	Version       string    `json:"version"`
+	BuildTime     string    `json:"build_time"`
+	GoVersion     string    `json:"go_version"`
+	StartTime     time.Time `json:"start_time"`
+	UptimeSeconds float64   `json:"uptime_seconds"`
+}
+
+// processorStats статистика обработчика сообщений
+type processorStats struct {
+	MessagesReceived   int64     `json:"messages_received"`
+	MessagesProcessed  int64     `json:"messages_processed"`
+	MessagesValid      int64     `json:"messages_valid"`
+	MessagesInvalid    int64     `json:"messages_invalid"`
+	ChecksumErrors     int64     `json:"checksum_errors"`
+	ProcessingErrors   int64     `json:"processing_errors"`
+	PayloadErrors      int64     `json:"payload_errors"`
+	IntegrityErrors    int64     `json:"integrity_errors"`
+	MessagesStale      int64     `json:"messages_stale"`
+	TotalBytesReceived int64     `json:"total_bytes_received"`
+	AvgMessageSize     int64     `json:"avg_message_size"`
+	MinLatency         float64   `json:"min_latency_ms"`
+	MaxLatency         float64   `json:"max_latency_ms"`
+	AvgLatency         float64   `json:"avg_latency_ms"`
+	Throughput         float64   `json:"throughput_msg_per_sec"`
+	FirstMessageTime   time.Time `json:"first_message_time"`
+	LastMessageTime    time.Time `json:"last_message_time"`
+
+	Pipeline   processor.PipelineSnapshot `json:"pipeline,omitzero"`    // Длительности этапов обработки
+	MessageLog processor.MessageLogStats  `json:"message_log,omitzero"` // Очередь журнала сообщений
+}
+
+// consumerStats статистика consumer брокера
+type consumerStats struct {
+	MessagesReceived int64   `json:"messages_received"`
+	BytesReceived    int64   `json:"bytes_received"`
+	Errors           int64   `json:"errors"`
+	ReconnectCount   int32   `json:"reconnect_count"`
+	Connected        bool    `json:"connected"`
+	Subscribed       bool    `json:"subscribed"`
+	SubscribeErrors  int64   `json:"subscribe_errors"`
+	GrantedQoS       *int    `json:"granted_qos,omitempty"` // QoS, предоставленный брокером подписке на основной топик
+	UptimeSeconds    float64 `json:"uptime_seconds"`
+	AvgMessageSize   int64   `json:"avg_message_size"`
+	InFlight         int64   `json:"inflight"`
+	InFlightLimit    int     `json:"inflight_limit"`
+	Throttled        int64   `json:"throttled"`
+	Retained         int64   `json:"retained"`
+	Duplicates       int64   `json:"duplicate_flagged"`
+	Will             int64   `json:"will"`
+	Batches          int64   `json:"batches"` // Пакетов сообщений MessageBatch в одном сообщении брокера
+
+	Client *broker.ClientStats `json:"client,omitempty"` // Хранилище сессии клиента MQTT (только MQTT)
+}
+
+// newServiceInfo формирует сведения о сервисе
+func newServiceInfo(cfg config.ServiceConfig, startTime time.Time) serviceInfo {
+	return serviceInfo{
+		Name:          cfg.Name,
+		Instance:      cfg.Instance,
+		Version:       Version,
+		BuildTime:     BuildTime,
+		GoVersion:     runtime.Version(),
+		StartTime:     startTime,
+		UptimeSeconds: time.Since(startTime).Seconds(),
+	}
+}
+
+// newProcessorStats преобразует снимок статистики обработчика в ответ API
+func newProcessorStats(stats processor.ProcessorStatsSnapshot) processorStats {
+	return processorStats{
+		MessagesReceived:   stats.MessagesReceived,
+		MessagesProcessed:  stats.MessagesProcessed,
+		MessagesValid:      stats.MessagesValid,
+		MessagesInvalid:    stats.MessagesInvalid,
+		ChecksumErrors:     stats.ChecksumErrors,
+		ProcessingErrors:   stats.ProcessingErrors,
+		PayloadErrors:      stats.PayloadErrors,
+		IntegrityErrors:    stats.IntegrityErrors,
+		MessagesStale:      stats.MessagesStale,
+		TotalBytesReceived: stats.TotalBytesReceived,
+		AvgMessageSize:     stats.AvgMessageSize,
+		MinLatency:         stats.MinLatency,
+		MaxLatency:         stats.MaxLatency,
+		AvgLatency:         stats.AvgLatency,
+		Throughput:         stats.Throughput,
+		FirstMessageTime:   stats.FirstMessageTime,
+		LastMessageTime:    stats.LastMessageTime,
+		Pipeline:           stats.Pipeline,
+		MessageLog:         stats.MessageLog,
+	}
+}
+
+// newConsumerStats преобразует статистику consumer в ответ API
+func newValidationResponse(profile validator.Profile, rules validator.Rules) validationResponse {
+	return validationResponse{
+		Profile:     profile,
+		Checksum:    profile.Checksum(),
+		Payload:     profile.Payload(),
+		Integrity:   profile.Integrity(),
+		IndicatorID: rangeResponse{Min: rules.IndicatorMin, Max: rules.IndicatorMax},
+		EquipmentID: rangeResponse{Min: rules.EquipmentMin, Max: rules.EquipmentMax},
+		ValueLength: rules.ValueLength,
+		Binary:      rules.Binary,
+	}
+}
+
+func newConsumerStats(stats broker.ConsumerStats) consumerStats {
+	response := consumerStats{
+		MessagesReceived: stats.MessagesReceived,
+		BytesReceived:    stats.BytesReceived,
+		Errors:           stats.Errors,
+		ReconnectCount:   stats.ReconnectCount,
+		Connected:        stats.Connected,
+		Subscribed:       stats.Subscribed,
+		SubscribeErrors:  stats.SubscribeErrors,
+		GrantedQoS:       stats.GrantedQoS,
+		UptimeSeconds:    stats.Uptime.Seconds(),
+		AvgMessageSize:   stats.AvgMessageSize,
+		InFlight:         stats.InFlight,
+		InFlightLimit:    stats.InFlightLimit,
+		Throttled:        stats.Throttled,
+		Retained:         stats.Retained,
+		Duplicates:       stats.Duplicates,
+		Will:             stats.Will,
+		Batches:          stats.Batches,
+	}
+	if stats.Client.Store.Type != "" {
+		response.Client = &stats.Client
+	}
+	return response
+}
+
+// writeJSON записывает ответ в формате JSON с указанным статусом
+func writeJSON(w http.ResponseWriter, logger *zap.Logger, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("Ошибка формирования ответа", zap.Error(err))
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/infodiode/recipient/internal/store"
+	"github.com/infodiode/shared/models"
+)
+
+const (
+	// maxStoredSessions количество сохраненных отчетов в ответе /sessions
+	maxStoredSessions = 1000
+	// defaultMessagesLimit и maxMessagesLimit размер выборки /sessions/{id}/messages
+	defaultMessagesLimit = 1000
+	maxMessagesLimit     = 10000
+)
+
+// mergeStoredSessions дополняет отчеты из памяти сохраненными отчетами тестов,
+// которых уже нет в памяти; отчет из памяти актуальнее сохраненного
+func mergeStoredSessions(live, stored []*models.SessionReport) []*models.SessionReport {
+	seen := make(map[string]bool, len(live))
+	for _, report := range live {
+		seen[report.TestID] = true
+	}
+
+	merged := append([]*models.SessionReport{}, live...)
+	for _, report := range stored {
+		if !seen[report.TestID] {
+			merged = append(merged, report)
+		}
+	}
+	return merged
+}
+
+// parseSessionFilter разбирает параметры выборки отчетов по тестам: tenant и run_label
+func parseSessionFilter(query url.Values) (store.SessionFilter, error) {
+	filter := store.SessionFilter{
+		Tenant:   query.Get("tenant"),
+		RunLabel: query.Get("run_label"),
+		Limit:    maxStoredSessions,
+	}
+	if err := models.ValidateLabel("tenant", filter.Tenant); err != nil {
+		return filter, err
+	}
+	if err := models.ValidateLabel("run_label", filter.RunLabel); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// filterSessions оставляет отчеты тестов с метками фильтра
+func filterSessions(reports []*models.SessionReport, filter store.SessionFilter) []*models.SessionReport {
+	if filter.Tenant == "" && filter.RunLabel == "" {
+		return reports
+	}
+
+	filtered := []*models.SessionReport{}
+	for _, report := range reports {
+		if (filter.Tenant == "" || report.Tenant == filter.Tenant) &&
+			(filter.RunLabel == "" || report.RunLabel == filter.RunLabel) {
+			filtered = append(filtered, report)
+		}
+	}
+	return filtered
+}
+
+// parseMessageFilter разбирает параметры выборки записей о сообщениях:
+// invalid, stale (true/false), after (номер сообщения) и limit
+func parseMessageFilter(query url.Values) (store.MessageFilter, error) {
+	filter := store.MessageFilter{Limit: defaultMessagesLimit}
+
+	for name, target := range map[string]*bool{"invalid": &filter.InvalidOnly, "stale": &filter.StaleOnly} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return filter, fmt.Errorf("некорректное значение %s", name)
+			}
+			*target = parsed
+		}
+	}
+
+	if value := query.Get("after"); value != "" {
+		after, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || after < 0 {
+			return filter, fmt.Errorf("некорректное значение after")
+		}
+		filter.After = after
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxMessagesLimit {
+			return filter, fmt.Errorf("некорректное значение limit (1-%d)", maxMessagesLimit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"runtime"
+
+	"github.com/infodiode/recipient/config"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
+)
+
+// newVersionInfo формирует сведения о сборке и конфигурации для GET /version
+func newVersionInfo(cfg *config.Config) models.VersionInfo {
+	transports := []string{string(models.ProtocolMQTT)}
+	if cfg.TCP.Enabled {
+		transports = append(transports, string(models.ProtocolTCP))
+	}
+	if cfg.QUIC.Enabled {
+		transports = append(transports, string(models.ProtocolQUIC))
+	}
+	if cfg.NATS.Enabled {
+		transports = append(transports, string(models.ProtocolNATS))
+	}
+	if cfg.Serial.Enabled {
+		transports = append(transports, string(models.ProtocolSerial))
+	}
+
+	return models.VersionInfo{
+		Service:    cfg.Service.Name,
+		Version:    Version,
+		BuildTime:  BuildTime,
+		GitCommit:  gitCommit(),
+		GoVersion:  runtime.Version(),
+		Transports: transports,
+		Features:   enabledFeatures(cfg),
+		ConfigHash: utils.ConfigHash(cfg),
+	}
+}
+
+// gitCommit возвращает коммит сборки: заданный при сборке или встроенный go build
+func gitCommit() string {
+	if GitCommit != "" {
+		return GitCommit
+	}
+	return utils.VCSRevision()
+}
+
+// enabledFeatures возвращает включенные в конфигурации возможности
+func enabledFeatures(cfg *config.Config) []string {
+	features := []string{}
+	add := func(name string, enabled bool) {
+		if enabled {
+			features = append(features, name)
+		}
+	}
+
+	add("payload_validation", cfg.Validation.ValidationProfile().Payload())
+	add("message_ttl", cfg.Processing.MessageTTL > 0)
+	add("monotonic_latency", cfg.Processing.MonotonicLatency)
+	add("tcp_allowlist", cfg.TCP.Enabled && len(cfg.TCP.AllowedNetworks) > 0)
+	add("archive", cfg.Archive.Enabled)
+	add("correlation", cfg.Correlation.Enabled)
+	add("store", cfg.Store.Enabled)
+	add("files", cfg.Files.Enabled)
+	add("demux", cfg.Demux.Enabled)
+	add("cluster", len(cfg.Cluster.Peers) > 0)
+	add("audit", cfg.Audit.Enabled)
+	add("health_probe", cfg.HealthProbe.Enabled)
+	add("slow_consumer", cfg.SlowConsumer.Enabled)
+	add("slo", cfg.SLO.Enabled)
+	add("action_log", cfg.ActionLog.File != "")
+	add("syslog", cfg.Logger.Syslog.Enabled)
+	add("metrics", cfg.Metrics.Enabled)
+	add("pprof", cfg.Metrics.Debug)
+
+	return features
+}
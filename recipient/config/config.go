@@ -2,25 +2,63 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/infodiode/recipient/internal/validator"
+	"github.com/infodiode/shared/binrec"
+	"github.com/infodiode/shared/configwatch"
+	"github.com/infodiode/shared/logging"
+	"github.com/infodiode/shared/serialport"
+	"github.com/infodiode/shared/tcpframe"
+	"github.com/infodiode/shared/utils"
 	"github.com/spf13/viper"
 )
 
+// sloNamePattern допустимые имена целей SLO (используются как значение метки метрик)
+var sloNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
 // Config представляет полную конфигурацию сервиса recipient
 type Config struct {
 	Service ServiceConfig `mapstructure:"service"`
 	MQTT    MQTTConfig    `mapstructure:"mqtt"`
 	TCP     TCPConfig     `mapstructure:"tcp"`
+	QUIC    QUICConfig    `mapstructure:"quic"`
+	NATS    NATSConfig    `mapstructure:"nats"`
+	Serial  SerialConfig  `mapstructure:"serial"`
 	Logger  LoggerConfig  `mapstructure:"logger"`
 	Metrics MetricsConfig `mapstructure:"metrics"`
+	Archive ArchiveConfig `mapstructure:"archive"`
+
+	Correlation CorrelationConfig `mapstructure:"correlation"`
+	MessageLog  MessageLogConfig  `mapstructure:"message_log"`
+
+	Processing ProcessingConfig `mapstructure:"processing"`
+	Validation ValidationConfig `mapstructure:"validation"`
+	Cluster    ClusterConfig    `mapstructure:"cluster"`
+	Store      StoreConfig      `mapstructure:"store"`
+	Files      FilesConfig      `mapstructure:"files"`
+	Demux      DemuxConfig      `mapstructure:"demux"`
+	Audit      AuditConfig      `mapstructure:"audit"`
+
+	HealthProbe HealthProbeConfig `mapstructure:"health_probe"`
+
+	SlowConsumer SlowConsumerConfig `mapstructure:"slow_consumer"`
+	SLO          SLOConfig          `mapstructure:"slo"`
+
+	ActionLog ActionLogConfig `mapstructure:"action_log"`
 }
 
 // ServiceConfig конфигурация сервиса
 type ServiceConfig struct {
-	Name    string `mapstructure:"name"`
-	Version string `mapstructure:"version"`
+	Name     string `mapstructure:"name"`
+	Version  string `mapstructure:"version"`
+	Instance string `mapstructure:"instance"` // Имя экземпляра в объединенной статистике (по умолчанию имя хоста)
 }
 
 // MQTTConfig конфигурация MQTT брокера
@@ -39,6 +77,8 @@ type MQTTConfig struct {
 	OrderMatters    bool          `mapstructure:"order_matters"`          // Сохранять ли порядок сообщений
 	StoreDirectory  string        `mapstructure:"store_directory"`        // Директория для хранения сообщений
 	MaxInflight     int           `mapstructure:"max_inflight"`           // Максимум сообщений в обработке
+	WillTopic       string        `mapstructure:"will_topic"`             // Топик last will sender (пусто - не подписываться)
+	SharedGroup     string        `mapstructure:"shared_group"`           // Группа общей подписки $share/<group>/<topic> (пусто - обычная подписка)
 }
 
 // TCPConfig конфигурация TCP сервера
@@ -46,10 +86,103 @@ type TCPConfig struct {
 	Address         string        `mapstructure:"address"`           // Адрес для прослушивания (host:port)
 	MaxConnections  int           `mapstructure:"max_connections"`   // Максимальное количество подключений
 	ReadTimeout     time.Duration `mapstructure:"read_timeout"`      // Таймаут чтения
+	DrainTimeout    time.Duration `mapstructure:"drain_timeout"`     // Ожидание завершения кадров подключений при остановке
 	WriteTimeout    time.Duration `mapstructure:"write_timeout"`     // Таймаут записи
 	KeepAlive       bool          `mapstructure:"keep_alive"`        // Использовать ли keep-alive
 	KeepAlivePeriod time.Duration `mapstructure:"keep_alive_period"` // Период keep-alive
+	PingInterval    time.Duration `mapstructure:"ping_interval"`     // Период ping сервера в протоколе v2 (0 - не отправлять)
+	PingTimeout     time.Duration `mapstructure:"ping_timeout"`      // Предельное время без pong клиента (0 - три периода ping)
 	Enabled         bool          `mapstructure:"enabled"`           // Включен ли TCP сервер
+	AllowedNetworks []string      `mapstructure:"allowed_networks"`  // Сети (CIDR) или адреса, с которых разрешено подключение (пусто - с любых)
+
+	ReadBufferSize int  `mapstructure:"read_buffer_size"` // Буфер чтения кадров подключения, байт
+	RecvBuffer     int  `mapstructure:"recv_buffer"`      // SO_RCVBUF, байт (0 - по умолчанию ОС)
+	SendBuffer     int  `mapstructure:"send_buffer"`      // SO_SNDBUF, байт (0 - по умолчанию ОС)
+	NoDelay        bool `mapstructure:"no_delay"`         // TCP_NODELAY
+	Linger         int  `mapstructure:"linger"`           // SO_LINGER, секунд (-1 - по умолчанию ОС)
+}
+
+// SocketOptions возвращает параметры сокета подключений
+func (c TCPConfig) SocketOptions() tcpframe.SocketOptions {
+	return tcpframe.SocketOptions{
+		ReadBufferSize: c.ReadBufferSize,
+		RecvBuffer:     c.RecvBuffer,
+		SendBuffer:     c.SendBuffer,
+		NoDelay:        c.NoDelay,
+		Linger:         c.Linger,
+	}
+}
+
+// QUICConfig конфигурация QUIC сервера
+type QUICConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`           // Включен ли QUIC сервер
+	Address         string        `mapstructure:"address"`           // Адрес для прослушивания (host:port, UDP)
+	CertFile        string        `mapstructure:"cert_file"`         // Сертификат сервера (пусто - самоподписанный)
+	KeyFile         string        `mapstructure:"key_file"`          // Ключ сертификата
+	MaxStreams      int           `mapstructure:"max_streams"`       // Одновременных потоков в соединении
+	ZeroRTT         bool          `mapstructure:"zero_rtt"`          // Принимать данные 0-RTT при возобновлении сессии
+	KeepAlivePeriod time.Duration `mapstructure:"keep_alive_period"` // Период keep-alive QUIC (0 - не отправлять)
+	IdleTimeout     time.Duration `mapstructure:"idle_timeout"`      // Время простоя до закрытия соединения
+}
+
+// Networks возвращает разрешенные сети; отдельный адрес означает сеть из одного адреса
+func (c TCPConfig) Networks() ([]netip.Prefix, error) {
+	networks := make([]netip.Prefix, 0, len(c.AllowedNetworks))
+	for _, entry := range c.AllowedNetworks {
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			networks = append(networks, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("некорректная сеть в tcp.allowed_networks: %s", entry)
+		}
+		networks = append(networks, prefix.Masked())
+	}
+	return networks, nil
+}
+
+// NATSConfig конфигурация NATS JetStream
+type NATSConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`         // Включен ли прием через NATS
+	URL            string        `mapstructure:"url"`             // Адрес сервера (nats://host:port)
+	Username       string        `mapstructure:"username"`        // Имя пользователя для аутентификации
+	Password       string        `mapstructure:"password"`        // Пароль для аутентификации
+	Token          string        `mapstructure:"token"`           // Токен аутентификации
+	Stream         string        `mapstructure:"stream"`          // Поток JetStream (создается при отсутствии)
+	Subject        string        `mapstructure:"subject"`         // Субъект потока
+	Durable        string        `mapstructure:"durable"`         // Имя durable pull consumer
+	ConnectTimeout time.Duration `mapstructure:"connect_timeout"` // Таймаут подключения и запросов API
+	AckWait        time.Duration `mapstructure:"ack_wait"`        // Время до повторной доставки неподтвержденного сообщения
+	FetchBatch     int           `mapstructure:"fetch_batch"`     // Сообщений в одном запросе к consumer
+	FetchTimeout   time.Duration `mapstructure:"fetch_timeout"`   // Время ожидания сообщений в одном запросе
+	ReconnectWait  time.Duration `mapstructure:"reconnect_wait"`  // Интервал между попытками переподключения
+}
+
+// SerialConfig конфигурация приема через последовательный порт (RS-232/RS-485)
+type SerialConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`         // Включен ли прием через последовательный порт
+	Device         string        `mapstructure:"device"`          // Устройство порта (/dev/ttyS0, /dev/ttyUSB0)
+	BaudRate       int           `mapstructure:"baud_rate"`       // Скорость, бод
+	DataBits       int           `mapstructure:"data_bits"`       // Битов данных (5-8)
+	Parity         string        `mapstructure:"parity"`          // Контроль четности: none, even или odd
+	StopBits       int           `mapstructure:"stop_bits"`       // Стоповых битов (1 или 2)
+	RS485          bool          `mapstructure:"rs485"`           // Режим RS-485 (направление передачи через RTS)
+	MaxFrameSize   int           `mapstructure:"max_frame_size"`  // Максимальный размер данных кадра, байт
+	FrameTimeout   time.Duration `mapstructure:"frame_timeout"`   // Запас времени приема кадра сверх времени передачи на скорости порта
+	ReopenInterval time.Duration `mapstructure:"reopen_interval"` // Интервал повторного открытия порта после ошибки
+}
+
+// PortConfig возвращает параметры порта
+func (c SerialConfig) PortConfig() serialport.PortConfig {
+	return serialport.PortConfig{
+		Device:   c.Device,
+		BaudRate: c.BaudRate,
+		DataBits: c.DataBits,
+		Parity:   c.Parity,
+		StopBits: c.StopBits,
+		RS485:    c.RS485,
+	}
 }
 
 // LoggerConfig конфигурация логирования
@@ -61,17 +194,247 @@ type LoggerConfig struct {
 	MaxAge     int    `mapstructure:"max_age"` // days
 	Compress   bool   `mapstructure:"compress"`
 	Console    bool   `mapstructure:"console"`
+
+	Format        string `mapstructure:"format"`         // Формат файла: json, logfmt или console
+	FileLevel     string `mapstructure:"file_level"`     // Уровень файла (пусто - общий level)
+	ConsoleFormat string `mapstructure:"console_format"` // Формат консоли: console, json или logfmt
+	ConsoleLevel  string `mapstructure:"console_level"`  // Уровень консоли (пусто - общий level)
+
+	Syslog SyslogConfig `mapstructure:"syslog"`
+
+	Messages MessageFileConfig `mapstructure:"messages"` // Отдельный файл журнала сообщений (message_log)
+}
+
+// MessageFileConfig конфигурация отдельного файла журнала полученных сообщений (JSONL,
+// строка - models.LogEntry). Пустой file_path - запись в основной лог сервиса
+type MessageFileConfig struct {
+	FilePath   string `mapstructure:"file_path"`
+	MaxSize    int    `mapstructure:"max_size"` // megabytes
+	MaxBackups int    `mapstructure:"max_backups"`
+	MaxAge     int    `mapstructure:"max_age"` // days
+	Compress   bool   `mapstructure:"compress"`
+}
+
+// SyslogConfig конфигурация вывода логов на сервер syslog (RFC5424)
+type SyslogConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Network  string `mapstructure:"network"`  // udp, tcp, unix или unixgram (/dev/log для journald)
+	Address  string `mapstructure:"address"`  // host:port или путь к сокету
+	Facility string `mapstructure:"facility"` // Источник: user, daemon, local0-local7 и др.
+	AppName  string `mapstructure:"app_name"` // APP-NAME заголовка
+	Level    string `mapstructure:"level"`    // Уровень вывода (пусто - общий level)
+	Format   string `mapstructure:"format"`   // Формат текста сообщения: logfmt или json
+}
+
+// SinkConfig возвращает параметры вывода на сервер syslog
+func (c SyslogConfig) SinkConfig() logging.SyslogConfig {
+	return logging.SyslogConfig{
+		Network:  c.Network,
+		Address:  c.Address,
+		Facility: c.Facility,
+		AppName:  c.AppName,
+		Format:   c.Format,
+	}
 }
 
 // MetricsConfig конфигурация метрик
 type MetricsConfig struct {
-	Enabled bool   `mapstructure:"enabled"`
-	Path    string `mapstructure:"path"`
-	Port    int    `mapstructure:"port"`
+	Enabled           bool          `mapstructure:"enabled"`
+	Path              string        `mapstructure:"path"`
+	Host              string        `mapstructure:"host"` // Адрес прослушивания HTTP сервера (например, адрес в сети управления)
+	Port              int           `mapstructure:"port"`
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"` // Таймаут чтения заголовков запроса
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"` // Время простоя соединения keep-alive до закрытия
+	CertFile          string        `mapstructure:"cert_file"`    // Сертификат HTTPS (пусто - HTTP)
+	KeyFile           string        `mapstructure:"key_file"`     // Закрытый ключ сертификата HTTPS
+	Debug             bool          `mapstructure:"debug"`        // Обработчики профилирования /debug/pprof/
+}
+
+// ProcessingConfig параметры обработки принятых сообщений
+type ProcessingConfig struct {
+	MessageTTL        time.Duration `mapstructure:"message_ttl"`         // Срок актуальности сообщений без ttl_ms (0 - не проверять)
+	StatsFile         string        `mapstructure:"stats_file"`          // Файл счетчиков, накопленных между перезапусками (пусто - не накапливать)
+	StatsSaveInterval time.Duration `mapstructure:"stats_save_interval"` // Интервал сохранения счетчиков в файл (0 - только при остановке)
+	MonotonicLatency  bool          `mapstructure:"monotonic_latency"`   // Задержка по send_time монотонных часов (sender на том же хосте)
+}
+
+// ValidationConfig правила проверки записей payload
+type ValidationConfig struct {
+	Profile     string      `mapstructure:"profile"`      // Профиль проверки: off, checksum, schema, strict (пусто - по payload)
+	Payload     bool        `mapstructure:"payload"`      // Разбирать payload и проверять записи (false - только контрольная сумма)
+	IndicatorID RangeConfig `mapstructure:"indicator_id"` // Допустимый диапазон indicator_id
+	EquipmentID RangeConfig `mapstructure:"equipment_id"` // Допустимый диапазон equipment_id
+	ValueLength int         `mapstructure:"value_length"` // Длина indicator_value, символов
+
+	Binary binrec.Layout `mapstructure:"binary"` // Раскладка двоичных записей data.binary отправителя
+}
+
+// ValidationProfile возвращает профиль проверки; без profile он определяется
+// прежним параметром payload: strict при true, checksum при false
+func (c ValidationConfig) ValidationProfile() validator.Profile {
+	if c.Profile != "" {
+		return validator.Profile(c.Profile)
+	}
+	if c.Payload {
+		return validator.ProfileStrict
+	}
+	return validator.ProfileChecksum
+}
+
+// Rules возвращает правила проверки записей
+func (c ValidationConfig) Rules() validator.Rules {
+	rules := validator.Rules{
+		IndicatorMin: c.IndicatorID.Min,
+		IndicatorMax: c.IndicatorID.Max,
+		EquipmentMin: c.EquipmentID.Min,
+		EquipmentMax: c.EquipmentID.Max,
+		ValueLength:  c.ValueLength,
+	}
+	if c.Binary.Enabled() {
+		layout := c.Binary
+		rules.Binary = &layout
+	}
+	return rules
+}
+
+// RangeConfig диапазон допустимых значений [min, max]
+type RangeConfig struct {
+	Min int `mapstructure:"min"`
+	Max int `mapstructure:"max"`
+}
+
+// ClusterConfig параметры объединения статистики нескольких экземпляров recipient
+type ClusterConfig struct {
+	Peers   []string      `mapstructure:"peers"`   // Адреса HTTP API остальных экземпляров (http://host:port)
+	Timeout time.Duration `mapstructure:"timeout"` // Таймаут запроса к экземпляру
+	CAFile  string        `mapstructure:"ca_file"` // Сертификаты удостоверяющих центров для https (пусто - системные)
+}
+
+// StoreConfig конфигурация встроенного хранилища результатов (SQLite)
+type StoreConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`        // Сохранять результаты обработки в базу
+	Path          string        `mapstructure:"path"`           // Файл базы данных
+	QueueSize     int           `mapstructure:"queue_size"`     // Очередь записей, ожидающих сохранения
+	ValidMessages bool          `mapstructure:"valid_messages"` // Сохранять корректные сообщения (иначе только с ошибками и устаревшие)
+	Retention     time.Duration `mapstructure:"retention"`      // Срок хранения записей (0 - без ограничения)
+
+	BatchSize       int           `mapstructure:"batch_size"`       // Записей в одной транзакции
+	FlushInterval   time.Duration `mapstructure:"flush_interval"`   // Период сохранения неполного пакета
+	MaxPending      int           `mapstructure:"max_pending"`      // Предел несохраненных записей в памяти при ошибках базы
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"` // Время на сохранение оставшихся записей при остановке
+}
+
+// FilesConfig конфигурация приема файлов теста передачи файлов
+type FilesConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`       // Собирать файлы из принятых фрагментов
+	Directory   string `mapstructure:"directory"`     // Директория собранных файлов
+	MaxFileSize int    `mapstructure:"max_file_size"` // Предельный размер файла, megabytes
+}
+
+// AuditConfig конфигурация канала аудита: периодическая публикация сводок о принятых
+// сообщениях по обратному каналу стенда, по которым sender вычисляет потери во время теста
+type AuditConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`   // Публиковать сводки
+	Broker   string        `mapstructure:"broker"`    // Брокер обратного канала (пусто - mqtt.broker)
+	ClientID string        `mapstructure:"client_id"` // Идентификатор клиента (пусто - mqtt.client_id с суффиксом -audit)
+	Username string        `mapstructure:"username"`  // Имя пользователя (пусто - mqtt.username)
+	Password string        `mapstructure:"password"`  // Пароль (пусто - mqtt.password)
+	Topic    string        `mapstructure:"topic"`     // Топик сводок
+	QoS      byte          `mapstructure:"qos"`       // QoS публикации сводок
+	Interval time.Duration `mapstructure:"interval"`  // Интервал публикации
+	Window   time.Duration `mapstructure:"window"`    // Сводки публикуются по тестам с сообщениями за это время
+}
+
+// SlowConsumerConfig конфигурация контроля отставания обработки: пропускная способность
+// в скользящем окне, ее пиковые значения и событие отставания при превышении порогов
+type SlowConsumerConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`         // Контролировать отставание обработки
+	Window         time.Duration `mapstructure:"window"`          // Скользящее окно пропускной способности
+	MaxLag         time.Duration `mapstructure:"max_lag"`         // Предельная задержка от получения до конца обработки (0 - не проверять)
+	MaxQueueDepth  int64         `mapstructure:"max_queue_depth"` // Предельное число сообщений в обработке MQTT (0 - не проверять)
+	WebhookURL     string        `mapstructure:"webhook_url"`     // Адрес, на который отправляются события (пусто - не отправлять)
+	WebhookTimeout time.Duration `mapstructure:"webhook_timeout"` // Таймаут отправки события
 }
 
-// Load загружает конфигурацию из файла и переменных окружения
+// SLOConfig конфигурация целей по задержке доставки (SLO) и оповещений о скорости
+// расхода бюджета ошибок (burn rate)
+type SLOConfig struct {
+	Enabled        bool           `mapstructure:"enabled"`         // Оценивать цели
+	Objectives     []SLOObjective `mapstructure:"objectives"`      // Цели
+	WebhookURL     string         `mapstructure:"webhook_url"`     // Адрес, на который отправляются события (пусто - не отправлять)
+	WebhookTimeout time.Duration  `mapstructure:"webhook_timeout"` // Таймаут отправки события
+}
+
+// SLOObjective цель: доля target (%) сообщений с задержкой не больше latency за окно window
+type SLOObjective struct {
+	Name        string        `mapstructure:"name"`         // Имя цели в метриках и событиях
+	Latency     time.Duration `mapstructure:"latency"`      // Порог задержки от send_time до получения
+	Target      float64       `mapstructure:"target"`       // Доля сообщений в пределах порога, %
+	Window      time.Duration `mapstructure:"window"`       // Длинное окно оценки
+	ShortWindow time.Duration `mapstructure:"short_window"` // Короткое окно (по умолчанию window/12, не меньше 1s)
+	BurnRate    float64       `mapstructure:"burn_rate"`    // Порог скорости расхода бюджета ошибок (по умолчанию 1)
+}
+
+// ActionLogConfig журнал действий API (audit trail): сброс статистики, изменение профиля
+// проверки, повторная подписка, перечитывание конфигурации
+type ActionLogConfig struct {
+	File string `mapstructure:"file"` // Файл журнала, только дописывается (пусто - не ведется)
+}
+
+// ArchiveConfig конфигурация архива принятых кадров
+type ArchiveConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`       // Записывать ли принятые кадры в архив
+	Directory   string `mapstructure:"directory"`     // Директория файлов архива
+	MaxFileSize int    `mapstructure:"max_file_size"` // Размер файла до ротации, megabytes
+	MaxFiles    int    `mapstructure:"max_files"`     // Максимум хранимых файлов (0 - без ограничения)
+}
+
+// CorrelationConfig конфигурация журнала корреляции полученных сообщений
+// (test_id, message_id, sequence, время получения, контрольная сумма)
+type CorrelationConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`       // Записывать ли журнал
+	Directory   string `mapstructure:"directory"`     // Директория файлов журнала
+	MaxFileSize int    `mapstructure:"max_file_size"` // Размер файла до ротации, megabytes
+}
+
+// DemuxConfig конфигурация записи проверенных записей payload в файлы по оборудованию
+// (структура директории импорта исторической базы)
+type DemuxConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`         // Записывать ли записи в файлы оборудования
+	Directory      string        `mapstructure:"directory"`       // Корневая директория (файлы в <directory>/equipment-<id>)
+	Format         string        `mapstructure:"format"`          // Формат файлов: jsonl, csv
+	MaxFileSize    int           `mapstructure:"max_file_size"`   // Размер файла до ротации, megabytes
+	RotateInterval time.Duration `mapstructure:"rotate_interval"` // Время записи файла до ротации (0 - только по размеру)
+	MaxOpenFiles   int           `mapstructure:"max_open_files"`  // Одновременно открытых файлов
+	QueueSize      int           `mapstructure:"queue_size"`      // Очередь сообщений; при заполнении записи отбрасываются
+}
+
+// HealthProbeConfig конфигурация проверки получения пробных сообщений sender (/healthz)
+type HealthProbeConfig struct {
+	Enabled bool          `mapstructure:"enabled"` // Подписываться на пробы и проверять их получение
+	Topic   string        `mapstructure:"topic"`   // Топик проб (health_probe.topic sender), подписка с QoS mqtt.qos
+	MaxAge  time.Duration `mapstructure:"max_age"` // Срок, после которого без новой пробы /healthz неработоспособен
+}
+
+// MessageLogConfig конфигурация записи полученных сообщений в лог
+type MessageLogConfig struct {
+	Enabled     bool `mapstructure:"enabled"`      // Записывать ли каждое сообщение в лог
+	QueueSize   int  `mapstructure:"queue_size"`   // Очередь записей; при заполнении записи отбрасываются
+	SampleEvery int  `mapstructure:"sample_every"` // Записывать каждое N-е сообщение с верной контрольной суммой
+}
+
+// Load загружает конфигурацию из файла (с подключаемыми файлами include и подстановкой
+// ${ПЕРЕМЕННАЯ}) и переменных окружения
 func Load(configPath string) (*Config, error) {
+	config, _, err := load(configPath)
+	return config, err
+}
+
+// load читает конфигурацию и возвращает также прочитанные файлы (основной и подключаемые),
+// в том числе при ошибке, чтобы Watch наблюдал за ними
+func load(configPath string) (*Config, []string, error) {
 	v := viper.New()
 
 	// Устанавливаем значения по умолчанию
@@ -81,30 +444,38 @@ func Load(configPath string) (*Config, error) {
 	v.SetEnvPrefix("RECIPIENT")
 	v.AutomaticEnv()
 
-	// Если указан путь к конфигурации, читаем файл
+	// Если указан путь к конфигурации, читаем файл и подключаемые им файлы
+	var files []string
 	if configPath != "" {
-		v.SetConfigFile(configPath)
-		if err := v.ReadInConfig(); err != nil {
-			return nil, fmt.Errorf("ошибка чтения конфигурации: %w", err)
+		var err error
+		files, err = configwatch.ReadFiles(v, configPath)
+		if err != nil {
+			return nil, files, fmt.Errorf("ошибка чтения конфигурации: %w", err)
 		}
 	}
 
 	var config Config
 	if err := v.Unmarshal(&config); err != nil {
-		return nil, fmt.Errorf("ошибка парсинга конфигурации: %w", err)
+		return nil, files, fmt.Errorf("ошибка парсинга конфигурации: %w", err)
+	}
+
+	if config.Service.Instance == "" {
+		config.Service.Instance = defaultInstance()
 	}
+	config.Audit.applyMQTTDefaults(&config.MQTT)
+	config.SLO.applyDefaults()
 
 	// Валидация конфигурации
 	if err := validate(&config); err != nil {
-		return nil, fmt.Errorf("ошибка валидации конфигурации: %w", err)
+		return nil, files, fmt.Errorf("ошибка валидации конфигурации: %w", err)
 	}
 
 	// Создаем директории если не существуют
 	if err := ensureDirectories(&config); err != nil {
-		return nil, fmt.Errorf("ошибка создания директорий: %w", err)
+		return nil, files, fmt.Errorf("ошибка создания директорий: %w", err)
 	}
 
-	return &config, nil
+	return &config, files, nil
 }
 
 // setDefaults устанавливает значения по умолчанию
@@ -112,6 +483,7 @@ func setDefaults(v *viper.Viper) {
 	// Service
 	v.SetDefault("service.name", "recipient")
 	v.SetDefault("service.version", "1.0.0")
+	v.SetDefault("service.instance", "")
 
 	// MQTT
 	v.SetDefault("mqtt.broker", "tcp://localhost:1883")
@@ -128,6 +500,59 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("mqtt.order_matters", true)
 	v.SetDefault("mqtt.store_directory", "/tmp/mqtt-recipient-store")
 	v.SetDefault("mqtt.max_inflight", 100)
+	v.SetDefault("mqtt.will_topic", "")
+	v.SetDefault("mqtt.shared_group", "")
+
+	// TCP
+	v.SetDefault("tcp.read_timeout", "60s")
+	v.SetDefault("tcp.drain_timeout", "10s")
+	v.SetDefault("tcp.write_timeout", "10s")
+	v.SetDefault("tcp.keep_alive", true)
+	v.SetDefault("tcp.keep_alive_period", "30s")
+	v.SetDefault("tcp.ping_interval", "10s")
+	v.SetDefault("tcp.ping_timeout", "30s")
+	v.SetDefault("tcp.read_buffer_size", tcpframe.DefaultReadBufferSize)
+	v.SetDefault("tcp.recv_buffer", 0)
+	v.SetDefault("tcp.send_buffer", 0)
+	v.SetDefault("tcp.no_delay", true)
+	v.SetDefault("tcp.linger", -1)
+
+	// QUIC
+	v.SetDefault("quic.enabled", false)
+	v.SetDefault("quic.address", ":9443")
+	v.SetDefault("quic.cert_file", "")
+	v.SetDefault("quic.key_file", "")
+	v.SetDefault("quic.max_streams", 100)
+	v.SetDefault("quic.zero_rtt", true)
+	v.SetDefault("quic.keep_alive_period", "10s")
+	v.SetDefault("quic.idle_timeout", "30s")
+
+	// NATS
+	v.SetDefault("nats.enabled", false)
+	v.SetDefault("nats.url", "nats://localhost:4222")
+	v.SetDefault("nats.username", "")
+	v.SetDefault("nats.password", "")
+	v.SetDefault("nats.token", "")
+	v.SetDefault("nats.stream", "INFODIODE")
+	v.SetDefault("nats.subject", "test.messages")
+	v.SetDefault("nats.durable", "recipient")
+	v.SetDefault("nats.connect_timeout", "10s")
+	v.SetDefault("nats.ack_wait", "30s")
+	v.SetDefault("nats.fetch_batch", 100)
+	v.SetDefault("nats.fetch_timeout", "5s")
+	v.SetDefault("nats.reconnect_wait", "2s")
+
+	// Serial
+	v.SetDefault("serial.enabled", false)
+	v.SetDefault("serial.device", "/dev/ttyUSB0")
+	v.SetDefault("serial.baud_rate", 115200)
+	v.SetDefault("serial.data_bits", 8)
+	v.SetDefault("serial.parity", serialport.ParityNone)
+	v.SetDefault("serial.stop_bits", 1)
+	v.SetDefault("serial.rs485", false)
+	v.SetDefault("serial.max_frame_size", 1024*1024)
+	v.SetDefault("serial.frame_timeout", "1s")
+	v.SetDefault("serial.reopen_interval", "5s")
 
 	// Logger
 	v.SetDefault("logger.level", "info")
@@ -137,11 +562,156 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("logger.max_age", 30)
 	v.SetDefault("logger.compress", true)
 	v.SetDefault("logger.console", true)
+	v.SetDefault("logger.format", logging.FormatJSON)
+	v.SetDefault("logger.console_format", logging.FormatConsole)
+	v.SetDefault("logger.syslog.enabled", false)
+	v.SetDefault("logger.syslog.network", "udp")
+	v.SetDefault("logger.syslog.address", "localhost:514")
+	v.SetDefault("logger.syslog.facility", "local0")
+	v.SetDefault("logger.syslog.app_name", "recipient")
+	v.SetDefault("logger.syslog.format", logging.FormatLogfmt)
+	v.SetDefault("logger.messages.file_path", "")
+	v.SetDefault("logger.messages.max_size", 100)
+	v.SetDefault("logger.messages.max_backups", 5)
+	v.SetDefault("logger.messages.max_age", 30)
+	v.SetDefault("logger.messages.compress", true)
 
 	// Metrics
 	v.SetDefault("metrics.enabled", true)
 	v.SetDefault("metrics.path", "/metrics")
+	v.SetDefault("metrics.host", "0.0.0.0")
 	v.SetDefault("metrics.port", 8081)
+	v.SetDefault("metrics.read_timeout", "10s")
+	v.SetDefault("metrics.read_header_timeout", "5s")
+	v.SetDefault("metrics.write_timeout", "10s")
+	v.SetDefault("metrics.idle_timeout", "120s")
+	v.SetDefault("metrics.cert_file", "")
+	v.SetDefault("metrics.key_file", "")
+	v.SetDefault("metrics.debug", false)
+
+	// Processing
+	v.SetDefault("processing.message_ttl", "0s")
+	v.SetDefault("processing.stats_file", "")
+	v.SetDefault("processing.stats_save_interval", "1m")
+	v.SetDefault("processing.monotonic_latency", false)
+
+	// Validation
+	v.SetDefault("validation.profile", "")
+	v.SetDefault("validation.payload", true)
+	v.SetDefault("validation.indicator_id.min", 1)
+	v.SetDefault("validation.indicator_id.max", 1000)
+	v.SetDefault("validation.equipment_id.min", 1)
+	v.SetDefault("validation.equipment_id.max", 100)
+	v.SetDefault("validation.value_length", 15)
+	v.SetDefault("validation.binary.byte_order", "big")
+
+	// Cluster
+	v.SetDefault("cluster.peers", []string{})
+	v.SetDefault("cluster.timeout", "3s")
+	v.SetDefault("cluster.ca_file", "")
+
+	// Store
+	v.SetDefault("store.enabled", false)
+	v.SetDefault("store.path", "data/results.db")
+	v.SetDefault("store.queue_size", 10000)
+	v.SetDefault("store.valid_messages", true)
+	v.SetDefault("store.retention", "0s")
+	v.SetDefault("store.batch_size", 500)
+	v.SetDefault("store.flush_interval", "1s")
+	v.SetDefault("store.max_pending", 100000)
+	v.SetDefault("store.shutdown_timeout", "10s")
+
+	// Files
+	v.SetDefault("files.enabled", true)
+	v.SetDefault("files.directory", "received")
+	v.SetDefault("files.max_file_size", 4096)
+
+	// Demux
+	v.SetDefault("demux.enabled", false)
+	v.SetDefault("demux.directory", "historian")
+	v.SetDefault("demux.format", "jsonl")
+	v.SetDefault("demux.max_file_size", 16)
+	v.SetDefault("demux.rotate_interval", "1m")
+	v.SetDefault("demux.max_open_files", 256)
+	v.SetDefault("demux.queue_size", 10000)
+
+	// Archive
+	v.SetDefault("archive.enabled", false)
+	v.SetDefault("archive.directory", "archive")
+	v.SetDefault("archive.max_file_size", 100)
+	v.SetDefault("archive.max_files", 50)
+
+	// Correlation
+	v.SetDefault("correlation.enabled", false)
+	v.SetDefault("correlation.directory", "correlation")
+	v.SetDefault("correlation.max_file_size", 100)
+
+	// MessageLog
+	v.SetDefault("message_log.enabled", true)
+	v.SetDefault("message_log.queue_size", 10000)
+	v.SetDefault("message_log.sample_every", 1)
+
+	// Audit
+	v.SetDefault("audit.enabled", false)
+	v.SetDefault("audit.broker", "")
+	v.SetDefault("audit.client_id", "")
+	v.SetDefault("audit.username", "")
+	v.SetDefault("audit.password", "")
+	v.SetDefault("audit.topic", "test/audit")
+	v.SetDefault("audit.qos", 0)
+	v.SetDefault("audit.interval", "5s")
+	v.SetDefault("audit.window", "1m")
+
+	// Health probe
+	v.SetDefault("health_probe.enabled", false)
+	v.SetDefault("health_probe.topic", "test/health")
+	v.SetDefault("health_probe.max_age", "30s")
+
+	// Slow consumer
+	v.SetDefault("slow_consumer.enabled", true)
+	v.SetDefault("slow_consumer.window", "10s")
+	v.SetDefault("slow_consumer.max_lag", "2s")
+	v.SetDefault("slow_consumer.max_queue_depth", 0)
+	v.SetDefault("slow_consumer.webhook_url", "")
+	v.SetDefault("slow_consumer.webhook_timeout", "5s")
+
+	// SLO
+	v.SetDefault("slo.enabled", false)
+	v.SetDefault("slo.objectives", []map[string]any{})
+	v.SetDefault("slo.webhook_url", "")
+	v.SetDefault("slo.webhook_timeout", "5s")
+
+	// Журнал действий
+	v.SetDefault("action_log.file", "")
+}
+
+// applyDefaults заполняет незаданные короткое окно и порог скорости расхода целей
+func (c *SLOConfig) applyDefaults() {
+	for i := range c.Objectives {
+		objective := &c.Objectives[i]
+		if objective.ShortWindow == 0 {
+			objective.ShortWindow = max(objective.Window/12, time.Second).Truncate(time.Second)
+		}
+		if objective.BurnRate == 0 {
+			objective.BurnRate = 1
+		}
+	}
+}
+
+// applyMQTTDefaults заполняет незаданные параметры подключения канала аудита из раздела mqtt
+func (c *AuditConfig) applyMQTTDefaults(mqtt *MQTTConfig) {
+	if c.Broker == "" {
+		c.Broker = mqtt.Broker
+	}
+	if c.ClientID == "" {
+		c.ClientID = mqtt.ClientID + "-audit"
+	}
+	if c.Username == "" {
+		c.Username = mqtt.Username
+	}
+	if c.Password == "" {
+		c.Password = mqtt.Password
+	}
 }
 
 // validate проверяет корректность конфигурации
@@ -162,17 +732,379 @@ func validate(cfg *Config) error {
 		return fmt.Errorf("некорректный уровень QoS: %d (должен быть 0, 1 или 2)", cfg.MQTT.QoS)
 	}
 
-	if cfg.MQTT.MaxInflight <= 0 {
-		return fmt.Errorf("max_inflight должно быть больше 0")
+	if cfg.MQTT.MaxInflight < 0 {
+		return fmt.Errorf("некорректное значение max_inflight: %d", cfg.MQTT.MaxInflight)
+	}
+
+	if cfg.MQTT.WillTopic != "" && cfg.MQTT.WillTopic == cfg.MQTT.Topic {
+		return fmt.Errorf("mqtt.will_topic должен отличаться от mqtt.topic")
+	}
+
+	if strings.ContainsAny(cfg.MQTT.SharedGroup, "/+#") {
+		return fmt.Errorf("некорректное имя группы mqtt.shared_group: %s", cfg.MQTT.SharedGroup)
+	}
+
+	if cfg.NATS.Enabled {
+		if cfg.NATS.URL == "" {
+			return fmt.Errorf("не указан адрес NATS сервера")
+		}
+		if cfg.NATS.Stream == "" || cfg.NATS.Subject == "" || cfg.NATS.Durable == "" {
+			return fmt.Errorf("не указаны поток, субъект или durable consumer NATS")
+		}
+		if cfg.NATS.FetchBatch <= 0 {
+			return fmt.Errorf("некорректное значение nats.fetch_batch: %d", cfg.NATS.FetchBatch)
+		}
+		if cfg.NATS.FetchTimeout <= 0 {
+			return fmt.Errorf("некорректное значение nats.fetch_timeout: %s", cfg.NATS.FetchTimeout)
+		}
+	}
+
+	if cfg.TCP.Enabled {
+		if cfg.TCP.MaxConnections < 0 {
+			return fmt.Errorf("некорректное значение tcp.max_connections: %d", cfg.TCP.MaxConnections)
+		}
+		if _, err := cfg.TCP.Networks(); err != nil {
+			return err
+		}
+		if cfg.TCP.DrainTimeout < 0 {
+			return fmt.Errorf("tcp.drain_timeout не может быть отрицательным")
+		}
+		if cfg.TCP.PingInterval < 0 || cfg.TCP.PingTimeout < 0 {
+			return fmt.Errorf("tcp.ping_interval и tcp.ping_timeout не могут быть отрицательными")
+		}
+		if cfg.TCP.PingInterval > 0 && cfg.TCP.PingTimeout > 0 && cfg.TCP.PingTimeout <= cfg.TCP.PingInterval {
+			return fmt.Errorf("tcp.ping_timeout должен быть больше tcp.ping_interval")
+		}
+		if cfg.TCP.ReadBufferSize < 0 || cfg.TCP.RecvBuffer < 0 || cfg.TCP.SendBuffer < 0 {
+			return fmt.Errorf("tcp.read_buffer_size, tcp.recv_buffer и tcp.send_buffer не могут быть отрицательными")
+		}
+		if cfg.TCP.Linger < -1 {
+			return fmt.Errorf("некорректное значение tcp.linger: %d (-1 - по умолчанию ОС, 0 и больше - секунд)", cfg.TCP.Linger)
+		}
+	}
+
+	if cfg.QUIC.Enabled {
+		if cfg.QUIC.Address == "" {
+			return fmt.Errorf("не указан адрес QUIC сервера")
+		}
+		if (cfg.QUIC.CertFile == "") != (cfg.QUIC.KeyFile == "") {
+			return fmt.Errorf("quic.cert_file и quic.key_file задаются вместе")
+		}
+		if cfg.QUIC.MaxStreams < 1 {
+			return fmt.Errorf("некорректное значение quic.max_streams: %d", cfg.QUIC.MaxStreams)
+		}
+		if cfg.QUIC.KeepAlivePeriod < 0 || cfg.QUIC.IdleTimeout < 0 {
+			return fmt.Errorf("quic.keep_alive_period и quic.idle_timeout не могут быть отрицательными")
+		}
+	}
+
+	if cfg.Serial.Enabled {
+		if err := cfg.Serial.PortConfig().Validate(); err != nil {
+			return fmt.Errorf("serial: %w", err)
+		}
+		if cfg.Serial.MaxFrameSize <= 0 {
+			return fmt.Errorf("некорректное значение serial.max_frame_size: %d", cfg.Serial.MaxFrameSize)
+		}
+		if cfg.Serial.FrameTimeout <= 0 {
+			return fmt.Errorf("некорректное значение serial.frame_timeout: %s", cfg.Serial.FrameTimeout)
+		}
+		if cfg.Serial.ReopenInterval <= 0 {
+			return fmt.Errorf("некорректное значение serial.reopen_interval: %s", cfg.Serial.ReopenInterval)
+		}
+	}
+
+	if cfg.Processing.MessageTTL < 0 {
+		return fmt.Errorf("некорректное значение processing.message_ttl: %s", cfg.Processing.MessageTTL)
+	}
+	if cfg.Processing.StatsSaveInterval < 0 {
+		return fmt.Errorf("некорректное значение processing.stats_save_interval: %s", cfg.Processing.StatsSaveInterval)
+	}
+	if cfg.Processing.MonotonicLatency {
+		if _, err := utils.MonotonicNow(); err != nil {
+			return fmt.Errorf("processing.monotonic_latency недоступен: %w", err)
+		}
+	}
+
+	if cfg.Validation.Profile != "" {
+		if _, err := validator.ParseProfile(cfg.Validation.Profile); err != nil {
+			return fmt.Errorf("некорректное значение validation.profile: %w", err)
+		}
+	}
+	if r := cfg.Validation.IndicatorID; r.Min < 1 || r.Max < r.Min {
+		return fmt.Errorf("некорректный диапазон validation.indicator_id: [%d, %d]", r.Min, r.Max)
+	}
+	if r := cfg.Validation.EquipmentID; r.Min < 1 || r.Max < r.Min {
+		return fmt.Errorf("некорректный диапазон validation.equipment_id: [%d, %d]", r.Min, r.Max)
+	}
+	if cfg.Validation.ValueLength <= 0 {
+		return fmt.Errorf("некорректное значение validation.value_length: %d", cfg.Validation.ValueLength)
+	}
+	if err := cfg.Validation.Binary.Validate("validation.binary"); err != nil {
+		return err
+	}
+
+	for _, peer := range cfg.Cluster.Peers {
+		if u, err := url.Parse(peer); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("некорректный адрес экземпляра в cluster.peers: %s", peer)
+		}
+	}
+	if len(cfg.Cluster.Peers) > 0 && cfg.Cluster.Timeout <= 0 {
+		return fmt.Errorf("некорректное значение cluster.timeout: %s", cfg.Cluster.Timeout)
 	}
 
 	if cfg.Metrics.Port <= 0 || cfg.Metrics.Port > 65535 {
 		return fmt.Errorf("некорректный порт для метрик: %d", cfg.Metrics.Port)
 	}
+	if cfg.Metrics.ReadTimeout < 0 || cfg.Metrics.ReadHeaderTimeout < 0 || cfg.Metrics.WriteTimeout < 0 || cfg.Metrics.IdleTimeout < 0 {
+		return fmt.Errorf("таймауты HTTP сервера не могут быть отрицательными")
+	}
+	if (cfg.Metrics.CertFile == "") != (cfg.Metrics.KeyFile == "") {
+		return fmt.Errorf("metrics.cert_file и metrics.key_file задаются вместе")
+	}
+
+	if cfg.Store.Enabled {
+		if cfg.Store.Path == "" {
+			return fmt.Errorf("не указан файл хранилища результатов")
+		}
+		if cfg.Store.QueueSize <= 0 {
+			return fmt.Errorf("некорректное значение store.queue_size: %d", cfg.Store.QueueSize)
+		}
+		if cfg.Store.Retention < 0 {
+			return fmt.Errorf("некорректное значение store.retention: %s", cfg.Store.Retention)
+		}
+		if cfg.Store.BatchSize <= 0 {
+			return fmt.Errorf("некорректное значение store.batch_size: %d", cfg.Store.BatchSize)
+		}
+		if cfg.Store.FlushInterval <= 0 {
+			return fmt.Errorf("некорректное значение store.flush_interval: %s", cfg.Store.FlushInterval)
+		}
+		if cfg.Store.MaxPending < cfg.Store.BatchSize {
+			return fmt.Errorf("store.max_pending (%d) должен быть не меньше store.batch_size (%d)", cfg.Store.MaxPending, cfg.Store.BatchSize)
+		}
+		if cfg.Store.ShutdownTimeout < 0 {
+			return fmt.Errorf("некорректное значение store.shutdown_timeout: %s", cfg.Store.ShutdownTimeout)
+		}
+	}
+
+	if cfg.Files.Enabled {
+		if cfg.Files.Directory == "" {
+			return fmt.Errorf("не указана директория принятых файлов")
+		}
+		if cfg.Files.MaxFileSize <= 0 {
+			return fmt.Errorf("некорректное значение files.max_file_size: %d", cfg.Files.MaxFileSize)
+		}
+	}
+
+	if cfg.Demux.Enabled {
+		if cfg.Demux.Directory == "" {
+			return fmt.Errorf("не указана директория файлов оборудования")
+		}
+		if cfg.Demux.Format != "jsonl" && cfg.Demux.Format != "csv" {
+			return fmt.Errorf("некорректное значение demux.format: %s (допустимо jsonl, csv)", cfg.Demux.Format)
+		}
+		if cfg.Demux.MaxFileSize <= 0 {
+			return fmt.Errorf("некорректное значение demux.max_file_size: %d", cfg.Demux.MaxFileSize)
+		}
+		if cfg.Demux.RotateInterval < 0 {
+			return fmt.Errorf("некорректное значение demux.rotate_interval: %s", cfg.Demux.RotateInterval)
+		}
+		if cfg.Demux.MaxOpenFiles <= 0 {
+			return fmt.Errorf("некорректное значение demux.max_open_files: %d", cfg.Demux.MaxOpenFiles)
+		}
+		if cfg.Demux.QueueSize <= 0 {
+			return fmt.Errorf("некорректное значение demux.queue_size: %d", cfg.Demux.QueueSize)
+		}
+	}
+
+	if cfg.Archive.Enabled {
+		if cfg.Archive.Directory == "" {
+			return fmt.Errorf("не указана директория архива")
+		}
+		if cfg.Archive.MaxFileSize <= 0 {
+			return fmt.Errorf("некорректное значение archive.max_file_size: %d", cfg.Archive.MaxFileSize)
+		}
+		if cfg.Archive.MaxFiles < 0 {
+			return fmt.Errorf("некорректное значение archive.max_files: %d", cfg.Archive.MaxFiles)
+		}
+	}
+
+	if cfg.Correlation.Enabled {
+		if cfg.Correlation.Directory == "" {
+			return fmt.Errorf("не указана директория журнала корреляции")
+		}
+		if cfg.Correlation.MaxFileSize <= 0 {
+			return fmt.Errorf("некорректное значение correlation.max_file_size: %d", cfg.Correlation.MaxFileSize)
+		}
+	}
 
+	if cfg.MessageLog.Enabled {
+		if cfg.MessageLog.QueueSize <= 0 {
+			return fmt.Errorf("некорректное значение message_log.queue_size: %d", cfg.MessageLog.QueueSize)
+		}
+		if cfg.MessageLog.SampleEvery < 1 {
+			return fmt.Errorf("некорректное значение message_log.sample_every: %d (должно быть не меньше 1)", cfg.MessageLog.SampleEvery)
+		}
+	}
+
+	if cfg.Audit.Enabled {
+		if cfg.Audit.Topic == "" {
+			return fmt.Errorf("не указан топик канала аудита")
+		}
+		if cfg.Audit.Topic == cfg.MQTT.Topic || cfg.Audit.Topic == cfg.MQTT.WillTopic {
+			return fmt.Errorf("audit.topic должен отличаться от mqtt.topic и mqtt.will_topic")
+		}
+		if cfg.Audit.QoS > 2 {
+			return fmt.Errorf("некорректный уровень QoS канала аудита: %d (должен быть 0, 1 или 2)", cfg.Audit.QoS)
+		}
+		if cfg.Audit.Interval <= 0 {
+			return fmt.Errorf("некорректное значение audit.interval: %s", cfg.Audit.Interval)
+		}
+		if cfg.Audit.Window < cfg.Audit.Interval {
+			return fmt.Errorf("audit.window должен быть не меньше audit.interval")
+		}
+	}
+
+	if cfg.HealthProbe.Enabled {
+		if cfg.HealthProbe.Topic == "" {
+			return fmt.Errorf("не указан топик пробных сообщений")
+		}
+		if cfg.HealthProbe.Topic == cfg.MQTT.Topic || cfg.HealthProbe.Topic == cfg.MQTT.WillTopic || cfg.HealthProbe.Topic == cfg.Audit.Topic {
+			return fmt.Errorf("health_probe.topic должен отличаться от mqtt.topic, mqtt.will_topic и audit.topic")
+		}
+		if cfg.HealthProbe.MaxAge <= 0 {
+			return fmt.Errorf("некорректное значение health_probe.max_age: %s", cfg.HealthProbe.MaxAge)
+		}
+	}
+
+	if cfg.SlowConsumer.Enabled {
+		if cfg.SlowConsumer.Window < time.Second {
+			return fmt.Errorf("slow_consumer.window должен быть не меньше 1s: %s", cfg.SlowConsumer.Window)
+		}
+		if cfg.SlowConsumer.MaxLag < 0 {
+			return fmt.Errorf("некорректное значение slow_consumer.max_lag: %s", cfg.SlowConsumer.MaxLag)
+		}
+		if cfg.SlowConsumer.MaxQueueDepth < 0 {
+			return fmt.Errorf("некорректное значение slow_consumer.max_queue_depth: %d", cfg.SlowConsumer.MaxQueueDepth)
+		}
+		if cfg.SlowConsumer.WebhookURL != "" {
+			if u, err := url.Parse(cfg.SlowConsumer.WebhookURL); err != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("некорректный адрес slow_consumer.webhook_url: %s", cfg.SlowConsumer.WebhookURL)
+			}
+			if cfg.SlowConsumer.WebhookTimeout <= 0 {
+				return fmt.Errorf("некорректное значение slow_consumer.webhook_timeout: %s", cfg.SlowConsumer.WebhookTimeout)
+			}
+		}
+	}
+
+	if cfg.SLO.Enabled {
+		if err := validateSLO(&cfg.SLO); err != nil {
+			return err
+		}
+	}
+
+	if err := validateLogger(&cfg.Logger); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateSLO проверяет цели по задержке
+func validateSLO(cfg *SLOConfig) error {
+	if len(cfg.Objectives) == 0 {
+		return fmt.Errorf("не заданы цели slo.objectives")
+	}
+
+	names := make(map[string]bool, len(cfg.Objectives))
+	for i, objective := range cfg.Objectives {
+		if !sloNamePattern.MatchString(objective.Name) {
+			return fmt.Errorf("некорректное имя slo.objectives[%d].name: %q (допустимы a-z, 0-9, _ и -)", i, objective.Name)
+		}
+		if names[objective.Name] {
+			return fmt.Errorf("повторяющееся имя цели slo.objectives: %s", objective.Name)
+		}
+		names[objective.Name] = true
+
+		if objective.Latency <= 0 {
+			return fmt.Errorf("некорректное значение slo.objectives[%s].latency: %s", objective.Name, objective.Latency)
+		}
+		if objective.Target <= 0 || objective.Target >= 100 {
+			return fmt.Errorf("slo.objectives[%s].target должен быть больше 0 и меньше 100: %g", objective.Name, objective.Target)
+		}
+		if objective.Window < 10*time.Second || objective.Window > 24*time.Hour {
+			return fmt.Errorf("slo.objectives[%s].window должен быть от 10s до 24h: %s", objective.Name, objective.Window)
+		}
+		if objective.ShortWindow < time.Second || objective.ShortWindow >= objective.Window {
+			return fmt.Errorf("slo.objectives[%s].short_window должен быть не меньше 1s и меньше window: %s", objective.Name, objective.ShortWindow)
+		}
+		if objective.BurnRate <= 0 {
+			return fmt.Errorf("некорректное значение slo.objectives[%s].burn_rate: %g", objective.Name, objective.BurnRate)
+		}
+	}
+
+	if cfg.WebhookURL != "" {
+		if u, err := url.Parse(cfg.WebhookURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("некорректный адрес slo.webhook_url: %s", cfg.WebhookURL)
+		}
+		if cfg.WebhookTimeout <= 0 {
+			return fmt.Errorf("некорректное значение slo.webhook_timeout: %s", cfg.WebhookTimeout)
+		}
+	}
+	return nil
+}
+
+// validateLogger проверяет форматы и уровни выводов логов
+func validateLogger(cfg *LoggerConfig) error {
+	if _, err := logging.ParseLevel(cfg.Level); err != nil {
+		return fmt.Errorf("некорректное значение logger.level: %w", err)
+	}
+	if err := logging.ValidateFormat(cfg.Format); err != nil {
+		return fmt.Errorf("некорректное значение logger.format: %w", err)
+	}
+	if err := logging.ValidateFormat(cfg.ConsoleFormat); err != nil {
+		return fmt.Errorf("некорректное значение logger.console_format: %w", err)
+	}
+
+	// Уровни отдельных выводов необязательны: пустой означает общий logger.level
+	levels := []struct{ key, level string }{
+		{"logger.file_level", cfg.FileLevel},
+		{"logger.console_level", cfg.ConsoleLevel},
+		{"logger.syslog.level", cfg.Syslog.Level},
+	}
+	for _, sink := range levels {
+		if sink.level == "" {
+			continue
+		}
+		if _, err := logging.ParseLevel(sink.level); err != nil {
+			return fmt.Errorf("некорректное значение %s: %w", sink.key, err)
+		}
+	}
+
+	if cfg.Syslog.Enabled {
+		if err := logging.ValidateSyslog(cfg.Syslog.SinkConfig()); err != nil {
+			return fmt.Errorf("некорректная конфигурация logger.syslog: %w", err)
+		}
+	}
+
+	// Две ротации одного файла мешают друг другу
+	if cfg.Messages.FilePath != "" && filepath.Clean(cfg.Messages.FilePath) == filepath.Clean(cfg.FilePath) {
+		return fmt.Errorf("logger.messages.file_path совпадает с logger.file_path: %s", cfg.FilePath)
+	}
+	if cfg.Messages.MaxSize < 0 || cfg.Messages.MaxBackups < 0 || cfg.Messages.MaxAge < 0 {
+		return fmt.Errorf("некорректные параметры ротации logger.messages: значения не могут быть отрицательными")
+	}
 	return nil
 }
 
+// defaultInstance возвращает имя экземпляра по умолчанию - имя хоста
+func defaultInstance() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "recipient"
+	}
+	return hostname
+}
+
 // ensureDirectories создает необходимые директории
 func ensureDirectories(cfg *Config) error {
 	// Создаем директорию для логов
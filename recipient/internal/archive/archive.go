@@ -0,0 +1,385 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Формат файла архива: сигнатура fileMagic, затем записи вида
+// [источник 1 байт][вид 1 байт][время получения, нс Unix 8 байт][длина 4 байта][данные].
+// Числа записываются в big-endian
+const (
+	fileMagic      = "INFOARC1"
+	fileGlob       = "frames-*.arc"
+	recordHeadSize = 14
+	maxRecordSize  = 100 * 1024 * 1024
+
+	flushInterval = time.Second
+	errorLogEvery = time.Minute
+)
+
+// Source канал, по которому получен кадр
+type Source byte
+
+const (
+	SourceMQTT   Source = 1 // Сообщение MQTT
+	SourceTCP    Source = 2 // Кадр TCP
+	SourceNATS   Source = 3 // Сообщение NATS JetStream
+	SourceSerial Source = 4 // Кадр последовательного порта
+	SourceQUIC   Source = 5 // Кадр потока QUIC
+)
+
+// String возвращает название канала
+func (s Source) String() string {
+	switch s {
+	case SourceMQTT:
+		return "mqtt"
+	case SourceTCP:
+		return "tcp"
+	case SourceNATS:
+		return "nats"
+	case SourceSerial:
+		return "serial"
+	case SourceQUIC:
+		return "quic"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(s))
+	}
+}
+
+// Kind вид содержимого кадра
+type Kind byte
+
+const (
+	KindMessage Kind = 1 // Одно сообщение models.Message
+	KindBatch   Kind = 2 // Пакет models.MessageBatch
+)
+
+// Record кадр архива
+type Record struct {
+	Source     Source
+	Kind       Kind
+	ReceivedAt time.Time
+	Data       []byte
+}
+
+// Config конфигурация архива
+type Config struct {
+	Directory   string // Директория файлов архива
+	MaxFileSize int64  // Размер файла в байтах, после которого начинается новый файл
+	MaxFiles    int    // Максимум хранимых файлов, старые удаляются (0 - без ограничения)
+}
+
+// Stats статистика записи архива
+type Stats struct {
+	CurrentFile    string `json:"current_file"`
+	FilesCreated   int64  `json:"files_created"`
+	RecordsWritten int64  `json:"records_written"`
+	BytesWritten   int64  `json:"bytes_written"`
+	Errors         int64  `json:"errors"`
+}
+
+// Writer записывает принятые кадры в файлы архива с ротацией по размеру.
+// Методы nil *Writer ничего не делают, поэтому архив можно не проверять на nil
+type Writer struct {
+	config   Config
+	logger   *zap.Logger
+	mu       sync.Mutex
+	file     *os.File
+	buf      *bufio.Writer
+	size     int64
+	seq      int
+	stats    Stats
+	lastLog  time.Time
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewWriter создает архив и открывает первый файл
+func NewWriter(cfg Config, logger *zap.Logger) (*Writer, error) {
+	if cfg.Directory == "" {
+		return nil, fmt.Errorf("не указана директория архива")
+	}
+	if cfg.MaxFileSize <= 0 {
+		return nil, fmt.Errorf("некорректный размер файла архива: %d", cfg.MaxFileSize)
+	}
+	if err := os.MkdirAll(cfg.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию архива: %w", err)
+	}
+
+	w := &Writer{
+		config:   cfg,
+		logger:   logger,
+		stopChan: make(chan struct{}),
+	}
+
+	w.mu.Lock()
+	err := w.rotate()
+	w.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	go w.flushLoop()
+
+	return w, nil
+}
+
+// Write добавляет кадр в архив. Ошибки записи не прерывают прием данных:
+// они учитываются в статистике и периодически пишутся в лог
+func (w *Writer) Write(source Source, kind Kind, receivedAt time.Time, data []byte) {
+	if w == nil {
+		return
+	}
+
+	var head [recordHeadSize]byte
+	head[0] = byte(source)
+	head[1] = byte(kind)
+	binary.BigEndian.PutUint64(head[2:10], uint64(receivedAt.UnixNano()))
+	binary.BigEndian.PutUint32(head[10:14], uint32(len(data)))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buf == nil {
+		return // архив закрыт
+	}
+
+	recordSize := int64(recordHeadSize + len(data))
+	if w.size > int64(len(fileMagic)) && w.size+recordSize > w.config.MaxFileSize {
+		if err := w.rotate(); err != nil {
+			w.recordError(err)
+			return
+		}
+	}
+
+	if _, err := w.buf.Write(head[:]); err != nil {
+		w.recordError(err)
+		return
+	}
+	if _, err := w.buf.Write(data); err != nil {
+		w.recordError(err)
+		return
+	}
+
+	w.size += recordSize
+	w.stats.RecordsWritten++
+	w.stats.BytesWritten += recordSize
+}
+
+// rotate закрывает текущий файл и открывает новый (вызывается под w.mu)
+func (w *Writer) rotate() error {
+	if err := w.closeFile(); err != nil {
+		w.recordError(err)
+	}
+
+	w.seq++
+	name := fmt.Sprintf("frames-%s-%04d.arc", time.Now().Format("20060102-150405"), w.seq)
+	path := filepath.Join(w.config.Directory, name)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла архива: %w", err)
+	}
+
+	w.file = file
+	w.buf = bufio.NewWriterSize(file, 256*1024)
+	if _, err := w.buf.WriteString(fileMagic); err != nil {
+		return fmt.Errorf("ошибка записи файла архива: %w", err)
+	}
+	w.size = int64(len(fileMagic))
+	w.stats.CurrentFile = path
+	w.stats.FilesCreated++
+
+	w.logger.Info("Открыт файл архива кадров", zap.String("file", path))
+
+	w.removeOldFiles()
+	return nil
+}
+
+// closeFile сбрасывает буфер и закрывает текущий файл (вызывается под w.mu)
+func (w *Writer) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+
+	flushErr := w.buf.Flush()
+	closeErr := w.file.Close()
+	w.file = nil
+	w.buf = nil
+
+	return errors.Join(flushErr, closeErr)
+}
+
+// removeOldFiles удаляет самые старые файлы сверх max_files (вызывается под w.mu)
+func (w *Writer) removeOldFiles() {
+	if w.config.MaxFiles <= 0 {
+		return
+	}
+
+	files, err := filepath.Glob(filepath.Join(w.config.Directory, fileGlob))
+	if err != nil {
+		return
+	}
+	sort.Strings(files)
+
+	for len(files) > w.config.MaxFiles {
+		if err := os.Remove(files[0]); err != nil {
+			w.recordError(err)
+		} else {
+			w.logger.Info("Удален старый файл архива кадров", zap.String("file", files[0]))
+		}
+		files = files[1:]
+	}
+}
+
+// recordError учитывает ошибку и пишет ее в лог не чаще раза в минуту (вызывается под w.mu)
+func (w *Writer) recordError(err error) {
+	w.stats.Errors++
+	if time.Since(w.lastLog) >= errorLogEvery {
+		w.lastLog = time.Now()
+		w.logger.Error("Ошибка записи архива кадров",
+			zap.Int64("errors", w.stats.Errors),
+			zap.Error(err))
+	}
+}
+
+// flushLoop периодически сбрасывает буфер на диск
+func (w *Writer) flushLoop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.buf != nil {
+				if err := w.buf.Flush(); err != nil {
+					w.recordError(err)
+				}
+			}
+			w.mu.Unlock()
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+// Stats возвращает статистику записи архива
+func (w *Writer) Stats() Stats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
+
+// Close сбрасывает буфер и закрывает файл архива
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+
+	close(w.stopChan)
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeFile()
+}
+
+// Files возвращает файлы архива по пути к файлу или директории в порядке записи
+func Files(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, fileGlob))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Replay последовательно читает кадры всех файлов архива и передает их в handler.
+// Обрезанная последняя запись файла (например, после аварийного завершения) пропускается
+func Replay(path string, handler func(*Record) error) error {
+	files, err := Files(path)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("файлы архива не найдены: %s", path)
+	}
+
+	for _, file := range files {
+		if err := ReadFile(file, handler); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// ReadFile читает кадры одного файла архива и передает их в handler
+func ReadFile(path string, handler func(*Record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 256*1024)
+
+	magic := make([]byte, len(fileMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != fileMagic {
+		return fmt.Errorf("файл не является архивом кадров")
+	}
+
+	var head [recordHeadSize]byte
+	for {
+		if _, err := io.ReadFull(reader, head[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+
+		length := binary.BigEndian.Uint32(head[10:14])
+		if length > maxRecordSize {
+			return fmt.Errorf("некорректная длина записи архива: %d", length)
+		}
+
+		record := &Record{
+			Source:     Source(head[0]),
+			Kind:       Kind(head[1]),
+			ReceivedAt: time.Unix(0, int64(binary.BigEndian.Uint64(head[2:10]))),
+			Data:       make([]byte, length),
+		}
+		if _, err := io.ReadFull(reader, record.Data); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+
+		if err := handler(record); err != nil {
+			return err
+		}
+	}
+}
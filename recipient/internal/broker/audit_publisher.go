@@ -0,0 +1,170 @@
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/infodiode/recipient/config"
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// AuditSource возвращает сводки по тестам, сообщения которых получены не раньше since
+type AuditSource func(since time.Time) []models.AuditTestDigest
+
+// AuditPublisher периодически публикует сводки о принятых сообщениях в канал аудита.
+// Соединение с брокером обратного канала устанавливается в фоне и не влияет на прием
+type AuditPublisher struct {
+	client      mqtt.Client
+	config      *config.AuditConfig
+	instance    string
+	logger      *zap.Logger
+	source      AuditSource
+	published   atomic.Int64
+	skipped     atomic.Int64 // Сводок, не отправленных без соединения
+	errors      atomic.Int64
+	lastPublish atomic.Value // time.Time
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
+}
+
+// AuditStats статистика канала аудита
+type AuditStats struct {
+	Connected   bool      `json:"connected"`
+	Topic       string    `json:"topic"`
+	Published   int64     `json:"published"`
+	Skipped     int64     `json:"skipped"`
+	Errors      int64     `json:"errors"`
+	LastPublish time.Time `json:"last_publish,omitzero"`
+}
+
+// NewAuditPublisher создает публикацию сводок экземпляра instance по данным source
+func NewAuditPublisher(cfg *config.AuditConfig, instance string, logger *zap.Logger, source AuditSource) *AuditPublisher {
+	p := &AuditPublisher{
+		config:   cfg,
+		instance: instance,
+		logger:   logger.With(zap.String("component", "audit")),
+		source:   source,
+		stopChan: make(chan struct{}),
+	}
+
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(cfg.Broker)
+	opts.SetClientID(cfg.ClientID)
+	if cfg.Username != "" {
+		opts.SetUsername(cfg.Username)
+	}
+	if cfg.Password != "" {
+		opts.SetPassword(cfg.Password)
+	}
+
+	// Сводки периодические: пропущенная заменяется следующей, сессия не сохраняется
+	opts.SetCleanSession(true)
+	opts.SetAutoReconnect(true)
+	opts.SetConnectRetry(true)
+	opts.SetOnConnectHandler(func(mqtt.Client) {
+		p.logger.Info("Подключение канала аудита установлено", zap.String("broker", cfg.Broker))
+	})
+	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		p.logger.Warn("Потеря соединения канала аудита", zap.Error(err))
+	})
+
+	p.client = mqtt.NewClient(opts)
+	return p
+}
+
+// Start начинает подключение к брокеру и периодическую публикацию сводок
+func (p *AuditPublisher) Start() {
+	p.logger.Info("Запуск канала аудита",
+		zap.String("broker", p.config.Broker),
+		zap.String("topic", p.config.Topic),
+		zap.Duration("interval", p.config.Interval))
+
+	// При недоступном брокере подключение повторяется в фоне
+	p.client.Connect()
+
+	p.wg.Add(1)
+	go p.run()
+}
+
+// run публикует сводки с интервалом audit.interval
+func (p *AuditPublisher) run() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case now := <-ticker.C:
+			if err := p.publish(now); err != nil {
+				p.errors.Add(1)
+				p.logger.Debug("Ошибка публикации сводки", zap.Error(err))
+			}
+		}
+	}
+}
+
+// publish публикует сводку по тестам, сообщения которых получены за audit.window;
+// без недавних тестов сводка не публикуется, чтобы не занимать обратный канал
+func (p *AuditPublisher) publish(now time.Time) error {
+	tests := p.source(now.Add(-p.config.Window))
+	if len(tests) == 0 {
+		return nil
+	}
+	if !p.client.IsConnected() {
+		p.skipped.Add(1)
+		return nil
+	}
+
+	data, err := json.Marshal(models.AuditDigest{
+		Instance: p.instance,
+		Time:     now,
+		Tests:    tests,
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации сводки: %w", err)
+	}
+
+	token := p.client.Publish(p.config.Topic, p.config.QoS, false, data)
+	if !token.WaitTimeout(p.config.Interval) {
+		return fmt.Errorf("таймаут публикации сводки")
+	}
+	if err := token.Error(); err != nil {
+		return err
+	}
+
+	p.published.Add(1)
+	p.lastPublish.Store(now)
+	return nil
+}
+
+// Stats возвращает статистику канала аудита
+func (p *AuditPublisher) Stats() AuditStats {
+	last, _ := p.lastPublish.Load().(time.Time)
+	return AuditStats{
+		Connected:   p.client.IsConnected(),
+		Topic:       p.config.Topic,
+		Published:   p.published.Load(),
+		Skipped:     p.skipped.Load(),
+		Errors:      p.errors.Load(),
+		LastPublish: last,
+	}
+}
+
+// Close публикует последнюю сводку и отключается от брокера
+func (p *AuditPublisher) Close() {
+	close(p.stopChan)
+	p.wg.Wait()
+
+	if err := p.publish(time.Now()); err != nil {
+		p.logger.Warn("Ошибка публикации последней сводки", zap.Error(err))
+	}
+	p.client.Disconnect(1000)
+}
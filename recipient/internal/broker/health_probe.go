@@ -0,0 +1,138 @@
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/infodiode/recipient/config"
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// ProbeMonitor учитывает пробные сообщения sender (models.HealthProbe) из топика
+// health_probe.topic, полученные MQTT consumer. Пробы проходят тот же путь, что и
+// сообщения тестов (sender, брокер, диод, брокер recipient), поэтому свежая проба
+// означает работоспособность всего пути, а не только подключение клиента к брокеру.
+// Пробы не передаются в обработку и не учитываются в статистике приема
+type ProbeMonitor struct {
+	config *config.HealthProbeConfig
+	logger *zap.Logger
+
+	mu           sync.RWMutex
+	received     int64
+	invalid      int64
+	missed       int64            // Пропущенных номеров проб
+	sequences    map[string]int64 // Последний номер пробы по отправителям
+	last         models.HealthProbe
+	lastReceived time.Time
+	lastLatency  time.Duration
+}
+
+// ProbeStats статистика пробных сообщений
+type ProbeStats struct {
+	Topic        string     `json:"topic"`
+	Received     int64      `json:"received"`
+	Invalid      int64      `json:"invalid"`
+	Missed       int64      `json:"missed"`                  // Пропущенных номеров проб (потери на пути)
+	Senders      int        `json:"senders"`                 // Отправителей, пробы которых получены
+	LastSender   string     `json:"last_sender,omitempty"`   // Отправитель последней пробы
+	LastSequence int64      `json:"last_sequence"`           // Номер последней пробы
+	LastReceived *time.Time `json:"last_received,omitempty"` // Время получения последней пробы
+	LatencyMs    float64    `json:"latency_ms"`              // Задержка последней пробы от публикации sender
+	AgeSeconds   float64    `json:"age_seconds,omitempty"`   // Время с получения последней пробы
+}
+
+// NewProbeMonitor создает учет пробных сообщений
+func NewProbeMonitor(cfg *config.HealthProbeConfig, logger *zap.Logger) *ProbeMonitor {
+	return &ProbeMonitor{
+		config:    cfg,
+		logger:    logger.With(zap.String("component", "health_probe")),
+		sequences: make(map[string]int64),
+	}
+}
+
+// Topic возвращает топик проб; пусто для nil *ProbeMonitor
+func (m *ProbeMonitor) Topic() string {
+	if m == nil {
+		return ""
+	}
+	return m.config.Topic
+}
+
+// observe учитывает пробу, полученную в receivedAt. Номер меньше или равный
+// предыдущему означает перезапуск sender или повторную доставку и не считается пропуском
+func (m *ProbeMonitor) observe(payload []byte, receivedAt time.Time) {
+	var probe models.HealthProbe
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		m.mu.Lock()
+		m.invalid++
+		m.mu.Unlock()
+		m.logger.Debug("Некорректное пробное сообщение", zap.Error(err))
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if previous, ok := m.sequences[probe.Instance]; ok && probe.Sequence > previous+1 {
+		m.missed += probe.Sequence - previous - 1
+	}
+	m.sequences[probe.Instance] = probe.Sequence
+	m.received++
+	m.last = probe
+	m.lastReceived = receivedAt
+	m.lastLatency = receivedAt.Sub(probe.SendTime)
+}
+
+// Check возвращает проверку /healthz: проба должна быть получена не раньше
+// health_probe.max_age назад
+func (m *ProbeMonitor) Check() models.Check {
+	check := models.Check{
+		Component: "mqtt_probe",
+		Status:    "healthy",
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.lastReceived.IsZero() {
+		check.Status = "unhealthy"
+		check.Message = fmt.Sprintf("no probe received on %s", m.config.Topic)
+		return check
+	}
+
+	age := time.Since(m.lastReceived)
+	check.Message = fmt.Sprintf("Topic: %s, last probe #%d from %s received %s ago, latency %s, missed %d",
+		m.config.Topic, m.last.Sequence, m.last.Instance, age.Round(time.Millisecond),
+		m.lastLatency.Round(time.Microsecond), m.missed)
+	if age > m.config.MaxAge {
+		check.Status = "unhealthy"
+		check.Message += fmt.Sprintf(", older than %s", m.config.MaxAge)
+	}
+	return check
+}
+
+// Stats возвращает статистику пробных сообщений
+func (m *ProbeMonitor) Stats() ProbeStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := ProbeStats{
+		Topic:        m.config.Topic,
+		Received:     m.received,
+		Invalid:      m.invalid,
+		Missed:       m.missed,
+		Senders:      len(m.sequences),
+		LastSender:   m.last.Instance,
+		LastSequence: m.last.Sequence,
+		LatencyMs:    float64(m.lastLatency.Microseconds()) / 1000,
+	}
+	if !m.lastReceived.IsZero() {
+		lastReceived := m.lastReceived
+		stats.LastReceived = &lastReceived
+		stats.AgeSeconds = time.Since(lastReceived).Seconds()
+	}
+	return stats
+}
@@ -1,7 +1,6 @@
 package broker
 
 import (
-	"encoding/json"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -9,6 +8,7 @@ import (
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/infodiode/recipient/config"
+	"github.com/infodiode/recipient/internal/archive"
 	"github.com/infodiode/shared/models"
 	"go.uber.org/zap"
 )
@@ -19,22 +19,60 @@ type MQTTConsumer struct {
 	config          *config.MQTTConfig
 	logger          *zap.Logger
 	connected       atomic.Bool
+	subscribed      atomic.Bool  // Подписка на все топики выполнена в текущем подключении
+	subscribeGen    atomic.Int64 // Поколение подписки: меняется при подключении, потере связи и остановке
+	subscribeErrors atomic.Int64
+	subscribeErr    string       // Последняя ошибка подписки (под mu)
+	grantedQoS      atomic.Int32 // QoS, предоставленный брокером подписке на основной топик
 	messageCounter  atomic.Int64
 	errorCounter    atomic.Int64
 	bytesCounter    atomic.Int64
 	reconnectCount  atomic.Int32
 	lastConnectTime time.Time
 	messageHandler  MessageHandler
+	archive         *archive.Writer // Архив принятых кадров, nil если отключен
+	probe           *ProbeMonitor   // Учет пробных сообщений sender, nil если отключен
+	inflightMu      sync.Mutex
+	inflightCond    *sync.Cond
+	inflightCount   int64 // Сообщений в обработке (под inflightMu)
+	inflightLimit   int   // Размер окна обработки, 0 - без ограничения (под inflightMu)
+	throttledCount  atomic.Int64
+	retainedCount   atomic.Int64 // Сохраненных брокером сообщений, доставленных при подписке
+	duplicateCount  atomic.Int64 // Повторных доставок с флагом DUP
+	willCount       atomic.Int64 // Сообщений last will из mqtt.will_topic
+	batchCount      atomic.Int64 // Пакетов сообщений MessageBatch (mqtt.aggregate_batches sender)
+	lagObserver     atomic.Pointer[func(time.Duration)]
+	decodeObserver  atomic.Pointer[func(time.Duration)]
+	orderObserver   atomic.Pointer[OrderObserver]
+	receiveObserver atomic.Pointer[ReceiveObserver]
+	store           *clientStore // Хранилище сессии клиента paho с учетом записей
+	resumes         atomic.Int64
+	resume          atomic.Pointer[ResumeState] // Состояние хранилища при последнем возобновлении сессии
 	mu              sync.RWMutex
 	stopChan        chan struct{}
 	wg              sync.WaitGroup
 }
 
+// Интервалы повторной подписки после ошибки
+const (
+	subscribeRetryMin = time.Second
+	subscribeRetryMax = 30 * time.Second
+)
+
 // MessageHandler обработчик входящих сообщений
 type MessageHandler func(*models.Message) error
 
-// NewMQTTConsumer создает новый экземпляр MQTT consumer
-func NewMQTTConsumer(cfg *config.MQTTConfig, logger *zap.Logger, handler MessageHandler) (*MQTTConsumer, error) {
+// OrderObserver получатель номера sequence сообщения теста testID, полученного в потоке
+// stream (топик, субъект) канала channel, для проверки порядка
+type OrderObserver func(channel, stream, testID string, sequence int64)
+
+// ReceiveObserver получатель объема bytes байт и числа messages сообщений, принятых
+// по каналу channel
+type ReceiveObserver func(channel string, messages, bytes int)
+
+// NewMQTTConsumer создает новый экземпляр MQTT consumer; при заданном probe consumer
+// подписывается также на топик пробных сообщений
+func NewMQTTConsumer(cfg *config.MQTTConfig, logger *zap.Logger, handler MessageHandler, archiver *archive.Writer, probe *ProbeMonitor) (*MQTTConsumer, error) {
 	if handler == nil {
 		return nil, fmt.Errorf("обработчик сообщений не может быть nil")
 	}
@@ -43,9 +81,14 @@ func NewMQTTConsumer(cfg *config.MQTTConfig, logger *zap.Logger, handler Message
 		config:         cfg,
 		logger:         logger,
 		messageHandler: handler,
+		archive:        archiver,
+		probe:          probe,
 		stopChan:       make(chan struct{}),
 	}
 
+	c.inflightCond = sync.NewCond(&c.inflightMu)
+	c.inflightLimit = cfg.MaxInflight
+
 	// Настройка опций клиента MQTT
 	opts := mqtt.NewClientOptions()
 	opts.AddBroker(cfg.Broker)
@@ -65,11 +108,10 @@ func NewMQTTConsumer(cfg *config.MQTTConfig, logger *zap.Logger, handler Message
 	opts.SetMaxReconnectInterval(cfg.MaxReconnectInt)
 	opts.SetOrderMatters(cfg.OrderMatters)
 
-	// Настройка хранилища для сохранения состояния
-	if cfg.StoreDirectory != "" {
-		store := mqtt.NewFileStore(cfg.StoreDirectory)
-		opts.SetStore(store)
-	}
+	// Настройка хранилища для сохранения состояния (без директории - в памяти, как по
+	// умолчанию в paho); записи хранилища учитываются в статистике клиента
+	c.store = newClientStore(cfg.StoreDirectory)
+	opts.SetStore(c.store)
 
 	// Обработчики событий подключения
 	opts.SetOnConnectHandler(c.onConnect)
@@ -122,33 +164,191 @@ func (c *MQTTConsumer) onConnect(client mqtt.Client) {
 		c.logger.Info("Переподключение к MQTT брокеру выполнено успешно",
 			zap.Int32("попытка", reconnects),
 			zap.String("broker", c.config.Broker))
+		c.recordResume()
 	} else {
 		c.logger.Info("Подключение к MQTT брокеру установлено",
 			zap.String("broker", c.config.Broker),
 			zap.String("client_id", c.config.ClientID))
 	}
 
-	// Подписка на топик
+	// Подписка на топики; при ошибке повторяется в фоне, пока соединение не изменится
+	c.subscribed.Store(false)
+	gen := c.subscribeGen.Add(1)
 	if err := c.subscribe(); err != nil {
-		c.logger.Error("Ошибка подписки на топик", zap.Error(err))
+		c.recordSubscribeError(err)
+		c.wg.Add(1)
+		go c.retrySubscribe(gen)
+		return
+	}
+	c.subscribed.Store(true)
+}
+
+// recordResume фиксирует незавершенные обмены в хранилище сессии при переподключении:
+// paho продолжает их после подключения
+func (c *MQTTConsumer) recordResume() {
+	store := c.store.stats()
+	if store.Outbound == 0 && store.Inbound == 0 {
+		return
 	}
+
+	c.resume.Store(&ResumeState{Time: time.Now(), Outbound: store.Outbound, Inbound: store.Inbound})
+	c.resumes.Add(1)
+	c.logger.Warn("Возобновление сессии с незавершенными сообщениями в хранилище MQTT клиента",
+		zap.String("store", store.Type),
+		zap.Int64("outbound", store.Outbound),
+		zap.Int64("inbound", store.Inbound))
 }
 
-// subscribe подписывается на топик
+// retrySubscribe повторяет подписку с растущим интервалом (до mqtt.max_reconnect_interval),
+// пока она не выполнится, соединение не сменится или consumer не остановится
+func (c *MQTTConsumer) retrySubscribe(gen int64) {
+	defer c.wg.Done()
+
+	delay := subscribeRetryMin
+	maxDelay := c.config.MaxReconnectInt
+	if maxDelay < subscribeRetryMin {
+		maxDelay = subscribeRetryMax
+	}
+
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		case <-time.After(delay):
+		}
+
+		if c.subscribeGen.Load() != gen || !c.IsConnected() {
+			return
+		}
+
+		if err := c.subscribe(); err != nil {
+			c.recordSubscribeError(err)
+			delay = min(delay*2, maxDelay)
+			continue
+		}
+
+		if c.subscribeGen.Load() == gen {
+			c.subscribed.Store(true)
+			c.logger.Info("Подписка на топики восстановлена")
+		}
+		return
+	}
+}
+
+// recordSubscribeError учитывает ошибку подписки
+func (c *MQTTConsumer) recordSubscribeError(err error) {
+	c.subscribeErrors.Add(1)
+
+	c.mu.Lock()
+	c.subscribeErr = err.Error()
+	c.mu.Unlock()
+
+	c.logger.Error("Ошибка подписки на топик", zap.Error(err))
+}
+
+// IsSubscribed проверяет, подключен ли consumer и подписан ли он на все топики
+func (c *MQTTConsumer) IsSubscribed() bool {
+	return c.IsConnected() && c.subscribed.Load()
+}
+
+// topics возвращает топики подписки: основной и, если заданы, топики last will и
+// пробных сообщений. При заданной группе основной топик подписывается как общий: брокер
+// распределяет сообщения между экземплярами группы, last will и пробы получает каждый экземпляр
+func (c *MQTTConsumer) topics() []string {
+	topic := c.config.Topic
+	if c.config.SharedGroup != "" {
+		topic = "$share/" + c.config.SharedGroup + "/" + topic
+	}
+
+	topics := []string{topic}
+	if c.config.WillTopic != "" {
+		topics = append(topics, c.config.WillTopic)
+	}
+	if probeTopic := c.probe.Topic(); probeTopic != "" {
+		topics = append(topics, probeTopic)
+	}
+	return topics
+}
+
+// subackFailure код отказа в подписке в SUBACK (MQTT 3.1.1, раздел 3.9.3)
+const subackFailure = 0x80
+
+// subscribe подписывается на топики
 func (c *MQTTConsumer) subscribe() error {
-	token := c.client.Subscribe(c.config.Topic, c.config.QoS, nil)
+	for i, topic := range c.topics() {
+		token := c.client.Subscribe(topic, c.config.QoS, nil)
+
+		if !token.WaitTimeout(5 * time.Second) {
+			return fmt.Errorf("таймаут подписки на топик %s", topic)
+		}
+
+		if err := token.Error(); err != nil {
+			return fmt.Errorf("ошибка подписки на топик %s: %w", topic, err)
+		}
+
+		// paho не возвращает ошибку, если брокер отклонил подписку (например, по ACL):
+		// код отказа передается в SUBACK вместо предоставленного QoS
+		granted := c.config.QoS
+		if subscribeToken, ok := token.(*mqtt.SubscribeToken); ok {
+			if code, ok := subscribeToken.Result()[topic]; ok {
+				granted = code
+			}
+		}
+		if granted == subackFailure {
+			return fmt.Errorf("брокер отклонил подписку на топик %s (код SUBACK 0x80)", topic)
+		}
+		if i == 0 {
+			c.grantedQoS.Store(int32(granted))
+		}
+
+		c.logger.Info("Подписка на топик выполнена",
+			zap.String("topic", topic),
+			zap.Uint8("qos", c.config.QoS),
+			zap.Uint8("granted_qos", granted))
+	}
+
+	return nil
+}
+
+// unsubscribe отписывается от топиков
+func (c *MQTTConsumer) unsubscribe() error {
+	topics := c.topics()
+	token := c.client.Unsubscribe(topics...)
 
 	if !token.WaitTimeout(5 * time.Second) {
-		return fmt.Errorf("таймаут подписки на топик %s", c.config.Topic)
+		return fmt.Errorf("таймаут отписки от топиков %v", topics)
 	}
 
 	if err := token.Error(); err != nil {
-		return fmt.Errorf("ошибка подписки на топик %s: %w", c.config.Topic, err)
+		return fmt.Errorf("ошибка отписки от топиков %v: %w", topics, err)
+	}
+
+	c.logger.Info("Отписка от топиков выполнена", zap.Strings("topics", topics))
+
+	return nil
+}
+
+// Resubscribe повторно подписывается на топики; брокер при этом доставляет
+// сохраненные (retained) сообщения с флагом retain, что позволяет проверить их хранение
+func (c *MQTTConsumer) Resubscribe() error {
+	if !c.IsConnected() {
+		return fmt.Errorf("нет соединения с MQTT брокером")
 	}
 
-	c.logger.Info("Подписка на топик выполнена",
-		zap.String("topic", c.config.Topic),
-		zap.Uint8("qos", c.config.QoS))
+	if err := c.unsubscribe(); err != nil {
+		return err
+	}
+
+	// До повторной подписки сообщения не принимаются; при ошибке подписка повторяется в фоне
+	c.subscribed.Store(false)
+	gen := c.subscribeGen.Add(1)
+	if err := c.subscribe(); err != nil {
+		c.recordSubscribeError(err)
+		c.wg.Add(1)
+		go c.retrySubscribe(gen)
+		return err
+	}
+	c.subscribed.Store(true)
 
 	return nil
 }
@@ -156,6 +356,8 @@ func (c *MQTTConsumer) subscribe() error {
 // onConnectionLost вызывается при потере соединения
 func (c *MQTTConsumer) onConnectionLost(client mqtt.Client, err error) {
 	c.connected.Store(false)
+	c.subscribed.Store(false)
+	c.subscribeGen.Add(1)
 	c.errorCounter.Add(1)
 
 	c.logger.Error("Потеря соединения с MQTT брокером",
@@ -173,25 +375,211 @@ func (c *MQTTConsumer) onReconnecting(client mqtt.Client, opts *mqtt.ClientOptio
 
 // onMessageReceived обработчик входящих сообщений
 func (c *MQTTConsumer) onMessageReceived(client mqtt.Client, msg mqtt.Message) {
+	arrived := time.Now()
+
+	// Пробы sender проверяют путь и не относятся к тестам: не проверяются, не
+	// архивируются и не учитываются в статистике приема
+	if c.probe != nil && msg.Topic() == c.probe.Topic() {
+		c.probe.observe(msg.Payload(), arrived)
+		return
+	}
+
+	c.observeOrder(msg)
+	if !c.acquireSlot() {
+		// Consumer останавливается - обрабатываем сообщение синхронно, чтобы не потерять его
+		c.processMessage(msg)
+		c.observeLag(arrived)
+		return
+	}
+
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
+		defer c.releaseSlot()
 		c.processMessage(msg)
+		c.observeLag(arrived)
 	}()
 }
 
+// SetLagObserver задает получателя задержек от доставки сообщения paho до конца
+// обработки, включая ожидание места в окне обработки (nil - не передавать)
+func (c *MQTTConsumer) SetLagObserver(observer func(time.Duration)) {
+	if observer == nil {
+		c.lagObserver.Store(nil)
+		return
+	}
+	c.lagObserver.Store(&observer)
+}
+
+// SetReceiveObserver задает получателя объема принятых сообщений для учета приема
+// по протоколам (nil - не передавать)
+func (c *MQTTConsumer) SetReceiveObserver(observer ReceiveObserver) {
+	if observer == nil {
+		c.receiveObserver.Store(nil)
+		return
+	}
+	c.receiveObserver.Store(&observer)
+}
+
+// SetOrderObserver задает получателя номеров сообщений для проверки порядка (nil - не передавать)
+func (c *MQTTConsumer) SetOrderObserver(observer OrderObserver) {
+	if observer == nil {
+		c.orderObserver.Store(nil)
+		return
+	}
+	c.orderObserver.Store(&observer)
+}
+
+// observeOrder передает номер сообщения для проверки порядка в пределах топика. Вызывается
+// в порядке доставки paho (при order_matters), до параллельной обработки, поэтому номер
+// извлекается без полного разбора. Номера сообщений пакета передаются по порядку.
+// Сохраненные сообщения и last will не проверяются
+func (c *MQTTConsumer) observeOrder(msg mqtt.Message) {
+	observe := c.orderObserver.Load()
+	if observe == nil || msg.Retained() || len(msg.Payload()) == 0 ||
+		(c.config.WillTopic != "" && msg.Topic() == c.config.WillTopic) {
+		return
+	}
+
+	if models.IsBatchPayload(msg.Payload()) {
+		// Ошибка разбора будет учтена при обработке пакета
+		models.PeekBatchSequences(msg.Payload(), func(testID string, sequence int64) {
+			(*observe)(archive.SourceMQTT.String(), msg.Topic(), testID, sequence)
+		})
+		return
+	}
+
+	testID, sequence := models.PeekSequence(msg.Payload())
+	(*observe)(archive.SourceMQTT.String(), msg.Topic(), testID, sequence)
+}
+
+// SetDecodeObserver задает получателя длительностей разбора сообщений (nil - не передавать)
+func (c *MQTTConsumer) SetDecodeObserver(observer func(time.Duration)) {
+	if observer == nil {
+		c.decodeObserver.Store(nil)
+		return
+	}
+	c.decodeObserver.Store(&observer)
+}
+
+// observeLag передает задержку сообщения, доставленного в момент arrived
+func (c *MQTTConsumer) observeLag(arrived time.Time) {
+	if observe := c.lagObserver.Load(); observe != nil {
+		(*observe)(time.Since(arrived))
+	}
+}
+
+// acquireSlot занимает место в окне обработки. Пока окно заполнено, обработчик paho
+// блокируется и перестает забирать сообщения, поэтому всплеск сохраненных брокером
+// сообщений после переподключения не перегружает обработчик
+func (c *MQTTConsumer) acquireSlot() bool {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+
+	if c.windowFull() {
+		c.throttledCount.Add(1)
+		for c.windowFull() {
+			select {
+			case <-c.stopChan:
+				return false
+			default:
+			}
+			c.inflightCond.Wait()
+		}
+	}
+
+	c.inflightCount++
+	return true
+}
+
+// windowFull проверяет заполнение окна обработки (вызывается под inflightMu)
+func (c *MQTTConsumer) windowFull() bool {
+	return c.inflightLimit > 0 && c.inflightCount >= int64(c.inflightLimit)
+}
+
+// releaseSlot освобождает место в окне обработки
+func (c *MQTTConsumer) releaseSlot() {
+	c.inflightMu.Lock()
+	c.inflightCount--
+	c.inflightMu.Unlock()
+	c.inflightCond.Signal()
+}
+
+// SetMaxInflight изменяет размер окна обработки без переподключения (0 - без ограничения).
+// При уменьшении окна уже принятые сообщения дообрабатываются, новые ждут освобождения мест
+func (c *MQTTConsumer) SetMaxInflight(limit int) {
+	c.inflightMu.Lock()
+	c.inflightLimit = limit
+	c.inflightMu.Unlock()
+	c.inflightCond.Broadcast()
+}
+
 // processMessage обрабатывает полученное сообщение
 func (c *MQTTConsumer) processMessage(msg mqtt.Message) {
 	startTime := time.Now()
 	payload := msg.Payload()
 
+	// Пакет сообщений учитывается по количеству сообщений в нем
+	kind, count := archive.KindMessage, 1
+	batch := models.IsBatchPayload(payload)
+	if batch {
+		kind = archive.KindBatch
+		if n, err := models.PeekBatchSequences(payload, nil); err == nil {
+			count = n
+		}
+	}
+
 	// Обновление счетчиков
-	c.messageCounter.Add(1)
+	c.messageCounter.Add(int64(count))
 	c.bytesCounter.Add(int64(len(payload)))
+	c.archive.Write(archive.SourceMQTT, kind, startTime, payload)
+	if observe := c.receiveObserver.Load(); observe != nil {
+		(*observe)(archive.SourceMQTT.String(), count, len(payload))
+	}
+
+	if msg.Duplicate() {
+		c.duplicateCount.Add(1)
+	}
+
+	// Last will sender не является тестовым сообщением и учитывается отдельно
+	if c.config.WillTopic != "" && msg.Topic() == c.config.WillTopic {
+		c.willCount.Add(1)
+		c.logger.Info("Получено сообщение last will",
+			zap.String("topic", msg.Topic()),
+			zap.Bool("retained", msg.Retained()),
+			zap.ByteString("payload", payload))
+		return
+	}
+
+	// Пустое сообщение удаляет сохраненное брокером, обрабатывать нечего
+	if len(payload) == 0 {
+		c.logger.Debug("Получено сообщение очистки retained", zap.String("topic", msg.Topic()))
+		return
+	}
+
+	// Сохраненное сообщение повторяет уже доставленное и не учитывается в обработке,
+	// чтобы не искажать статистику повторов сессии
+	if msg.Retained() {
+		c.retainedCount.Add(1)
+		c.logger.Info("Получено сохраненное брокером сообщение",
+			zap.String("topic", msg.Topic()),
+			zap.Int("size", len(payload)))
+		return
+	}
+
+	if batch {
+		c.processBatch(msg, startTime)
+		return
+	}
 
 	// Десериализация сообщения
 	var message models.Message
-	if err := json.Unmarshal(payload, &message); err != nil {
+	decodeStart := time.Now()
+	err := message.UnmarshalJSON(payload)
+	if observe := c.decodeObserver.Load(); observe != nil {
+		(*observe)(time.Since(decodeStart))
+	}
+	if err != nil {
 		c.errorCounter.Add(1)
 		c.logger.Error("Ошибка десериализации сообщения",
 			zap.Error(err),
@@ -227,7 +615,55 @@ func (c *MQTTConsumer) processMessage(msg mqtt.Message) {
 	}
 }
 
-// Start начинает прием сообщений (подписка уже выполнена в onConnect)
+// processBatch разбирает пакет сообщений MessageBatch, опубликованный sender одним
+// сообщением брокера, и передает обработчику каждое сообщение пакета
+func (c *MQTTConsumer) processBatch(msg mqtt.Message, startTime time.Time) {
+	payload := msg.Payload()
+	c.batchCount.Add(1)
+
+	var batch models.MessageBatch
+	decodeStart := time.Now()
+	err := batch.UnmarshalJSON(payload)
+	if observe := c.decodeObserver.Load(); observe != nil {
+		(*observe)(time.Since(decodeStart))
+	}
+	if err != nil {
+		c.errorCounter.Add(1)
+		c.logger.Error("Ошибка десериализации пакета сообщений",
+			zap.Error(err),
+			zap.String("topic", msg.Topic()),
+			zap.Int("size", len(payload)))
+		return
+	}
+
+	c.logger.Debug("Пакет сообщений получен",
+		zap.String("topic", msg.Topic()),
+		zap.Int("count", len(batch.Messages)),
+		zap.Int("size", len(payload)),
+		zap.Uint8("qos", msg.Qos()),
+		zap.Bool("duplicate", msg.Duplicate()))
+
+	for _, message := range batch.Messages {
+		if message == nil {
+			continue
+		}
+		if err := c.messageHandler(message); err != nil {
+			c.errorCounter.Add(1)
+			c.logger.Error("Ошибка обработки сообщения из пакета",
+				zap.Error(err),
+				zap.Int("message_id", message.MessageID))
+		}
+	}
+
+	processingTime := time.Since(startTime)
+	if processingTime > time.Second {
+		c.logger.Warn("Долгая обработка пакета сообщений",
+			zap.Int("count", len(batch.Messages)),
+			zap.Duration("время_обработки", processingTime))
+	}
+}
+
+// Start начинает прием сообщений (подписка выполняется в onConnect и при ошибке повторяется в фоне)
 func (c *MQTTConsumer) Start() error {
 	if !c.IsConnected() {
 		return fmt.Errorf("нет соединения с MQTT брокером")
@@ -243,18 +679,12 @@ func (c *MQTTConsumer) Start() error {
 func (c *MQTTConsumer) Stop() error {
 	c.logger.Info("Остановка consumer")
 
-	// Отписка от топика
+	// Отписка от топиков
+	c.subscribed.Store(false)
+	c.subscribeGen.Add(1)
 	if c.client.IsConnected() {
-		token := c.client.Unsubscribe(c.config.Topic)
-		if token.WaitTimeout(5 * time.Second) {
-			if err := token.Error(); err != nil {
-				c.logger.Warn("Ошибка при отписке от топика",
-					zap.Error(err),
-					zap.String("topic", c.config.Topic))
-			} else {
-				c.logger.Info("Отписка от топика выполнена",
-					zap.String("topic", c.config.Topic))
-			}
+		if err := c.unsubscribe(); err != nil {
+			c.logger.Warn("Ошибка при отписке от топиков", zap.Error(err))
 		}
 	}
 
@@ -270,8 +700,13 @@ func (c *MQTTConsumer) IsConnected() bool {
 func (c *MQTTConsumer) GetStats() ConsumerStats {
 	c.mu.RLock()
 	lastConnect := c.lastConnectTime
+	lastSubscribeErr := c.subscribeErr
 	c.mu.RUnlock()
 
+	c.inflightMu.Lock()
+	inflight, inflightLimit := c.inflightCount, c.inflightLimit
+	c.inflightMu.Unlock()
+
 	messagesReceived := c.messageCounter.Load()
 	bytesReceived := c.bytesCounter.Load()
 
@@ -280,15 +715,43 @@ func (c *MQTTConsumer) GetStats() ConsumerStats {
 		avgMessageSize = bytesReceived / messagesReceived
 	}
 
+	var grantedQoS *int
+	if c.IsSubscribed() {
+		qos := int(c.grantedQoS.Load())
+		grantedQoS = &qos
+	}
+
 	return ConsumerStats{
 		MessagesReceived: messagesReceived,
 		BytesReceived:    bytesReceived,
 		Errors:           c.errorCounter.Load(),
 		ReconnectCount:   c.reconnectCount.Load(),
 		Connected:        c.IsConnected(),
+		Subscribed:       c.IsSubscribed(),
+		SubscribeErrors:  c.subscribeErrors.Load(),
+		LastSubscribeErr: lastSubscribeErr,
+		GrantedQoS:       grantedQoS,
 		LastConnectTime:  lastConnect,
 		Uptime:           time.Since(lastConnect),
 		AvgMessageSize:   avgMessageSize,
+		InFlight:         inflight,
+		InFlightLimit:    inflightLimit,
+		Throttled:        c.throttledCount.Load(),
+		Retained:         c.retainedCount.Load(),
+		Duplicates:       c.duplicateCount.Load(),
+		Will:             c.willCount.Load(),
+		Batches:          c.batchCount.Load(),
+		Client:           c.ClientStats(),
+	}
+}
+
+// ClientStats возвращает внутреннее состояние клиента paho: записи хранилища сессии и
+// возобновление сессии после переподключения
+func (c *MQTTConsumer) ClientStats() ClientStats {
+	return ClientStats{
+		Store:   c.store.stats(),
+		Resumes: c.resumes.Load(),
+		Resume:  c.resume.Load(),
 	}
 }
 
@@ -297,6 +760,12 @@ func (c *MQTTConsumer) ResetStats() {
 	c.messageCounter.Store(0)
 	c.bytesCounter.Store(0)
 	c.errorCounter.Store(0)
+	c.subscribeErrors.Store(0)
+	c.throttledCount.Store(0)
+	c.retainedCount.Store(0)
+	c.duplicateCount.Store(0)
+	c.willCount.Store(0)
+	c.batchCount.Store(0)
 	// reconnectCount не сбрасываем, так как это общий счетчик
 }
 
@@ -334,8 +803,11 @@ func (c *MQTTConsumer) Flush(timeout time.Duration) error {
 func (c *MQTTConsumer) Close() error {
 	c.logger.Info("Закрытие соединения с MQTT брокером")
 
-	// Сигнал остановки
+	// Сигнал остановки (в том числе для ожидающих места в окне обработки)
 	close(c.stopChan)
+	c.inflightMu.Lock()
+	c.inflightCond.Broadcast()
+	c.inflightMu.Unlock()
 
 	// Остановка приема сообщений
 	if err := c.Stop(); err != nil {
@@ -373,7 +845,19 @@ type ConsumerStats struct {
 	Errors           int64
 	ReconnectCount   int32
 	Connected        bool
+	Subscribed       bool   // Подписка на топики выполнена
+	SubscribeErrors  int64  // Неудачных попыток подписки
+	LastSubscribeErr string // Последняя ошибка подписки
+	GrantedQoS       *int   // QoS, предоставленный брокером подписке на основной топик (nil - подписки нет)
 	LastConnectTime  time.Time
 	Uptime           time.Duration
 	AvgMessageSize   int64
+	InFlight         int64       // Сообщений в обработке
+	InFlightLimit    int         // Размер окна обработки (0 - без ограничения)
+	Throttled        int64       // Сколько раз прием ожидал освобождения окна
+	Retained         int64       // Сохраненных брокером сообщений (флаг retain)
+	Duplicates       int64       // Повторных доставок (флаг DUP)
+	Will             int64       // Сообщений last will
+	Batches          int64       // Пакетов сообщений (одно сообщение брокера на пакет)
+	Client           ClientStats // Хранилище сессии клиента paho
 }
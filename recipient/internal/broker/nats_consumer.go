@@ -0,0 +1,382 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/recipient/config"
+	"github.com/infodiode/recipient/internal/archive"
+	"github.com/infodiode/shared/models"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// NATSConsumer получает сообщения из потока NATS JetStream через durable pull consumer.
+// Переподключение и PING выполняет клиент nats.go
+type NATSConsumer struct {
+	config          *config.NATSConfig
+	logger          *zap.Logger
+	conn            *nats.Conn
+	consumer        jetstream.Consumer
+	messageCounter  atomic.Int64
+	errorCounter    atomic.Int64
+	bytesCounter    atomic.Int64
+	reconnectCount  atomic.Int32
+	lastConnectTime time.Time
+	messageHandler  MessageHandler
+	archive         *archive.Writer // Архив принятых кадров, nil если отключен
+	decodeObserver  atomic.Pointer[func(time.Duration)]
+	orderObserver   atomic.Pointer[OrderObserver]
+	receiveObserver atomic.Pointer[ReceiveObserver]
+	mu              sync.RWMutex
+	stopChan        chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewNATSConsumer создает consumer и подключается к серверу, создавая поток и consumer при необходимости
+func NewNATSConsumer(cfg *config.NATSConfig, logger *zap.Logger, handler MessageHandler, archiver *archive.Writer) (*NATSConsumer, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("обработчик сообщений не может быть nil")
+	}
+
+	c := &NATSConsumer{
+		config:         cfg,
+		logger:         logger,
+		messageHandler: handler,
+		archive:        archiver,
+		stopChan:       make(chan struct{}),
+	}
+
+	if err := c.connect(); err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к NATS серверу: %w", err)
+	}
+
+	return c, nil
+}
+
+// connect подключается к серверу и проверяет наличие потока и consumer
+func (c *NATSConsumer) connect() error {
+	c.logger.Info("Подключение к NATS серверу",
+		zap.String("url", c.config.URL),
+		zap.String("stream", c.config.Stream),
+		zap.String("durable", c.config.Durable))
+
+	opts := []nats.Option{
+		nats.Name("infodiode-recipient"),
+		nats.Timeout(c.config.ConnectTimeout),
+		nats.ReconnectWait(c.config.ReconnectWait),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			c.logger.Warn("Соединение с NATS сервером потеряно", zap.Error(err))
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			c.reconnectCount.Add(1)
+			c.mu.Lock()
+			c.lastConnectTime = time.Now()
+			c.mu.Unlock()
+			c.logger.Info("Переподключено к NATS серверу", zap.String("url", nc.ConnectedUrlRedacted()))
+		}),
+	}
+	if c.config.Username != "" {
+		opts = append(opts, nats.UserInfo(c.config.Username, c.config.Password))
+	}
+	if c.config.Token != "" {
+		opts = append(opts, nats.Token(c.config.Token))
+	}
+
+	nc, err := nats.Connect(c.config.URL, opts...)
+	if err != nil {
+		return err
+	}
+
+	consumer, err := c.ensureConsumer(nc)
+	if err != nil {
+		nc.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	c.conn = nc
+	c.consumer = consumer
+	c.lastConnectTime = time.Now()
+	c.mu.Unlock()
+
+	c.logger.Info("Подключено к NATS серверу",
+		zap.String("url", nc.ConnectedUrlRedacted()),
+		zap.String("server_version", nc.ConnectedServerVersion()))
+
+	return nil
+}
+
+// ensureConsumer создает поток и durable consumer, если они еще не существуют;
+// настройки существующих не изменяются
+func (c *NATSConsumer) ensureConsumer(nc *nats.Conn) (jetstream.Consumer, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.ConnectTimeout)
+	defer cancel()
+
+	stream, err := js.Stream(ctx, c.config.Stream)
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		stream, err = js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:     c.config.Stream,
+			Subjects: []string{c.config.Subject},
+			Storage:  jetstream.FileStorage,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ошибка создания потока %s: %w", c.config.Stream, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	consumer, err := stream.Consumer(ctx, c.config.Durable)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) {
+		consumer, err = stream.CreateConsumer(ctx, jetstream.ConsumerConfig{
+			Durable:       c.config.Durable,
+			AckPolicy:     jetstream.AckExplicitPolicy,
+			DeliverPolicy: jetstream.DeliverAllPolicy,
+			AckWait:       c.config.AckWait,
+			MaxAckPending: c.config.FetchBatch * 10,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ошибка создания consumer %s: %w", c.config.Durable, err)
+		}
+	}
+	return consumer, err
+}
+
+// Start запускает цикл получения сообщений
+func (c *NATSConsumer) Start() error {
+	c.wg.Add(1)
+	go c.fetchLoop()
+
+	c.logger.Info("NATS consumer запущен",
+		zap.String("stream", c.config.Stream),
+		zap.String("durable", c.config.Durable))
+
+	return nil
+}
+
+// fetchLoop запрашивает сообщения у consumer до остановки. Во время переподключения
+// клиента запросы завершаются ошибкой и повторяются через reconnect_wait
+func (c *NATSConsumer) fetchLoop() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		default:
+		}
+
+		c.mu.RLock()
+		nc, consumer := c.conn, c.consumer
+		c.mu.RUnlock()
+
+		err := errNATSDisconnected
+		if nc.IsConnected() {
+			err = c.fetch(consumer)
+		}
+		if err == nil {
+			continue
+		}
+
+		if nc.IsConnected() {
+			c.errorCounter.Add(1)
+			c.logger.Warn("Ошибка получения сообщений из NATS", zap.Error(err))
+		}
+
+		// Пауза, чтобы не нагружать сервер повторными запросами при постоянной ошибке
+		select {
+		case <-time.After(c.config.ReconnectWait):
+		case <-c.stopChan:
+			return
+		}
+	}
+}
+
+// errNATSDisconnected соединение восстанавливается клиентом, запрос сообщений откладывается
+var errNATSDisconnected = errors.New("нет соединения с NATS сервером")
+
+// fetch запрашивает один пакет сообщений и обрабатывает их по мере поступления
+func (c *NATSConsumer) fetch(consumer jetstream.Consumer) error {
+	batch, err := consumer.Fetch(c.config.FetchBatch, jetstream.FetchMaxWait(c.config.FetchTimeout))
+	if err != nil {
+		return err
+	}
+	for msg := range batch.Messages() {
+		c.processMessage(msg)
+	}
+	if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+		return err
+	}
+	return nil
+}
+
+// processMessage обрабатывает и подтверждает полученное сообщение
+func (c *NATSConsumer) processMessage(msg jetstream.Msg) {
+	startTime := time.Now()
+
+	c.messageCounter.Add(1)
+	c.bytesCounter.Add(int64(len(msg.Data())))
+	c.archive.Write(archive.SourceNATS, archive.KindMessage, startTime, msg.Data())
+	if observe := c.receiveObserver.Load(); observe != nil {
+		(*observe)(archive.SourceNATS.String(), 1, len(msg.Data()))
+	}
+
+	// Сообщение подтверждается и при ошибке разбора, иначе оно будет доставляться бесконечно
+	defer func() {
+		if err := msg.Ack(); err != nil {
+			c.logger.Warn("Ошибка подтверждения сообщения NATS", zap.Error(err))
+		}
+	}()
+
+	var message models.Message
+	decodeStart := time.Now()
+	err := message.UnmarshalJSON(msg.Data())
+	if observe := c.decodeObserver.Load(); observe != nil {
+		(*observe)(time.Since(decodeStart))
+	}
+	if err != nil {
+		c.errorCounter.Add(1)
+		c.logger.Error("Ошибка десериализации сообщения",
+			zap.Error(err),
+			zap.String("subject", msg.Subject()),
+			zap.Int("size", len(msg.Data())))
+		return
+	}
+
+	// Сообщения пакета Fetch обрабатываются по одному в порядке доставки
+	if observe := c.orderObserver.Load(); observe != nil {
+		(*observe)(archive.SourceNATS.String(), msg.Subject(), message.TestID, message.Sequence)
+	}
+
+	c.mu.RLock()
+	handler := c.messageHandler
+	c.mu.RUnlock()
+
+	if err := handler(&message); err != nil {
+		c.errorCounter.Add(1)
+		c.logger.Error("Ошибка обработки сообщения",
+			zap.Error(err),
+			zap.Int("message_id", message.MessageID))
+		return
+	}
+
+	if processingTime := time.Since(startTime); processingTime > time.Second {
+		c.logger.Warn("Долгая обработка сообщения",
+			zap.Int("message_id", message.MessageID),
+			zap.Duration("время_обработки", processingTime))
+	}
+}
+
+// IsConnected проверяет состояние подключения
+func (c *NATSConsumer) IsConnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conn != nil && c.conn.IsConnected()
+}
+
+// GetStats возвращает статистику consumer
+func (c *NATSConsumer) GetStats() ConsumerStats {
+	c.mu.RLock()
+	lastConnect := c.lastConnectTime
+	c.mu.RUnlock()
+
+	messagesReceived := c.messageCounter.Load()
+	bytesReceived := c.bytesCounter.Load()
+
+	var avgMessageSize int64
+	if messagesReceived > 0 {
+		avgMessageSize = bytesReceived / messagesReceived
+	}
+
+	return ConsumerStats{
+		MessagesReceived: messagesReceived,
+		BytesReceived:    bytesReceived,
+		Errors:           c.errorCounter.Load(),
+		ReconnectCount:   c.reconnectCount.Load(),
+		Connected:        c.IsConnected(),
+		Subscribed:       c.IsConnected(), // Pull consumer получает сообщения без подписки, пока есть подключение
+		LastConnectTime:  lastConnect,
+		Uptime:           time.Since(lastConnect),
+		AvgMessageSize:   avgMessageSize,
+	}
+}
+
+// ResetStats сбрасывает счетчики статистики
+func (c *NATSConsumer) ResetStats() {
+	c.messageCounter.Store(0)
+	c.bytesCounter.Store(0)
+	c.errorCounter.Store(0)
+}
+
+// Close останавливает получение сообщений и закрывает соединение
+func (c *NATSConsumer) Close() error {
+	c.logger.Info("Закрытие соединения с NATS сервером")
+
+	close(c.stopChan)
+
+	// Текущий запрос завершается не позже fetch_timeout
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(c.config.FetchTimeout + 5*time.Second):
+		c.logger.Warn("Таймаут при ожидании завершения получения сообщений NATS")
+	}
+
+	c.mu.Lock()
+	c.conn.Close()
+	c.mu.Unlock()
+
+	stats := c.GetStats()
+	c.logger.Info("NATS consumer закрыт",
+		zap.Int64("сообщений_получено", stats.MessagesReceived),
+		zap.Int64("байт_получено", stats.BytesReceived),
+		zap.Int64("ошибок", stats.Errors))
+
+	return nil
+}
+
+// SetReceiveObserver задает получателя объема принятых сообщений для учета приема
+// по протоколам (nil - не передавать)
+func (c *NATSConsumer) SetReceiveObserver(observer ReceiveObserver) {
+	if observer == nil {
+		c.receiveObserver.Store(nil)
+		return
+	}
+	c.receiveObserver.Store(&observer)
+}
+
+// SetOrderObserver задает получателя номеров сообщений для проверки порядка (nil - не передавать)
+func (c *NATSConsumer) SetOrderObserver(observer OrderObserver) {
+	if observer == nil {
+		c.orderObserver.Store(nil)
+		return
+	}
+	c.orderObserver.Store(&observer)
+}
+
+// SetDecodeObserver задает получателя длительностей разбора сообщений (nil - не передавать)
+func (c *NATSConsumer) SetDecodeObserver(observer func(time.Duration)) {
+	if observer == nil {
+		c.decodeObserver.Store(nil)
+		return
+	}
+	c.decodeObserver.Store(&observer)
+}
@@ -0,0 +1,395 @@
+package demux
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// Форматы файлов записей
+const (
+	FormatJSONL = "jsonl" // Строка - JSON запись models.Data
+	FormatCSV   = "csv"   // Заголовок csvHeader, строка - поля записи
+)
+
+// Параметры по умолчанию
+const (
+	DefaultQueueSize    = 10000
+	DefaultMaxOpenFiles = 256
+
+	partSuffix    = ".part"
+	csvHeader     = "id,timestamp,indicator_id,indicator_value,equipment_id\n"
+	checkInterval = time.Second
+	errorLogEvery = time.Minute
+)
+
+// Config конфигурация записи записей по оборудованию
+type Config struct {
+	Directory      string        // Корневая директория; файлы оборудования - в <Directory>/equipment-<id>
+	Format         string        // Формат файлов (FormatJSONL, FormatCSV)
+	MaxFileSize    int64         // Размер файла в байтах, после которого начинается новый файл
+	RotateInterval time.Duration // Время записи файла, после которого начинается новый файл (0 - только по размеру)
+	MaxOpenFiles   int           // Одновременно открытых файлов; при превышении завершается давно не дописывавшийся
+	QueueSize      int           // Размер очереди сообщений; при заполненной очереди записи отбрасываются
+}
+
+// Stats статистика записи
+type Stats struct {
+	Directory      string `json:"directory"`
+	Format         string `json:"format"`
+	Equipment      int    `json:"equipment"`       // Оборудования, для которого получены записи
+	OpenFiles      int    `json:"open_files"`      // Файлов в записи (*.part)
+	FilesCreated   int64  `json:"files_created"`   // Начатых файлов
+	FilesCompleted int64  `json:"files_completed"` // Завершенных файлов (переименованы без .part)
+	RecordsWritten int64  `json:"records_written"`
+	BytesWritten   int64  `json:"bytes_written"`
+	Queued         int    `json:"queued"`
+	QueueSize      int    `json:"queue_size"`
+	Dropped        int64  `json:"dropped_records"` // Записей, отброшенных при заполненной очереди
+	Errors         int64  `json:"errors"`
+}
+
+// sinkFile файл записей одного оборудования
+type sinkFile struct {
+	path      string // Путь завершенного файла; запись идет в path + partSuffix
+	file      *os.File
+	buf       *bufio.Writer
+	size      int64
+	opened    time.Time
+	lastWrite time.Time
+}
+
+// Writer раскладывает проверенные записи payload по файлам оборудования (equipment_id),
+// повторяя структуру директории импорта исторической базы за диодом: файл пишется с
+// суффиксом .part и переименовывается после ротации по размеру или времени, поэтому
+// импорт видит только завершенные файлы. Записи передаются фоновой горутине через
+// очередь, обработка сообщений не ждет диска. Методы nil *Writer ничего не делают
+type Writer struct {
+	config  Config
+	logger  *zap.Logger
+	queue   chan []*models.Data
+	dropped atomic.Int64
+
+	// Поля ниже изменяются только горутиной записи; stats читается под mu
+	files     map[int]*sinkFile
+	seen      map[int]struct{}
+	seq       int
+	line      []byte
+	lastLog   time.Time
+	mu        sync.Mutex
+	stats     Stats
+	closed    atomic.Bool
+	closeOnce sync.Once
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewWriter создает корневую директорию и запускает запись
+func NewWriter(cfg Config, logger *zap.Logger) (*Writer, error) {
+	if cfg.Directory == "" {
+		return nil, fmt.Errorf("не указана директория файлов оборудования")
+	}
+	if cfg.Format != FormatJSONL && cfg.Format != FormatCSV {
+		return nil, fmt.Errorf("некорректный формат файлов оборудования: %s (допустимо %s, %s)", cfg.Format, FormatJSONL, FormatCSV)
+	}
+	if cfg.MaxFileSize <= 0 {
+		return nil, fmt.Errorf("некорректный размер файла оборудования: %d", cfg.MaxFileSize)
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.MaxOpenFiles <= 0 {
+		cfg.MaxOpenFiles = DefaultMaxOpenFiles
+	}
+	if err := os.MkdirAll(cfg.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию файлов оборудования: %w", err)
+	}
+
+	w := &Writer{
+		config:   cfg,
+		logger:   logger,
+		queue:    make(chan []*models.Data, cfg.QueueSize),
+		files:    make(map[int]*sinkFile),
+		seen:     make(map[int]struct{}),
+		stopChan: make(chan struct{}),
+		stats:    Stats{Directory: cfg.Directory, Format: cfg.Format},
+	}
+
+	w.wg.Add(1)
+	go w.run()
+
+	return w, nil
+}
+
+// Write ставит проверенные записи одного сообщения в очередь без ожидания
+func (w *Writer) Write(records []*models.Data) {
+	if w == nil || len(records) == 0 || w.closed.Load() {
+		return
+	}
+
+	select {
+	case w.queue <- records:
+	default:
+		w.dropped.Add(int64(len(records)))
+	}
+}
+
+// run записывает записи из очереди до остановки, затем дописывает оставшиеся и
+// завершает все файлы. Буферы сбрасываются, когда очередь опустела
+func (w *Writer) run() {
+	defer w.wg.Done()
+	defer w.completeAll()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case records := <-w.queue:
+			w.write(records)
+			if len(w.queue) == 0 {
+				w.flush()
+			}
+		case <-ticker.C:
+			w.rotateExpired()
+		case <-w.stopChan:
+			for {
+				select {
+				case records := <-w.queue:
+					w.write(records)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write дописывает записи в файлы их оборудования
+func (w *Writer) write(records []*models.Data) {
+	for _, data := range records {
+		if data == nil {
+			continue
+		}
+
+		file, err := w.file(data.EquipmentID)
+		if err != nil {
+			w.recordError(err)
+			continue
+		}
+
+		w.line = w.appendRecord(w.line[:0], data)
+		if _, err := file.buf.Write(w.line); err != nil {
+			w.recordError(fmt.Errorf("%s: %w", file.path, err))
+			w.discard(data.EquipmentID, file)
+			continue
+		}
+
+		file.size += int64(len(w.line))
+		file.lastWrite = time.Now()
+
+		w.mu.Lock()
+		w.stats.RecordsWritten++
+		w.stats.BytesWritten += int64(len(w.line))
+		w.mu.Unlock()
+
+		if file.size >= w.config.MaxFileSize {
+			w.complete(data.EquipmentID, file)
+		}
+	}
+}
+
+// appendRecord дописывает строку записи в формате файлов
+func (w *Writer) appendRecord(dst []byte, data *models.Data) []byte {
+	if w.config.Format == FormatJSONL {
+		return append(data.AppendJSON(dst), '\n')
+	}
+
+	dst = strconv.AppendInt(dst, int64(data.ID), 10)
+	dst = append(dst, ',')
+	dst = appendCSVField(dst, data.Timestamp)
+	dst = append(dst, ',')
+	dst = strconv.AppendInt(dst, int64(data.IndicatorID), 10)
+	dst = append(dst, ',')
+	dst = appendCSVField(dst, data.IndicatorValue)
+	dst = append(dst, ',')
+	dst = strconv.AppendInt(dst, int64(data.EquipmentID), 10)
+	return append(dst, '\n')
+}
+
+// appendCSVField дописывает строковое поле CSV (RFC 4180): поле с разделителем,
+// кавычкой или переводом строки заключается в кавычки, кавычки удваиваются
+func appendCSVField(dst []byte, value string) []byte {
+	if !strings.ContainsAny(value, ",\"\r\n") {
+		return append(dst, value...)
+	}
+	dst = append(dst, '"')
+	dst = append(dst, strings.ReplaceAll(value, `"`, `""`)...)
+	return append(dst, '"')
+}
+
+// file возвращает открытый файл оборудования, при необходимости начиная новый
+func (w *Writer) file(equipmentID int) (*sinkFile, error) {
+	if file, ok := w.files[equipmentID]; ok {
+		return file, nil
+	}
+
+	// Предел открытых файлов: завершается файл, который дольше всех не дописывался
+	if len(w.files) >= w.config.MaxOpenFiles {
+		oldestID, oldest := 0, (*sinkFile)(nil)
+		for id, file := range w.files {
+			if oldest == nil || file.lastWrite.Before(oldest.lastWrite) {
+				oldestID, oldest = id, file
+			}
+		}
+		w.complete(oldestID, oldest)
+	}
+
+	dir := filepath.Join(w.config.Directory, fmt.Sprintf("equipment-%d", equipmentID))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию оборудования: %w", err)
+	}
+
+	now := time.Now()
+	w.seq++
+	path := filepath.Join(dir, fmt.Sprintf("%s-%06d.%s", now.Format("20060102-150405"), w.seq, w.config.Format))
+	f, err := os.OpenFile(path+partSuffix, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания файла оборудования: %w", err)
+	}
+
+	file := &sinkFile{
+		path:      path,
+		file:      f,
+		buf:       bufio.NewWriterSize(f, 64*1024),
+		opened:    now,
+		lastWrite: now,
+	}
+	if w.config.Format == FormatCSV {
+		file.buf.WriteString(csvHeader)
+		file.size = int64(len(csvHeader))
+	}
+	w.files[equipmentID] = file
+	w.seen[equipmentID] = struct{}{}
+
+	w.mu.Lock()
+	w.stats.FilesCreated++
+	w.stats.OpenFiles = len(w.files)
+	w.stats.Equipment = len(w.seen)
+	w.mu.Unlock()
+
+	return file, nil
+}
+
+// complete дописывает и закрывает файл оборудования и снимает с него суффикс .part
+func (w *Writer) complete(equipmentID int, file *sinkFile) {
+	delete(w.files, equipmentID)
+
+	err := errors.Join(file.buf.Flush(), file.file.Close())
+	if err == nil {
+		err = os.Rename(file.path+partSuffix, file.path)
+	}
+
+	w.mu.Lock()
+	w.stats.OpenFiles = len(w.files)
+	if err == nil {
+		w.stats.FilesCompleted++
+	}
+	w.mu.Unlock()
+
+	if err != nil {
+		w.recordError(fmt.Errorf("%s: %w", file.path, err))
+	}
+}
+
+// discard закрывает файл после ошибки записи; файл остается с суффиксом .part,
+// следующая запись оборудования начинает новый файл
+func (w *Writer) discard(equipmentID int, file *sinkFile) {
+	delete(w.files, equipmentID)
+	file.file.Close()
+
+	w.mu.Lock()
+	w.stats.OpenFiles = len(w.files)
+	w.mu.Unlock()
+}
+
+// rotateExpired завершает файлы, запись которых длится дольше RotateInterval, и
+// сбрасывает буферы остальных
+func (w *Writer) rotateExpired() {
+	for id, file := range w.files {
+		if w.config.RotateInterval > 0 && time.Since(file.opened) >= w.config.RotateInterval {
+			w.complete(id, file)
+		}
+	}
+	w.flush()
+}
+
+// flush сбрасывает буферы открытых файлов
+func (w *Writer) flush() {
+	for id, file := range w.files {
+		if err := file.buf.Flush(); err != nil {
+			w.recordError(fmt.Errorf("%s: %w", file.path, err))
+			w.discard(id, file)
+		}
+	}
+}
+
+// completeAll завершает все открытые файлы
+func (w *Writer) completeAll() {
+	for id, file := range w.files {
+		w.complete(id, file)
+	}
+}
+
+// recordError учитывает ошибку и пишет ее в лог не чаще раза в минуту
+func (w *Writer) recordError(err error) {
+	w.mu.Lock()
+	w.stats.Errors++
+	errorsCount := w.stats.Errors
+	w.mu.Unlock()
+
+	if time.Since(w.lastLog) >= errorLogEvery {
+		w.lastLog = time.Now()
+		w.logger.Error("Ошибка записи файлов оборудования",
+			zap.Int64("errors", errorsCount),
+			zap.Error(err))
+	}
+}
+
+// Stats возвращает статистику записи
+func (w *Writer) Stats() Stats {
+	if w == nil {
+		return Stats{}
+	}
+
+	w.mu.Lock()
+	stats := w.stats
+	w.mu.Unlock()
+
+	stats.Queued = len(w.queue)
+	stats.QueueSize = cap(w.queue)
+	stats.Dropped = w.dropped.Load()
+	return stats
+}
+
+// Close прекращает прием записей, дописывает очередь и завершает все файлы
+func (w *Writer) Close() {
+	if w == nil {
+		return
+	}
+	w.closeOnce.Do(func() {
+		w.closed.Store(true)
+		close(w.stopChan)
+		w.wg.Wait()
+	})
+}
@@ -0,0 +1,373 @@
+package files
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxTransfers количество отслеживаемых передач (старые вытесняются)
+	maxTransfers = 100
+	// maxChunkSize предельный размер данных одного фрагмента
+	maxChunkSize = 4 * 1024 * 1024
+)
+
+// transferIDPattern допустимый идентификатор передачи (используется в именах файлов)
+var transferIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]{0,63}$`)
+
+// Config конфигурация сборки файлов
+type Config struct {
+	Directory   string // Директория собранных файлов
+	MaxFileSize int64  // Предельный размер файла в байтах
+}
+
+// transfer состояние приема одного файла
+type transfer struct {
+	mu       sync.Mutex
+	report   models.FileTransferReport
+	chunks   map[int]struct{} // Номера полученных фрагментов
+	file     *os.File         // Файл сборки, nil после проверки
+	partPath string
+}
+
+// Assembler собирает файлы из фрагментов теста передачи файлов и проверяет их по манифесту.
+// Фрагменты записываются по смещению во временный файл <id>.part; после получения манифеста
+// и всех фрагментов файл проверяется и при совпадении переносится в <id>/<name>.
+// Методы nil *Assembler ничего не делают
+type Assembler struct {
+	config    Config
+	logger    *zap.Logger
+	mu        sync.Mutex
+	transfers map[string]*transfer
+	order     []string
+}
+
+// NewAssembler создает сборщик файлов
+func NewAssembler(cfg Config, logger *zap.Logger) (*Assembler, error) {
+	if cfg.Directory == "" {
+		return nil, fmt.Errorf("не указана директория файлов")
+	}
+	if cfg.MaxFileSize <= 0 {
+		return nil, fmt.Errorf("некорректный предельный размер файла: %d", cfg.MaxFileSize)
+	}
+	if err := os.MkdirAll(cfg.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию файлов: %w", err)
+	}
+
+	return &Assembler{
+		config:    cfg,
+		logger:    logger,
+		transfers: make(map[string]*transfer),
+	}, nil
+}
+
+// Handle обрабатывает часть файла из сообщения с корректной контрольной суммой.
+// Возвращает ошибку, если фрагмент или манифест не приняты
+func (a *Assembler) Handle(message *models.Message) error {
+	if a == nil || message.File == nil {
+		return nil
+	}
+
+	part := message.File
+	if !transferIDPattern.MatchString(part.TransferID) {
+		return fmt.Errorf("некорректный идентификатор передачи: %q", part.TransferID)
+	}
+
+	t, err := a.transfer(part.TransferID)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.report.UpdatedAt = time.Now()
+
+	if part.Index == models.FileManifestIndex {
+		err = a.handleManifest(t, message.Payload)
+	} else {
+		err = a.handleChunk(t, part, message.Payload)
+	}
+	if err != nil {
+		return err
+	}
+
+	a.tryComplete(t)
+	return nil
+}
+
+// transfer возвращает состояние передачи, создавая его при первом фрагменте
+func (a *Assembler) transfer(id string) (*transfer, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if t, ok := a.transfers[id]; ok {
+		return t, nil
+	}
+
+	partPath := filepath.Join(a.config.Directory, id+".part")
+	file, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать файл сборки: %w", err)
+	}
+
+	now := time.Now()
+	t := &transfer{
+		report: models.FileTransferReport{
+			TransferID: id,
+			Status:     models.FileTransferReceiving,
+			StartedAt:  now,
+			UpdatedAt:  now,
+		},
+		chunks:   make(map[int]struct{}),
+		file:     file,
+		partPath: partPath,
+	}
+	a.transfers[id] = t
+	a.order = append(a.order, id)
+
+	if len(a.order) > maxTransfers {
+		evicted := a.transfers[a.order[0]]
+		delete(a.transfers, a.order[0])
+		a.order = a.order[1:]
+		// Удаление выполняется под a.mu: новая передача с тем же идентификатором
+		// не успеет создать файл сборки, который будет удален
+		evicted.discard()
+	}
+
+	a.logger.Info("Начат прием файла", zap.String("transfer_id", id))
+	return t, nil
+}
+
+// handleManifest принимает манифест (вызывается под t.mu)
+func (a *Assembler) handleManifest(t *transfer, payload string) error {
+	if t.report.Manifest != nil {
+		t.report.Duplicates++
+		return nil
+	}
+
+	var manifest models.FileManifest
+	if err := json.Unmarshal([]byte(payload), &manifest); err != nil {
+		return fmt.Errorf("некорректный манифест: %w", err)
+	}
+	if manifest.Size < 0 || manifest.Size > a.config.MaxFileSize || manifest.ChunkSize <= 0 || manifest.Chunks < 0 {
+		return fmt.Errorf("некорректный манифест: размер %d, фрагмент %d, фрагментов %d",
+			manifest.Size, manifest.ChunkSize, manifest.Chunks)
+	}
+
+	t.report.Manifest = &manifest
+	return nil
+}
+
+// handleChunk проверяет фрагмент и записывает его по смещению (вызывается под t.mu)
+func (a *Assembler) handleChunk(t *transfer, part *models.FilePart, payload string) error {
+	if _, ok := t.chunks[part.Index]; ok {
+		t.report.Duplicates++
+		return nil
+	}
+	if t.file == nil {
+		// Передача уже проверена или вытеснена, новые фрагменты не принимаются
+		t.report.ChunkErrors++
+		return fmt.Errorf("фрагмент %d получен после завершения передачи", part.Index)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		t.report.ChunkErrors++
+		return fmt.Errorf("фрагмент %d: ошибка декодирования: %w", part.Index, err)
+	}
+
+	// Границы проверяются до хеша, чтобы не считать хеш фрагмента, который не будет записан
+	if part.Index < 0 || part.Offset < 0 || len(data) > maxChunkSize || part.Offset+int64(len(data)) > a.config.MaxFileSize {
+		t.report.ChunkErrors++
+		return fmt.Errorf("фрагмент %d: некорректное смещение %d или размер %d", part.Index, part.Offset, len(data))
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != part.SHA256 {
+		t.report.ChunkErrors++
+		return fmt.Errorf("фрагмент %d: хеш не совпадает", part.Index)
+	}
+
+	if _, err := t.file.WriteAt(data, part.Offset); err != nil {
+		t.report.ChunkErrors++
+		return fmt.Errorf("фрагмент %d: ошибка записи: %w", part.Index, err)
+	}
+
+	t.chunks[part.Index] = struct{}{}
+	t.report.ChunksReceived++
+	t.report.BytesReceived += int64(len(data))
+	return nil
+}
+
+// tryComplete проверяет собранный файл, когда получены манифест и все фрагменты (вызывается под t.mu)
+func (a *Assembler) tryComplete(t *transfer) {
+	manifest := t.report.Manifest
+	if manifest == nil || t.file == nil || t.report.ChunksReceived < manifest.Chunks {
+		return
+	}
+
+	start := time.Now()
+	failure := t.verify(manifest)
+	t.report.VerifyDuration = float64(time.Since(start).Microseconds()) / 1000
+	t.close()
+
+	if failure == "" {
+		dir := filepath.Join(a.config.Directory, t.report.TransferID)
+		path := filepath.Join(dir, fileName(manifest.Name))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			failure = fmt.Sprintf("не удалось создать директорию: %v", err)
+		} else if err := os.Rename(t.partPath, path); err != nil {
+			failure = fmt.Sprintf("не удалось перенести файл: %v", err)
+		} else {
+			t.report.Path = path
+		}
+	}
+
+	if failure != "" {
+		t.report.Status = models.FileTransferFailed
+		t.report.Error = failure
+		t.report.Path = t.partPath
+		a.logger.Warn("Принятый файл не совпал с манифестом",
+			zap.String("transfer_id", t.report.TransferID),
+			zap.String("name", manifest.Name),
+			zap.String("error", failure))
+		return
+	}
+
+	t.report.Status = models.FileTransferVerified
+	a.logger.Info("Файл принят и проверен",
+		zap.String("transfer_id", t.report.TransferID),
+		zap.String("path", t.report.Path),
+		zap.Int64("size", manifest.Size),
+		zap.Int("chunks", manifest.Chunks))
+}
+
+// verify сверяет размер и хеш файла сборки с манифестом; возвращает описание несовпадения
+func (t *transfer) verify(manifest *models.FileManifest) string {
+	if err := t.file.Sync(); err != nil {
+		return fmt.Sprintf("ошибка записи файла: %v", err)
+	}
+
+	info, err := t.file.Stat()
+	if err != nil {
+		return fmt.Sprintf("ошибка чтения файла: %v", err)
+	}
+	if info.Size() != manifest.Size {
+		return fmt.Sprintf("размер %d не совпадает с манифестом (%d)", info.Size(), manifest.Size)
+	}
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, io.NewSectionReader(t.file, 0, info.Size())); err != nil {
+		return fmt.Sprintf("ошибка чтения файла: %v", err)
+	}
+	t.report.SHA256 = hex.EncodeToString(hasher.Sum(nil))
+	if t.report.SHA256 != manifest.SHA256 {
+		return "хеш файла не совпадает с манифестом"
+	}
+	return ""
+}
+
+// close закрывает файл сборки (вызывается под t.mu)
+func (t *transfer) close() {
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+}
+
+// discard закрывает и удаляет файл сборки передачи, исключенной из учета: незавершенный
+// или не совпавший с манифестом. Проверенный файл уже перенесен и не удаляется
+func (t *transfer) discard() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.close()
+	if t.report.Status != models.FileTransferVerified {
+		os.Remove(t.partPath)
+	}
+}
+
+// fileName возвращает имя файла из манифеста без пути
+func fileName(name string) string {
+	base := filepath.Base(name)
+	if base == "." || base == ".." || base == string(filepath.Separator) {
+		return "file"
+	}
+	return base
+}
+
+// Report возвращает отчет о приеме файла
+func (a *Assembler) Report(id string) (*models.FileTransferReport, bool) {
+	if a == nil {
+		return nil, false
+	}
+
+	a.mu.Lock()
+	t, ok := a.transfers[id]
+	a.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return t.snapshot(), true
+}
+
+// Reports возвращает отчеты по всем отслеживаемым передачам, начиная с последней
+func (a *Assembler) Reports() []*models.FileTransferReport {
+	reports := []*models.FileTransferReport{}
+	if a == nil {
+		return reports
+	}
+
+	a.mu.Lock()
+	transfers := make([]*transfer, 0, len(a.order))
+	for i := len(a.order) - 1; i >= 0; i-- {
+		transfers = append(transfers, a.transfers[a.order[i]])
+	}
+	a.mu.Unlock()
+
+	for _, t := range transfers {
+		reports = append(reports, t.snapshot())
+	}
+	return reports
+}
+
+// snapshot возвращает копию отчета
+func (t *transfer) snapshot() *models.FileTransferReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := t.report
+	if report.Manifest != nil {
+		manifest := *report.Manifest
+		report.Manifest = &manifest
+	}
+	return &report
+}
+
+// Close закрывает файлы незавершенных передач
+func (a *Assembler) Close() error {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, t := range a.transfers {
+		t.mu.Lock()
+		t.close()
+		t.mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package files
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+func newTestAssembler(t *testing.T, maxFileSize int64) *Assembler {
+	t.Helper()
+	a, err := NewAssembler(Config{Directory: t.TempDir(), MaxFileSize: maxFileSize}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewAssembler: %v", err)
+	}
+	return a
+}
+
+func chunkMessage(id string, index int, offset int64, data []byte) *models.Message {
+	sum := sha256.Sum256(data)
+	return &models.Message{
+		Payload: base64.StdEncoding.EncodeToString(data),
+		File: &models.FilePart{
+			TransferID: id,
+			Index:      index,
+			Offset:     offset,
+			SHA256:     hex.EncodeToString(sum[:]),
+		},
+	}
+}
+
+func TestAssemblerEvictionRemovesPartFile(t *testing.T) {
+	a := newTestAssembler(t, 1024)
+
+	for i := 0; i <= maxTransfers; i++ {
+		if err := a.Handle(chunkMessage(fmt.Sprintf("t%d", i), 0, 0, []byte("data"))); err != nil {
+			t.Fatalf("Handle: %v", err)
+		}
+	}
+
+	if _, ok := a.Report("t0"); ok {
+		t.Fatal("старейшая передача не вытеснена")
+	}
+	if _, err := os.Stat(filepath.Join(a.config.Directory, "t0.part")); !os.IsNotExist(err) {
+		t.Errorf("временный файл вытесненной передачи не удален: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(a.config.Directory, "t1.part")); err != nil {
+		t.Errorf("временный файл отслеживаемой передачи: %v", err)
+	}
+}
+
+func TestAssemblerRejectsChunkOutOfBounds(t *testing.T) {
+	a := newTestAssembler(t, 8)
+
+	message := chunkMessage("t", 0, 4, []byte("12345"))
+	if err := a.Handle(message); err == nil {
+		t.Fatal("фрагмент за пределами max_file_size принят")
+	}
+
+	// Хеш не проверяется для фрагмента с некорректными границами
+	message = chunkMessage("t", 1, -1, []byte("1"))
+	message.File.SHA256 = "bad"
+	if err := a.Handle(message); err == nil || err.Error() != "фрагмент 1: некорректное смещение -1 или размер 1" {
+		t.Errorf("ошибка фрагмента: %v", err)
+	}
+
+	report, _ := a.Report("t")
+	if report.ChunkErrors != 2 || report.ChunksReceived != 0 {
+		t.Errorf("chunk_errors %d, chunks_received %d", report.ChunkErrors, report.ChunksReceived)
+	}
+}
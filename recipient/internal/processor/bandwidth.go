@@ -0,0 +1,166 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// bandwidthWindow окно расчета текущей скорости приема по протоколам, секунд
+const bandwidthWindow = 10
+
+// ProtocolBandwidth прием по одному протоколу (каналу приема)
+type ProtocolBandwidth struct {
+	Protocol         string    `json:"protocol"`          // Канал приема (mqtt, tcp, quic, nats, serial)
+	MessagesReceived int64     `json:"messages_received"` // Принято сообщений
+	BytesReceived    int64     `json:"bytes_received"`    // Принято байт
+	MessagesPerSec   float64   `json:"messages_per_sec"`  // Сообщений в секунду за окно
+	BytesPerSec      float64   `json:"bytes_per_sec"`     // Байт в секунду за окно
+	MbitPerSec       float64   `json:"mbit_per_sec"`      // Мбит в секунду за окно
+	LastReceived     time.Time `json:"last_received"`     // Время последнего приема
+}
+
+// BandwidthSnapshot прием по протоколам
+type BandwidthSnapshot struct {
+	WindowSeconds int                 `json:"window_seconds"`
+	Protocols     []ProtocolBandwidth `json:"protocols"`
+}
+
+// bandwidthBucket принятое за одну секунду
+type bandwidthBucket struct {
+	second   int64
+	messages int64
+	bytes    int64
+}
+
+// protocolCounter счетчики и посекундные корзины окна одного протокола
+type protocolCounter struct {
+	messages int64
+	bytes    int64
+	since    int64 // Секунда первого приема: окно до ее истечения неполное
+	last     time.Time
+	buckets  [bandwidthWindow + 1]bandwidthBucket
+}
+
+// bandwidthStats учет объема приема по протоколам. Текущая скорость считается по
+// завершенным секундам окна, поэтому не колеблется внутри текущей секунды
+type bandwidthStats struct {
+	mu        sync.Mutex
+	protocols map[string]*protocolCounter
+}
+
+// newBandwidthStats создает пустой учет приема по протоколам
+func newBandwidthStats() *bandwidthStats {
+	return &bandwidthStats{protocols: make(map[string]*protocolCounter)}
+}
+
+// observe учитывает прием messages сообщений объемом bytes байт по протоколу protocol
+func (b *bandwidthStats) observe(protocol string, messages, bytes int64, now time.Time) {
+	second := now.Unix()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	counter := b.protocols[protocol]
+	if counter == nil {
+		counter = &protocolCounter{since: second}
+		b.protocols[protocol] = counter
+	}
+	counter.messages += messages
+	counter.bytes += bytes
+	counter.last = now
+
+	bucket := &counter.buckets[second%int64(len(counter.buckets))]
+	if bucket.second != second {
+		*bucket = bandwidthBucket{second: second}
+	}
+	bucket.messages += messages
+	bucket.bytes += bytes
+}
+
+// snapshot возвращает прием по протоколам на момент now, упорядоченный по протоколу
+func (b *bandwidthStats) snapshot(now time.Time) BandwidthSnapshot {
+	second := now.Unix()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := BandwidthSnapshot{WindowSeconds: bandwidthWindow, Protocols: make([]ProtocolBandwidth, 0, len(b.protocols))}
+	for protocol, counter := range b.protocols {
+		entry := ProtocolBandwidth{
+			Protocol:         protocol,
+			MessagesReceived: counter.messages,
+			BytesReceived:    counter.bytes,
+			LastReceived:     counter.last,
+		}
+
+		// Завершенные секунды окна, но не раньше первого приема
+		span := min(second-counter.since, bandwidthWindow)
+		if span > 0 {
+			var messages, bytes int64
+			for _, bucket := range counter.buckets {
+				if bucket.second >= second-span && bucket.second < second {
+					messages += bucket.messages
+					bytes += bucket.bytes
+				}
+			}
+			entry.MessagesPerSec = float64(messages) / float64(span)
+			entry.BytesPerSec = float64(bytes) / float64(span)
+			entry.MbitPerSec = entry.BytesPerSec * 8 / 1e6
+		}
+		result.Protocols = append(result.Protocols, entry)
+	}
+	sort.Slice(result.Protocols, func(i, j int) bool { return result.Protocols[i].Protocol < result.Protocols[j].Protocol })
+	return result
+}
+
+// reset очищает учет приема по протоколам
+func (b *bandwidthStats) reset() {
+	b.mu.Lock()
+	b.protocols = make(map[string]*protocolCounter)
+	b.mu.Unlock()
+}
+
+// ObserveReceive учитывает прием по протоколу protocol (канал приема из archive.Source):
+// messages сообщений и bytes байт принятого кадра. Вызывается транспортами
+func (p *MessageProcessor) ObserveReceive(protocol string, messages, bytes int) {
+	p.bandwidth.observe(protocol, int64(messages), int64(bytes), time.Now())
+}
+
+// GetBandwidthStats возвращает объем и текущую скорость приема по протоколам
+func (p *MessageProcessor) GetBandwidthStats() BandwidthSnapshot {
+	return p.bandwidth.snapshot(time.Now())
+}
+
+// WriteBandwidthMetrics выводит объем и скорость приема по протоколам в текстовом формате Prometheus
+func (p *MessageProcessor) WriteBandwidthMetrics(w io.Writer) {
+	protocols := p.bandwidth.snapshot(time.Now()).Protocols
+	if len(protocols) == 0 {
+		return
+	}
+
+	metrics := []struct {
+		name   string
+		help   string
+		kind   string
+		format func(ProtocolBandwidth) string
+	}{
+		{"receive_protocol_messages_total", "Total number of messages received per ingress protocol", "counter",
+			func(s ProtocolBandwidth) string { return fmt.Sprintf("%d", s.MessagesReceived) }},
+		{"receive_protocol_bytes_total", "Total number of bytes received per ingress protocol", "counter",
+			func(s ProtocolBandwidth) string { return fmt.Sprintf("%d", s.BytesReceived) }},
+		{"receive_protocol_messages_per_sec", "Messages per second per ingress protocol over the bandwidth window", "gauge",
+			func(s ProtocolBandwidth) string { return fmt.Sprintf("%.2f", s.MessagesPerSec) }},
+		{"receive_protocol_bytes_per_sec", "Bytes per second per ingress protocol over the bandwidth window", "gauge",
+			func(s ProtocolBandwidth) string { return fmt.Sprintf("%.2f", s.BytesPerSec) }},
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(w, "\n# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+		for _, s := range protocols {
+			fmt.Fprintf(w, "%s{protocol=\"%s\"} %s\n", m.name, s.Protocol, m.format(s))
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package processor
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/infodiode/shared/models"
+)
+
+// distributionStats счетчики записей payload по оборудованию и индикаторам
+type distributionStats struct {
+	mu         sync.Mutex
+	records    int64
+	equipment  map[int]int64
+	indicators map[int]int64
+}
+
+// newDistributionStats создает пустые счетчики распределения
+func newDistributionStats() *distributionStats {
+	return &distributionStats{
+		equipment:  make(map[int]int64),
+		indicators: make(map[int]int64),
+	}
+}
+
+// record учитывает записи одного payload
+func (d *distributionStats) record(records []*models.Data) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, data := range records {
+		d.records++
+		d.equipment[data.EquipmentID]++
+		d.indicators[data.IndicatorID]++
+	}
+}
+
+// reset обнуляет счетчики
+func (d *distributionStats) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.records = 0
+	d.equipment = make(map[int]int64)
+	d.indicators = make(map[int]int64)
+}
+
+// DistributionEntry количество записей для одного идентификатора
+type DistributionEntry struct {
+	ID      int     `json:"id"`
+	Count   int64   `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// DistributionBreakdown распределение записей по одному измерению
+type DistributionBreakdown struct {
+	Unique int                 `json:"unique"`
+	Top    []DistributionEntry `json:"top"`
+}
+
+// DistributionSnapshot снимок распределения трафика по оборудованию и индикаторам
+type DistributionSnapshot struct {
+	Records         int64                 `json:"records"`
+	PayloadErrors   int64                 `json:"payload_errors"`
+	IntegrityErrors int64                 `json:"integrity_errors"`
+	Equipment       DistributionBreakdown `json:"equipment"`
+	Indicators      DistributionBreakdown `json:"indicators"`
+}
+
+// snapshot возвращает top-N идентификаторов по каждому измерению
+func (d *distributionStats) snapshot(topN int) DistributionSnapshot {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return DistributionSnapshot{
+		Records:    d.records,
+		Equipment:  topEntries(d.equipment, d.records, topN),
+		Indicators: topEntries(d.indicators, d.records, topN),
+	}
+}
+
+// topEntries сортирует счетчики по убыванию и оставляет первые topN (0 - все)
+func topEntries(counts map[int]int64, total int64, topN int) DistributionBreakdown {
+	entries := make([]DistributionEntry, 0, len(counts))
+	for id, count := range counts {
+		entry := DistributionEntry{ID: id, Count: count}
+		if total > 0 {
+			entry.Percent = float64(count) / float64(total) * 100
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count == entries[j].Count {
+			return entries[i].ID < entries[j].ID
+		}
+		return entries[i].Count > entries[j].Count
+	})
+
+	if topN > 0 && len(entries) > topN {
+		entries = entries[:topN]
+	}
+
+	return DistributionBreakdown{Unique: len(counts), Top: entries}
+}
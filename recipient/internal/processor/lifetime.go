@@ -0,0 +1,171 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/infodiode/shared/utils"
+	"go.uber.org/zap"
+)
+
+// LifetimeStats накопленные счетчики обработчика за все запуски recipient с начала
+// накопления: счетчики предыдущих процессов, сохраненные в файл, и текущего процесса
+type LifetimeStats struct {
+	Since              time.Time `json:"since"`    // Начало накопления (первый запуск или сброс статистики)
+	Restarts           int       `json:"restarts"` // Перезапусков с начала накопления
+	MessagesReceived   int64     `json:"messages_received"`
+	MessagesProcessed  int64     `json:"messages_processed"`
+	MessagesValid      int64     `json:"messages_valid"`
+	MessagesInvalid    int64     `json:"messages_invalid"`
+	ChecksumErrors     int64     `json:"checksum_errors"`
+	ProcessingErrors   int64     `json:"processing_errors"`
+	PayloadErrors      int64     `json:"payload_errors"`
+	IntegrityErrors    int64     `json:"integrity_errors"`
+	MessagesStale      int64     `json:"messages_stale"`
+	TotalBytesReceived int64     `json:"total_bytes_received"`
+	TotalLatencyUs     int64     `json:"total_latency_us"` // Сумма задержек обработанных сообщений, мкс
+	MinLatencyUs       int64     `json:"min_latency_us"`
+	MaxLatencyUs       int64     `json:"max_latency_us"`
+	AvgLatency         float64   `json:"avg_latency_ms"`
+	FirstMessageTime   time.Time `json:"first_message_time"`
+	LastMessageTime    time.Time `json:"last_message_time"`
+}
+
+// lifetimeStats счетчики предыдущих процессов, к которым добавляются счетчики текущего
+type lifetimeStats struct {
+	mu   sync.Mutex
+	base LifetimeStats
+}
+
+// RestoreLifetime включает накопление счетчиков между перезапусками: загружает счетчики
+// предыдущих процессов из path. Отсутствующий файл начинает накопление заново.
+// Вызывается до начала приема
+func (p *MessageProcessor) RestoreLifetime(path string) error {
+	lifetime := &lifetimeStats{base: LifetimeStats{Since: time.Now()}}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		p.logger.Info("Накопление статистики начато", zap.String("file", path))
+	case err != nil:
+		return fmt.Errorf("ошибка чтения файла статистики: %w", err)
+	default:
+		if err := json.Unmarshal(data, &lifetime.base); err != nil {
+			return fmt.Errorf("ошибка разбора файла статистики %s: %w", path, err)
+		}
+		lifetime.base.Restarts++
+		lifetime.base.AvgLatency = 0
+		p.logger.Info("Накопленная статистика загружена",
+			zap.String("file", path),
+			zap.Time("since", lifetime.base.Since),
+			zap.Int("restarts", lifetime.base.Restarts),
+			zap.Int64("messages_received", lifetime.base.MessagesReceived))
+	}
+
+	p.lifetime = lifetime
+	return nil
+}
+
+// SaveLifetime сохраняет накопленные счетчики в path; без накопления ничего не делает
+func (p *MessageProcessor) SaveLifetime(path string) error {
+	stats, ok := p.GetLifetimeStats()
+	if !ok {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("ошибка записи файла статистики: %w", err)
+	}
+	return nil
+}
+
+// GetLifetimeStats возвращает накопленные счетчики с учетом текущего процесса;
+// false, если накопление не включено
+func (p *MessageProcessor) GetLifetimeStats() (LifetimeStats, bool) {
+	if p.lifetime == nil {
+		return LifetimeStats{}, false
+	}
+
+	p.lifetime.mu.Lock()
+	total := p.lifetime.base
+	p.lifetime.mu.Unlock()
+
+	stats := p.stats.Load()
+	total.MessagesReceived += stats.MessagesReceived.Load()
+	total.MessagesProcessed += stats.MessagesProcessed.Load()
+	total.MessagesValid += stats.MessagesValid.Load()
+	total.MessagesInvalid += stats.MessagesInvalid.Load()
+	total.ChecksumErrors += stats.ChecksumErrors.Load()
+	total.ProcessingErrors += stats.ProcessingErrors.Load()
+	total.PayloadErrors += stats.PayloadErrors.Load()
+	total.IntegrityErrors += stats.IntegrityErrors.Load()
+	total.MessagesStale += stats.MessagesStale.Load()
+	total.TotalBytesReceived += stats.TotalBytesReceived.Load()
+	total.TotalLatencyUs += stats.TotalLatency.Load()
+
+	if minLatency := stats.MinLatency.Load(); minLatency > 0 && (total.MinLatencyUs == 0 || minLatency < total.MinLatencyUs) {
+		total.MinLatencyUs = minLatency
+	}
+	total.MaxLatencyUs = max(total.MaxLatencyUs, stats.MaxLatency.Load())
+	if first, _ := stats.FirstMessageTime.Load().(time.Time); !first.IsZero() && total.FirstMessageTime.IsZero() {
+		total.FirstMessageTime = first
+	}
+	if last, _ := stats.LastMessageTime.Load().(time.Time); last.After(total.LastMessageTime) {
+		total.LastMessageTime = last
+	}
+	if total.MessagesProcessed > 0 {
+		total.AvgLatency = float64(total.TotalLatencyUs) / float64(total.MessagesProcessed) / 1000.0
+	}
+
+	return total, true
+}
+
+// resetLifetime начинает накопление заново (при сбросе статистики)
+func (p *MessageProcessor) resetLifetime() {
+	if p.lifetime == nil {
+		return
+	}
+
+	p.lifetime.mu.Lock()
+	p.lifetime.base = LifetimeStats{Since: time.Now()}
+	p.lifetime.mu.Unlock()
+}
+
+// WriteLifetimeMetrics выводит накопленные за все запуски счетчики в текстовом формате
+// Prometheus; без накопления ничего не выводит
+func (p *MessageProcessor) WriteLifetimeMetrics(w io.Writer) {
+	stats, ok := p.GetLifetimeStats()
+	if !ok {
+		return
+	}
+
+	counters := []struct {
+		name  string
+		help  string
+		value int64
+	}{
+		{"lifetime_messages_received_total", "Total number of messages received across restarts", stats.MessagesReceived},
+		{"lifetime_messages_valid_total", "Total number of valid messages across restarts", stats.MessagesValid},
+		{"lifetime_messages_invalid_total", "Total number of invalid messages across restarts", stats.MessagesInvalid},
+		{"lifetime_bytes_received_total", "Total number of bytes received across restarts", stats.TotalBytesReceived},
+	}
+	for _, c := range counters {
+		fmt.Fprintf(w, "\n# HELP %s %s\n", c.name, c.help)
+		fmt.Fprintf(w, "# TYPE %s counter\n", c.name)
+		fmt.Fprintf(w, "%s %d\n", c.name, c.value)
+	}
+
+	fmt.Fprintf(w, "\n# HELP lifetime_restarts Number of restarts since lifetime statistics started\n")
+	fmt.Fprintf(w, "# TYPE lifetime_restarts gauge\n")
+	fmt.Fprintf(w, "lifetime_restarts %d\n", stats.Restarts)
+}
@@ -0,0 +1,253 @@
+package processor
+
+import (
+	"bufio"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Параметры журнала сообщений по умолчанию
+const (
+	DefaultMessageLogQueueSize = 10000
+)
+
+// MessageLogConfig конфигурация журнала сообщений
+type MessageLogConfig struct {
+	QueueSize   int // Размер очереди записей; при заполненной очереди записи отбрасываются
+	SampleEvery int // Записывать каждое N-е сообщение с верной контрольной суммой (1 - все)
+
+	// Отдельный файл журнала в формате JSONL (строка - models.LogEntry) с ротацией.
+	// Пустой FilePath - запись в основной лог сервиса
+	FilePath   string
+	MaxSize    int // megabytes
+	MaxBackups int
+	MaxAge     int // days
+	Compress   bool
+}
+
+// MessageLogStats статистика журнала сообщений
+type MessageLogStats struct {
+	Queued     int   `json:"queued"`
+	QueueSize  int   `json:"queue_size"`
+	Written    int64 `json:"written"`
+	SampledOut int64 `json:"sampled_out"`
+	Dropped    int64 `json:"dropped_log_entries"`
+
+	File        string `json:"file,omitempty"` // Отдельный файл журнала (пусто - основной лог)
+	WriteErrors int64  `json:"write_errors"`   // Записей, которые не удалось записать в файл
+}
+
+// MessageLogger записывает в лог каждое полученное сообщение. Записи передаются
+// через очередь фоновой горутине, поэтому обработка не ждет записи в лог: при
+// заполненной очереди запись отбрасывается и учитывается в Dropped.
+// Сообщения с ошибкой контрольной суммы записываются всегда, остальные - с
+// прореживанием SampleEvery. При заданном FilePath записи пишутся не в основной лог,
+// а в отдельный файл JSONL. Методы nil *MessageLogger ничего не делают
+type MessageLogger struct {
+	logger      *zap.Logger
+	file        *lumberjack.Logger // Отдельный файл журнала (nil - основной лог)
+	buffer      *bufio.Writer
+	writeErrors atomic.Int64
+	entries     chan models.LogEntry
+	sampleEvery int64
+	counter     atomic.Int64
+	written     atomic.Int64
+	sampledOut  atomic.Int64
+	dropped     atomic.Int64
+	closed      atomic.Bool
+	closeOnce   sync.Once
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewMessageLogger создает журнал сообщений и запускает запись
+func NewMessageLogger(logger *zap.Logger, cfg MessageLogConfig) *MessageLogger {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultMessageLogQueueSize
+	}
+	if cfg.SampleEvery < 1 {
+		cfg.SampleEvery = 1
+	}
+
+	l := &MessageLogger{
+		logger:      logger,
+		entries:     make(chan models.LogEntry, cfg.QueueSize),
+		sampleEvery: int64(cfg.SampleEvery),
+		stopChan:    make(chan struct{}),
+	}
+	if cfg.FilePath != "" {
+		l.file = &lumberjack.Logger{
+			Filename:   cfg.FilePath,
+			MaxSize:    cfg.MaxSize,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAge,
+			Compress:   cfg.Compress,
+			LocalTime:  true,
+		}
+		l.buffer = bufio.NewWriterSize(l.file, 64*1024)
+	}
+
+	l.wg.Add(1)
+	go l.run()
+
+	return l
+}
+
+// Log ставит запись в очередь без ожидания
+func (l *MessageLogger) Log(entry models.LogEntry) {
+	if l == nil || l.closed.Load() {
+		return
+	}
+
+	valid := entry.ChecksumValid == nil || *entry.ChecksumValid
+	if valid && l.sampleEvery > 1 && l.counter.Add(1)%l.sampleEvery != 0 {
+		l.sampledOut.Add(1)
+		return
+	}
+
+	select {
+	case l.entries <- entry:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+// run записывает записи из очереди до остановки, затем дописывает оставшиеся.
+// Буфер файла сбрасывается, когда очередь опустела
+func (l *MessageLogger) run() {
+	defer l.wg.Done()
+	defer l.closeFile()
+
+	for {
+		select {
+		case entry := <-l.entries:
+			l.write(entry)
+			if len(l.entries) == 0 {
+				l.flush()
+			}
+		case <-l.stopChan:
+			for {
+				select {
+				case entry := <-l.entries:
+					l.write(entry)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write записывает запись в отдельный файл или через zap logger (который настроен на
+// запись в файл)
+func (l *MessageLogger) write(entry models.LogEntry) {
+	if l.buffer != nil {
+		l.writeFile(entry)
+		return
+	}
+
+	fields := []zap.Field{
+		zap.Int("message_id", entry.MessageID),
+		zap.String("send_time", entry.SendTime),
+		zap.String("receive_time", entry.ReceiveTime),
+		zap.String("checksum", entry.Checksum),
+		zap.Int("message_size", entry.MessageSize),
+	}
+	if entry.ChecksumValid != nil {
+		fields = append(fields, zap.Bool("checksum_valid", *entry.ChecksumValid))
+	}
+	if entry.Error != "" {
+		fields = append(fields, zap.String("error", entry.Error))
+	}
+
+	l.logger.Info("Сообщение получено", fields...)
+	l.written.Add(1)
+}
+
+// writeFile дописывает запись строкой JSON в буфер отдельного файла
+func (l *MessageLogger) writeFile(entry models.LogEntry) {
+	data, err := json.Marshal(entry)
+	if err == nil {
+		data = append(data, '\n')
+		_, err = l.buffer.Write(data)
+	}
+	if err != nil {
+		l.writeError(err)
+		return
+	}
+	l.written.Add(1)
+}
+
+// flush сбрасывает буфер отдельного файла
+func (l *MessageLogger) flush() {
+	if l.buffer == nil {
+		return
+	}
+	if err := l.buffer.Flush(); err != nil {
+		l.writeError(err)
+		// bufio.Writer после ошибки отклоняет запись; начинаем буфер заново
+		l.buffer.Reset(l.file)
+	}
+}
+
+// writeError учитывает ошибку записи файла; в лог выводится первая ошибка
+func (l *MessageLogger) writeError(err error) {
+	if l.writeErrors.Add(1) == 1 {
+		l.logger.Error("Ошибка записи журнала сообщений",
+			zap.String("file", l.file.Filename),
+			zap.Error(err))
+	}
+}
+
+// closeFile сбрасывает буфер и закрывает отдельный файл
+func (l *MessageLogger) closeFile() {
+	if l.file == nil {
+		return
+	}
+	l.flush()
+	if err := l.file.Close(); err != nil {
+		l.logger.Warn("Ошибка закрытия журнала сообщений", zap.Error(err))
+	}
+}
+
+// Stats возвращает статистику журнала сообщений
+func (l *MessageLogger) Stats() MessageLogStats {
+	if l == nil {
+		return MessageLogStats{}
+	}
+	return MessageLogStats{
+		Queued:     len(l.entries),
+		QueueSize:  cap(l.entries),
+		Written:    l.written.Load(),
+		SampledOut: l.sampledOut.Load(),
+		Dropped:    l.dropped.Load(),
+
+		File:        l.fileName(),
+		WriteErrors: l.writeErrors.Load(),
+	}
+}
+
+// fileName возвращает путь отдельного файла журнала
+func (l *MessageLogger) fileName() string {
+	if l.file == nil {
+		return ""
+	}
+	return l.file.Filename
+}
+
+// Close прекращает прием записей и дописывает очередь
+func (l *MessageLogger) Close() {
+	if l == nil {
+		return
+	}
+	l.closeOnce.Do(func() {
+		l.closed.Store(true)
+		close(l.stopChan)
+		l.wg.Wait()
+	})
+}
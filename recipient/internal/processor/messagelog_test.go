@@ -0,0 +1,134 @@
+package processor
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// gateWriter задерживает запись в лог до закрытия release; started закрывается при
+// первой записи, чтобы тест знал, что горутина журнала занята
+type gateWriter struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+
+	mu    sync.Mutex
+	lines []string
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.release
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+func (w *gateWriter) Sync() error { return nil }
+
+func (w *gateWriter) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.lines)
+}
+
+func TestMessageLoggerQueueOverflow(t *testing.T) {
+	const queueSize = 4
+	writer := newGateWriter()
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), writer, zapcore.InfoLevel)
+	l := NewMessageLogger(zap.New(core), MessageLogConfig{QueueSize: queueSize, SampleEvery: 1})
+
+	// Первая запись занимает горутину журнала, следующие заполняют очередь и отбрасываются
+	l.Log(models.LogEntry{MessageID: 0})
+	<-writer.started
+	for i := 1; i <= queueSize+3; i++ {
+		l.Log(models.LogEntry{MessageID: i})
+	}
+
+	stats := l.Stats()
+	if stats.Queued != queueSize || stats.Dropped != 3 || stats.Written != 0 {
+		t.Fatalf("при заполненной очереди: queued=%d dropped=%d written=%d, ожидалось %d, 3, 0",
+			stats.Queued, stats.Dropped, stats.Written, queueSize)
+	}
+
+	// Close дописывает записи, оставшиеся в очереди
+	close(writer.release)
+	l.Close()
+
+	stats = l.Stats()
+	if stats.Written != queueSize+1 || stats.Queued != 0 || stats.Dropped != 3 || stats.SampledOut != 0 {
+		t.Errorf("после Close: written=%d queued=%d dropped=%d sampled_out=%d, ожидалось %d, 0, 3, 0",
+			stats.Written, stats.Queued, stats.Dropped, stats.SampledOut, queueSize+1)
+	}
+	if lines := writer.count(); lines != queueSize+1 {
+		t.Errorf("записано строк %d, ожидалось %d", lines, queueSize+1)
+	}
+
+	// После закрытия записи не принимаются
+	l.Log(models.LogEntry{MessageID: 100})
+	if stats := l.Stats(); stats.Written != queueSize+1 || stats.Dropped != 3 {
+		t.Errorf("запись после Close учтена: written=%d dropped=%d", stats.Written, stats.Dropped)
+	}
+}
+
+func TestMessageLoggerSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+	l := NewMessageLogger(zap.NewNop(), MessageLogConfig{QueueSize: 100, SampleEvery: 3, FilePath: path})
+
+	valid, invalid := true, false
+	for i := 1; i <= 9; i++ {
+		l.Log(models.LogEntry{MessageID: i, ChecksumValid: &valid})
+	}
+	// Сообщения с ошибкой контрольной суммы записываются без прореживания
+	l.Log(models.LogEntry{MessageID: 10, ChecksumValid: &invalid})
+	l.Log(models.LogEntry{MessageID: 11, ChecksumValid: &invalid})
+	l.Close()
+
+	stats := l.Stats()
+	if stats.Written != 5 || stats.SampledOut != 6 || stats.Dropped != 0 || stats.WriteErrors != 0 {
+		t.Errorf("written=%d sampled_out=%d dropped=%d write_errors=%d, ожидалось 5, 6, 0, 0",
+			stats.Written, stats.SampledOut, stats.Dropped, stats.WriteErrors)
+	}
+	if stats.File != path {
+		t.Errorf("file = %q, ожидалось %q", stats.File, path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var ids []int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry models.LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("некорректная строка журнала %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, entry.MessageID)
+	}
+	expected := []int{3, 6, 9, 10, 11}
+	if len(ids) != len(expected) {
+		t.Fatalf("в файле записи %v, ожидалось %v", ids, expected)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("в файле записи %v, ожидалось %v", ids, expected)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// maxOrderStreams количество отслеживаемых потоков (давно неактивные вытесняются)
+	maxOrderStreams = 1000
+	// maxOrderExamples количество хранимых примеров нарушения порядка (последние)
+	maxOrderExamples = 20
+)
+
+// orderKey поток сообщений одного теста: канал, соединение или топик и тест
+type orderKey struct {
+	channel string
+	stream  string
+	testID  string
+}
+
+// orderState наибольший номер, полученный в потоке
+type orderState struct {
+	last     int64
+	lastSeen time.Time
+}
+
+// OrderExample пример сообщения, пришедшего не по порядку
+type OrderExample struct {
+	Channel  string    `json:"channel"`  // Канал приема (mqtt, tcp, quic, nats, serial)
+	Stream   string    `json:"stream"`   // Топик, соединение или поток канала
+	TestID   string    `json:"test_id"`  // Тест
+	Sequence int64     `json:"sequence"` // Номер сообщения, пришедшего не по порядку
+	Previous int64     `json:"previous"` // Наибольший номер, полученный в потоке до него
+	Time     time.Time `json:"time"`     // Время получения
+}
+
+// orderTracker проверяет, что номера сообщений теста возрастают в пределах одного
+// потока приема (соединения TCP, потока QUIC, топика MQTT, субъекта NATS, порта)
+type orderTracker struct {
+	mu         sync.Mutex
+	streams    map[orderKey]*orderState
+	checked    map[string]int64 // Проверенные сообщения по каналам
+	outOfOrder map[string]int64 // Нарушения порядка по каналам
+	examples   []OrderExample   // Последние примеры, начиная с самого старого
+}
+
+// newOrderTracker создает пустую проверку порядка
+func newOrderTracker() *orderTracker {
+	return &orderTracker{
+		streams:    make(map[orderKey]*orderState),
+		checked:    make(map[string]int64),
+		outOfOrder: make(map[string]int64),
+	}
+}
+
+// observe учитывает номер сообщения в потоке. Номер меньше наибольшего полученного
+// в потоке - нарушение порядка; повтор наибольшего номера считается дубликатом
+func (t *orderTracker) observe(channel, stream, testID string, sequence int64, now time.Time) {
+	if testID == "" || sequence <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.checked[channel]++
+
+	key := orderKey{channel: channel, stream: stream, testID: testID}
+	s, ok := t.streams[key]
+	if !ok {
+		if len(t.streams) >= maxOrderStreams {
+			t.evictOldest()
+		}
+		s = &orderState{}
+		t.streams[key] = s
+	}
+	s.lastSeen = now
+
+	if sequence >= s.last {
+		s.last = sequence
+		return
+	}
+
+	t.outOfOrder[channel]++
+	if len(t.examples) >= maxOrderExamples {
+		t.examples = append(t.examples[:0], t.examples[1:]...)
+	}
+	t.examples = append(t.examples, OrderExample{
+		Channel:  channel,
+		Stream:   stream,
+		TestID:   testID,
+		Sequence: sequence,
+		Previous: s.last,
+		Time:     now,
+	})
+}
+
+// evictOldest удаляет поток, сообщения которого получены раньше остальных
+func (t *orderTracker) evictOldest() {
+	var oldest orderKey
+	var oldestSeen time.Time
+	for key, s := range t.streams {
+		if oldestSeen.IsZero() || s.lastSeen.Before(oldestSeen) {
+			oldest, oldestSeen = key, s.lastSeen
+		}
+	}
+	delete(t.streams, oldest)
+}
+
+// reset очищает проверку порядка
+func (t *orderTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.streams = make(map[orderKey]*orderState)
+	t.checked = make(map[string]int64)
+	t.outOfOrder = make(map[string]int64)
+	t.examples = nil
+}
+
+// OrderChannelStats проверка порядка сообщений одного канала
+type OrderChannelStats struct {
+	Channel    string `json:"channel"`
+	Checked    int64  `json:"checked"`      // Проверено сообщений с номерами
+	OutOfOrder int64  `json:"out_of_order"` // Сообщений с номером меньше уже полученного в потоке
+}
+
+// OrderingSnapshot результаты проверки порядка сообщений
+type OrderingSnapshot struct {
+	Streams  int                 `json:"streams"`  // Отслеживаемые потоки
+	Channels []OrderChannelStats `json:"channels"` // По каналам в алфавитном порядке
+	Examples []OrderExample      `json:"examples"` // Последние нарушения, начиная с последнего
+}
+
+// snapshot возвращает результаты проверки порядка
+func (t *orderTracker) snapshot() OrderingSnapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot := OrderingSnapshot{
+		Streams:  len(t.streams),
+		Channels: make([]OrderChannelStats, 0, len(t.checked)),
+		Examples: make([]OrderExample, 0, len(t.examples)),
+	}
+	for channel, checked := range t.checked {
+		snapshot.Channels = append(snapshot.Channels, OrderChannelStats{
+			Channel:    channel,
+			Checked:    checked,
+			OutOfOrder: t.outOfOrder[channel],
+		})
+	}
+	sort.Slice(snapshot.Channels, func(i, j int) bool {
+		return snapshot.Channels[i].Channel < snapshot.Channels[j].Channel
+	})
+	for i := len(t.examples) - 1; i >= 0; i-- {
+		snapshot.Examples = append(snapshot.Examples, t.examples[i])
+	}
+	return snapshot
+}
+
+// ObserveOrder проверяет порядок номера sequence сообщения теста testID в потоке stream
+// канала channel. Вызывается приемником в порядке получения сообщений, до их
+// параллельной обработки
+func (p *MessageProcessor) ObserveOrder(channel, stream, testID string, sequence int64) {
+	p.ordering.observe(channel, stream, testID, sequence, time.Now())
+}
+
+// GetOrderingStats возвращает результаты проверки порядка сообщений
+func (p *MessageProcessor) GetOrderingStats() OrderingSnapshot {
+	return p.ordering.snapshot()
+}
+
+// WriteOrderingMetrics выводит результаты проверки порядка по каналам в текстовом
+// формате Prometheus
+func (p *MessageProcessor) WriteOrderingMetrics(w io.Writer) {
+	channels := p.ordering.snapshot().Channels
+	if len(channels) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n# HELP ordering_checked_total Total number of sequenced messages checked for per-stream ordering\n")
+	fmt.Fprintf(w, "# TYPE ordering_checked_total counter\n")
+	for _, c := range channels {
+		fmt.Fprintf(w, "ordering_checked_total{channel=%q} %d\n", c.Channel, c.Checked)
+	}
+
+	fmt.Fprintf(w, "\n# HELP out_of_order_total Total number of messages received with a sequence lower than already received in the same stream\n")
+	fmt.Fprintf(w, "# TYPE out_of_order_total counter\n")
+	for _, c := range channels {
+		fmt.Fprintf(w, "out_of_order_total{channel=%q} %d\n", c.Channel, c.OutOfOrder)
+	}
+}
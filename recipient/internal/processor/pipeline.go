@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"io"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
+)
+
+// Phase этап обработки сообщения
+type Phase int
+
+// Этапы обработки сообщения
+const (
+	PhaseDecode      Phase = iota // Разбор JSON сообщения приемником канала
+	PhaseValidation               // Проверка контрольной суммы и записей payload
+	PhaseChecksum                 // Вычисление и сравнение контрольной суммы (входит в validation)
+	PhasePersistence              // Журнал сообщений, хранилище результатов и запись частей файлов
+	PhaseHandler                  // Обработка целиком: от передачи в обработчик до учета в статистике
+	phaseCount
+)
+
+// phaseNames названия этапов в /stats и метриках
+var phaseNames = [phaseCount]string{"decode", "validation", "checksum", "persistence", "handler"}
+
+// phaseHistogram длительности этапа: логарифмическая гистограмма для перцентилей
+// в /stats и гистограмма Prometheus
+type phaseHistogram struct {
+	stats   utils.JitterHistogram
+	metrics utils.LatencyHistogram
+}
+
+// pipelineStats длительности этапов обработки
+type pipelineStats [phaseCount]phaseHistogram
+
+// observe учитывает длительность этапа
+func (p *pipelineStats) observe(phase Phase, d time.Duration) {
+	p[phase].stats.Observe(d)
+	p[phase].metrics.Observe(float64(d.Microseconds()) / 1000.0)
+}
+
+// PipelineSnapshot длительности этапов обработки; этап без измерений не выводится
+type PipelineSnapshot struct {
+	Decode      *models.HopLatency `json:"decode,omitempty"`
+	Validation  *models.HopLatency `json:"validation,omitempty"`
+	Checksum    *models.HopLatency `json:"checksum,omitempty"`
+	Persistence *models.HopLatency `json:"persistence,omitempty"`
+	Handler     *models.HopLatency `json:"handler,omitempty"`
+}
+
+// snapshot возвращает длительности этапов
+func (p *pipelineStats) snapshot() PipelineSnapshot {
+	return PipelineSnapshot{
+		Decode:      p[PhaseDecode].stats.HopLatency(),
+		Validation:  p[PhaseValidation].stats.HopLatency(),
+		Checksum:    p[PhaseChecksum].stats.HopLatency(),
+		Persistence: p[PhasePersistence].stats.HopLatency(),
+		Handler:     p[PhaseHandler].stats.HopLatency(),
+	}
+}
+
+// ObserveDecode учитывает длительность разбора сообщения приемником канала
+func (p *MessageProcessor) ObserveDecode(d time.Duration) {
+	p.stats.Load().Pipeline.observe(PhaseDecode, d)
+}
+
+// WritePipelineMetrics выводит гистограммы этапов обработки в текстовом формате Prometheus
+// (processing_phase_<этап>_ms)
+func (p *MessageProcessor) WritePipelineMetrics(w io.Writer) {
+	pipeline := &p.stats.Load().Pipeline
+	for phase := range phaseCount {
+		pipeline[phase].metrics.WriteText(w, "processing_phase_"+phaseNames[phase]+"_ms",
+			"Message processing phase "+phaseNames[phase]+" duration in milliseconds")
+	}
+}
+
+// WritePipelineProto выводит гистограммы этапов обработки в формате protobuf с разделителями
+func (p *MessageProcessor) WritePipelineProto(w io.Writer) error {
+	pipeline := &p.stats.Load().Pipeline
+	for phase := range phaseCount {
+		err := pipeline[phase].metrics.WriteProto(w, "processing_phase_"+phaseNames[phase]+"_ms",
+			"Message processing phase "+phaseNames[phase]+" duration in milliseconds")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,13 +1,16 @@
 package processor
 
 import (
-	"encoding/json"
 	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/infodiode/recipient/internal/demux"
+	"github.com/infodiode/recipient/internal/files"
+	"github.com/infodiode/recipient/internal/store"
 	"github.com/infodiode/recipient/internal/validator"
+	"github.com/infodiode/shared/correlation"
 	"github.com/infodiode/shared/models"
 	"github.com/infodiode/shared/utils"
 	"go.uber.org/zap"
@@ -15,15 +18,39 @@ import (
 
 // MessageProcessor обрабатывает входящие сообщения
 type MessageProcessor struct {
-	logger     *zap.Logger
-	validator  *validator.ChecksumValidator
-	messageLog *MessageLogger
-	stats      *ProcessorStats
-	mu         sync.RWMutex
-	stopChan   chan struct{}
-	wg         sync.WaitGroup
+	logger      *zap.Logger
+	validator   *validator.ChecksumValidator
+	messageLog  *MessageLogger                 // Журнал сообщений, nil если отключен
+	stats       atomic.Pointer[ProcessorStats] // Заменяется целиком при сбросе статистики
+	dist        *distributionStats
+	sessions    *sessionTracker
+	tenants     *tenantStats
+	ordering    *orderTracker
+	bandwidth   *bandwidthStats
+	window      *utils.WindowCounters             // Посекундные показатели приема для /stats?window; не сбрасываются
+	lifetime    *lifetimeStats                    // Накопление счетчиков между перезапусками, nil если отключено
+	store       *store.Store                      // Хранилище результатов, nil если отключено
+	files       *files.Assembler                  // Сборщик файлов, nil если отключен
+	demux       *demux.Writer                     // Запись проверенных записей по файлам оборудования, nil если отключена
+	correlation *correlation.Writer               // Журнал корреляции полученных сообщений, nil если отключен
+	messageTTL  atomic.Int64                      // Срок актуальности сообщений без ttl_ms, миллисекунды (0 - не проверять)
+	monotonic   atomic.Bool                       // Вычислять задержку по send_time монотонных часов хоста
+	schemaWarn  atomic.Bool                       // Предупреждение о send_time без задержки уже выведено
+	profile     atomic.Pointer[validator.Profile] // Набор проверок сообщений
+	lag         atomic.Pointer[LagObserver]
+	latency     atomic.Pointer[LatencyObserver]
+	running     atomic.Bool
+	mu          sync.RWMutex
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
 }
 
+// LagObserver получает задержку сообщения от получения до конца обработки
+type LagObserver func(lag time.Duration)
+
+// LatencyObserver получает задержку доставки сообщения от send_time до получения, ms
+type LatencyObserver func(latencyMs float64)
+
 // ProcessorStats статистика обработчика
 type ProcessorStats struct {
 	MessagesReceived   atomic.Int64
@@ -32,96 +59,203 @@ type ProcessorStats struct {
 	MessagesInvalid    atomic.Int64
 	ChecksumErrors     atomic.Int64
 	ProcessingErrors   atomic.Int64
+	PayloadErrors      atomic.Int64
+	IntegrityErrors    atomic.Int64
+	MessagesStale      atomic.Int64
 	TotalBytesReceived atomic.Int64
 	LastMessageTime    atomic.Value // time.Time
 	FirstMessageTime   atomic.Value // time.Time
 	MinLatency         atomic.Int64 // microseconds
 	MaxLatency         atomic.Int64 // microseconds
 	TotalLatency       atomic.Int64 // microseconds
-}
-
-// MessageLogger логирует сообщения в файл
-type MessageLogger struct {
-	logger *zap.Logger
-	mu     sync.Mutex
+	Latency            utils.LatencyHistogram
+	Pipeline           pipelineStats
 }
 
 // NewMessageProcessor создает новый обработчик сообщений
 func NewMessageProcessor(logger *zap.Logger) *MessageProcessor {
-	return &MessageProcessor{
+	p := &MessageProcessor{
 		logger:     logger,
 		validator:  validator.NewChecksumValidator(logger),
-		messageLog: &MessageLogger{logger: logger},
-		stats:      &ProcessorStats{},
+		messageLog: NewMessageLogger(logger, MessageLogConfig{}),
+		dist:       newDistributionStats(),
+		sessions:   newSessionTracker(),
+		tenants:    newTenantStats(),
+		ordering:   newOrderTracker(),
+		bandwidth:  newBandwidthStats(),
+		window:     utils.NewWindowCounters(),
 		stopChan:   make(chan struct{}),
 	}
+	p.stats.Store(&ProcessorStats{})
+	p.SetProfile(validator.ProfileStrict)
+	return p
 }
 
 // ProcessMessage обрабатывает одно сообщение
 func (p *MessageProcessor) ProcessMessage(message *models.Message) error {
+	return p.ProcessMessageWithSize(message, -1)
+}
+
+// ProcessMessageWithSize обрабатывает сообщение с известным размером на линии;
+// при size < 0 размер вычисляется повторной сериализацией сообщения
+func (p *MessageProcessor) ProcessMessageWithSize(message *models.Message, size int) error {
+	return p.ProcessReceivedMessage(message, size, time.Now())
+}
+
+// ProcessReceivedMessage обрабатывает сообщение, полученное в момент receivedAt;
+// используется при воспроизведении архива, чтобы задержка считалась по исходному времени получения
+func (p *MessageProcessor) ProcessReceivedMessage(message *models.Message, size int, receivedAt time.Time) error {
 	startTime := time.Now()
-	receiveTime := utils.GetCurrentTime()
+	if observe := p.lag.Load(); observe != nil {
+		defer func() { (*observe)(time.Since(receivedAt)) }()
+	}
 
-	// Обновляем счетчик полученных сообщений
-	p.stats.MessagesReceived.Add(1)
+	// Сообщение целиком учитывается в статистике, действовавшей при его получении:
+	// сброс во время обработки не смешивает счетчики старой и новой статистики
+	stats := p.stats.Load()
 
-	// Обновляем время первого сообщения
-	if p.stats.MessagesReceived.Load() == 1 {
-		p.stats.FirstMessageTime.Store(startTime)
+	// Обновляем счетчик полученных сообщений и время первого сообщения
+	if stats.MessagesReceived.Add(1) == 1 {
+		stats.FirstMessageTime.Store(receivedAt)
 	}
-	p.stats.LastMessageTime.Store(startTime)
+	stats.LastMessageTime.Store(receivedAt)
+
+	// Метки команды и прогона; некорректные значения заменяются, чтобы не нарушать формат метрик
+	tenant, runLabel := normalizeLabel(message.Tenant), normalizeLabel(message.RunLabel)
+
+	// Учитываем порядковый номер для проверки полноты доставки
+	p.sessions.record(message.TestID, tenant, runLabel, message.Sequence, message.Attempt, receivedAt)
+	p.correlation.Write(correlation.Record{
+		TestID:    message.TestID,
+		MessageID: message.MessageID,
+		Sequence:  message.Sequence,
+		Time:      receivedAt,
+		Checksum:  message.Checksum,
+	})
 
 	// Размер сообщения
-	messageBytes, err := json.Marshal(message)
-	if err != nil {
-		p.stats.ProcessingErrors.Add(1)
-		return fmt.Errorf("ошибка сериализации сообщения: %w", err)
+	messageSize := size
+	if messageSize < 0 {
+		messageSize = len(message.AppendJSON(nil))
+	}
+	stats.TotalBytesReceived.Add(int64(messageSize))
+
+	record := store.MessageRecord{
+		TestID:     message.TestID,
+		Sequence:   message.Sequence,
+		MessageID:  message.MessageID,
+		ReceivedAt: receivedAt,
+		Size:       messageSize,
 	}
-	messageSize := len(messageBytes)
-	p.stats.TotalBytesReceived.Add(int64(messageSize))
 
-	// Валидация контрольной суммы
-	isValid, err := p.validator.ValidateMessage(message)
+	// Валидация контрольной суммы; время записи в журнал и хранилище внутри проверки
+	// учитывается в persistence и исключается из validation
+	validationStart := time.Now()
+	var persistence time.Duration
+	profile := p.Profile()
+	isValid, err := true, error(nil)
+	if profile.Checksum() {
+		isValid, err = p.validator.ValidateMessage(message)
+	}
+	stats.Pipeline.observe(PhaseChecksum, time.Since(validationStart))
 	if err != nil {
-		p.stats.ProcessingErrors.Add(1)
+		stats.ProcessingErrors.Add(1)
 		p.logger.Error("Ошибка валидации сообщения",
 			zap.Int("message_id", message.MessageID),
 			zap.Error(err))
 	}
 
 	if !isValid {
-		p.stats.MessagesInvalid.Add(1)
-		p.stats.ChecksumErrors.Add(1)
+		stats.MessagesInvalid.Add(1)
+		stats.ChecksumErrors.Add(1)
 
 		// Логируем сообщение с ошибкой контрольной суммы
-		p.logMessage(message, receiveTime, messageSize, false)
+		persistence += p.timeLogMessage(message, receivedAt, messageSize, false)
+		record.Error = "несовпадение контрольной суммы"
 
 		p.logger.Warn("Несовпадение контрольной суммы",
 			zap.Int("message_id", message.MessageID),
 			zap.String("expected", message.Checksum),
 			zap.String("actual", utils.CalculateChecksumString(message.Payload)))
 	} else {
-		p.stats.MessagesValid.Add(1)
+		stats.MessagesValid.Add(1)
 
 		// Логируем валидное сообщение
-		p.logMessage(message, receiveTime, messageSize, true)
+		persistence += p.timeLogMessage(message, receivedAt, messageSize, true)
+
+		if message.File != nil {
+			// Часть файла теста передачи файлов: payload не содержит записей телеметрии
+			fileStart := time.Now()
+			record.Error = p.recordFilePart(stats, message)
+			persistence += time.Since(fileStart)
+		} else if profile.Payload() {
+			// Разбираем payload для статистики по оборудованию и индикаторам;
+			// прошедшие проверку записи раскладываются по файлам оборудования
+			var records []*models.Data
+			records, record.Error = p.recordPayload(stats, message, profile.Integrity())
+			demuxStart := time.Now()
+			p.demux.Write(records)
+			persistence += time.Since(demuxStart)
+		}
+		record.Valid = record.Error == ""
 	}
+	stats.Pipeline.observe(PhaseValidation, time.Since(validationStart)-persistence)
 
-	// Вычисляем задержку
-	if message.SendTime != "" {
-		latency, err := utils.CalculateLatency(message.SendTime, receiveTime)
+	if record.Error != "" {
+		p.sessions.recordInvalid(message.TestID, receivedAt)
+	}
+
+	// Вычисляем задержку приема
+	var sent time.Time
+	if message.SendTime != "" && p.acceptSchema(message) {
+		var err error
+		sent, err = utils.ParseSendTime(message)
 		if err == nil {
+			latency := float64(receivedAt.Sub(sent).Microseconds()) / 1000.0
 			latencyMicros := int64(latency * 1000)
-			p.stats.TotalLatency.Add(latencyMicros)
-			p.updateMinMaxLatency(latencyMicros)
+			stats.TotalLatency.Add(latencyMicros)
+			stats.updateMinMaxLatency(latencyMicros)
+			stats.Latency.Observe(latency)
+			if observe := p.latency.Load(); observe != nil {
+				(*observe)(latency)
+			}
+			record.LatencyMs = &latency
+			record.Stale = p.checkStale(stats, message, latency)
+			p.window.ObserveLatency(receivedAt, receivedAt.Sub(sent))
 		}
 	}
 
+	var invalid int64
+	if record.Error != "" {
+		invalid = 1
+	}
+	p.window.Add(receivedAt, 1, int64(messageSize), invalid)
+
+	p.tenants.record(tenantMessage{
+		tenant:    tenant,
+		runLabel:  runLabel,
+		size:      messageSize,
+		valid:     record.Error == "",
+		stale:     record.Stale,
+		latencyMs: record.LatencyMs,
+		at:        receivedAt,
+	})
+
+	storeStart := time.Now()
+	p.store.RecordMessage(record)
+	stats.Pipeline.observe(PhasePersistence, persistence+time.Since(storeStart))
+
+	// Задержка по участкам и джиттер приема; обработка учитывается до этого момента
+	if !sent.IsZero() {
+		p.sessions.recordTransit(message.TestID, sent, receivedAt, time.Since(startTime))
+	}
+
 	// Обновляем счетчик обработанных сообщений
-	p.stats.MessagesProcessed.Add(1)
+	stats.MessagesProcessed.Add(1)
 
 	// Логируем время обработки если оно слишком большое
 	processingTime := time.Since(startTime)
+	stats.Pipeline.observe(PhaseHandler, processingTime)
 	if processingTime > 100*time.Millisecond {
 		p.logger.Warn("Долгая обработка сообщения",
 			zap.Int("message_id", message.MessageID),
@@ -131,17 +265,252 @@ func (p *MessageProcessor) ProcessMessage(message *models.Message) error {
 	return nil
 }
 
-// logMessage логирует сообщение в файл
-func (p *MessageProcessor) logMessage(message *models.Message, receiveTime string, size int, checksumValid bool) {
-	p.messageLog.mu.Lock()
-	defer p.messageLog.mu.Unlock()
+// checkStale учитывает сообщение, задержка которого превысила срок актуальности:
+// для телеметрии такое сообщение равнозначно потерянному
+func (p *MessageProcessor) checkStale(stats *ProcessorStats, message *models.Message, latencyMs float64) bool {
+	ttl := message.TTL
+	if ttl <= 0 {
+		ttl = p.messageTTL.Load()
+	}
+	if ttl <= 0 || latencyMs <= float64(ttl) {
+		return false
+	}
+
+	stats.MessagesStale.Add(1)
+	p.sessions.recordStale(message.TestID)
+	p.logger.Debug("Сообщение получено позже срока актуальности",
+		zap.Int("message_id", message.MessageID),
+		zap.Float64("latency_ms", latencyMs),
+		zap.Int64("ttl_ms", ttl))
+	return true
+}
+
+// SetMessageTTL задает срок актуальности для сообщений без собственного ttl_ms (0 - не проверять)
+func (p *MessageProcessor) SetMessageTTL(ttl time.Duration) {
+	p.messageTTL.Store(ttl.Milliseconds())
+}
+
+// SetLagObserver задает получателя задержек обработки (nil - не передавать)
+func (p *MessageProcessor) SetLagObserver(observer LagObserver) {
+	if observer == nil {
+		p.lag.Store(nil)
+		return
+	}
+	p.lag.Store(&observer)
+}
+
+// SetLatencyObserver задает получателя задержек доставки (nil - не передавать)
+func (p *MessageProcessor) SetLatencyObserver(observer LatencyObserver) {
+	if observer == nil {
+		p.latency.Store(nil)
+		return
+	}
+	p.latency.Store(&observer)
+}
+
+// SetValidation задает профиль проверки и правила проверки записей payload. Без
+// разбора payload (профили off и checksum) сообщения не учитываются в распределении
+func (p *MessageProcessor) SetValidation(profile validator.Profile, rules validator.Rules) {
+	p.validator.SetRules(rules)
+	p.SetProfile(profile)
+}
+
+// SetProfile переключает профиль проверки; действует для сообщений, обработка
+// которых начинается после переключения
+func (p *MessageProcessor) SetProfile(profile validator.Profile) {
+	p.profile.Store(&profile)
+}
+
+// Profile возвращает действующий профиль проверки
+func (p *MessageProcessor) Profile() validator.Profile {
+	return *p.profile.Load()
+}
+
+// ValidationRules возвращает действующие правила проверки записей payload
+func (p *MessageProcessor) ValidationRules() validator.Rules {
+	return p.validator.Rules()
+}
+
+// recordPayload разбирает payload и учитывает записи в распределении; при integrity
+// дополнительно проверяет диапазоны и формат значений записей.
+// Возвращает записи, прошедшие проверку, и описание первой найденной ошибки
+// (пусто, если payload корректен)
+func (p *MessageProcessor) recordPayload(stats *ProcessorStats, message *models.Message, integrity bool) ([]*models.Data, string) {
+	if p.validator.Rules().Binary != nil {
+		return nil, p.recordBinaryPayload(stats, message, integrity)
+	}
+
+	records, err := p.validator.ParsePayload(message)
+	if err != nil {
+		stats.PayloadErrors.Add(1)
+		p.logger.Debug("Некорректный payload",
+			zap.Int("message_id", message.MessageID),
+			zap.Error(err))
+		return nil, fmt.Sprintf("некорректный payload: %v", err)
+	}
+
+	if !integrity {
+		p.dist.record(records)
+		return records, ""
+	}
+
+	// Проверяем целостность каждой записи; в распределении учитываются только корректные
+	var failure string
+	valid := records[:0]
+	for _, data := range records {
+		if err := p.validator.ValidateDataIntegrity(data); err != nil {
+			stats.IntegrityErrors.Add(1)
+			p.logger.Debug("Нарушена целостность записи",
+				zap.Int("message_id", message.MessageID),
+				zap.Int("record_id", data.ID),
+				zap.Error(err))
+			if failure == "" {
+				failure = fmt.Sprintf("нарушена целостность записи %d: %v", data.ID, err)
+			}
+			continue
+		}
+		valid = append(valid, data)
+	}
+
+	p.dist.record(valid)
+	return valid, failure
+}
+
+// recordBinaryPayload проверяет двоичные записи payload по раскладке validation.binary:
+// декодирование и размер, при integrity - также значения полей и контрольную сумму.
+// Двоичные записи не учитываются в распределении и не раскладываются по оборудованию.
+// Возвращает описание первой найденной ошибки (пусто, если payload корректен)
+func (p *MessageProcessor) recordBinaryPayload(stats *ProcessorStats, message *models.Message, integrity bool) string {
+	records, err := p.validator.ParseBinaryPayload(message)
+	if err != nil {
+		stats.PayloadErrors.Add(1)
+		p.logger.Debug("Некорректный payload",
+			zap.Int("message_id", message.MessageID),
+			zap.Error(err))
+		return fmt.Sprintf("некорректный payload: %v", err)
+	}
+
+	if !integrity {
+		return ""
+	}
+
+	var failure string
+	for i, record := range records {
+		if err := p.validator.ValidateBinaryRecord(record); err != nil {
+			stats.IntegrityErrors.Add(1)
+			p.logger.Debug("Нарушена целостность двоичной записи",
+				zap.Int("message_id", message.MessageID),
+				zap.Int("record", i),
+				zap.Error(err))
+			if failure == "" {
+				failure = fmt.Sprintf("нарушена целостность двоичной записи %d: %v", i, err)
+			}
+		}
+	}
+
+	return failure
+}
+
+// recordFilePart передает часть файла сборщику.
+// Возвращает описание ошибки (пусто, если часть принята)
+func (p *MessageProcessor) recordFilePart(stats *ProcessorStats, message *models.Message) string {
+	if p.files == nil {
+		return ""
+	}
+
+	if err := p.files.Handle(message); err != nil {
+		stats.PayloadErrors.Add(1)
+		p.logger.Debug("Часть файла не принята",
+			zap.Int("message_id", message.MessageID),
+			zap.String("transfer_id", message.File.TransferID),
+			zap.Error(err))
+		return err.Error()
+	}
+	return ""
+}
+
+// GetDistribution возвращает top-N распределение записей по оборудованию и индикаторам
+func (p *MessageProcessor) GetDistribution(topN int) DistributionSnapshot {
+	snapshot := p.dist.snapshot(topN)
+	stats := p.stats.Load()
+	snapshot.PayloadErrors = stats.PayloadErrors.Load()
+	snapshot.IntegrityErrors = stats.IntegrityErrors.Load()
+	return snapshot
+}
+
+// SetStore задает хранилище результатов обработки отдельных сообщений
+func (p *MessageProcessor) SetStore(s *store.Store) {
+	p.store = s
+}
+
+// SetMessageLog заменяет журнал сообщений (nil - не записывать сообщения в лог);
+// прежний журнал закрывается. Вызывается до начала приема
+func (p *MessageProcessor) SetMessageLog(l *MessageLogger) {
+	p.messageLog.Close()
+	p.messageLog = l
+}
+
+// SetCorrelation задает журнал корреляции полученных сообщений
+func (p *MessageProcessor) SetCorrelation(w *correlation.Writer) {
+	p.correlation = w
+}
+
+// SetFiles задает сборщик файлов теста передачи файлов
+func (p *MessageProcessor) SetFiles(a *files.Assembler) {
+	p.files = a
+}
+
+// SetDemux задает запись проверенных записей payload по файлам оборудования
+func (p *MessageProcessor) SetDemux(w *demux.Writer) {
+	p.demux = w
+}
+
+// GetSessionReport возвращает отчет о полноте доставки сообщений теста
+func (p *MessageProcessor) GetSessionReport(testID string) (*models.SessionReport, bool) {
+	return p.sessions.report(testID)
+}
+
+// GetSessionTimeline возвращает посекундную динамику приема сообщений теста
+func (p *MessageProcessor) GetSessionTimeline(testID string) ([]models.ReceivePoint, bool) {
+	return p.sessions.timeline(testID)
+}
+
+// GetSessionReports возвращает отчеты по всем отслеживаемым тестам
+func (p *MessageProcessor) GetSessionReports() []*models.SessionReport {
+	return p.sessions.reports()
+}
+
+// GetWindowStats возвращает показатели приема за последние window: сообщения, байты,
+// некорректные сообщения и задержку доставки. Сброс статистики на них не влияет
+func (p *MessageProcessor) GetWindowStats(window time.Duration) models.WindowStats {
+	return p.window.Stats(window)
+}
+
+// GetAuditDigests возвращает сводки для канала аудита по тестам, сообщения которых
+// получены не раньше since
+func (p *MessageProcessor) GetAuditDigests(since time.Time) []models.AuditTestDigest {
+	return p.sessions.auditDigests(since)
+}
+
+// timeLogMessage логирует сообщение и возвращает длительность записи в журнал
+func (p *MessageProcessor) timeLogMessage(message *models.Message, receivedAt time.Time, size int, checksumValid bool) time.Duration {
+	start := time.Now()
+	p.logMessage(message, receivedAt, size, checksumValid)
+	return time.Since(start)
+}
+
+// logMessage ставит запись о сообщении в очередь журнала сообщений
+func (p *MessageProcessor) logMessage(message *models.Message, receivedAt time.Time, size int, checksumValid bool) {
+	if p.messageLog == nil {
+		return
+	}
 
 	// Создаем запись лога
 	logEntry := models.LogEntry{
 		Timestamp:     time.Now(),
 		MessageID:     message.MessageID,
 		SendTime:      message.SendTime,
-		ReceiveTime:   receiveTime,
+		ReceiveTime:   receivedAt.Format(utils.TimeFormat),
 		Checksum:      message.Checksum,
 		ChecksumValid: &checksumValid,
 		MessageSize:   size,
@@ -152,30 +521,16 @@ func (p *MessageProcessor) logMessage(message *models.Message, receiveTime strin
 		logEntry.Error = "Checksum mismatch"
 	}
 
-	// Логируем через zap logger (который настроен на запись в файл)
-	fields := []zap.Field{
-		zap.Int("message_id", logEntry.MessageID),
-		zap.String("send_time", logEntry.SendTime),
-		zap.String("receive_time", logEntry.ReceiveTime),
-		zap.String("checksum", logEntry.Checksum),
-		zap.Bool("checksum_valid", checksumValid),
-		zap.Int("message_size", logEntry.MessageSize),
-	}
-
-	if logEntry.Error != "" {
-		fields = append(fields, zap.String("error", logEntry.Error))
-	}
-
-	p.messageLog.logger.Info("Сообщение получено", fields...)
+	p.messageLog.Log(logEntry)
 }
 
 // updateMinMaxLatency обновляет минимальную и максимальную задержку
-func (p *MessageProcessor) updateMinMaxLatency(latencyMicros int64) {
+func (s *ProcessorStats) updateMinMaxLatency(latencyMicros int64) {
 	// Обновляем минимальную задержку
 	for {
-		oldMin := p.stats.MinLatency.Load()
+		oldMin := s.MinLatency.Load()
 		if oldMin == 0 || latencyMicros < oldMin {
-			if p.stats.MinLatency.CompareAndSwap(oldMin, latencyMicros) {
+			if s.MinLatency.CompareAndSwap(oldMin, latencyMicros) {
 				break
 			}
 		} else {
@@ -185,9 +540,9 @@ func (p *MessageProcessor) updateMinMaxLatency(latencyMicros int64) {
 
 	// Обновляем максимальную задержку
 	for {
-		oldMax := p.stats.MaxLatency.Load()
+		oldMax := s.MaxLatency.Load()
 		if latencyMicros > oldMax {
-			if p.stats.MaxLatency.CompareAndSwap(oldMax, latencyMicros) {
+			if s.MaxLatency.CompareAndSwap(oldMax, latencyMicros) {
 				break
 			}
 		} else {
@@ -196,16 +551,25 @@ func (p *MessageProcessor) updateMinMaxLatency(latencyMicros int64) {
 	}
 }
 
+// LatencyHistogram возвращает гистограмму задержек приема с последнего сброса статистики
+func (p *MessageProcessor) LatencyHistogram() *utils.LatencyHistogram {
+	return &p.stats.Load().Latency
+}
+
 // GetStats возвращает статистику обработчика
 func (p *MessageProcessor) GetStats() ProcessorStatsSnapshot {
-	received := p.stats.MessagesReceived.Load()
-	processed := p.stats.MessagesProcessed.Load()
-	valid := p.stats.MessagesValid.Load()
-	invalid := p.stats.MessagesInvalid.Load()
-	checksumErrors := p.stats.ChecksumErrors.Load()
-	processingErrors := p.stats.ProcessingErrors.Load()
-	totalBytes := p.stats.TotalBytesReceived.Load()
-	totalLatency := p.stats.TotalLatency.Load()
+	stats := p.stats.Load()
+	received := stats.MessagesReceived.Load()
+	processed := stats.MessagesProcessed.Load()
+	valid := stats.MessagesValid.Load()
+	invalid := stats.MessagesInvalid.Load()
+	checksumErrors := stats.ChecksumErrors.Load()
+	processingErrors := stats.ProcessingErrors.Load()
+	payloadErrors := stats.PayloadErrors.Load()
+	integrityErrors := stats.IntegrityErrors.Load()
+	stale := stats.MessagesStale.Load()
+	totalBytes := stats.TotalBytesReceived.Load()
+	totalLatency := stats.TotalLatency.Load()
 
 	// Вычисляем средние значения
 	var avgLatency float64
@@ -218,8 +582,8 @@ func (p *MessageProcessor) GetStats() ProcessorStatsSnapshot {
 	}
 
 	// Вычисляем пропускную способность
-	firstTime, _ := p.stats.FirstMessageTime.Load().(time.Time)
-	lastTime, _ := p.stats.LastMessageTime.Load().(time.Time)
+	firstTime, _ := stats.FirstMessageTime.Load().(time.Time)
+	lastTime, _ := stats.LastMessageTime.Load().(time.Time)
 	if !firstTime.IsZero() && !lastTime.IsZero() {
 		duration := lastTime.Sub(firstTime).Seconds()
 		if duration > 0 {
@@ -234,14 +598,19 @@ func (p *MessageProcessor) GetStats() ProcessorStatsSnapshot {
 		MessagesInvalid:    invalid,
 		ChecksumErrors:     checksumErrors,
 		ProcessingErrors:   processingErrors,
+		PayloadErrors:      payloadErrors,
+		IntegrityErrors:    integrityErrors,
+		MessagesStale:      stale,
 		TotalBytesReceived: totalBytes,
 		AvgMessageSize:     avgMessageSize,
-		MinLatency:         float64(p.stats.MinLatency.Load()) / 1000.0, // ms
-		MaxLatency:         float64(p.stats.MaxLatency.Load()) / 1000.0, // ms
+		MinLatency:         float64(stats.MinLatency.Load()) / 1000.0, // ms
+		MaxLatency:         float64(stats.MaxLatency.Load()) / 1000.0, // ms
 		AvgLatency:         avgLatency,
 		Throughput:         throughput,
 		FirstMessageTime:   firstTime,
 		LastMessageTime:    lastTime,
+		Pipeline:           stats.Pipeline.snapshot(),
+		MessageLog:         p.messageLog.Stats(),
 	}
 }
 
@@ -253,6 +622,9 @@ type ProcessorStatsSnapshot struct {
 	MessagesInvalid    int64
 	ChecksumErrors     int64
 	ProcessingErrors   int64
+	PayloadErrors      int64
+	IntegrityErrors    int64
+	MessagesStale      int64
 	TotalBytesReceived int64
 	AvgMessageSize     int64
 	MinLatency         float64 // ms
@@ -261,24 +633,41 @@ type ProcessorStatsSnapshot struct {
 	Throughput         float64 // msg/sec
 	FirstMessageTime   time.Time
 	LastMessageTime    time.Time
+	Pipeline           PipelineSnapshot // Длительности этапов обработки
+	MessageLog         MessageLogStats  // Очередь журнала сообщений (не сбрасывается со статистикой)
 }
 
-// ResetStats сбрасывает статистику
+// ResetStats сбрасывает статистику. Счетчики заменяются новыми целиком, поэтому
+// сообщения, обрабатываемые в момент сброса, дописываются в прежнюю статистику
 func (p *MessageProcessor) ResetStats() {
-	p.stats = &ProcessorStats{}
+	p.stats.Store(&ProcessorStats{})
+	p.dist.reset()
+	p.sessions.reset()
+	p.tenants.reset()
+	p.ordering.reset()
+	p.bandwidth.reset()
+	p.resetLifetime()
 	p.logger.Info("Статистика обработчика сброшена")
 }
 
 // Start запускает обработчик (для будущих расширений)
 func (p *MessageProcessor) Start() error {
+	p.running.Store(true)
 	p.logger.Info("Обработчик сообщений запущен")
 	return nil
 }
 
+// IsRunning проверяет, запущен ли обработчик
+func (p *MessageProcessor) IsRunning() bool {
+	return p.running.Load()
+}
+
 // Stop останавливает обработчик
 func (p *MessageProcessor) Stop() error {
+	p.running.Store(false)
 	close(p.stopChan)
 	p.wg.Wait()
+	p.messageLog.Close()
 
 	// Выводим финальную статистику
 	stats := p.GetStats()
@@ -0,0 +1,306 @@
+package processor
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
+)
+
+const (
+	// maxSessions количество отслеживаемых тестов (старые вытесняются)
+	maxSessions = 100
+	// maxMissingRanges количество диапазонов пропусков в отчете
+	maxMissingRanges = 100
+	// maxTrackedSequence предельный номер сообщения (ограничивает размер битовой карты)
+	maxTrackedSequence = 1 << 27
+	// maxRetriedSequences количество запоминаемых номеров повторно отправленных сообщений теста
+	maxRetriedSequences = 1 << 16
+)
+
+// session учет порядковых номеров сообщений одного теста
+type session struct {
+	tenant      string // Метки команды и прогона из первого сообщения теста
+	runLabel    string
+	seen        []uint64 // Битовая карта полученных номеров
+	digest      uint64   // Дайджест уникальных номеров для канала аудита
+	received    int64
+	unique      int64
+	duplicates  int64
+	retryDups   int64              // Повторы из-за повторной отправки sender (attempt > 0)
+	retried     map[int64]struct{} // Номера, первая полученная копия которых - повторная попытка
+	outOfOrder  int64
+	stale       int64
+	maxSequence int64
+	firstSeen   time.Time
+	lastSeen    time.Time
+	timeline    receiveTimeline // Посекундная динамика приема
+
+	// Джиттер: изменение времени доставки между последовательно полученными сообщениями
+	jitter      *utils.JitterHistogram
+	lastSent    time.Time
+	lastArrival time.Time
+
+	// Задержка по участкам: от отправки до получения и обработка recipient
+	delivery   *utils.JitterHistogram
+	processing *utils.JitterHistogram
+}
+
+// sessionTracker отслеживает полноту доставки сообщений по тестам
+type sessionTracker struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+	order    []string
+}
+
+// newSessionTracker создает пустой учет сессий
+func newSessionTracker() *sessionTracker {
+	return &sessionTracker{
+		sessions: make(map[string]*session),
+	}
+}
+
+// record учитывает сообщение с номером sequence из теста testID с метками tenant и runLabel;
+// attempt - номер повторной попытки отправки сообщения (0 - первая)
+func (t *sessionTracker) record(testID, tenant, runLabel string, sequence int64, attempt int, now time.Time) {
+	if testID == "" || sequence <= 0 || sequence > maxTrackedSequence {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.sessions[testID]
+	if !ok {
+		s = &session{
+			tenant:     tenant,
+			runLabel:   runLabel,
+			firstSeen:  now,
+			jitter:     utils.NewJitterHistogram(),
+			delivery:   utils.NewJitterHistogram(),
+			processing: utils.NewJitterHistogram(),
+		}
+		t.sessions[testID] = s
+		t.order = append(t.order, testID)
+		if len(t.order) > maxSessions {
+			delete(t.sessions, t.order[0])
+			t.order = t.order[1:]
+		}
+	}
+
+	s.received++
+	s.lastSeen = now
+	s.timeline.add(now, 1, 0)
+
+	index := (sequence - 1) / 64
+	bit := uint64(1) << uint((sequence-1)%64)
+	for int64(len(s.seen)) <= index {
+		s.seen = append(s.seen, 0)
+	}
+
+	if s.seen[index]&bit != 0 {
+		s.duplicates++
+		// Повтор отправки, если повторной попыткой была эта или первая полученная копия;
+		// иначе сообщение повторно доставил брокер или транспорт
+		if _, ok := s.retried[sequence]; ok || attempt > 0 {
+			s.retryDups++
+		}
+		return
+	}
+	s.seen[index] |= bit
+	if attempt > 0 {
+		if s.retried == nil {
+			s.retried = make(map[int64]struct{})
+		}
+		if len(s.retried) < maxRetriedSequences {
+			s.retried[sequence] = struct{}{}
+		}
+	}
+	s.unique++
+	s.digest ^= utils.SequenceHash(sequence)
+
+	if sequence < s.maxSequence {
+		s.outOfOrder++
+	} else {
+		s.maxSequence = sequence
+	}
+}
+
+// recordTransit учитывает задержку и джиттер приема сообщения теста, отправленного в момент
+// sent, полученного в момент received и обработанного за processing. Джиттер - отклонение
+// интервала между приходом соседних сообщений от интервала между их отправкой (RFC 3550),
+// не зависит от расхождения часов хостов, в отличие от задержки доставки
+func (t *sessionTracker) recordTransit(testID string, sent, received time.Time, processing time.Duration) {
+	if testID == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.sessions[testID]
+	if !ok {
+		return
+	}
+
+	if !s.lastArrival.IsZero() {
+		s.jitter.Observe(received.Sub(s.lastArrival) - sent.Sub(s.lastSent))
+	}
+	s.lastSent = sent
+	s.lastArrival = received
+
+	// Отрицательная задержка возможна только при расхождении часов и учитывается как нулевая
+	s.delivery.Observe(max(received.Sub(sent), 0))
+	s.processing.Observe(processing)
+}
+
+// recordStale учитывает сообщение теста, полученное позже срока актуальности
+func (t *sessionTracker) recordStale(testID string) {
+	if testID == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if s, ok := t.sessions[testID]; ok {
+		s.stale++
+	}
+}
+
+// recordInvalid учитывает в динамике приема сообщение теста, не прошедшее проверку
+func (t *sessionTracker) recordInvalid(testID string, now time.Time) {
+	if testID == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if s, ok := t.sessions[testID]; ok {
+		s.timeline.add(now, 0, 1)
+	}
+}
+
+// timeline возвращает посекундную динамику приема сообщений теста
+func (t *sessionTracker) timeline(testID string) ([]models.ReceivePoint, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.sessions[testID]
+	if !ok {
+		return nil, false
+	}
+	return s.timeline.snapshot(), true
+}
+
+// report формирует отчет по тесту
+func (t *sessionTracker) report(testID string) (*models.SessionReport, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.sessions[testID]
+	if !ok {
+		return nil, false
+	}
+
+	report := &models.SessionReport{
+		TestID:          testID,
+		Tenant:          s.tenant,
+		RunLabel:        s.runLabel,
+		Received:        s.received,
+		Unique:          s.unique,
+		Duplicates:      s.duplicates,
+		RetryDuplicates: s.retryDups,
+		MaxSequence:     s.maxSequence,
+		Missing:         s.maxSequence - s.unique,
+		MissingRanges:   []models.SequenceRange{},
+		OutOfOrder:      s.outOfOrder,
+		Stale:           s.stale,
+		FirstSeen:       s.firstSeen,
+		LastSeen:        s.lastSeen,
+	}
+	if jitter := s.jitter.Stats(); jitter.Samples > 0 {
+		report.Jitter = &jitter
+	}
+	if delivery := s.delivery.HopLatency(); delivery != nil {
+		report.Latency = &models.LatencyBreakdown{
+			Delivery:   delivery,
+			Processing: s.processing.HopLatency(),
+		}
+	}
+
+	// Собираем диапазоны пропущенных номеров
+	var current *models.SequenceRange
+	for seq := int64(1); seq <= s.maxSequence && len(report.MissingRanges) <= maxMissingRanges; seq++ {
+		index := (seq - 1) / 64
+		if s.seen[index]&(uint64(1)<<uint((seq-1)%64)) != 0 {
+			current = nil
+			continue
+		}
+		if current != nil && current.To == seq-1 {
+			current.To = seq
+			continue
+		}
+		report.MissingRanges = append(report.MissingRanges, models.SequenceRange{From: seq, To: seq})
+		current = &report.MissingRanges[len(report.MissingRanges)-1]
+	}
+	if len(report.MissingRanges) > maxMissingRanges {
+		report.MissingRanges = report.MissingRanges[:maxMissingRanges]
+	}
+
+	return report, true
+}
+
+// auditDigests возвращает сводки по тестам, сообщения которых получены не раньше since
+func (t *sessionTracker) auditDigests(since time.Time) []models.AuditTestDigest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	digests := []models.AuditTestDigest{}
+	for _, id := range t.order {
+		s := t.sessions[id]
+		if s.lastSeen.Before(since) {
+			continue
+		}
+		digests = append(digests, models.AuditTestDigest{
+			TestID:      id,
+			Received:    s.received,
+			Unique:      s.unique,
+			MaxSequence: s.maxSequence,
+			Digest:      utils.FormatDigest(s.digest),
+			LastSeen:    s.lastSeen,
+		})
+	}
+	return digests
+}
+
+// reports возвращает отчеты по всем отслеживаемым тестам, начиная с последнего
+func (t *sessionTracker) reports() []*models.SessionReport {
+	t.mu.Lock()
+	ids := append([]string(nil), t.order...)
+	t.mu.Unlock()
+
+	reports := make([]*models.SessionReport, 0, len(ids))
+	for _, id := range ids {
+		if report, ok := t.report(id); ok {
+			reports = append(reports, report)
+		}
+	}
+
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].LastSeen.After(reports[j].LastSeen)
+	})
+	return reports
+}
+
+// reset очищает учет сессий
+func (t *sessionTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sessions = make(map[string]*session)
+	t.order = nil
+}
@@ -0,0 +1,225 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/infodiode/shared/models"
+)
+
+const (
+	// maxTenantLabels количество отслеживаемых наборов меток (давно неактивные вытесняются)
+	maxTenantLabels = 100
+	// invalidLabel значение, которым заменяется некорректная метка сообщения
+	invalidLabel = "invalid"
+)
+
+// tenantKey набор меток команды и прогона
+type tenantKey struct {
+	tenant   string
+	runLabel string
+}
+
+// tenantCounters показатели приема сообщений одного набора меток
+type tenantCounters struct {
+	received     int64
+	valid        int64
+	invalid      int64
+	stale        int64
+	bytes        int64
+	latencySum   float64 // ms
+	latencyCount int64
+	lastSeen     time.Time
+}
+
+// tenantStats показатели приема по командам и прогонам (метки tenant и run_label
+// сообщений). Сообщения без меток не учитываются
+type tenantStats struct {
+	mu     sync.Mutex
+	labels map[tenantKey]*tenantCounters
+}
+
+// newTenantStats создает пустой учет по командам
+func newTenantStats() *tenantStats {
+	return &tenantStats{labels: make(map[tenantKey]*tenantCounters)}
+}
+
+// normalizeLabel заменяет некорректное значение метки, чтобы сообщения сторонних
+// отправителей не нарушали формат метрик
+func normalizeLabel(value string) string {
+	if models.ValidateLabel("", value) != nil {
+		return invalidLabel
+	}
+	return value
+}
+
+// tenantMessage результат обработки сообщения для учета по командам
+type tenantMessage struct {
+	tenant    string
+	runLabel  string
+	size      int
+	valid     bool
+	stale     bool
+	latencyMs *float64
+	at        time.Time
+}
+
+// record учитывает обработанное сообщение
+func (t *tenantStats) record(m tenantMessage) {
+	if m.tenant == "" && m.runLabel == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := tenantKey{tenant: m.tenant, runLabel: m.runLabel}
+	c, ok := t.labels[key]
+	if !ok {
+		if len(t.labels) >= maxTenantLabels {
+			t.evictOldest()
+		}
+		c = &tenantCounters{}
+		t.labels[key] = c
+	}
+
+	c.received++
+	c.bytes += int64(m.size)
+	if m.valid {
+		c.valid++
+	} else {
+		c.invalid++
+	}
+	if m.stale {
+		c.stale++
+	}
+	if m.latencyMs != nil {
+		c.latencySum += *m.latencyMs
+		c.latencyCount++
+	}
+	c.lastSeen = m.at
+}
+
+// evictOldest удаляет набор меток, сообщения которого получены раньше остальных
+func (t *tenantStats) evictOldest() {
+	var oldest tenantKey
+	var oldestSeen time.Time
+	for key, c := range t.labels {
+		if oldestSeen.IsZero() || c.lastSeen.Before(oldestSeen) {
+			oldest, oldestSeen = key, c.lastSeen
+		}
+	}
+	delete(t.labels, oldest)
+}
+
+// reset очищает учет по командам
+func (t *tenantStats) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.labels = make(map[tenantKey]*tenantCounters)
+}
+
+// TenantSnapshot показатели приема сообщений одной команды и прогона
+type TenantSnapshot struct {
+	Tenant           string    `json:"tenant"`
+	RunLabel         string    `json:"run_label"`
+	MessagesReceived int64     `json:"messages_received"`
+	MessagesValid    int64     `json:"messages_valid"`
+	MessagesInvalid  int64     `json:"messages_invalid"`
+	MessagesStale    int64     `json:"messages_stale"`
+	BytesReceived    int64     `json:"bytes_received"`
+	AvgLatency       float64   `json:"avg_latency_ms"`
+	LastMessageTime  time.Time `json:"last_message_time"`
+
+	latencySum   float64 // Сумма и количество задержек для метрик Prometheus
+	latencyCount int64
+}
+
+// snapshot возвращает показатели, упорядоченные по команде и метке прогона
+func (t *tenantStats) snapshot() []TenantSnapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	list := make([]TenantSnapshot, 0, len(t.labels))
+	for key, c := range t.labels {
+		s := TenantSnapshot{
+			Tenant:           key.tenant,
+			RunLabel:         key.runLabel,
+			MessagesReceived: c.received,
+			MessagesValid:    c.valid,
+			MessagesInvalid:  c.invalid,
+			MessagesStale:    c.stale,
+			BytesReceived:    c.bytes,
+			latencySum:       c.latencySum,
+			latencyCount:     c.latencyCount,
+			LastMessageTime:  c.lastSeen,
+		}
+		if c.latencyCount > 0 {
+			s.AvgLatency = c.latencySum / float64(c.latencyCount)
+		}
+		list = append(list, s)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Tenant == list[j].Tenant {
+			return list[i].RunLabel < list[j].RunLabel
+		}
+		return list[i].Tenant < list[j].Tenant
+	})
+	return list
+}
+
+// WriteTenantMetrics выводит показатели приема по командам и прогонам в текстовом
+// формате Prometheus с метками tenant и run_label
+func (p *MessageProcessor) WriteTenantMetrics(w io.Writer) {
+	tenants := p.tenants.snapshot()
+	if len(tenants) == 0 {
+		return
+	}
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(TenantSnapshot) int64
+	}{
+		{"tenant_messages_received_total", "Total number of messages received per tenant and run label",
+			func(s TenantSnapshot) int64 { return s.MessagesReceived }},
+		{"tenant_messages_invalid_total", "Total number of invalid messages per tenant and run label",
+			func(s TenantSnapshot) int64 { return s.MessagesInvalid }},
+		{"tenant_messages_stale_total", "Total number of stale messages per tenant and run label",
+			func(s TenantSnapshot) int64 { return s.MessagesStale }},
+		{"tenant_bytes_received_total", "Total number of bytes received per tenant and run label",
+			func(s TenantSnapshot) int64 { return s.BytesReceived }},
+	}
+	for _, c := range counters {
+		fmt.Fprintf(w, "\n# HELP %s %s\n", c.name, c.help)
+		fmt.Fprintf(w, "# TYPE %s counter\n", c.name)
+		for _, s := range tenants {
+			fmt.Fprintf(w, "%s{%s} %d\n", c.name, tenantLabels(s), c.value(s))
+		}
+	}
+
+	fmt.Fprintf(w, "\n# HELP tenant_message_latency_ms Message latency per tenant and run label in milliseconds\n")
+	fmt.Fprintf(w, "# TYPE tenant_message_latency_ms summary\n")
+	for _, s := range tenants {
+		labels := tenantLabels(s)
+		fmt.Fprintf(w, "tenant_message_latency_ms_sum{%s} %s\n", labels, strconv.FormatFloat(s.latencySum, 'f', 3, 64))
+		fmt.Fprintf(w, "tenant_message_latency_ms_count{%s} %d\n", labels, s.latencyCount)
+	}
+}
+
+// GetTenantStats возвращает показатели приема по командам и прогонам
+func (p *MessageProcessor) GetTenantStats() []TenantSnapshot {
+	return p.tenants.snapshot()
+}
+
+// tenantLabels формирует метки Prometheus; значения проверены normalizeLabel
+// и не требуют экранирования
+func tenantLabels(s TenantSnapshot) string {
+	return fmt.Sprintf(`tenant=%q,run_label=%q`, s.Tenant, s.RunLabel)
+}
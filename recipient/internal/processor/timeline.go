@@ -0,0 +1,58 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/infodiode/shared/models"
+)
+
+// maxTimelineSeconds глубина посекундной динамики приема теста (кольцевой буфер)
+const maxTimelineSeconds = 3600
+
+// receiveTimeline кольцевой буфер посекундных показателей приема одного теста.
+// Секунды отсчитываются от первой полученной; хранятся последние maxTimelineSeconds
+type receiveTimeline struct {
+	base   int64 // Секунда Unix первого сообщения
+	next   int64 // Номер секунды от base, следующей за последней записанной
+	points []models.ReceivePoint
+}
+
+// add учитывает сообщения, полученные в момент at
+func (r *receiveTimeline) add(at time.Time, received, invalid int64) {
+	unix := at.Unix()
+	if r.points == nil {
+		r.base = unix
+	}
+
+	second := unix - r.base
+	if second < 0 || second < r.next-maxTimelineSeconds {
+		// Секунда раньше начала учета или уже вытеснена из буфера
+		return
+	}
+
+	for r.next <= second {
+		point := models.ReceivePoint{Time: time.Unix(r.base+r.next, 0).UTC()}
+		if len(r.points) < maxTimelineSeconds {
+			r.points = append(r.points, point)
+		} else {
+			r.points[r.next%maxTimelineSeconds] = point
+		}
+		r.next++
+	}
+
+	point := &r.points[second%maxTimelineSeconds]
+	point.Received += received
+	point.Invalid += invalid
+}
+
+// snapshot возвращает точки в хронологическом порядке
+func (r *receiveTimeline) snapshot() []models.ReceivePoint {
+	points := make([]models.ReceivePoint, 0, len(r.points))
+	if r.next <= maxTimelineSeconds {
+		return append(points, r.points...)
+	}
+
+	start := r.next % maxTimelineSeconds
+	points = append(points, r.points[start:]...)
+	return append(points, r.points[:start]...)
+}
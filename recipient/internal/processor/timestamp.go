@@ -0,0 +1,37 @@
+package processor
+
+import (
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
+	"go.uber.org/zap"
+)
+
+// SetMonotonicLatency разрешает вычислять задержку сообщений, send_time которых задан
+// по монотонным часам хоста (models.SchemaMonotonic). Показания часов сравнимы только
+// на одном хосте, поэтому режим включается, если sender работает там же, где recipient
+func (p *MessageProcessor) SetMonotonicLatency(enabled bool) {
+	p.monotonic.Store(enabled)
+}
+
+// acceptSchema проверяет, можно ли вычислить задержку по send_time сообщения: версия
+// схемы известна, а время монотонных часов принимается только в разрешенном режиме.
+// О первом таком сообщении выводится предупреждение
+func (p *MessageProcessor) acceptSchema(message *models.Message) bool {
+	if _, err := utils.SchemaTimeFormat(message.Schema); err != nil {
+		if p.schemaWarn.CompareAndSwap(false, true) {
+			p.logger.Warn("Задержка сообщений не вычисляется: неизвестная версия схемы",
+				zap.String("test_id", message.TestID),
+				zap.Int("schema", message.Schema))
+		}
+		return false
+	}
+	if message.Schema == models.SchemaMonotonic && !p.monotonic.Load() {
+		if p.schemaWarn.CompareAndSwap(false, true) {
+			p.logger.Warn("Задержка сообщений не вычисляется: send_time по монотонным часам sender, "+
+				"processing.monotonic_latency выключен",
+				zap.String("test_id", message.TestID))
+		}
+		return false
+	}
+	return true
+}
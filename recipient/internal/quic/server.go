@@ -0,0 +1,469 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/recipient/internal/archive"
+	"github.com/infodiode/recipient/internal/processor"
+	"github.com/infodiode/recipient/internal/tcp"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/quicconn"
+	"github.com/infodiode/shared/tcpframe"
+	quicgo "github.com/quic-go/quic-go"
+	"go.uber.org/zap"
+)
+
+// maxFrameSize максимальный размер кадра (сообщения или пакета)
+const maxFrameSize = 100 * 1024 * 1024
+
+// streamErrorFraming код отмены потока, границы кадров которого потеряны
+const streamErrorFraming quicgo.StreamErrorCode = 0x01
+
+// errFraming ошибка, после которой границы кадров потока потеряны и поток закрывается
+var errFraming = errors.New("нарушен формат кадров")
+
+// QUICServer сервер для приема данных по QUIC. Каждый поток соединения
+// передает кадры протокола v2 TCP (сообщения и пакеты) и читается отдельно,
+// поэтому потеря пакета одного потока не задерживает остальные
+type QUICServer struct {
+	address    string
+	tlsConfig  *tls.Config
+	quicConfig *quicgo.Config
+	logger     *zap.Logger
+	processor  *processor.MessageProcessor
+	archive    *archive.Writer // Архив принятых кадров, nil если отключен
+	listener   *quicgo.EarlyListener
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+	mu         sync.RWMutex
+	isRunning  bool
+	connMu     sync.Mutex
+	conns      map[*quicgo.Conn]struct{} // Активные соединения
+
+	connectionsTotal  atomic.Int64
+	connectionsActive atomic.Int64
+	resumed           atomic.Int64
+	zeroRTT           atomic.Int64
+	streamsTotal      atomic.Int64
+	streamsActive     atomic.Int64
+	messagesReceived  atomic.Int64
+	batchesReceived   atomic.Int64
+	bytesReceived     atomic.Int64
+	errors            atomic.Int64
+	lastMessageTime   atomic.Int64 // unix nano, 0 - сообщений не было
+}
+
+// Config конфигурация QUIC сервера
+type Config struct {
+	Address         string        `yaml:"address" json:"address"`                     // Адрес приема (host:port, UDP)
+	CertFile        string        `yaml:"cert_file" json:"cert_file"`                 // Сертификат сервера (пусто - самоподписанный)
+	KeyFile         string        `yaml:"key_file" json:"key_file"`                   // Ключ сертификата
+	MaxStreams      int           `yaml:"max_streams" json:"max_streams"`             // Одновременных потоков в соединении
+	ZeroRTT         bool          `yaml:"zero_rtt" json:"zero_rtt"`                   // Принимать данные 0-RTT
+	KeepAlivePeriod time.Duration `yaml:"keep_alive_period" json:"keep_alive_period"` // Период keep-alive QUIC (0 - не отправлять)
+	IdleTimeout     time.Duration `yaml:"idle_timeout" json:"idle_timeout"`           // Время простоя до закрытия соединения
+}
+
+// NewQUICServer создает новый QUIC сервер
+func NewQUICServer(config *Config, logger *zap.Logger, processor *processor.MessageProcessor, archiver *archive.Writer) (*QUICServer, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("QUIC адрес не указан")
+	}
+
+	tlsConfig, selfSigned, err := quicconn.ServerTLSConfig(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if selfSigned {
+		logger.Warn("Сертификат QUIC не задан, используется самоподписанный сертификат")
+	}
+
+	maxStreams := config.MaxStreams
+	if maxStreams <= 0 {
+		maxStreams = 100
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &QUICServer{
+		address:   config.Address,
+		tlsConfig: tlsConfig,
+		quicConfig: &quicgo.Config{
+			MaxIncomingStreams:    int64(maxStreams),
+			MaxIncomingUniStreams: -1,
+			Allow0RTT:             config.ZeroRTT,
+			KeepAlivePeriod:       config.KeepAlivePeriod,
+			MaxIdleTimeout:        config.IdleTimeout,
+		},
+		logger:    logger,
+		processor: processor,
+		archive:   archiver,
+		ctx:       ctx,
+		cancel:    cancel,
+		conns:     make(map[*quicgo.Conn]struct{}),
+	}, nil
+}
+
+// Start запускает QUIC сервер
+func (s *QUICServer) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isRunning {
+		return fmt.Errorf("сервер уже запущен")
+	}
+
+	listener, err := quicgo.ListenAddrEarly(s.address, s.tlsConfig, s.quicConfig)
+	if err != nil {
+		return fmt.Errorf("ошибка запуска QUIC сервера: %w", err)
+	}
+
+	s.listener = listener
+	s.isRunning = true
+
+	s.logger.Info("QUIC сервер запущен",
+		zap.String("address", s.address),
+		zap.Bool("zero_rtt", s.quicConfig.Allow0RTT))
+
+	s.wg.Add(1)
+	go s.acceptConnections()
+
+	return nil
+}
+
+// Stop останавливает QUIC сервер и закрывает активные соединения
+func (s *QUICServer) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isRunning {
+		return nil
+	}
+
+	s.logger.Info("Остановка QUIC сервера")
+
+	s.isRunning = false
+	s.cancel()
+	s.listener.Close()
+
+	s.connMu.Lock()
+	for conn := range s.conns {
+		conn.CloseWithError(quicconn.CodeShutdown, "остановка сервера")
+	}
+	s.connMu.Unlock()
+
+	// Ждем завершения всех горутин
+	s.wg.Wait()
+
+	s.logger.Info("QUIC сервер остановлен")
+	return nil
+}
+
+// acceptConnections принимает входящие соединения до остановки сервера
+func (s *QUICServer) acceptConnections() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept(s.ctx)
+		if err != nil {
+			if s.ctx.Err() != nil || errors.Is(err, quicgo.ErrServerClosed) {
+				return
+			}
+			s.logger.Error("Ошибка принятия соединения", zap.Error(err))
+			s.errors.Add(1)
+			continue
+		}
+
+		s.connMu.Lock()
+		s.conns[conn] = struct{}{}
+		s.connMu.Unlock()
+		s.connectionsTotal.Add(1)
+		s.connectionsActive.Add(1)
+
+		s.wg.Add(1)
+		go s.handleConnection(conn)
+	}
+}
+
+// handleConnection принимает потоки соединения до его закрытия. Соединение
+// передается до завершения рукопожатия, поэтому данные 0-RTT обрабатываются сразу
+func (s *QUICServer) handleConnection(conn *quicgo.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.connMu.Lock()
+		delete(s.conns, conn)
+		s.connMu.Unlock()
+		s.connectionsActive.Add(-1)
+	}()
+
+	client := conn.RemoteAddr().String()
+	connectedAt := time.Now()
+	s.logger.Info("Новое QUIC соединение", zap.String("client", client))
+
+	s.wg.Add(1)
+	go s.watchHandshake(conn, client)
+
+	var streams sync.WaitGroup
+	for {
+		stream, err := conn.AcceptStream(s.ctx)
+		if err != nil {
+			break
+		}
+
+		s.streamsTotal.Add(1)
+		s.streamsActive.Add(1)
+		streams.Add(1)
+		go func() {
+			defer streams.Done()
+			defer s.streamsActive.Add(-1)
+			s.handleStream(stream, client)
+		}()
+	}
+	streams.Wait()
+
+	s.logger.Info("QUIC соединение закрыто",
+		zap.String("client", client),
+		zap.Duration("duration", time.Since(connectedAt)),
+		zap.NamedError("reason", context.Cause(conn.Context())))
+}
+
+// watchHandshake учитывает возобновление сессии и прием данных 0-RTT соединения
+func (s *QUICServer) watchHandshake(conn *quicgo.Conn, client string) {
+	defer s.wg.Done()
+
+	select {
+	case <-conn.HandshakeComplete():
+	case <-conn.Context().Done():
+		return
+	}
+
+	state := conn.ConnectionState()
+	if state.TLS.DidResume {
+		s.resumed.Add(1)
+	}
+	if state.Used0RTT {
+		s.zeroRTT.Add(1)
+	}
+	s.logger.Debug("Рукопожатие QUIC завершено",
+		zap.String("client", client),
+		zap.Bool("resumed", state.TLS.DidResume),
+		zap.Bool("zero_rtt", state.Used0RTT))
+}
+
+// handleStream читает кадры потока до его закрытия
+func (s *QUICServer) handleStream(stream *quicgo.Stream, client string) {
+	defer stream.Close()
+
+	for {
+		frameType, length, err := tcpframe.ReadHeader(stream)
+		if err != nil {
+			if err != io.EOF && s.ctx.Err() == nil && !isConnectionClosed(err) {
+				s.logger.Error("Ошибка чтения потока QUIC", zap.String("client", client), zap.Error(err))
+				s.errors.Add(1)
+			}
+			return
+		}
+
+		switch frameType {
+		case tcpframe.TypeMessage:
+			err = s.handleMessage(stream, length, client)
+		case tcpframe.TypeBatch:
+			err = s.handleBatch(stream, length, client)
+		default:
+			err = fmt.Errorf("%w: неизвестный тип кадра 0x%02x", errFraming, frameType)
+		}
+
+		if err != nil {
+			s.logger.Error("Ошибка обработки кадра QUIC", zap.String("client", client), zap.Error(err))
+			s.errors.Add(1)
+			if errors.Is(err, errFraming) {
+				stream.CancelRead(streamErrorFraming)
+				return
+			}
+		}
+	}
+}
+
+// streamName возвращает имя потока для проверки порядка сообщений: адрес клиента и номер потока
+func streamName(stream *quicgo.Stream, client string) string {
+	return fmt.Sprintf("%s/%d", client, stream.StreamID())
+}
+
+// isConnectionClosed проверяет, вызвана ли ошибка закрытием соединения клиентом
+func isConnectionClosed(err error) bool {
+	var (
+		appErr    *quicgo.ApplicationError
+		idleErr   *quicgo.IdleTimeoutError
+		streamErr *quicgo.StreamError
+	)
+	return errors.As(err, &appErr) || errors.As(err, &idleErr) || errors.As(err, &streamErr) ||
+		errors.Is(err, quicgo.Err0RTTRejected)
+}
+
+// handleMessage обрабатывает одиночное сообщение
+func (s *QUICServer) handleMessage(stream *quicgo.Stream, length uint32, client string) error {
+	if length > maxFrameSize {
+		return fmt.Errorf("%w: слишком большое сообщение: %d байт", errFraming, length)
+	}
+
+	// Кадр читается целиком до разбора, чтобы длительность разбора не включала чтение из потока
+	frame, err := s.readFrame(stream, length, archive.KindMessage)
+	if err != nil {
+		return fmt.Errorf("%w: ошибка чтения сообщения: %v", errFraming, err)
+	}
+	defer tcp.ReleaseFrame(frame)
+
+	var message models.Message
+	decodeStart := time.Now()
+	err = json.Unmarshal(*frame, &message)
+	s.processor.ObserveDecode(time.Since(decodeStart))
+	if err != nil {
+		return fmt.Errorf("ошибка десериализации сообщения: %w", err)
+	}
+
+	s.processor.ObserveOrder(archive.SourceQUIC.String(), streamName(stream, client), message.TestID, message.Sequence)
+	if err := s.processor.ProcessMessageWithSize(&message, int(length)); err != nil {
+		return fmt.Errorf("ошибка обработки сообщения: %w", err)
+	}
+
+	s.messagesReceived.Add(1)
+	s.bytesReceived.Add(int64(length))
+	s.processor.ObserveReceive(archive.SourceQUIC.String(), 1, int(length))
+	s.lastMessageTime.Store(time.Now().UnixNano())
+
+	s.logger.Debug("Сообщение получено",
+		zap.String("client", client),
+		zap.Int("message_id", message.MessageID),
+		zap.Int("size", int(length)))
+
+	return nil
+}
+
+// handleBatch обрабатывает пакет сообщений
+func (s *QUICServer) handleBatch(stream *quicgo.Stream, length uint32, client string) error {
+	if length > maxFrameSize {
+		return fmt.Errorf("%w: слишком большой пакет: %d байт", errFraming, length)
+	}
+
+	frame, err := s.readFrame(stream, length, archive.KindBatch)
+	if err != nil {
+		return fmt.Errorf("%w: ошибка чтения пакета: %v", errFraming, err)
+	}
+	defer tcp.ReleaseFrame(frame)
+
+	processed := 0
+	name := streamName(stream, client)
+	batchCount, err := tcp.DecodeBatch(json.NewDecoder(bytes.NewReader(*frame)), s.processor.ObserveDecode, func(message *models.Message, size int) {
+		processed++
+		s.processor.ObserveOrder(archive.SourceQUIC.String(), name, message.TestID, message.Sequence)
+		if err := s.processor.ProcessMessageWithSize(message, size); err != nil {
+			s.logger.Error("Ошибка обработки сообщения из пакета",
+				zap.Int("message_id", message.MessageID),
+				zap.Error(err))
+			s.errors.Add(1)
+		}
+	})
+
+	// Учитываем и сообщения, обработанные до ошибки разбора
+	s.batchesReceived.Add(1)
+	s.messagesReceived.Add(int64(processed))
+	s.bytesReceived.Add(int64(length))
+	s.processor.ObserveReceive(archive.SourceQUIC.String(), processed, int(length))
+	s.lastMessageTime.Store(time.Now().UnixNano())
+
+	if err != nil {
+		return fmt.Errorf("ошибка десериализации пакета после %d сообщений: %w", processed, err)
+	}
+
+	s.logger.Info("Пакет сообщений получен",
+		zap.String("client", client),
+		zap.Int("count", batchCount),
+		zap.Int("size", int(length)))
+
+	return nil
+}
+
+// readFrame читает тело кадра в буфер из пула (возвращается tcp.ReleaseFrame после разбора)
+// и записывает кадр в архив, если он включен
+func (s *QUICServer) readFrame(stream io.Reader, length uint32, kind archive.Kind) (*[]byte, error) {
+	frame, err := tcp.ReadFrame(stream, length)
+	if err != nil {
+		return nil, err
+	}
+	if s.archive != nil {
+		s.archive.Write(archive.SourceQUIC, kind, time.Now(), *frame)
+	}
+	return frame, nil
+}
+
+// ResetStats сбрасывает счетчики статистики; количество активных соединений и потоков сохраняется
+func (s *QUICServer) ResetStats() {
+	s.connectionsTotal.Store(s.connectionsActive.Load())
+	s.streamsTotal.Store(s.streamsActive.Load())
+	s.resumed.Store(0)
+	s.zeroRTT.Store(0)
+	s.messagesReceived.Store(0)
+	s.batchesReceived.Store(0)
+	s.bytesReceived.Store(0)
+	s.errors.Store(0)
+	s.lastMessageTime.Store(0)
+}
+
+// StatsSnapshot снимок статистики сервера
+type StatsSnapshot struct {
+	Running           bool       `json:"running"`
+	Address           string     `json:"address"`
+	ZeroRTTEnabled    bool       `json:"zero_rtt_enabled"` // Принимаются данные 0-RTT
+	ConnectionsTotal  int64      `json:"connections_total"`
+	ConnectionsActive int64      `json:"connections_active"`
+	Resumed           int64      `json:"resumed"`           // Соединений с возобновлением сессии TLS
+	ZeroRTT           int64      `json:"zero_rtt_accepted"` // Соединений, данные 0-RTT которых приняты
+	StreamsTotal      int64      `json:"streams_total"`
+	StreamsActive     int64      `json:"streams_active"`
+	MessagesReceived  int64      `json:"messages_received"`
+	BatchesReceived   int64      `json:"batches_received"`
+	BytesReceived     int64      `json:"bytes_received"`
+	Errors            int64      `json:"errors"`
+	LastMessageTime   *time.Time `json:"last_message_time,omitempty"`
+}
+
+// GetStats возвращает статистику сервера
+func (s *QUICServer) GetStats() StatsSnapshot {
+	stats := StatsSnapshot{
+		Running:           s.IsRunning(),
+		Address:           s.address,
+		ZeroRTTEnabled:    s.quicConfig.Allow0RTT,
+		ConnectionsTotal:  s.connectionsTotal.Load(),
+		ConnectionsActive: s.connectionsActive.Load(),
+		Resumed:           s.resumed.Load(),
+		ZeroRTT:           s.zeroRTT.Load(),
+		StreamsTotal:      s.streamsTotal.Load(),
+		StreamsActive:     s.streamsActive.Load(),
+		MessagesReceived:  s.messagesReceived.Load(),
+		BatchesReceived:   s.batchesReceived.Load(),
+		BytesReceived:     s.bytesReceived.Load(),
+		Errors:            s.errors.Load(),
+	}
+	if last := s.lastMessageTime.Load(); last != 0 {
+		at := time.Unix(0, last)
+		stats.LastMessageTime = &at
+	}
+	return stats
+}
+
+// IsRunning проверяет, работает ли сервер
+func (s *QUICServer) IsRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.isRunning
+}
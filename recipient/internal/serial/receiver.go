@@ -0,0 +1,319 @@
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/recipient/internal/archive"
+	"github.com/infodiode/recipient/internal/processor"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/serialport"
+	"go.uber.org/zap"
+)
+
+// Receiver прием сообщений через последовательный порт. Каждый кадр содержит одно
+// сообщение; кадры с неверной CRC или длиной отбрасываются, прием продолжается
+// со следующего маркера начала кадра
+type Receiver struct {
+	config    Config
+	logger    *zap.Logger
+	processor *processor.MessageProcessor
+	archive   *archive.Writer // Архив принятых кадров, nil если отключен
+	wg        sync.WaitGroup
+	stopChan  chan struct{}
+	mu        sync.Mutex
+	isRunning bool
+	port      *serialport.Port // Открытый порт (под mu), nil между попытками открытия
+
+	framesReceived   atomic.Int64
+	bytesReceived    atomic.Int64
+	crcErrors        atomic.Int64
+	sizeErrors       atomic.Int64
+	incompleteFrames atomic.Int64
+	decodeErrors     atomic.Int64
+	processErrors    atomic.Int64
+	skippedBytes     atomic.Int64
+	openErrors       atomic.Int64
+	reopenCount      atomic.Int64
+	lastFrameTime    atomic.Int64 // unix nano, 0 - кадров не было
+}
+
+// Config конфигурация приема через последовательный порт
+type Config struct {
+	Port           serialport.PortConfig
+	MaxFrameSize   int           // Максимальный размер данных кадра
+	FrameTimeout   time.Duration // Запас времени приема кадра сверх времени передачи на скорости порта
+	ReopenInterval time.Duration // Интервал повторного открытия порта после ошибки
+}
+
+// NewReceiver создает прием через последовательный порт
+func NewReceiver(config *Config, logger *zap.Logger, processor *processor.MessageProcessor, archiver *archive.Writer) (*Receiver, error) {
+	if err := config.Port.Validate(); err != nil {
+		return nil, err
+	}
+	if config.MaxFrameSize <= 0 {
+		return nil, fmt.Errorf("некорректный максимальный размер кадра: %d", config.MaxFrameSize)
+	}
+
+	receiver := &Receiver{
+		config:    *config,
+		logger:    logger,
+		processor: processor,
+		archive:   archiver,
+		stopChan:  make(chan struct{}),
+	}
+	if receiver.config.ReopenInterval <= 0 {
+		receiver.config.ReopenInterval = 5 * time.Second
+	}
+
+	return receiver, nil
+}
+
+// Start запускает прием. Порт открывается в фоне: недоступный порт открывается
+// повторно через ReopenInterval
+func (r *Receiver) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.isRunning {
+		return fmt.Errorf("прием уже запущен")
+	}
+	r.isRunning = true
+
+	r.wg.Add(1)
+	go r.run()
+
+	return nil
+}
+
+// Stop останавливает прием и закрывает порт
+func (r *Receiver) Stop() error {
+	r.mu.Lock()
+	if !r.isRunning {
+		r.mu.Unlock()
+		return nil
+	}
+
+	close(r.stopChan)
+	r.isRunning = false
+
+	// Закрытие порта прерывает ожидающее чтение
+	if r.port != nil {
+		r.port.Close()
+	}
+	r.mu.Unlock()
+
+	r.wg.Wait()
+
+	r.logger.Info("Прием через последовательный порт остановлен")
+	return nil
+}
+
+// run открывает порт и читает кадры до остановки, открывая порт заново после ошибок
+func (r *Receiver) run() {
+	defer r.wg.Done()
+
+	opened := false
+	for {
+		port, err := serialport.Open(r.config.Port)
+		if err != nil {
+			r.openErrors.Add(1)
+			r.logger.Warn("Не удалось открыть последовательный порт", zap.Error(err))
+		} else if r.setPort(port) {
+			if opened {
+				r.reopenCount.Add(1)
+			}
+			opened = true
+
+			r.logger.Info("Последовательный порт открыт",
+				zap.String("device", r.config.Port.Device),
+				zap.Int("baud_rate", r.config.Port.BaudRate))
+
+			err = r.readFrames(port)
+			r.setPort(nil)
+			port.Close()
+
+			if r.stopped() {
+				return
+			}
+			r.logger.Error("Ошибка чтения последовательного порта", zap.Error(err))
+		} else {
+			// Прием остановлен во время открытия порта
+			port.Close()
+			return
+		}
+
+		select {
+		case <-r.stopChan:
+			return
+		case <-time.After(r.config.ReopenInterval):
+		}
+	}
+}
+
+// setPort сохраняет открытый порт; возвращает false, если прием уже остановлен
+func (r *Receiver) setPort(port *serialport.Port) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if port != nil && !r.isRunning {
+		return false
+	}
+	r.port = port
+	return true
+}
+
+// stopped проверяет, остановлен ли прием
+func (r *Receiver) stopped() bool {
+	select {
+	case <-r.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// readFrames читает кадры из порта до ошибки ввода-вывода
+func (r *Receiver) readFrames(port *serialport.Port) error {
+	reader := serialport.NewFrameReader(port, r.config.MaxFrameSize, r.config.FrameTimeout, r.config.Port.ByteTime())
+
+	var skipped int64
+	for {
+		payload, err := reader.ReadFrame()
+
+		// Байты, пропущенные при поиске начала кадра (шум на линии, потерянные кадры)
+		if total := reader.Skipped(); total != skipped {
+			r.skippedBytes.Add(total - skipped)
+			skipped = total
+		}
+
+		switch {
+		case err == nil:
+			r.handleFrame(payload)
+		case errors.Is(err, serialport.ErrFrameCRC):
+			r.crcErrors.Add(1)
+			r.logger.Debug("Кадр отброшен: несовпадение CRC")
+		case errors.Is(err, serialport.ErrFrameSize):
+			r.sizeErrors.Add(1)
+			r.logger.Debug("Кадр отброшен: превышен размер")
+		case errors.Is(err, serialport.ErrFrameTimeout):
+			r.incompleteFrames.Add(1)
+			r.logger.Debug("Кадр отброшен: получен не полностью")
+		default:
+			return err
+		}
+	}
+}
+
+// handleFrame разбирает и обрабатывает сообщение из данных кадра
+func (r *Receiver) handleFrame(payload []byte) {
+	receivedAt := time.Now()
+	r.framesReceived.Add(1)
+	r.bytesReceived.Add(int64(len(payload)))
+	r.lastFrameTime.Store(receivedAt.UnixNano())
+	r.processor.ObserveReceive(archive.SourceSerial.String(), 1, len(payload))
+
+	if r.archive != nil {
+		r.archive.Write(archive.SourceSerial, archive.KindMessage, receivedAt, payload)
+	}
+
+	var message models.Message
+	decodeStart := time.Now()
+	err := message.UnmarshalJSON(payload)
+	r.processor.ObserveDecode(time.Since(decodeStart))
+	if err != nil {
+		r.decodeErrors.Add(1)
+		r.logger.Error("Ошибка десериализации сообщения", zap.Error(err))
+		return
+	}
+
+	r.processor.ObserveOrder(archive.SourceSerial.String(), r.config.Port.Device, message.TestID, message.Sequence)
+	if err := r.processor.ProcessMessageWithSize(&message, len(payload)); err != nil {
+		r.processErrors.Add(1)
+		r.logger.Error("Ошибка обработки сообщения",
+			zap.Int("message_id", message.MessageID),
+			zap.Error(err))
+		return
+	}
+
+	r.logger.Debug("Сообщение получено",
+		zap.Int("message_id", message.MessageID),
+		zap.Int("size", len(payload)))
+}
+
+// ResetStats сбрасывает счетчики статистики
+func (r *Receiver) ResetStats() {
+	r.framesReceived.Store(0)
+	r.bytesReceived.Store(0)
+	r.crcErrors.Store(0)
+	r.sizeErrors.Store(0)
+	r.incompleteFrames.Store(0)
+	r.decodeErrors.Store(0)
+	r.processErrors.Store(0)
+	r.skippedBytes.Store(0)
+	r.openErrors.Store(0)
+	r.reopenCount.Store(0)
+	r.lastFrameTime.Store(0)
+}
+
+// StatsSnapshot снимок статистики приема
+type StatsSnapshot struct {
+	Running          bool       `json:"running"`
+	Open             bool       `json:"open"` // Порт открыт
+	Device           string     `json:"device"`
+	BaudRate         int        `json:"baud_rate"`
+	FramesReceived   int64      `json:"frames_received"`   // Принято кадров с верной CRC
+	BytesReceived    int64      `json:"bytes_received"`    // Данных в принятых кадрах, байт
+	CRCErrors        int64      `json:"crc_errors"`        // Кадров с неверной CRC
+	SizeErrors       int64      `json:"size_errors"`       // Кадров с длиной больше max_frame_size
+	IncompleteFrames int64      `json:"incomplete_frames"` // Кадров, не полученных целиком за отведенное время
+	DecodeErrors     int64      `json:"decode_errors"`     // Кадров, не разобранных как сообщение
+	ProcessErrors    int64      `json:"process_errors"`    // Ошибок обработки сообщений
+	SkippedBytes     int64      `json:"skipped_bytes"`     // Байтов, пропущенных при поиске начала кадра
+	OpenErrors       int64      `json:"open_errors"`       // Неудачных попыток открытия порта
+	ReopenCount      int64      `json:"reopen_count"`      // Повторных открытий порта после ошибок
+	LastFrameTime    *time.Time `json:"last_frame_time,omitempty"`
+}
+
+// GetStats возвращает статистику приема
+func (r *Receiver) GetStats() StatsSnapshot {
+	stats := StatsSnapshot{
+		Running:          r.IsRunning(),
+		Open:             r.IsOpen(),
+		Device:           r.config.Port.Device,
+		BaudRate:         r.config.Port.BaudRate,
+		FramesReceived:   r.framesReceived.Load(),
+		BytesReceived:    r.bytesReceived.Load(),
+		CRCErrors:        r.crcErrors.Load(),
+		SizeErrors:       r.sizeErrors.Load(),
+		IncompleteFrames: r.incompleteFrames.Load(),
+		DecodeErrors:     r.decodeErrors.Load(),
+		ProcessErrors:    r.processErrors.Load(),
+		SkippedBytes:     r.skippedBytes.Load(),
+		OpenErrors:       r.openErrors.Load(),
+		ReopenCount:      r.reopenCount.Load(),
+	}
+	if last := r.lastFrameTime.Load(); last != 0 {
+		at := time.Unix(0, last)
+		stats.LastFrameTime = &at
+	}
+
+	return stats
+}
+
+// IsRunning проверяет, запущен ли прием
+func (r *Receiver) IsRunning() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.isRunning
+}
+
+// IsOpen проверяет, открыт ли порт
+func (r *Receiver) IsOpen() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.port != nil
+}
@@ -0,0 +1,418 @@
+package slo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// sampleInterval период снятия счетчиков и проверки целей
+const sampleInterval = time.Second
+
+// maxViolations количество хранимых нарушений
+const maxViolations = 100
+
+// Типы событий целей
+const (
+	EventViolation = "slo_violation" // Бюджет ошибок расходуется быстрее порога
+	EventRecovered = "slo_recovered" // Скорость расхода вернулась ниже порога
+)
+
+// Objective цель по задержке: доля Target (%) сообщений с задержкой доставки не больше
+// Latency за окно Window
+type Objective struct {
+	Name        string
+	Latency     time.Duration // Порог задержки от send_time до получения
+	Target      float64       // Доля сообщений в пределах порога, %
+	Window      time.Duration // Длинное окно оценки
+	ShortWindow time.Duration // Короткое окно: нарушение фиксируется, когда порог превышен в обоих окнах
+	BurnRate    float64       // Порог скорости расхода бюджета ошибок
+}
+
+// Config параметры контроля целей
+type Config struct {
+	Objectives     []Objective
+	WebhookURL     string        // Адрес отправки событий (пусто - не отправлять)
+	WebhookTimeout time.Duration // Таймаут отправки события
+}
+
+// WindowStats показатели цели за окно
+type WindowStats struct {
+	Seconds    float64 `json:"seconds"`
+	Messages   int64   `json:"messages"`    // Сообщений с измеренной задержкой
+	Slow       int64   `json:"slow"`        // Из них с задержкой выше порога
+	ErrorRatio float64 `json:"error_ratio"` // Доля медленных сообщений
+	BurnRate   float64 `json:"burn_rate"`   // Доля медленных, деленная на бюджет ошибок (100 - target)%
+}
+
+// ObjectiveStats состояние цели
+type ObjectiveStats struct {
+	Name       string      `json:"name"`
+	LatencyMs  float64     `json:"latency_ms"`
+	Target     float64     `json:"target"`
+	BurnRate   float64     `json:"burn_rate_threshold"`
+	Long       WindowStats `json:"long"`
+	Short      WindowStats `json:"short"`
+	Violating  bool        `json:"violating"`
+	Violations int64       `json:"violations"` // Нарушений с запуска
+	Since      *time.Time  `json:"since,omitempty"`
+}
+
+// Violation нарушение цели: период, когда скорость расхода бюджета превышала порог
+// в длинном и коротком окнах
+type Violation struct {
+	Objective    string     `json:"objective"`
+	Start        time.Time  `json:"start"`
+	End          *time.Time `json:"end,omitempty"` // Пусто - нарушение продолжается
+	PeakBurnRate float64    `json:"peak_burn_rate"`
+	Messages     int64      `json:"messages"` // Сообщений за длинное окно при начале нарушения
+	Slow         int64      `json:"slow"`
+}
+
+// Event событие нарушения цели или восстановления после него
+type Event struct {
+	Type      string    `json:"type"`
+	Instance  string    `json:"instance"`
+	Time      time.Time `json:"time"`
+	Objective string    `json:"objective"`
+	LatencyMs float64   `json:"latency_ms"`
+	Target    float64   `json:"target"`
+	Threshold float64   `json:"burn_rate_threshold"`
+	Long      float64   `json:"long_burn_rate"`
+	Short     float64   `json:"short_burn_rate"`
+}
+
+// Stats состояние целей и нарушения
+type Stats struct {
+	Objectives    []ObjectiveStats `json:"objectives"`
+	WebhookErrors int64            `json:"webhook_errors"`
+}
+
+// bucket счетчики одной секунды
+type bucket struct {
+	total int64
+	slow  []int64 // По целям
+}
+
+// objectiveState накопленное состояние цели (под Monitor.mu)
+type objectiveState struct {
+	long, short WindowStats
+	violating   bool
+	violations  int64
+	current     *Violation
+}
+
+// Monitor учитывает задержку доставки сообщений и ежесекундно оценивает скорость
+// расхода бюджета ошибок каждой цели в длинном и коротком окнах. Нарушение начинается,
+// когда порог превышен в обоих окнах, и заканчивается, когда скорость в одном из них
+// опускается ниже порога; о смене состояния сообщается в журнал и на webhook
+type Monitor struct {
+	config   Config
+	instance string
+	logger   *zap.Logger
+	client   *http.Client
+	thresh   []float64      // Порог задержки целей, ms
+	total    atomic.Int64   // Сообщений в текущей секунде
+	slow     []atomic.Int64 // Медленных сообщений в текущей секунде по целям
+	failures atomic.Int64
+
+	mu         sync.Mutex
+	buckets    []bucket // Кольцо счетчиков по секундам
+	next       int
+	count      int
+	states     []objectiveState
+	violations []*Violation // Завершенные и текущие нарушения, последние maxViolations
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewMonitor создает контроль целей экземпляра instance
+func NewMonitor(cfg Config, instance string, logger *zap.Logger) *Monitor {
+	var longest time.Duration
+	thresh := make([]float64, len(cfg.Objectives))
+	for i, objective := range cfg.Objectives {
+		longest = max(longest, objective.Window)
+		thresh[i] = float64(objective.Latency.Microseconds()) / 1000
+	}
+
+	buckets := make([]bucket, max(int(longest/sampleInterval), 1))
+	for i := range buckets {
+		buckets[i].slow = make([]int64, len(cfg.Objectives))
+	}
+
+	return &Monitor{
+		config:   cfg,
+		instance: instance,
+		logger:   logger.With(zap.String("component", "slo")),
+		client:   &http.Client{Timeout: cfg.WebhookTimeout},
+		thresh:   thresh,
+		slow:     make([]atomic.Int64, len(cfg.Objectives)),
+		buckets:  buckets,
+		states:   make([]objectiveState, len(cfg.Objectives)),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start запускает периодическую оценку целей
+func (m *Monitor) Start() {
+	for _, objective := range m.config.Objectives {
+		m.logger.Info("Контроль цели по задержке",
+			zap.String("objective", objective.Name),
+			zap.Duration("latency", objective.Latency),
+			zap.Float64("target", objective.Target),
+			zap.Duration("window", objective.Window),
+			zap.Duration("short_window", objective.ShortWindow),
+			zap.Float64("burn_rate", objective.BurnRate))
+	}
+
+	m.wg.Add(1)
+	go m.run()
+}
+
+// run оценивает цели с интервалом sampleInterval
+func (m *Monitor) run() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopChan:
+			return
+		case now := <-ticker.C:
+			for _, event := range m.sample(now) {
+				m.emit(event)
+			}
+		}
+	}
+}
+
+// ObserveLatency учитывает задержку доставки сообщения (ms)
+func (m *Monitor) ObserveLatency(latencyMs float64) {
+	m.total.Add(1)
+	for i, threshold := range m.thresh {
+		if latencyMs > threshold {
+			m.slow[i].Add(1)
+		}
+	}
+}
+
+// sample переносит счетчики секунды в кольцо, оценивает цели и возвращает события
+// смены состояния
+func (m *Monitor) sample(now time.Time) []*Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := &m.buckets[m.next]
+	b.total = m.total.Swap(0)
+	for i := range m.slow {
+		b.slow[i] = m.slow[i].Swap(0)
+	}
+	m.next = (m.next + 1) % len(m.buckets)
+	m.count = min(m.count+1, len(m.buckets))
+
+	var events []*Event
+	for i, objective := range m.config.Objectives {
+		state := &m.states[i]
+		state.long = m.window(i, objective.Window, objective.Target)
+		state.short = m.window(i, objective.ShortWindow, objective.Target)
+
+		violating := state.long.BurnRate > objective.BurnRate && state.short.BurnRate > objective.BurnRate
+		if state.current != nil {
+			state.current.PeakBurnRate = max(state.current.PeakBurnRate, state.long.BurnRate)
+		}
+		if violating == state.violating {
+			continue
+		}
+		state.violating = violating
+
+		event := &Event{
+			Type:      EventRecovered,
+			Instance:  m.instance,
+			Time:      now,
+			Objective: objective.Name,
+			LatencyMs: m.thresh[i],
+			Target:    objective.Target,
+			Threshold: objective.BurnRate,
+			Long:      state.long.BurnRate,
+			Short:     state.short.BurnRate,
+		}
+		if violating {
+			event.Type = EventViolation
+			state.violations++
+			state.current = &Violation{
+				Objective:    objective.Name,
+				Start:        now,
+				PeakBurnRate: state.long.BurnRate,
+				Messages:     state.long.Messages,
+				Slow:         state.long.Slow,
+			}
+			m.violations = append(m.violations, state.current)
+			if len(m.violations) > maxViolations {
+				m.violations = m.violations[len(m.violations)-maxViolations:]
+			}
+		} else {
+			m.endViolation(state, now)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// endViolation фиксирует окончание текущего нарушения цели (вызывается под m.mu)
+func (m *Monitor) endViolation(state *objectiveState, now time.Time) {
+	if state.current == nil {
+		return
+	}
+	end := now
+	state.current.End = &end
+	state.current = nil
+}
+
+// window суммирует счетчики цели i за последние duration секунд (вызывается под m.mu)
+func (m *Monitor) window(i int, duration time.Duration, target float64) WindowStats {
+	seconds := min(max(int(duration/sampleInterval), 1), m.count)
+	stats := WindowStats{Seconds: float64(seconds) * sampleInterval.Seconds()}
+	for s := 1; s <= seconds; s++ {
+		b := &m.buckets[(m.next-s+len(m.buckets))%len(m.buckets)]
+		stats.Messages += b.total
+		stats.Slow += b.slow[i]
+	}
+
+	if stats.Messages > 0 {
+		stats.ErrorRatio = float64(stats.Slow) / float64(stats.Messages)
+		stats.BurnRate = stats.ErrorRatio / (1 - target/100)
+	}
+	return stats
+}
+
+// emit записывает событие в журнал и отправляет его на webhook
+func (m *Monitor) emit(event *Event) {
+	fields := []zap.Field{
+		zap.String("event", event.Type),
+		zap.String("objective", event.Objective),
+		zap.Float64("long_burn_rate", event.Long),
+		zap.Float64("short_burn_rate", event.Short),
+		zap.Float64("threshold", event.Threshold),
+	}
+	if event.Type == EventViolation {
+		m.logger.Warn("Нарушение цели по задержке: бюджет ошибок расходуется быстрее порога", fields...)
+	} else {
+		m.logger.Info("Цель по задержке восстановлена", fields...)
+	}
+
+	if m.config.WebhookURL == "" {
+		return
+	}
+
+	// Отправка не задерживает оценку целей
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		if err := m.post(event); err != nil {
+			m.failures.Add(1)
+			m.logger.Warn("Ошибка отправки события цели", zap.Error(err))
+		}
+	}()
+}
+
+// post отправляет событие на webhook в формате JSON
+func (m *Monitor) post(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации события: %w", err)
+	}
+
+	resp, err := m.client.Post(m.config.WebhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook ответил статусом %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Stats возвращает состояние целей
+func (m *Monitor) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := Stats{
+		Objectives:    make([]ObjectiveStats, 0, len(m.config.Objectives)),
+		WebhookErrors: m.failures.Load(),
+	}
+	for i, objective := range m.config.Objectives {
+		state := &m.states[i]
+		objectiveStats := ObjectiveStats{
+			Name:       objective.Name,
+			LatencyMs:  m.thresh[i],
+			Target:     objective.Target,
+			BurnRate:   objective.BurnRate,
+			Long:       state.long,
+			Short:      state.short,
+			Violating:  state.violating,
+			Violations: state.violations,
+		}
+		if state.current != nil {
+			since := state.current.Start
+			objectiveStats.Since = &since
+		}
+		stats.Objectives = append(stats.Objectives, objectiveStats)
+	}
+	return stats
+}
+
+// Violations возвращает нарушения целей (последние maxViolations) в порядке начала;
+// objective отбирает нарушения одной цели (пусто - всех), active - только продолжающиеся
+func (m *Monitor) Violations(objective string, active bool) []Violation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	violations := []Violation{}
+	for _, violation := range m.violations {
+		if objective != "" && violation.Objective != objective || active && violation.End != nil {
+			continue
+		}
+		copied := *violation
+		if violation.End != nil {
+			end := *violation.End
+			copied.End = &end
+		}
+		violations = append(violations, copied)
+	}
+	return violations
+}
+
+// Reset очищает окна целей (при сбросе статистики); текущие нарушения завершаются
+func (m *Monitor) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for i := range m.buckets {
+		m.buckets[i].total = 0
+		clear(m.buckets[i].slow)
+	}
+	m.count = 0
+	for i := range m.states {
+		m.endViolation(&m.states[i], now)
+		m.states[i] = objectiveState{violations: m.states[i].violations}
+	}
+}
+
+// Close останавливает оценку целей и ожидает отправки событий
+func (m *Monitor) Close() {
+	close(m.stopChan)
+	m.wg.Wait()
+}
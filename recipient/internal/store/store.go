@@ -0,0 +1,646 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite" // Драйвер SQLite без cgo
+)
+
+const (
+	sessionsInterval = 5 * time.Second
+	pruneInterval    = time.Hour
+	errorLogEvery    = time.Minute
+
+	retryMinDelay = 100 * time.Millisecond // Пауза перед первым повтором неудавшейся записи
+	retryMaxDelay = 30 * time.Second       // Предельная пауза между повторами
+)
+
+// schema таблицы хранилища: записи о сообщениях и итоги по тестам
+const schema = `
+CREATE TABLE IF NOT EXISTS messages (
+	id          INTEGER PRIMARY KEY,
+	test_id     TEXT    NOT NULL,
+	sequence    INTEGER NOT NULL,
+	message_id  INTEGER NOT NULL,
+	received_at INTEGER NOT NULL,
+	latency_ms  REAL,
+	size        INTEGER NOT NULL,
+	valid       INTEGER NOT NULL,
+	stale       INTEGER NOT NULL,
+	error       TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS messages_test ON messages (test_id, sequence);
+CREATE INDEX IF NOT EXISTS messages_received ON messages (received_at);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	test_id        TEXT PRIMARY KEY,
+	tenant         TEXT    NOT NULL DEFAULT '',
+	run_label      TEXT    NOT NULL DEFAULT '',
+	received       INTEGER NOT NULL,
+	unique_count   INTEGER NOT NULL,
+	duplicates     INTEGER NOT NULL,
+	retry_duplicates INTEGER NOT NULL DEFAULT 0,
+	max_sequence   INTEGER NOT NULL,
+	missing        INTEGER NOT NULL,
+	missing_ranges TEXT    NOT NULL,
+	out_of_order   INTEGER NOT NULL,
+	stale          INTEGER NOT NULL,
+	first_seen     INTEGER NOT NULL,
+	last_seen      INTEGER NOT NULL
+);
+`
+
+// sessionColumns столбцы таблицы sessions, добавленные после первой версии схемы;
+// в базе, созданной прежней версией, они добавляются при открытии
+var sessionColumns = []struct{ name, definition string }{
+	{"tenant", "TEXT NOT NULL DEFAULT ''"},
+	{"run_label", "TEXT NOT NULL DEFAULT ''"},
+	{"retry_duplicates", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// sessionFields столбцы отчета по тесту в порядке чтения scanSession
+const sessionFields = `test_id, tenant, run_label, received, unique_count, duplicates, retry_duplicates, max_sequence, missing,
+	missing_ranges, out_of_order, stale, first_seen, last_seen`
+
+// Config конфигурация хранилища результатов
+type Config struct {
+	Path          string        // Файл базы данных SQLite
+	QueueSize     int           // Очередь записей, ожидающих сохранения
+	ValidMessages bool          // Сохранять корректные сообщения (иначе только с ошибками и устаревшие)
+	Retention     time.Duration // Срок хранения записей (0 - без ограничения)
+
+	BatchSize       int           // Записей в одной транзакции
+	FlushInterval   time.Duration // Период сохранения неполного пакета
+	MaxPending      int           // Предел несохраненных записей в памяти, пока база недоступна
+	ShutdownTimeout time.Duration // Время на сохранение оставшихся записей при закрытии
+}
+
+// MessageRecord результат обработки одного сообщения
+type MessageRecord struct {
+	TestID     string    `json:"test_id"`
+	Sequence   int64     `json:"sequence"`
+	MessageID  int       `json:"message_id"`
+	ReceivedAt time.Time `json:"received_at"`
+	LatencyMs  *float64  `json:"latency_ms,omitempty"` // nil, если задержку не удалось вычислить
+	Size       int       `json:"size"`
+	Valid      bool      `json:"valid"`           // Контрольная сумма и содержимое корректны
+	Stale      bool      `json:"stale"`           // Получено позже срока актуальности
+	Error      string    `json:"error,omitempty"` // Описание ошибки проверки
+}
+
+// SessionFilter параметры выборки сохраненных отчетов по тестам
+type SessionFilter struct {
+	Tenant   string // Только тесты команды (пусто - все)
+	RunLabel string // Только тесты с меткой прогона (пусто - все)
+	Limit    int    // Максимум отчетов
+}
+
+// MessageFilter параметры выборки записей о сообщениях теста
+type MessageFilter struct {
+	InvalidOnly bool  // Только некорректные сообщения
+	StaleOnly   bool  // Только устаревшие сообщения
+	After       int64 // Номер сообщения, после которого начинается выборка
+	Limit       int   // Максимум записей
+}
+
+// Stats статистика хранилища
+type Stats struct {
+	Path            string  `json:"path"`
+	MessagesWritten int64   `json:"messages_written"`
+	MessagesDropped int64   `json:"messages_dropped"` // Не сохранены из-за переполнения очереди
+	MessagesLost    int64   `json:"messages_lost"`    // Не сохранены к закрытию хранилища
+	SessionsSaved   int64   `json:"sessions_saved"`
+	Queued          int     `json:"queued"`
+	Pending         int64   `json:"pending"`        // Приняты из очереди, но еще не сохранены
+	LagSeconds      float64 `json:"lag_seconds"`    // Возраст самой старой несохраненной записи
+	WriteFailures   int64   `json:"write_failures"` // Неудавшиеся транзакции записи сообщений
+	Errors          int64   `json:"errors"`
+}
+
+// Store встроенное хранилище результатов обработки в SQLite для анализа после теста
+// на хостах без сервера БД. Записи сохраняются пакетами в отдельной горутине и не
+// задерживают прием. Пакет, транзакция которого не удалась, остается в памяти и
+// сохраняется повторно (не больше MaxPending записей), поэтому кратковременная
+// недоступность базы не теряет записи. Повторы есть только у хранилища: файловые
+// приемники (журнал корреляции, demux, журнал сообщений, архив кадров) учитывают
+// ошибки записи в своей статистике и записи не повторяют. Методы nil *Store ничего
+// не делают, поэтому хранилище можно не проверять на nil
+type Store struct {
+	config   Config
+	logger   *zap.Logger
+	db       *sql.DB
+	sessions func() []*models.SessionReport
+	queue    chan MessageRecord
+
+	// Состояние горутины записи
+	pending     []MessageRecord // Записи в порядке получения; несохраненные начинаются с pendingHead
+	pendingHead int             // Индекс первой несохраненной записи в pending
+	retryDelay  time.Duration
+	retryAt     time.Time
+
+	written       atomic.Int64
+	dropped       atomic.Int64
+	lost          atomic.Int64
+	saved         atomic.Int64
+	errors        atomic.Int64
+	writeFailures atomic.Int64
+	pendingCount  atomic.Int64
+	oldest        atomic.Int64 // Время получения самой старой несохраненной записи, нс Unix (0 - нет)
+	lastLog       atomic.Int64 // Время последней записи ошибки в лог, нс Unix
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Open открывает (создает) базу данных; sessions возвращает текущие отчеты по тестам,
+// которые периодически сохраняются в таблицу sessions
+func Open(cfg Config, logger *zap.Logger, sessions func() []*models.SessionReport) (*Store, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("не указан файл базы данных")
+	}
+	if cfg.QueueSize <= 0 {
+		return nil, fmt.Errorf("некорректный размер очереди: %d", cfg.QueueSize)
+	}
+	if cfg.BatchSize <= 0 {
+		return nil, fmt.Errorf("некорректный размер пакета: %d", cfg.BatchSize)
+	}
+	if cfg.FlushInterval <= 0 {
+		return nil, fmt.Errorf("некорректный период сохранения: %s", cfg.FlushInterval)
+	}
+	if cfg.MaxPending < cfg.BatchSize {
+		return nil, fmt.Errorf("предел несохраненных записей %d меньше размера пакета %d", cfg.MaxPending, cfg.BatchSize)
+	}
+	if dir := filepath.Dir(cfg.Path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("не удалось создать директорию базы данных: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", cfg.Path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия базы данных: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ошибка создания таблиц: %w", err)
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ошибка обновления таблиц: %w", err)
+	}
+
+	s := &Store{
+		config:   cfg,
+		logger:   logger,
+		db:       db,
+		sessions: sessions,
+		queue:    make(chan MessageRecord, cfg.QueueSize),
+		pending:  make([]MessageRecord, 0, cfg.BatchSize),
+		stopChan: make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.writeLoop()
+
+	return s, nil
+}
+
+// migrate добавляет в таблицу sessions столбцы, которых нет в базе прежней версии
+func migrate(db *sql.DB) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('sessions')`)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range sessionColumns {
+		if existing[column.name] {
+			continue
+		}
+		if _, err := db.Exec(`ALTER TABLE sessions ADD COLUMN ` + column.name + ` ` + column.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordMessage ставит запись в очередь на сохранение. При переполненной очереди
+// запись отбрасывается и учитывается в messages_dropped, чтобы не задерживать прием
+func (s *Store) RecordMessage(record MessageRecord) {
+	if s == nil {
+		return
+	}
+	if !s.config.ValidMessages && record.Valid && !record.Stale {
+		return
+	}
+
+	select {
+	case s.queue <- record:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// writeLoop сохраняет записи пакетами, периодически сохраняет отчеты по тестам
+// и удаляет записи старше срока хранения
+func (s *Store) writeLoop() {
+	defer s.wg.Done()
+
+	flush := time.NewTicker(s.config.FlushInterval)
+	defer flush.Stop()
+	sessions := time.NewTicker(sessionsInterval)
+	defer sessions.Stop()
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+
+	for {
+		// Пока несохраненных записей больше предела, очередь не читается: новые записи
+		// отбрасываются при постановке в очередь, а принятые сохраняются
+		queue := s.queue
+		if s.pendingLen() >= s.config.MaxPending {
+			queue = nil
+		}
+
+		select {
+		case record := <-queue:
+			s.addPending(record)
+			if s.pendingLen() >= s.config.BatchSize {
+				s.flush()
+			}
+		case <-flush.C:
+			s.flush()
+		case <-sessions.C:
+			s.saveSessions()
+		case <-prune.C:
+			s.prune()
+		case <-s.stopChan:
+			// Дописываем оставшиеся в очереди записи
+			for len(s.queue) > 0 {
+				s.addPending(<-s.queue)
+			}
+			s.flushOnClose()
+			s.saveSessions()
+			return
+		}
+	}
+}
+
+// pendingLen возвращает количество несохраненных записей
+func (s *Store) pendingLen() int {
+	return len(s.pending) - s.pendingHead
+}
+
+// addPending добавляет запись к несохраненным
+func (s *Store) addPending(record MessageRecord) {
+	if s.pendingLen() == 0 {
+		s.oldest.Store(record.ReceivedAt.UnixNano())
+	}
+	s.pending = append(s.pending, record)
+	s.pendingCount.Store(int64(s.pendingLen()))
+}
+
+// flush сохраняет несохраненные записи пакетами по BatchSize в отдельных транзакциях.
+// После ошибки записи остаются в памяти, а следующая попытка выполняется не раньше
+// паузы, которая удваивается после каждой неудачи
+func (s *Store) flush() {
+	for s.pendingLen() > 0 && !time.Now().Before(s.retryAt) {
+		if err := s.flushBatch(); err != nil {
+			s.retryDelay = min(max(s.retryDelay*2, retryMinDelay), retryMaxDelay)
+			s.retryAt = time.Now().Add(s.retryDelay)
+			return
+		}
+	}
+}
+
+// flushBatch сохраняет первый пакет несохраненных записей
+func (s *Store) flushBatch() error {
+	n := min(s.pendingLen(), s.config.BatchSize)
+	if err := s.insertMessages(s.pending[s.pendingHead : s.pendingHead+n]); err != nil {
+		s.writeFailures.Add(1)
+		s.recordError("Ошибка сохранения записей о сообщениях", err)
+		return err
+	}
+
+	s.written.Add(int64(n))
+	s.retryDelay = 0
+	s.retryAt = time.Time{}
+	s.pendingHead += n
+	s.compactPending()
+	s.pendingCount.Store(int64(s.pendingLen()))
+	if s.pendingLen() > 0 {
+		s.oldest.Store(s.pending[s.pendingHead].ReceivedAt.UnixNano())
+	} else {
+		s.oldest.Store(0)
+	}
+	return nil
+}
+
+// compactPending освобождает место сохраненных записей в начале pending. Остаток
+// сдвигается, только когда он не больше сохраненной части, поэтому сдвиг обходится
+// в O(1) на сохраненную запись, а не O(pending) на каждый пакет
+func (s *Store) compactPending() {
+	switch {
+	case s.pendingLen() == 0:
+		clear(s.pending)
+		s.pending, s.pendingHead = s.pending[:0], 0
+	case s.pendingHead >= s.pendingLen():
+		n := copy(s.pending, s.pending[s.pendingHead:])
+		clear(s.pending[n:])
+		s.pending, s.pendingHead = s.pending[:n], 0
+	}
+}
+
+// flushOnClose сохраняет оставшиеся записи при закрытии, повторяя неудавшиеся
+// транзакции до истечения ShutdownTimeout; несохраненные записи учитываются как потерянные
+func (s *Store) flushOnClose() {
+	deadline := time.Now().Add(s.config.ShutdownTimeout)
+	delay := retryMinDelay
+	for s.pendingLen() > 0 {
+		if s.flushBatch() == nil {
+			continue
+		}
+		if time.Now().Add(delay).After(deadline) {
+			break
+		}
+		time.Sleep(delay)
+		delay = min(delay*2, retryMaxDelay)
+	}
+
+	if s.pendingLen() > 0 {
+		s.lost.Add(int64(s.pendingLen()))
+		s.logger.Error("Записи о сообщениях не сохранены при закрытии хранилища",
+			zap.Int("records", s.pendingLen()))
+		clear(s.pending)
+		s.pending, s.pendingHead = s.pending[:0], 0
+		s.pendingCount.Store(0)
+		s.oldest.Store(0)
+	}
+}
+
+// insertMessages вставляет записи о сообщениях
+func (s *Store) insertMessages(batch []MessageRecord) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO messages
+		(test_id, sequence, message_id, received_at, latency_ms, size, valid, stale, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range batch {
+		if _, err := stmt.Exec(r.TestID, r.Sequence, r.MessageID, r.ReceivedAt.UnixNano(),
+			r.LatencyMs, r.Size, r.Valid, r.Stale, r.Error); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// saveSessions сохраняет текущие отчеты по тестам
+func (s *Store) saveSessions() {
+	if s.sessions == nil {
+		return
+	}
+	reports := s.sessions()
+	if len(reports) == 0 {
+		return
+	}
+
+	if err := s.upsertSessions(reports); err != nil {
+		s.recordError("Ошибка сохранения отчетов по тестам", err)
+		return
+	}
+	s.saved.Add(int64(len(reports)))
+}
+
+// upsertSessions вставляет или обновляет отчеты по тестам
+func (s *Store) upsertSessions(reports []*models.SessionReport) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO sessions
+		(test_id, tenant, run_label, received, unique_count, duplicates, retry_duplicates, max_sequence, missing,
+		 missing_ranges, out_of_order, stale, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (test_id) DO UPDATE SET
+			tenant = excluded.tenant,
+			run_label = excluded.run_label,
+			received = excluded.received,
+			unique_count = excluded.unique_count,
+			duplicates = excluded.duplicates,
+			retry_duplicates = excluded.retry_duplicates,
+			max_sequence = excluded.max_sequence,
+			missing = excluded.missing,
+			missing_ranges = excluded.missing_ranges,
+			out_of_order = excluded.out_of_order,
+			stale = excluded.stale,
+			first_seen = excluded.first_seen,
+			last_seen = excluded.last_seen`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range reports {
+		ranges, err := json.Marshal(r.MissingRanges)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(r.TestID, r.Tenant, r.RunLabel, r.Received, r.Unique, r.Duplicates, r.RetryDuplicates, r.MaxSequence, r.Missing,
+			string(ranges), r.OutOfOrder, r.Stale, r.FirstSeen.UnixNano(), r.LastSeen.UnixNano()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// prune удаляет записи о сообщениях и отчеты по тестам старше срока хранения
+func (s *Store) prune() {
+	if s.config.Retention <= 0 {
+		return
+	}
+
+	cutoff := time.Now().Add(-s.config.Retention).UnixNano()
+	if _, err := s.db.Exec(`DELETE FROM messages WHERE received_at < ?`, cutoff); err != nil {
+		s.recordError("Ошибка удаления устаревших записей", err)
+		return
+	}
+	if _, err := s.db.Exec(`DELETE FROM sessions WHERE last_seen < ?`, cutoff); err != nil {
+		s.recordError("Ошибка удаления устаревших отчетов", err)
+	}
+}
+
+// Sessions возвращает сохраненные отчеты по тестам, начиная с последнего активного
+func (s *Store) Sessions(filter SessionFilter) ([]*models.SessionReport, error) {
+	query := `SELECT ` + sessionFields + ` FROM sessions WHERE 1 = 1`
+	var args []interface{}
+	if filter.Tenant != "" {
+		query += ` AND tenant = ?`
+		args = append(args, filter.Tenant)
+	}
+	if filter.RunLabel != "" {
+		query += ` AND run_label = ?`
+		args = append(args, filter.RunLabel)
+	}
+	query += ` ORDER BY last_seen DESC LIMIT ?`
+	args = append(args, filter.Limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []*models.SessionReport{}
+	for rows.Next() {
+		report, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// Session возвращает сохраненный отчет по тесту
+func (s *Store) Session(testID string) (*models.SessionReport, bool, error) {
+	row := s.db.QueryRow(`SELECT `+sessionFields+` FROM sessions WHERE test_id = ?`, testID)
+
+	report, err := scanSession(row)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return report, true, nil
+}
+
+// scanSession читает отчет по тесту из строки результата
+func scanSession(row interface{ Scan(...interface{}) error }) (*models.SessionReport, error) {
+	var report models.SessionReport
+	var ranges string
+	var firstSeen, lastSeen int64
+	if err := row.Scan(&report.TestID, &report.Tenant, &report.RunLabel, &report.Received, &report.Unique, &report.Duplicates,
+		&report.RetryDuplicates, &report.MaxSequence, &report.Missing, &ranges, &report.OutOfOrder, &report.Stale,
+		&firstSeen, &lastSeen); err != nil {
+		return nil, err
+	}
+
+	report.MissingRanges = []models.SequenceRange{}
+	if err := json.Unmarshal([]byte(ranges), &report.MissingRanges); err != nil {
+		return nil, fmt.Errorf("некорректные диапазоны пропусков теста %s: %w", report.TestID, err)
+	}
+	report.FirstSeen = time.Unix(0, firstSeen)
+	report.LastSeen = time.Unix(0, lastSeen)
+	return &report, nil
+}
+
+// Messages возвращает записи о сообщениях теста в порядке номеров
+func (s *Store) Messages(testID string, filter MessageFilter) ([]MessageRecord, error) {
+	query := `SELECT test_id, sequence, message_id, received_at, latency_ms, size, valid, stale, error
+		FROM messages WHERE test_id = ? AND sequence > ?`
+	if filter.InvalidOnly {
+		query += ` AND valid = 0`
+	}
+	if filter.StaleOnly {
+		query += ` AND stale = 1`
+	}
+	query += ` ORDER BY sequence, id LIMIT ?`
+
+	rows, err := s.db.Query(query, testID, filter.After, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []MessageRecord{}
+	for rows.Next() {
+		var r MessageRecord
+		var receivedAt int64
+		var latency sql.NullFloat64
+		if err := rows.Scan(&r.TestID, &r.Sequence, &r.MessageID, &receivedAt, &latency,
+			&r.Size, &r.Valid, &r.Stale, &r.Error); err != nil {
+			return nil, err
+		}
+		r.ReceivedAt = time.Unix(0, receivedAt)
+		if latency.Valid {
+			r.LatencyMs = &latency.Float64
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// Stats возвращает статистику хранилища
+func (s *Store) Stats() Stats {
+	var lag float64
+	if oldest := s.oldest.Load(); oldest > 0 {
+		lag = time.Since(time.Unix(0, oldest)).Seconds()
+	}
+	return Stats{
+		Path:            s.config.Path,
+		MessagesWritten: s.written.Load(),
+		MessagesDropped: s.dropped.Load(),
+		MessagesLost:    s.lost.Load(),
+		SessionsSaved:   s.saved.Load(),
+		Queued:          len(s.queue),
+		Pending:         s.pendingCount.Load(),
+		LagSeconds:      lag,
+		WriteFailures:   s.writeFailures.Load(),
+		Errors:          s.errors.Load(),
+	}
+}
+
+// recordError учитывает ошибку и пишет ее в лог не чаще раза в минуту
+func (s *Store) recordError(msg string, err error) {
+	s.errors.Add(1)
+
+	now := time.Now().UnixNano()
+	last := s.lastLog.Load()
+	if now-last < int64(errorLogEvery) || !s.lastLog.CompareAndSwap(last, now) {
+		return
+	}
+	s.logger.Error(msg, zap.Error(err), zap.Int64("errors", s.errors.Load()))
+}
+
+// Close сохраняет оставшиеся записи и закрывает базу данных
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+
+	close(s.stopChan)
+	s.wg.Wait()
+	return s.db.Close()
+}
@@ -2,30 +2,164 @@ package tcp
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net"
+	"net/netip"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/infodiode/recipient/internal/archive"
 	"github.com/infodiode/recipient/internal/processor"
 	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/tcpframe"
 	"go.uber.org/zap"
 )
 
+// maxFrameSize максимальный размер кадра (сообщения или пакета)
+const maxFrameSize = 100 * 1024 * 1024
+
+// Причины отклонения подключения
+const (
+	RejectNotAllowed = "not_allowed" // Адрес клиента не входит в разрешенные сети
+	RejectLimit      = "limit"       // Достигнуто максимальное количество подключений
+)
+
+// keepAliveProbes неотвеченных проб TCP keep-alive до разрыва соединения
+const keepAliveProbes = 3
+
+// errFraming ошибка, после которой границы кадров соединения потеряны и соединение закрывается
+var errFraming = errors.New("нарушен формат кадров")
+
+// rejectLogEvery минимальный интервал записи в лог отклоненных подключений,
+// чтобы поток подключений с чужих адресов не переполнял лог
+const rejectLogEvery = 10 * time.Second
+
 // TCPServer сервер для приема данных по TCP
 type TCPServer struct {
-	address   string
-	listener  net.Listener
-	logger    *zap.Logger
-	processor *processor.MessageProcessor
-	wg        sync.WaitGroup
-	stopChan  chan struct{}
-	isRunning bool
-	mu        sync.RWMutex
-	stats     *ServerStats
+	address         string
+	maxConns        int           // Максимум одновременных подключений (0 - без ограничения)
+	readTimeout     time.Duration // Период проверки остановки сервера при ожидании данных
+	drainTimeout    time.Duration // Ожидание завершения кадров подключений при остановке
+	writeTimeout    time.Duration // Таймаут записи ответов протокола v2
+	keepAlive       bool
+	keepAlivePeriod time.Duration
+	socket          tcpframe.SocketOptions
+	pingInterval    time.Duration  // Период ping сервера в протоколе v2 (0 - не отправлять)
+	pingTimeout     time.Duration  // Предельное время без pong клиента, после которого подключение закрывается
+	allowed         []netip.Prefix // Разрешенные сети клиентов (пусто - любые)
+	listener        net.Listener
+	logger          *zap.Logger
+	processor       *processor.MessageProcessor
+	archive         *archive.Writer // Архив принятых кадров, nil если отключен
+	wg              sync.WaitGroup
+	stopChan        chan struct{}
+	isRunning       bool
+	mu              sync.RWMutex
+	stats           *ServerStats
+	connMu          sync.RWMutex
+	conns           map[uint64]*connState // Активные подключения по идентификатору
+	connSeq         atomic.Uint64
+	lastRejectLog   time.Time // Время последней записи об отклонении (только в горутине приема)
+}
+
+// connState статистика одного подключения
+type connState struct {
+	id           uint64
+	conn         net.Conn
+	remoteAddr   string
+	connectedAt  time.Time
+	messages     atomic.Int64
+	batches      atomic.Int64
+	bytes        atomic.Int64
+	errors       atomic.Int64
+	pings        atomic.Int64
+	rawBytes     atomic.Int64 // Байт кадров-заполнителей теста пропускной способности
+	v2           atomic.Bool  // Согласован протокол v2
+	checksum     atomic.Bool  // Согласована контрольная сумма кадров
+	frameErrors  atomic.Int64 // Кадров с несовпавшей контрольной суммой
+	lastActivity atomic.Int64 // Время последнего чтения данных (unix nano)
+
+	writeMu    sync.Mutex    // Запись ответов и ping сервера кадрами целиком
+	serverPing atomic.Bool   // Согласован ping сервера
+	pingsSent  atomic.Int64  // Отправлено ping сервера
+	lastPong   atomic.Int64  // Время последнего pong клиента или согласования (unix nano)
+	rtt        tcpframe.RTT  // Время прохождения ping сервера
+	done       chan struct{} // Закрывается при завершении обработки подключения
+
+	drainMu  sync.Mutex
+	waiting  bool // Подключение ожидает следующий кадр (под drainMu)
+	draining bool // Сервер останавливается: новые кадры не читаются (под drainMu)
+}
+
+// write записывает кадр frame в подключение с таймаутом timeout; кадры ответов и
+// ping сервера пишутся из разных горутин, поэтому запись выполняется под writeMu
+func (c *connState) write(frame []byte, timeout time.Duration) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(timeout))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// pingRTT возвращает время прохождения ping сервера, nil - ping сервера не согласован
+func (c *connState) pingRTT() *tcpframe.RTTStats {
+	if !c.serverPing.Load() {
+		return nil
+	}
+	stats := c.rtt.Stats()
+	return &stats
+}
+
+// touch отмечает активность подключения
+func (c *connState) touch() {
+	c.lastActivity.Store(time.Now().UnixNano())
+}
+
+// beginWait переводит подключение в ожидание следующего кадра с таймаутом чтения timeout;
+// false, если сервер останавливается и подключение нужно закрыть
+func (c *connState) beginWait(timeout time.Duration) bool {
+	c.drainMu.Lock()
+	defer c.drainMu.Unlock()
+
+	if c.draining {
+		return false
+	}
+	c.waiting = true
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
+	return true
+}
+
+// endWait отмечает начало чтения кадра. Если остановка прервала ожидание в момент
+// поступления кадра, таймаут чтения восстанавливается, чтобы кадр был дочитан
+func (c *connState) endWait(timeout time.Duration) {
+	c.drainMu.Lock()
+	defer c.drainMu.Unlock()
+
+	c.waiting = false
+	if c.draining {
+		c.conn.SetReadDeadline(time.Now().Add(timeout))
+	}
+}
+
+// drain уведомляет подключение об остановке сервера: ожидание следующего кадра
+// прерывается сразу, читаемый кадр дочитывается и обрабатывается
+func (c *connState) drain() {
+	c.drainMu.Lock()
+	defer c.drainMu.Unlock()
+
+	c.draining = true
+	if c.waiting {
+		c.conn.SetReadDeadline(time.Now())
+	}
 }
 
 // ServerStats статистика работы сервера
@@ -35,33 +169,72 @@ type ServerStats struct {
 	MessagesReceived  int64
 	BatchesReceived   int64
 	BytesReceived     int64
+	RawFramesReceived int64 // Кадры-заполнители теста пропускной способности (в сообщениях не учитываются)
+	RawBytesReceived  int64
 	Errors            int64
+	Rejected          map[string]int64 // Отклоненные подключения по причинам
 	LastMessageTime   time.Time
 	mu                sync.RWMutex
+
+	FrameChecksumErrors int64 // Кадров с несовпавшей контрольной суммой (протокол v2)
 }
 
 // Config конфигурация TCP сервера
 type Config struct {
-	Address         string        `yaml:"address" json:"address"`
-	MaxConnections  int           `yaml:"max_connections" json:"max_connections"`
-	ReadTimeout     time.Duration `yaml:"read_timeout" json:"read_timeout"`
-	WriteTimeout    time.Duration `yaml:"write_timeout" json:"write_timeout"`
-	KeepAlive       bool          `yaml:"keep_alive" json:"keep_alive"`
-	KeepAlivePeriod time.Duration `yaml:"keep_alive_period" json:"keep_alive_period"`
+	Address         string         `yaml:"address" json:"address"`
+	MaxConnections  int            `yaml:"max_connections" json:"max_connections"`
+	ReadTimeout     time.Duration  `yaml:"read_timeout" json:"read_timeout"`
+	DrainTimeout    time.Duration  `yaml:"drain_timeout" json:"drain_timeout"` // Ожидание завершения кадров при остановке
+	WriteTimeout    time.Duration  `yaml:"write_timeout" json:"write_timeout"`
+	KeepAlive       bool           `yaml:"keep_alive" json:"keep_alive"`
+	KeepAlivePeriod time.Duration  `yaml:"keep_alive_period" json:"keep_alive_period"`
+	PingInterval    time.Duration  `yaml:"ping_interval" json:"ping_interval"` // Период ping сервера (0 - не отправлять)
+	PingTimeout     time.Duration  `yaml:"ping_timeout" json:"ping_timeout"`   // Предельное время без pong (0 - три периода ping)
+	AllowedNetworks []netip.Prefix `yaml:"-" json:"allowed_networks"`          // Разрешенные сети клиентов (пусто - любые)
+
+	Socket tcpframe.SocketOptions `yaml:"-" json:"socket"` // Буферы, TCP_NODELAY и SO_LINGER подключений
 }
 
 // NewTCPServer создает новый TCP сервер
-func NewTCPServer(config *Config, logger *zap.Logger, processor *processor.MessageProcessor) (*TCPServer, error) {
+func NewTCPServer(config *Config, logger *zap.Logger, processor *processor.MessageProcessor, archiver *archive.Writer) (*TCPServer, error) {
 	if config.Address == "" {
 		return nil, fmt.Errorf("TCP адрес не указан")
 	}
 
 	server := &TCPServer{
-		address:   config.Address,
-		logger:    logger,
-		processor: processor,
-		stopChan:  make(chan struct{}),
-		stats:     &ServerStats{},
+		address:         config.Address,
+		maxConns:        config.MaxConnections,
+		readTimeout:     config.ReadTimeout,
+		drainTimeout:    config.DrainTimeout,
+		writeTimeout:    config.WriteTimeout,
+		keepAlive:       config.KeepAlive,
+		keepAlivePeriod: config.KeepAlivePeriod,
+		pingInterval:    config.PingInterval,
+		pingTimeout:     config.PingTimeout,
+		allowed:         config.AllowedNetworks,
+		socket:          config.Socket,
+		logger:          logger,
+		processor:       processor,
+		archive:         archiver,
+		stopChan:        make(chan struct{}),
+		stats:           &ServerStats{Rejected: make(map[string]int64)},
+		conns:           make(map[uint64]*connState),
+	}
+
+	if server.readTimeout == 0 {
+		server.readTimeout = 60 * time.Second
+	}
+	if server.drainTimeout == 0 {
+		server.drainTimeout = 10 * time.Second
+	}
+	if server.writeTimeout == 0 {
+		server.writeTimeout = 10 * time.Second
+	}
+	if server.keepAlivePeriod == 0 {
+		server.keepAlivePeriod = 30 * time.Second
+	}
+	if server.pingTimeout == 0 {
+		server.pingTimeout = 3 * server.pingInterval
 	}
 
 	return server, nil
@@ -93,7 +266,8 @@ func (s *TCPServer) Start() error {
 	return nil
 }
 
-// Stop останавливает TCP сервер
+// Stop останавливает TCP сервер: прекращает прием подключений, ожидает завершения
+// читаемых кадров не дольше drain_timeout и закрывает оставшиеся подключения
 func (s *TCPServer) Stop() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -102,7 +276,7 @@ func (s *TCPServer) Stop() error {
 		return nil
 	}
 
-	s.logger.Info("Остановка TCP сервера")
+	s.logger.Info("Остановка TCP сервера", zap.Duration("drain_timeout", s.drainTimeout))
 
 	close(s.stopChan)
 	s.isRunning = false
@@ -111,13 +285,49 @@ func (s *TCPServer) Stop() error {
 		s.listener.Close()
 	}
 
-	// Ждем завершения всех горутин
-	s.wg.Wait()
+	// Подключения, ожидающие кадр, закрываются сразу; читаемые кадры дочитываются
+	s.connMu.RLock()
+	active := len(s.conns)
+	for _, state := range s.conns {
+		state.drain()
+	}
+	s.connMu.RUnlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	forceClosed := 0
+	select {
+	case <-done:
+	case <-time.After(s.drainTimeout):
+		forceClosed = s.closeConnections()
+		<-done
+	}
 
-	s.logger.Info("TCP сервер остановлен")
+	s.logger.Info("TCP сервер остановлен",
+		zap.Int("drained", active-forceClosed),
+		zap.Int("force_closed", forceClosed))
 	return nil
 }
 
+// closeConnections принудительно закрывает подключения, не завершившие кадр за
+// drain_timeout; возвращает количество закрытых подключений
+func (s *TCPServer) closeConnections() int {
+	s.connMu.RLock()
+	defer s.connMu.RUnlock()
+
+	for _, state := range s.conns {
+		s.logger.Warn("Подключение не завершило кадр за время остановки и закрыто принудительно",
+			zap.String("client", state.remoteAddr),
+			zap.Duration("idle", time.Since(time.Unix(0, state.lastActivity.Load()))))
+		state.conn.Close()
+	}
+	return len(s.conns)
+}
+
 // acceptConnections принимает входящие подключения
 func (s *TCPServer) acceptConnections() {
 	defer s.wg.Done()
@@ -136,46 +346,62 @@ func (s *TCPServer) acceptConnections() {
 				return
 			default:
 				s.logger.Error("Ошибка принятия подключения", zap.Error(err))
-				s.incrementErrorCount()
+				s.incrementErrorCount(nil)
 				continue
 			}
 		}
 
-		s.incrementConnectionCount()
+		if reason := s.admit(conn); reason != "" {
+			s.rejectConnection(conn, reason)
+			continue
+		}
+
+		state := s.registerConnection(conn)
 		s.wg.Add(1)
-		go s.handleConnection(conn)
+		go s.handleConnection(conn, state)
 	}
 }
 
 // handleConnection обрабатывает подключение клиента
-func (s *TCPServer) handleConnection(conn net.Conn) {
+func (s *TCPServer) handleConnection(conn net.Conn, state *connState) {
 	defer s.wg.Done()
 	defer conn.Close()
-	defer s.decrementConnectionCount()
+	defer s.unregisterConnection(state)
+	defer close(state.done)
 
-	clientAddr := conn.RemoteAddr().String()
+	clientAddr := state.remoteAddr
 	s.logger.Info("Новое подключение", zap.String("client", clientAddr))
 
-	// Устанавливаем keep-alive
+	// Обрыв соединения без закрытия обнаруживается средствами TCP keep-alive
 	if tcpConn, ok := conn.(*net.TCPConn); ok {
-		tcpConn.SetKeepAlive(true)
-		tcpConn.SetKeepAlivePeriod(30 * time.Second)
+		tcpConn.SetKeepAliveConfig(net.KeepAliveConfig{
+			Enable:   s.keepAlive,
+			Idle:     s.keepAlivePeriod,
+			Interval: s.keepAlivePeriod,
+			Count:    keepAliveProbes,
+		})
 	}
 
-	reader := bufio.NewReader(conn)
+	if err := s.socket.Apply(conn); err != nil {
+		// Подключение работает и с параметрами ОС
+		s.logger.Warn("Не удалось применить параметры сокета",
+			zap.String("client", clientAddr),
+			zap.Error(err))
+	}
+
+	reader := bufio.NewReaderSize(conn, s.socket.BufferSize())
+	first := true
 
 	for {
-		select {
-		case <-s.stopChan:
+		// Ожидание кадра с таймаутом чтения; при остановке сервера подключение закрывается
+		if !state.beginWait(s.readTimeout) {
 			return
-		default:
 		}
 
-		// Устанавливаем таймаут на чтение
-		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-
-		// Читаем первый байт для определения типа сообщения
-		firstByte, err := reader.ReadByte()
+		// Первый байт определяет тип кадра и не извлекается: в исходном формате
+		// одиночное сообщение начинается сразу с длины
+		peek, err := reader.Peek(1)
+		state.endWait(s.readTimeout)
 		if err != nil {
 			if err == io.EOF {
 				s.logger.Info("Клиент закрыл соединение", zap.String("client", clientAddr))
@@ -185,69 +411,232 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 				// Таймаут - продолжаем ждать
 				continue
 			}
-			if firstByte != 0x00 { // Игнорируем keep-alive пакеты
-				s.logger.Error("Ошибка чтения данных", zap.String("client", clientAddr), zap.Error(err))
-				s.incrementErrorCount()
-			}
+			s.logger.Error("Ошибка чтения данных", zap.String("client", clientAddr), zap.Error(err))
+			s.incrementErrorCount(state)
 			return
 		}
+		state.touch()
+		frameType := peek[0]
 
-		// Обрабатываем в зависимости от типа
-		if firstByte == 0x01 {
-			// Пакетная отправка
-			if err := s.handleBatch(reader, clientAddr); err != nil {
-				s.logger.Error("Ошибка обработки пакета", zap.String("client", clientAddr), zap.Error(err))
-				s.incrementErrorCount()
-			}
-		} else if firstByte == 0x00 {
-			// Keep-alive пакет - игнорируем
-			continue
-		} else {
-			// Обычное сообщение - возвращаем байт обратно
-			reader.UnreadByte()
-			if err := s.handleMessage(reader, clientAddr); err != nil {
-				s.logger.Error("Ошибка обработки сообщения", zap.String("client", clientAddr), zap.Error(err))
-				s.incrementErrorCount()
+		switch {
+		case first && tcpframe.IsHello(reader):
+			// Приветствие допускается только первым кадром соединения
+			err = s.handleHello(conn, reader, state)
+		case frameType == tcpframe.TypeBatch:
+			// Пакетная отправка (одинакова в протоколе v2 и исходном формате)
+			reader.Discard(1)
+			err = s.handleBatch(reader, state)
+		case state.v2.Load():
+			reader.Discard(1)
+			err = s.handleFrame(reader, state, frameType)
+		default:
+			// Одиночное сообщение исходного формата
+			err = s.handleMessage(reader, state)
+		}
+		first = false
+
+		if err != nil {
+			s.logger.Error("Ошибка обработки кадра", zap.String("client", clientAddr), zap.Error(err))
+			s.incrementErrorCount(state)
+			if errors.Is(err, errFraming) {
+				return
 			}
 		}
 	}
 }
 
+// handleHello отвечает на приветствие клиента и переводит соединение на протокол v2;
+// из возможностей клиента подтверждаются поддерживаемые сервером
+func (s *TCPServer) handleHello(conn net.Conn, reader *bufio.Reader, state *connState) error {
+	hello, err := tcpframe.ReadHello(reader)
+	if err != nil {
+		return fmt.Errorf("%w: ошибка чтения приветствия: %v", errFraming, err)
+	}
+
+	supported := tcpframe.FeaturePing | tcpframe.FeatureRaw | tcpframe.FeatureChecksum
+	if s.pingInterval > 0 {
+		supported |= tcpframe.FeatureServerPing
+	}
+	reply := tcpframe.Hello{Version: tcpframe.Version, Features: hello.Features & supported}
+	if err := state.write(tcpframe.AppendHello(nil, reply), s.writeTimeout); err != nil {
+		return fmt.Errorf("%w: ошибка ответа на приветствие: %v", errFraming, err)
+	}
+	state.v2.Store(true)
+	state.checksum.Store(reply.Has(tcpframe.FeatureChecksum))
+
+	s.logger.Info("Согласован протокол v2",
+		zap.String("client", state.remoteAddr),
+		zap.Uint8("client_version", hello.Version),
+		zap.Bool("ping", reply.Has(tcpframe.FeaturePing)),
+		zap.Bool("server_ping", reply.Has(tcpframe.FeatureServerPing)),
+		zap.Bool("raw", reply.Has(tcpframe.FeatureRaw)),
+		zap.Bool("checksum", reply.Has(tcpframe.FeatureChecksum)))
+
+	if reply.Has(tcpframe.FeatureServerPing) {
+		state.serverPing.Store(true)
+		state.lastPong.Store(time.Now().UnixNano())
+		s.wg.Add(1)
+		go s.pingClient(state)
+	}
+
+	return nil
+}
+
+// pingClient отправляет клиенту ping каждые ping_interval до завершения подключения и
+// закрывает подключение, если pong не было дольше ping_timeout. Pong читается вместе с
+// кадрами данных, поэтому время прохождения включает обработку кадров, принятых до него
+func (s *TCPServer) pingClient(state *connState) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-state.done:
+			return
+		case <-ticker.C:
+		}
+
+		if silence := time.Since(time.Unix(0, state.lastPong.Load())); silence > s.pingTimeout {
+			s.logger.Warn("Нет ответа на ping, подключение закрывается",
+				zap.String("client", state.remoteAddr),
+				zap.Duration("silence", silence.Round(time.Millisecond)))
+			state.conn.Close()
+			return
+		}
+
+		if err := state.write(tcpframe.AppendPing(nil, tcpframe.TypePing, time.Now()), s.writeTimeout); err != nil {
+			// Ошибка записи означает разрыв; чтение обнаружит его и завершит подключение
+			s.logger.Warn("Ошибка отправки ping", zap.String("client", state.remoteAddr), zap.Error(err))
+			state.conn.Close()
+			return
+		}
+		state.pingsSent.Add(1)
+	}
+}
+
+// handleFrame обрабатывает кадр протокола v2 (тип кадра уже прочитан)
+func (s *TCPServer) handleFrame(reader *bufio.Reader, state *connState, frameType byte) error {
+	switch frameType {
+	case tcpframe.TypeMessage:
+		return s.handleMessage(reader, state)
+	case tcpframe.TypePing:
+		return s.handlePing(reader, state)
+	case tcpframe.TypePong:
+		return s.handlePong(reader, state)
+	case tcpframe.TypeRaw:
+		return s.handleRaw(reader, state)
+	default:
+		return fmt.Errorf("%w: неизвестный тип кадра 0x%02x", errFraming, frameType)
+	}
+}
+
+// handlePing отвечает на ping клиента кадром pong с тем же временем отправки
+func (s *TCPServer) handlePing(reader *bufio.Reader, state *connState) error {
+	length, err := readFrameLength(reader)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения длины ping: %w", err)
+	}
+	sent, err := tcpframe.ReadPing(reader, length)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errFraming, err)
+	}
+
+	if err := state.write(tcpframe.AppendPing(nil, tcpframe.TypePong, sent), s.writeTimeout); err != nil {
+		return fmt.Errorf("%w: ошибка отправки pong: %v", errFraming, err)
+	}
+	state.pings.Add(1)
+
+	return nil
+}
+
+// handlePong учитывает ответ клиента на ping сервера
+func (s *TCPServer) handlePong(reader *bufio.Reader, state *connState) error {
+	length, err := readFrameLength(reader)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения длины pong: %w", err)
+	}
+	sent, err := tcpframe.ReadPing(reader, length)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errFraming, err)
+	}
+	if !state.serverPing.Load() {
+		return fmt.Errorf("pong без согласованного ping сервера")
+	}
+
+	state.rtt.Observe(sent)
+	state.lastPong.Store(time.Now().UnixNano())
+	return nil
+}
+
+// handleRaw пропускает кадр-заполнитель теста пропускной способности: тело не разбирается,
+// не архивируется и не передается обработчику, учитывается только объем
+func (s *TCPServer) handleRaw(reader *bufio.Reader, state *connState) error {
+	length, err := readFrameLength(reader)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения длины кадра-заполнителя: %w", err)
+	}
+	if length > maxFrameSize {
+		return fmt.Errorf("%w: слишком большой кадр-заполнитель: %d байт", errFraming, length)
+	}
+	if _, err := reader.Discard(int(length)); err != nil {
+		return fmt.Errorf("ошибка чтения кадра-заполнителя: %w", err)
+	}
+
+	size := int64(tcpframe.HeaderSize) + int64(length)
+	state.rawBytes.Add(size)
+	s.processor.ObserveReceive(archive.SourceTCP.String(), 0, int(size))
+
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+	s.stats.RawFramesReceived++
+	s.stats.RawBytesReceived += size
+
+	return nil
+}
+
 // handleMessage обрабатывает одиночное сообщение
-func (s *TCPServer) handleMessage(reader *bufio.Reader, clientAddr string) error {
-	// Читаем длину сообщения (4 байта)
-	lengthBytes := make([]byte, 4)
-	if _, err := io.ReadFull(reader, lengthBytes); err != nil {
+func (s *TCPServer) handleMessage(reader *bufio.Reader, state *connState) error {
+	length, err := readFrameLength(reader)
+	if err != nil {
 		return fmt.Errorf("ошибка чтения длины сообщения: %w", err)
 	}
-
-	length := binary.BigEndian.Uint32(lengthBytes)
-	if length > 100*1024*1024 { // Максимум 100MB
+	if length > maxFrameSize {
 		return fmt.Errorf("слишком большое сообщение: %d байт", length)
 	}
+	dataLength, sum, err := frameChecksum(state, length)
+	if err != nil {
+		return err
+	}
 
-	// Читаем само сообщение
-	messageBytes := make([]byte, length)
-	if _, err := io.ReadFull(reader, messageBytes); err != nil {
-		return fmt.Errorf("ошибка чтения сообщения: %w", err)
+	frame, err := s.readFrame(reader, dataLength, archive.KindMessage, sum, state)
+	if err != nil {
+		return fmt.Errorf("%w: ошибка чтения сообщения: %v", errFraming, err)
 	}
+	defer ReleaseFrame(frame)
 
-	// Десериализуем сообщение
 	var message models.Message
-	if err := json.Unmarshal(messageBytes, &message); err != nil {
+	decodeStart := time.Now()
+	err = json.Unmarshal(*frame, &message)
+	s.processor.ObserveDecode(time.Since(decodeStart))
+	if err != nil {
 		return fmt.Errorf("ошибка десериализации сообщения: %w", err)
 	}
 
+	// Порядок проверяется в пределах соединения
+	s.processor.ObserveOrder(archive.SourceTCP.String(), state.remoteAddr, message.TestID, message.Sequence)
+
 	// Обрабатываем сообщение
-	if err := s.processor.ProcessMessage(&message); err != nil {
+	if err := s.processor.ProcessMessageWithSize(&message, int(length)); err != nil {
 		return fmt.Errorf("ошибка обработки сообщения: %w", err)
 	}
 
 	// Обновляем статистику
-	s.incrementMessageCount(int64(length))
+	s.incrementMessageCount(state, int64(length))
 
 	s.logger.Debug("Сообщение получено",
-		zap.String("client", clientAddr),
+		zap.String("client", state.remoteAddr),
 		zap.Int("message_id", message.MessageID),
 		zap.Int("size", int(length)))
 
@@ -255,68 +644,349 @@ func (s *TCPServer) handleMessage(reader *bufio.Reader, clientAddr string) error
 }
 
 // handleBatch обрабатывает пакет сообщений
-func (s *TCPServer) handleBatch(reader *bufio.Reader, clientAddr string) error {
-	// Читаем длину пакета (4 байта)
-	lengthBytes := make([]byte, 4)
-	if _, err := io.ReadFull(reader, lengthBytes); err != nil {
+func (s *TCPServer) handleBatch(reader *bufio.Reader, state *connState) error {
+	length, err := readFrameLength(reader)
+	if err != nil {
 		return fmt.Errorf("ошибка чтения длины пакета: %w", err)
 	}
-
-	length := binary.BigEndian.Uint32(lengthBytes)
-	if length > 100*1024*1024 { // Максимум 100MB
+	if length > maxFrameSize {
 		return fmt.Errorf("слишком большой пакет: %d байт", length)
 	}
-
-	// Читаем данные пакета
-	batchBytes := make([]byte, length)
-	if _, err := io.ReadFull(reader, batchBytes); err != nil {
-		return fmt.Errorf("ошибка чтения пакета: %w", err)
+	dataLength, sum, err := frameChecksum(state, length)
+	if err != nil {
+		return err
 	}
 
-	// Десериализуем пакет
-	var batch models.MessageBatch
-	if err := json.Unmarshal(batchBytes, &batch); err != nil {
-		return fmt.Errorf("ошибка десериализации пакета: %w", err)
+	frame, err := s.readFrame(reader, dataLength, archive.KindBatch, sum, state)
+	if err != nil {
+		return fmt.Errorf("%w: ошибка чтения пакета: %v", errFraming, err)
 	}
+	defer ReleaseFrame(frame)
 
-	// Обрабатываем каждое сообщение в пакете
-	for _, message := range batch.Messages {
-		if err := s.processor.ProcessMessage(message); err != nil {
+	// Сообщения пакета декодируются и обрабатываются по одному; размер сообщения -
+	// длина его JSON в кадре, поэтому обработчику не нужно сериализовать его повторно
+	processed := 0
+	batchCount, err := DecodeBatch(json.NewDecoder(bytes.NewReader(*frame)), s.processor.ObserveDecode, func(message *models.Message, size int) {
+		processed++
+		s.processor.ObserveOrder(archive.SourceTCP.String(), state.remoteAddr, message.TestID, message.Sequence)
+		if err := s.processor.ProcessMessageWithSize(message, size); err != nil {
 			s.logger.Error("Ошибка обработки сообщения из пакета",
 				zap.Int("message_id", message.MessageID),
 				zap.Error(err))
-			s.incrementErrorCount()
+			s.incrementErrorCount(state)
 		}
-	}
+	})
 
-	// Обновляем статистику
-	s.incrementBatchCount(int64(length), len(batch.Messages))
+	// Обновляем статистику (учитываем и сообщения, обработанные до ошибки разбора)
+	s.incrementBatchCount(state, int64(length), processed)
+
+	if err != nil {
+		return fmt.Errorf("ошибка десериализации пакета после %d сообщений: %w", processed, err)
+	}
 
 	s.logger.Info("Пакет сообщений получен",
-		zap.String("client", clientAddr),
-		zap.Int("count", batch.Count),
+		zap.String("client", state.remoteAddr),
+		zap.Int("count", batchCount),
 		zap.Int("size", int(length)))
 
 	return nil
 }
 
-// incrementConnectionCount увеличивает счетчик подключений
-func (s *TCPServer) incrementConnectionCount() {
+// readFrameLength читает длину кадра (4 байта, big-endian)
+func readFrameLength(reader io.Reader) (uint32, error) {
+	var lengthBytes [4]byte
+	if _, err := io.ReadFull(reader, lengthBytes[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(lengthBytes[:]), nil
+}
+
+// frameChecksum возвращает длину данных кадра length без контрольной суммы и вычислитель
+// контрольной суммы, если она согласована для подключения (nil - кадры без нее)
+func frameChecksum(state *connState, length uint32) (uint32, hash.Hash32, error) {
+	if !state.checksum.Load() {
+		return length, nil, nil
+	}
+	if length < tcpframe.ChecksumSize {
+		return 0, nil, fmt.Errorf("%w: кадр короче контрольной суммы: %d байт", errFraming, length)
+	}
+	return length - tcpframe.ChecksumSize, tcpframe.NewChecksum(), nil
+}
+
+// readFrame читает данные кадра длины length в буфер из пула (возвращается ReleaseFrame
+// после разбора) и записывает кадр в архив, если он включен. При согласованной контрольной
+// сумме кадра sum она читается следом и сверяется с данными. Сообщения кадра при
+// несовпадении все равно обрабатываются и проверяются по контрольной сумме payload, поэтому
+// несовпадение учитывается отдельным счетчиком: оно означает искажение кадра при передаче,
+// в том числе вне payload (send_time, sequence, заголовок)
+func (s *TCPServer) readFrame(reader *bufio.Reader, length uint32, kind archive.Kind, sum hash.Hash32, state *connState) (*[]byte, error) {
+	frame, err := ReadFrame(reader, length)
+	if err != nil {
+		return nil, err
+	}
+	if s.archive != nil {
+		s.archive.Write(archive.SourceTCP, kind, time.Now(), *frame)
+	}
+	if sum == nil {
+		return frame, nil
+	}
+
+	expected, err := tcpframe.ReadChecksum(reader)
+	if err != nil {
+		ReleaseFrame(frame)
+		return nil, err
+	}
+	sum.Write(*frame)
+	if actual := sum.Sum32(); actual != expected {
+		state.frameErrors.Add(1)
+		s.stats.mu.Lock()
+		s.stats.FrameChecksumErrors++
+		s.stats.mu.Unlock()
+
+		s.logger.Warn("Несовпадение контрольной суммы кадра",
+			zap.String("client", state.remoteAddr),
+			zap.String("expected", fmt.Sprintf("%08x", expected)),
+			zap.String("actual", fmt.Sprintf("%08x", actual)))
+	}
+
+	return frame, nil
+}
+
+// maxPooledFrameSize буферы кадров больше этого размера не возвращаются в пул,
+// чтобы единичные большие пакеты не удерживали память
+const maxPooledFrameSize = 4 * 1024 * 1024
+
+// framePool буферы данных кадров сообщений и пакетов
+var framePool = sync.Pool{
+	New: func() interface{} {
+		return new([]byte)
+	},
+}
+
+// ReadFrame читает данные кадра длины length в буфер из пула; после разбора кадра
+// буфер возвращается вызовом ReleaseFrame
+func ReadFrame(reader io.Reader, length uint32) (*[]byte, error) {
+	frame := framePool.Get().(*[]byte)
+	if uint32(cap(*frame)) < length {
+		*frame = make([]byte, length)
+	}
+	*frame = (*frame)[:length]
+
+	if _, err := io.ReadFull(reader, *frame); err != nil {
+		ReleaseFrame(frame)
+		return nil, err
+	}
+	return frame, nil
+}
+
+// ReleaseFrame возвращает буфер кадра в пул; после вызова буфер использовать нельзя
+func ReleaseFrame(frame *[]byte) {
+	if cap(*frame) > maxPooledFrameSize {
+		return
+	}
+	framePool.Put(frame)
+}
+
+// DecodeBatch потоково разбирает объект MessageBatch, передавая каждое сообщение
+// и длину его JSON в handler сразу после декодирования, а длительность разбора
+// сообщения (json.Unmarshal) - в observe; возвращает значение поля count
+func DecodeBatch(decoder *json.Decoder, observe func(time.Duration), handler func(*models.Message, int)) (int, error) {
+	if err := expectDelim(decoder, '{'); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return count, err
+		}
+		key, _ := token.(string)
+
+		switch key {
+		case "messages":
+			if err := decodeMessages(decoder, observe, handler); err != nil {
+				return count, err
+			}
+		case "count":
+			if err := decoder.Decode(&count); err != nil {
+				return count, err
+			}
+		default:
+			// timestamp и неизвестные поля пропускаем
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return count, err
+			}
+		}
+	}
+
+	return count, expectDelim(decoder, '}')
+}
+
+// decodeMessages разбирает массив сообщений пакета
+func decodeMessages(decoder *json.Decoder, observe func(time.Duration), handler func(*models.Message, int)) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil // "messages": null
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("ожидался массив сообщений, получено: %v", token)
+	}
+
+	for decoder.More() {
+		var (
+			raw     json.RawMessage
+			message models.Message
+		)
+		if err := decoder.Decode(&raw); err != nil {
+			return err
+		}
+		// Учитывается только разбор сообщения, без чтения кадра и поиска границ в пакете
+		decodeStart := time.Now()
+		err := json.Unmarshal(raw, &message)
+		observe(time.Since(decodeStart))
+		if err != nil {
+			return err
+		}
+		handler(&message, len(raw))
+	}
+
+	return expectDelim(decoder, ']')
+}
+
+// expectDelim читает следующий токен и проверяет, что это указанный разделитель
+func expectDelim(decoder *json.Decoder, expected json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != expected {
+		return fmt.Errorf("ожидался символ %v, получено: %v", expected, token)
+	}
+	return nil
+}
+
+// admit проверяет, можно ли принять подключение; возвращает причину отклонения или
+// пустую строку. Подключения регистрируются только в горутине приема, поэтому
+// количество активных подключений не может превысить ограничение между проверкой и регистрацией
+func (s *TCPServer) admit(conn net.Conn) string {
+	if len(s.allowed) > 0 && !s.isAllowed(conn.RemoteAddr()) {
+		return RejectNotAllowed
+	}
+
+	if s.maxConns > 0 {
+		s.stats.mu.RLock()
+		active := s.stats.ConnectionsActive
+		s.stats.mu.RUnlock()
+
+		if active >= int64(s.maxConns) {
+			return RejectLimit
+		}
+	}
+
+	return ""
+}
+
+// isAllowed проверяет, входит ли адрес клиента в разрешенные сети
+func (s *TCPServer) isAllowed(remote net.Addr) bool {
+	tcpAddr, ok := remote.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	addr, ok := netip.AddrFromSlice(tcpAddr.IP)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range s.allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectConnection закрывает отклоненное подключение и учитывает его в статистике
+func (s *TCPServer) rejectConnection(conn net.Conn, reason string) {
+	conn.Close()
+
+	s.stats.mu.Lock()
+	s.stats.Rejected[reason]++
+	rejected := s.stats.Rejected[reason]
+	s.stats.mu.Unlock()
+
+	if time.Since(s.lastRejectLog) >= rejectLogEvery {
+		s.lastRejectLog = time.Now()
+		s.logger.Warn("Подключение отклонено",
+			zap.String("client", conn.RemoteAddr().String()),
+			zap.String("reason", reason),
+			zap.Int64("rejected", rejected))
+	}
+}
+
+// registerConnection учитывает новое подключение и начинает сбор его статистики
+func (s *TCPServer) registerConnection(conn net.Conn) *connState {
+	state := &connState{
+		id:          s.connSeq.Add(1),
+		conn:        conn,
+		remoteAddr:  conn.RemoteAddr().String(),
+		connectedAt: time.Now(),
+		done:        make(chan struct{}),
+	}
+	state.touch()
+
+	// Подключение, принятое одновременно с остановкой, сразу переводится в завершение
+	s.connMu.Lock()
+	s.conns[state.id] = state
+	select {
+	case <-s.stopChan:
+		state.draining = true
+	default:
+	}
+	s.connMu.Unlock()
+
 	s.stats.mu.Lock()
 	defer s.stats.mu.Unlock()
 	s.stats.ConnectionsTotal++
 	s.stats.ConnectionsActive++
+
+	return state
 }
 
-// decrementConnectionCount уменьшает счетчик активных подключений
-func (s *TCPServer) decrementConnectionCount() {
+// unregisterConnection исключает закрытое подключение из списка активных
+func (s *TCPServer) unregisterConnection(state *connState) {
+	s.connMu.Lock()
+	delete(s.conns, state.id)
+	s.connMu.Unlock()
+
 	s.stats.mu.Lock()
-	defer s.stats.mu.Unlock()
 	s.stats.ConnectionsActive--
+	s.stats.mu.Unlock()
+
+	fields := []zap.Field{
+		zap.String("client", state.remoteAddr),
+		zap.Int64("messages", state.messages.Load()),
+		zap.Int64("bytes", state.bytes.Load()),
+		zap.Int64("errors", state.errors.Load()),
+		zap.Duration("duration", time.Since(state.connectedAt)),
+	}
+	if rtt := state.pingRTT(); rtt != nil {
+		fields = append(fields, zap.Float64("ping_rtt_avg_ms", rtt.AvgMs), zap.Float64("ping_rtt_max_ms", rtt.MaxMs))
+	}
+	s.logger.Info("Подключение закрыто", fields...)
 }
 
 // incrementMessageCount увеличивает счетчик сообщений
-func (s *TCPServer) incrementMessageCount(bytes int64) {
+func (s *TCPServer) incrementMessageCount(state *connState, bytes int64) {
+	state.messages.Add(1)
+	state.bytes.Add(bytes)
+	s.processor.ObserveReceive(archive.SourceTCP.String(), 1, int(bytes))
+
 	s.stats.mu.Lock()
 	defer s.stats.mu.Unlock()
 	s.stats.MessagesReceived++
@@ -325,7 +995,12 @@ func (s *TCPServer) incrementMessageCount(bytes int64) {
 }
 
 // incrementBatchCount увеличивает счетчик пакетов
-func (s *TCPServer) incrementBatchCount(bytes int64, messages int) {
+func (s *TCPServer) incrementBatchCount(state *connState, bytes int64, messages int) {
+	state.batches.Add(1)
+	state.messages.Add(int64(messages))
+	state.bytes.Add(bytes)
+	s.processor.ObserveReceive(archive.SourceTCP.String(), messages, int(bytes))
+
 	s.stats.mu.Lock()
 	defer s.stats.mu.Unlock()
 	s.stats.BatchesReceived++
@@ -334,29 +1009,139 @@ func (s *TCPServer) incrementBatchCount(bytes int64, messages int) {
 	s.stats.LastMessageTime = time.Now()
 }
 
-// incrementErrorCount увеличивает счетчик ошибок
-func (s *TCPServer) incrementErrorCount() {
+// incrementErrorCount увеличивает счетчик ошибок (state - подключение, nil для ошибок приема)
+func (s *TCPServer) incrementErrorCount(state *connState) {
+	if state != nil {
+		state.errors.Add(1)
+	}
+
 	s.stats.mu.Lock()
 	defer s.stats.mu.Unlock()
 	s.stats.Errors++
 }
 
+// ResetStats сбрасывает счетчики статистики; количество активных подключений сохраняется
+func (s *TCPServer) ResetStats() {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	s.stats.ConnectionsTotal = s.stats.ConnectionsActive
+	s.stats.MessagesReceived = 0
+	s.stats.BatchesReceived = 0
+	s.stats.BytesReceived = 0
+	s.stats.RawFramesReceived = 0
+	s.stats.RawBytesReceived = 0
+	s.stats.Errors = 0
+	s.stats.FrameChecksumErrors = 0
+	s.stats.Rejected = make(map[string]int64)
+	s.stats.LastMessageTime = time.Time{}
+}
+
+// StatsSnapshot снимок статистики сервера
+type StatsSnapshot struct {
+	Running           bool             `json:"running"`
+	Address           string           `json:"address"`
+	ConnectionsTotal  int64            `json:"connections_total"`
+	ConnectionsActive int64            `json:"connections_active"`
+	MessagesReceived  int64            `json:"messages_received"`
+	BatchesReceived   int64            `json:"batches_received"`
+	BytesReceived     int64            `json:"bytes_received"`
+	RawFramesReceived int64            `json:"raw_frames_received"` // Кадры-заполнители теста пропускной способности
+	RawBytesReceived  int64            `json:"raw_bytes_received"`  // Байт кадров-заполнителей с заголовками
+	Errors            int64            `json:"errors"`
+	Rejected          map[string]int64 `json:"rejected"` // Отклоненные подключения по причинам (not_allowed, limit)
+	LastMessageTime   time.Time        `json:"last_message_time"`
+
+	FrameChecksumErrors int64 `json:"frame_checksum_errors"` // Кадров с несовпавшей контрольной суммой (протокол v2)
+}
+
 // GetStats возвращает статистику сервера
-func (s *TCPServer) GetStats() map[string]interface{} {
+func (s *TCPServer) GetStats() StatsSnapshot {
+	running := s.IsRunning()
+
 	s.stats.mu.RLock()
 	defer s.stats.mu.RUnlock()
 
-	return map[string]interface{}{
-		"running":            s.isRunning,
-		"address":            s.address,
-		"connections_total":  s.stats.ConnectionsTotal,
-		"connections_active": s.stats.ConnectionsActive,
-		"messages_received":  s.stats.MessagesReceived,
-		"batches_received":   s.stats.BatchesReceived,
-		"bytes_received":     s.stats.BytesReceived,
-		"errors":             s.stats.Errors,
-		"last_message_time":  s.stats.LastMessageTime.Format(time.RFC3339),
+	rejected := make(map[string]int64, len(s.stats.Rejected))
+	for reason, count := range s.stats.Rejected {
+		rejected[reason] = count
+	}
+
+	return StatsSnapshot{
+		Running:           running,
+		Address:           s.address,
+		ConnectionsTotal:  s.stats.ConnectionsTotal,
+		ConnectionsActive: s.stats.ConnectionsActive,
+		MessagesReceived:  s.stats.MessagesReceived,
+		BatchesReceived:   s.stats.BatchesReceived,
+		BytesReceived:     s.stats.BytesReceived,
+		RawFramesReceived: s.stats.RawFramesReceived,
+		RawBytesReceived:  s.stats.RawBytesReceived,
+		Errors:            s.stats.Errors,
+		Rejected:          rejected,
+		LastMessageTime:   s.stats.LastMessageTime,
+
+		FrameChecksumErrors: s.stats.FrameChecksumErrors,
+	}
+}
+
+// ConnectionInfo статистика активного подключения
+type ConnectionInfo struct {
+	ID               uint64    `json:"id"`
+	RemoteAddr       string    `json:"remote_addr"`
+	ConnectedAt      time.Time `json:"connected_at"`
+	MessagesReceived int64     `json:"messages_received"`
+	BatchesReceived  int64     `json:"batches_received"`
+	BytesReceived    int64     `json:"bytes_received"`
+	RawBytesReceived int64     `json:"raw_bytes_received"` // Байт кадров-заполнителей теста пропускной способности
+	Errors           int64     `json:"errors"`
+	Framing          string    `json:"framing"` // Формат кадров (v2, legacy)
+	Pings            int64     `json:"pings"`   // Получено ping (протокол v2)
+	LastActivity     time.Time `json:"last_activity"`
+
+	PingsSent int64              `json:"pings_sent"`         // Отправлено ping сервера
+	PingRTT   *tcpframe.RTTStats `json:"ping_rtt,omitempty"` // Время прохождения ping сервера, nil - не согласован
+
+	FrameChecksum       bool  `json:"frame_checksum"`        // Кадры передаются с контрольной суммой
+	FrameChecksumErrors int64 `json:"frame_checksum_errors"` // Кадров с несовпавшей контрольной суммой
+}
+
+// Connections возвращает статистику активных подключений в порядке подключения
+func (s *TCPServer) Connections() []ConnectionInfo {
+	s.connMu.RLock()
+	connections := make([]ConnectionInfo, 0, len(s.conns))
+	for _, state := range s.conns {
+		framing := "legacy"
+		if state.v2.Load() {
+			framing = "v2"
+		}
+		connections = append(connections, ConnectionInfo{
+			ID:               state.id,
+			RemoteAddr:       state.remoteAddr,
+			ConnectedAt:      state.connectedAt,
+			MessagesReceived: state.messages.Load(),
+			BatchesReceived:  state.batches.Load(),
+			BytesReceived:    state.bytes.Load(),
+			RawBytesReceived: state.rawBytes.Load(),
+			Errors:           state.errors.Load(),
+			Framing:          framing,
+			Pings:            state.pings.Load(),
+			LastActivity:     time.Unix(0, state.lastActivity.Load()),
+
+			FrameChecksum:       state.checksum.Load(),
+			FrameChecksumErrors: state.frameErrors.Load(),
+
+			PingsSent: state.pingsSent.Load(),
+			PingRTT:   state.pingRTT(),
+		})
 	}
+	s.connMu.RUnlock()
+
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].ID < connections[j].ID
+	})
+
+	return connections
 }
 
 // IsRunning проверяет, работает ли сервер
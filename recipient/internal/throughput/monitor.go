@@ -0,0 +1,303 @@
+package throughput
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// sampleInterval период снятия показателей и проверки порогов
+const sampleInterval = time.Second
+
+// Типы событий отставания
+const (
+	EventFallingBehind = "falling_behind" // Обработка не успевает за приемом
+	EventRecovered     = "recovered"      // Показатели вернулись в пределы порогов
+)
+
+// Config параметры контроля отставания обработки
+type Config struct {
+	Window         time.Duration // Скользящее окно пропускной способности
+	MaxLag         time.Duration // Предельная задержка от получения до конца обработки (0 - не проверять)
+	MaxQueueDepth  int64         // Предельное число сообщений в обработке (0 - не проверять)
+	WebhookURL     string        // Адрес отправки событий (пусто - не отправлять)
+	WebhookTimeout time.Duration // Таймаут отправки события
+}
+
+// Source возвращает текущее значение показателя: число обработанных сообщений или глубину очереди
+type Source func() int64
+
+// Event событие отставания обработки или восстановления после него
+type Event struct {
+	Type          string    `json:"type"`
+	Instance      string    `json:"instance"`
+	Time          time.Time `json:"time"`
+	Reasons       []string  `json:"reasons,omitempty"`
+	LagMs         float64   `json:"lag_ms"`
+	QueueDepth    int64     `json:"queue_depth"`
+	Throughput    float64   `json:"throughput"`
+	MaxLagMs      float64   `json:"max_lag_ms,omitempty"`
+	MaxQueueDepth int64     `json:"max_queue_depth,omitempty"`
+}
+
+// Stats показатели пропускной способности и состояние отставания
+type Stats struct {
+	WindowSeconds float64 `json:"window_seconds"`
+	Throughput    float64 `json:"throughput"`     // Сообщений в секунду за окно
+	HighWatermark float64 `json:"high_watermark"` // Наибольшая пропускная способность окна
+	LowWatermark  float64 `json:"low_watermark"`  // Наименьшая ненулевая пропускная способность полного окна
+	LagMs         float64 `json:"lag_ms"`         // Наибольшая задержка обработки за последнюю секунду
+	QueueDepth    int64   `json:"queue_depth"`
+	FallingBehind bool    `json:"falling_behind"`
+	Events        int64   `json:"events"`
+	WebhookErrors int64   `json:"webhook_errors"`
+	LastEvent     *Event  `json:"last_event,omitempty"`
+}
+
+// Monitor ежесекундно снимает число обработанных сообщений и глубину очереди,
+// вычисляет пропускную способность в скользящем окне и сообщает об отставании
+// обработки (журнал, счетчик и webhook) при превышении порогов задержки или очереди
+type Monitor struct {
+	config    Config
+	instance  string
+	logger    *zap.Logger
+	processed Source
+	depth     Source
+	client    *http.Client
+	lagNs     atomic.Int64 // Наибольшая задержка с последнего снятия показателей
+	events    atomic.Int64
+	failures  atomic.Int64 // Ошибок отправки webhook
+
+	mu         sync.Mutex
+	samples    []int64 // Кольцо значений processed по секундам
+	count      int     // Заполнено значений
+	next       int     // Позиция следующего значения
+	throughput float64
+	high       float64
+	low        float64
+	lag        time.Duration
+	queueDepth int64
+	behind     bool
+	lastEvent  *Event
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewMonitor создает контроль отставания экземпляра instance
+func NewMonitor(cfg Config, instance string, logger *zap.Logger, processed, depth Source) *Monitor {
+	seconds := max(int(cfg.Window/sampleInterval), 1)
+	return &Monitor{
+		config:    cfg,
+		instance:  instance,
+		logger:    logger.With(zap.String("component", "throughput")),
+		processed: processed,
+		depth:     depth,
+		client:    &http.Client{Timeout: cfg.WebhookTimeout},
+		samples:   make([]int64, seconds+1),
+		stopChan:  make(chan struct{}),
+	}
+}
+
+// Start запускает периодическое снятие показателей
+func (m *Monitor) Start() {
+	m.logger.Info("Запуск контроля отставания обработки",
+		zap.Duration("window", m.config.Window),
+		zap.Duration("max_lag", m.config.MaxLag),
+		zap.Int64("max_queue_depth", m.config.MaxQueueDepth))
+
+	m.wg.Add(1)
+	go m.run()
+}
+
+// run снимает показатели с интервалом sampleInterval
+func (m *Monitor) run() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopChan:
+			return
+		case now := <-ticker.C:
+			if event := m.sample(now); event != nil {
+				m.emit(event)
+			}
+		}
+	}
+}
+
+// ObserveLag учитывает задержку сообщения от получения до конца обработки
+func (m *Monitor) ObserveLag(lag time.Duration) {
+	for {
+		current := m.lagNs.Load()
+		if int64(lag) <= current || m.lagNs.CompareAndSwap(current, int64(lag)) {
+			return
+		}
+	}
+}
+
+// sample снимает показатели и возвращает событие при смене состояния отставания
+func (m *Monitor) sample(now time.Time) *Event {
+	processed := m.processed()
+	var depth int64
+	if m.depth != nil {
+		depth = m.depth()
+	}
+	lag := time.Duration(m.lagNs.Swap(0))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Уменьшение счетчика означает сброс статистики обработчика - окно начинается заново
+	if m.count > 0 && processed < m.samples[(m.next+len(m.samples)-1)%len(m.samples)] {
+		m.count = 0
+	}
+	m.samples[m.next] = processed
+	m.next = (m.next + 1) % len(m.samples)
+	m.count = min(m.count+1, len(m.samples))
+
+	if m.count > 1 {
+		oldest := m.samples[(m.next+len(m.samples)-m.count)%len(m.samples)]
+		seconds := float64(m.count-1) * sampleInterval.Seconds()
+		m.throughput = float64(processed-oldest) / seconds
+		m.high = max(m.high, m.throughput)
+
+		// Нижняя отметка учитывается по полному окну и только пока сообщения поступают,
+		// иначе ее обнуляли бы разгон и паузы между тестами
+		if m.count == len(m.samples) && m.throughput > 0 && (m.low == 0 || m.throughput < m.low) {
+			m.low = m.throughput
+		}
+	}
+	m.lag = lag
+	m.queueDepth = depth
+
+	var reasons []string
+	if m.config.MaxLag > 0 && lag > m.config.MaxLag {
+		reasons = append(reasons, fmt.Sprintf("задержка обработки %s превышает %s", lag.Round(time.Millisecond), m.config.MaxLag))
+	}
+	if m.config.MaxQueueDepth > 0 && depth > m.config.MaxQueueDepth {
+		reasons = append(reasons, fmt.Sprintf("в обработке %d сообщений при пороге %d", depth, m.config.MaxQueueDepth))
+	}
+
+	behind := len(reasons) > 0
+	if behind == m.behind {
+		return nil
+	}
+	m.behind = behind
+
+	event := &Event{
+		Type:          EventRecovered,
+		Instance:      m.instance,
+		Time:          now,
+		Reasons:       reasons,
+		LagMs:         float64(lag.Microseconds()) / 1000,
+		QueueDepth:    depth,
+		Throughput:    m.throughput,
+		MaxLagMs:      float64(m.config.MaxLag.Microseconds()) / 1000,
+		MaxQueueDepth: m.config.MaxQueueDepth,
+	}
+	if behind {
+		event.Type = EventFallingBehind
+	}
+	m.lastEvent = event
+	return event
+}
+
+// emit записывает событие в журнал и отправляет его на webhook
+func (m *Monitor) emit(event *Event) {
+	fields := []zap.Field{
+		zap.String("event", event.Type),
+		zap.Strings("reasons", event.Reasons),
+		zap.Float64("lag_ms", event.LagMs),
+		zap.Int64("queue_depth", event.QueueDepth),
+		zap.Float64("throughput", event.Throughput),
+	}
+	if event.Type == EventFallingBehind {
+		m.events.Add(1)
+		m.logger.Warn("Обработка отстает от приема", fields...)
+	} else {
+		m.logger.Info("Обработка догнала прием", fields...)
+	}
+
+	if m.config.WebhookURL == "" {
+		return
+	}
+
+	// Отправка не задерживает снятие показателей
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		if err := m.post(event); err != nil {
+			m.failures.Add(1)
+			m.logger.Warn("Ошибка отправки события отставания", zap.Error(err))
+		}
+	}()
+}
+
+// post отправляет событие на webhook в формате JSON
+func (m *Monitor) post(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации события: %w", err)
+	}
+
+	resp, err := m.client.Post(m.config.WebhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook ответил статусом %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Stats возвращает показатели пропускной способности и состояние отставания
+func (m *Monitor) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := Stats{
+		WindowSeconds: float64(len(m.samples)-1) * sampleInterval.Seconds(),
+		Throughput:    m.throughput,
+		HighWatermark: m.high,
+		LowWatermark:  m.low,
+		LagMs:         float64(m.lag.Microseconds()) / 1000,
+		QueueDepth:    m.queueDepth,
+		FallingBehind: m.behind,
+		Events:        m.events.Load(),
+		WebhookErrors: m.failures.Load(),
+	}
+	if m.lastEvent != nil {
+		event := *m.lastEvent
+		stats.LastEvent = &event
+	}
+	return stats
+}
+
+// ResetWatermarks сбрасывает отметки пропускной способности и окно
+func (m *Monitor) ResetWatermarks() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.count = 0
+	m.throughput = 0
+	m.high = 0
+	m.low = 0
+}
+
+// Close останавливает снятие показателей и ожидает отправки событий
+func (m *Monitor) Close() {
+	close(m.stopChan)
+	m.wg.Wait()
+}
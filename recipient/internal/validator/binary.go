@@ -0,0 +1,62 @@
+package validator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/infodiode/shared/models"
+)
+
+// ParseBinaryPayload разбирает payload с двоичными записями data.binary отправителя:
+// строку base64 или массив таких строк (большие пакеты). Проверяет декодирование и
+// размер каждой записи по раскладке validation.binary
+func (v *ChecksumValidator) ParseBinaryPayload(message *models.Message) ([][]byte, error) {
+	layout := v.rules.Load().Binary
+	if layout == nil {
+		return nil, fmt.Errorf("раскладка двоичной записи не задана")
+	}
+
+	payload := strings.TrimLeft(message.Payload, " \t\r\n")
+	if payload == "" {
+		return nil, fmt.Errorf("payload пустой")
+	}
+
+	var encoded []string
+	if payload[0] == '[' {
+		if err := json.Unmarshal([]byte(payload), &encoded); err != nil {
+			return nil, fmt.Errorf("ошибка десериализации payload: %w", err)
+		}
+	} else {
+		var record string
+		if err := json.Unmarshal([]byte(payload), &record); err != nil {
+			return nil, fmt.Errorf("ошибка десериализации payload: %w", err)
+		}
+		encoded = []string{record}
+	}
+
+	size := layout.RecordSize()
+	records := make([][]byte, len(encoded))
+	for i, s := range encoded {
+		record, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("запись %d: некорректный base64: %w", i, err)
+		}
+		if len(record) != size {
+			return nil, fmt.Errorf("запись %d: размер %d байт, ожидалось %d", i, len(record), size)
+		}
+		records[i] = record
+	}
+
+	return records, nil
+}
+
+// ValidateBinaryRecord проверяет значения полей двоичной записи по раскладке
+func (v *ChecksumValidator) ValidateBinaryRecord(record []byte) error {
+	layout := v.rules.Load().Binary
+	if layout == nil {
+		return fmt.Errorf("раскладка двоичной записи не задана")
+	}
+	return layout.Check(record)
+}
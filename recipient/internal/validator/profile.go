@@ -0,0 +1,38 @@
+package validator
+
+import "fmt"
+
+// Profile набор проверок принятых сообщений
+type Profile string
+
+const (
+	ProfileOff      Profile = "off"      // Без проверок: сообщения только учитываются
+	ProfileChecksum Profile = "checksum" // Только контрольная сумма, payload не разбирается
+	ProfileSchema   Profile = "schema"   // Контрольная сумма и обязательные поля записей payload
+	ProfileStrict   Profile = "strict"   // Все проверки, включая диапазоны и формат значений записей
+)
+
+// ParseProfile разбирает название профиля проверки
+func ParseProfile(name string) (Profile, error) {
+	switch profile := Profile(name); profile {
+	case ProfileOff, ProfileChecksum, ProfileSchema, ProfileStrict:
+		return profile, nil
+	default:
+		return "", fmt.Errorf("неизвестный профиль проверки: %s (допустимы off, checksum, schema, strict)", name)
+	}
+}
+
+// Checksum проверяется ли контрольная сумма
+func (p Profile) Checksum() bool {
+	return p != ProfileOff
+}
+
+// Payload разбирается ли payload
+func (p Profile) Payload() bool {
+	return p == ProfileSchema || p == ProfileStrict
+}
+
+// Integrity проверяются ли диапазоны и формат значений записей
+func (p Profile) Integrity() bool {
+	return p == ProfileStrict
+}
@@ -1,9 +1,11 @@
 package validator
 
 import (
-	"encoding/json"
 	"fmt"
+	"strings"
+	"sync/atomic"
 
+	"github.com/infodiode/shared/binrec"
 	"github.com/infodiode/shared/models"
 	"github.com/infodiode/shared/utils"
 	"go.uber.org/zap"
@@ -12,13 +14,48 @@ import (
 // ChecksumValidator проверяет контрольные суммы сообщений
 type ChecksumValidator struct {
 	logger *zap.Logger
+	rules  atomic.Pointer[Rules] // Заменяются целиком при перечитывании конфигурации
 }
 
-// NewChecksumValidator создает новый валидатор
+// Rules правила проверки записей payload
+type Rules struct {
+	IndicatorMin int // Допустимый диапазон indicator_id
+	IndicatorMax int
+	EquipmentMin int // Допустимый диапазон equipment_id
+	EquipmentMax int
+	ValueLength  int // Длина indicator_value, символов
+
+	Binary *binrec.Layout // Раскладка двоичных записей (nil - payload содержит записи JSON)
+}
+
+// DefaultRules возвращает правила, соответствующие данным генератора sender
+func DefaultRules() Rules {
+	return Rules{
+		IndicatorMin: 1,
+		IndicatorMax: 1000,
+		EquipmentMin: 1,
+		EquipmentMax: 100,
+		ValueLength:  15,
+	}
+}
+
+// NewChecksumValidator создает новый валидатор с правилами по умолчанию
 func NewChecksumValidator(logger *zap.Logger) *ChecksumValidator {
-	return &ChecksumValidator{
+	v := &ChecksumValidator{
 		logger: logger,
 	}
+	v.SetRules(DefaultRules())
+	return v
+}
+
+// SetRules задает правила проверки записей
+func (v *ChecksumValidator) SetRules(rules Rules) {
+	v.rules.Store(&rules)
+}
+
+// Rules возвращает действующие правила проверки записей
+func (v *ChecksumValidator) Rules() Rules {
+	return *v.rules.Load()
 }
 
 // ValidateMessage проверяет контрольную сумму сообщения
@@ -62,33 +99,75 @@ func (v *ChecksumValidator) ValidatePayload(message *models.Message) (*models.Da
 
 	// Пытаемся десериализовать payload
 	var data models.Data
-	if err := json.Unmarshal([]byte(message.Payload), &data); err != nil {
+	if err := data.UnmarshalJSON([]byte(message.Payload)); err != nil {
+		return nil, fmt.Errorf("ошибка десериализации payload: %w", err)
+	}
+
+	if err := validateRequiredFields(&data, v.rules.Load()); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// ParsePayload разбирает payload, содержащий одну запись или массив записей
+// (большие пакеты), и проверяет обязательные поля каждой записи
+func (v *ChecksumValidator) ParsePayload(message *models.Message) ([]*models.Data, error) {
+	payload := strings.TrimLeft(message.Payload, " \t\r\n")
+	if payload == "" {
+		return nil, fmt.Errorf("payload пустой")
+	}
+
+	if payload[0] != '[' {
+		data, err := v.ValidatePayload(message)
+		if err != nil {
+			return nil, err
+		}
+		return []*models.Data{data}, nil
+	}
+
+	records, err := models.UnmarshalDataArray([]byte(payload))
+	if err != nil {
 		return nil, fmt.Errorf("ошибка десериализации payload: %w", err)
 	}
 
-	// Проверяем обязательные поля
+	rules := v.rules.Load()
+	for i, data := range records {
+		if data == nil {
+			return nil, fmt.Errorf("запись %d: пустая запись", i)
+		}
+		if err := validateRequiredFields(data, rules); err != nil {
+			return nil, fmt.Errorf("запись %d: %w", i, err)
+		}
+	}
+
+	return records, nil
+}
+
+// validateRequiredFields проверяет обязательные поля записи
+func validateRequiredFields(data *models.Data, rules *Rules) error {
 	if data.ID <= 0 {
-		return nil, fmt.Errorf("некорректный ID: %d", data.ID)
+		return fmt.Errorf("некорректный ID: %d", data.ID)
 	}
 
 	if data.Timestamp == "" {
-		return nil, fmt.Errorf("отсутствует timestamp")
+		return fmt.Errorf("отсутствует timestamp")
 	}
 
 	if data.IndicatorID <= 0 {
-		return nil, fmt.Errorf("некорректный indicator_id: %d", data.IndicatorID)
+		return fmt.Errorf("некорректный indicator_id: %d", data.IndicatorID)
 	}
 
 	if data.EquipmentID <= 0 {
-		return nil, fmt.Errorf("некорректный equipment_id: %d", data.EquipmentID)
+		return fmt.Errorf("некорректный equipment_id: %d", data.EquipmentID)
 	}
 
-	// Проверяем длину indicator_value (должна быть 15 символов)
-	if len(data.IndicatorValue) != 15 {
-		return nil, fmt.Errorf("некорректная длина indicator_value: %d (должна быть 15)", len(data.IndicatorValue))
+	// Проверяем длину indicator_value
+	if len(data.IndicatorValue) != rules.ValueLength {
+		return fmt.Errorf("некорректная длина indicator_value: %d (должна быть %d)", len(data.IndicatorValue), rules.ValueLength)
 	}
 
-	return &data, nil
+	return nil
 }
 
 // ValidateBatch проверяет пакет сообщений
@@ -175,16 +254,17 @@ func (v *ChecksumValidator) ValidateDataIntegrity(data *models.Data) error {
 	}
 
 	// Проверяем диапазоны ID
-	if data.IndicatorID < 1 || data.IndicatorID > 1000 {
-		return fmt.Errorf("indicator_id вне диапазона [1, 1000]: %d", data.IndicatorID)
+	rules := v.rules.Load()
+	if data.IndicatorID < rules.IndicatorMin || data.IndicatorID > rules.IndicatorMax {
+		return fmt.Errorf("indicator_id вне диапазона [%d, %d]: %d", rules.IndicatorMin, rules.IndicatorMax, data.IndicatorID)
 	}
 
-	if data.EquipmentID < 1 || data.EquipmentID > 100 {
-		return fmt.Errorf("equipment_id вне диапазона [1, 100]: %d", data.EquipmentID)
+	if data.EquipmentID < rules.EquipmentMin || data.EquipmentID > rules.EquipmentMax {
+		return fmt.Errorf("equipment_id вне диапазона [%d, %d]: %d", rules.EquipmentMin, rules.EquipmentMax, data.EquipmentID)
 	}
 
 	// Проверяем indicator_value
-	if err := v.validateIndicatorValue(data.IndicatorValue); err != nil {
+	if err := v.validateIndicatorValue(data.IndicatorValue, rules.ValueLength); err != nil {
 		return fmt.Errorf("некорректный indicator_value: %w", err)
 	}
 
@@ -192,9 +272,9 @@ func (v *ChecksumValidator) ValidateDataIntegrity(data *models.Data) error {
 }
 
 // validateIndicatorValue проверяет корректность значения индикатора
-func (v *ChecksumValidator) validateIndicatorValue(value string) error {
-	if len(value) != 15 {
-		return fmt.Errorf("длина должна быть 15 символов, получено: %d", len(value))
+func (v *ChecksumValidator) validateIndicatorValue(value string, length int) error {
+	if len(value) != length {
+		return fmt.Errorf("длина должна быть %d символов, получено: %d", length, len(value))
 	}
 
 	// Удаляем trailing пробелы для проверки типа
@@ -7,13 +7,27 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/infodiode/sender/config"
 	"github.com/infodiode/sender/internal/api"
 	"github.com/infodiode/sender/internal/broker"
+	"github.com/infodiode/sender/internal/cluster"
 	"github.com/infodiode/sender/internal/generator"
 	"github.com/infodiode/sender/internal/logger"
+	"github.com/infodiode/sender/internal/orchestration"
+	"github.com/infodiode/sender/internal/quic"
+	"github.com/infodiode/sender/internal/serial"
 	"github.com/infodiode/sender/internal/tcp"
+	"github.com/infodiode/sender/internal/templates"
+	"github.com/infodiode/sender/internal/test"
+	"github.com/infodiode/sender/internal/transport"
+	"github.com/infodiode/sender/internal/webhook"
+	"github.com/infodiode/shared/actionlog"
+	"github.com/infodiode/shared/logging"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/supervisor"
+	"github.com/infodiode/shared/utils"
 	"go.uber.org/zap"
 )
 
@@ -21,6 +35,7 @@ var (
 	// Version информация о версии (устанавливается при сборке)
 	Version   = "dev"
 	BuildTime = "unknown"
+	GitCommit = "" // Пусто - коммит определяется по данным сборки go build
 )
 
 func main() {
@@ -34,7 +49,7 @@ func main() {
 
 	// Показываем версию если запрошено
 	if *showVersion {
-		fmt.Printf("Sender Service\nVersion: %s\nBuild time: %s\n", Version, BuildTime)
+		fmt.Printf("Sender Service\nVersion: %s\nBuild time: %s\nCommit: %s\n", Version, BuildTime, gitCommit())
 		os.Exit(0)
 	}
 
@@ -54,6 +69,13 @@ func main() {
 		MaxAge:     cfg.Logger.MaxAge,
 		Compress:   cfg.Logger.Compress,
 		Console:    cfg.Logger.Console,
+
+		Format:        cfg.Logger.Format,
+		FileLevel:     cfg.Logger.FileLevel,
+		ConsoleFormat: cfg.Logger.ConsoleFormat,
+		ConsoleLevel:  cfg.Logger.ConsoleLevel,
+		Syslog:        syslogSink(cfg.Logger.Syslog),
+		SyslogLevel:   cfg.Logger.Syslog.Level,
 	})
 	if err != nil {
 		fmt.Printf("Ошибка инициализации логгера: %v\n", err)
@@ -80,8 +102,23 @@ func main() {
 		SmallBatchSize:   cfg.Data.SmallBatchSize,
 		MediumBatchSize:  cfg.Data.MediumBatchSize,
 		LargeBatchSizes:  cfg.Data.LargeBatchSizes,
+		CompressionLevel: cfg.Data.CompressionLevel,
+		Schema:           cfg.Data.Schema,
+		Binary:           cfg.Data.Binary,
+		ValueModels:      cfg.Data.ValueModels,
+	}
+	if cfg.Data.TagsFile != "" {
+		tags, err := generator.LoadTags(cfg.Data.TagsFile)
+		if err != nil {
+			log.Fatal("Ошибка загрузки определений тегов", zap.Error(err))
+		}
+		genConfig.Tags = tags
+		log.Info("Загружены определения тегов",
+			zap.String("file", cfg.Data.TagsFile),
+			zap.Int("tags", len(tags)))
 	}
 	dataGenerator := generator.NewDataGenerator(genConfig, log.Logger)
+	dataGenerator.SetRetention(retentionPolicy(&cfg.Data.Retention))
 
 	// Если указан флаг generate, генерируем данные и выходим
 	if *generateOnly {
@@ -122,16 +159,94 @@ func main() {
 	}
 	defer producer.Close()
 
+	// Счетчики producer, накопленные предыдущими процессами (если включено).
+	// Сохраняются периодически и при остановке
+	saveLifetime := func() {}
+	if cfg.MQTT.StatsFile != "" {
+		if err := producer.RestoreLifetime(cfg.MQTT.StatsFile); err != nil {
+			log.Fatal("Ошибка загрузки накопленной статистики", zap.Error(err))
+		}
+		saveLifetime = func() {
+			if err := producer.SaveLifetime(cfg.MQTT.StatsFile); err != nil {
+				log.Error("Ошибка сохранения накопленной статистики", zap.Error(err))
+			}
+		}
+
+		if interval := cfg.MQTT.StatsSaveInterval; interval > 0 {
+			saveTicker := time.NewTicker(interval)
+			defer saveTicker.Stop()
+			go func() {
+				for range saveTicker.C {
+					saveLifetime()
+				}
+			}()
+		}
+	}
+
+	// Транспорты отправки тестовых сообщений по протоколам
+	transports := transport.NewRegistry()
+	transports.Register(transport.NewMQTT(producer))
+	transports.RegisterFactory(models.ProtocolMQTT, func(topic string) (transport.Transport, error) {
+		return transport.NewMQTTTopic(producer, topic), nil
+	})
+	transports.RegisterFactory(models.ProtocolTCP, func(address string) (transport.Transport, error) {
+		// Пустой адрес - адрес recipient из конфигурации (отдельные соединения теста насыщения канала)
+		if address == "" {
+			address = cfg.TCP.Address
+		}
+		client, err := tcp.NewTCPClient(&tcp.Config{
+			Address:         address,
+			ReconnectInt:    cfg.TCP.ReconnectInt,
+			MaxReconnectInt: cfg.TCP.MaxReconnectInt,
+			MaxRetries:      cfg.TCP.MaxRetries,
+			Timeout:         cfg.TCP.Timeout,
+			KeepAlive:       cfg.TCP.KeepAlive,
+			KeepAlivePeriod: cfg.TCP.KeepAlivePeriod,
+			Framing:         cfg.TCP.Framing,
+			PingInterval:    cfg.TCP.PingInterval,
+			PingTimeout:     cfg.TCP.PingTimeout,
+			FrameChecksum:   cfg.TCP.FrameChecksum,
+			Socket:          cfg.TCP.SocketOptions(),
+		}, log.Logger)
+		if err != nil {
+			return nil, err
+		}
+		return transport.NewTCP(client), nil
+	})
+	transports.RegisterFactory(models.ProtocolQUIC, func(address string) (transport.Transport, error) {
+		client, err := quic.NewQUICClient(cfg.QUIC.ClientConfig(address), log.Logger)
+		if err != nil {
+			return nil, err
+		}
+		return transport.NewQUIC(client), nil
+	})
+	transports.RegisterFactory(models.ProtocolSerial, func(device string) (transport.Transport, error) {
+		client, err := serial.NewClient(&serial.Config{
+			Port:    cfg.Serial.PortConfig(device),
+			Timeout: cfg.Serial.Timeout,
+		}, log.Logger)
+		if err != nil {
+			return nil, err
+		}
+		return transport.NewSerial(client), nil
+	})
+
 	// Создаем TCP client (если включен)
 	var tcpClient *tcp.TCPClient
 	if cfg.TCP.Enabled {
 		tcpConfig := &tcp.Config{
 			Address:         cfg.TCP.Address,
 			ReconnectInt:    cfg.TCP.ReconnectInt,
+			MaxReconnectInt: cfg.TCP.MaxReconnectInt,
 			MaxRetries:      cfg.TCP.MaxRetries,
 			Timeout:         cfg.TCP.Timeout,
 			KeepAlive:       cfg.TCP.KeepAlive,
 			KeepAlivePeriod: cfg.TCP.KeepAlivePeriod,
+			Framing:         cfg.TCP.Framing,
+			PingInterval:    cfg.TCP.PingInterval,
+			PingTimeout:     cfg.TCP.PingTimeout,
+			FrameChecksum:   cfg.TCP.FrameChecksum,
+			Socket:          cfg.TCP.SocketOptions(),
 		}
 		tcpClient, err = tcp.NewTCPClient(tcpConfig, log.Logger)
 		if err != nil {
@@ -144,6 +259,7 @@ func main() {
 			} else {
 				log.Info("TCP клиент подключен", zap.String("address", cfg.TCP.Address))
 			}
+			transports.Register(transport.NewTCP(tcpClient))
 			defer func() {
 				if err := tcpClient.Disconnect(); err != nil {
 					log.Error("Ошибка отключения TCP клиента", zap.Error(err))
@@ -152,16 +268,199 @@ func main() {
 		}
 	}
 
+	// Создаем QUIC client (если включен)
+	if cfg.QUIC.Enabled {
+		quicClient, err := quic.NewQUICClient(cfg.QUIC.ClientConfig(cfg.QUIC.Address), log.Logger)
+		if err != nil {
+			log.Error("Ошибка создания QUIC клиента", zap.Error(err))
+			// Не завершаем работу, продолжаем без QUIC
+		} else {
+			// Сервер, недоступный при старте, подключается при первой отправке
+			if err := quicClient.Connect(); err != nil {
+				log.Warn("Не удалось подключиться к QUIC серверу при старте", zap.Error(err))
+			}
+			transports.Register(transport.NewQUIC(quicClient))
+			defer func() {
+				if err := quicClient.Disconnect(); err != nil {
+					log.Error("Ошибка отключения QUIC клиента", zap.Error(err))
+				}
+			}()
+		}
+	}
+
+	// Создаем NATS JetStream producer (если включен)
+	var natsProducer *broker.NATSProducer
+	if cfg.NATS.Enabled {
+		natsProducer, err = broker.NewNATSProducer(&cfg.NATS, log.Logger)
+		if err != nil {
+			log.Error("Ошибка создания NATS producer", zap.Error(err))
+			// Не завершаем работу, продолжаем без NATS
+		} else {
+			transports.Register(transport.NewNATS(natsProducer))
+			defer func() {
+				if err := natsProducer.Close(); err != nil {
+					log.Error("Ошибка закрытия NATS producer", zap.Error(err))
+				}
+			}()
+		}
+	}
+
+	// Создаем клиент последовательного порта (если включен)
+	if cfg.Serial.Enabled {
+		serialClient, err := serial.NewClient(&serial.Config{
+			Port:    cfg.Serial.PortConfig(cfg.Serial.Device),
+			Timeout: cfg.Serial.Timeout,
+		}, log.Logger)
+		if err != nil {
+			log.Error("Ошибка создания клиента последовательного порта", zap.Error(err))
+			// Не завершаем работу, продолжаем без последовательного порта
+		} else {
+			// Порт, недоступный при старте, открывается при первой отправке
+			if err := serialClient.Connect(); err != nil {
+				log.Warn("Не удалось открыть последовательный порт при старте", zap.Error(err))
+			}
+			transports.Register(transport.NewSerial(serialClient))
+			defer func() {
+				if err := serialClient.Disconnect(); err != nil {
+					log.Error("Ошибка закрытия последовательного порта", zap.Error(err))
+				}
+			}()
+		}
+	}
+
+	// Прием сводок recipient по обратному каналу (если включен)
+	var auditListener *broker.AuditListener
+	if cfg.Audit.Enabled {
+		auditListener = broker.NewAuditListener(&cfg.Audit, log.Logger)
+		auditListener.Start()
+		defer auditListener.Close()
+	}
+
+	// Пробные сообщения проверки пути до recipient для /healthz (если включены);
+	// останавливаются перед закрытием MQTT соединения
+	var healthProber *broker.HealthProber
+	if cfg.HealthProbe.Enabled {
+		healthProber = broker.NewHealthProber(producer, &cfg.HealthProbe, log.Logger)
+		healthProber.Start()
+	}
+
+	// Уведомления о событиях тестов (если заданы получатели)
+	var notifier *webhook.Notifier
+	if len(cfg.Webhooks) > 0 {
+		notifier = webhook.NewNotifier(webhookTargets(cfg.Webhooks), log.Logger)
+		notifier.Start()
+		defer notifier.Close()
+	}
+
+	// Согласованные тесты нескольких sender: ведущий принимает регистрацию,
+	// остальные sender регистрируются у него после запуска HTTP API
+	var coordinator *cluster.Coordinator
+	var clusterAgent *cluster.Agent
+	if cfg.Cluster.Lead || cfg.Cluster.LeadURL != "" {
+		clusterClient, err := utils.HTTPClient(cfg.Cluster.Timeout, cfg.Cluster.CAFile)
+		if err != nil {
+			log.Fatal("Ошибка создания клиента кластера", zap.Error(err))
+		}
+		if cfg.Cluster.Lead {
+			registry := cluster.NewRegistry(cfg.Cluster.HeartbeatInterval)
+			coordinator = cluster.NewCoordinator(registry, clusterClient, cfg.Cluster.StartDelay, log.Logger)
+		}
+		if cfg.Cluster.LeadURL != "" {
+			nodeID := clusterNodeID(&cfg.Cluster)
+			test.SetTestIDSuffix("-" + nodeID)
+			clusterAgent = cluster.NewAgent(cfg.Cluster.LeadURL, cluster.Registration{
+				ID:      nodeID,
+				URL:     cfg.Cluster.AdvertiseURL,
+				Version: Version,
+			}, cfg.Cluster.HeartbeatInterval, clusterClient, log.Logger)
+		}
+	}
+
+	// Шаблоны тестов, сохраненные через API
+	templateStore, err := templates.NewStore(cfg.Tests.TemplatesDirectory, log.Logger)
+	if err != nil {
+		log.Fatal("Ошибка загрузки шаблонов тестов", zap.Error(err))
+	}
+
+	// Журнал действий API (если задан файл)
+	var actions *actionlog.Log
+	if cfg.ActionLog.File != "" {
+		actions, err = actionlog.Open(cfg.ActionLog.File)
+		if err != nil {
+			log.Fatal("Ошибка открытия журнала действий", zap.Error(err))
+		}
+		defer actions.Close()
+
+		if status := actions.Status(); !status.ChainValid {
+			log.Warn("Нарушена цепочка записей журнала действий",
+				zap.String("file", status.File),
+				zap.String("reason", status.ChainError))
+		}
+		recordAction(log, actions, "service.start", map[string]string{"version": Version, "commit": gitCommit()}, nil)
+		defer recordAction(log, actions, "service.stop", nil, nil)
+	}
+
 	// Создаем HTTP API сервер
 	apiConfig := &api.Config{
-		Host:            cfg.HTTP.Host,
-		Port:            cfg.HTTP.Port,
-		ReadTimeout:     cfg.HTTP.ReadTimeout,
-		WriteTimeout:    cfg.HTTP.WriteTimeout,
-		ShutdownTimeout: cfg.HTTP.ShutdownTimeout,
+		Host:              cfg.HTTP.Host,
+		Port:              cfg.HTTP.Port,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		ShutdownTimeout:   cfg.HTTP.ShutdownTimeout,
+		CertFile:          cfg.HTTP.CertFile,
+		KeyFile:           cfg.HTTP.KeyFile,
+		MetricsEnabled:    cfg.Metrics.Enabled,
+		Debug:             cfg.Metrics.Debug,
+		CaptureDir:        cfg.Tests.CaptureDirectory,
+		CorrelationDir:    cfg.Tests.CorrelationDirectory,
+		FilesDir:          cfg.Tests.FilesDirectory,
+		Templates:         templateStore,
+		TestLimits:        testLimits(&cfg.Tests),
+		TenantTopics:      cfg.MQTT.TenantTopics,
+		AbortPolicy:       abortPolicy(&cfg.Tests.Abort),
+		MemoryPolicy:      memoryPolicy(&cfg.Tests.Memory),
+		TimeFormat:        cfg.Tests.TimeFormat,
+		Health:            healthPolicy(&cfg.Health),
+		LogDir:            logDir(&cfg.Logger),
+		Version:           newVersionInfo(cfg),
+		Audit:             auditListener,
+		HealthProber:      healthProber,
+		Notifier:          notifier,
+		ActionLog:         actions,
+		Cluster:           coordinator,
+		ClusterAgent:      clusterAgent,
+	}
+
+	// Канал оркестрации для запроса статистики recipient (если настроен)
+	orchestrator, err := orchestration.NewClient(&orchestration.Config{
+		RecipientURL: cfg.Tests.RecipientURL,
+		Timeout:      cfg.Tests.RecipientTimeout,
+		CAFile:       cfg.Tests.RecipientCAFile,
+	})
+	if err != nil {
+		log.Fatal("Ошибка создания клиента оркестрации", zap.Error(err))
 	}
 
-	apiServer := api.NewAPI(apiConfig, log.Logger, producer, dataGenerator, tcpClient)
+	apiServer := api.NewAPI(apiConfig, log.Logger, producer, dataGenerator, transports, orchestrator)
+
+	// Политика хранения наборов данных; проверки пропускаются во время тестов
+	dataGenerator.StartRetention(apiServer.TestsRunning)
+
+	// Применение изменений конфигурации без перезапуска (изменение файла или SIGHUP)
+	reloader := &configReloader{
+		current:   *cfg,
+		log:       log,
+		generator: dataGenerator,
+		api:       apiServer,
+		actions:   actions,
+	}
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if err := config.Watch(watchCtx, *configPath, reloader.apply); err != nil {
+		log.Warn("Отслеживание изменений конфигурации недоступно", zap.Error(err))
+	}
 
 	// Канал для graceful shutdown
 	shutdown := make(chan os.Signal, 1)
@@ -170,6 +469,12 @@ func main() {
 	// Канал для ошибок
 	errChan := make(chan error, 1)
 
+	// Уведомления systemd и работа службой Windows
+	service, err := supervisor.Start("infodiode-sender", shutdown, errChan)
+	if err != nil {
+		log.Fatal("Ошибка подключения к супервизору", zap.Error(err))
+	}
+
 	// Запускаем HTTP сервер
 	go func() {
 		log.Info("Запуск HTTP API сервера",
@@ -181,33 +486,100 @@ func main() {
 		}
 	}()
 
+	if clusterAgent != nil {
+		clusterAgent.Start()
+	}
+
+	if err := service.Ready(); err != nil {
+		log.Warn("Ошибка уведомления systemd о готовности", zap.Error(err))
+	}
+	if interval := service.Watchdog(nil); interval > 0 {
+		log.Info("Watchdog systemd включен", zap.Duration("interval", interval))
+	}
+
 	// Ожидаем сигнал завершения или ошибку
+	var exitErr error
 	select {
 	case sig := <-shutdown:
 		log.Info("Получен сигнал завершения", zap.String("signal", sig.String()))
-	case err := <-errChan:
-		log.Error("Критическая ошибка", zap.Error(err))
+	case exitErr = <-errChan:
+		log.Error("Критическая ошибка", zap.Error(exitErr))
 	}
 
 	// Graceful shutdown
 	log.Info("Начало graceful shutdown...")
+	service.Stopping()
 
 	// Создаем контекст с таймаутом для shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
 	defer cancel()
 
+	// Снимаем регистрацию у ведущего до остановки HTTP API
+	if clusterAgent != nil {
+		clusterAgent.Close()
+	}
+
 	// Останавливаем HTTP сервер
 	if err := apiServer.Shutdown(ctx); err != nil {
 		log.Error("Ошибка остановки HTTP сервера", zap.Error(err))
 	}
 
 	// Закрываем MQTT соединение
+	if healthProber != nil {
+		healthProber.Close()
+	}
 	if err := producer.Close(); err != nil {
 		log.Error("Ошибка закрытия MQTT producer", zap.Error(err))
 	}
+	saveLifetime()
 
-	// Очищаем кеш генератора
+	// Останавливаем проверку политики хранения и очищаем кеш генератора
+	dataGenerator.StopRetention()
 	dataGenerator.ClearCache()
 
 	log.Info("Sender сервис остановлен")
+	service.Stopped(exitErr)
+
+	// Ненулевой код завершения после критической ошибки, чтобы супервизор перезапустил сервис
+	if exitErr != nil {
+		log.Close()
+		os.Exit(1)
+	}
+}
+
+// webhookTargets возвращает получателей уведомлений о событиях тестов из конфигурации
+func webhookTargets(webhooks []config.WebhookConfig) []webhook.Target {
+	targets := make([]webhook.Target, 0, len(webhooks))
+	for _, hook := range webhooks {
+		targets = append(targets, webhook.Target{
+			URL:        hook.URL,
+			Headers:    hook.Headers,
+			Events:     hook.Events,
+			Timeout:    hook.Timeout,
+			Retries:    hook.Retries,
+			RetryDelay: hook.RetryDelay,
+		})
+	}
+	return targets
+}
+
+// syslogSink возвращает параметры вывода логов на сервер syslog или nil, если он выключен
+func syslogSink(cfg config.SyslogConfig) *logging.SyslogConfig {
+	if !cfg.Enabled {
+		return nil
+	}
+	sink := cfg.SinkConfig()
+	return &sink
+}
+
+// clusterNodeID возвращает имя sender в кластере: cluster.node_id или имя хоста
+func clusterNodeID(cfg *config.ClusterConfig) string {
+	if cfg.NodeID != "" {
+		return cfg.NodeID
+	}
+	hostname, err := os.Hostname()
+	if err != nil || models.ValidateLabel("node_id", hostname) != nil {
+		return "sender"
+	}
+	return hostname
 }
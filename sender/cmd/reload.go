@@ -0,0 +1,241 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/infodiode/sender/config"
+	"github.com/infodiode/sender/internal/api"
+	"github.com/infodiode/sender/internal/generator"
+	"github.com/infodiode/sender/internal/logger"
+	"github.com/infodiode/sender/internal/test"
+	"github.com/infodiode/shared/actionlog"
+	"github.com/infodiode/shared/configwatch"
+	"go.uber.org/zap"
+)
+
+// configReloader применяет изменения конфигурации без перезапуска сервиса.
+// Без перезапуска меняются уровень логирования, распределение типов значений
+// генератора, политика хранения наборов данных, включение экспорта метрик,
+// ограничения одновременных тестов (для тестов, запущенных после изменения), пороги
+// их прерывания и пороги проверок /health; об остальных изменениях пишется в лог
+type configReloader struct {
+	current   config.Config
+	log       *logger.Logger
+	generator *generator.DataGenerator
+	api       *api.API
+	actions   *actionlog.Log // Журнал действий, nil если не ведется
+}
+
+// apply применяет перечитанную конфигурацию
+func (r *configReloader) apply(next *config.Config, err error) {
+	if err != nil {
+		r.log.Error("Ошибка перечитывания конфигурации, используется текущая", zap.Error(err))
+		recordAction(r.log, r.actions, "config.reload", nil, err)
+		return
+	}
+
+	var applied []string
+
+	if next.Logger.Level != r.current.Logger.Level {
+		if err := r.log.SetLevel(next.Logger.Level); err != nil {
+			r.log.Error("Ошибка изменения уровня логирования", zap.Error(err))
+		} else {
+			r.log.Info("Уровень логирования изменен",
+				zap.String("old", r.current.Logger.Level),
+				zap.String("new", next.Logger.Level))
+			r.current.Logger.Level = next.Logger.Level
+			applied = append(applied, "logger.level")
+		}
+	}
+
+	if next.Data.NullPercent != r.current.Data.NullPercent ||
+		next.Data.BoolPercent != r.current.Data.BoolPercent ||
+		next.Data.FloatPercent != r.current.Data.FloatPercent ||
+		next.Data.StringPercent != r.current.Data.StringPercent {
+		r.generator.SetDistribution(next.Data.NullPercent, next.Data.BoolPercent,
+			next.Data.FloatPercent, next.Data.StringPercent)
+		r.log.Info("Распределение типов значений генератора изменено",
+			zap.Float64("null_percent", next.Data.NullPercent),
+			zap.Float64("bool_percent", next.Data.BoolPercent),
+			zap.Float64("float_percent", next.Data.FloatPercent),
+			zap.Float64("string_percent", next.Data.StringPercent))
+		r.current.Data.NullPercent = next.Data.NullPercent
+		r.current.Data.BoolPercent = next.Data.BoolPercent
+		r.current.Data.FloatPercent = next.Data.FloatPercent
+		r.current.Data.StringPercent = next.Data.StringPercent
+		applied = append(applied, "data.distribution")
+	}
+
+	if !reflect.DeepEqual(next.Data.Retention, r.current.Data.Retention) {
+		r.generator.SetRetention(retentionPolicy(&next.Data.Retention))
+		r.log.Info("Политика хранения наборов данных изменена",
+			zap.Strings("sets", next.Data.Retention.Sets),
+			zap.Duration("max_age", next.Data.Retention.MaxAge),
+			zap.Int("max_total_mb", next.Data.Retention.MaxTotalMB),
+			zap.Duration("interval", next.Data.Retention.Interval))
+		r.current.Data.Retention = next.Data.Retention
+		applied = append(applied, "data.retention")
+	}
+
+	if next.Metrics.Enabled != r.current.Metrics.Enabled {
+		r.api.SetMetricsEnabled(next.Metrics.Enabled)
+		r.log.Info("Экспорт метрик изменен", zap.Bool("enabled", next.Metrics.Enabled))
+		r.current.Metrics.Enabled = next.Metrics.Enabled
+		applied = append(applied, "metrics.enabled")
+	}
+
+	if next.Health != r.current.Health {
+		r.api.SetHealthPolicy(healthPolicy(&next.Health))
+		r.log.Info("Пороги проверок состояния изменены",
+			zap.Float64("min_free_disk_percent", next.Health.MinFreeDiskPercent),
+			zap.Int64("max_store_backlog", next.Health.MaxStoreBacklog))
+		r.current.Health = next.Health
+		applied = append(applied, "health")
+	}
+
+	// Ограничения проверяются при запуске теста: темп отправки выполняющихся тестов
+	// задан их параметрами и не меняется, поэтому превышение новых ограничений
+	// выполняющимися тестами записывается в лог отдельно
+	var notApplied []string
+	if limits := testLimits(&next.Tests); limits != testLimits(&r.current.Tests) {
+		threads, rate := r.api.SetTestLimits(limits)
+		r.log.Info("Ограничения одновременных тестов изменены, применяются к тестам, запущенным после изменения",
+			zap.Int("max_concurrent", limits.MaxConcurrent),
+			zap.Int("max_total_threads", limits.MaxTotalThreads),
+			zap.Int("max_total_rate", limits.MaxTotalRate))
+		if limits.MaxTotalThreads > 0 && threads > limits.MaxTotalThreads {
+			notApplied = append(notApplied, "tests.max_total_threads")
+		}
+		if limits.MaxTotalRate > 0 && rate > limits.MaxTotalRate {
+			notApplied = append(notApplied, "tests.max_total_rate")
+		}
+		if len(notApplied) > 0 {
+			r.log.Warn("Выполняющиеся тесты превышают новые ограничения и продолжаются с заданными при запуске потоками и скоростью",
+				zap.Strings("limits", notApplied),
+				zap.Int("running_threads", threads),
+				zap.Int("running_rate", rate))
+		}
+		r.current.Tests.MaxConcurrent = next.Tests.MaxConcurrent
+		r.current.Tests.MaxTotalThreads = next.Tests.MaxTotalThreads
+		r.current.Tests.MaxTotalRate = next.Tests.MaxTotalRate
+		applied = append(applied, "tests.limits")
+	}
+
+	if next.Tests.Abort != r.current.Tests.Abort {
+		r.api.SetAbortPolicy(abortPolicy(&next.Tests.Abort))
+		r.log.Info("Пороги прерывания тестов изменены",
+			zap.Float64("error_rate", next.Tests.Abort.ErrorRate),
+			zap.Int("min_attempts", next.Tests.Abort.MinAttempts),
+			zap.Int("consecutive_errors", next.Tests.Abort.ConsecutiveErrors))
+		r.current.Tests.Abort = next.Tests.Abort
+		applied = append(applied, "tests.abort")
+	}
+
+	if next.Tests.Memory != r.current.Tests.Memory {
+		r.api.SetMemoryPolicy(memoryPolicy(&next.Tests.Memory))
+		r.log.Info("Ограничение памяти тестов изменено",
+			zap.Int("budget_mb", next.Tests.Memory.BudgetMB),
+			zap.String("policy", next.Tests.Memory.Policy))
+		r.current.Tests.Memory = next.Tests.Memory
+		applied = append(applied, "tests.memory")
+	}
+
+	if next.Tests.TimeFormat != r.current.Tests.TimeFormat {
+		r.api.SetTimeFormat(next.Tests.TimeFormat)
+		r.log.Info("Формат времени отправки изменен",
+			zap.String("old", r.current.Tests.TimeFormat),
+			zap.String("new", next.Tests.TimeFormat))
+		r.current.Tests.TimeFormat = next.Tests.TimeFormat
+		applied = append(applied, "tests.time_format")
+	}
+
+	// Seed по умолчанию берется из текущего времени и меняется при каждом чтении
+	next.Data.GeneratorSeed = r.current.Data.GeneratorSeed
+
+	sections := configwatch.ChangedSections(&r.current, next)
+	if len(sections) > 0 {
+		r.log.Warn("Изменения конфигурации вступят в силу после перезапуска сервиса",
+			zap.Strings("sections", sections))
+	}
+
+	if len(applied) > 0 || len(sections) > 0 {
+		details := map[string]string{}
+		if len(applied) > 0 {
+			details["applied"] = strings.Join(applied, ",")
+		}
+		if len(sections) > 0 {
+			details["pending_restart"] = strings.Join(sections, ",")
+		}
+		if len(notApplied) > 0 {
+			details["not_applied_running"] = strings.Join(notApplied, ",")
+		}
+		recordAction(r.log, r.actions, "config.reload", details, nil)
+	}
+}
+
+// recordAction записывает в журнал действие сервиса, выполненное без запроса API
+func recordAction(log *logger.Logger, actions *actionlog.Log, action string, details map[string]string, err error) {
+	event := actionlog.Event{Actor: actionlog.ActorSystem, Action: action, Details: details}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	if err := actions.Record(event); err != nil {
+		log.Error("Ошибка записи действия в журнал", zap.String("action", action), zap.Error(err))
+	}
+}
+
+// testLimits возвращает ограничения одновременных тестов из конфигурации
+func testLimits(cfg *config.TestsConfig) api.TestLimits {
+	return api.TestLimits{
+		MaxConcurrent:   cfg.MaxConcurrent,
+		MaxTotalThreads: cfg.MaxTotalThreads,
+		MaxTotalRate:    cfg.MaxTotalRate,
+	}
+}
+
+// abortPolicy возвращает пороги прерывания тестов из конфигурации
+func abortPolicy(cfg *config.AbortConfig) test.AbortPolicy {
+	return test.AbortPolicy{
+		ErrorRate:         cfg.ErrorRate,
+		MinAttempts:       int64(cfg.MinAttempts),
+		ConsecutiveErrors: int64(cfg.ConsecutiveErrors),
+	}
+}
+
+// healthPolicy возвращает пороги проверок /health из конфигурации
+func healthPolicy(cfg *config.HealthConfig) api.HealthPolicy {
+	return api.HealthPolicy{
+		MinFreeDiskPercent: cfg.MinFreeDiskPercent,
+		MaxStoreBacklog:    cfg.MaxStoreBacklog,
+	}
+}
+
+// logDir возвращает директорию файла логов (пусто - логи только в консоль)
+func logDir(cfg *config.LoggerConfig) string {
+	if cfg.FilePath == "" {
+		return ""
+	}
+	return filepath.Dir(cfg.FilePath)
+}
+
+// retentionPolicy возвращает политику хранения наборов данных из конфигурации
+func retentionPolicy(cfg *config.RetentionConfig) generator.RetentionPolicy {
+	return generator.RetentionPolicy{
+		Sets:          cfg.Sets,
+		MaxAge:        cfg.MaxAge,
+		MaxTotalBytes: int64(cfg.MaxTotalMB) << 20,
+		Interval:      cfg.Interval,
+	}
+}
+
+// memoryPolicy возвращает ограничение памяти тестов из конфигурации
+func memoryPolicy(cfg *config.MemoryConfig) test.MemoryPolicy {
+	return test.MemoryPolicy{
+		Budget: int64(cfg.BudgetMB) << 20,
+		Stream: cfg.Policy == config.MemoryPolicyStream,
+
+		MmapThreshold: int64(cfg.MmapThresholdMB) << 20,
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"runtime"
+
+	"github.com/infodiode/sender/config"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
+)
+
+// newVersionInfo формирует сведения о сборке и конфигурации для GET /version;
+// транспорты и хеш генератора заполняет API при запросе
+func newVersionInfo(cfg *config.Config) models.VersionInfo {
+	return models.VersionInfo{
+		Service:    "sender",
+		Version:    Version,
+		BuildTime:  BuildTime,
+		GitCommit:  gitCommit(),
+		GoVersion:  runtime.Version(),
+		Features:   enabledFeatures(cfg),
+		ConfigHash: utils.ConfigHash(cfg),
+	}
+}
+
+// gitCommit возвращает коммит сборки: заданный при сборке или встроенный go build
+func gitCommit() string {
+	if GitCommit != "" {
+		return GitCommit
+	}
+	return utils.VCSRevision()
+}
+
+// enabledFeatures возвращает включенные в конфигурации возможности
+func enabledFeatures(cfg *config.Config) []string {
+	features := []string{}
+	add := func(name string, enabled bool) {
+		if enabled {
+			features = append(features, name)
+		}
+	}
+
+	add("mqtt_store", cfg.MQTT.StoreDirectory != "")
+	add("mqtt_queue", cfg.MQTT.QueueDirectory != "")
+	add("mqtt_will", cfg.MQTT.WillTopic != "")
+	add("mqtt_aggregate_batches", cfg.MQTT.AggregateBatches)
+	add("data_schema", len(cfg.Data.Schema) > 0)
+	add("data_binary", len(cfg.Data.Binary.Fields) > 0)
+	add("data_compression", cfg.Data.CompressionLevel > 0)
+	add("data_tags", cfg.Data.TagsFile != "")
+	add("data_retention", cfg.Data.Retention.MaxAge > 0 || cfg.Data.Retention.MaxTotalMB > 0)
+	add("tcp_frame_checksum", cfg.TCP.Enabled && cfg.TCP.FrameChecksum)
+	add("orchestration", cfg.Tests.RecipientURL != "")
+	add("audit", cfg.Audit.Enabled)
+	add("health_probe", cfg.HealthProbe.Enabled)
+	add("correlation", cfg.Tests.CorrelationDirectory != "")
+	add("memory_budget", cfg.Tests.Memory.BudgetMB > 0)
+	add("dataset_mmap", cfg.Tests.Memory.MmapThresholdMB > 0)
+	add("time_format_"+cfg.Tests.TimeFormat, cfg.Tests.TimeFormat != utils.TimeFormatRFC3339Nano)
+	add("webhooks", len(cfg.Webhooks) > 0)
+	add("cluster_lead", cfg.Cluster.Lead)
+	add("cluster_node", cfg.Cluster.LeadURL != "")
+	add("action_log", cfg.ActionLog.File != "")
+	add("syslog", cfg.Logger.Syslog.Enabled)
+	add("metrics", cfg.Metrics.Enabled)
+	add("pprof", cfg.Metrics.Debug)
+
+	return features
+}
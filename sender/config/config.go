@@ -2,9 +2,21 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"slices"
 	"time"
 
+	"github.com/infodiode/sender/internal/quic"
+	"github.com/infodiode/sender/internal/tcp"
+	"github.com/infodiode/sender/internal/webhook"
+	"github.com/infodiode/shared/binrec"
+	"github.com/infodiode/shared/configwatch"
+	"github.com/infodiode/shared/logging"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/serialport"
+	"github.com/infodiode/shared/tcpframe"
+	"github.com/infodiode/shared/utils"
 	"github.com/spf13/viper"
 )
 
@@ -13,11 +25,22 @@ type Config struct {
 	Service ServiceConfig `mapstructure:"service"`
 	MQTT    MQTTConfig    `mapstructure:"mqtt"`
 	TCP     TCPConfig     `mapstructure:"tcp"`
+	QUIC    QUICConfig    `mapstructure:"quic"`
+	NATS    NATSConfig    `mapstructure:"nats"`
+	Serial  SerialConfig  `mapstructure:"serial"`
 	Logger  LoggerConfig  `mapstructure:"logger"`
 	Data    DataConfig    `mapstructure:"data"`
 	HTTP    HTTPConfig    `mapstructure:"http"`
 	Metrics MetricsConfig `mapstructure:"metrics"`
+	Health  HealthConfig  `mapstructure:"health"`
 	Tests   TestsConfig   `mapstructure:"tests"`
+	Audit   AuditConfig   `mapstructure:"audit"`
+
+	HealthProbe HealthProbeConfig `mapstructure:"health_probe"`
+
+	Webhooks  []WebhookConfig `mapstructure:"webhooks"`
+	ActionLog ActionLogConfig `mapstructure:"action_log"`
+	Cluster   ClusterConfig   `mapstructure:"cluster"`
 }
 
 // ServiceConfig конфигурация сервиса
@@ -28,32 +51,154 @@ type ServiceConfig struct {
 
 // MQTTConfig конфигурация MQTT брокера
 type MQTTConfig struct {
-	Broker          string        `mapstructure:"broker"`                 // Адрес брокера (tcp://host:port)
-	ClientID        string        `mapstructure:"client_id"`              // Уникальный идентификатор клиента
-	Username        string        `mapstructure:"username"`               // Имя пользователя для аутентификации
-	Password        string        `mapstructure:"password"`               // Пароль для аутентификации
-	Topic           string        `mapstructure:"topic"`                  // Топик для публикации
-	QoS             byte          `mapstructure:"qos"`                    // Quality of Service (0, 1, 2)
-	Retained        bool          `mapstructure:"retained"`               // Сохранять ли последнее сообщение
-	CleanSession    bool          `mapstructure:"clean_session"`          // Очищать ли сессию при подключении
-	KeepAlive       time.Duration `mapstructure:"keep_alive"`             // Интервал keep-alive
-	ConnectTimeout  time.Duration `mapstructure:"connect_timeout"`        // Таймаут подключения
-	MaxReconnectInt time.Duration `mapstructure:"max_reconnect_interval"` // Максимальный интервал переподключения
-	AutoReconnect   bool          `mapstructure:"auto_reconnect"`         // Автоматическое переподключение
-	OrderMatters    bool          `mapstructure:"order_matters"`          // Сохранять ли порядок сообщений
-	StoreDirectory  string        `mapstructure:"store_directory"`        // Директория для хранения сообщений при отсутствии связи
-	MaxBufferedMsgs int           `mapstructure:"max_buffered_messages"`  // Максимум буферизованных сообщений
+	Broker            string        `mapstructure:"broker"`                 // Адрес брокера (tcp://host:port)
+	Brokers           []string      `mapstructure:"brokers"`                // Список брокеров для отказоустойчивости (приоритетнее broker)
+	BrokerStrategy    string        `mapstructure:"broker_strategy"`        // Стратегия выбора брокера: failover или round_robin
+	ClientID          string        `mapstructure:"client_id"`              // Уникальный идентификатор клиента
+	Username          string        `mapstructure:"username"`               // Имя пользователя для аутентификации
+	Password          string        `mapstructure:"password"`               // Пароль для аутентификации
+	Topic             string        `mapstructure:"topic"`                  // Топик для публикации
+	TenantTopics      bool          `mapstructure:"tenant_topics"`          // Публиковать тесты с tenant в топик <topic>/<tenant>
+	QoS               byte          `mapstructure:"qos"`                    // Quality of Service (0, 1, 2)
+	Retained          bool          `mapstructure:"retained"`               // Сохранять ли последнее сообщение
+	CleanSession      bool          `mapstructure:"clean_session"`          // Очищать ли сессию при подключении
+	KeepAlive         time.Duration `mapstructure:"keep_alive"`             // Интервал keep-alive
+	ConnectTimeout    time.Duration `mapstructure:"connect_timeout"`        // Таймаут подключения
+	MaxReconnectInt   time.Duration `mapstructure:"max_reconnect_interval"` // Максимальный интервал переподключения
+	AutoReconnect     bool          `mapstructure:"auto_reconnect"`         // Автоматическое переподключение
+	OrderMatters      bool          `mapstructure:"order_matters"`          // Сохранять ли порядок сообщений
+	StoreDirectory    string        `mapstructure:"store_directory"`        // Директория для хранения сообщений при отсутствии связи
+	MaxBufferedMsgs   int           `mapstructure:"max_buffered_messages"`  // Максимум сообщений в очереди отправки (0 - без ограничения)
+	QueueDirectory    string        `mapstructure:"queue_directory"`        // Директория очереди отправки на время недоступности брокера (пусто - отключена)
+	StatsFile         string        `mapstructure:"stats_file"`             // Файл счетчиков, накопленных между перезапусками (пусто - не накапливать)
+	StatsSaveInterval time.Duration `mapstructure:"stats_save_interval"`    // Интервал сохранения счетчиков в файл (0 - только при остановке)
+	WillTopic         string        `mapstructure:"will_topic"`             // Топик last will (пусто - last will не задается)
+	WillPayload       string        `mapstructure:"will_payload"`           // Содержимое last will
+	WillQoS           byte          `mapstructure:"will_qos"`               // QoS last will
+	WillRetained      bool          `mapstructure:"will_retained"`          // Сохранять ли last will на брокере
+	AggregateBatches  bool          `mapstructure:"aggregate_batches"`      // Публиковать пакет сообщений теста batch одним сообщением брокера
+}
+
+// Стратегии выбора брокера при переподключении
+const (
+	BrokerStrategyFailover   = "failover"    // Всегда начинать с первого брокера списка
+	BrokerStrategyRoundRobin = "round_robin" // Начинать со следующего брокера после текущего
+)
+
+// BrokerList возвращает список брокеров с учетом одиночного адреса broker
+func (c *MQTTConfig) BrokerList() []string {
+	if len(c.Brokers) > 0 {
+		return c.Brokers
+	}
+	if c.Broker != "" {
+		return []string{c.Broker}
+	}
+	return nil
 }
 
 // TCPConfig конфигурация TCP клиента
 type TCPConfig struct {
 	Address         string        `mapstructure:"address"`            // Адрес TCP сервера (host:port)
-	ReconnectInt    time.Duration `mapstructure:"reconnect_interval"` // Интервал переподключения
-	MaxRetries      int           `mapstructure:"max_retries"`        // Максимальное количество попыток
+	ReconnectInt    time.Duration `mapstructure:"reconnect_interval"` // Пауза после первой неудачной попытки переподключения
+	MaxRetries      int           `mapstructure:"max_retries"`        // Попыток в серии переподключения
 	Timeout         time.Duration `mapstructure:"timeout"`            // Таймаут операций
 	KeepAlive       bool          `mapstructure:"keep_alive"`         // Использовать ли keep-alive
 	KeepAlivePeriod time.Duration `mapstructure:"keep_alive_period"`  // Период keep-alive
+	Framing         string        `mapstructure:"framing"`            // Режим согласования формата кадров (auto, legacy)
+	PingInterval    time.Duration `mapstructure:"ping_interval"`      // Период ping в протоколе v2 (0 - не отправлять)
+	PingTimeout     time.Duration `mapstructure:"ping_timeout"`       // Предельное время без pong (0 - три периода ping)
 	Enabled         bool          `mapstructure:"enabled"`            // Включен ли TCP транспорт
+
+	MaxReconnectInt time.Duration `mapstructure:"max_reconnect_interval"` // Предельная пауза между попытками переподключения
+	FrameChecksum   bool          `mapstructure:"frame_checksum"`         // Контрольная сумма кадров в протоколе v2
+
+	ReadBufferSize int  `mapstructure:"read_buffer_size"` // Буфер чтения кадров сервера, байт
+	RecvBuffer     int  `mapstructure:"recv_buffer"`      // SO_RCVBUF, байт (0 - по умолчанию ОС)
+	SendBuffer     int  `mapstructure:"send_buffer"`      // SO_SNDBUF, байт (0 - по умолчанию ОС)
+	NoDelay        bool `mapstructure:"no_delay"`         // TCP_NODELAY
+	Linger         int  `mapstructure:"linger"`           // SO_LINGER, секунд (-1 - по умолчанию ОС)
+}
+
+// SocketOptions возвращает параметры сокета соединения
+func (c TCPConfig) SocketOptions() tcpframe.SocketOptions {
+	return tcpframe.SocketOptions{
+		ReadBufferSize: c.ReadBufferSize,
+		RecvBuffer:     c.RecvBuffer,
+		SendBuffer:     c.SendBuffer,
+		NoDelay:        c.NoDelay,
+		Linger:         c.Linger,
+	}
+}
+
+// QUICConfig конфигурация QUIC клиента
+type QUICConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`              // Включен ли QUIC транспорт
+	Address         string        `mapstructure:"address"`              // Адрес QUIC сервера (host:port, UDP)
+	ServerName      string        `mapstructure:"server_name"`          // Имя сервера в сертификате (пусто - хост из address)
+	CAFile          string        `mapstructure:"ca_file"`              // Центр сертификации сервера (пусто - системные сертификаты)
+	InsecureSkip    bool          `mapstructure:"insecure_skip_verify"` // Не проверять сертификат сервера (самоподписанный сертификат recipient)
+	Streams         int           `mapstructure:"streams"`              // Потоков в соединении
+	ZeroRTT         bool          `mapstructure:"zero_rtt"`             // Отправка 0-RTT при возобновлении сессии
+	Timeout         time.Duration `mapstructure:"timeout"`              // Таймаут подключения и записи
+	ReconnectInt    time.Duration `mapstructure:"reconnect_interval"`   // Интервал переподключения
+	MaxRetries      int           `mapstructure:"max_retries"`          // Максимальное количество попыток
+	KeepAlivePeriod time.Duration `mapstructure:"keep_alive_period"`    // Период keep-alive QUIC (0 - не отправлять)
+	IdleTimeout     time.Duration `mapstructure:"idle_timeout"`         // Время простоя до закрытия соединения
+}
+
+// ClientConfig возвращает параметры клиента для адреса address
+func (c QUICConfig) ClientConfig(address string) *quic.Config {
+	return &quic.Config{
+		Address:         address,
+		ServerName:      c.ServerName,
+		CAFile:          c.CAFile,
+		InsecureSkip:    c.InsecureSkip,
+		Streams:         c.Streams,
+		ZeroRTT:         c.ZeroRTT,
+		Timeout:         c.Timeout,
+		ReconnectInt:    c.ReconnectInt,
+		MaxRetries:      c.MaxRetries,
+		KeepAlivePeriod: c.KeepAlivePeriod,
+		IdleTimeout:     c.IdleTimeout,
+	}
+}
+
+// NATSConfig конфигурация NATS JetStream
+type NATSConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`         // Включен ли NATS транспорт
+	URL            string        `mapstructure:"url"`             // Адрес сервера (nats://host:port)
+	Username       string        `mapstructure:"username"`        // Имя пользователя для аутентификации
+	Password       string        `mapstructure:"password"`        // Пароль для аутентификации
+	Token          string        `mapstructure:"token"`           // Токен аутентификации
+	Stream         string        `mapstructure:"stream"`          // Поток JetStream (создается при отсутствии)
+	Subject        string        `mapstructure:"subject"`         // Субъект для публикации
+	ConnectTimeout time.Duration `mapstructure:"connect_timeout"` // Таймаут подключения
+	AckTimeout     time.Duration `mapstructure:"ack_timeout"`     // Таймаут ожидания подтверждения JetStream
+	ReconnectWait  time.Duration `mapstructure:"reconnect_wait"`  // Минимальный интервал между попытками переподключения
+}
+
+// SerialConfig конфигурация последовательного порта (RS-232/RS-485)
+type SerialConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`   // Включен ли транспорт последовательного порта
+	Device   string        `mapstructure:"device"`    // Устройство порта (/dev/ttyS0, /dev/ttyUSB0)
+	BaudRate int           `mapstructure:"baud_rate"` // Скорость, бод
+	DataBits int           `mapstructure:"data_bits"` // Битов данных (5-8)
+	Parity   string        `mapstructure:"parity"`    // Контроль четности: none, even или odd
+	StopBits int           `mapstructure:"stop_bits"` // Стоповых битов (1 или 2)
+	RS485    bool          `mapstructure:"rs485"`     // Режим RS-485 (направление передачи через RTS)
+	Timeout  time.Duration `mapstructure:"timeout"`   // Запас времени записи сверх времени передачи кадра
+}
+
+// PortConfig возвращает параметры порта для устройства device
+func (c SerialConfig) PortConfig(device string) serialport.PortConfig {
+	return serialport.PortConfig{
+		Device:   device,
+		BaudRate: c.BaudRate,
+		DataBits: c.DataBits,
+		Parity:   c.Parity,
+		StopBits: c.StopBits,
+		RS485:    c.RS485,
+	}
 }
 
 // LoggerConfig конфигурация логирования
@@ -65,6 +210,35 @@ type LoggerConfig struct {
 	MaxAge     int    `mapstructure:"max_age"` // days
 	Compress   bool   `mapstructure:"compress"`
 	Console    bool   `mapstructure:"console"`
+
+	Format        string `mapstructure:"format"`         // Формат файла: json, logfmt или console
+	FileLevel     string `mapstructure:"file_level"`     // Уровень файла (пусто - общий level)
+	ConsoleFormat string `mapstructure:"console_format"` // Формат консоли: console, json или logfmt
+	ConsoleLevel  string `mapstructure:"console_level"`  // Уровень консоли (пусто - общий level)
+
+	Syslog SyslogConfig `mapstructure:"syslog"`
+}
+
+// SyslogConfig конфигурация вывода логов на сервер syslog (RFC5424)
+type SyslogConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Network  string `mapstructure:"network"`  // udp, tcp, unix или unixgram (/dev/log для journald)
+	Address  string `mapstructure:"address"`  // host:port или путь к сокету
+	Facility string `mapstructure:"facility"` // Источник: user, daemon, local0-local7 и др.
+	AppName  string `mapstructure:"app_name"` // APP-NAME заголовка
+	Level    string `mapstructure:"level"`    // Уровень вывода (пусто - общий level)
+	Format   string `mapstructure:"format"`   // Формат текста сообщения: logfmt или json
+}
+
+// SinkConfig возвращает параметры вывода на сервер syslog
+func (c SyslogConfig) SinkConfig() logging.SyslogConfig {
+	return logging.SyslogConfig{
+		Network:  c.Network,
+		Address:  c.Address,
+		Facility: c.Facility,
+		AppName:  c.AppName,
+		Format:   c.Format,
+	}
 }
 
 // DataConfig конфигурация генератора данных
@@ -80,21 +254,147 @@ type DataConfig struct {
 	SmallBatchSize   int     `mapstructure:"small_batch_size"`
 	MediumBatchSize  int     `mapstructure:"medium_batch_size"`
 	LargeBatchSizes  []int   `mapstructure:"large_batch_sizes"`
+	CompressionLevel int     `mapstructure:"compression_level"` // Уровень сжатия zstd генерируемых файлов 1-9 (0 - без сжатия)
+	TagsFile         string  `mapstructure:"tags_file"`         // CSV или JSON определений тегов из историка (пусто - диапазоны и распределение типов)
+
+	// ValueModels модели значений показателей стандартной записи (тренд, периодичность,
+	// скачки уровня и шум); показатели без модели получают случайные значения
+	ValueModels []ValueModel `mapstructure:"value_models"`
+
+	// Schema пользовательская схема записи; если задана, заменяет стандартную запись из 5 полей
+	Schema []SchemaField `mapstructure:"schema"`
+
+	// Binary двоичная запись фиксированной ширины; если заданы поля, заменяет JSON запись
+	Binary BinaryConfig `mapstructure:"binary"`
+
+	// Retention политика хранения сгенерированных наборов (изменяется без перезапуска)
+	Retention RetentionConfig `mapstructure:"retention"`
+}
+
+// RetentionConfig политика хранения сгенерированных наборов данных: файлы наборов sets
+// старше max_age и самые старые файлы сверх max_total_mb удаляются фоновой проверкой
+type RetentionConfig struct {
+	Sets       []string      `mapstructure:"sets"`         // Наборы, к которым применяется политика (small, medium, large)
+	MaxAge     time.Duration `mapstructure:"max_age"`      // Предельный возраст файла (0 - без ограничения)
+	MaxTotalMB int           `mapstructure:"max_total_mb"` // Предельный суммарный размер файлов наборов sets, MB (0 - без ограничения)
+	Interval   time.Duration `mapstructure:"interval"`     // Период проверки
+}
+
+// Уровни сжатия zstd data.compression_level (шкала утилиты zstd)
+const (
+	DefaultCompressionLevel = 3
+	MaxCompressionLevel     = 9
+)
+
+// Модели значений показателей
+const (
+	ValueModelRandomWalk = "random_walk"
+	ValueModelSine       = "sine"
+	ValueModelStep       = "step"
+)
+
+// ValueModel модель значений группы показателей: вместо равномерно случайных чисел
+// каждый ряд (показатель оборудования) получает правдоподобную последовательность значений
+type ValueModel struct {
+	IndicatorIDRange []int   `mapstructure:"indicator_id_range"` // Показатели [от, до], к которым применяется модель
+	Model            string  `mapstructure:"model"`              // random_walk, sine или step
+	Base             float64 `mapstructure:"base"`               // Начальное значение (для sine - среднее)
+	Drift            float64 `mapstructure:"drift"`              // Тренд: изменение за одно значение ряда
+	Noise            float64 `mapstructure:"noise"`              // Стандартное отклонение нормального шума
+	Step             float64 `mapstructure:"step"`               // random_walk - стандартное отклонение приращения, step - величина скачка уровня
+	StepProbability  float64 `mapstructure:"step_probability"`   // Вероятность скачка уровня на очередном значении (step)
+	Amplitude        float64 `mapstructure:"amplitude"`          // Амплитуда (sine)
+	Period           int     `mapstructure:"period"`             // Период в значениях ряда (sine)
+	Min              float64 `mapstructure:"min"`                // Ограничение значений снизу и сверху
+	Max              float64 `mapstructure:"max"`                // (min == max - без ограничения)
+}
+
+// Типы полей двоичной записи
+const (
+	BinaryTypeUint8      = binrec.TypeUint8
+	BinaryTypeUint16     = binrec.TypeUint16
+	BinaryTypeUint32     = binrec.TypeUint32
+	BinaryTypeInt16      = binrec.TypeInt16
+	BinaryTypeInt32      = binrec.TypeInt32
+	BinaryTypeFloat32    = binrec.TypeFloat32
+	BinaryTypeFloat64    = binrec.TypeFloat64
+	BinaryTypeBytes      = binrec.TypeBytes
+	BinaryTypeConst      = binrec.TypeConst
+	BinaryTypeTimestamp  = binrec.TypeTimestamp
+	BinaryTypeCP56Time2a = binrec.TypeCP56Time2a
+	BinaryTypeCRC16      = binrec.TypeCRC16
+)
+
+// BinaryConfig раскладка двоичной записи фиксированной ширины (кадры Modbus, IEC-104);
+// та же раскладка задается получателю в validation.binary для проверки записей
+type BinaryConfig = binrec.Layout
+
+// BinaryField описание поля двоичной записи
+type BinaryField = binrec.Field
+
+// Типы полей пользовательской схемы
+const (
+	FieldTypeInt       = "int"
+	FieldTypeFloat     = "float"
+	FieldTypeBool      = "bool"
+	FieldTypeString    = "string"
+	FieldTypeEnum      = "enum"
+	FieldTypeTimestamp = "timestamp"
+	FieldTypeNull      = "null"
+	FieldTypeObject    = "object"
+	FieldTypeArray     = "array"
+)
+
+// SchemaField описание поля пользовательской схемы записи
+type SchemaField struct {
+	Name      string        `mapstructure:"name"`      // Имя поля (для элементов массива не используется)
+	Type      string        `mapstructure:"type"`      // Тип поля (int, float, bool, string, enum, timestamp, null, object, array)
+	Rule      string        `mapstructure:"rule"`      // Правило генерации: int - random/sequence, string - alnum/alpha/digits/hex
+	Length    int           `mapstructure:"length"`    // Длина строки или количество элементов массива
+	Min       float64       `mapstructure:"min"`       // Минимальное значение для int/float
+	Max       float64       `mapstructure:"max"`       // Максимальное значение для int/float
+	Precision int           `mapstructure:"precision"` // Знаков после запятой для float
+	Values    []string      `mapstructure:"values"`    // Допустимые значения для enum
+	NullRate  float64       `mapstructure:"null_rate"` // Доля значений null, %
+	Fields    []SchemaField `mapstructure:"fields"`    // Вложенные поля для object
+	Items     *SchemaField  `mapstructure:"items"`     // Описание элементов для array
 }
 
 // HTTPConfig конфигурация HTTP сервера
 type HTTPConfig struct {
-	Host            string        `mapstructure:"host"`
-	Port            int           `mapstructure:"port"`
-	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
-	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	Host              string        `mapstructure:"host"` // Адрес прослушивания (например, адрес в сети управления)
+	Port              int           `mapstructure:"port"`
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"` // Таймаут чтения заголовков запроса
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"` // Время простоя соединения keep-alive до закрытия
+	ShutdownTimeout   time.Duration `mapstructure:"shutdown_timeout"`
+	CertFile          string        `mapstructure:"cert_file"` // Сертификат HTTPS (пусто - HTTP)
+	KeyFile           string        `mapstructure:"key_file"`  // Закрытый ключ сертификата HTTPS
 }
 
 // MetricsConfig конфигурация метрик
 type MetricsConfig struct {
 	Enabled bool   `mapstructure:"enabled"`
 	Path    string `mapstructure:"path"`
+	Debug   bool   `mapstructure:"debug"` // Обработчики профилирования /debug/pprof/
+}
+
+// HealthConfig пороги проверок /health; при нарушении порога сервис считается неработоспособным
+type HealthConfig struct {
+	MinFreeDiskPercent float64 `mapstructure:"min_free_disk_percent"` // Минимум свободного места в data.data_path и директории логов, % (0 - без проверки порога)
+	MaxStoreBacklog    int64   `mapstructure:"max_store_backlog"`     // Предел сообщений в хранилище сессии MQTT без завершения обмена (0 - без проверки порога)
+}
+
+// HealthProbeConfig конфигурация пробных сообщений проверки пути до recipient (/healthz):
+// sender периодически публикует пробу в отдельный топик и ждет подтверждения брокера,
+// recipient проверяет, что пробы поступают
+type HealthProbeConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`  // Публиковать пробные сообщения
+	Topic    string        `mapstructure:"topic"`    // Топик проб (отличается от mqtt.topic, recipient подписывается на него же)
+	QoS      byte          `mapstructure:"qos"`      // QoS проб
+	Interval time.Duration `mapstructure:"interval"` // Период публикации; подтверждение брокера ожидается не дольше периода
+	MaxAge   time.Duration `mapstructure:"max_age"`  // Срок, после которого без подтвержденной пробы /healthz неработоспособен
 }
 
 // TestsConfig конфигурация тестов
@@ -104,10 +404,130 @@ type TestsConfig struct {
 	LargeSizes      []int         `mapstructure:"large_sizes"`
 	DefaultDuration time.Duration `mapstructure:"default_duration"`
 	MaxTestDuration time.Duration `mapstructure:"max_test_duration"`
+
+	RecipientURL         string        `mapstructure:"recipient_url"`         // Адрес HTTP API recipient для канала оркестрации
+	RecipientTimeout     time.Duration `mapstructure:"recipient_timeout"`     // Таймаут запросов к recipient
+	RecipientCAFile      string        `mapstructure:"recipient_ca_file"`     // Сертификаты удостоверяющих центров для HTTPS API recipient (пусто - системные)
+	CaptureDirectory     string        `mapstructure:"capture_directory"`     // Директория файлов записи трафика
+	CorrelationDirectory string        `mapstructure:"correlation_directory"` // Директория журналов корреляции отправленных сообщений (пусто - не ведутся)
+	FilesDirectory       string        `mapstructure:"files_directory"`       // Директория файлов для теста передачи файлов
+	TemplatesDirectory   string        `mapstructure:"templates_directory"`   // Директория шаблонов тестов (/templates)
+
+	MaxConcurrent   int `mapstructure:"max_concurrent"`    // Количество одновременно выполняющихся тестов
+	MaxTotalThreads int `mapstructure:"max_total_threads"` // Суммарное количество потоков одновременных тестов (0 - без ограничения)
+	MaxTotalRate    int `mapstructure:"max_total_rate"`    // Суммарная скорость одновременных тестов, сообщений/сек (0 - без ограничения)
+
+	TimeFormat string `mapstructure:"time_format"` // Формат send_time: rfc3339nano, epoch_micros, epoch_nanos, monotonic
+
+	Abort  AbortConfig  `mapstructure:"abort"`  // Прерывание теста по порогу ошибок отправки
+	Memory MemoryConfig `mapstructure:"memory"` // Ограничение памяти тестов с большими пакетами
 }
 
-// Load загружает конфигурацию из файла и переменных окружения
+// AbortConfig пороги досрочного прерывания теста по ошибкам отправки (0 - порог не проверяется)
+type AbortConfig struct {
+	ErrorRate         float64 `mapstructure:"error_rate"`         // Доля ошибок отправки, %
+	MinAttempts       int     `mapstructure:"min_attempts"`       // Попыток отправки до проверки доли ошибок
+	ConsecutiveErrors int     `mapstructure:"consecutive_errors"` // Ошибок отправки подряд
+}
+
+// Режимы тестов с большими пакетами при превышении ограничения памяти
+const (
+	MemoryPolicyStream = "stream" // Потоковый режим: записи читаются из файла без загрузки, отправки ограничиваются
+	MemoryPolicyRefuse = "refuse" // Тест не запускается
+)
+
+// MemoryConfig ограничение памяти тестов с большими пакетами: перед запуском оценивается
+// память набора данных и одновременных отправок всех потоков
+type MemoryConfig struct {
+	BudgetMB int    `mapstructure:"budget_mb"` // Ограничение, MB (0 - GOMEMLIMIT, если задан, иначе не проверяется)
+	Policy   string `mapstructure:"policy"`    // Действие при превышении: stream или refuse
+
+	MmapThresholdMB int `mapstructure:"mmap_threshold_mb"` // Файлы наборов без сжатия от этого размера отображаются в память вместо загрузки, MB (0 - не отображать)
+}
+
+// ClusterConfig согласованные тесты нескольких sender: ведущий sender делит нагрузку
+// между зарегистрированными у него sender, запускает их одновременно и объединяет статистику
+type ClusterConfig struct {
+	Lead         bool   `mapstructure:"lead"`          // Ведущий: принимать регистрацию sender и запускать согласованные тесты
+	LeadURL      string `mapstructure:"lead_url"`      // Адрес HTTP API ведущего для регистрации (пусто - не регистрироваться)
+	AdvertiseURL string `mapstructure:"advertise_url"` // Адрес HTTP API этого sender, доступный ведущему
+	NodeID       string `mapstructure:"node_id"`       // Имя sender в кластере (пусто - имя хоста)
+	CAFile       string `mapstructure:"ca_file"`       // Сертификаты удостоверяющих центров для HTTPS API других sender (пусто - системные)
+
+	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"` // Период подтверждения регистрации; sender без подтверждения 3 периода исключается
+	Timeout           time.Duration `mapstructure:"timeout"`            // Таймаут запросов между sender
+	StartDelay        time.Duration `mapstructure:"start_delay"`        // Задержка одновременного запуска по умолчанию
+}
+
+// AuditConfig конфигурация канала аудита: прием сводок recipient о принятых сообщениях
+// по обратному каналу стенда для вычисления потерь во время теста
+type AuditConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`   // Принимать сводки
+	Broker   string `mapstructure:"broker"`    // Брокер обратного канала (пусто - первый брокер mqtt)
+	ClientID string `mapstructure:"client_id"` // Идентификатор клиента (пусто - mqtt.client_id с суффиксом -audit)
+	Username string `mapstructure:"username"`  // Имя пользователя (пусто - mqtt.username)
+	Password string `mapstructure:"password"`  // Пароль (пусто - mqtt.password)
+	Topic    string `mapstructure:"topic"`     // Топик сводок
+	QoS      byte   `mapstructure:"qos"`       // QoS подписки
+}
+
+// applyMQTTDefaults заполняет незаданные параметры подключения канала аудита из раздела mqtt
+func (c *AuditConfig) applyMQTTDefaults(mqtt *MQTTConfig) {
+	if c.Broker == "" {
+		if brokers := mqtt.BrokerList(); len(brokers) > 0 {
+			c.Broker = brokers[0]
+		}
+	}
+	if c.ClientID == "" {
+		c.ClientID = mqtt.ClientID + "-audit"
+	}
+	if c.Username == "" {
+		c.Username = mqtt.Username
+	}
+	if c.Password == "" {
+		c.Password = mqtt.Password
+	}
+}
+
+// ActionLogConfig журнал действий API (audit trail): запуск и остановка тестов,
+// изменение шаблонов и данных, перечитывание конфигурации
+type ActionLogConfig struct {
+	File string `mapstructure:"file"` // Файл журнала, только дописывается (пусто - не ведется)
+}
+
+// WebhookConfig получатель уведомлений о событиях тестов
+type WebhookConfig struct {
+	URL        string            `mapstructure:"url"`         // Адрес, на который отправляется POST с уведомлением
+	Headers    map[string]string `mapstructure:"headers"`     // Дополнительные заголовки запроса (например, Authorization)
+	Events     []string          `mapstructure:"events"`      // События, о которых уведомляется получатель (пусто - все)
+	Timeout    time.Duration     `mapstructure:"timeout"`     // Таймаут запроса (0 - 5s)
+	Retries    int               `mapstructure:"retries"`     // Повторов после неудачного запроса
+	RetryDelay time.Duration     `mapstructure:"retry_delay"` // Пауза перед первым повтором, удваивается (0 - 1s)
+}
+
+// applyWebhookDefaults заполняет незаданные таймауты получателей уведомлений;
+// значения по умолчанию элементов списка не задаются через viper
+func applyWebhookDefaults(webhooks []WebhookConfig) {
+	for i := range webhooks {
+		if webhooks[i].Timeout == 0 {
+			webhooks[i].Timeout = 5 * time.Second
+		}
+		if webhooks[i].RetryDelay == 0 {
+			webhooks[i].RetryDelay = time.Second
+		}
+	}
+}
+
+// Load загружает конфигурацию из файла (с подключаемыми файлами include и подстановкой
+// ${ПЕРЕМЕННАЯ}) и переменных окружения
 func Load(configPath string) (*Config, error) {
+	config, _, err := load(configPath)
+	return config, err
+}
+
+// load читает конфигурацию и возвращает также прочитанные файлы (основной и подключаемые),
+// в том числе при ошибке, чтобы Watch наблюдал за ними
+func load(configPath string) (*Config, []string, error) {
 	v := viper.New()
 
 	// Устанавливаем значения по умолчанию
@@ -117,30 +537,34 @@ func Load(configPath string) (*Config, error) {
 	v.SetEnvPrefix("SENDER")
 	v.AutomaticEnv()
 
-	// Если указан путь к конфигурации, читаем файл
+	// Если указан путь к конфигурации, читаем файл и подключаемые им файлы
+	var files []string
 	if configPath != "" {
-		v.SetConfigFile(configPath)
-		if err := v.ReadInConfig(); err != nil {
-			return nil, fmt.Errorf("ошибка чтения конфигурации: %w", err)
+		var err error
+		files, err = configwatch.ReadFiles(v, configPath)
+		if err != nil {
+			return nil, files, fmt.Errorf("ошибка чтения конфигурации: %w", err)
 		}
 	}
 
 	var config Config
 	if err := v.Unmarshal(&config); err != nil {
-		return nil, fmt.Errorf("ошибка парсинга конфигурации: %w", err)
+		return nil, files, fmt.Errorf("ошибка парсинга конфигурации: %w", err)
 	}
+	config.Audit.applyMQTTDefaults(&config.MQTT)
+	applyWebhookDefaults(config.Webhooks)
 
 	// Валидация конфигурации
 	if err := validate(&config); err != nil {
-		return nil, fmt.Errorf("ошибка валидации конфигурации: %w", err)
+		return nil, files, fmt.Errorf("ошибка валидации конфигурации: %w", err)
 	}
 
 	// Создаем директории если не существуют
 	if err := ensureDirectories(&config); err != nil {
-		return nil, fmt.Errorf("ошибка создания директорий: %w", err)
+		return nil, files, fmt.Errorf("ошибка создания директорий: %w", err)
 	}
 
-	return &config, nil
+	return &config, files, nil
 }
 
 // setDefaults устанавливает значения по умолчанию
@@ -151,6 +575,8 @@ func setDefaults(v *viper.Viper) {
 
 	// MQTT
 	v.SetDefault("mqtt.broker", "tcp://localhost:1883")
+	v.SetDefault("mqtt.brokers", []string{})
+	v.SetDefault("mqtt.broker_strategy", BrokerStrategyFailover)
 	v.SetDefault("mqtt.client_id", "sender-001")
 	v.SetDefault("mqtt.username", "")
 	v.SetDefault("mqtt.password", "")
@@ -158,6 +584,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("mqtt.qos", 1) // At least once delivery
 	v.SetDefault("mqtt.retained", false)
 	v.SetDefault("mqtt.clean_session", false)
+	v.SetDefault("mqtt.tenant_topics", false)
 	v.SetDefault("mqtt.keep_alive", "60s")
 	v.SetDefault("mqtt.connect_timeout", "30s")
 	v.SetDefault("mqtt.max_reconnect_interval", "10m")
@@ -165,6 +592,64 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("mqtt.order_matters", true)
 	v.SetDefault("mqtt.store_directory", "/tmp/mqtt-sender-store")
 	v.SetDefault("mqtt.max_buffered_messages", 10000)
+	v.SetDefault("mqtt.queue_directory", "")
+	v.SetDefault("mqtt.stats_file", "")
+	v.SetDefault("mqtt.stats_save_interval", "1m")
+	v.SetDefault("mqtt.will_topic", "")
+	v.SetDefault("mqtt.will_payload", "offline")
+	v.SetDefault("mqtt.will_qos", 1)
+	v.SetDefault("mqtt.will_retained", false)
+	v.SetDefault("mqtt.aggregate_batches", false)
+
+	// TCP
+	v.SetDefault("tcp.max_reconnect_interval", "1m")
+	v.SetDefault("tcp.keep_alive", true)
+	v.SetDefault("tcp.keep_alive_period", "30s")
+	v.SetDefault("tcp.framing", tcp.FramingAuto)
+	v.SetDefault("tcp.ping_interval", "10s")
+	v.SetDefault("tcp.ping_timeout", "30s")
+	v.SetDefault("tcp.frame_checksum", true)
+	v.SetDefault("tcp.read_buffer_size", tcpframe.DefaultReadBufferSize)
+	v.SetDefault("tcp.recv_buffer", 0)
+	v.SetDefault("tcp.send_buffer", 0)
+	v.SetDefault("tcp.no_delay", true)
+	v.SetDefault("tcp.linger", -1)
+
+	// QUIC
+	v.SetDefault("quic.enabled", false)
+	v.SetDefault("quic.address", "localhost:9443")
+	v.SetDefault("quic.server_name", "")
+	v.SetDefault("quic.ca_file", "")
+	v.SetDefault("quic.insecure_skip_verify", false)
+	v.SetDefault("quic.streams", 4)
+	v.SetDefault("quic.zero_rtt", true)
+	v.SetDefault("quic.timeout", "10s")
+	v.SetDefault("quic.reconnect_interval", "5s")
+	v.SetDefault("quic.max_retries", 3)
+	v.SetDefault("quic.keep_alive_period", "10s")
+	v.SetDefault("quic.idle_timeout", "30s")
+
+	// NATS
+	v.SetDefault("nats.enabled", false)
+	v.SetDefault("nats.url", "nats://localhost:4222")
+	v.SetDefault("nats.username", "")
+	v.SetDefault("nats.password", "")
+	v.SetDefault("nats.token", "")
+	v.SetDefault("nats.stream", "INFODIODE")
+	v.SetDefault("nats.subject", "test.messages")
+	v.SetDefault("nats.connect_timeout", "10s")
+	v.SetDefault("nats.ack_timeout", "5s")
+	v.SetDefault("nats.reconnect_wait", "2s")
+
+	// Serial
+	v.SetDefault("serial.enabled", false)
+	v.SetDefault("serial.device", "/dev/ttyUSB0")
+	v.SetDefault("serial.baud_rate", 115200)
+	v.SetDefault("serial.data_bits", 8)
+	v.SetDefault("serial.parity", serialport.ParityNone)
+	v.SetDefault("serial.stop_bits", 1)
+	v.SetDefault("serial.rs485", false)
+	v.SetDefault("serial.timeout", "10s")
 
 	// Logger
 	v.SetDefault("logger.level", "info")
@@ -174,6 +659,14 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("logger.max_age", 30)
 	v.SetDefault("logger.compress", true)
 	v.SetDefault("logger.console", true)
+	v.SetDefault("logger.format", logging.FormatJSON)
+	v.SetDefault("logger.console_format", logging.FormatConsole)
+	v.SetDefault("logger.syslog.enabled", false)
+	v.SetDefault("logger.syslog.network", "udp")
+	v.SetDefault("logger.syslog.address", "localhost:514")
+	v.SetDefault("logger.syslog.facility", "local0")
+	v.SetDefault("logger.syslog.app_name", "sender")
+	v.SetDefault("logger.syslog.format", logging.FormatLogfmt)
 
 	// Data
 	v.SetDefault("data.data_path", "data")
@@ -187,17 +680,33 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("data.small_batch_size", 1000)
 	v.SetDefault("data.medium_batch_size", 10000)
 	v.SetDefault("data.large_batch_sizes", []int{5, 10, 50, 100})
+	v.SetDefault("data.compression_level", 0)
+	v.SetDefault("data.tags_file", "")
+	v.SetDefault("data.binary.byte_order", "big")
+	v.SetDefault("data.retention.sets", []string{"large"})
+	v.SetDefault("data.retention.max_age", 0)
+	v.SetDefault("data.retention.max_total_mb", 0)
+	v.SetDefault("data.retention.interval", 10*time.Minute)
 
 	// HTTP
 	v.SetDefault("http.host", "0.0.0.0")
 	v.SetDefault("http.port", 8080)
 	v.SetDefault("http.read_timeout", "30s")
+	v.SetDefault("http.read_header_timeout", "10s")
 	v.SetDefault("http.write_timeout", "30s")
+	v.SetDefault("http.idle_timeout", "120s")
 	v.SetDefault("http.shutdown_timeout", "10s")
+	v.SetDefault("http.cert_file", "")
+	v.SetDefault("http.key_file", "")
 
 	// Metrics
 	v.SetDefault("metrics.enabled", true)
 	v.SetDefault("metrics.path", "/metrics")
+	v.SetDefault("metrics.debug", false)
+
+	// Health
+	v.SetDefault("health.min_free_disk_percent", 5.0)
+	v.SetDefault("health.max_store_backlog", 10000)
 
 	// Tests
 	v.SetDefault("tests.batch_threads", []int{25, 50, 100})
@@ -205,14 +714,65 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("tests.large_sizes", []int{5, 10, 50, 100})
 	v.SetDefault("tests.default_duration", "60s")
 	v.SetDefault("tests.max_test_duration", "3600s")
+	v.SetDefault("tests.recipient_url", "")
+	v.SetDefault("tests.recipient_timeout", "5s")
+	v.SetDefault("tests.recipient_ca_file", "")
+	v.SetDefault("tests.capture_directory", "captures")
+	v.SetDefault("tests.correlation_directory", "")
+	v.SetDefault("tests.files_directory", "files")
+	v.SetDefault("tests.templates_directory", "templates")
+	v.SetDefault("tests.max_concurrent", 1)
+	v.SetDefault("tests.max_total_threads", 0)
+	v.SetDefault("tests.max_total_rate", 0)
+	v.SetDefault("tests.time_format", utils.TimeFormatRFC3339Nano)
+	v.SetDefault("tests.abort.error_rate", 0)
+	v.SetDefault("tests.abort.min_attempts", 100)
+	v.SetDefault("tests.abort.consecutive_errors", 0)
+	v.SetDefault("tests.memory.budget_mb", 0)
+	v.SetDefault("tests.memory.policy", MemoryPolicyStream)
+	v.SetDefault("tests.memory.mmap_threshold_mb", 64)
+
+	// Cluster
+	v.SetDefault("cluster.lead", false)
+	v.SetDefault("cluster.lead_url", "")
+	v.SetDefault("cluster.advertise_url", "")
+	v.SetDefault("cluster.node_id", "")
+	v.SetDefault("cluster.ca_file", "")
+	v.SetDefault("cluster.heartbeat_interval", "10s")
+	v.SetDefault("cluster.timeout", "10s")
+	v.SetDefault("cluster.start_delay", "3s")
+
+	// Audit
+	v.SetDefault("audit.enabled", false)
+	v.SetDefault("audit.broker", "")
+	v.SetDefault("audit.client_id", "")
+	v.SetDefault("audit.username", "")
+	v.SetDefault("audit.password", "")
+	v.SetDefault("audit.topic", "test/audit")
+	v.SetDefault("audit.qos", 0)
+
+	// Health probe
+	v.SetDefault("health_probe.enabled", false)
+	v.SetDefault("health_probe.topic", "test/health")
+	v.SetDefault("health_probe.qos", 1)
+	v.SetDefault("health_probe.interval", "5s")
+	v.SetDefault("health_probe.max_age", "30s")
+
+	v.SetDefault("action_log.file", "")
 }
 
 // validate проверяет корректность конфигурации
 func validate(cfg *Config) error {
-	if cfg.MQTT.Broker == "" {
+	if len(cfg.MQTT.BrokerList()) == 0 {
 		return fmt.Errorf("не указан адрес MQTT брокера")
 	}
 
+	switch cfg.MQTT.BrokerStrategy {
+	case BrokerStrategyFailover, BrokerStrategyRoundRobin:
+	default:
+		return fmt.Errorf("некорректная стратегия выбора брокера: %s", cfg.MQTT.BrokerStrategy)
+	}
+
 	if cfg.MQTT.ClientID == "" {
 		return fmt.Errorf("не указан client_id для MQTT")
 	}
@@ -225,9 +785,90 @@ func validate(cfg *Config) error {
 		return fmt.Errorf("некорректный уровень QoS: %d (должен быть 0, 1 или 2)", cfg.MQTT.QoS)
 	}
 
+	if cfg.MQTT.MaxBufferedMsgs < 0 {
+		return fmt.Errorf("некорректное значение mqtt.max_buffered_messages: %d", cfg.MQTT.MaxBufferedMsgs)
+	}
+
+	if cfg.MQTT.StatsSaveInterval < 0 {
+		return fmt.Errorf("некорректное значение mqtt.stats_save_interval: %s", cfg.MQTT.StatsSaveInterval)
+	}
+
+	if cfg.MQTT.WillTopic != "" {
+		if cfg.MQTT.WillTopic == cfg.MQTT.Topic {
+			return fmt.Errorf("mqtt.will_topic должен отличаться от mqtt.topic")
+		}
+		if cfg.MQTT.WillQoS > 2 {
+			return fmt.Errorf("некорректный уровень QoS last will: %d (должен быть 0, 1 или 2)", cfg.MQTT.WillQoS)
+		}
+	}
+
+	if cfg.TCP.Framing != tcp.FramingAuto && cfg.TCP.Framing != tcp.FramingLegacy {
+		return fmt.Errorf("некорректный режим tcp.framing: %s (допустимо %s, %s)", cfg.TCP.Framing, tcp.FramingAuto, tcp.FramingLegacy)
+	}
+	if cfg.TCP.ReconnectInt < 0 || cfg.TCP.MaxReconnectInt < 0 || cfg.TCP.MaxRetries < 0 {
+		return fmt.Errorf("tcp.reconnect_interval, tcp.max_reconnect_interval и tcp.max_retries не могут быть отрицательными")
+	}
+	if cfg.TCP.MaxReconnectInt > 0 && cfg.TCP.MaxReconnectInt < cfg.TCP.ReconnectInt {
+		return fmt.Errorf("tcp.max_reconnect_interval (%s) меньше tcp.reconnect_interval (%s)", cfg.TCP.MaxReconnectInt, cfg.TCP.ReconnectInt)
+	}
+	if cfg.TCP.PingInterval < 0 || cfg.TCP.PingTimeout < 0 {
+		return fmt.Errorf("tcp.ping_interval и tcp.ping_timeout не могут быть отрицательными")
+	}
+	if cfg.TCP.PingInterval > 0 && cfg.TCP.PingTimeout > 0 && cfg.TCP.PingTimeout <= cfg.TCP.PingInterval {
+		return fmt.Errorf("tcp.ping_timeout должен быть больше tcp.ping_interval")
+	}
+	if cfg.TCP.ReadBufferSize < 0 || cfg.TCP.RecvBuffer < 0 || cfg.TCP.SendBuffer < 0 {
+		return fmt.Errorf("tcp.read_buffer_size, tcp.recv_buffer и tcp.send_buffer не могут быть отрицательными")
+	}
+	if cfg.TCP.Linger < -1 {
+		return fmt.Errorf("некорректное значение tcp.linger: %d (-1 - по умолчанию ОС, 0 и больше - секунд)", cfg.TCP.Linger)
+	}
+
+	if cfg.QUIC.Enabled {
+		if cfg.QUIC.Address == "" {
+			return fmt.Errorf("не указан адрес QUIC сервера")
+		}
+		if cfg.QUIC.Streams < 1 || cfg.QUIC.Streams > 1000 {
+			return fmt.Errorf("некорректное значение quic.streams: %d (допустимо 1-1000)", cfg.QUIC.Streams)
+		}
+		if cfg.QUIC.Timeout <= 0 {
+			return fmt.Errorf("некорректное значение quic.timeout: %s", cfg.QUIC.Timeout)
+		}
+		if cfg.QUIC.CAFile != "" && cfg.QUIC.InsecureSkip {
+			return fmt.Errorf("quic.ca_file и quic.insecure_skip_verify не задаются вместе")
+		}
+		if cfg.QUIC.KeepAlivePeriod < 0 || cfg.QUIC.IdleTimeout < 0 {
+			return fmt.Errorf("quic.keep_alive_period и quic.idle_timeout не могут быть отрицательными")
+		}
+		if cfg.QUIC.KeepAlivePeriod > 0 && cfg.QUIC.IdleTimeout > 0 && cfg.QUIC.KeepAlivePeriod >= cfg.QUIC.IdleTimeout {
+			return fmt.Errorf("quic.keep_alive_period должен быть меньше quic.idle_timeout")
+		}
+	}
+
+	if cfg.NATS.Enabled {
+		if cfg.NATS.URL == "" {
+			return fmt.Errorf("не указан адрес NATS сервера")
+		}
+		if cfg.NATS.Stream == "" || cfg.NATS.Subject == "" {
+			return fmt.Errorf("не указаны поток или субъект NATS")
+		}
+	}
+
+	if cfg.Serial.Enabled {
+		if err := cfg.Serial.PortConfig(cfg.Serial.Device).Validate(); err != nil {
+			return fmt.Errorf("serial: %w", err)
+		}
+	}
+
 	if cfg.HTTP.Port <= 0 || cfg.HTTP.Port > 65535 {
 		return fmt.Errorf("некорректный порт HTTP: %d", cfg.HTTP.Port)
 	}
+	if cfg.HTTP.ReadTimeout < 0 || cfg.HTTP.ReadHeaderTimeout < 0 || cfg.HTTP.WriteTimeout < 0 || cfg.HTTP.IdleTimeout < 0 {
+		return fmt.Errorf("таймауты HTTP не могут быть отрицательными")
+	}
+	if (cfg.HTTP.CertFile == "") != (cfg.HTTP.KeyFile == "") {
+		return fmt.Errorf("http.cert_file и http.key_file задаются вместе")
+	}
 
 	percentSum := cfg.Data.NullPercent + cfg.Data.BoolPercent +
 		cfg.Data.FloatPercent + cfg.Data.StringPercent
@@ -243,6 +884,307 @@ func validate(cfg *Config) error {
 		return fmt.Errorf("некорректный диапазон equipment_id")
 	}
 
+	if err := validateSchema(cfg.Data.Schema, "data.schema"); err != nil {
+		return err
+	}
+
+	if err := cfg.Data.Binary.Validate("data.binary"); err != nil {
+		return err
+	}
+	if len(cfg.Data.Binary.Fields) > 0 && len(cfg.Data.Schema) > 0 {
+		return fmt.Errorf("data.schema и data.binary не могут быть заданы одновременно")
+	}
+	if err := validateValueModels(cfg.Data.ValueModels); err != nil {
+		return err
+	}
+	if len(cfg.Data.ValueModels) > 0 && (len(cfg.Data.Schema) > 0 || len(cfg.Data.Binary.Fields) > 0) {
+		return fmt.Errorf("data.value_models не может быть задан вместе с data.schema или data.binary")
+	}
+	if cfg.Data.TagsFile != "" && (len(cfg.Data.Schema) > 0 || len(cfg.Data.Binary.Fields) > 0) {
+		return fmt.Errorf("data.tags_file не может быть задан вместе с data.schema или data.binary")
+	}
+
+	if cfg.Data.CompressionLevel < 0 || cfg.Data.CompressionLevel > MaxCompressionLevel {
+		return fmt.Errorf("некорректный уровень сжатия data.compression_level: %d (допустимо 0-%d)", cfg.Data.CompressionLevel, MaxCompressionLevel)
+	}
+	if err := validateRetention(&cfg.Data.Retention); err != nil {
+		return err
+	}
+
+	if cfg.Tests.MaxConcurrent < 1 {
+		return fmt.Errorf("tests.max_concurrent должен быть не меньше 1, получено: %d", cfg.Tests.MaxConcurrent)
+	}
+	if cfg.Tests.MaxTotalThreads < 0 || cfg.Tests.MaxTotalRate < 0 {
+		return fmt.Errorf("tests.max_total_threads и tests.max_total_rate не могут быть отрицательными")
+	}
+	if err := utils.ValidateTimeFormat(cfg.Tests.TimeFormat); err != nil {
+		return fmt.Errorf("некорректный tests.time_format: %w", err)
+	}
+	if cfg.Tests.Abort.ErrorRate < 0 || cfg.Tests.Abort.ErrorRate > 100 {
+		return fmt.Errorf("tests.abort.error_rate должен быть в диапазоне 0-100, получено: %g", cfg.Tests.Abort.ErrorRate)
+	}
+	if cfg.Tests.Abort.MinAttempts < 0 || cfg.Tests.Abort.ConsecutiveErrors < 0 {
+		return fmt.Errorf("tests.abort.min_attempts и tests.abort.consecutive_errors не могут быть отрицательными")
+	}
+	if cfg.Tests.Memory.BudgetMB < 0 {
+		return fmt.Errorf("tests.memory.budget_mb не может быть отрицательным, получено: %d", cfg.Tests.Memory.BudgetMB)
+	}
+	if cfg.Tests.Memory.Policy != MemoryPolicyStream && cfg.Tests.Memory.Policy != MemoryPolicyRefuse {
+		return fmt.Errorf("некорректный tests.memory.policy: %s (допустимо %s, %s)",
+			cfg.Tests.Memory.Policy, MemoryPolicyStream, MemoryPolicyRefuse)
+	}
+	if cfg.Tests.Memory.MmapThresholdMB < 0 {
+		return fmt.Errorf("tests.memory.mmap_threshold_mb не может быть отрицательным, получено: %d", cfg.Tests.Memory.MmapThresholdMB)
+	}
+
+	if err := validateCluster(&cfg.Cluster); err != nil {
+		return err
+	}
+
+	if cfg.Audit.Enabled {
+		if cfg.Audit.Broker == "" {
+			return fmt.Errorf("не указан брокер канала аудита")
+		}
+		if cfg.Audit.Topic == "" {
+			return fmt.Errorf("не указан топик канала аудита")
+		}
+		if cfg.Audit.Topic == cfg.MQTT.Topic || cfg.Audit.Topic == cfg.MQTT.WillTopic {
+			return fmt.Errorf("audit.topic должен отличаться от mqtt.topic и mqtt.will_topic")
+		}
+		if cfg.Audit.QoS > 2 {
+			return fmt.Errorf("некорректный уровень QoS канала аудита: %d (должен быть 0, 1 или 2)", cfg.Audit.QoS)
+		}
+	}
+
+	if cfg.HealthProbe.Enabled {
+		if cfg.HealthProbe.Topic == "" {
+			return fmt.Errorf("не указан топик пробных сообщений")
+		}
+		if cfg.HealthProbe.Topic == cfg.MQTT.Topic || cfg.HealthProbe.Topic == cfg.MQTT.WillTopic || cfg.HealthProbe.Topic == cfg.Audit.Topic {
+			return fmt.Errorf("health_probe.topic должен отличаться от mqtt.topic, mqtt.will_topic и audit.topic")
+		}
+		if cfg.HealthProbe.QoS > 2 {
+			return fmt.Errorf("некорректный уровень QoS пробных сообщений: %d (должен быть 0, 1 или 2)", cfg.HealthProbe.QoS)
+		}
+		if cfg.HealthProbe.Interval <= 0 {
+			return fmt.Errorf("некорректное значение health_probe.interval: %s", cfg.HealthProbe.Interval)
+		}
+		if cfg.HealthProbe.MaxAge < cfg.HealthProbe.Interval {
+			return fmt.Errorf("health_probe.max_age должен быть не меньше health_probe.interval")
+		}
+	}
+
+	for i, hook := range cfg.Webhooks {
+		if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhooks[%d]: некорректный адрес %q", i, hook.URL)
+		}
+		for _, event := range hook.Events {
+			if !slices.Contains(webhook.Events, event) {
+				return fmt.Errorf("webhooks[%d]: неизвестное событие %q (допустимы %v)", i, event, webhook.Events)
+			}
+		}
+		if hook.Timeout < 0 || hook.RetryDelay < 0 {
+			return fmt.Errorf("webhooks[%d]: таймаут и пауза повтора не могут быть отрицательными", i)
+		}
+		if hook.Retries < 0 {
+			return fmt.Errorf("webhooks[%d]: некорректное количество повторов: %d", i, hook.Retries)
+		}
+	}
+
+	if cfg.Health.MinFreeDiskPercent < 0 || cfg.Health.MinFreeDiskPercent >= 100 {
+		return fmt.Errorf("health.min_free_disk_percent должен быть от 0 до 100, получено: %.2f", cfg.Health.MinFreeDiskPercent)
+	}
+	if cfg.Health.MaxStoreBacklog < 0 {
+		return fmt.Errorf("health.max_store_backlog не может быть отрицательным, получено: %d", cfg.Health.MaxStoreBacklog)
+	}
+
+	if err := validateLogger(&cfg.Logger); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateCluster проверяет адреса и периоды согласованных тестов нескольких sender
+func validateCluster(cfg *ClusterConfig) error {
+	if cfg.LeadURL == "" && !cfg.Lead {
+		return nil
+	}
+	if cfg.HeartbeatInterval <= 0 || cfg.Timeout <= 0 {
+		return fmt.Errorf("cluster.heartbeat_interval и cluster.timeout должны быть положительными")
+	}
+	if cfg.StartDelay < 0 {
+		return fmt.Errorf("cluster.start_delay не может быть отрицательным")
+	}
+	if cfg.LeadURL == "" {
+		return nil
+	}
+
+	for _, address := range []struct{ key, value string }{
+		{"cluster.lead_url", cfg.LeadURL},
+		{"cluster.advertise_url", cfg.AdvertiseURL},
+	} {
+		if u, err := url.Parse(address.value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("некорректный адрес %s: %q", address.key, address.value)
+		}
+	}
+	if err := models.ValidateLabel("cluster.node_id", cfg.NodeID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateLogger проверяет форматы и уровни выводов логов
+func validateLogger(cfg *LoggerConfig) error {
+	if _, err := logging.ParseLevel(cfg.Level); err != nil {
+		return fmt.Errorf("некорректное значение logger.level: %w", err)
+	}
+	if err := logging.ValidateFormat(cfg.Format); err != nil {
+		return fmt.Errorf("некорректное значение logger.format: %w", err)
+	}
+	if err := logging.ValidateFormat(cfg.ConsoleFormat); err != nil {
+		return fmt.Errorf("некорректное значение logger.console_format: %w", err)
+	}
+
+	// Уровни отдельных выводов необязательны: пустой означает общий logger.level
+	levels := []struct{ key, level string }{
+		{"logger.file_level", cfg.FileLevel},
+		{"logger.console_level", cfg.ConsoleLevel},
+		{"logger.syslog.level", cfg.Syslog.Level},
+	}
+	for _, sink := range levels {
+		if sink.level == "" {
+			continue
+		}
+		if _, err := logging.ParseLevel(sink.level); err != nil {
+			return fmt.Errorf("некорректное значение %s: %w", sink.key, err)
+		}
+	}
+
+	if cfg.Syslog.Enabled {
+		if err := logging.ValidateSyslog(cfg.Syslog.SinkConfig()); err != nil {
+			return fmt.Errorf("некорректная конфигурация logger.syslog: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateSchema рекурсивно проверяет описание полей пользовательской схемы
+func validateSchema(fields []SchemaField, path string) error {
+	names := make(map[string]bool, len(fields))
+	for i := range fields {
+		field := &fields[i]
+		if field.Name == "" {
+			return fmt.Errorf("%s[%d]: не указано имя поля", path, i)
+		}
+		if names[field.Name] {
+			return fmt.Errorf("%s: повторяющееся имя поля %s", path, field.Name)
+		}
+		names[field.Name] = true
+
+		if err := validateSchemaField(field, path+"."+field.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateRetention проверяет политику хранения наборов данных
+func validateRetention(cfg *RetentionConfig) error {
+	for _, set := range cfg.Sets {
+		switch set {
+		case "small", "medium", "large":
+		default:
+			return fmt.Errorf("неизвестный набор data.retention.sets: %s (допустимы small, medium, large)", set)
+		}
+	}
+	if cfg.MaxAge < 0 || cfg.MaxTotalMB < 0 {
+		return fmt.Errorf("data.retention.max_age и data.retention.max_total_mb не могут быть отрицательными")
+	}
+	if cfg.Interval <= 0 {
+		return fmt.Errorf("data.retention.interval должен быть положительным, получено: %s", cfg.Interval)
+	}
+	return nil
+}
+
+// validateValueModels проверяет модели значений показателей
+func validateValueModels(models []ValueModel) error {
+	for i := range models {
+		m := &models[i]
+		path := fmt.Sprintf("data.value_models[%d]", i)
+
+		if len(m.IndicatorIDRange) != 2 || m.IndicatorIDRange[0] > m.IndicatorIDRange[1] {
+			return fmt.Errorf("%s: некорректный indicator_id_range", path)
+		}
+		if m.Noise < 0 || m.Step < 0 || m.Amplitude < 0 {
+			return fmt.Errorf("%s: noise, step и amplitude не могут быть отрицательными", path)
+		}
+		if m.Min > m.Max {
+			return fmt.Errorf("%s: min больше max", path)
+		}
+
+		switch m.Model {
+		case ValueModelRandomWalk:
+		case ValueModelSine:
+			if m.Period < 2 {
+				return fmt.Errorf("%s: для sine необходимо указать period не меньше 2", path)
+			}
+		case ValueModelStep:
+			if m.StepProbability <= 0 || m.StepProbability > 1 {
+				return fmt.Errorf("%s: step_probability должен быть в диапазоне (0, 1]", path)
+			}
+		default:
+			return fmt.Errorf("%s: неизвестная модель %q (допустимы random_walk, sine, step)", path, m.Model)
+		}
+	}
+	return nil
+}
+
+// validateSchemaField проверяет описание одного поля схемы
+func validateSchemaField(field *SchemaField, path string) error {
+	if field.NullRate < 0 || field.NullRate > 100 {
+		return fmt.Errorf("%s: null_rate должен быть в диапазоне [0, 100]", path)
+	}
+
+	switch field.Type {
+	case FieldTypeInt, FieldTypeFloat:
+		if field.Min > field.Max {
+			return fmt.Errorf("%s: min больше max", path)
+		}
+		if field.Type == FieldTypeInt && field.Rule != "" && field.Rule != "random" && field.Rule != "sequence" {
+			return fmt.Errorf("%s: неизвестное правило генерации %s", path, field.Rule)
+		}
+	case FieldTypeString:
+		switch field.Rule {
+		case "", "alnum", "alpha", "digits", "hex":
+		default:
+			return fmt.Errorf("%s: неизвестное правило генерации %s", path, field.Rule)
+		}
+		if field.Length <= 0 {
+			return fmt.Errorf("%s: для строки необходимо указать length", path)
+		}
+	case FieldTypeEnum:
+		if len(field.Values) == 0 {
+			return fmt.Errorf("%s: для enum необходимо указать values", path)
+		}
+	case FieldTypeBool, FieldTypeTimestamp, FieldTypeNull:
+	case FieldTypeObject:
+		if len(field.Fields) == 0 {
+			return fmt.Errorf("%s: для object необходимо указать fields", path)
+		}
+		return validateSchema(field.Fields, path)
+	case FieldTypeArray:
+		if field.Items == nil {
+			return fmt.Errorf("%s: для array необходимо указать items", path)
+		}
+		if field.Length < 0 {
+			return fmt.Errorf("%s: некорректная длина массива %d", path, field.Length)
+		}
+		return validateSchemaField(field.Items, path+"[]")
+	default:
+		return fmt.Errorf("%s: неизвестный тип поля %s", path, field.Type)
+	}
+
 	return nil
 }
 
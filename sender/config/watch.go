@@ -0,0 +1,16 @@
+package config
+
+import (
+	"context"
+
+	"github.com/infodiode/shared/configwatch"
+	"github.com/spf13/viper"
+)
+
+// Watch перечитывает конфигурацию при изменении файла или подключаемых им файлов
+// или получении SIGHUP и передает результат в onReload до отмены ctx. Ошибки чтения
+// и валидации передаются в onReload, текущая конфигурация при этом не меняется
+func Watch(ctx context.Context, configPath string, onReload func(*Config, error)) error {
+	files, _ := configwatch.ReadFiles(viper.New(), configPath)
+	return configwatch.Watch(ctx, configPath, files, load, onReload)
+}
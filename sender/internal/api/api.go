@@ -1,41 +1,191 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/infodiode/sender/internal/broker"
+	"github.com/infodiode/sender/internal/cluster"
 	"github.com/infodiode/sender/internal/generator"
+	"github.com/infodiode/sender/internal/orchestration"
+	"github.com/infodiode/sender/internal/report"
 	"github.com/infodiode/sender/internal/tcp"
+	"github.com/infodiode/sender/internal/templates"
 	"github.com/infodiode/sender/internal/test"
+	"github.com/infodiode/sender/internal/transport"
+	"github.com/infodiode/sender/internal/webhook"
+	"github.com/infodiode/shared/actionlog"
 	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
 	"go.uber.org/zap"
 )
 
 // API структура HTTP API сервера
 type API struct {
-	router       *gin.Engine
-	logger       *zap.Logger
-	producer     *broker.MQTTProducer
-	generator    *generator.DataGenerator
-	testManager  *test.Manager
-	server       *http.Server
-	mu           sync.RWMutex
-	currentTest  *models.TestConfig
-	isTestActive bool
+	router      *gin.Engine
+	logger      *zap.Logger
+	producer    *broker.MQTTProducer
+	transports  *transport.Registry
+	generator   *generator.DataGenerator
+	testManager *test.Manager
+	audit       *broker.AuditListener // Прием сводок канала аудита, nil - канал отключен
+	prober      *broker.HealthProber  // Пробные сообщения /healthz, nil - отключены
+	notifier    *webhook.Notifier     // Уведомления о событиях тестов, nil - отключены
+	templates   *templates.Store      // Шаблоны тестов
+	actions     *actionlog.Log        // Журнал действий API, nil - не ведется
+	cluster     *cluster.Coordinator  // Согласованные тесты нескольких sender, nil - sender не ведущий
+	agent       *cluster.Agent        // Регистрация у ведущего sender, nil - не выполняется
+	server      *http.Server
+	certFile    string // Сертификат и ключ HTTPS (пусто - HTTP)
+	keyFile     string
+	mu          sync.RWMutex
+	running     map[string]*models.TestConfig // Выполняющиеся тесты по идентификатору
+	limits      TestLimits
+
+	tenantTopics bool // Публиковать тесты с tenant в топик <mqtt.topic>/<tenant>
+
+	metricsEnabled atomic.Bool
+	healthPolicy   atomic.Pointer[HealthPolicy]
+	logDir         string // Директория файла логов для проверки свободного места (пусто - не проверяется)
+	captureDir     string
+	filesDir       string
+	version        models.VersionInfo
+	openapi        []byte // Описание API для /openapi.json
 }
 
+// captureNamePattern допустимое имя файла записи трафика (без пути)
+var captureNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+// Значения по умолчанию теста передачи файла
+const (
+	defaultFileChunkSize  = 64 * 1024 // байт
+	defaultFileTimeout    = 600       // секунд на передачу
+	defaultFileSettleTime = 30        // секунд ожидания проверки на recipient
+)
+
 // Config конфигурация API
 type Config struct {
-	Host            string
-	Port            int
-	ReadTimeout     time.Duration
-	WriteTimeout    time.Duration
-	ShutdownTimeout time.Duration
+	Host              string
+	Port              int
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	CertFile          string // Сертификат HTTPS (пусто - HTTP)
+	KeyFile           string // Закрытый ключ сертификата HTTPS
+	MetricsEnabled    bool   // Отдавать ли метрики (изменяется без перезапуска через SetMetricsEnabled)
+	Debug             bool   // Обработчики профилирования /debug/pprof/
+	CaptureDir        string // Директория файлов записи трафика
+	CorrelationDir    string // Директория журналов корреляции отправленных сообщений (пусто - не ведутся)
+	FilesDir          string // Директория файлов для теста передачи файлов
+	TestLimits        TestLimits
+	TenantTopics      bool                  // Публиковать тесты с tenant в топик <mqtt.topic>/<tenant>
+	AbortPolicy       test.AbortPolicy      // Пороги прерывания тестов (изменяются без перезапуска через SetAbortPolicy)
+	MemoryPolicy      test.MemoryPolicy     // Ограничение памяти тестов с большими пакетами (изменяется через SetMemoryPolicy)
+	TimeFormat        string                // Формат send_time сообщений (изменяется через SetTimeFormat)
+	Health            HealthPolicy          // Пороги проверок /health (изменяются через SetHealthPolicy)
+	LogDir            string                // Директория файла логов (пусто - логи только в консоль)
+	Version           models.VersionInfo    // Сведения о сборке и конфигурации для /version
+	Audit             *broker.AuditListener // Прием сводок канала аудита (nil - отключен)
+	HealthProber      *broker.HealthProber  // Пробные сообщения для /healthz (nil - отключены)
+	Notifier          *webhook.Notifier     // Уведомления о событиях тестов (nil - отключены)
+	Templates         *templates.Store      // Шаблоны тестов
+	ActionLog         *actionlog.Log        // Журнал действий API (nil - не ведется)
+	Cluster           *cluster.Coordinator  // Согласованные тесты нескольких sender (nil - sender не ведущий)
+	ClusterAgent      *cluster.Agent        // Регистрация у ведущего sender (nil - не выполняется)
+}
+
+// TestLimits ограничения одновременно выполняющихся тестов (изменяются без перезапуска через SetTestLimits)
+type TestLimits struct {
+	MaxConcurrent   int // Количество одновременных тестов
+	MaxTotalThreads int // Суммарное количество потоков тестов (0 - без ограничения)
+	MaxTotalRate    int // Суммарная заданная скорость тестов, сообщений/сек (0 - без ограничения)
+}
+
+// quotaRate возвращает скорость теста в счет tests.max_total_rate: тест без ограничения
+// скорости (messages_per_sec 0) занимает всю квоту
+func (l TestLimits) quotaRate(config *models.TestConfig) int {
+	if config.MessagesPerSec <= 0 {
+		return l.MaxTotalRate
+	}
+	return config.MessagesPerSec
+}
+
+// exclusiveTestTypes тесты, которые управляют соединением с брокером или измеряют предел
+// пропускной способности и поэтому выполняются только без других тестов
+var exclusiveTestTypes = map[models.TestType]bool{
+	models.TestTypeDiscovery:    true,
+	models.TestTypeSweep:        true,
+	models.TestTypeSession:      true,
+	models.TestTypeMQTTFeatures: true,
+	models.TestTypeRaw:          true,
+}
+
+// exclusiveTest проверяет, что тест выполняется только без других тестов: тест из
+// exclusiveTestTypes или тест с разрывами соединений (chaos), которые обрывают общее
+// соединение протокола и искажают результаты одновременных тестов
+func exclusiveTest(config *models.TestConfig) bool {
+	return exclusiveTestTypes[config.Type] || config.Chaos != nil
+}
+
+// exclusiveName возвращает название теста для сообщения об отказе в запуске
+func exclusiveName(config *models.TestConfig) string {
+	if !exclusiveTestTypes[config.Type] && config.Chaos != nil {
+		return string(config.Type) + " с разрывами соединений (chaos)"
+	}
+	return string(config.Type)
+}
+
+// templateTest тест, который можно сохранить в шаблоне: запрос запуска и его обработчик
+type templateTest struct {
+	request func() any
+	start   func(*API, *gin.Context)
+}
+
+// templateTests тесты, доступные в шаблонах, по типу теста
+var templateTests = map[models.TestType]templateTest{
+	models.TestTypeBatch:        {func() any { return &BatchTestRequest{} }, (*API).startBatchTest},
+	models.TestTypeStream:       {func() any { return &StreamTestRequest{} }, (*API).startStreamTest},
+	models.TestTypeLarge:        {func() any { return &LargeTestRequest{} }, (*API).startLargeTest},
+	models.TestTypeDiscovery:    {func() any { return &DiscoveryTestRequest{} }, (*API).startDiscoveryTest},
+	models.TestTypeSweep:        {func() any { return &SweepTestRequest{} }, (*API).startSweepTest},
+	models.TestTypeSession:      {func() any { return &SessionTestRequest{} }, (*API).startSessionTest},
+	models.TestTypeExactlyOnce:  {func() any { return &ExactlyOnceTestRequest{} }, (*API).startExactlyOnceTest},
+	models.TestTypeMQTTFeatures: {func() any { return &MQTTFeaturesTestRequest{} }, (*API).startMQTTFeaturesTest},
+	models.TestTypeMixed:        {func() any { return &MixedTestRequest{} }, (*API).startMixedTest},
+	models.TestTypeFanout:       {func() any { return &FanoutTestRequest{} }, (*API).startFanoutTest},
+	models.TestTypeReplay:       {func() any { return &ReplayTestRequest{} }, (*API).startReplayTest},
+	models.TestTypeFile:         {func() any { return &FileTestRequest{} }, (*API).startFileTest},
+	models.TestTypeRaw:          {func() any { return &RawTestRequest{} }, (*API).startRawTest},
+}
+
+// targetTestTypes тесты, которые поддерживают отдельную точку назначения (Config.Target)
+var targetTestTypes = map[models.TestType]bool{
+	models.TestTypeBatch:  true,
+	models.TestTypeStream: true,
+	models.TestTypeLarge:  true,
+	models.TestTypeSweep:  true,
+	models.TestTypeFile:   true,
 }
 
 // NewAPI создает новый API сервер
@@ -44,22 +194,55 @@ func NewAPI(
 	logger *zap.Logger,
 	producer *broker.MQTTProducer,
 	generator *generator.DataGenerator,
-	tcpClient *tcp.TCPClient,
+	transports *transport.Registry,
+	orchestrator *orchestration.Client,
 ) *API {
 	api := &API{
 		logger:      logger,
 		producer:    producer,
+		transports:  transports,
 		generator:   generator,
-		testManager: test.NewManager(logger, producer, tcpClient, generator),
+		testManager: test.NewManager(logger, producer, transports, generator, orchestrator),
+		audit:       cfg.Audit,
+		prober:      cfg.HealthProber,
+		notifier:    cfg.Notifier,
+		templates:   cfg.Templates,
+		actions:     cfg.ActionLog,
+		cluster:     cfg.Cluster,
+		agent:       cfg.ClusterAgent,
+		certFile:    cfg.CertFile,
+		keyFile:     cfg.KeyFile,
+		captureDir:  cfg.CaptureDir,
+		filesDir:    cfg.FilesDir,
+		logDir:      cfg.LogDir,
+		running:     make(map[string]*models.TestConfig),
+		limits:      cfg.TestLimits,
+
+		tenantTopics: cfg.TenantTopics,
+		version:      cfg.Version,
 	}
 
+	api.testManager.SetAbortPolicy(cfg.AbortPolicy)
+	api.testManager.SetMemoryPolicy(cfg.MemoryPolicy)
+	api.testManager.SetTimeFormat(cfg.TimeFormat)
+	api.testManager.SetAuditListener(cfg.Audit)
+	api.testManager.SetNotifier(cfg.Notifier)
+	api.testManager.SetCorrelationDirectory(cfg.CorrelationDir)
+	api.metricsEnabled.Store(cfg.MetricsEnabled)
+	api.SetHealthPolicy(cfg.Health)
+	api.setupOpenAPI()
 	api.setupRouter()
+	if cfg.Debug {
+		api.setupDebugRoutes()
+	}
 
 	api.server = &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Handler:      api.router,
-		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: cfg.WriteTimeout,
+		Addr:              net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Handler:           actionlog.Handler(cfg.ActionLog, logger, api.router),
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
 	}
 
 	return api
@@ -76,10 +259,14 @@ func (api *API) setupRouter() {
 
 	// Health checks
 	api.router.GET("/health", api.healthCheck)
+	api.router.GET("/healthz", api.deepHealthCheck)
 	api.router.GET("/ready", api.readyCheck)
+	api.router.GET("/version", api.getVersion)
+	api.router.GET("/openapi.json", api.getOpenAPI)
 
 	// Metrics
 	api.router.GET("/metrics", api.prometheusMetrics)
+	api.router.GET("/metrics/native", api.nativeMetrics)
 
 	// Test management
 	testGroup := api.router.Group("/test")
@@ -87,14 +274,70 @@ func (api *API) setupRouter() {
 		testGroup.POST("/batch", api.startBatchTest)
 		testGroup.POST("/stream", api.startStreamTest)
 		testGroup.POST("/large", api.startLargeTest)
+		testGroup.POST("/discovery", api.startDiscoveryTest)
+		testGroup.POST("/sweep", api.startSweepTest)
+		testGroup.POST("/session", api.startSessionTest)
+		testGroup.POST("/exactly-once", api.startExactlyOnceTest)
+		testGroup.POST("/mqtt-features", api.startMQTTFeaturesTest)
+		testGroup.POST("/mixed", api.startMixedTest)
+		testGroup.POST("/fanout", api.startFanoutTest)
+		testGroup.POST("/replay", api.startReplayTest)
+		testGroup.POST("/file", api.startFileTest)
+		testGroup.POST("/raw", api.startRawTest)
+		testGroup.POST("/from-template/:name", api.startTemplateTest)
 		testGroup.POST("/stop", api.stopTest)
+		testGroup.GET("/current", api.getCurrentTest)
+		testGroup.GET("/:id", api.getTestResult)
+		testGroup.GET("/:id/report", api.getTestReport)
+	}
+
+	// Согласованные тесты нескольких sender (ведущий sender)
+	clusterGroup := api.router.Group("/cluster")
+	{
+		clusterGroup.GET("/nodes", api.listClusterNodes)
+		clusterGroup.POST("/nodes", api.registerClusterNode)
+		clusterGroup.DELETE("/nodes/:id", api.removeClusterNode)
+		clusterGroup.POST("/test/:type", api.startClusterTest)
+		clusterGroup.GET("/runs", api.listClusterRuns)
+		clusterGroup.GET("/runs/:id", api.getClusterRun)
+		clusterGroup.POST("/runs/:id/stop", api.stopClusterRun)
+	}
+
+	// Test templates
+	templateGroup := api.router.Group("/templates")
+	{
+		templateGroup.GET("", api.listTemplates)
+		templateGroup.GET("/:name", api.getTemplate)
+		templateGroup.PUT("/:name", api.putTemplate)
+		templateGroup.DELETE("/:name", api.deleteTemplate)
+	}
+
+	// Traffic capture
+	captureGroup := api.router.Group("/capture")
+	{
+		captureGroup.GET("", api.getCapture)
+		captureGroup.POST("/start", api.startCapture)
+		captureGroup.POST("/stop", api.stopCapture)
 	}
 
 	// Statistics
 	api.router.GET("/stats", api.getStats)
 
+	// Журнал действий API
+	api.router.GET("/audit", api.getActionLog)
+
 	// Generator
 	api.router.POST("/generate", api.generateData)
+
+	// Generated datasets
+	datasetGroup := api.router.Group("/datasets")
+	{
+		datasetGroup.GET("", api.listDatasets)
+		datasetGroup.GET("/:set/:name/sample", api.sampleDataset)
+		datasetGroup.DELETE("/:set", api.deleteDataset)
+		datasetGroup.DELETE("/:set/:name", api.deleteDataset)
+	}
+	api.router.DELETE("/data", api.deleteData)
 }
 
 // loggingMiddleware middleware для логирования запросов
@@ -132,7 +375,7 @@ func (api *API) corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Actor")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -145,10 +388,43 @@ func (api *API) corsMiddleware() gin.HandlerFunc {
 
 // healthCheck проверка состояния сервиса
 func (api *API) healthCheck(c *gin.Context) {
+	writeHealth(c, api.healthStatus())
+}
+
+// deepHealthCheck проверка состояния сервиса с проверкой пути до брокера: кроме проверок
+// /health последняя пробная публикация должна быть подтверждена брокером не раньше
+// health_probe.max_age назад
+func (api *API) deepHealthCheck(c *gin.Context) {
+	if api.prober == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "пробные сообщения отключены (health_probe.enabled)"})
+		return
+	}
+
+	status := api.healthStatus()
+	probeCheck := api.prober.Check()
+	if probeCheck.Status != "healthy" {
+		status.Status = "unhealthy"
+	}
+	status.Checks = append(status.Checks, probeCheck)
+
+	writeHealth(c, status)
+}
+
+// writeHealth отвечает состоянием сервиса: 503, если сервис неработоспособен
+func writeHealth(c *gin.Context, status models.HealthStatus) {
+	if status.Status == "healthy" {
+		c.JSON(http.StatusOK, status)
+	} else {
+		c.JSON(http.StatusServiceUnavailable, status)
+	}
+}
+
+// healthStatus выполняет проверки /health
+func (api *API) healthStatus() models.HealthStatus {
 	status := models.HealthStatus{
 		Status:    "healthy",
 		Service:   "sender",
-		Version:   "1.0.0",
+		Version:   api.version.Version,
 		Timestamp: time.Now(),
 		Checks:    []models.Check{},
 	}
@@ -167,23 +443,88 @@ func (api *API) healthCheck(c *gin.Context) {
 
 	status.Checks = append(status.Checks, mqttCheck)
 
+	// Проверка подключения к NATS (если включен)
+	if nats, err := api.transports.Get(models.ProtocolNATS); err == nil {
+		natsCheck := models.Check{
+			Component: "nats",
+			Status:    "healthy",
+		}
+
+		if !nats.Connected() {
+			natsCheck.Status = "unhealthy"
+			natsCheck.Message = "NATS server disconnected"
+			status.Status = "unhealthy"
+		}
+
+		status.Checks = append(status.Checks, natsCheck)
+	}
+
+	// Проверка подключения к TCP серверу (если включен)
+	if tcp, err := api.transports.Get(models.ProtocolTCP); err == nil {
+		tcpCheck := models.Check{
+			Component: "tcp",
+			Status:    "healthy",
+		}
+
+		if !tcp.Connected() {
+			tcpCheck.Status = "unhealthy"
+			tcpCheck.Message = "TCP server disconnected"
+			status.Status = "unhealthy"
+		}
+
+		status.Checks = append(status.Checks, tcpCheck)
+	}
+
+	// Проверка последовательного порта (если включен)
+	if serial, err := api.transports.Get(models.ProtocolSerial); err == nil {
+		serialCheck := models.Check{
+			Component: "serial",
+			Status:    "healthy",
+		}
+
+		if !serial.Connected() {
+			serialCheck.Status = "unhealthy"
+			serialCheck.Message = "serial port closed"
+			status.Status = "unhealthy"
+		}
+
+		status.Checks = append(status.Checks, serialCheck)
+	}
+
+	// Проверка свободного места для данных и логов и хранилища сессии MQTT
+	policy := api.healthPolicy.Load()
+	resourceChecks := []models.Check{diskCheck("disk_data", api.generator.DataPath(), policy.MinFreeDiskPercent)}
+	if api.logDir != "" {
+		resourceChecks = append(resourceChecks, diskCheck("disk_logs", api.logDir, policy.MinFreeDiskPercent))
+	}
+	resourceChecks = append(resourceChecks, api.storeCheck(policy.MaxStoreBacklog))
+	for _, check := range resourceChecks {
+		if check.Status != "healthy" {
+			status.Status = "unhealthy"
+		}
+		status.Checks = append(status.Checks, check)
+	}
+
 	// Проверка тестового менеджера
 	testCheck := models.Check{
 		Component: "test_manager",
 		Status:    "healthy",
 	}
 
-	if api.isTestActive {
-		testCheck.Message = fmt.Sprintf("Test running: %s", api.currentTest.Type)
+	api.mu.RLock()
+	if len(api.running) > 0 {
+		types := make([]string, 0, len(api.running))
+		for _, config := range api.running {
+			types = append(types, string(config.Type))
+		}
+		sort.Strings(types)
+		testCheck.Message = fmt.Sprintf("Tests running: %s", strings.Join(types, ", "))
 	}
+	api.mu.RUnlock()
 
 	status.Checks = append(status.Checks, testCheck)
 
-	if status.Status == "healthy" {
-		c.JSON(http.StatusOK, status)
-	} else {
-		c.JSON(http.StatusServiceUnavailable, status)
-	}
+	return status
 }
 
 // readyCheck проверка готовности сервиса
@@ -203,23 +544,35 @@ func (api *API) startBatchTest(c *gin.Context) {
 		return
 	}
 
-	// Проверка, что нет активного теста
-	api.mu.RLock()
-	if api.isTestActive {
-		api.mu.RUnlock()
-		c.JSON(http.StatusConflict, gin.H{"error": "тест уже запущен"})
+	if _, err := generator.ParseCorruptionKinds(req.CorruptionKinds); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	api.mu.RUnlock()
 
 	// Создание конфигурации теста
 	config := &models.TestConfig{
 		Type:          models.TestTypeBatch,
 		Protocol:      req.Protocol,
+		Target:        req.Target,
+		MQTT:          req.MQTT,
 		ThreadCount:   req.ThreadCount,
 		PacketSize:    req.PacketSize,
 		TotalMessages: req.TotalMessages,
 		Duration:      req.Duration,
+		WarmupSeconds: req.WarmupSeconds,
+		PadToSize:     req.PadToSize,
+
+		InvalidPercent:  req.InvalidPercent,
+		CorruptionKinds: req.CorruptionKinds,
+		MessageTTL:      req.MessageTTL,
+		Seed:            req.Seed,
+		Chaos:           req.Chaos,
+
+		DataSource: req.DataSource,
+
+		Tenant:   req.Tenant,
+		RunLabel: req.RunLabel,
+		StartAt:  req.StartAt,
 	}
 
 	// Установка протокола по умолчанию, если не указан
@@ -227,29 +580,7 @@ func (api *API) startBatchTest(c *gin.Context) {
 		config.Protocol = models.ProtocolMQTT
 	}
 
-	// Запуск теста
-	api.mu.Lock()
-	api.currentTest = config
-	api.isTestActive = true
-	api.mu.Unlock()
-
-	go func() {
-		defer func() {
-			api.mu.Lock()
-			api.isTestActive = false
-			api.mu.Unlock()
-		}()
-
-		if err := api.testManager.RunBatchTest(config); err != nil {
-			api.logger.Error("Ошибка выполнения batch теста", zap.Error(err))
-		}
-	}()
-
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "started",
-		"test_id": time.Now().Unix(),
-		"config":  config,
-	})
+	api.launchTest(c, config, api.testManager.RunBatchTest)
 }
 
 // startStreamTest запуск потокового теста
@@ -260,23 +591,36 @@ func (api *API) startStreamTest(c *gin.Context) {
 		return
 	}
 
-	// Проверка, что нет активного теста
-	api.mu.RLock()
-	if api.isTestActive {
-		api.mu.RUnlock()
-		c.JSON(http.StatusConflict, gin.H{"error": "тест уже запущен"})
+	if _, err := generator.ParseCorruptionKinds(req.CorruptionKinds); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	api.mu.RUnlock()
 
 	// Создание конфигурации теста
 	config := &models.TestConfig{
 		Type:           models.TestTypeStream,
 		Protocol:       req.Protocol,
+		Target:         req.Target,
+		MQTT:           req.MQTT,
 		MessagesPerSec: req.MessagesPerSec,
 		PacketSize:     req.PacketSize,
 		Duration:       req.Duration,
+		WarmupSeconds:  req.WarmupSeconds,
+		PadToSize:      req.PadToSize,
 		ThreadCount:    1, // Потоковый тест использует один поток
+
+		InvalidPercent:  req.InvalidPercent,
+		CorruptionKinds: req.CorruptionKinds,
+		MessageTTL:      req.MessageTTL,
+		SendRetries:     req.SendRetries,
+		Seed:            req.Seed,
+		Chaos:           req.Chaos,
+
+		DataSource: req.DataSource,
+
+		Tenant:   req.Tenant,
+		RunLabel: req.RunLabel,
+		StartAt:  req.StartAt,
 	}
 
 	// Установка протокола по умолчанию, если не указан
@@ -284,29 +628,7 @@ func (api *API) startStreamTest(c *gin.Context) {
 		config.Protocol = models.ProtocolMQTT
 	}
 
-	// Запуск теста
-	api.mu.Lock()
-	api.currentTest = config
-	api.isTestActive = true
-	api.mu.Unlock()
-
-	go func() {
-		defer func() {
-			api.mu.Lock()
-			api.isTestActive = false
-			api.mu.Unlock()
-		}()
-
-		if err := api.testManager.RunStreamTest(config); err != nil {
-			api.logger.Error("Ошибка выполнения stream теста", zap.Error(err))
-		}
-	}()
-
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "started",
-		"test_id": time.Now().Unix(),
-		"config":  config,
-	})
+	api.launchTest(c, config, api.testManager.RunStreamTest)
 }
 
 // startLargeTest запуск теста с большими пакетами
@@ -317,22 +639,26 @@ func (api *API) startLargeTest(c *gin.Context) {
 		return
 	}
 
-	// Проверка, что нет активного теста
-	api.mu.RLock()
-	if api.isTestActive {
-		api.mu.RUnlock()
-		c.JSON(http.StatusConflict, gin.H{"error": "тест уже запущен"})
-		return
-	}
-	api.mu.RUnlock()
-
 	// Создание конфигурации теста
 	config := &models.TestConfig{
-		Type:        models.TestTypeLarge,
-		Protocol:    req.Protocol,
-		ThreadCount: req.ThreadCount,
-		PacketSize:  req.PacketSizeMB * 1024 * 1024, // Конвертация MB в байты
-		Duration:    req.Duration,
+		Type:          models.TestTypeLarge,
+		Protocol:      req.Protocol,
+		Target:        req.Target,
+		MQTT:          req.MQTT,
+		ThreadCount:   req.ThreadCount,
+		PacketSize:    req.PacketSizeMB * 1024 * 1024, // Конвертация MB в байты
+		Duration:      req.Duration,
+		WarmupSeconds: req.WarmupSeconds,
+		PadToSize:     req.PadToSize,
+		MessageTTL:    req.MessageTTL,
+		SendRetries:   req.SendRetries,
+		Chaos:         req.Chaos,
+
+		DataSource: req.DataSource,
+
+		Tenant:   req.Tenant,
+		RunLabel: req.RunLabel,
+		StartAt:  req.StartAt,
 	}
 
 	// Установка протокола по умолчанию, если не указан
@@ -340,149 +666,1881 @@ func (api *API) startLargeTest(c *gin.Context) {
 		config.Protocol = models.ProtocolMQTT
 	}
 
-	// Запуск теста
-	api.mu.Lock()
-	api.currentTest = config
-	api.isTestActive = true
-	api.mu.Unlock()
-
-	go func() {
-		defer func() {
-			api.mu.Lock()
-			api.isTestActive = false
-			api.mu.Unlock()
-		}()
-
-		if err := api.testManager.RunLargeTest(config); err != nil {
-			api.logger.Error("Ошибка выполнения large теста", zap.Error(err))
-		}
-	}()
-
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "started",
-		"test_id": time.Now().Unix(),
-		"config":  config,
-	})
+	api.launchTest(c, config, api.testManager.RunLargeTest)
 }
 
-// stopTest остановка текущего теста
-func (api *API) stopTest(c *gin.Context) {
-	api.mu.RLock()
-	if !api.isTestActive {
-		api.mu.RUnlock()
-		c.JSON(http.StatusBadRequest, gin.H{"error": "нет активного теста"})
+// startDiscoveryTest запуск поиска максимальной пропускной способности
+func (api *API) startDiscoveryTest(c *gin.Context) {
+	var req DiscoveryTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	api.mu.RUnlock()
 
-	if err := api.testManager.StopCurrentTest(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if req.MaxRate < req.StartRate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_rate должен быть не меньше start_rate"})
 		return
 	}
 
-	api.mu.Lock()
-	api.isTestActive = false
-	api.mu.Unlock()
+	discovery := &models.DiscoveryConfig{
+		StartRate:      req.StartRate,
+		StepRate:       req.StepRate,
+		MaxRate:        req.MaxRate,
+		StepDuration:   req.StepDuration,
+		SettleTime:     req.SettleTime,
+		MaxLossPercent: req.MaxLossPercent,
+		MaxLatencyMs:   req.MaxLatencyMs,
+	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "stopped"})
-}
+	// Общая длительность с запасом на опрос recipient; при прогреве добавляется
+	// один шаг на ожидание доставки прогревочных сообщений
+	steps := (req.MaxRate-req.StartRate)/req.StepRate + 1
+	if req.WarmupSeconds > 0 {
+		steps++
+	}
+	config := &models.TestConfig{
+		Type:           models.TestTypeDiscovery,
+		Protocol:       req.Protocol,
+		MessagesPerSec: req.StartRate,
+		PacketSize:     req.PacketSize,
+		Duration:       steps*(req.StepDuration+req.SettleTime) + 30,
+		ThreadCount:    1,
+		Discovery:      discovery,
+		WarmupSeconds:  req.WarmupSeconds,
+		PadToSize:      req.PadToSize,
 
-// getStats получение статистики
-func (api *API) getStats(c *gin.Context) {
-	producerStats := api.producer.GetStats()
-	testStats := api.testManager.GetStats()
+		DataSource: req.DataSource,
 
-	api.mu.RLock()
-	isActive := api.isTestActive
-	var currentTestType string
-	if api.currentTest != nil {
-		currentTestType = string(api.currentTest.Type)
+		Tenant:   req.Tenant,
+		RunLabel: req.RunLabel,
+		StartAt:  req.StartAt,
 	}
-	api.mu.RUnlock()
 
-	c.JSON(http.StatusOK, gin.H{
-		"producer":     producerStats,
-		"test":         testStats,
-		"active":       isActive,
-		"current_test": currentTestType,
-	})
+	if config.Protocol == "" {
+		config.Protocol = models.ProtocolMQTT
+	}
+
+	api.launchTest(c, config, api.testManager.RunDiscoveryTest)
 }
 
-// generateData генерация тестовых данных
-func (api *API) generateData(c *gin.Context) {
-	var req GenerateDataRequest
+// startSweepTest запуск перебора размеров сообщений
+func (api *API) startSweepTest(c *gin.Context) {
+	var req SweepTestRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	go func() {
-		switch req.Type {
-		case "all":
-			if err := api.generator.GenerateAllTestData(); err != nil {
-				api.logger.Error("Ошибка генерации всех данных", zap.Error(err))
-			}
-		case "small":
-			if err := api.generator.GenerateSmallBatches(); err != nil {
-				api.logger.Error("Ошибка генерации маленьких пакетов", zap.Error(err))
-			}
-		case "medium":
-			if err := api.generator.GenerateMediumBatches(); err != nil {
-				api.logger.Error("Ошибка генерации средних пакетов", zap.Error(err))
+	sizes, err := sweepSizes(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Общая длительность с запасом на опрос recipient; при прогреве добавляется
+	// один шаг на ожидание доставки прогревочных сообщений
+	steps := len(sizes)
+	if req.WarmupSeconds > 0 {
+		steps++
+	}
+	config := &models.TestConfig{
+		Type:        models.TestTypeSweep,
+		Protocol:    req.Protocol,
+		Target:      req.Target,
+		MQTT:        req.MQTT,
+		ThreadCount: req.ThreadCount,
+		PacketSize:  sizes[0],
+		PadToSize:   true,
+		Duration:    steps*(req.StepDuration+req.SettleTime) + 30,
+		Sweep: &models.SweepConfig{
+			Sizes:        sizes,
+			StepDuration: req.StepDuration,
+			SettleTime:   req.SettleTime,
+		},
+		WarmupSeconds: req.WarmupSeconds,
+		MessageTTL:    req.MessageTTL,
+		SendRetries:   req.SendRetries,
+
+		DataSource: req.DataSource,
+
+		Tenant:   req.Tenant,
+		RunLabel: req.RunLabel,
+		StartAt:  req.StartAt,
+	}
+
+	if config.Protocol == "" {
+		config.Protocol = models.ProtocolMQTT
+	}
+
+	api.launchTest(c, config, api.testManager.RunSweepTest)
+}
+
+// sweepSizes возвращает размеры сообщений шагов перебора: заданный список или
+// размеры от min_size до max_size, увеличивающиеся в factor раз
+func sweepSizes(req *SweepTestRequest) ([]int, error) {
+	if len(req.Sizes) > 0 {
+		if req.MinSize != 0 || req.MaxSize != 0 || req.Factor != 0 {
+			return nil, fmt.Errorf("sizes задается без min_size, max_size и factor")
+		}
+		for i, size := range req.Sizes {
+			if size < minSweepSize || size > maxSweepSize {
+				return nil, fmt.Errorf("sizes[%d]: размер должен быть от %d до %d байт", i, minSweepSize, maxSweepSize)
 			}
-		case "large":
-			if err := api.generator.GenerateLargeBatches(); err != nil {
-				api.logger.Error("Ошибка генерации больших пакетов", zap.Error(err))
+			if i > 0 && size <= req.Sizes[i-1] {
+				return nil, fmt.Errorf("sizes должны возрастать")
 			}
 		}
-	}()
+		if len(req.Sizes) > maxSweepSteps {
+			return nil, fmt.Errorf("sizes: шагов больше %d", maxSweepSteps)
+		}
+		return req.Sizes, nil
+	}
 
-	c.JSON(http.StatusAccepted, gin.H{"status": "generation started"})
-}
+	minSize, maxSize, factor := req.MinSize, req.MaxSize, req.Factor
+	if minSize == 0 {
+		minSize = 512
+	}
+	if maxSize == 0 {
+		maxSize = 1024 * 1024
+	}
+	if factor == 0 {
+		factor = 2
+	}
+	if minSize > maxSize {
+		return nil, fmt.Errorf("max_size должен быть не меньше min_size")
+	}
 
-// prometheusMetrics возвращает метрики в формате Prometheus
-func (api *API) prometheusMetrics(c *gin.Context) {
-	// TODO: Реализовать экспорт метрик в формате Prometheus
-	c.String(http.StatusOK, "# HELP mqtt_messages_sent_total Total number of messages sent\n")
+	var sizes []int
+	for size := float64(minSize); int(size) <= maxSize; size *= factor {
+		// Дробный множитель может дать тот же размер после округления
+		if n := int(size); len(sizes) == 0 || n > sizes[len(sizes)-1] {
+			sizes = append(sizes, n)
+		}
+		if len(sizes) > maxSweepSteps {
+			return nil, fmt.Errorf("шагов перебора больше %d: увеличьте factor", maxSweepSteps)
+		}
+	}
+	return sizes, nil
 }
 
-// Start запускает HTTP сервер
-func (api *API) Start() error {
-	api.logger.Info("Запуск HTTP API сервера", zap.String("addr", api.server.Addr))
-	return api.server.ListenAndServe()
-}
+// startSessionTest запуск проверки восстановления MQTT сессии после разрывов соединения
+func (api *API) startSessionTest(c *gin.Context) {
+	var req SessionTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-// Shutdown корректно останавливает HTTP сервер
-func (api *API) Shutdown(ctx context.Context) error {
-	api.logger.Info("Остановка HTTP API сервера")
-	return api.server.Shutdown(ctx)
-}
+	session := &models.SessionConfig{
+		Interruptions: req.Interruptions,
+		Interval:      req.Interval,
+		PauseDuration: req.PauseDuration,
+		SettleTime:    req.SettleTime,
+	}
 
-// Request structures
+	// Общая длительность с запасом на переподключения и опрос recipient
+	config := &models.TestConfig{
+		Type:           models.TestTypeSession,
+		Protocol:       models.ProtocolMQTT,
+		MessagesPerSec: req.MessagesPerSec,
+		PacketSize:     req.PacketSize,
+		Duration:       (req.Interruptions+1)*req.Interval + req.Interruptions*req.PauseDuration + req.SettleTime + 30,
+		ThreadCount:    1,
+		Session:        session,
+		WarmupSeconds:  req.WarmupSeconds,
 
-// BatchTestRequest запрос на запуск пакетного теста
-type BatchTestRequest struct {
-	Protocol      models.TestProtocol `json:"protocol" binding:"omitempty,oneof=mqtt tcp"`
-	ThreadCount   int                 `json:"thread_count" binding:"required,min=1,max=1000"`
-	PacketSize    int                 `json:"packet_size" binding:"required,min=100"`
+		DataSource: req.DataSource,
+
+		Tenant:   req.Tenant,
+		RunLabel: req.RunLabel,
+		StartAt:  req.StartAt,
+	}
+
+	api.launchTest(c, config, api.testManager.RunSessionResumeTest)
+}
+
+// startExactlyOnceTest запуск проверки доставки ровно один раз через MQTT QoS 2
+func (api *API) startExactlyOnceTest(c *gin.Context) {
+	var req ExactlyOnceTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Общая длительность с запасом на опрос recipient
+	config := &models.TestConfig{
+		Type:           models.TestTypeExactlyOnce,
+		Protocol:       models.ProtocolMQTT,
+		MessagesPerSec: req.MessagesPerSec,
+		PacketSize:     req.PacketSize,
+		Duration:       req.Duration + req.SettleTime + 30,
+		ThreadCount:    1,
+		WarmupSeconds:  req.WarmupSeconds,
+		ExactlyOnce: &models.ExactlyOnceConfig{
+			SendDuration: req.Duration,
+			SettleTime:   req.SettleTime,
+		},
+
+		DataSource: req.DataSource,
+
+		Tenant:   req.Tenant,
+		RunLabel: req.RunLabel,
+		StartAt:  req.StartAt,
+	}
+
+	api.launchTest(c, config, api.testManager.RunExactlyOnceTest)
+}
+
+// startMQTTFeaturesTest запуск проверки retained сообщений и last will
+func (api *API) startMQTTFeaturesTest(c *gin.Context) {
+	var req MQTTFeaturesTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Общая длительность: два ожидания доставки с запасом на переподключение и опрос recipient
+	config := &models.TestConfig{
+		Type:          models.TestTypeMQTTFeatures,
+		Protocol:      models.ProtocolMQTT,
+		Duration:      2*req.SettleTime + 60,
+		ThreadCount:   1,
+		TotalMessages: req.RetainedMarkers,
+		MQTTFeatures: &models.MQTTFeaturesConfig{
+			RetainedMarkers: req.RetainedMarkers,
+			TriggerWill:     req.TriggerWill,
+			SettleTime:      req.SettleTime,
+		},
+
+		DataSource: req.DataSource,
+
+		Tenant:   req.Tenant,
+		RunLabel: req.RunLabel,
+		StartAt:  req.StartAt,
+	}
+
+	api.launchTest(c, config, api.testManager.RunMQTTFeaturesTest)
+}
+
+// startMixedTest запуск пакетного теста с одновременной отправкой через MQTT и TCP
+func (api *API) startMixedTest(c *gin.Context) {
+	var req MixedTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := generator.ParseCorruptionKinds(req.CorruptionKinds); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mqttThreads, tcpThreads := test.SplitThreads(req.ThreadCount, req.MQTTPercent)
+	if mqttThreads == 0 || tcpThreads == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mqtt_percent должен оставлять хотя бы один поток каждого протокола"})
+		return
+	}
+
+	config := &models.TestConfig{
+		Type:          models.TestTypeMixed,
+		ThreadCount:   req.ThreadCount,
+		PacketSize:    req.PacketSize,
+		TotalMessages: req.TotalMessages,
+		Duration:      req.Duration,
+		WarmupSeconds: req.WarmupSeconds,
+		PadToSize:     req.PadToSize,
+		Mixed: &models.MixedConfig{
+			MQTTPercent: req.MQTTPercent,
+			MQTTThreads: mqttThreads,
+			TCPThreads:  tcpThreads,
+		},
+
+		InvalidPercent:  req.InvalidPercent,
+		CorruptionKinds: req.CorruptionKinds,
+		MessageTTL:      req.MessageTTL,
+		Seed:            req.Seed,
+		Chaos:           req.Chaos,
+
+		DataSource: req.DataSource,
+
+		Tenant:   req.Tenant,
+		RunLabel: req.RunLabel,
+		StartAt:  req.StartAt,
+	}
+
+	api.launchTest(c, config, api.testManager.RunMixedTest)
+}
+
+// startFanoutTest запуск одновременной отправки потока в несколько точек назначения
+func (api *API) startFanoutTest(c *gin.Context) {
+	var req FanoutTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := generator.ParseCorruptionKinds(req.CorruptionKinds); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	destinations := make([]models.Destination, 0, len(req.Destinations))
+	seen := make(map[models.Destination]bool, len(req.Destinations))
+	for _, d := range req.Destinations {
+		dest := models.Destination{Protocol: d.Protocol, Target: d.Target}
+		if seen[dest] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("точка назначения %s указана повторно", dest)})
+			return
+		}
+		seen[dest] = true
+		destinations = append(destinations, dest)
+	}
+
+	config := &models.TestConfig{
+		Type:           models.TestTypeFanout,
+		MessagesPerSec: req.MessagesPerSec,
+		PacketSize:     req.PacketSize,
+		Duration:       req.Duration,
+		WarmupSeconds:  req.WarmupSeconds,
+		PadToSize:      req.PadToSize,
+		ThreadCount:    len(destinations),
+		Fanout:         &models.FanoutConfig{Destinations: destinations},
+
+		InvalidPercent:  req.InvalidPercent,
+		CorruptionKinds: req.CorruptionKinds,
+		MessageTTL:      req.MessageTTL,
+		Seed:            req.Seed,
+
+		DataSource: req.DataSource,
+
+		Tenant:   req.Tenant,
+		RunLabel: req.RunLabel,
+		StartAt:  req.StartAt,
+	}
+
+	api.launchTest(c, config, api.testManager.RunFanoutTest)
+}
+
+// startReplayTest запуск воспроизведения трафика, записанного через /capture
+func (api *API) startReplayTest(c *gin.Context) {
+	var req ReplayTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	path, err := api.capturePath(req.Capture)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	capture, err := test.LoadCapture(path)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, os.ErrNotExist) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	speed := req.Speed
+	if speed == 0 {
+		speed = 1
+	}
+
+	// Длительность записи с запасом на завершение последних отправок
+	config := &models.TestConfig{
+		Type:        models.TestTypeReplay,
+		Protocol:    req.Protocol,
+		Duration:    int(math.Ceil(capture.Duration().Seconds()/speed)) + 30,
+		ThreadCount: 1,
+		Replay: &models.ReplayConfig{
+			Capture:      req.Capture,
+			SourceTestID: capture.Header.TestID,
+			Speed:        speed,
+			Events:       len(capture.Events),
+		},
+
+		Tenant:   req.Tenant,
+		RunLabel: req.RunLabel,
+		StartAt:  req.StartAt,
+	}
+
+	api.launchTest(c, config, func(config *models.TestConfig) error {
+		return api.testManager.RunReplayTest(config, capture)
+	})
+}
+
+// startFileTest запуск передачи файла фрагментами с проверкой по манифесту на recipient
+func (api *API) startFileTest(c *gin.Context) {
+	var req FileTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fc := &models.FileConfig{
+		Name:       req.File,
+		ChunkSize:  req.ChunkSize,
+		SettleTime: req.SettleTime,
+	}
+	if fc.ChunkSize == 0 {
+		fc.ChunkSize = defaultFileChunkSize
+	}
+	if fc.SettleTime == 0 {
+		fc.SettleTime = defaultFileSettleTime
+	}
+
+	var path string
+	switch {
+	case req.File != "" && req.SizeMB > 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "укажите file или size_mb, но не оба"})
+		return
+	case req.File != "":
+		// Имя проверяется так же, как имя файла записи: без пути
+		if !captureNamePattern.MatchString(req.File) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("некорректное имя файла: %q", req.File)})
+			return
+		}
+		path = filepath.Join(api.filesDir, req.File)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, os.ErrNotExist) {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		if !info.Mode().IsRegular() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s не является файлом", req.File)})
+			return
+		}
+		fc.Size = info.Size()
+	case req.SizeMB > 0:
+		fc.Size = int64(req.SizeMB) * 1024 * 1024
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "укажите file или size_mb"})
+		return
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = defaultFileTimeout
+	}
+
+	// Общая длительность: передача и ожидание проверки файла на recipient
+	config := &models.TestConfig{
+		Type:           models.TestTypeFile,
+		Protocol:       req.Protocol,
+		Target:         req.Target,
+		MQTT:           req.MQTT,
+		MessagesPerSec: req.MessagesPerSec,
+		PacketSize:     fc.ChunkSize,
+		Duration:       timeout + fc.SettleTime,
+		ThreadCount:    1,
+		File:           fc,
+		Seed:           req.Seed,
+
+		Tenant:   req.Tenant,
+		RunLabel: req.RunLabel,
+		StartAt:  req.StartAt,
+	}
+
+	api.launchTest(c, config, func(config *models.TestConfig) error {
+		return api.testManager.RunFileTest(config, path)
+	})
+}
+
+// startRawTest запуск насыщения канала TCP кадрами-заполнителями без сериализации
+func (api *API) startRawTest(c *gin.Context) {
+	var req RawTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	connections := req.Connections
+	if connections == 0 {
+		connections = 1
+	}
+
+	config := &models.TestConfig{
+		Type:           models.TestTypeRaw,
+		Protocol:       models.ProtocolTCP,
+		Target:         req.Target,
+		ThreadCount:    connections,
+		PacketSize:     req.FrameSize,
+		MessagesPerSec: req.FramesPerSec,
+		Duration:       req.Duration,
+		WarmupSeconds:  req.WarmupSeconds,
+		Seed:           req.Seed,
+
+		Tenant:   req.Tenant,
+		RunLabel: req.RunLabel,
+		StartAt:  req.StartAt,
+	}
+
+	api.launchTest(c, config, api.testManager.RunRawTest)
+}
+
+// launchTest запускает тест в фоне, если он укладывается в ограничения одновременных тестов
+func (api *API) launchTest(c *gin.Context, config *models.TestConfig, run func(*models.TestConfig) error) {
+	if config.Target != "" && config.Protocol == models.ProtocolNATS {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("протокол %s не поддерживает выбор точки назначения", config.Protocol)})
+		return
+	}
+	if err := validateMQTTOptions(config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateLabels(config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	api.applyTenantTopic(config)
+	if err := test.ValidateChaos(config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := test.ValidateStartAt(config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := api.testManager.ValidateDataSource(config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := api.testManager.PlanMemory(config); err != nil {
+		if errors.Is(err, test.ErrMemoryBudget) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "memory": config.Memory})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if config.Memory != nil && config.Memory.Streaming {
+		api.logger.Warn("Оценка памяти теста превышает ограничение, тест запускается в потоковом режиме",
+			zap.Int64("estimated_bytes", config.Memory.EstimatedBytes),
+			zap.Int64("budget_bytes", config.Memory.BudgetBytes),
+			zap.Int("in_flight", config.Memory.InFlight))
+	}
+
+	api.mu.Lock()
+	if err := api.checkLimits(config); err != nil {
+		api.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	config.ID = test.NewTestID()
+	api.running[config.ID] = config
+	api.mu.Unlock()
+	actionlog.Annotate(c.Request.Context(), "test_id", config.ID)
+
+	go func() {
+		defer func() {
+			api.mu.Lock()
+			delete(api.running, config.ID)
+			api.mu.Unlock()
+		}()
+
+		if err := run(config); err != nil {
+			api.logger.Error("Ошибка выполнения теста",
+				zap.String("type", string(config.Type)),
+				zap.String("test_id", config.ID),
+				zap.Error(err))
+		}
+	}()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "started",
+		"test_id": config.ID,
+		"config":  config,
+	})
+}
+
+// validateLabels проверяет метки теста
+func validateLabels(config *models.TestConfig) error {
+	if err := models.ValidateLabel("tenant", config.Tenant); err != nil {
+		return err
+	}
+	return models.ValidateLabel("run_label", config.RunLabel)
+}
+
+// applyTenantTopic направляет тест команды без точки назначения в топик команды
+// <mqtt.topic>/<tenant>, если включен mqtt.tenant_topics
+func (api *API) applyTenantTopic(config *models.TestConfig) {
+	protocol := config.Protocol
+	if protocol == "" {
+		protocol = models.ProtocolMQTT
+	}
+	if !api.tenantTopics || config.Tenant == "" || config.Target != "" ||
+		protocol != models.ProtocolMQTT || !targetTestTypes[config.Type] || api.producer == nil {
+		return
+	}
+	config.Target = api.producer.PublishOptions().Topic + "/" + config.Tenant
+}
+
+// validateMQTTOptions проверяет параметры публикации MQTT теста
+func validateMQTTOptions(config *models.TestConfig) error {
+	if config.MQTT == nil {
+		return nil
+	}
+	if config.Protocol != "" && config.Protocol != models.ProtocolMQTT {
+		return fmt.Errorf("параметры mqtt задаются только для протокола %s", models.ProtocolMQTT)
+	}
+	if qos := config.MQTT.QoS; qos != nil && *qos > 2 {
+		return fmt.Errorf("mqtt.qos должен быть 0, 1 или 2")
+	}
+	return nil
+}
+
+// checkLimits проверяет, что тест можно запустить вместе с выполняющимися (вызывается под api.mu)
+func (api *API) checkLimits(config *models.TestConfig) error {
+	if len(api.running) > 0 {
+		if len(api.running) >= api.limits.MaxConcurrent {
+			return fmt.Errorf("уже выполняется тестов: %d (предел tests.max_concurrent: %d)", len(api.running), api.limits.MaxConcurrent)
+		}
+		if exclusiveTest(config) {
+			return fmt.Errorf("тест %s выполняется только без других тестов", exclusiveName(config))
+		}
+	}
+
+	threads, rate := config.ThreadCount, api.limits.quotaRate(config)
+	for _, running := range api.running {
+		if exclusiveTest(running) {
+			return fmt.Errorf("выполняется тест %s, который не допускает одновременных тестов", exclusiveName(running))
+		}
+		threads += running.ThreadCount
+		rate += api.limits.quotaRate(running)
+	}
+
+	if api.limits.MaxTotalThreads > 0 && threads > api.limits.MaxTotalThreads {
+		return fmt.Errorf("суммарное количество потоков %d превышает tests.max_total_threads (%d)", threads, api.limits.MaxTotalThreads)
+	}
+	if api.limits.MaxTotalRate > 0 && rate > api.limits.MaxTotalRate {
+		return fmt.Errorf("суммарная скорость %d сообщений/сек превышает tests.max_total_rate (%d)", rate, api.limits.MaxTotalRate)
+	}
+	return nil
+}
+
+// SetTestLimits изменяет ограничения одновременных тестов; выполняющиеся тесты не прерываются
+// и продолжаются с заданными при запуске потоками и скоростью. Возвращает суммарные
+// потоки и скорость выполняющихся тестов на момент изменения
+func (api *API) SetTestLimits(limits TestLimits) (threads, rate int) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.limits = limits
+	for _, running := range api.running {
+		threads += running.ThreadCount
+		rate += limits.quotaRate(running)
+	}
+	return threads, rate
+}
+
+// SetAbortPolicy изменяет пороги прерывания тестов по ошибкам отправки;
+// выполняющиеся тесты используют пороги на момент запуска
+func (api *API) SetAbortPolicy(policy test.AbortPolicy) {
+	api.testManager.SetAbortPolicy(policy)
+}
+
+// SetMemoryPolicy изменяет ограничение памяти тестов с большими пакетами
+func (api *API) SetMemoryPolicy(policy test.MemoryPolicy) {
+	api.testManager.SetMemoryPolicy(policy)
+}
+
+// SetTimeFormat изменяет формат send_time сообщений; применяется к тестам, запущенным после изменения
+func (api *API) SetTimeFormat(format string) {
+	api.testManager.SetTimeFormat(format)
+}
+
+// stopTest остановка теста test_id (из тела запроса или параметра) или всех выполняющихся тестов
+func (api *API) stopTest(c *gin.Context) {
+	var req StopTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TestID == "" {
+		req.TestID = c.Query("test_id")
+	}
+
+	api.mu.RLock()
+	_, running := api.running[req.TestID]
+	active := len(api.running) > 0
+	api.mu.RUnlock()
+
+	switch {
+	case !active:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "нет активного теста"})
+		return
+	case req.TestID != "" && !running:
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("тест %s не выполняется", req.TestID)})
+		return
+	}
+
+	stopped, err := api.testManager.StopTest(req.TestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	actionlog.Annotate(c.Request.Context(), "test_id", strings.Join(stopped, ","))
+
+	c.JSON(http.StatusOK, gin.H{"status": "stopped", "stopped": stopped})
+}
+
+// getCurrentTest возвращает ход выполняющегося теста test_id (по умолчанию последнего
+// запущенного): долю выполнения, текущую скорость и оценку оставшегося времени
+func (api *API) getCurrentTest(c *gin.Context) {
+	progress, err := api.testManager.Progress(c.Query("test_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// maxActionLogLimit предельное количество записей журнала действий в ответе
+const maxActionLogLimit = 10000
+
+// getActionLog возвращает последние записи журнала действий с отбором по параметрам
+// since (RFC3339), actor, action (начало действия), test_id и limit (по умолчанию 100)
+func (api *API) getActionLog(c *gin.Context) {
+	if api.actions == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "журнал действий не ведется (action_log.file)"})
+		return
+	}
+
+	query := actionlog.Query{
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+		TestID: c.Query("test_id"),
+		Limit:  100,
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxActionLogLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit должен быть от 1 до %d", maxActionLogLimit)})
+			return
+		}
+		query.Limit = limit
+	}
+	if value := c.Query("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since должен быть в формате RFC3339"})
+			return
+		}
+		query.Since = since
+	}
+
+	events, err := api.actions.Query(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"log": api.actions.Status(), "events": events})
+}
+
+// listTemplates возвращает сохраненные шаблоны тестов
+func (api *API) listTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"templates": api.templates.List()})
+}
+
+// getTemplate возвращает шаблон теста
+func (api *API) getTemplate(c *gin.Context) {
+	template, err := api.templates.Get(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// putTemplate создает или заменяет шаблон теста. Тело запроса проверяется так же,
+// как при запуске теста, поэтому сохраняются только запускаемые шаблоны
+func (api *API) putTemplate(c *gin.Context) {
+	name := c.Param("name")
+	if err := templates.ValidateName(name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	test, ok := templateTests[req.Type]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("тест %q нельзя сохранить в шаблоне", req.Type)})
+		return
+	}
+	if err := binding.JSON.BindBody(req.Request, test.request()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("некорректный запрос теста: %v", err)})
+		return
+	}
+
+	template, created, err := api.templates.Put(templates.Template{
+		Name:        name,
+		Type:        req.Type,
+		Description: req.Description,
+		Request:     req.Request,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, template)
+}
+
+// deleteTemplate удаляет шаблон теста
+func (api *API) deleteTemplate(c *gin.Context) {
+	if err := api.templates.Delete(c.Param("name")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, templates.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted", "name": c.Param("name")})
+}
+
+// startTemplateTest запускает тест по шаблону. Поля JSON объекта в теле запроса
+// (необязательного) заменяют одноименные поля запроса из шаблона
+func (api *API) startTemplateTest(c *gin.Context) {
+	template, err := api.templates.Get(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	test, ok := templateTests[template.Type]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("тест %q нельзя запустить по шаблону", template.Type)})
+		return
+	}
+
+	overrides, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	body, err := mergeTemplateRequest(template.Request, overrides)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	c.Request.ContentLength = int64(len(body))
+	test.start(api, c)
+}
+
+// mergeTemplateRequest заменяет поля запроса шаблона полями overrides (JSON объект
+// или пустое тело)
+func mergeTemplateRequest(request json.RawMessage, overrides []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(overrides)) == 0 {
+		return request, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(request, &fields); err != nil {
+		return nil, fmt.Errorf("некорректный запрос в шаблоне: %w", err)
+	}
+	var replaced map[string]json.RawMessage
+	if err := json.Unmarshal(overrides, &replaced); err != nil {
+		return nil, fmt.Errorf("тело запроса должно быть JSON объектом с заменяемыми полями: %w", err)
+	}
+
+	if fields == nil {
+		fields = make(map[string]json.RawMessage, len(replaced))
+	}
+	for key, value := range replaced {
+		fields[key] = value
+	}
+
+	return json.Marshal(fields)
+}
+
+// startCapture начинает запись отправок текущего или следующего теста в файл
+func (api *API) startCapture(c *gin.Context) {
+	var req CaptureStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.File == "" {
+		req.File = fmt.Sprintf("capture-%s.jsonl", time.Now().Format("20060102-150405"))
+	}
+
+	path, err := api.capturePath(req.File)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := api.testManager.StartCapture(path)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// stopCapture завершает запись трафика
+func (api *API) stopCapture(c *gin.Context) {
+	status, err := api.testManager.StopCapture()
+	if status == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// getCapture возвращает состояние записи трафика
+func (api *API) getCapture(c *gin.Context) {
+	status := api.testManager.CaptureStatus()
+	if status == nil {
+		status = &test.CaptureStatus{}
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// capturePath возвращает путь к файлу записи в директории записей
+func (api *API) capturePath(name string) (string, error) {
+	if !captureNamePattern.MatchString(name) {
+		return "", fmt.Errorf("некорректное имя файла записи: %q", name)
+	}
+	return filepath.Join(api.captureDir, name), nil
+}
+
+// getVersion возвращает сведения о сборке, включенных транспортах и конфигурации
+func (api *API) getVersion(c *gin.Context) {
+	info := api.version
+	info.Transports = []string{}
+	for _, protocol := range api.transports.Protocols() {
+		info.Transports = append(info.Transports, string(protocol))
+	}
+	// Распределение значений генератора изменяется без перезапуска
+	info.GeneratorConfigHash = api.generator.ConfigHash()
+
+	c.JSON(http.StatusOK, info)
+}
+
+// getStats получение статистики
+func (api *API) getStats(c *gin.Context) {
+	window, err := utils.ParseStatsWindow(c.Query("window"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	producerStats := api.producer.GetStats()
+	testStats := api.testManager.GetStats()
+
+	running := api.testManager.RunningTests()
+
+	// current_test - тип последнего запущенного из выполняющихся тестов
+	var currentTestType string
+	if len(running) > 0 {
+		currentTestType = string(running[len(running)-1].Type)
+	}
+
+	response := gin.H{
+		"producer":     producerStats, // С запуска процесса
+		"test":         testStats,
+		"active":       len(running) > 0,
+		"current_test": currentTestType,
+		"running":      running,
+		"transports":   api.transports.Stats(),
+	}
+	if window > 0 {
+		response["window"] = api.testManager.WindowStats(window) // За последние window
+	}
+	if lifetime, ok := api.producer.GetLifetimeStats(); ok {
+		response["producer_lifetime"] = lifetime // За все запуски (при mqtt.stats_file)
+	}
+	if api.audit != nil {
+		response["audit"] = api.audit.Stats()
+	}
+	if api.prober != nil {
+		response["health_probe"] = api.prober.Stats()
+	}
+	if api.notifier != nil {
+		response["webhooks"] = api.notifier.Stats()
+	}
+	if api.agent != nil {
+		response["cluster"] = api.agent.Stats()
+	}
+
+	// Кеш данных и заполнение диска директории данных: при нехватке памяти или места
+	// генерация и загрузка наборов данных завершаются ошибкой
+	response["generator"] = gin.H{
+		"cache":     api.generator.CacheStats(),
+		"retention": api.generator.RetentionStats(),
+	}
+	if usage, err := utils.GetDiskUsage(api.generator.DataPath()); err != nil {
+		response["disk"] = gin.H{"path": api.generator.DataPath(), "error": err.Error()}
+	} else {
+		response["disk"] = usage
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// getTestResult возвращает результат выполняющегося или завершенного теста в JSON
+func (api *API) getTestResult(c *gin.Context) {
+	result, ok := api.testManager.GetResult(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "тест не найден"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// getTestReport формирует отчет о тесте в формате HTML или CSV
+func (api *API) getTestReport(c *gin.Context) {
+	format, err := report.ParseFormat(c.DefaultQuery("format", string(report.FormatHTML)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, ok := api.testManager.GetResult(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "тест не найден"})
+		return
+	}
+
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, report.FileName(result, format)))
+	c.Status(http.StatusOK)
+
+	if err := report.Render(c.Writer, result, format); err != nil {
+		api.logger.Error("Ошибка формирования отчета",
+			zap.String("test_id", result.ID),
+			zap.Error(err))
+	}
+}
+
+// requireLead проверяет, что sender ведущий; иначе отвечает 404
+func (api *API) requireLead(c *gin.Context) bool {
+	if api.cluster == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "sender не является ведущим (cluster.lead)"})
+		return false
+	}
+	return true
+}
+
+// listClusterNodes возвращает sender, зарегистрированные у ведущего
+func (api *API) listClusterNodes(c *gin.Context) {
+	if !api.requireLead(c) {
+		return
+	}
+
+	nodes := api.cluster.Registry().Nodes()
+	c.JSON(http.StatusOK, gin.H{"count": len(nodes), "nodes": nodes})
+}
+
+// registerClusterNode регистрирует sender у ведущего или подтверждает регистрацию;
+// подтверждения не записываются в журнал действий
+func (api *API) registerClusterNode(c *gin.Context) {
+	if !api.requireLead(c) {
+		return
+	}
+
+	var reg cluster.Registration
+	if err := c.ShouldBindJSON(&reg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	node, created, err := api.cluster.Registry().Register(reg)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if created {
+		api.logger.Info("Sender зарегистрирован в кластере",
+			zap.String("node_id", node.ID),
+			zap.String("url", node.URL))
+	} else {
+		actionlog.Skip(c.Request.Context())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"node":               node,
+		"heartbeat_interval": api.cluster.Registry().HeartbeatInterval().String(),
+	})
+}
+
+// removeClusterNode снимает регистрацию sender
+func (api *API) removeClusterNode(c *gin.Context) {
+	if !api.requireLead(c) {
+		return
+	}
+
+	if !api.cluster.Registry().Remove(c.Param("id")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("sender %s не зарегистрирован", c.Param("id"))})
+		return
+	}
+	api.logger.Info("Регистрация sender в кластере снята", zap.String("node_id", c.Param("id")))
+
+	c.JSON(http.StatusOK, gin.H{"status": "removed"})
+}
+
+// startClusterTest запускает тест type (stream или batch) на sender кластера: total_messages
+// или messages_per_sec запроса делится между sender, тесты запускаются одновременно
+// через start_delay. Параметр nodes выбирает sender через запятую (по умолчанию все)
+func (api *API) startClusterTest(c *gin.Context) {
+	if !api.requireLead(c) {
+		return
+	}
+
+	delay := time.Duration(-1)
+	if value := c.Query("start_delay"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("некорректная задержка запуска start_delay: %q", value)})
+			return
+		}
+		delay = parsed
+	}
+	var nodes []string
+	if value := c.Query("nodes"); value != "" {
+		nodes = strings.Split(value, ",")
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	run, err := api.cluster.Start(models.TestType(c.Param("type")), body, nodes, delay)
+	if err != nil {
+		if run != nil {
+			// Запросы отправлены, но ни один sender не запустил тест
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "run": run})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	actionlog.Annotate(c.Request.Context(), "run_id", run.ID)
+
+	c.JSON(http.StatusOK, gin.H{"status": "started", "run": run})
+}
+
+// listClusterRuns возвращает согласованные тесты без результатов
+func (api *API) listClusterRuns(c *gin.Context) {
+	if !api.requireLead(c) {
+		return
+	}
+
+	runs := api.cluster.Runs()
+	c.JSON(http.StatusOK, gin.H{"count": len(runs), "runs": runs})
+}
+
+// getClusterRun возвращает объединенный результат согласованного теста
+func (api *API) getClusterRun(c *gin.Context) {
+	if !api.requireLead(c) {
+		return
+	}
+
+	result, err := api.cluster.Result(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// stopClusterRun останавливает согласованный тест на всех sender
+func (api *API) stopClusterRun(c *gin.Context) {
+	if !api.requireLead(c) {
+		return
+	}
+
+	failures, err := api.cluster.Stop(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if len(failures) > 0 {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "тест не остановлен на части sender", "failures": failures})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "stopped"})
+}
+
+// generateData генерация тестовых данных
+func (api *API) generateData(c *gin.Context) {
+	var req GenerateDataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	go func() {
+		switch req.Type {
+		case "all":
+			if err := api.generator.GenerateAllTestData(); err != nil {
+				api.logger.Error("Ошибка генерации всех данных", zap.Error(err))
+			}
+		case "small":
+			if err := api.generator.GenerateSmallBatches(); err != nil {
+				api.logger.Error("Ошибка генерации маленьких пакетов", zap.Error(err))
+			}
+		case "medium":
+			if err := api.generator.GenerateMediumBatches(); err != nil {
+				api.logger.Error("Ошибка генерации средних пакетов", zap.Error(err))
+			}
+		case "large":
+			if err := api.generator.GenerateLargeBatches(); err != nil {
+				api.logger.Error("Ошибка генерации больших пакетов", zap.Error(err))
+			}
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{"status": "generation started"})
+}
+
+// listDatasets возвращает сгенерированные наборы данных и итоговую статистику
+func (api *API) listDatasets(c *gin.Context) {
+	datasets, err := api.generator.ListDatasets()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"datasets": datasets,
+		"stats":    generator.SummarizeDatasets(datasets),
+	})
+}
+
+// sampleDataset отдает первые записи файла набора данных в формате JSON Lines
+func (api *API) sampleDataset(c *gin.Context) {
+	set, name := c.Param("set"), c.Param("name")
+	if err := generator.ValidateDataset(set, name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	count := 10
+	if value := c.Query("count"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "count должен быть от 1 до 1000"})
+			return
+		}
+		count = n
+	}
+
+	sample, err := api.generator.SampleDataset(set, name, count)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, generator.ErrDatasetNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=sample_%s_%s", set, name))
+	c.Data(http.StatusOK, "application/x-ndjson", sample)
+}
+
+// deleteDataset удаляет файл набора данных или весь набор
+func (api *API) deleteDataset(c *gin.Context) {
+	set, name := c.Param("set"), c.Param("name")
+	if err := generator.ValidateDataset(set, name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if api.TestsRunning() {
+		c.JSON(http.StatusConflict, gin.H{"error": "наборы данных нельзя удалять во время теста"})
+		return
+	}
+
+	deleted, err := api.generator.DeleteDataset(set, name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, generator.ErrDatasetNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "deleted": deleted})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
+// deleteData удаляет файлы наборов данных по типу набора type (через запятую) и
+// возрасту older_than (длительность, например 24h); задается хотя бы один параметр
+func (api *API) deleteData(c *gin.Context) {
+	var filter generator.DatasetFilter
+	if value := c.Query("type"); value != "" {
+		for _, set := range strings.Split(value, ",") {
+			set = strings.TrimSpace(set)
+			if err := generator.ValidateDataset(set, ""); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			filter.Sets = append(filter.Sets, set)
+		}
+	}
+	if value := c.Query("older_than"); value != "" {
+		age, err := time.ParseDuration(value)
+		if err != nil || age <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "older_than должен быть положительной длительностью, например 24h"})
+			return
+		}
+		filter.Before = time.Now().Add(-age)
+	}
+	if len(filter.Sets) == 0 && filter.Before.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "не задан отбор: type и/или older_than"})
+		return
+	}
+
+	if api.TestsRunning() {
+		c.JSON(http.StatusConflict, gin.H{"error": "наборы данных нельзя удалять во время теста"})
+		return
+	}
+
+	result, err := api.generator.DeleteDatasets(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "deleted": result.Deleted})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// TestsRunning сообщает, выполняются ли тесты
+func (api *API) TestsRunning() bool {
+	api.mu.RLock()
+	defer api.mu.RUnlock()
+	return len(api.running) > 0
+}
+
+// setupDebugRoutes подключает обработчики профилирования net/http/pprof
+func (api *API) setupDebugRoutes() {
+	debug := api.router.Group("/debug/pprof")
+	{
+		debug.GET("/", gin.WrapF(pprof.Index))
+		debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/profile", gin.WrapF(pprof.Profile))
+		debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/trace", gin.WrapF(pprof.Trace))
+		debug.GET("/:name", gin.WrapF(pprof.Index)) // heap, goroutine, block, mutex, allocs
+	}
+
+	api.logger.Warn("Включены обработчики профилирования /debug/pprof/")
+}
+
+// prometheusMetrics возвращает метрики в формате Prometheus
+func (api *API) prometheusMetrics(c *gin.Context) {
+	if !api.metricsEnabled.Load() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "экспорт метрик отключен"})
+		return
+	}
+
+	stats := api.producer.GetStats()
+
+	c.Header("Content-Type", "text/plain")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "# HELP mqtt_messages_sent_total Total number of messages sent\n")
+	fmt.Fprintf(c.Writer, "# TYPE mqtt_messages_sent_total counter\n")
+	fmt.Fprintf(c.Writer, "mqtt_messages_sent_total %d\n", stats.MessagesPublished)
+
+	fmt.Fprintf(c.Writer, "\n# HELP mqtt_batches_sent_total Total number of message batches sent as a single MQTT message\n")
+	fmt.Fprintf(c.Writer, "# TYPE mqtt_batches_sent_total counter\n")
+	fmt.Fprintf(c.Writer, "mqtt_batches_sent_total %d\n", stats.BatchesPublished)
+
+	if lifetime, ok := api.producer.GetLifetimeStats(); ok {
+		fmt.Fprintf(c.Writer, "\n# HELP lifetime_mqtt_messages_sent_total Total number of messages sent across restarts\n")
+		fmt.Fprintf(c.Writer, "# TYPE lifetime_mqtt_messages_sent_total counter\n")
+		fmt.Fprintf(c.Writer, "lifetime_mqtt_messages_sent_total %d\n", lifetime.MessagesPublished)
+
+		fmt.Fprintf(c.Writer, "\n# HELP lifetime_mqtt_bytes_sent_total Total number of bytes sent across restarts\n")
+		fmt.Fprintf(c.Writer, "# TYPE lifetime_mqtt_bytes_sent_total counter\n")
+		fmt.Fprintf(c.Writer, "lifetime_mqtt_bytes_sent_total %d\n", lifetime.BytesSent)
+
+		fmt.Fprintf(c.Writer, "\n# HELP lifetime_restarts Number of restarts since lifetime statistics started\n")
+		fmt.Fprintf(c.Writer, "# TYPE lifetime_restarts gauge\n")
+		fmt.Fprintf(c.Writer, "lifetime_restarts %d\n", lifetime.Restarts)
+	}
+
+	if stats.Queue != nil {
+		fmt.Fprintf(c.Writer, "\n# HELP mqtt_queue_depth Number of messages in the outbound disk queue\n")
+		fmt.Fprintf(c.Writer, "# TYPE mqtt_queue_depth gauge\n")
+		fmt.Fprintf(c.Writer, "mqtt_queue_depth %d\n", stats.Queue.Depth)
+
+		fmt.Fprintf(c.Writer, "\n# HELP mqtt_queue_bytes Size of messages in the outbound disk queue\n")
+		fmt.Fprintf(c.Writer, "# TYPE mqtt_queue_bytes gauge\n")
+		fmt.Fprintf(c.Writer, "mqtt_queue_bytes %d\n", stats.Queue.Bytes)
+
+		fmt.Fprintf(c.Writer, "\n# HELP mqtt_queue_oldest_age_seconds Age of the oldest message in the outbound disk queue\n")
+		fmt.Fprintf(c.Writer, "# TYPE mqtt_queue_oldest_age_seconds gauge\n")
+		fmt.Fprintf(c.Writer, "mqtt_queue_oldest_age_seconds %.3f\n", float64(stats.Queue.OldestAgeMs)/1000)
+
+		fmt.Fprintf(c.Writer, "\n# HELP mqtt_queue_replayed_total Total number of messages sent from the outbound disk queue\n")
+		fmt.Fprintf(c.Writer, "# TYPE mqtt_queue_replayed_total counter\n")
+		fmt.Fprintf(c.Writer, "mqtt_queue_replayed_total %d\n", stats.Queue.Replayed)
+	}
+
+	fmt.Fprintf(c.Writer, "\n# HELP mqtt_store_outbound_messages Number of unacknowledged outbound messages in the MQTT client store\n")
+	fmt.Fprintf(c.Writer, "# TYPE mqtt_store_outbound_messages gauge\n")
+	fmt.Fprintf(c.Writer, "mqtt_store_outbound_messages{store=\"%s\"} %d\n", stats.Client.Store.Type, stats.Client.Store.Outbound)
+
+	fmt.Fprintf(c.Writer, "\n# HELP mqtt_store_inbound_messages Number of incomplete inbound messages in the MQTT client store\n")
+	fmt.Fprintf(c.Writer, "# TYPE mqtt_store_inbound_messages gauge\n")
+	fmt.Fprintf(c.Writer, "mqtt_store_inbound_messages{store=\"%s\"} %d\n", stats.Client.Store.Type, stats.Client.Store.Inbound)
+
+	fmt.Fprintf(c.Writer, "\n# HELP mqtt_pending_tokens Number of QoS 1/2 publications awaiting broker acknowledgement\n")
+	fmt.Fprintf(c.Writer, "# TYPE mqtt_pending_tokens gauge\n")
+	fmt.Fprintf(c.Writer, "mqtt_pending_tokens %d\n", stats.Client.PendingTokens)
+
+	fmt.Fprintf(c.Writer, "\n# HELP mqtt_timed_out_tokens Number of pending publications that exceeded the acknowledgement timeout\n")
+	fmt.Fprintf(c.Writer, "# TYPE mqtt_timed_out_tokens gauge\n")
+	fmt.Fprintf(c.Writer, "mqtt_timed_out_tokens %d\n", stats.Client.TimedOutTokens)
+
+	fmt.Fprintf(c.Writer, "\n# HELP mqtt_session_resumes_total Total number of reconnects with unacknowledged messages in the client store\n")
+	fmt.Fprintf(c.Writer, "# TYPE mqtt_session_resumes_total counter\n")
+	fmt.Fprintf(c.Writer, "mqtt_session_resumes_total %d\n", stats.Client.Resumes)
+
+	if t, err := api.transports.Get(models.ProtocolTCP); err == nil {
+		if tcpStats, ok := t.Stats().(tcp.ClientStats); ok {
+			connected := 0
+			if tcpStats.Connected {
+				connected = 1
+			}
+			fmt.Fprintf(c.Writer, "\n# HELP tcp_connected Whether the TCP client is connected to the recipient\n")
+			fmt.Fprintf(c.Writer, "# TYPE tcp_connected gauge\n")
+			fmt.Fprintf(c.Writer, "tcp_connected %d\n", connected)
+
+			fmt.Fprintf(c.Writer, "\n# HELP tcp_reconnects_total Total number of successful TCP reconnects\n")
+			fmt.Fprintf(c.Writer, "# TYPE tcp_reconnects_total counter\n")
+			fmt.Fprintf(c.Writer, "tcp_reconnects_total %d\n", tcpStats.ReconnectCount)
+
+			fmt.Fprintf(c.Writer, "\n# HELP tcp_reconnect_attempts_total Total number of TCP reconnect attempts\n")
+			fmt.Fprintf(c.Writer, "# TYPE tcp_reconnect_attempts_total counter\n")
+			fmt.Fprintf(c.Writer, "tcp_reconnect_attempts_total %d\n", tcpStats.ReconnectAttempts)
+		}
+	}
+
+	if api.audit != nil {
+		fmt.Fprintf(c.Writer, "\n# HELP audit_digests_received_total Total number of digests received from audit channel\n")
+		fmt.Fprintf(c.Writer, "# TYPE audit_digests_received_total counter\n")
+		fmt.Fprintf(c.Writer, "audit_digests_received_total %d\n", api.audit.Stats().Received)
+	}
+
+	if api.prober != nil {
+		probeStats := api.prober.Stats()
+
+		fmt.Fprintf(c.Writer, "\n# HELP health_probes_sent_total Total number of health probes acknowledged by the broker\n")
+		fmt.Fprintf(c.Writer, "# TYPE health_probes_sent_total counter\n")
+		fmt.Fprintf(c.Writer, "health_probes_sent_total %d\n", probeStats.Sent)
+
+		fmt.Fprintf(c.Writer, "\n# HELP health_probe_failures_total Total number of health probes not acknowledged by the broker\n")
+		fmt.Fprintf(c.Writer, "# TYPE health_probe_failures_total counter\n")
+		fmt.Fprintf(c.Writer, "health_probe_failures_total %d\n", probeStats.Failures)
+
+		fmt.Fprintf(c.Writer, "\n# HELP health_probe_rtt_ms Time from publishing the last acknowledged health probe to the broker acknowledgement\n")
+		fmt.Fprintf(c.Writer, "# TYPE health_probe_rtt_ms gauge\n")
+		fmt.Fprintf(c.Writer, "health_probe_rtt_ms %.3f\n", probeStats.LastRTTMs)
+	}
+
+	cache := api.generator.CacheStats()
+	fmt.Fprintf(c.Writer, "\n# HELP generator_cache_records Number of data records loaded into the generator cache\n")
+	fmt.Fprintf(c.Writer, "# TYPE generator_cache_records gauge\n")
+	fmt.Fprintf(c.Writer, "generator_cache_records %d\n", cache.Records)
+
+	fmt.Fprintf(c.Writer, "\n# HELP generator_cache_bytes Estimated memory used by the generator cache\n")
+	fmt.Fprintf(c.Writer, "# TYPE generator_cache_bytes gauge\n")
+	fmt.Fprintf(c.Writer, "generator_cache_bytes %d\n", cache.Bytes)
+
+	fmt.Fprintf(c.Writer, "\n# HELP generator_mapped_files Number of dataset files memory-mapped by running tests\n")
+	fmt.Fprintf(c.Writer, "# TYPE generator_mapped_files gauge\n")
+	fmt.Fprintf(c.Writer, "generator_mapped_files %d\n", cache.MappedFiles)
+
+	fmt.Fprintf(c.Writer, "\n# HELP generator_mapped_bytes Total size of dataset files memory-mapped by running tests\n")
+	fmt.Fprintf(c.Writer, "# TYPE generator_mapped_bytes gauge\n")
+	fmt.Fprintf(c.Writer, "generator_mapped_bytes %d\n", cache.MappedBytes)
+
+	retention := api.generator.RetentionStats()
+	fmt.Fprintf(c.Writer, "\n# HELP generator_retention_deleted_files_total Dataset files deleted by the retention policy\n")
+	fmt.Fprintf(c.Writer, "# TYPE generator_retention_deleted_files_total counter\n")
+	fmt.Fprintf(c.Writer, "generator_retention_deleted_files_total %d\n", retention.DeletedFiles)
+
+	fmt.Fprintf(c.Writer, "\n# HELP generator_retention_freed_bytes_total Bytes freed by deleting dataset files under the retention policy\n")
+	fmt.Fprintf(c.Writer, "# TYPE generator_retention_freed_bytes_total counter\n")
+	fmt.Fprintf(c.Writer, "generator_retention_freed_bytes_total %d\n", retention.FreedBytes)
+
+	if usage, err := utils.GetDiskUsage(api.generator.DataPath()); err == nil {
+		fmt.Fprintf(c.Writer, "\n# HELP data_disk_free_bytes Free space available on the data path file system\n")
+		fmt.Fprintf(c.Writer, "# TYPE data_disk_free_bytes gauge\n")
+		fmt.Fprintf(c.Writer, "data_disk_free_bytes %d\n", usage.FreeBytes)
+
+		fmt.Fprintf(c.Writer, "\n# HELP data_disk_used_percent Used space of the data path file system in percent\n")
+		fmt.Fprintf(c.Writer, "# TYPE data_disk_used_percent gauge\n")
+		fmt.Fprintf(c.Writer, "data_disk_used_percent %.2f\n", usage.UsedPercent)
+	}
+
+	api.testManager.SendLatency().WriteText(c.Writer, "send_latency_ms", sendLatencyHelp)
+
+	utils.WriteRuntimeMetrics(c.Writer)
+}
+
+// sendLatencyHelp описание гистограммы задержки отправки
+const sendLatencyHelp = "Message send latency from send_time to broker acknowledgement or socket write in milliseconds"
+
+// nativeMetrics гистограмма задержки отправки в формате protobuf: классические и нативные корзины Prometheus
+func (api *API) nativeMetrics(c *gin.Context) {
+	if !api.metricsEnabled.Load() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "экспорт метрик отключен"})
+		return
+	}
+
+	c.Header("Content-Type", utils.ProtoContentType)
+	c.Status(http.StatusOK)
+	if err := api.testManager.SendLatency().WriteProto(c.Writer, "send_latency_ms", sendLatencyHelp); err != nil {
+		api.logger.Warn("Ошибка вывода гистограммы задержки отправки", zap.Error(err))
+	}
+}
+
+// SetMetricsEnabled включает или отключает экспорт метрик
+func (api *API) SetMetricsEnabled(enabled bool) {
+	api.metricsEnabled.Store(enabled)
+}
+
+// Start запускает HTTP сервер
+func (api *API) Start() error {
+	api.logger.Info("Запуск HTTP API сервера",
+		zap.String("addr", api.server.Addr),
+		zap.Bool("tls", api.certFile != ""))
+	return utils.ListenAndServe(api.server, api.certFile, api.keyFile)
+}
+
+// Shutdown корректно останавливает HTTP сервер
+func (api *API) Shutdown(ctx context.Context) error {
+	api.logger.Info("Остановка HTTP API сервера")
+	return api.server.Shutdown(ctx)
+}
+
+// Request structures
+
+// BatchTestRequest запрос на запуск пакетного теста
+type BatchTestRequest struct {
+	Protocol      models.TestProtocol `json:"protocol" binding:"omitempty,oneof=mqtt tcp quic nats serial"`
+	Target        string              `json:"target"`
+	MQTT          *models.MQTTOptions `json:"mqtt"`
+	ThreadCount   int                 `json:"thread_count" binding:"required,min=1,max=1000"`
+	PacketSize    int                 `json:"packet_size" binding:"required,min=100"`
 	TotalMessages int                 `json:"total_messages" binding:"required,min=1"`
 	Duration      int                 `json:"duration" binding:"required,min=1"`
+	WarmupSeconds int                 `json:"warmup_seconds" binding:"min=0,max=600"`
+	PadToSize     bool                `json:"pad_to_size"`
+
+	InvalidPercent  float64             `json:"invalid_percent" binding:"min=0,max=100"`
+	CorruptionKinds []string            `json:"corruption_kinds"`
+	MessageTTL      int                 `json:"message_ttl_ms" binding:"min=0,max=3600000"`
+	Seed            int64               `json:"seed"`
+	Chaos           *models.ChaosConfig `json:"chaos"`
+
+	DataSource *models.DataSource `json:"data_source"`
+
+	Tenant   string     `json:"tenant"`
+	RunLabel string     `json:"run_label"`
+	StartAt  *time.Time `json:"start_at"`
 }
 
 // StreamTestRequest запрос на запуск потокового теста
 type StreamTestRequest struct {
-	Protocol       models.TestProtocol `json:"protocol" binding:"omitempty,oneof=mqtt tcp"`
+	Protocol       models.TestProtocol `json:"protocol" binding:"omitempty,oneof=mqtt tcp quic nats serial"`
+	Target         string              `json:"target"`
+	MQTT           *models.MQTTOptions `json:"mqtt"`
 	MessagesPerSec int                 `json:"messages_per_sec" binding:"required,min=1,max=100000"`
 	PacketSize     int                 `json:"packet_size" binding:"required,min=100"`
 	Duration       int                 `json:"duration" binding:"required,min=1"`
+	WarmupSeconds  int                 `json:"warmup_seconds" binding:"min=0,max=600"`
+	PadToSize      bool                `json:"pad_to_size"`
+
+	InvalidPercent  float64             `json:"invalid_percent" binding:"min=0,max=100"`
+	CorruptionKinds []string            `json:"corruption_kinds"`
+	MessageTTL      int                 `json:"message_ttl_ms" binding:"min=0,max=3600000"`
+	SendRetries     int                 `json:"send_retries" binding:"min=0,max=10"`
+	Seed            int64               `json:"seed"`
+	Chaos           *models.ChaosConfig `json:"chaos"`
+
+	DataSource *models.DataSource `json:"data_source"`
+
+	Tenant   string     `json:"tenant"`
+	RunLabel string     `json:"run_label"`
+	StartAt  *time.Time `json:"start_at"`
 }
 
 // LargeTestRequest запрос на запуск теста с большими пакетами
 type LargeTestRequest struct {
-	Protocol     models.TestProtocol `json:"protocol" binding:"omitempty,oneof=mqtt tcp"`
-	ThreadCount  int                 `json:"thread_count" binding:"required,min=1,max=100"`
-	PacketSizeMB int                 `json:"packet_size_mb" binding:"required,min=1,max=1000"`
-	Duration     int                 `json:"duration" binding:"required,min=1"`
+	Protocol      models.TestProtocol `json:"protocol" binding:"omitempty,oneof=mqtt tcp quic nats serial"`
+	Target        string              `json:"target"`
+	MQTT          *models.MQTTOptions `json:"mqtt"`
+	ThreadCount   int                 `json:"thread_count" binding:"required,min=1,max=100"`
+	PacketSizeMB  int                 `json:"packet_size_mb" binding:"required,min=1,max=1000"`
+	Duration      int                 `json:"duration" binding:"required,min=1"`
+	WarmupSeconds int                 `json:"warmup_seconds" binding:"min=0,max=600"`
+	PadToSize     bool                `json:"pad_to_size"`
+	MessageTTL    int                 `json:"message_ttl_ms" binding:"min=0,max=3600000"`
+	SendRetries   int                 `json:"send_retries" binding:"min=0,max=10"`
+	Chaos         *models.ChaosConfig `json:"chaos"`
+
+	DataSource *models.DataSource `json:"data_source"`
+
+	Tenant   string     `json:"tenant"`
+	RunLabel string     `json:"run_label"`
+	StartAt  *time.Time `json:"start_at"`
+}
+
+// DiscoveryTestRequest запрос на поиск максимальной пропускной способности
+type DiscoveryTestRequest struct {
+	Protocol       models.TestProtocol `json:"protocol" binding:"omitempty,oneof=mqtt tcp quic nats serial"`
+	StartRate      int                 `json:"start_rate" binding:"required,min=1,max=100000"`
+	StepRate       int                 `json:"step_rate" binding:"required,min=1,max=100000"`
+	MaxRate        int                 `json:"max_rate" binding:"required,min=1,max=100000"`
+	StepDuration   int                 `json:"step_duration" binding:"required,min=1,max=600"`
+	SettleTime     int                 `json:"settle_time" binding:"min=0,max=120"`
+	PacketSize     int                 `json:"packet_size" binding:"required,min=100"`
+	MaxLossPercent float64             `json:"max_loss_percent" binding:"min=0,max=100"`
+	MaxLatencyMs   float64             `json:"max_latency_ms" binding:"min=0"`
+	WarmupSeconds  int                 `json:"warmup_seconds" binding:"min=0,max=600"`
+	PadToSize      bool                `json:"pad_to_size"`
+
+	DataSource *models.DataSource `json:"data_source"`
+
+	Tenant   string     `json:"tenant"`
+	RunLabel string     `json:"run_label"`
+	StartAt  *time.Time `json:"start_at"`
+}
+
+// Ограничения перебора размеров сообщений
+const (
+	minSweepSize  = 256              // Наименьший размер сообщения шага, байт
+	maxSweepSize  = 64 * 1024 * 1024 // Наибольший размер сообщения шага, байт
+	maxSweepSteps = 64               // Наибольшее количество шагов
+)
+
+// SweepTestRequest запрос на перебор размеров сообщений. Размеры задаются списком sizes
+// либо диапазоном min_size..max_size с множителем factor (по умолчанию 512 байт - 1 MB, x2)
+type SweepTestRequest struct {
+	Protocol      models.TestProtocol `json:"protocol" binding:"omitempty,oneof=mqtt tcp quic nats serial"`
+	Target        string              `json:"target"`
+	MQTT          *models.MQTTOptions `json:"mqtt"`
+	ThreadCount   int                 `json:"thread_count" binding:"required,min=1,max=100"`
+	MinSize       int                 `json:"min_size" binding:"omitempty,min=256,max=67108864"`
+	MaxSize       int                 `json:"max_size" binding:"omitempty,min=256,max=67108864"`
+	Factor        float64             `json:"factor" binding:"omitempty,gt=1,max=16"`
+	Sizes         []int               `json:"sizes"`
+	StepDuration  int                 `json:"step_duration" binding:"required,min=1,max=600"`
+	SettleTime    int                 `json:"settle_time" binding:"min=0,max=120"`
+	WarmupSeconds int                 `json:"warmup_seconds" binding:"min=0,max=600"`
+	MessageTTL    int                 `json:"message_ttl_ms" binding:"min=0,max=3600000"`
+	SendRetries   int                 `json:"send_retries" binding:"min=0,max=10"`
+
+	DataSource *models.DataSource `json:"data_source"`
+
+	Tenant   string     `json:"tenant"`
+	RunLabel string     `json:"run_label"`
+	StartAt  *time.Time `json:"start_at"`
+}
+
+// SessionTestRequest запрос на проверку восстановления MQTT сессии
+type SessionTestRequest struct {
+	MessagesPerSec int `json:"messages_per_sec" binding:"required,min=1,max=100000"`
+	PacketSize     int `json:"packet_size" binding:"required,min=100"`
+	Interruptions  int `json:"interruptions" binding:"required,min=1,max=100"`
+	Interval       int `json:"interval" binding:"required,min=1,max=600"`
+	PauseDuration  int `json:"pause_duration" binding:"required,min=1,max=600"`
+	SettleTime     int `json:"settle_time" binding:"min=0,max=120"`
+	WarmupSeconds  int `json:"warmup_seconds" binding:"min=0,max=600"`
+
+	DataSource *models.DataSource `json:"data_source"`
+
+	Tenant   string     `json:"tenant"`
+	RunLabel string     `json:"run_label"`
+	StartAt  *time.Time `json:"start_at"`
+}
+
+// ExactlyOnceTestRequest запрос на проверку доставки ровно один раз
+type ExactlyOnceTestRequest struct {
+	MessagesPerSec int `json:"messages_per_sec" binding:"required,min=1,max=100000"`
+	PacketSize     int `json:"packet_size" binding:"required,min=100"`
+	Duration       int `json:"duration" binding:"required,min=1,max=3600"`
+	SettleTime     int `json:"settle_time" binding:"min=0,max=120"`
+	WarmupSeconds  int `json:"warmup_seconds" binding:"min=0,max=600"`
+
+	DataSource *models.DataSource `json:"data_source"`
+
+	Tenant   string     `json:"tenant"`
+	RunLabel string     `json:"run_label"`
+	StartAt  *time.Time `json:"start_at"`
+}
+
+// MQTTFeaturesTestRequest запрос на проверку retained сообщений и last will
+type MQTTFeaturesTestRequest struct {
+	RetainedMarkers int  `json:"retained_markers" binding:"min=0,max=1000"`
+	TriggerWill     bool `json:"trigger_will"`
+	SettleTime      int  `json:"settle_time" binding:"min=0,max=120"`
+
+	DataSource *models.DataSource `json:"data_source"`
+
+	Tenant   string     `json:"tenant"`
+	RunLabel string     `json:"run_label"`
+	StartAt  *time.Time `json:"start_at"`
+}
+
+// MixedTestRequest запрос на запуск смешанного теста MQTT и TCP
+type MixedTestRequest struct {
+	ThreadCount   int     `json:"thread_count" binding:"required,min=2,max=1000"`
+	MQTTPercent   float64 `json:"mqtt_percent" binding:"required,gt=0,lt=100"`
+	PacketSize    int     `json:"packet_size" binding:"required,min=100"`
+	TotalMessages int     `json:"total_messages" binding:"required,min=1"`
+	Duration      int     `json:"duration" binding:"required,min=1"`
+	WarmupSeconds int     `json:"warmup_seconds" binding:"min=0,max=600"`
+	PadToSize     bool    `json:"pad_to_size"`
+
+	InvalidPercent  float64             `json:"invalid_percent" binding:"min=0,max=100"`
+	CorruptionKinds []string            `json:"corruption_kinds"`
+	MessageTTL      int                 `json:"message_ttl_ms" binding:"min=0,max=3600000"`
+	Seed            int64               `json:"seed"`
+	Chaos           *models.ChaosConfig `json:"chaos"`
+
+	DataSource *models.DataSource `json:"data_source"`
+
+	Tenant   string     `json:"tenant"`
+	RunLabel string     `json:"run_label"`
+	StartAt  *time.Time `json:"start_at"`
+}
+
+// FanoutTestRequest запрос на одновременную отправку потока в несколько точек назначения
+type FanoutTestRequest struct {
+	Destinations   []DestinationRequest `json:"destinations" binding:"required,min=1,max=16,dive"`
+	MessagesPerSec int                  `json:"messages_per_sec" binding:"required,min=1,max=100000"`
+	PacketSize     int                  `json:"packet_size" binding:"required,min=100"`
+	Duration       int                  `json:"duration" binding:"required,min=1"`
+	WarmupSeconds  int                  `json:"warmup_seconds" binding:"min=0,max=600"`
+	PadToSize      bool                 `json:"pad_to_size"`
+
+	InvalidPercent  float64  `json:"invalid_percent" binding:"min=0,max=100"`
+	CorruptionKinds []string `json:"corruption_kinds"`
+	MessageTTL      int      `json:"message_ttl_ms" binding:"min=0,max=3600000"`
+	Seed            int64    `json:"seed"`
+
+	DataSource *models.DataSource `json:"data_source"`
+
+	Tenant   string     `json:"tenant"`
+	RunLabel string     `json:"run_label"`
+	StartAt  *time.Time `json:"start_at"`
+}
+
+// DestinationRequest точка назначения теста fan-out: топик MQTT или адрес TCP или QUIC сервера
+type DestinationRequest struct {
+	Protocol models.TestProtocol `json:"protocol" binding:"required,oneof=mqtt tcp quic"`
+	Target   string              `json:"target" binding:"required"`
+}
+
+// ReplayTestRequest запрос на воспроизведение записанного трафика
+type ReplayTestRequest struct {
+	Capture  string              `json:"capture" binding:"required"`
+	Protocol models.TestProtocol `json:"protocol" binding:"omitempty,oneof=mqtt tcp quic nats serial"`
+	Speed    float64             `json:"speed" binding:"omitempty,gt=0,max=100"`
+
+	Tenant   string     `json:"tenant"`
+	RunLabel string     `json:"run_label"`
+	StartAt  *time.Time `json:"start_at"`
+}
+
+// FileTestRequest запрос на передачу файла; передается файл из tests.files_directory
+// или сгенерированный из seed файл размером size_mb
+type FileTestRequest struct {
+	Protocol       models.TestProtocol `json:"protocol" binding:"omitempty,oneof=mqtt tcp quic nats serial"`
+	Target         string              `json:"target"`
+	MQTT           *models.MQTTOptions `json:"mqtt"`
+	File           string              `json:"file"`
+	SizeMB         int                 `json:"size_mb" binding:"min=0,max=4096"`
+	ChunkSize      int                 `json:"chunk_size" binding:"omitempty,min=1024,max=524288"`
+	MessagesPerSec int                 `json:"messages_per_sec" binding:"min=0,max=100000"`
+	Timeout        int                 `json:"timeout" binding:"min=0,max=86400"`
+	SettleTime     int                 `json:"settle_time" binding:"min=0,max=600"`
+	Seed           int64               `json:"seed"`
+
+	Tenant   string     `json:"tenant"`
+	RunLabel string     `json:"run_label"`
+	StartAt  *time.Time `json:"start_at"`
+}
+
+// RawTestRequest запрос на насыщение канала TCP кадрами-заполнителями; без target
+// используется адрес tcp.address конфигурации, по соединению на каждый из connections
+type RawTestRequest struct {
+	Target        string `json:"target"`
+	FrameSize     int    `json:"frame_size" binding:"required,min=64,max=16777216"`
+	Connections   int    `json:"connections" binding:"min=0,max=64"`
+	FramesPerSec  int    `json:"frames_per_sec" binding:"min=0,max=1000000"`
+	Duration      int    `json:"duration" binding:"required,min=1,max=3600"`
+	WarmupSeconds int    `json:"warmup_seconds" binding:"min=0,max=600"`
+	Seed          int64  `json:"seed"`
+
+	Tenant   string     `json:"tenant"`
+	RunLabel string     `json:"run_label"`
+	StartAt  *time.Time `json:"start_at"`
+}
+
+// TemplateRequest запрос на сохранение шаблона теста
+type TemplateRequest struct {
+	Type        models.TestType `json:"type" binding:"required"`    // Тип теста (batch, stream, large, ...)
+	Description string          `json:"description"`                // Описание шаблона
+	Request     json.RawMessage `json:"request" binding:"required"` // Тело запроса запуска теста
+}
+
+// StopTestRequest запрос на остановку теста; без test_id останавливаются все выполняющиеся тесты
+type StopTestRequest struct {
+	TestID string `json:"test_id"`
+}
+
+// CaptureStartRequest запрос на начало записи трафика
+type CaptureStartRequest struct {
+	File string `json:"file"` // Имя файла в директории записей (по умолчанию по текущему времени)
 }
 
 // GenerateDataRequest запрос на генерацию данных
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
+)
+
+// HealthPolicy пороги проверок /health (изменяются без перезапуска через SetHealthPolicy)
+type HealthPolicy struct {
+	MinFreeDiskPercent float64 // Минимум свободного места в директориях данных и логов, % (0 - без порога)
+	MaxStoreBacklog    int64   // Предел сообщений в хранилище сессии MQTT без завершения обмена (0 - без порога)
+}
+
+// SetHealthPolicy изменяет пороги проверок /health
+func (api *API) SetHealthPolicy(policy HealthPolicy) {
+	api.healthPolicy.Store(&policy)
+}
+
+// diskCheck проверяет свободное место в директории path: ошибка определения
+// заполнения или свободного места меньше minFreePercent делает проверку неуспешной
+func diskCheck(component, path string, minFreePercent float64) models.Check {
+	check := models.Check{
+		Component: component,
+		Status:    "healthy",
+	}
+
+	usage, err := utils.GetDiskUsage(path)
+	if err != nil {
+		check.Status = "unhealthy"
+		check.Message = fmt.Sprintf("%s: %v", path, err)
+		return check
+	}
+
+	freePercent := 100 - usage.UsedPercent
+	check.Message = fmt.Sprintf("%s: free %.1f%% (%d MB)", path, freePercent, usage.FreeBytes>>20)
+	if minFreePercent > 0 && freePercent < minFreePercent {
+		check.Status = "unhealthy"
+		check.Message += fmt.Sprintf(", below %.1f%%", minFreePercent)
+	}
+	return check
+}
+
+// storeCheck проверяет количество сообщений в хранилище сессии MQTT клиента: рост
+// хранилища означает, что брокер не подтверждает публикации
+func (api *API) storeCheck(maxBacklog int64) models.Check {
+	check := models.Check{
+		Component: "mqtt_store",
+		Status:    "healthy",
+	}
+
+	store := api.producer.ClientStats().Store
+	backlog := store.Outbound + store.Inbound
+	check.Message = fmt.Sprintf("Store: %s, Outbound: %d, Inbound: %d", store.Type, store.Outbound, store.Inbound)
+	if maxBacklog > 0 && backlog > maxBacklog {
+		check.Status = "unhealthy"
+		check.Message += fmt.Sprintf(", above %d", maxBacklog)
+	}
+	return check
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/infodiode/sender/internal/broker"
+	"github.com/infodiode/sender/internal/cluster"
+	"github.com/infodiode/sender/internal/generator"
+	"github.com/infodiode/sender/internal/templates"
+	"github.com/infodiode/sender/internal/test"
+	"github.com/infodiode/sender/internal/webhook"
+	"github.com/infodiode/shared/actionlog"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/openapi"
+	"github.com/infodiode/shared/utils"
+	"go.uber.org/zap"
+)
+
+// Ответы API, которые обработчики формируют через gin.H; типы описывают их состав
+// в документе OpenAPI
+
+// statusResponse ответ с состоянием операции
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+// testStartedResponse ответ на запуск теста
+type testStartedResponse struct {
+	Status string             `json:"status"`
+	TestID string             `json:"test_id"`
+	Config *models.TestConfig `json:"config"`
+}
+
+// testStoppedResponse ответ на остановку тестов
+type testStoppedResponse struct {
+	Status  string   `json:"status"`
+	Stopped []string `json:"stopped"`
+}
+
+// statsResponse ответ /stats
+type statsResponse struct {
+	Producer         broker.ProducerStats          `json:"producer"` // С запуска процесса
+	Test             *models.TestStats             `json:"test"`
+	Active           bool                          `json:"active"`
+	CurrentTest      string                        `json:"current_test"`
+	Running          []test.RunningTest            `json:"running"`
+	Transports       map[models.TestProtocol]any   `json:"transports"`
+	Window           *models.WindowStats           `json:"window,omitempty"`            // При запросе с окном
+	ProducerLifetime *broker.ProducerLifetimeStats `json:"producer_lifetime,omitempty"` // При mqtt.stats_file
+	Audit            *broker.AuditStats            `json:"audit,omitempty"`
+	Webhooks         []webhook.TargetStats         `json:"webhooks,omitempty"`
+	Cluster          *cluster.AgentStats           `json:"cluster,omitempty"`
+	Generator        generatorStats                `json:"generator"`
+	Disk             utils.DiskUsage               `json:"disk"`
+}
+
+// generatorStats раздел generator ответа /stats
+type generatorStats struct {
+	Cache generator.CacheStats `json:"cache"`
+}
+
+// actionLogResponse ответ /audit
+type actionLogResponse struct {
+	Log    actionlog.Status  `json:"log"`
+	Events []actionlog.Event `json:"events"`
+}
+
+// templatesResponse ответ /templates
+type templatesResponse struct {
+	Templates []*templates.Template `json:"templates"`
+}
+
+// templateDeletedResponse ответ на удаление шаблона
+type templateDeletedResponse struct {
+	Status string `json:"status"`
+	Name   string `json:"name"`
+}
+
+// clusterNodesResponse ответ /cluster/nodes
+type clusterNodesResponse struct {
+	Count int            `json:"count"`
+	Nodes []cluster.Node `json:"nodes"`
+}
+
+// clusterRegisteredResponse ответ на регистрацию sender у ведущего
+type clusterRegisteredResponse struct {
+	Node              cluster.Node `json:"node"`
+	HeartbeatInterval string       `json:"heartbeat_interval"`
+}
+
+// clusterStartedResponse ответ на запуск согласованного теста
+type clusterStartedResponse struct {
+	Status string       `json:"status"`
+	Run    *cluster.Run `json:"run"`
+}
+
+// clusterRunsResponse ответ /cluster/runs
+type clusterRunsResponse struct {
+	Count int           `json:"count"`
+	Runs  []cluster.Run `json:"runs"`
+}
+
+// datasetsResponse ответ /datasets
+type datasetsResponse struct {
+	Datasets []generator.Dataset      `json:"datasets"`
+	Stats    generator.GeneratorStats `json:"stats"`
+}
+
+// datasetsDeletedResponse ответ на удаление наборов данных
+type datasetsDeletedResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// testRoutes операции запуска тестов: путь после /test/ и тип запроса
+var testRoutes = []struct {
+	Kind    string
+	Summary string
+	Request any
+}{
+	{"batch", "Пакетный тест", BatchTestRequest{}},
+	{"stream", "Потоковый тест", StreamTestRequest{}},
+	{"large", "Тест больших сообщений", LargeTestRequest{}},
+	{"discovery", "Поиск предельной скорости", DiscoveryTestRequest{}},
+	{"sweep", "Тест серии размеров сообщений", SweepTestRequest{}},
+	{"session", "Тест полноты доставки", SessionTestRequest{}},
+	{"exactly-once", "Тест доставки ровно один раз", ExactlyOnceTestRequest{}},
+	{"mqtt-features", "Тест возможностей MQTT (retained, DUP, last will)", MQTTFeaturesTestRequest{}},
+	{"mixed", "Тест смешанной нагрузки", MixedTestRequest{}},
+	{"fanout", "Тест одновременной отправки несколькими транспортами", FanoutTestRequest{}},
+	{"replay", "Повтор записанного трафика", ReplayTestRequest{}},
+	{"file", "Тест передачи файла", FileTestRequest{}},
+	{"raw", "Отправка произвольных сообщений", RawTestRequest{}},
+}
+
+// openAPIDocument формирует документ OpenAPI API sender версии version
+func openAPIDocument(version string) ([]byte, error) {
+	doc := openapi.New(openapi.Info{
+		Title:       "infodiode sender",
+		Version:     version,
+		Description: "HTTP API генератора нагрузки: запуск тестов, статистика и управление данными",
+	},
+		openapi.Tag{Name: "service", Description: "Состояние и метрики"},
+		openapi.Tag{Name: "tests", Description: "Запуск и результаты тестов"},
+		openapi.Tag{Name: "cluster", Description: "Согласованные тесты нескольких sender"},
+		openapi.Tag{Name: "templates", Description: "Шаблоны тестов"},
+		openapi.Tag{Name: "capture", Description: "Запись трафика"},
+		openapi.Tag{Name: "data", Description: "Генерация и наборы данных"},
+	)
+
+	window := openapi.Param{Name: "window", Description: "Окно показателей: 1s..5m или all"}
+
+	doc.Add(
+		openapi.Route{Method: http.MethodGet, Path: "/health", Tag: "service", Summary: "Состояние sender",
+			Response: models.HealthStatus{}, Errors: []int{http.StatusServiceUnavailable}},
+		openapi.Route{Method: http.MethodGet, Path: "/healthz", Tag: "service", Summary: "Состояние sender с проверкой публикации пробных сообщений",
+			Response: models.HealthStatus{}, Errors: []int{http.StatusNotFound, http.StatusServiceUnavailable}},
+		openapi.Route{Method: http.MethodGet, Path: "/ready", Tag: "service", Summary: "Готовность к запуску тестов",
+			Response: statusResponse{}},
+		openapi.Route{Method: http.MethodGet, Path: "/version", Tag: "service", Summary: "Сведения о сборке и конфигурации",
+			Response: models.VersionInfo{}},
+		openapi.Route{Method: http.MethodGet, Path: "/openapi.json", Tag: "service", Summary: "Описание API (OpenAPI 3)",
+			Response: json.RawMessage{}},
+		openapi.Route{Method: http.MethodGet, Path: "/metrics", Tag: "service", Summary: "Метрики Prometheus",
+			ContentType: "text/plain", Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodGet, Path: "/metrics/native", Tag: "service", Summary: "Гистограмма задержки отправки (native histogram)",
+			ContentType: utils.ProtoContentType, Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodGet, Path: "/stats", Tag: "service", Summary: "Статистика отправки",
+			Query: []openapi.Param{window}, Response: statsResponse{}, Errors: []int{http.StatusBadRequest}},
+		openapi.Route{Method: http.MethodGet, Path: "/audit", Tag: "service", Summary: "Журнал действий API",
+			Query: []openapi.Param{
+				{Name: "since", Description: "Записи начиная с момента (RFC3339)"},
+				{Name: "actor", Description: "Инициатор действия"},
+				{Name: "action", Description: "Начало имени действия"},
+				{Name: "test_id", Description: "Идентификатор теста"},
+				{Name: "limit", Type: "integer", Description: "Количество записей (по умолчанию 100)"},
+			},
+			Response: actionLogResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError}},
+	)
+
+	for _, route := range testRoutes {
+		doc.Add(openapi.Route{Method: http.MethodPost, Path: "/test/" + route.Kind, Tag: "tests", Summary: route.Summary,
+			Request: route.Request, Response: testStartedResponse{}, Errors: []int{http.StatusBadRequest, http.StatusConflict}})
+	}
+
+	doc.Add(
+		openapi.Route{Method: http.MethodPost, Path: "/test/from-template/:name", Tag: "tests", Summary: "Запуск теста по шаблону с заменой полей запроса",
+			Request: json.RawMessage{}, Response: testStartedResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		openapi.Route{Method: http.MethodPost, Path: "/test/stop", Tag: "tests", Summary: "Остановка теста (без test_id - всех тестов)",
+			Request: StopTestRequest{}, Response: testStoppedResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		openapi.Route{Method: http.MethodGet, Path: "/test/current", Tag: "tests", Summary: "Ход выполняющегося теста",
+			Query:    []openapi.Param{{Name: "test_id", Description: "Тест (по умолчанию последний запущенный)"}},
+			Response: test.Progress{}, Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodGet, Path: "/test/:id", Tag: "tests", Summary: "Результат теста",
+			Response: models.TestResult{}, Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodGet, Path: "/test/:id/report", Tag: "tests", Summary: "Отчет о тесте в HTML или CSV",
+			Query:       []openapi.Param{{Name: "format", Description: "html (по умолчанию) или csv"}},
+			ContentType: "text/html", Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+		openapi.Route{Method: http.MethodGet, Path: "/cluster/nodes", Tag: "cluster", Summary: "Зарегистрированные sender",
+			Response: clusterNodesResponse{}, Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodPost, Path: "/cluster/nodes", Tag: "cluster", Summary: "Регистрация sender у ведущего",
+			Request: cluster.Registration{}, Response: clusterRegisteredResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		openapi.Route{Method: http.MethodDelete, Path: "/cluster/nodes/:id", Tag: "cluster", Summary: "Снятие регистрации sender",
+			Response: statusResponse{}, Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodPost, Path: "/cluster/test/:type", Tag: "cluster", Summary: "Запуск согласованного теста (stream или batch)",
+			Query: []openapi.Param{
+				{Name: "start_delay", Description: "Задержка одновременного запуска"},
+				{Name: "nodes", Description: "Sender через запятую (по умолчанию все)"},
+			},
+			Request: json.RawMessage{}, Response: clusterStartedResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusBadGateway}},
+		openapi.Route{Method: http.MethodGet, Path: "/cluster/runs", Tag: "cluster", Summary: "Согласованные тесты",
+			Response: clusterRunsResponse{}, Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodGet, Path: "/cluster/runs/:id", Tag: "cluster", Summary: "Объединенный результат согласованного теста",
+			Response: cluster.RunResult{}, Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodPost, Path: "/cluster/runs/:id/stop", Tag: "cluster", Summary: "Остановка согласованного теста",
+			Response: statusResponse{}, Errors: []int{http.StatusNotFound, http.StatusBadGateway}},
+
+		openapi.Route{Method: http.MethodGet, Path: "/templates", Tag: "templates", Summary: "Шаблоны тестов",
+			Response: templatesResponse{}},
+		openapi.Route{Method: http.MethodGet, Path: "/templates/:name", Tag: "templates", Summary: "Шаблон теста",
+			Response: templates.Template{}, Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodPut, Path: "/templates/:name", Tag: "templates", Summary: "Создание или замена шаблона (201 при создании)",
+			Request: TemplateRequest{}, Response: templates.Template{}, Errors: []int{http.StatusBadRequest}},
+		openapi.Route{Method: http.MethodDelete, Path: "/templates/:name", Tag: "templates", Summary: "Удаление шаблона",
+			Response: templateDeletedResponse{}, Errors: []int{http.StatusNotFound}},
+
+		openapi.Route{Method: http.MethodGet, Path: "/capture", Tag: "capture", Summary: "Состояние записи трафика",
+			Response: test.CaptureStatus{}},
+		openapi.Route{Method: http.MethodPost, Path: "/capture/start", Tag: "capture", Summary: "Начало записи трафика",
+			Request: CaptureStartRequest{}, Response: test.CaptureStatus{}, Errors: []int{http.StatusBadRequest, http.StatusConflict}},
+		openapi.Route{Method: http.MethodPost, Path: "/capture/stop", Tag: "capture", Summary: "Остановка записи трафика",
+			Response: test.CaptureStatus{}, Errors: []int{http.StatusConflict}},
+
+		openapi.Route{Method: http.MethodPost, Path: "/generate", Tag: "data", Summary: "Генерация тестовых данных",
+			Request: GenerateDataRequest{}, Response: statusResponse{}, Status: http.StatusAccepted, Errors: []int{http.StatusBadRequest}},
+		openapi.Route{Method: http.MethodGet, Path: "/datasets", Tag: "data", Summary: "Наборы данных",
+			Response: datasetsResponse{}, Errors: []int{http.StatusInternalServerError}},
+		openapi.Route{Method: http.MethodGet, Path: "/datasets/:set/:name/sample", Tag: "data", Summary: "Первые записи файла набора (JSON Lines)",
+			Query:       []openapi.Param{{Name: "count", Type: "integer", Description: "Количество записей: 1..1000 (по умолчанию 10)"}},
+			ContentType: "application/x-ndjson", Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		openapi.Route{Method: http.MethodDelete, Path: "/datasets/:set", Tag: "data", Summary: "Удаление набора данных",
+			Response: datasetsDeletedResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		openapi.Route{Method: http.MethodDelete, Path: "/datasets/:set/:name", Tag: "data", Summary: "Удаление файла набора данных",
+			Response: datasetsDeletedResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		openapi.Route{Method: http.MethodDelete, Path: "/data", Tag: "data", Summary: "Удаление файлов наборов данных по типу и возрасту",
+			Query: []openapi.Param{
+				{Name: "type", Description: "Наборы через запятую: small, medium, large (по умолчанию все)"},
+				{Name: "older_than", Description: "Файлы старше длительности, например 24h"},
+			},
+			Response: generator.PruneResult{}, Errors: []int{http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError}},
+	)
+
+	return doc.JSON()
+}
+
+// getOpenAPI возвращает описание API в формате OpenAPI 3
+func (api *API) getOpenAPI(c *gin.Context) {
+	if api.openapi == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "описание API не сформировано"})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", api.openapi)
+}
+
+// setupOpenAPI формирует описание API для /openapi.json
+func (api *API) setupOpenAPI() {
+	document, err := openAPIDocument(api.version.Version)
+	if err != nil {
+		api.logger.Error("Ошибка формирования описания API", zap.Error(err))
+		return
+	}
+	api.openapi = document
+}
@@ -0,0 +1,180 @@
+package broker
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/infodiode/sender/config"
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// maxAuditTests количество тестов, сводки которых хранятся; сводки самых давних тестов вытесняются
+const maxAuditTests = 100
+
+// AuditListener принимает сводки recipient о принятых сообщениях из канала аудита
+// и хранит последнюю сводку каждого экземпляра по каждому тесту
+type AuditListener struct {
+	client   mqtt.Client
+	config   *config.AuditConfig
+	logger   *zap.Logger
+	mu       sync.RWMutex
+	tests    map[string]map[string]models.AuditTestDigest // Тест -> экземпляр recipient -> сводка
+	order    []string                                     // Тесты в порядке первой сводки
+	updated  map[string]time.Time                         // Время последней сводки теста
+	received atomic.Int64
+	invalid  atomic.Int64
+}
+
+// AuditReport сводка по тесту, объединенная по всем экземплярам recipient
+type AuditReport struct {
+	Received  int64
+	Unique    int64
+	Digest    uint64 // XOR дайджестов экземпляров
+	Instances []string
+	UpdatedAt time.Time
+}
+
+// AuditStats статистика канала аудита
+type AuditStats struct {
+	Connected bool   `json:"connected"`
+	Topic     string `json:"topic"`
+	Received  int64  `json:"received"`
+	Invalid   int64  `json:"invalid"`
+	Tests     int    `json:"tests"`
+}
+
+// NewAuditListener создает прием сводок канала аудита
+func NewAuditListener(cfg *config.AuditConfig, logger *zap.Logger) *AuditListener {
+	l := &AuditListener{
+		config:  cfg,
+		logger:  logger.With(zap.String("component", "audit")),
+		tests:   make(map[string]map[string]models.AuditTestDigest),
+		updated: make(map[string]time.Time),
+	}
+
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(cfg.Broker)
+	opts.SetClientID(cfg.ClientID)
+	if cfg.Username != "" {
+		opts.SetUsername(cfg.Username)
+	}
+	if cfg.Password != "" {
+		opts.SetPassword(cfg.Password)
+	}
+
+	// Сводки периодические, поэтому сессия не сохраняется; подписка повторяется при каждом подключении
+	opts.SetCleanSession(true)
+	opts.SetAutoReconnect(true)
+	opts.SetConnectRetry(true)
+	opts.SetOnConnectHandler(l.onConnect)
+	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		l.logger.Warn("Потеря соединения канала аудита", zap.Error(err))
+	})
+
+	l.client = mqtt.NewClient(opts)
+	return l
+}
+
+// Start начинает подключение к брокеру обратного канала; при недоступном брокере
+// подключение повторяется в фоне и не задерживает запуск сервиса
+func (l *AuditListener) Start() {
+	l.logger.Info("Запуск канала аудита",
+		zap.String("broker", l.config.Broker),
+		zap.String("topic", l.config.Topic))
+
+	l.client.Connect()
+}
+
+// onConnect подписывается на топик сводок
+func (l *AuditListener) onConnect(client mqtt.Client) {
+	l.logger.Info("Подключение канала аудита установлено", zap.String("broker", l.config.Broker))
+
+	token := client.Subscribe(l.config.Topic, l.config.QoS, l.onMessage)
+	go func() {
+		token.Wait()
+		if err := token.Error(); err != nil {
+			l.logger.Error("Ошибка подписки на канал аудита", zap.Error(err))
+		}
+	}()
+}
+
+// onMessage сохраняет сводки экземпляра recipient
+func (l *AuditListener) onMessage(_ mqtt.Client, msg mqtt.Message) {
+	var digest models.AuditDigest
+	if err := json.Unmarshal(msg.Payload(), &digest); err != nil {
+		l.invalid.Add(1)
+		l.logger.Debug("Некорректная сводка канала аудита", zap.Error(err))
+		return
+	}
+	l.received.Add(1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, test := range digest.Tests {
+		instances, ok := l.tests[test.TestID]
+		if !ok {
+			instances = make(map[string]models.AuditTestDigest)
+			l.tests[test.TestID] = instances
+			l.order = append(l.order, test.TestID)
+		}
+		instances[digest.Instance] = test
+		l.updated[test.TestID] = digest.Time
+	}
+
+	for len(l.order) > maxAuditTests {
+		delete(l.tests, l.order[0])
+		delete(l.updated, l.order[0])
+		l.order = l.order[1:]
+	}
+}
+
+// Report возвращает объединенную сводку по тесту; false, если сводок по тесту не поступало
+func (l *AuditListener) Report(testID string) (AuditReport, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	instances, ok := l.tests[testID]
+	if !ok {
+		return AuditReport{}, false
+	}
+
+	report := AuditReport{UpdatedAt: l.updated[testID]}
+	for instance, test := range instances {
+		report.Received += test.Received
+		report.Unique += test.Unique
+		if digest, err := strconv.ParseUint(test.Digest, 16, 64); err == nil {
+			report.Digest ^= digest
+		}
+		report.Instances = append(report.Instances, instance)
+	}
+	sort.Strings(report.Instances)
+
+	return report, true
+}
+
+// Stats возвращает статистику канала аудита
+func (l *AuditListener) Stats() AuditStats {
+	l.mu.RLock()
+	tests := len(l.tests)
+	l.mu.RUnlock()
+
+	return AuditStats{
+		Connected: l.client.IsConnected(),
+		Topic:     l.config.Topic,
+		Received:  l.received.Load(),
+		Invalid:   l.invalid.Load(),
+		Tests:     tests,
+	}
+}
+
+// Close отключается от брокера обратного канала
+func (l *AuditListener) Close() {
+	l.client.Disconnect(1000)
+}
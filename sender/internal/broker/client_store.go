@@ -0,0 +1,127 @@
+package broker
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+)
+
+// storeInboundPrefix префикс ключей входящих сообщений в хранилище сессии paho
+// (исходящие - "o.")
+const storeInboundPrefix = "i."
+
+// ClientStats внутреннее состояние MQTT клиента: хранилище сессии и ожидающие
+// подтверждения публикации. Публикация может завершиться ошибкой таймаута, оставив
+// сообщение в хранилище, поэтому тест без ошибок не гарантирует пустое хранилище
+type ClientStats struct {
+	Store          ClientStoreStats `json:"store"`            // Хранилище сессии
+	PendingTokens  int64            `json:"pending_tokens"`   // Публикаций QoS 1/2, ожидающих подтверждения брокера
+	TimedOutTokens int64            `json:"timed_out_tokens"` // Из них превысивших таймаут ожидания (сообщение осталось у клиента)
+	Resumes        int64            `json:"resumes"`          // Переподключений с неподтвержденными сообщениями в хранилище
+	Resume         *ResumeState     `json:"resume,omitempty"` // Возобновление сессии при последнем таком переподключении
+}
+
+// ClientStoreStats записи хранилища сессии MQTT клиента
+type ClientStoreStats struct {
+	Type     string `json:"type"`     // file (store_directory) или memory
+	Outbound int64  `json:"outbound"` // Исходящих сообщений QoS 1/2 без подтверждения брокера
+	Inbound  int64  `json:"inbound"`  // Входящих сообщений QoS 2 без завершения обмена
+}
+
+// ResumeState состояние хранилища при переподключении: исходящие сообщения из хранилища
+// paho отправляет повторно после подключения
+type ResumeState struct {
+	Time     time.Time `json:"time"`     // Момент переподключения
+	Outbound int64     `json:"outbound"` // Исходящих сообщений в хранилище к моменту переподключения
+	Inbound  int64     `json:"inbound"`  // Входящих сообщений в хранилище к моменту переподключения
+}
+
+// clientStore хранилище сессии paho с учетом количества записей. Ключи запоминаются,
+// так как paho перезаписывает запись сообщения QoS 2 на каждом шаге обмена
+type clientStore struct {
+	mqtt.Store
+	kind string
+
+	mu       sync.Mutex
+	keys     map[string]struct{}
+	outbound atomic.Int64
+	inbound  atomic.Int64
+}
+
+// newClientStore создает хранилище сессии: файловое в directory или в памяти, если
+// директория не задана
+func newClientStore(directory string) *clientStore {
+	if directory == "" {
+		return &clientStore{Store: mqtt.NewMemoryStore(), kind: "memory", keys: make(map[string]struct{})}
+	}
+	return &clientStore{Store: mqtt.NewFileStore(directory), kind: "file", keys: make(map[string]struct{})}
+}
+
+// Open открывает хранилище и учитывает записи, оставшиеся от предыдущего запуска
+func (s *clientStore) Open() {
+	s.Store.Open()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.Store.All() {
+		s.add(key)
+	}
+}
+
+// Put сохраняет пакет по ключу key
+func (s *clientStore) Put(key string, message packets.ControlPacket) {
+	s.Store.Put(key, message)
+
+	s.mu.Lock()
+	s.add(key)
+	s.mu.Unlock()
+}
+
+// Del удаляет пакет по ключу key
+func (s *clientStore) Del(key string) {
+	s.Store.Del(key)
+
+	s.mu.Lock()
+	if _, ok := s.keys[key]; ok {
+		delete(s.keys, key)
+		s.counter(key).Add(-1)
+	}
+	s.mu.Unlock()
+}
+
+// Reset удаляет все записи хранилища
+func (s *clientStore) Reset() {
+	s.Store.Reset()
+
+	s.mu.Lock()
+	s.keys = make(map[string]struct{})
+	s.outbound.Store(0)
+	s.inbound.Store(0)
+	s.mu.Unlock()
+}
+
+// add учитывает ключ key (вызывается под s.mu)
+func (s *clientStore) add(key string) {
+	if _, ok := s.keys[key]; ok {
+		return
+	}
+	s.keys[key] = struct{}{}
+	s.counter(key).Add(1)
+}
+
+// counter возвращает счетчик направления ключа key
+func (s *clientStore) counter(key string) *atomic.Int64 {
+	if strings.HasPrefix(key, storeInboundPrefix) {
+		return &s.inbound
+	}
+	return &s.outbound
+}
+
+// stats возвращает количество записей хранилища
+func (s *clientStore) stats() ClientStoreStats {
+	return ClientStoreStats{Type: s.kind, Outbound: s.outbound.Load(), Inbound: s.inbound.Load()}
+}
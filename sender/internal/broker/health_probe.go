@@ -0,0 +1,186 @@
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/sender/config"
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// HealthProber периодически публикует пробные сообщения models.HealthProbe в топик
+// health_probe.topic через соединение producer и ждет подтверждения брокера. В отличие
+// от IsConnected, успешная проба означает, что брокер действительно принимает публикации;
+// получение проб за диодом проверяет recipient
+type HealthProber struct {
+	producer *MQTTProducer
+	config   *config.HealthProbeConfig
+	logger   *zap.Logger
+	sequence atomic.Int64
+	sent     atomic.Int64
+	failures atomic.Int64
+
+	mu        sync.RWMutex
+	lastAcked time.Time     // Время публикации последней подтвержденной пробы
+	lastRTT   time.Duration // Время от публикации до подтверждения последней пробы
+	lastError string        // Ошибка последней попытки (пусто - успешна)
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// ProbeStats статистика пробных сообщений
+type ProbeStats struct {
+	Topic        string     `json:"topic"`
+	Sent         int64      `json:"sent"`                  // Подтвержденных проб
+	Failures     int64      `json:"failures"`              // Проб без соединения, с ошибкой или без подтверждения
+	LastSequence int64      `json:"last_sequence"`         // Номер последней пробы
+	LastAcked    *time.Time `json:"last_acked,omitempty"`  // Время публикации последней подтвержденной пробы
+	LastRTTMs    float64    `json:"last_rtt_ms"`           // Время до подтверждения последней пробы
+	LastError    string     `json:"last_error,omitempty"`  // Ошибка последней попытки
+	AgeSeconds   float64    `json:"age_seconds,omitempty"` // Время с последней подтвержденной пробы
+}
+
+// NewHealthProber создает публикацию пробных сообщений через соединение producer
+func NewHealthProber(producer *MQTTProducer, cfg *config.HealthProbeConfig, logger *zap.Logger) *HealthProber {
+	return &HealthProber{
+		producer: producer,
+		config:   cfg,
+		logger:   logger.With(zap.String("component", "health_probe")),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start запускает публикацию проб с периодом health_probe.interval; первая проба
+// публикуется сразу
+func (p *HealthProber) Start() {
+	p.logger.Info("Запуск пробных сообщений",
+		zap.String("topic", p.config.Topic),
+		zap.Duration("interval", p.config.Interval))
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(p.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			p.probe()
+			select {
+			case <-ticker.C:
+			case <-p.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// probe публикует одну пробу и ждет подтверждения брокера не дольше периода проб
+func (p *HealthProber) probe() {
+	probe := models.HealthProbe{
+		Instance: p.producer.config.ClientID,
+		Sequence: p.sequence.Add(1),
+		SendTime: time.Now(),
+	}
+
+	err := p.publish(&probe)
+
+	p.mu.Lock()
+	if err == nil {
+		p.lastAcked = probe.SendTime
+		p.lastRTT = time.Since(probe.SendTime)
+		p.lastError = ""
+	} else {
+		p.lastError = err.Error()
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		p.failures.Add(1)
+		p.logger.Debug("Проба не подтверждена брокером",
+			zap.Int64("sequence", probe.Sequence),
+			zap.Error(err))
+		return
+	}
+	p.sent.Add(1)
+}
+
+// publish публикует пробу в обход счетчиков и очереди отправки producer
+func (p *HealthProber) publish(probe *models.HealthProbe) error {
+	if !p.producer.IsConnected() {
+		return ErrNotConnected
+	}
+
+	data, err := json.Marshal(probe)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации пробы: %w", err)
+	}
+
+	token := p.producer.client.Publish(p.config.Topic, p.config.QoS, false, data)
+	if !token.WaitTimeout(p.config.Interval) {
+		return ErrPublishTimeout
+	}
+	return token.Error()
+}
+
+// Check возвращает проверку /healthz: проба должна быть подтверждена брокером не
+// раньше health_probe.max_age назад
+func (p *HealthProber) Check() models.Check {
+	check := models.Check{
+		Component: "mqtt_probe",
+		Status:    "healthy",
+	}
+
+	p.mu.RLock()
+	lastAcked, lastRTT, lastError := p.lastAcked, p.lastRTT, p.lastError
+	p.mu.RUnlock()
+
+	if lastAcked.IsZero() {
+		check.Status = "unhealthy"
+		check.Message = fmt.Sprintf("no acknowledged probe on %s", p.config.Topic)
+	} else {
+		age := time.Since(lastAcked)
+		check.Message = fmt.Sprintf("Topic: %s, last probe acknowledged %s ago, rtt %s",
+			p.config.Topic, age.Round(time.Millisecond), lastRTT.Round(time.Microsecond))
+		if age > p.config.MaxAge {
+			check.Status = "unhealthy"
+			check.Message += fmt.Sprintf(", older than %s", p.config.MaxAge)
+		}
+	}
+	if lastError != "" {
+		check.Message += ", last error: " + lastError
+	}
+	return check
+}
+
+// Stats возвращает статистику пробных сообщений
+func (p *HealthProber) Stats() ProbeStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stats := ProbeStats{
+		Topic:        p.config.Topic,
+		Sent:         p.sent.Load(),
+		Failures:     p.failures.Load(),
+		LastSequence: p.sequence.Load(),
+		LastRTTMs:    float64(p.lastRTT.Microseconds()) / 1000,
+		LastError:    p.lastError,
+	}
+	if !p.lastAcked.IsZero() {
+		lastAcked := p.lastAcked
+		stats.LastAcked = &lastAcked
+		stats.AgeSeconds = time.Since(lastAcked).Seconds()
+	}
+	return stats
+}
+
+// Close останавливает публикацию проб
+func (p *HealthProber) Close() {
+	close(p.stopChan)
+	p.wg.Wait()
+}
@@ -0,0 +1,96 @@
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/infodiode/shared/utils"
+	"go.uber.org/zap"
+)
+
+// ProducerLifetimeStats накопленные счетчики producer за все запуски sender с начала
+// накопления: счетчики предыдущих процессов, сохраненные в файл, и текущего процесса
+type ProducerLifetimeStats struct {
+	Since             time.Time `json:"since"`    // Начало накопления (первый запуск или сброс статистики)
+	Restarts          int       `json:"restarts"` // Перезапусков с начала накопления
+	MessagesPublished int64     `json:"messages_published"`
+	BytesSent         int64     `json:"bytes_sent"`
+	Errors            int64     `json:"errors"`
+	ReconnectCount    int64     `json:"reconnect_count"`
+	BrokerSwitches    int64     `json:"broker_switches"`
+}
+
+// producerLifetime счетчики предыдущих процессов, к которым добавляются счетчики текущего
+type producerLifetime struct {
+	mu   sync.Mutex
+	base ProducerLifetimeStats
+}
+
+// RestoreLifetime включает накопление счетчиков между перезапусками: загружает счетчики
+// предыдущих процессов из path. Отсутствующий файл начинает накопление заново.
+// Вызывается до начала отправки
+func (p *MQTTProducer) RestoreLifetime(path string) error {
+	lifetime := &producerLifetime{base: ProducerLifetimeStats{Since: time.Now()}}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		p.logger.Info("Накопление статистики начато", zap.String("file", path))
+	case err != nil:
+		return fmt.Errorf("ошибка чтения файла статистики: %w", err)
+	default:
+		if err := json.Unmarshal(data, &lifetime.base); err != nil {
+			return fmt.Errorf("ошибка разбора файла статистики %s: %w", path, err)
+		}
+		lifetime.base.Restarts++
+		p.logger.Info("Накопленная статистика загружена",
+			zap.String("file", path),
+			zap.Time("since", lifetime.base.Since),
+			zap.Int("restarts", lifetime.base.Restarts),
+			zap.Int64("messages_published", lifetime.base.MessagesPublished))
+	}
+
+	p.lifetime = lifetime
+	return nil
+}
+
+// SaveLifetime сохраняет накопленные счетчики в path; без накопления ничего не делает
+func (p *MQTTProducer) SaveLifetime(path string) error {
+	stats, ok := p.GetLifetimeStats()
+	if !ok {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("ошибка записи файла статистики: %w", err)
+	}
+	return nil
+}
+
+// GetLifetimeStats возвращает накопленные счетчики с учетом текущего процесса;
+// false, если накопление не включено
+func (p *MQTTProducer) GetLifetimeStats() (ProducerLifetimeStats, bool) {
+	if p.lifetime == nil {
+		return ProducerLifetimeStats{}, false
+	}
+
+	p.lifetime.mu.Lock()
+	total := p.lifetime.base
+	p.lifetime.mu.Unlock()
+
+	total.MessagesPublished += p.messageCounter.Load()
+	total.BytesSent += p.bytesCounter.Load()
+	total.Errors += p.errorCounter.Load()
+	total.ReconnectCount += int64(p.reconnectCount.Load())
+	total.BrokerSwitches += int64(p.brokerSwitches.Load())
+	return total, true
+}
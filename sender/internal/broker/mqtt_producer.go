@@ -1,8 +1,11 @@
 package broker
 
 import (
-	"encoding/json"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -10,9 +13,20 @@ import (
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/infodiode/sender/config"
 	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrNotConnected возвращается при отправке без соединения с брокером
+	ErrNotConnected = errors.New("нет соединения с MQTT брокером")
+	// ErrPublishTimeout возвращается, если брокер не подтвердил публикацию вовремя
+	ErrPublishTimeout = errors.New("таймаут при отправке сообщения")
+)
+
+// maxBrokerEvents количество хранимых событий переключения брокера
+const maxBrokerEvents = 50
+
 // MQTTProducer структура для отправки сообщений в MQTT
 type MQTTProducer struct {
 	client          mqtt.Client
@@ -20,15 +34,38 @@ type MQTTProducer struct {
 	logger          *zap.Logger
 	connected       atomic.Bool
 	messageCounter  atomic.Int64
+	batchCounter    atomic.Int64 // Пакетов сообщений, опубликованных одним сообщением брокера
 	errorCounter    atomic.Int64
 	bytesCounter    atomic.Int64
 	reconnectCount  atomic.Int32
 	lastConnectTime time.Time
+	attemptBroker   string
+	currentBroker   string
+	brokerSwitches  atomic.Int32
+	brokerEvents    []BrokerSwitchEvent
+	conn            net.Conn    // Текущее соединение, известно только при заданном last will
+	queue           *DiskQueue  // Очередь отправки на время недоступности брокера, nil если отключена
+	replaying       atomic.Bool // Выполняется отправка сообщений из очереди
+	queuedCounter   atomic.Int64
+	replayedCounter atomic.Int64
+	lifetime        *producerLifetime // Накопление счетчиков между перезапусками, nil если отключено
+	store           *clientStore      // Хранилище сессии клиента paho с учетом записей
+	pendingTokens   atomic.Int64      // Публикации QoS 1/2, ожидающие подтверждения брокера
+	timedOutTokens  atomic.Int64      // Публикации, подтверждение которых не получено за таймаут
+	resumes         atomic.Int64
+	resume          atomic.Pointer[ResumeState] // Состояние хранилища при последнем возобновлении сессии
 	mu              sync.RWMutex
 	stopChan        chan struct{}
 	wg              sync.WaitGroup
 }
 
+// BrokerSwitchEvent событие смены брокера после переподключения
+type BrokerSwitchEvent struct {
+	Time time.Time `json:"time"`
+	From string    `json:"from"`
+	To   string    `json:"to"`
+}
+
 // NewMQTTProducer создает новый экземпляр MQTT producer
 func NewMQTTProducer(cfg *config.MQTTConfig, logger *zap.Logger) (*MQTTProducer, error) {
 	p := &MQTTProducer{
@@ -39,7 +76,9 @@ func NewMQTTProducer(cfg *config.MQTTConfig, logger *zap.Logger) (*MQTTProducer,
 
 	// Настройка опций клиента MQTT
 	opts := mqtt.NewClientOptions()
-	opts.AddBroker(cfg.Broker)
+	for _, broker := range cfg.BrokerList() {
+		opts.AddBroker(broker)
+	}
 	opts.SetClientID(cfg.ClientID)
 
 	if cfg.Username != "" {
@@ -56,32 +95,68 @@ func NewMQTTProducer(cfg *config.MQTTConfig, logger *zap.Logger) (*MQTTProducer,
 	opts.SetMaxReconnectInterval(cfg.MaxReconnectInt)
 	opts.SetOrderMatters(cfg.OrderMatters)
 
-	// Настройка хранилища для буферизации сообщений
-	if cfg.StoreDirectory != "" {
-		store := mqtt.NewFileStore(cfg.StoreDirectory)
-		opts.SetStore(store)
-	}
+	// Настройка хранилища для буферизации сообщений (без директории - в памяти, как по
+	// умолчанию в paho); записи хранилища учитываются в статистике клиента
+	p.store = newClientStore(cfg.StoreDirectory)
+	opts.SetStore(p.store)
 
 	// Обработчики событий подключения
 	opts.SetOnConnectHandler(p.onConnect)
 	opts.SetConnectionLostHandler(p.onConnectionLost)
 	opts.SetReconnectingHandler(p.onReconnecting)
+	opts.SetConnectionAttemptHandler(p.onConnectAttempt)
+
+	// Last will публикуется брокером при обрыве соединения без DISCONNECT;
+	// соединение открывается самостоятельно, чтобы его можно было оборвать в Abort
+	if cfg.WillTopic != "" {
+		opts.SetWill(cfg.WillTopic, cfg.WillPayload, cfg.WillQoS, cfg.WillRetained)
+		opts.SetCustomOpenConnectionFn(p.openConnection)
+	}
+
+	// Очередь отправки открывается до подключения, чтобы сохраненные сообщения
+	// были отправлены сразу после него
+	if cfg.QueueDirectory != "" {
+		queue, err := OpenDiskQueue(cfg.QueueDirectory, cfg.MaxBufferedMsgs, logger)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка открытия очереди отправки: %w", err)
+		}
+		p.queue = queue
+	}
 
 	// Создание клиента
 	p.client = mqtt.NewClient(opts)
 
 	// Подключение к брокеру
 	if err := p.connect(); err != nil {
+		if p.queue != nil {
+			p.queue.Close()
+		}
 		return nil, fmt.Errorf("не удалось подключиться к MQTT брокеру: %w", err)
 	}
 
 	return p, nil
 }
 
+// NewSession создает producer с отдельным соединением к тем же брокерам: идентификатор
+// клиента дополняется суффиксом suffix, сессия чистая при cleanSession. Соединение не
+// использует файловое хранилище, очередь отправки и last will основного producer;
+// закрывает его вызывающий
+func (p *MQTTProducer) NewSession(suffix string, cleanSession bool) (*MQTTProducer, error) {
+	cfg := *p.config
+	cfg.ClientID = p.config.ClientID + "-" + suffix
+	cfg.CleanSession = cleanSession
+	cfg.StoreDirectory = ""
+	cfg.QueueDirectory = ""
+	cfg.WillTopic = ""
+
+	return NewMQTTProducer(&cfg, p.logger.With(zap.String("client_id", cfg.ClientID)))
+}
+
 // connect выполняет подключение к брокеру
 func (p *MQTTProducer) connect() error {
 	p.logger.Info("Подключение к MQTT брокеру",
-		zap.String("broker", p.config.Broker),
+		zap.Strings("brokers", p.config.BrokerList()),
+		zap.String("strategy", p.config.BrokerStrategy),
 		zap.String("client_id", p.config.ClientID),
 		zap.String("topic", p.config.Topic))
 
@@ -97,24 +172,97 @@ func (p *MQTTProducer) connect() error {
 	return nil
 }
 
+// openConnection открывает TCP или TLS соединение с брокером и запоминает его
+func (p *MQTTProducer) openConnection(uri *url.URL, options mqtt.ClientOptions) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: options.ConnectTimeout}
+
+	var conn net.Conn
+	var err error
+	switch uri.Scheme {
+	case "tcp", "mqtt":
+		conn, err = dialer.Dial("tcp", uri.Host)
+	case "ssl", "tls", "tcps", "mqtts":
+		conn, err = tls.DialWithDialer(dialer, "tcp", uri.Host, options.TLSConfig)
+	default:
+		return nil, fmt.Errorf("схема %s не поддерживается при заданном last will", uri.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.conn = conn
+	p.mu.Unlock()
+
+	return conn, nil
+}
+
+// onConnectAttempt вызывается перед попыткой подключения к каждому брокеру
+func (p *MQTTProducer) onConnectAttempt(broker *url.URL, tlsCfg *tls.Config) *tls.Config {
+	p.mu.Lock()
+	p.attemptBroker = broker.String()
+	p.mu.Unlock()
+	return tlsCfg
+}
+
 // onConnect вызывается при успешном подключении
 func (p *MQTTProducer) onConnect(client mqtt.Client) {
 	p.mu.Lock()
 	p.lastConnectTime = time.Now()
+	previous := p.currentBroker
+	current := p.attemptBroker
+	p.currentBroker = current
+	if previous != "" && previous != current {
+		p.brokerEvents = append(p.brokerEvents, BrokerSwitchEvent{
+			Time: p.lastConnectTime,
+			From: previous,
+			To:   current,
+		})
+		if len(p.brokerEvents) > maxBrokerEvents {
+			p.brokerEvents = p.brokerEvents[len(p.brokerEvents)-maxBrokerEvents:]
+		}
+	}
 	p.mu.Unlock()
 
 	p.connected.Store(true)
 	reconnects := p.reconnectCount.Load()
 
+	if previous != "" && previous != current {
+		switches := p.brokerSwitches.Add(1)
+		p.logger.Warn("Смена MQTT брокера после переподключения",
+			zap.String("from", previous),
+			zap.String("to", current),
+			zap.Int32("switches", switches))
+	}
+
 	if reconnects > 0 {
 		p.logger.Info("Переподключение к MQTT брокеру выполнено успешно",
 			zap.Int32("попытка", reconnects),
-			zap.String("broker", p.config.Broker))
+			zap.String("broker", current))
+		p.recordResume()
 	} else {
 		p.logger.Info("Подключение к MQTT брокеру установлено",
-			zap.String("broker", p.config.Broker),
+			zap.String("broker", current),
 			zap.String("client_id", p.config.ClientID))
 	}
+
+	p.startReplay()
+}
+
+// recordResume фиксирует неподтвержденные сообщения в хранилище сессии при
+// переподключении: paho отправляет их повторно после подключения
+func (p *MQTTProducer) recordResume() {
+	store := p.store.stats()
+	if store.Outbound == 0 && store.Inbound == 0 {
+		return
+	}
+
+	p.resume.Store(&ResumeState{Time: time.Now(), Outbound: store.Outbound, Inbound: store.Inbound})
+	p.resumes.Add(1)
+	p.logger.Warn("Возобновление сессии с неподтвержденными сообщениями в хранилище MQTT клиента",
+		zap.String("store", store.Type),
+		zap.Int64("outbound", store.Outbound),
+		zap.Int64("inbound", store.Inbound))
 }
 
 // onConnectionLost вызывается при потере соединения
@@ -124,74 +272,385 @@ func (p *MQTTProducer) onConnectionLost(client mqtt.Client, err error) {
 
 	p.logger.Error("Потеря соединения с MQTT брокером",
 		zap.Error(err),
-		zap.String("broker", p.config.Broker))
+		zap.String("broker", p.CurrentBroker()))
 }
 
 // onReconnecting вызывается при попытке переподключения
 func (p *MQTTProducer) onReconnecting(client mqtt.Client, opts *mqtt.ClientOptions) {
 	attempts := p.reconnectCount.Add(1)
+
+	// При round_robin начинаем перебор со следующего брокера после текущего
+	if p.config.BrokerStrategy == config.BrokerStrategyRoundRobin && len(opts.Servers) > 1 {
+		servers := make([]*url.URL, 0, len(opts.Servers))
+		servers = append(servers, opts.Servers[1:]...)
+		opts.Servers = append(servers, opts.Servers[0])
+	}
+
+	brokers := make([]string, 0, len(opts.Servers))
+	for _, server := range opts.Servers {
+		brokers = append(brokers, server.String())
+	}
+
 	p.logger.Warn("Попытка переподключения к MQTT брокеру",
 		zap.Int32("попытка", attempts),
-		zap.String("broker", p.config.Broker))
+		zap.Strings("brokers", brokers))
 }
 
-// Publish отправляет сообщение в MQTT
+// CurrentBroker возвращает адрес брокера, к которому подключен producer
+func (p *MQTTProducer) CurrentBroker() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.currentBroker
+}
+
+// PublishOptions параметры публикации сообщений
+type PublishOptions struct {
+	Topic     string
+	QoS       byte
+	Retained  bool
+	Aggregate bool // Публиковать пакет сообщений одним сообщением брокера (MessageBatch)
+}
+
+// PublishOptions возвращает параметры публикации из конфигурации
+func (p *MQTTProducer) PublishOptions() PublishOptions {
+	return PublishOptions{
+		Topic:     p.config.Topic,
+		QoS:       p.config.QoS,
+		Retained:  p.config.Retained,
+		Aggregate: p.config.AggregateBatches,
+	}
+}
+
+// CleanSession сообщает, подключается ли producer с чистой сессией
+func (p *MQTTProducer) CleanSession() bool {
+	return p.config.CleanSession
+}
+
+// Publish отправляет сообщение в MQTT с QoS из конфигурации
 func (p *MQTTProducer) Publish(message *models.Message) error {
+	return p.PublishQoS(message, p.config.QoS)
+}
+
+// PublishWith отправляет сообщение с параметрами публикации opts
+func (p *MQTTProducer) PublishWith(opts PublishOptions, message *models.Message) error {
+	return p.publish(opts.Topic, message, opts.QoS, opts.Retained)
+}
+
+// PublishQoS отправляет сообщение в MQTT с указанным QoS
+func (p *MQTTProducer) PublishQoS(message *models.Message, qos byte) error {
+	return p.publish(p.config.Topic, message, qos, p.config.Retained)
+}
+
+// PublishTopic отправляет сообщение в указанный топик вместо топика из конфигурации
+func (p *MQTTProducer) PublishTopic(topic string, message *models.Message) error {
+	return p.publish(topic, message, p.config.QoS, p.config.Retained)
+}
+
+// PublishRetained отправляет сообщение с флагом retain: брокер сохраняет его
+// и доставляет каждому новому подписчику топика
+func (p *MQTTProducer) PublishRetained(message *models.Message) error {
+	return p.publish(p.config.Topic, message, p.config.QoS, true)
+}
+
+// ClearRetained удаляет сохраненное на брокере сообщение топика
+// (пустое сообщение с флагом retain)
+func (p *MQTTProducer) ClearRetained() error {
 	if !p.IsConnected() {
-		return fmt.Errorf("нет соединения с MQTT брокером")
+		return ErrNotConnected
 	}
 
-	// Сериализация сообщения в JSON
-	data, err := json.Marshal(message)
-	if err != nil {
+	token := p.client.Publish(p.config.Topic, p.config.QoS, true, []byte{})
+	if !token.WaitTimeout(5 * time.Second) {
+		return ErrPublishTimeout
+	}
+	return token.Error()
+}
+
+// publish сериализует и отправляет сообщение в топик topic. При включенной очереди
+// сообщение ставится в нее, если нет соединения или в очереди есть неотправленные
+// сообщения (чтобы сохранить порядок), а также при потере соединения во время отправки
+func (p *MQTTProducer) publish(topic string, message *models.Message, qos byte, retained bool) error {
+	if p.queue == nil && !p.IsConnected() {
+		return ErrNotConnected
+	}
+
+	// Сериализация сообщения в JSON через буфер из пула
+	buf := utils.GetBuffer()
+	if err := buf.EncodeJSON(message); err != nil {
+		utils.PutBuffer(buf)
 		p.errorCounter.Add(1)
 		return fmt.Errorf("ошибка сериализации сообщения: %w", err)
 	}
 
+	return p.publishEncoded(topic, message.MessageID, 1, buf, qos, retained)
+}
+
+// publishEncoded отправляет сериализованные в buf сообщения (одно сообщение или пакет
+// из count сообщений, начинающийся с сообщения messageID) с учетом очереди отправки
+func (p *MQTTProducer) publishEncoded(topic string, messageID, count int, buf *utils.Buffer, qos byte, retained bool) error {
+	data := buf.Bytes()
+
+	if p.queue != nil && (!p.IsConnected() || p.queue.Len() > 0) {
+		defer utils.PutBuffer(buf)
+		return p.enqueue(topic, messageID, data, qos, retained)
+	}
+
+	token, err := p.send(topic, messageID, count, data, qos, retained)
+	if err != nil && p.queue != nil && !p.IsConnected() {
+		// Соединение потеряно во время отправки: сообщение будет отправлено из очереди
+		// (брокер мог успеть его принять, тогда получатель увидит дубликат)
+		err = p.enqueue(topic, messageID, data, qos, retained)
+	}
+	releaseBuffer(token, buf)
+	return err
+}
+
+// releaseBuffer возвращает буфер публикации в пул после завершения token (nil - данные
+// не переданы paho). Пока пакет в очереди или хранилище paho, он ссылается на данные
+// буфера, поэтому для незавершенной публикации (QoS 0 или истекшее ожидание) буфер
+// возвращается из горутины после завершения. При ошибке публикации буфер в пул не
+// возвращается: paho может повторить отправку из хранилища после переподключения
+func releaseBuffer(token mqtt.Token, buf *utils.Buffer) {
+	if token == nil {
+		utils.PutBuffer(buf)
+		return
+	}
+
+	select {
+	case <-token.Done():
+		if token.Error() == nil {
+			utils.PutBuffer(buf)
+		}
+	default:
+		go func() {
+			<-token.Done()
+			if token.Error() == nil {
+				utils.PutBuffer(buf)
+			}
+		}()
+	}
+}
+
+// send публикует сериализованное сообщение data; count - количество сообщений в data
+// (больше 1 для пакета сообщений). Возвращает token публикации (nil, если сообщение
+// не передано paho), по завершении которого данные можно переиспользовать
+func (p *MQTTProducer) send(topic string, messageID, count int, data []byte, qos byte, retained bool) (mqtt.Token, error) {
+	if !p.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
 	// Публикация сообщения
 	token := p.client.Publish(
-		p.config.Topic,
-		p.config.QoS,
-		p.config.Retained,
+		topic,
+		qos,
+		retained,
 		data,
 	)
 
 	// Ожидание подтверждения отправки (для QoS > 0)
-	if p.config.QoS > 0 {
-		if !token.WaitTimeout(5 * time.Second) {
+	if qos > 0 {
+		if !p.waitPublish(token, qos) {
 			p.errorCounter.Add(1)
-			return fmt.Errorf("таймаут при отправке сообщения")
+			return token, ErrPublishTimeout
 		}
 
 		if err := token.Error(); err != nil {
 			p.errorCounter.Add(1)
-			return fmt.Errorf("ошибка при отправке сообщения: %w", err)
+			return token, fmt.Errorf("ошибка при отправке сообщения: %w", err)
 		}
 	}
 
 	// Обновление счетчиков
-	p.messageCounter.Add(1)
+	p.messageCounter.Add(int64(count))
 	p.bytesCounter.Add(int64(len(data)))
 
+	if count > 1 {
+		p.batchCounter.Add(1)
+		p.logger.Debug("Пакет сообщений отправлен",
+			zap.Int("message_id", messageID),
+			zap.Int("messages", count),
+			zap.String("topic", topic),
+			zap.Int("size", len(data)))
+		return token, nil
+	}
+
 	p.logger.Debug("Сообщение отправлено",
-		zap.Int("message_id", message.MessageID),
-		zap.String("topic", p.config.Topic),
+		zap.Int("message_id", messageID),
+		zap.String("topic", topic),
 		zap.Int("size", len(data)))
 
+	return token, nil
+}
+
+// waitPublish ожидает завершения публикации не дольше 5 секунд; false при таймауте.
+// Публикация QoS 1/2 учитывается как ожидающая, пока paho не завершит token: после
+// таймаута сообщение остается в хранилище клиента и может быть подтверждено позже
+func (p *MQTTProducer) waitPublish(token mqtt.Token, qos byte) bool {
+	if qos == 0 {
+		return token.WaitTimeout(5 * time.Second)
+	}
+
+	p.pendingTokens.Add(1)
+	if token.WaitTimeout(5 * time.Second) {
+		p.pendingTokens.Add(-1)
+		return true
+	}
+
+	p.timedOutTokens.Add(1)
+	go func() {
+		<-token.Done()
+		p.timedOutTokens.Add(-1)
+		p.pendingTokens.Add(-1)
+	}()
+	return false
+}
+
+// enqueue ставит сериализованное сообщение в очередь отправки и запускает
+// отправку из очереди, если соединение есть
+func (p *MQTTProducer) enqueue(topic string, messageID int, data []byte, qos byte, retained bool) error {
+	err := p.queue.Push(&QueuedMessage{
+		Topic:      topic,
+		QoS:        qos,
+		Retained:   retained,
+		EnqueuedAt: time.Now(),
+		Data:       data,
+	})
+	if err != nil {
+		p.errorCounter.Add(1)
+		return err
+	}
+	p.queuedCounter.Add(1)
+
+	p.logger.Debug("Сообщение поставлено в очередь отправки",
+		zap.Int("message_id", messageID),
+		zap.String("topic", topic))
+
+	p.startReplay()
+	return nil
+}
+
+// startReplay запускает отправку сообщений из очереди, если есть соединение,
+// очередь не пуста и отправка еще не выполняется
+func (p *MQTTProducer) startReplay() {
+	if p.queue == nil || !p.IsConnected() || p.queue.Len() == 0 {
+		return
+	}
+	if !p.replaying.CompareAndSwap(false, true) {
+		return
+	}
+
+	p.wg.Add(1)
+	go p.replay()
+}
+
+// replay отправляет сообщения из очереди по порядку; сообщение удаляется из очереди
+// после подтверждения брокером. При потере соединения отправка продолжается после
+// переподключения
+func (p *MQTTProducer) replay() {
+	defer p.wg.Done()
+
+	depth := p.queue.Len()
+	p.logger.Info("Отправка сообщений из очереди", zap.Int("messages", depth))
+
+	var replayed int64
+	for {
+		select {
+		case <-p.stopChan:
+			p.replaying.Store(false)
+			return
+		default:
+		}
+
+		msg, err := p.queue.Peek()
+		if err != nil {
+			p.replaying.Store(false)
+			p.errorCounter.Add(1)
+			p.logger.Error("Ошибка чтения очереди отправки", zap.Error(err))
+			return
+		}
+		if msg == nil {
+			p.replaying.Store(false)
+			p.logger.Info("Очередь отправки пуста", zap.Int64("sent", replayed))
+
+			// Сообщение могло быть поставлено в очередь после проверки
+			p.startReplay()
+			return
+		}
+
+		if err := p.sendQueued(msg); err != nil {
+			p.replaying.Store(false)
+			p.logger.Warn("Отправка из очереди прервана",
+				zap.Int64("sent", replayed),
+				zap.Int("remaining", p.queue.Len()),
+				zap.Error(err))
+
+			// Соединение восстановилось до сброса признака отправки
+			p.startReplay()
+			return
+		}
+		p.queue.Pop()
+		replayed++
+	}
+}
+
+// sendQueued публикует сообщение из очереди и ожидает подтверждения брокера
+func (p *MQTTProducer) sendQueued(msg *QueuedMessage) error {
+	if !p.IsConnected() {
+		return ErrNotConnected
+	}
+
+	token := p.client.Publish(msg.Topic, msg.QoS, msg.Retained, msg.Data)
+	if !p.waitPublish(token, msg.QoS) {
+		p.errorCounter.Add(1)
+		return ErrPublishTimeout
+	}
+	if err := token.Error(); err != nil {
+		p.errorCounter.Add(1)
+		return fmt.Errorf("ошибка при отправке сообщения: %w", err)
+	}
+
+	// Пакет сообщений в очереди учитывается по количеству сообщений в нем
+	count := 1
+	if models.IsBatchPayload(msg.Data) {
+		if n, err := models.PeekBatchSequences(msg.Data, nil); err == nil && n > 0 {
+			count = n
+			p.batchCounter.Add(1)
+		}
+	}
+
+	p.messageCounter.Add(int64(count))
+	p.replayedCounter.Add(1)
+	p.bytesCounter.Add(int64(len(msg.Data)))
 	return nil
 }
 
 // PublishBatch отправляет пакет сообщений
 func (p *MQTTProducer) PublishBatch(messages []*models.Message) error {
-	if !p.IsConnected() {
-		return fmt.Errorf("нет соединения с MQTT брокером")
+	return p.PublishBatchTopic(p.config.Topic, messages)
+}
+
+// PublishBatchTopic отправляет пакет сообщений в указанный топик
+func (p *MQTTProducer) PublishBatchTopic(topic string, messages []*models.Message) error {
+	opts := p.PublishOptions()
+	opts.Topic = topic
+	return p.PublishBatchWith(opts, messages)
+}
+
+// PublishBatchWith отправляет пакет сообщений с параметрами публикации opts. При
+// opts.Aggregate пакет публикуется одним сообщением брокера, как пакет TCP
+func (p *MQTTProducer) PublishBatchWith(opts PublishOptions, messages []*models.Message) error {
+	if p.queue == nil && !p.IsConnected() {
+		return ErrNotConnected
+	}
+	if opts.Aggregate && len(messages) > 1 {
+		return p.publishAggregated(opts, messages)
 	}
 
 	var errs []error
 	successCount := 0
 
 	for _, msg := range messages {
-		if err := p.Publish(msg); err != nil {
+		if err := p.PublishWith(opts, msg); err != nil {
 			errs = append(errs, fmt.Errorf("сообщение %d: %w", msg.MessageID, err))
 		} else {
 			successCount++
@@ -199,13 +658,35 @@ func (p *MQTTProducer) PublishBatch(messages []*models.Message) error {
 	}
 
 	if len(errs) > 0 {
-		return fmt.Errorf("отправлено %d из %d сообщений, ошибки: %v",
-			successCount, len(messages), errs)
+		return fmt.Errorf("отправлено %d из %d сообщений, ошибки: %w",
+			successCount, len(messages), errors.Join(errs...))
 	}
 
 	return nil
 }
 
+// publishAggregated сериализует пакет сообщений в MessageBatch и публикует его одним
+// сообщением брокера; recipient распознает пакет и обрабатывает сообщения по отдельности
+func (p *MQTTProducer) publishAggregated(opts PublishOptions, messages []*models.Message) error {
+	batch := models.MessageBatch{
+		Messages:  messages,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Count:     len(messages),
+	}
+
+	buf := utils.GetBuffer()
+	if err := buf.EncodeJSON(batch); err != nil {
+		utils.PutBuffer(buf)
+		p.errorCounter.Add(1)
+		return fmt.Errorf("ошибка сериализации пакета сообщений: %w", err)
+	}
+
+	if err := p.publishEncoded(opts.Topic, messages[0].MessageID, len(messages), buf, opts.QoS, opts.Retained); err != nil {
+		return fmt.Errorf("пакет из %d сообщений: %w", len(messages), err)
+	}
+	return nil
+}
+
 // PublishAsync отправляет сообщение асинхронно
 func (p *MQTTProducer) PublishAsync(message *models.Message, callback func(error)) {
 	p.wg.Add(1)
@@ -219,17 +700,16 @@ func (p *MQTTProducer) PublishAsync(message *models.Message, callback func(error
 	}()
 }
 
-// PublishWithRetry отправляет сообщение с повторными попытками
+// PublishWithRetry отправляет сообщение с повторными попытками. Повторы сохраняют
+// message_id и sequence и помечаются номером попытки (attempt): если первая попытка
+// завершилась по таймауту, но брокер ее принял, recipient отличит повтор отправки от
+// повторной доставки брокера
 func (p *MQTTProducer) PublishWithRetry(message *models.Message, maxRetries int) error {
 	var lastErr error
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		if attempt > 0 {
-			// Экспоненциальная задержка между попытками
-			delay := time.Duration(attempt) * time.Second
-			if delay > 30*time.Second {
-				delay = 30 * time.Second
-			}
+			delay := RetryDelay(attempt)
 
 			p.logger.Debug("Повторная попытка отправки сообщения",
 				zap.Int("message_id", message.MessageID),
@@ -237,6 +717,7 @@ func (p *MQTTProducer) PublishWithRetry(message *models.Message, maxRetries int)
 				zap.Duration("задержка", delay))
 
 			time.Sleep(delay)
+			message.MarkRetry(attempt)
 		}
 
 		if err := p.Publish(message); err != nil {
@@ -250,6 +731,12 @@ func (p *MQTTProducer) PublishWithRetry(message *models.Message, maxRetries int)
 	return fmt.Errorf("не удалось отправить сообщение после %d попыток: %w", maxRetries, lastErr)
 }
 
+// RetryDelay возвращает задержку перед повторной попыткой отправки attempt:
+// растет на секунду с каждой попыткой, но не больше 30 секунд
+func RetryDelay(attempt int) time.Duration {
+	return min(time.Duration(attempt)*time.Second, 30*time.Second)
+}
+
 // IsConnected проверяет состояние подключения
 func (p *MQTTProducer) IsConnected() bool {
 	return p.client.IsConnected() && p.connected.Load()
@@ -259,27 +746,171 @@ func (p *MQTTProducer) IsConnected() bool {
 func (p *MQTTProducer) GetStats() ProducerStats {
 	p.mu.RLock()
 	lastConnect := p.lastConnectTime
+	currentBroker := p.currentBroker
+	events := make([]BrokerSwitchEvent, len(p.brokerEvents))
+	copy(events, p.brokerEvents)
 	p.mu.RUnlock()
 
-	return ProducerStats{
+	stats := ProducerStats{
 		MessagesPublished: p.messageCounter.Load(),
+		BatchesPublished:  p.batchCounter.Load(),
 		BytesSent:         p.bytesCounter.Load(),
 		Errors:            p.errorCounter.Load(),
 		ReconnectCount:    p.reconnectCount.Load(),
 		Connected:         p.IsConnected(),
 		LastConnectTime:   lastConnect,
 		Uptime:            time.Since(lastConnect),
+		CurrentBroker:     currentBroker,
+		BrokerSwitches:    p.brokerSwitches.Load(),
+		BrokerEvents:      events,
+		Client:            p.ClientStats(),
+	}
+	if p.queue != nil {
+		queue := p.queue.Stats()
+		stats.Queue = &ProducerQueueStats{
+			Depth:       queue.Depth,
+			Bytes:       queue.Bytes,
+			OldestAgeMs: queue.OldestAge.Milliseconds(),
+			Queued:      p.queuedCounter.Load(),
+			Replayed:    p.replayedCounter.Load(),
+			Replaying:   p.replaying.Load(),
+		}
+	}
+
+	return stats
+}
+
+// ClientStats возвращает внутреннее состояние клиента paho: записи хранилища сессии,
+// ожидающие подтверждения публикации и возобновление сессии после переподключения
+func (p *MQTTProducer) ClientStats() ClientStats {
+	return ClientStats{
+		Store:          p.store.stats(),
+		PendingTokens:  p.pendingTokens.Load(),
+		TimedOutTokens: p.timedOutTokens.Load(),
+		Resumes:        p.resumes.Load(),
+		Resume:         p.resume.Load(),
 	}
 }
 
 // ResetStats сбрасывает счетчики статистики
 func (p *MQTTProducer) ResetStats() {
 	p.messageCounter.Store(0)
+	p.batchCounter.Store(0)
 	p.bytesCounter.Store(0)
 	p.errorCounter.Store(0)
+	p.queuedCounter.Store(0)
+	p.replayedCounter.Store(0)
 	// reconnectCount не сбрасываем, так как это общий счетчик
 }
 
+// SessionPersistent сообщает, сохраняются ли неподтвержденные сообщения между
+// подключениями (QoS 1/2, CleanSession=false и файловое хранилище)
+func (p *MQTTProducer) SessionPersistent() bool {
+	return p.config.QoS > 0 && !p.config.CleanSession && p.config.StoreDirectory != ""
+}
+
+// Interrupt разрывает соединение с брокером без очистки сессии;
+// неподтвержденные QoS 1/2 сообщения остаются в файловом хранилище
+func (p *MQTTProducer) Interrupt() error {
+	if !p.SessionPersistent() {
+		return fmt.Errorf("прерывание сессии требует qos 1/2, clean_session=false и store_directory")
+	}
+
+	p.logger.Info("Прерывание соединения с MQTT брокером",
+		zap.String("broker", p.CurrentBroker()))
+
+	p.connected.Store(false)
+	p.client.Disconnect(0)
+
+	return nil
+}
+
+// Resume восстанавливает соединение после Interrupt; при подключении
+// paho повторно отправляет сохраненные неподтвержденные сообщения
+func (p *MQTTProducer) Resume() error {
+	if err := p.connect(); err != nil {
+		return fmt.Errorf("не удалось восстановить соединение: %w", err)
+	}
+
+	p.logger.Info("Соединение с MQTT брокером восстановлено",
+		zap.String("broker", p.CurrentBroker()))
+
+	return nil
+}
+
+// Churn принудительно разрывает соединение с брокером и через downtime подключается
+// заново. Сообщения, публикуемые без соединения, отклоняются или попадают в очередь отправки
+func (p *MQTTProducer) Churn(downtime time.Duration) error {
+	if !p.IsConnected() {
+		return ErrNotConnected
+	}
+
+	p.logger.Warn("Принудительный разрыв соединения с MQTT брокером",
+		zap.String("broker", p.CurrentBroker()),
+		zap.Duration("downtime", downtime))
+
+	p.connected.Store(false)
+	p.client.Disconnect(0)
+
+	time.Sleep(downtime)
+	return p.Resume()
+}
+
+// WillConfigured сообщает, задан ли last will
+func (p *MQTTProducer) WillConfigured() bool {
+	return p.config.WillTopic != ""
+}
+
+// Abort обрывает соединение с брокером без пакета DISCONNECT, после чего брокер
+// публикует last will; соединение восстанавливается вызовом Reconnect
+func (p *MQTTProducer) Abort() error {
+	if !p.WillConfigured() {
+		return fmt.Errorf("обрыв соединения для проверки last will требует mqtt.will_topic")
+	}
+
+	p.mu.RLock()
+	conn := p.conn
+	p.mu.RUnlock()
+
+	if conn == nil || !p.IsConnected() {
+		return ErrNotConnected
+	}
+
+	p.logger.Info("Обрыв соединения с MQTT брокером для публикации last will",
+		zap.String("broker", p.CurrentBroker()),
+		zap.String("will_topic", p.config.WillTopic))
+
+	// Признак соединения снимается до обрыва и восстанавливается в onConnect
+	p.connected.Store(false)
+	return conn.Close()
+}
+
+// Reconnect восстанавливает соединение после Abort: при auto_reconnect ожидает
+// переподключения paho, иначе подключается заново
+func (p *MQTTProducer) Reconnect(timeout time.Duration) error {
+	if !p.config.AutoReconnect {
+		// Дожидаемся, пока paho обработает обрыв, иначе Connect вернет ошибку
+		deadline := time.Now().Add(timeout)
+		for p.client.IsConnected() {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("таймаут ожидания обработки обрыва соединения")
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		return p.Resume()
+	}
+
+	deadline := time.Now().Add(timeout)
+	for !p.IsConnected() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("таймаут ожидания переподключения к брокеру")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return nil
+}
+
 // Flush ожидает завершения всех асинхронных операций
 func (p *MQTTProducer) Flush(timeout time.Duration) error {
 	done := make(chan struct{})
@@ -316,6 +947,24 @@ func (p *MQTTProducer) Close() error {
 
 	p.connected.Store(false)
 
+	// Сообщения без подтверждения брокера остаются в хранилище сессии: из файлового
+	// хранилища они будут отправлены после запуска, из памяти - потеряны
+	if store := p.store.stats(); store.Outbound > 0 {
+		p.logger.Warn("В хранилище MQTT клиента остались неподтвержденные сообщения",
+			zap.String("store", store.Type),
+			zap.Int64("messages", store.Outbound))
+	}
+
+	// Неотправленные сообщения остаются на диске и будут отправлены после запуска
+	if p.queue != nil {
+		if depth := p.queue.Len(); depth > 0 {
+			p.logger.Warn("В очереди отправки остались сообщения", zap.Int("messages", depth))
+		}
+		if err := p.queue.Close(); err != nil {
+			p.logger.Error("Ошибка закрытия очереди отправки", zap.Error(err))
+		}
+	}
+
 	// Логирование финальной статистики
 	stats := p.GetStats()
 	p.logger.Info("MQTT producer закрыт",
@@ -330,10 +979,26 @@ func (p *MQTTProducer) Close() error {
 // ProducerStats статистика producer
 type ProducerStats struct {
 	MessagesPublished int64
+	BatchesPublished  int64 // Пакетов сообщений, опубликованных одним сообщением брокера (aggregate_batches)
 	BytesSent         int64
 	Errors            int64
 	ReconnectCount    int32
 	Connected         bool
 	LastConnectTime   time.Time
 	Uptime            time.Duration
+	CurrentBroker     string
+	BrokerSwitches    int32
+	BrokerEvents      []BrokerSwitchEvent
+	Queue             *ProducerQueueStats `json:",omitempty"` // Очередь отправки, nil если отключена
+	Client            ClientStats         // Хранилище сессии и ожидающие публикации клиента paho
+}
+
+// ProducerQueueStats статистика очереди отправки producer
+type ProducerQueueStats struct {
+	Depth       int   `json:"depth"`         // Сообщений в очереди
+	Bytes       int64 `json:"bytes"`         // Размер сообщений в очереди, байт
+	OldestAgeMs int64 `json:"oldest_age_ms"` // Время ожидания первого сообщения очереди
+	Queued      int64 `json:"queued"`        // Поставлено в очередь
+	Replayed    int64 `json:"replayed"`      // Отправлено из очереди
+	Replaying   bool  `json:"replaying"`     // Выполняется отправка из очереди
 }
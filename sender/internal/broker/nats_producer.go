@@ -0,0 +1,265 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/sender/config"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// ErrNATSNotConnected возвращается при отправке без соединения с NATS сервером
+var ErrNATSNotConnected = errors.New("нет соединения с NATS сервером")
+
+// NATSProducer отправляет сообщения в поток NATS JetStream с ожиданием подтверждения сохранения.
+// Переподключение и PING выполняет клиент nats.go
+type NATSProducer struct {
+	config          *config.NATSConfig
+	logger          *zap.Logger
+	conn            *nats.Conn
+	js              jetstream.JetStream
+	lastConnectTime time.Time
+	messageCounter  atomic.Int64
+	errorCounter    atomic.Int64
+	bytesCounter    atomic.Int64
+	reconnectCount  atomic.Int32
+	mu              sync.Mutex
+}
+
+// NewNATSProducer создает producer и подключается к серверу, создавая поток при необходимости
+func NewNATSProducer(cfg *config.NATSConfig, logger *zap.Logger) (*NATSProducer, error) {
+	p := &NATSProducer{
+		config: cfg,
+		logger: logger,
+	}
+
+	if err := p.connect(); err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к NATS серверу: %w", err)
+	}
+
+	return p, nil
+}
+
+// connect подключается к серверу и проверяет наличие потока
+func (p *NATSProducer) connect() error {
+	p.logger.Info("Подключение к NATS серверу",
+		zap.String("url", p.config.URL),
+		zap.String("stream", p.config.Stream),
+		zap.String("subject", p.config.Subject))
+
+	opts := []nats.Option{
+		nats.Name("infodiode-sender"),
+		nats.Timeout(p.config.ConnectTimeout),
+		nats.ReconnectWait(p.config.ReconnectWait),
+		nats.MaxReconnects(-1),
+		// Во время переподключения отправки завершаются ошибкой, а не накапливаются в буфере клиента
+		nats.ReconnectBufSize(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			p.logger.Warn("Соединение с NATS сервером потеряно", zap.Error(err))
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			p.reconnectCount.Add(1)
+			p.mu.Lock()
+			p.lastConnectTime = time.Now()
+			p.mu.Unlock()
+			p.logger.Info("Переподключено к NATS серверу", zap.String("url", nc.ConnectedUrlRedacted()))
+		}),
+	}
+	if p.config.Username != "" {
+		opts = append(opts, nats.UserInfo(p.config.Username, p.config.Password))
+	}
+	if p.config.Token != "" {
+		opts = append(opts, nats.Token(p.config.Token))
+	}
+
+	nc, err := nats.Connect(p.config.URL, opts...)
+	if err != nil {
+		return err
+	}
+
+	js, err := jetstream.New(nc, jetstream.WithPublishAsyncTimeout(p.config.AckTimeout))
+	if err != nil {
+		nc.Close()
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.AckTimeout)
+	defer cancel()
+	stream := jetstream.StreamConfig{
+		Name:     p.config.Stream,
+		Subjects: []string{p.config.Subject},
+		Storage:  jetstream.FileStorage,
+	}
+	if err := ensureStream(ctx, js, stream); err != nil {
+		nc.Close()
+		return err
+	}
+
+	p.mu.Lock()
+	p.conn = nc
+	p.js = js
+	p.lastConnectTime = time.Now()
+	p.mu.Unlock()
+
+	p.logger.Info("Подключено к NATS серверу",
+		zap.String("url", nc.ConnectedUrlRedacted()),
+		zap.String("server_version", nc.ConnectedServerVersion()))
+
+	return nil
+}
+
+// ensureStream создает поток, если он еще не существует; настройки существующего потока не изменяются
+func ensureStream(ctx context.Context, js jetstream.JetStream, cfg jetstream.StreamConfig) error {
+	_, err := js.Stream(ctx, cfg.Name)
+	if !errors.Is(err, jetstream.ErrStreamNotFound) {
+		return err
+	}
+	if _, err := js.CreateStream(ctx, cfg); err != nil {
+		return fmt.Errorf("ошибка создания потока %s: %w", cfg.Name, err)
+	}
+	return nil
+}
+
+// Publish отправляет сообщение и ожидает подтверждения сохранения в потоке
+func (p *NATSProducer) Publish(message *models.Message) error {
+	return p.PublishBatch([]*models.Message{message})
+}
+
+// PublishBatch отправляет пакет сообщений асинхронно и ожидает подтверждения всех
+func (p *NATSProducer) PublishBatch(messages []*models.Message) error {
+	p.mu.Lock()
+	nc, js := p.conn, p.js
+	p.mu.Unlock()
+
+	if nc == nil || !nc.IsConnected() {
+		p.errorCounter.Add(int64(len(messages)))
+		return ErrNATSNotConnected
+	}
+
+	futures := make([]jetstream.PubAckFuture, 0, len(messages))
+	var bytes int64
+	var firstErr error
+	for _, message := range messages {
+		buf := utils.GetBuffer()
+		if err := buf.EncodeJSON(message); err != nil {
+			utils.PutBuffer(buf)
+			p.errorCounter.Add(1)
+			firstErr = fmt.Errorf("ошибка сериализации сообщения: %w", err)
+			break
+		}
+		// Клиент хранит данные до подтверждения для повторной отправки, поэтому буфер пула копируется
+		data := slices.Clone(buf.Bytes())
+		utils.PutBuffer(buf)
+
+		future, err := js.PublishAsync(p.config.Subject, data)
+		if err != nil {
+			p.errorCounter.Add(1)
+			firstErr = p.wrapError(err)
+			break
+		}
+
+		futures = append(futures, future)
+		bytes += int64(len(data))
+	}
+
+	// Подтверждения уже отправленных сообщений ожидаются и в случае ошибки;
+	// каждое ожидание ограничено ack_timeout через WithPublishAsyncTimeout
+	for _, future := range futures {
+		select {
+		case <-future.Ok():
+			p.messageCounter.Add(1)
+		case err := <-future.Err():
+			p.errorCounter.Add(1)
+			if firstErr == nil {
+				firstErr = p.wrapError(err)
+			}
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	p.bytesCounter.Add(bytes)
+	return nil
+}
+
+// wrapError приводит ошибки клиента к общим категориям broker
+func (p *NATSProducer) wrapError(err error) error {
+	switch {
+	case errors.Is(err, jetstream.ErrAsyncPublishTimeout), errors.Is(err, nats.ErrTimeout),
+		errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: нет подтверждения JetStream", ErrPublishTimeout)
+	case errors.Is(err, nats.ErrConnectionClosed), errors.Is(err, nats.ErrReconnectBufExceeded),
+		errors.Is(err, nats.ErrDisconnected):
+		return fmt.Errorf("%w: %v", ErrNATSNotConnected, err)
+	default:
+		return fmt.Errorf("ошибка публикации в NATS: %w", err)
+	}
+}
+
+// IsConnected проверяет наличие соединения с сервером
+func (p *NATSProducer) IsConnected() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.conn != nil && p.conn.IsConnected()
+}
+
+// GetStats возвращает статистику producer
+func (p *NATSProducer) GetStats() ProducerStats {
+	p.mu.Lock()
+	lastConnect := p.lastConnectTime
+	p.mu.Unlock()
+
+	return ProducerStats{
+		MessagesPublished: p.messageCounter.Load(),
+		BytesSent:         p.bytesCounter.Load(),
+		Errors:            p.errorCounter.Load(),
+		ReconnectCount:    p.reconnectCount.Load(),
+		Connected:         p.IsConnected(),
+		LastConnectTime:   lastConnect,
+		Uptime:            time.Since(lastConnect),
+		CurrentBroker:     p.config.URL,
+	}
+}
+
+// ResetStats сбрасывает статистику
+func (p *NATSProducer) ResetStats() {
+	p.messageCounter.Store(0)
+	p.bytesCounter.Store(0)
+	p.errorCounter.Store(0)
+}
+
+// Close закрывает соединение с сервером
+func (p *NATSProducer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		return nil
+	}
+
+	p.logger.Info("Закрытие соединения с NATS сервером")
+
+	// Дожидаемся обработки сервером уже отправленных сообщений
+	if err := p.conn.FlushTimeout(p.config.AckTimeout); err != nil && !errors.Is(err, nats.ErrConnectionClosed) {
+		p.logger.Warn("Ошибка при завершении отправки в NATS", zap.Error(err))
+	}
+	p.conn.Close()
+	p.conn = nil
+	p.js = nil
+
+	p.logger.Info("NATS producer закрыт",
+		zap.Int64("сообщений_отправлено", p.messageCounter.Load()),
+		zap.Int64("ошибок", p.errorCounter.Load()))
+
+	return nil
+}
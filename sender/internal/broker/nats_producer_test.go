@@ -0,0 +1,54 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/infodiode/sender/config"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+func TestNATSProducerWrapError(t *testing.T) {
+	p := &NATSProducer{}
+
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{jetstream.ErrAsyncPublishTimeout, ErrPublishTimeout},
+		{nats.ErrTimeout, ErrPublishTimeout},
+		{fmt.Errorf("ожидание: %w", context.DeadlineExceeded), ErrPublishTimeout},
+		{nats.ErrConnectionClosed, ErrNATSNotConnected},
+		{nats.ErrReconnectBufExceeded, ErrNATSNotConnected},
+	}
+	for _, tt := range tests {
+		if got := p.wrapError(tt.err); !errors.Is(got, tt.want) {
+			t.Errorf("wrapError(%v) = %v, ожидалось %v", tt.err, got, tt.want)
+		}
+	}
+
+	other := errors.New("другая ошибка")
+	got := p.wrapError(other)
+	if !errors.Is(got, other) || errors.Is(got, ErrPublishTimeout) || errors.Is(got, ErrNATSNotConnected) {
+		t.Errorf("wrapError(%v) = %v", other, got)
+	}
+}
+
+func TestNewNATSProducerNoServer(t *testing.T) {
+	cfg := &config.NATSConfig{
+		URL:            "nats://127.0.0.1:1",
+		Stream:         "TEST",
+		Subject:        "test.data",
+		ConnectTimeout: 500 * time.Millisecond,
+		AckTimeout:     500 * time.Millisecond,
+		ReconnectWait:  100 * time.Millisecond,
+	}
+	if _, err := NewNATSProducer(cfg, zap.NewNop()); err == nil {
+		t.Fatal("ожидалась ошибка подключения к недоступному серверу")
+	}
+}
@@ -0,0 +1,483 @@
+package broker
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrQueueFull возвращается, если очередь отправки заполнена до max_buffered_messages
+	ErrQueueFull = errors.New("очередь отправки заполнена")
+	// errQueueCorrupt возвращается при чтении поврежденной записи очереди
+	errQueueCorrupt = errors.New("поврежденная запись очереди")
+)
+
+const (
+	// queueSegmentSize размер файла очереди, после которого начинается новый
+	queueSegmentSize = 16 * 1024 * 1024
+	// queueHeaderSize заголовок записи: длина данных и CRC-32
+	queueHeaderSize = 8
+	// queueMaxRecord максимальный размер записи очереди
+	queueMaxRecord = 512 * 1024 * 1024
+	// queueCursorFile файл позиции чтения: номер первого файла очереди, смещение
+	// первой неизвлеченной записи в нем и CRC-32 этих полей
+	queueCursorFile = "cursor.dat"
+	queueCursorSize = 8 + 8 + 4
+)
+
+// QueuedMessage сообщение очереди отправки
+type QueuedMessage struct {
+	Topic      string
+	QoS        byte
+	Retained   bool
+	EnqueuedAt time.Time
+	Data       []byte // Сериализованное сообщение
+}
+
+// DiskQueue очередь исходящих сообщений на диске. Записи дописываются в файлы
+// queue-<номер>.dat; файл удаляется, когда все его записи извлечены. Позиция чтения
+// сохраняется в cursor.dat при каждом извлечении, поэтому после перезапуска sender
+// заново отправляются только неизвлеченные записи
+type DiskQueue struct {
+	dir         string
+	maxMessages int
+	logger      *zap.Logger
+	mu          sync.Mutex
+	segments    []uint64 // Номера файлов очереди по порядку
+	writer      *os.File // Последний файл очереди, открытый для записи
+	writeSize   int64
+	reader      *os.File // Первый файл очереди, открытый для чтения
+	readOffset  int64    // Смещение первой неизвлеченной записи в первом файле
+	cursor      *os.File // Файл позиции чтения, открывается при первом извлечении
+	head        *QueuedMessage
+	headSize    int64       // Размер записи head в файле
+	times       []time.Time // Время постановки записей в очередь по порядку
+	bytes       int64
+}
+
+// OpenDiskQueue открывает очередь в директории dir, восстанавливая сохраненные записи.
+// Поврежденный конец файла (например, после аварийного завершения) отбрасывается
+func OpenDiskQueue(dir string, maxMessages int, logger *zap.Logger) (*DiskQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию очереди %s: %w", dir, err)
+	}
+
+	q := &DiskQueue{
+		dir:         dir,
+		maxMessages: maxMessages,
+		logger:      logger,
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения директории очереди: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "queue-") || !strings.HasSuffix(name, ".dat") {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, "queue-"), ".dat"), 10, 64)
+		if err != nil {
+			continue
+		}
+		q.segments = append(q.segments, id)
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i] < q.segments[j] })
+
+	// Извлеченные записи первого файла пропускаются; позиция в уже удаленном
+	// файле означает, что все его записи извлечены
+	var skip int64
+	if len(q.segments) > 0 {
+		if id, offset, ok := q.readCursor(); ok && id == q.segments[0] {
+			skip = offset
+		}
+	}
+
+	for i, id := range q.segments {
+		offset := int64(0)
+		if i == 0 {
+			offset = skip
+		}
+		if err := q.recover(id, offset); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(q.times) > 0 {
+		logger.Info("Восстановлена очередь отправки",
+			zap.String("directory", dir),
+			zap.Int("messages", len(q.times)),
+			zap.Int64("bytes", q.bytes))
+	}
+
+	return q, nil
+}
+
+// segmentPath возвращает путь к файлу очереди
+func (q *DiskQueue) segmentPath(id uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("queue-%020d.dat", id))
+}
+
+// readCursor читает сохраненную позицию чтения; ok - false, если позиции нет или
+// она повреждена (тогда записи первого файла отправляются заново)
+func (q *DiskQueue) readCursor() (id uint64, offset int64, ok bool) {
+	data, err := os.ReadFile(filepath.Join(q.dir, queueCursorFile))
+	if err != nil || len(data) != queueCursorSize {
+		return 0, 0, false
+	}
+	if crc32.ChecksumIEEE(data[:16]) != binary.BigEndian.Uint32(data[16:20]) {
+		q.logger.Warn("Поврежденная позиция чтения очереди отброшена")
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint64(data[0:8]), int64(binary.BigEndian.Uint64(data[8:16])), true
+}
+
+// saveCursor сохраняет позицию чтения; вызывается под mu. Позиция перезаписывается
+// на месте без fsync, как и записи очереди: при сбое ОС возможен повтор записей, но не потеря
+func (q *DiskQueue) saveCursor() error {
+	if q.cursor == nil {
+		file, err := os.OpenFile(filepath.Join(q.dir, queueCursorFile), os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return fmt.Errorf("ошибка открытия позиции чтения очереди: %w", err)
+		}
+		q.cursor = file
+	}
+
+	var buf [queueCursorSize]byte
+	binary.BigEndian.PutUint64(buf[0:8], q.segments[0])
+	binary.BigEndian.PutUint64(buf[8:16], uint64(q.readOffset))
+	binary.BigEndian.PutUint32(buf[16:20], crc32.ChecksumIEEE(buf[:16]))
+	if _, err := q.cursor.WriteAt(buf[:], 0); err != nil {
+		return fmt.Errorf("ошибка записи позиции чтения очереди: %w", err)
+	}
+	return nil
+}
+
+// recover читает записи файла очереди при открытии и обрезает поврежденный конец.
+// Записи до смещения skip уже извлечены и не учитываются; чтение первого файла
+// начнется с первой записи не раньше skip
+func (q *DiskQueue) recover(id uint64, skip int64) error {
+	path := q.segmentPath(id)
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия файла очереди: %w", err)
+	}
+	defer file.Close()
+
+	var offset int64
+	defer func() {
+		if skip > 0 {
+			q.readOffset = offset
+		}
+	}()
+	for {
+		msg, size, err := readQueueRecord(file)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			q.logger.Warn("Поврежденный конец файла очереди отброшен",
+				zap.String("file", path),
+				zap.Int64("offset", offset),
+				zap.Error(err))
+			return os.Truncate(path, offset)
+		}
+		if offset < skip {
+			offset += size
+			continue
+		}
+		if skip > 0 {
+			// Первая неизвлеченная запись: с нее начнется чтение
+			q.readOffset = offset
+			skip = 0
+		}
+		offset += size
+		q.times = append(q.times, msg.EnqueuedAt)
+		q.bytes += int64(len(msg.Data))
+	}
+}
+
+// Push добавляет сообщение в конец очереди
+func (q *DiskQueue) Push(msg *QueuedMessage) error {
+	record := encodeQueueRecord(msg)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.maxMessages > 0 && len(q.times) >= q.maxMessages {
+		return ErrQueueFull
+	}
+
+	if q.writer == nil || q.writeSize+int64(len(record)) > queueSegmentSize {
+		if err := q.rotate(); err != nil {
+			return err
+		}
+	}
+
+	// Запись целиком одним вызовом: читатель не видит частично записанных данных
+	if _, err := q.writer.Write(record); err != nil {
+		return fmt.Errorf("ошибка записи в очередь: %w", err)
+	}
+	q.writeSize += int64(len(record))
+	q.times = append(q.times, msg.EnqueuedAt)
+	q.bytes += int64(len(msg.Data))
+
+	return nil
+}
+
+// rotate начинает новый файл очереди; вызывается под mu
+func (q *DiskQueue) rotate() error {
+	var id uint64 = 1
+	if len(q.segments) > 0 {
+		id = q.segments[len(q.segments)-1] + 1
+	}
+
+	// После восстановления дописываем в последний существующий файл, если он не заполнен
+	if q.writer == nil && len(q.segments) > 0 {
+		last := q.segments[len(q.segments)-1]
+		if info, err := os.Stat(q.segmentPath(last)); err == nil && info.Size() < queueSegmentSize {
+			file, err := os.OpenFile(q.segmentPath(last), os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return fmt.Errorf("ошибка открытия файла очереди: %w", err)
+			}
+			q.writer = file
+			q.writeSize = info.Size()
+			return nil
+		}
+	}
+
+	file, err := os.OpenFile(q.segmentPath(id), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла очереди: %w", err)
+	}
+	if q.writer != nil {
+		q.writer.Close()
+	}
+	q.writer = file
+	q.writeSize = 0
+	q.segments = append(q.segments, id)
+
+	return nil
+}
+
+// Peek возвращает первое сообщение очереди без извлечения; nil, если очередь пуста
+func (q *DiskQueue) Peek() (*QueuedMessage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.head != nil {
+		return q.head, nil
+	}
+
+	for len(q.times) > 0 {
+		if q.reader == nil {
+			file, err := os.Open(q.segmentPath(q.segments[0]))
+			if err != nil {
+				return nil, fmt.Errorf("ошибка открытия файла очереди: %w", err)
+			}
+			if _, err := file.Seek(q.readOffset, io.SeekStart); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("ошибка открытия файла очереди: %w", err)
+			}
+			q.reader = file
+		}
+
+		msg, size, err := readQueueRecord(q.reader)
+		if err == io.EOF && len(q.segments) > 1 {
+			// Файл прочитан целиком: записи продолжаются в следующем
+			q.removeHeadSegment()
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения очереди: %w", err)
+		}
+
+		q.head = msg
+		q.headSize = size
+		return msg, nil
+	}
+
+	return nil, nil
+}
+
+// Pop удаляет из очереди сообщение, полученное Peek
+func (q *DiskQueue) Pop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.head == nil {
+		return
+	}
+	q.bytes -= int64(len(q.head.Data))
+	q.head = nil
+	q.times = q.times[1:]
+	q.readOffset += q.headSize
+
+	// Очередь опустела: файлы больше не нужны
+	if len(q.times) == 0 {
+		q.clear()
+		return
+	}
+	if err := q.saveCursor(); err != nil {
+		q.logger.Warn("Не удалось сохранить позицию чтения очереди", zap.Error(err))
+	}
+}
+
+// removeHeadSegment закрывает и удаляет первый файл очереди; вызывается под mu
+func (q *DiskQueue) removeHeadSegment() {
+	q.reader.Close()
+	q.reader = nil
+	q.readOffset = 0
+	if err := os.Remove(q.segmentPath(q.segments[0])); err != nil {
+		q.logger.Warn("Не удалось удалить файл очереди", zap.Error(err))
+	}
+	q.segments = q.segments[1:]
+}
+
+// clear закрывает и удаляет все файлы пустой очереди; вызывается под mu
+func (q *DiskQueue) clear() {
+	if q.reader != nil {
+		q.reader.Close()
+		q.reader = nil
+	}
+	if q.writer != nil {
+		q.writer.Close()
+		q.writer = nil
+	}
+	if q.cursor != nil {
+		q.cursor.Close()
+		q.cursor = nil
+	}
+	if err := os.Remove(filepath.Join(q.dir, queueCursorFile)); err != nil && !os.IsNotExist(err) {
+		q.logger.Warn("Не удалось удалить позицию чтения очереди", zap.Error(err))
+	}
+	for _, id := range q.segments {
+		if err := os.Remove(q.segmentPath(id)); err != nil && !os.IsNotExist(err) {
+			q.logger.Warn("Не удалось удалить файл очереди", zap.Error(err))
+		}
+	}
+	q.segments = nil
+	q.readOffset = 0
+	q.writeSize = 0
+	q.times = nil
+	q.bytes = 0
+}
+
+// Len возвращает количество сообщений в очереди
+func (q *DiskQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.times)
+}
+
+// QueueStats состояние очереди отправки
+type QueueStats struct {
+	Depth     int           // Сообщений в очереди
+	Bytes     int64         // Размер сообщений в очереди, байт
+	OldestAge time.Duration // Время ожидания первого сообщения очереди
+}
+
+// Stats возвращает состояние очереди
+func (q *DiskQueue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := QueueStats{Depth: len(q.times), Bytes: q.bytes}
+	if len(q.times) > 0 {
+		stats.OldestAge = time.Since(q.times[0])
+	}
+	return stats
+}
+
+// Close закрывает файлы очереди; записи остаются на диске
+func (q *DiskQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.reader != nil {
+		q.reader.Close()
+		q.reader = nil
+	}
+	q.head = nil
+	if q.cursor != nil {
+		q.cursor.Close()
+		q.cursor = nil
+	}
+	if q.writer != nil {
+		err := q.writer.Close()
+		q.writer = nil
+		return err
+	}
+	return nil
+}
+
+// encodeQueueRecord сериализует запись очереди: длина и CRC-32 данных, затем QoS,
+// признак retain, время постановки (unix nano), длина и имя топика и сообщение
+func encodeQueueRecord(msg *QueuedMessage) []byte {
+	bodySize := 2 + 8 + 2 + len(msg.Topic) + len(msg.Data)
+	record := make([]byte, queueHeaderSize, queueHeaderSize+bodySize)
+
+	record = append(record, msg.QoS)
+	if msg.Retained {
+		record = append(record, 1)
+	} else {
+		record = append(record, 0)
+	}
+	record = binary.BigEndian.AppendUint64(record, uint64(msg.EnqueuedAt.UnixNano()))
+	record = binary.BigEndian.AppendUint16(record, uint16(len(msg.Topic)))
+	record = append(record, msg.Topic...)
+	record = append(record, msg.Data...)
+
+	binary.BigEndian.PutUint32(record[0:4], uint32(bodySize))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(record[queueHeaderSize:]))
+	return record
+}
+
+// readQueueRecord читает запись очереди; возвращает io.EOF в конце файла и размер записи
+func readQueueRecord(r io.Reader) (*QueuedMessage, int64, error) {
+	var header [queueHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, errQueueCorrupt
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size < 12 || size > queueMaxRecord {
+		return nil, 0, errQueueCorrupt
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, 0, errQueueCorrupt
+	}
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, errQueueCorrupt
+	}
+
+	topicLen := int(binary.BigEndian.Uint16(body[10:12]))
+	if 12+topicLen > len(body) {
+		return nil, 0, errQueueCorrupt
+	}
+
+	return &QueuedMessage{
+		QoS:        body[0],
+		Retained:   body[1] == 1,
+		EnqueuedAt: time.Unix(0, int64(binary.BigEndian.Uint64(body[2:10]))),
+		Topic:      string(body[12 : 12+topicLen]),
+		Data:       body[12+topicLen:],
+	}, int64(queueHeaderSize) + int64(size), nil
+}
@@ -0,0 +1,136 @@
+package broker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func openTestQueue(t *testing.T, dir string) *DiskQueue {
+	t.Helper()
+	q, err := OpenDiskQueue(dir, 0, zap.NewNop())
+	if err != nil {
+		t.Fatalf("OpenDiskQueue: %v", err)
+	}
+	return q
+}
+
+func pushMessages(t *testing.T, q *DiskQueue, from, to int, size int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		data := []byte(fmt.Sprintf("%08d", i))
+		if size > len(data) {
+			data = append(data, make([]byte, size-len(data))...)
+		}
+		msg := &QueuedMessage{Topic: "test/data", QoS: 1, EnqueuedAt: time.Now(), Data: data}
+		if err := q.Push(msg); err != nil {
+			t.Fatalf("Push %d: %v", i, err)
+		}
+	}
+}
+
+// popMessages извлекает count сообщений и проверяет их порядок, начиная с from
+func popMessages(t *testing.T, q *DiskQueue, from, count int) {
+	t.Helper()
+	for i := from; i < from+count; i++ {
+		msg, err := q.Peek()
+		if err != nil {
+			t.Fatalf("Peek %d: %v", i, err)
+		}
+		if msg == nil {
+			t.Fatalf("Peek %d: очередь пуста", i)
+		}
+		if got, want := string(msg.Data[:8]), fmt.Sprintf("%08d", i); got != want {
+			t.Fatalf("Peek: получено сообщение %s, ожидалось %s", got, want)
+		}
+		q.Pop()
+	}
+}
+
+func TestDiskQueuePushPop(t *testing.T) {
+	q := openTestQueue(t, t.TempDir())
+	defer q.Close()
+
+	pushMessages(t, q, 0, 10, 0)
+	if q.Len() != 10 {
+		t.Fatalf("Len = %d, ожидалось 10", q.Len())
+	}
+	popMessages(t, q, 0, 10)
+
+	msg, err := q.Peek()
+	if err != nil || msg != nil {
+		t.Fatalf("Peek пустой очереди = %v, %v", msg, err)
+	}
+}
+
+func TestDiskQueueReopenDoesNotReplayPopped(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir)
+	pushMessages(t, q, 0, 10, 0)
+	popMessages(t, q, 0, 4)
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	q = openTestQueue(t, dir)
+	if q.Len() != 6 {
+		t.Fatalf("после открытия Len = %d, ожидалось 6", q.Len())
+	}
+	popMessages(t, q, 4, 3)
+	pushMessages(t, q, 10, 12, 0)
+	q.Close()
+
+	q = openTestQueue(t, dir)
+	defer q.Close()
+	if q.Len() != 5 {
+		t.Fatalf("после второго открытия Len = %d, ожидалось 5", q.Len())
+	}
+	popMessages(t, q, 7, 5)
+	if _, err := os.Stat(filepath.Join(dir, queueCursorFile)); !os.IsNotExist(err) {
+		t.Fatalf("позиция чтения пустой очереди не удалена: %v", err)
+	}
+}
+
+func TestDiskQueueReopenAcrossSegments(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir)
+
+	// Записи по 1 MB: очередь занимает несколько файлов
+	const size = 1024 * 1024
+	perSegment := queueSegmentSize / (size + 64)
+	total := perSegment*2 + 3
+	pushMessages(t, q, 0, total, size)
+	popMessages(t, q, 0, perSegment+2)
+	q.Close()
+
+	q = openTestQueue(t, dir)
+	defer q.Close()
+	if want := total - perSegment - 2; q.Len() != want {
+		t.Fatalf("после открытия Len = %d, ожидалось %d", q.Len(), want)
+	}
+	popMessages(t, q, perSegment+2, total-perSegment-2)
+}
+
+func TestDiskQueueCorruptCursorReplays(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir)
+	pushMessages(t, q, 0, 5, 0)
+	popMessages(t, q, 0, 2)
+	q.Close()
+
+	if err := os.WriteFile(filepath.Join(dir, queueCursorFile), make([]byte, queueCursorSize), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Без позиции чтения записи отправляются заново, но не теряются
+	q = openTestQueue(t, dir)
+	defer q.Close()
+	if q.Len() != 5 {
+		t.Fatalf("Len = %d, ожидалось 5", q.Len())
+	}
+	popMessages(t, q, 0, 5)
+}
@@ -0,0 +1,137 @@
+package cluster
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/shared/client"
+	"go.uber.org/zap"
+)
+
+// AgentStats состояние регистрации sender у ведущего
+type AgentStats struct {
+	Lead         string     `json:"lead"`
+	NodeID       string     `json:"node_id"`
+	Registered   bool       `json:"registered"`
+	LastRegister *time.Time `json:"last_register,omitempty"` // Последнее успешное подтверждение регистрации
+	Failures     int64      `json:"failures"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// Agent регистрирует sender у ведущего и подтверждает регистрацию каждые interval;
+// при остановке регистрация снимается
+type Agent struct {
+	leadURL  string
+	reg      Registration
+	interval time.Duration
+	timeout  time.Duration
+	lead     *client.Sender
+	logger   *zap.Logger
+
+	registered   atomic.Bool
+	lastRegister atomic.Pointer[time.Time]
+	failures     atomic.Int64
+	lastError    atomic.Value // string
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewAgent создает регистрацию reg у ведущего leadURL
+func NewAgent(leadURL string, reg Registration, interval time.Duration, httpClient *http.Client, logger *zap.Logger) *Agent {
+	return &Agent{
+		leadURL:  leadURL,
+		reg:      reg,
+		interval: interval,
+		timeout:  httpClient.Timeout,
+		lead:     client.NewSender(leadURL, httpClient),
+		logger:   logger.With(zap.String("component", "cluster")),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start запускает регистрацию у ведущего
+func (a *Agent) Start() {
+	a.logger.Info("Регистрация у ведущего sender",
+		zap.String("lead", a.leadURL),
+		zap.String("node_id", a.reg.ID),
+		zap.String("url", a.reg.URL))
+
+	a.wg.Add(1)
+	go a.run()
+}
+
+// Close останавливает подтверждение регистрации и снимает регистрацию у ведущего
+func (a *Agent) Close() {
+	close(a.stop)
+	a.wg.Wait()
+
+	if !a.registered.Load() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+	err := a.lead.RemoveNode(ctx, a.reg.ID)
+	if err != nil {
+		a.logger.Warn("Не удалось снять регистрацию у ведущего sender", zap.Error(err))
+	}
+}
+
+// Stats возвращает состояние регистрации
+func (a *Agent) Stats() AgentStats {
+	stats := AgentStats{
+		Lead:         a.leadURL,
+		NodeID:       a.reg.ID,
+		Registered:   a.registered.Load(),
+		LastRegister: a.lastRegister.Load(),
+		Failures:     a.failures.Load(),
+	}
+	if lastError, ok := a.lastError.Load().(string); ok {
+		stats.LastError = lastError
+	}
+	return stats
+}
+
+// run подтверждает регистрацию до остановки
+func (a *Agent) run() {
+	defer a.wg.Done()
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		a.register()
+
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// register отправляет регистрацию ведущему
+func (a *Agent) register() {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	err := a.lead.RegisterNode(ctx, a.reg)
+	if err != nil {
+		a.failures.Add(1)
+		a.lastError.Store(err.Error())
+		// Ошибка логируется только при потере регистрации, чтобы не повторять ее каждый период
+		if a.registered.Swap(false) || a.failures.Load() == 1 {
+			a.logger.Warn("Ошибка регистрации у ведущего sender", zap.Error(err))
+		}
+		return
+	}
+
+	now := time.Now()
+	a.lastRegister.Store(&now)
+	if !a.registered.Swap(true) {
+		a.logger.Info("Sender зарегистрирован у ведущего", zap.String("lead", a.leadURL))
+	}
+}
@@ -0,0 +1,350 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/infodiode/shared/client"
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// maxRuns хранимых согласованных тестов; более ранние удаляются
+const maxRuns = 100
+
+// MaxStartDelay предельная задержка одновременного запуска
+const MaxStartDelay = 10 * time.Minute
+
+// splitFields поле запроса теста, значение которого делится между sender
+var splitFields = map[models.TestType]string{
+	models.TestTypeStream: "messages_per_sec",
+	models.TestTypeBatch:  "total_messages",
+}
+
+// ErrRunNotFound согласованный тест не найден
+var ErrRunNotFound = errors.New("согласованный тест не найден")
+
+// Part часть согласованного теста, выполняемая одним sender
+type Part struct {
+	Node           string            `json:"node"`
+	URL            string            `json:"url"`
+	TotalMessages  int               `json:"total_messages,omitempty"`   // Доля total_messages (batch)
+	MessagesPerSec int               `json:"messages_per_sec,omitempty"` // Доля messages_per_sec (stream)
+	TestID         string            `json:"test_id,omitempty"`          // Тест на sender; пусто - не запущен
+	Error          string            `json:"error,omitempty"`            // Ошибка запуска или запроса результата
+	Status         models.TestStatus `json:"status,omitempty"`           // Состояние теста на sender (в результате)
+	Stats          *models.TestStats `json:"stats,omitempty"`            // Статистика теста на sender (в результате)
+}
+
+// Run согласованный тест нескольких sender
+type Run struct {
+	ID       string          `json:"id"`
+	Type     models.TestType `json:"type"`
+	RunLabel string          `json:"run_label"` // Метка прогона тестов всех sender
+	StartAt  time.Time       `json:"start_at"`  // Назначенное время одновременного запуска
+	Parts    []Part          `json:"parts"`
+}
+
+// Coordinator запускает согласованные тесты на sender, зарегистрированных у ведущего:
+// делит нагрузку, запускает тесты одновременно и объединяет их результаты
+type Coordinator struct {
+	registry   *Registry
+	httpClient *http.Client
+	startDelay time.Duration
+	logger     *zap.Logger
+
+	mu    sync.Mutex
+	runs  map[string]*Run
+	order []string
+	last  int64 // Последний выданный идентификатор
+}
+
+// NewCoordinator создает запуск согласованных тестов на sender реестра registry
+func NewCoordinator(registry *Registry, httpClient *http.Client, startDelay time.Duration, logger *zap.Logger) *Coordinator {
+	return &Coordinator{
+		registry:   registry,
+		httpClient: httpClient,
+		startDelay: startDelay,
+		logger:     logger.With(zap.String("component", "cluster")),
+		runs:       make(map[string]*Run),
+	}
+}
+
+// Registry возвращает реестр sender
+func (c *Coordinator) Registry() *Registry {
+	return c.registry
+}
+
+// sender возвращает клиент API sender по адресу baseURL
+func (c *Coordinator) sender(baseURL string) *client.Sender {
+	return client.NewSender(baseURL, c.httpClient)
+}
+
+// Start делит поле нагрузки запроса request теста testType между sender nodes (пусто -
+// все зарегистрированные) и запускает на них тесты с общим временем начала отправки
+// start_at: заданным в запросе или через delay (отрицательное - задержка из конфигурации).
+// Sender готовят тест сразу и начинают отправку в start_at по своим часам. Ошибки
+// запуска на отдельных sender записываются в части теста; ошибка возвращается, если
+// тест не запущен ни на одном sender
+func (c *Coordinator) Start(testType models.TestType, request []byte, nodes []string, delay time.Duration) (*Run, error) {
+	field, ok := splitFields[testType]
+	if !ok {
+		return nil, fmt.Errorf("тест %s не поддерживает согласованный запуск (допустимы stream, batch)", testType)
+	}
+	if delay < 0 {
+		delay = c.startDelay
+	}
+	if delay > MaxStartDelay {
+		return nil, fmt.Errorf("задержка запуска должна быть не больше %s", MaxStartDelay)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(request, &fields); err != nil {
+		return nil, fmt.Errorf("некорректный запрос теста: %w", err)
+	}
+	var total int
+	if err := json.Unmarshal(fields[field], &total); err != nil || total <= 0 {
+		return nil, fmt.Errorf("не задано значение %s", field)
+	}
+
+	selected, err := c.selectNodes(nodes)
+	if err != nil {
+		return nil, err
+	}
+	if total < len(selected) {
+		return nil, fmt.Errorf("%s (%d) меньше числа sender (%d)", field, total, len(selected))
+	}
+
+	run := &Run{
+		ID:      c.newRunID(),
+		Type:    testType,
+		StartAt: time.Now().Add(delay),
+	}
+	if raw, ok := fields["start_at"]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &run.StartAt); err != nil {
+			return nil, fmt.Errorf("некорректное время начала start_at: %w", err)
+		}
+		if time.Until(run.StartAt) > MaxStartDelay {
+			return nil, fmt.Errorf("время начала start_at должно быть не позже чем через %s", MaxStartDelay)
+		}
+	}
+	fields["start_at"], _ = json.Marshal(run.StartAt.UTC())
+
+	// Тесты всех sender получают общую метку прогона для сопоставления на recipient
+	if err := json.Unmarshal(fields["run_label"], &run.RunLabel); err != nil || run.RunLabel == "" {
+		run.RunLabel = "cluster-" + run.ID
+		fields["run_label"], _ = json.Marshal(run.RunLabel)
+	}
+
+	bodies := make([]json.RawMessage, len(selected))
+	for i, share := range split(total, len(selected)) {
+		part := Part{Node: selected[i].ID, URL: selected[i].URL}
+		if testType == models.TestTypeBatch {
+			part.TotalMessages = share
+		} else {
+			part.MessagesPerSec = share
+		}
+		run.Parts = append(run.Parts, part)
+
+		fields[field] = json.RawMessage(strconv.Itoa(share))
+		if bodies[i], err = json.Marshal(fields); err != nil {
+			return nil, err
+		}
+	}
+
+	c.logger.Info("Согласованный тест запускается",
+		zap.String("run_id", run.ID),
+		zap.String("type", string(testType)),
+		zap.Int("nodes", len(selected)),
+		zap.Time("start_at", run.StartAt))
+
+	// Запросы запуска отправляются всем sender параллельно; время начала отправки
+	// задает start_at, поэтому задержки запросов на него не влияют
+	var wg sync.WaitGroup
+	for i := range run.Parts {
+		wg.Add(1)
+		go func(part *Part, body json.RawMessage) {
+			defer wg.Done()
+
+			startCtx, cancel := context.WithTimeout(context.Background(), c.httpClient.Timeout)
+			defer cancel()
+			started, err := c.sender(part.URL).StartTest(startCtx, string(testType), body)
+			if err != nil {
+				part.Error = err.Error()
+				return
+			}
+			part.TestID = started.TestID
+		}(&run.Parts[i], bodies[i])
+	}
+	wg.Wait()
+
+	c.store(run)
+
+	started := 0
+	for _, part := range run.Parts {
+		if part.Error != "" {
+			c.logger.Error("Тест согласованного запуска не запущен на sender",
+				zap.String("run_id", run.ID),
+				zap.String("node", part.Node),
+				zap.String("error", part.Error))
+			continue
+		}
+		started++
+	}
+	if started == 0 {
+		return run, fmt.Errorf("тест не запущен ни на одном sender")
+	}
+	return run, nil
+}
+
+// Stop останавливает тесты согласованного теста id на всех sender; тесты, уже
+// завершившиеся на sender, пропускаются. Возвращает ошибки по имени sender
+func (c *Coordinator) Stop(ctx context.Context, id string) (map[string]string, error) {
+	run, ok := c.get(id)
+	if !ok {
+		return nil, ErrRunNotFound
+	}
+
+	failures := make(map[string]string)
+	var mu sync.Mutex
+	c.forEachTest(run, func(_ int, part *Part) {
+		_, err := c.sender(part.URL).StopTest(ctx, part.TestID)
+		// 400 и 404 - на sender нет выполняющегося теста
+		if code := client.StatusCode(err); err != nil && code != http.StatusBadRequest && code != http.StatusNotFound {
+			mu.Lock()
+			failures[part.Node] = err.Error()
+			mu.Unlock()
+		}
+	})
+	return failures, nil
+}
+
+// Result запрашивает результаты тестов согласованного теста id у всех sender и объединяет их
+func (c *Coordinator) Result(ctx context.Context, id string) (*RunResult, error) {
+	run, ok := c.get(id)
+	if !ok {
+		return nil, ErrRunNotFound
+	}
+
+	results := make([]*models.TestResult, len(run.Parts))
+	c.forEachTest(run, func(i int, part *Part) {
+		result, err := c.sender(part.URL).TestResult(ctx, part.TestID)
+		if err != nil {
+			part.Error = err.Error()
+			return
+		}
+		part.Status = result.Status
+		part.Stats = result.Stats
+		results[i] = result
+	})
+
+	return mergeRun(run, results), nil
+}
+
+// Runs возвращает согласованные тесты в порядке запуска
+func (c *Coordinator) Runs() []Run {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	runs := make([]Run, 0, len(c.order))
+	for _, id := range c.order {
+		run := *c.runs[id]
+		run.Parts = slices.Clone(run.Parts)
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// forEachTest выполняет fn для частей run с запущенным тестом параллельно
+func (c *Coordinator) forEachTest(run *Run, fn func(i int, part *Part)) {
+	var wg sync.WaitGroup
+	for i := range run.Parts {
+		if run.Parts[i].TestID == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fn(i, &run.Parts[i])
+		}(i)
+	}
+	wg.Wait()
+}
+
+// selectNodes возвращает зарегистрированные sender с именами ids (пусто - все)
+func (c *Coordinator) selectNodes(ids []string) ([]Node, error) {
+	nodes := c.registry.Nodes()
+	if len(ids) == 0 {
+		if len(nodes) == 0 {
+			return nil, fmt.Errorf("нет зарегистрированных sender")
+		}
+		return nodes, nil
+	}
+
+	selected := make([]Node, 0, len(ids))
+	for _, id := range ids {
+		i := slices.IndexFunc(nodes, func(n Node) bool { return n.ID == id })
+		if i < 0 {
+			return nil, fmt.Errorf("sender %s не зарегистрирован", id)
+		}
+		if !slices.ContainsFunc(selected, func(n Node) bool { return n.ID == id }) {
+			selected = append(selected, nodes[i])
+		}
+	}
+	return selected, nil
+}
+
+// newRunID возвращает возрастающий идентификатор согласованного теста
+func (c *Coordinator) newRunID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.last = max(time.Now().UnixMilli(), c.last+1)
+	return strconv.FormatInt(c.last, 10)
+}
+
+// store сохраняет согласованный тест, удаляя самые ранние сверх maxRuns
+func (c *Coordinator) store(run *Run) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.runs[run.ID] = run
+	c.order = append(c.order, run.ID)
+	if len(c.order) > maxRuns {
+		delete(c.runs, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// get возвращает копию согласованного теста id
+func (c *Coordinator) get(id string) (*Run, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	run, ok := c.runs[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *run
+	copied.Parts = slices.Clone(run.Parts)
+	return &copied, true
+}
+
+// split делит total на n долей, отличающихся не больше чем на 1
+func split(total, n int) []int {
+	shares := make([]int, n)
+	for i := range shares {
+		shares[i] = total / n
+		if i < total%n {
+			shares[i]++
+		}
+	}
+	return shares
+}
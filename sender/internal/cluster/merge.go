@@ -0,0 +1,190 @@
+package cluster
+
+import (
+	"time"
+
+	"github.com/infodiode/shared/models"
+)
+
+// RunResult объединенный результат согласованного теста
+type RunResult struct {
+	Run
+	Status           models.TestStatus        `json:"status"`                      // Running, пока тест выполняется хотя бы на одном sender
+	StartSkew        float64                  `json:"start_skew_ms"`               // Разброс фактического начала отправки sender по их часам (ms)
+	Stats            *models.TestStats        `json:"stats"`                       // Сумма статистики sender
+	Timeline         []models.TimelinePoint   `json:"timeline,omitempty"`          // Посекундная динамика отправки всех sender
+	LatencyHistogram []models.HistogramBucket `json:"latency_histogram,omitempty"` // Распределение задержек всех sender
+	ErrorBreakdown   map[string]int64         `json:"error_breakdown,omitempty"`   // Ошибки по категориям всех sender
+}
+
+// statusPriority порядок выбора общего состояния: общий тест принимает
+// состояние части с наибольшим приоритетом
+var statusPriority = map[models.TestStatus]int{
+	models.TestStatusCompleted: 1,
+	models.TestStatusStopped:   2,
+	models.TestStatusAborted:   3,
+	models.TestStatusFailed:    4,
+	models.TestStatusRunning:   5,
+}
+
+// mergeRun объединяет результаты тестов sender; results[i] - результат части
+// run.Parts[i], nil - тест не запущен или результат не получен
+func mergeRun(run *Run, results []*models.TestResult) *RunResult {
+	merged := &RunResult{Run: *run, Stats: &models.TestStats{}}
+	stats := merged.Stats
+
+	var (
+		latencySum float64
+		latencyN   int64
+		first      time.Time
+		last       time.Time
+		ended      = true
+	)
+	timeline := make(map[int]*models.TimelinePoint)
+
+	for i, part := range run.Parts {
+		result := results[i]
+		if result == nil || result.Stats == nil {
+			// Часть без результата считается неуспешной
+			merged.Status = higherStatus(merged.Status, models.TestStatusFailed)
+			if part.TestID != "" {
+				ended = false
+			}
+			continue
+		}
+		merged.Status = higherStatus(merged.Status, result.Status)
+
+		s := result.Stats
+		if first.IsZero() || s.StartTime.Before(first) {
+			first = s.StartTime
+		}
+		// Отправка начинается в start_at; sender, не успевший подготовиться, опаздывает на start_lag_ms
+		started := s.StartTime
+		if result.StartLag != nil {
+			started = started.Add(time.Duration(*result.StartLag * float64(time.Millisecond)))
+		}
+		if started.After(last) {
+			last = started
+		}
+		if s.EndTime == nil {
+			ended = false
+		} else if stats.EndTime == nil || s.EndTime.After(*stats.EndTime) {
+			end := *s.EndTime
+			stats.EndTime = &end
+		}
+		stats.Duration = max(stats.Duration, s.Duration)
+
+		stats.MessagesSent += s.MessagesSent
+		stats.MessagesReceived += s.MessagesReceived
+		stats.BytesSent += s.BytesSent
+		stats.BytesReceived += s.BytesReceived
+		stats.Errors += s.Errors
+		stats.InvalidSent += s.InvalidSent
+		stats.OversizeMessages += s.OversizeMessages
+		stats.RetriedMessages += s.RetriedMessages
+		stats.SendRetries += s.SendRetries
+		// Sender отправляют параллельно, поэтому пропускная способность суммируется
+		stats.AvgThroughput += s.AvgThroughput
+
+		if s.MessagesSent > 0 {
+			latencySum += s.AvgLatency * float64(s.MessagesSent)
+			latencyN += s.MessagesSent
+			if stats.MinLatency == 0 || s.MinLatency < stats.MinLatency {
+				stats.MinLatency = s.MinLatency
+			}
+			stats.MaxLatency = max(stats.MaxLatency, s.MaxLatency)
+		}
+
+		for _, point := range result.Timeline {
+			p, ok := timeline[point.Second]
+			if !ok {
+				p = &models.TimelinePoint{Second: point.Second}
+				timeline[point.Second] = p
+			}
+			p.Sent += point.Sent
+			p.Bytes += point.Bytes
+			p.Errors += point.Errors
+			p.Received += point.Received
+			p.ReceiveErrors += point.ReceiveErrors
+		}
+
+		merged.LatencyHistogram = addBuckets(merged.LatencyHistogram, result.LatencyHistogram)
+		for category, count := range result.ErrorBreakdown {
+			if merged.ErrorBreakdown == nil {
+				merged.ErrorBreakdown = make(map[string]int64)
+			}
+			merged.ErrorBreakdown[category] += count
+		}
+	}
+
+	stats.StartTime = first
+	if !ended {
+		stats.EndTime = nil
+	}
+	if ended && stats.EndTime != nil && !first.IsZero() {
+		stats.Duration = stats.EndTime.Sub(first)
+	}
+	if !first.IsZero() {
+		merged.StartSkew = float64(last.Sub(first).Microseconds()) / 1000
+	}
+	if latencyN > 0 {
+		stats.AvgLatency = latencySum / float64(latencyN)
+	}
+	stats.P50Latency = bucketQuantile(merged.LatencyHistogram, 0.50, stats.MaxLatency)
+	stats.P95Latency = bucketQuantile(merged.LatencyHistogram, 0.95, stats.MaxLatency)
+	stats.P99Latency = bucketQuantile(merged.LatencyHistogram, 0.99, stats.MaxLatency)
+
+	for second := 0; len(merged.Timeline) < len(timeline); second++ {
+		if p, ok := timeline[second]; ok {
+			merged.Timeline = append(merged.Timeline, *p)
+		}
+	}
+
+	return merged
+}
+
+// higherStatus возвращает состояние с большим приоритетом
+func higherStatus(current, next models.TestStatus) models.TestStatus {
+	if statusPriority[next] > statusPriority[current] {
+		return next
+	}
+	return current
+}
+
+// addBuckets складывает гистограммы задержек sender с одинаковыми корзинами
+func addBuckets(total, buckets []models.HistogramBucket) []models.HistogramBucket {
+	if len(total) == 0 {
+		return append([]models.HistogramBucket(nil), buckets...)
+	}
+	for i := range total {
+		if i < len(buckets) {
+			total[i].Count += buckets[i].Count
+		}
+	}
+	return total
+}
+
+// bucketQuantile оценивает квантиль q по гистограмме верхней границей корзины;
+// для корзины без границы возвращается maxLatency
+func bucketQuantile(buckets []models.HistogramBucket, q, maxLatency float64) float64 {
+	var total int64
+	for _, bucket := range buckets {
+		total += bucket.Count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := int64(q * float64(total))
+	var seen int64
+	for _, bucket := range buckets {
+		seen += bucket.Count
+		if seen > rank {
+			if bucket.UpperBoundMs == 0 {
+				return maxLatency
+			}
+			return min(bucket.UpperBoundMs, maxLatency)
+		}
+	}
+	return maxLatency
+}
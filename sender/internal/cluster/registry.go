@@ -0,0 +1,107 @@
+package cluster
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/infodiode/shared/models"
+)
+
+// missedHeartbeats подтверждений регистрации, после пропуска которых sender исключается
+const missedHeartbeats = 3
+
+// Registration регистрация sender у ведущего; повторяется каждые heartbeat_interval
+type Registration struct {
+	ID      string `json:"id"`                // Имя sender в кластере
+	URL     string `json:"url"`               // Адрес HTTP API sender, доступный ведущему
+	Version string `json:"version,omitempty"` // Версия sender
+}
+
+// Node sender, зарегистрированный у ведущего
+type Node struct {
+	Registration
+	RegisteredAt time.Time `json:"registered_at"`
+	LastSeen     time.Time `json:"last_seen"` // Последнее подтверждение регистрации
+}
+
+// Registry sender, зарегистрированные у ведущего. Sender, не подтвердивший
+// регистрацию в течение missedHeartbeats периодов, исключается
+type Registry struct {
+	mu       sync.Mutex
+	nodes    map[string]*Node
+	interval time.Duration
+}
+
+// NewRegistry создает реестр sender с периодом подтверждения регистрации interval
+func NewRegistry(interval time.Duration) *Registry {
+	return &Registry{
+		nodes:    make(map[string]*Node),
+		interval: interval,
+	}
+}
+
+// HeartbeatInterval возвращает период подтверждения регистрации
+func (r *Registry) HeartbeatInterval() time.Duration {
+	return r.interval
+}
+
+// Register регистрирует sender или подтверждает его регистрацию; created - sender
+// зарегистрирован впервые или с другим адресом
+func (r *Registry) Register(reg Registration) (node Node, created bool, err error) {
+	if reg.ID == "" {
+		return Node{}, false, fmt.Errorf("не указано имя sender")
+	}
+	if err := models.ValidateLabel("id", reg.ID); err != nil {
+		return Node{}, false, err
+	}
+	if u, err := url.Parse(reg.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Node{}, false, fmt.Errorf("некорректный адрес sender: %q", reg.URL)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	current, ok := r.nodes[reg.ID]
+	if !ok || current.URL != reg.URL {
+		current = &Node{RegisteredAt: now}
+		r.nodes[reg.ID] = current
+		created = true
+	}
+	current.Registration = reg
+	current.LastSeen = now
+	return *current, created, nil
+}
+
+// Remove исключает sender id; возвращает false, если sender не зарегистрирован
+func (r *Registry) Remove(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.nodes[id]; !ok {
+		return false
+	}
+	delete(r.nodes, id)
+	return true
+}
+
+// Nodes возвращает зарегистрированные sender по имени, исключая не подтвердившие регистрацию
+func (r *Registry) Nodes() []Node {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expired := time.Now().Add(-missedHeartbeats * r.interval)
+	nodes := make([]Node, 0, len(r.nodes))
+	for id, node := range r.nodes {
+		if node.LastSeen.Before(expired) {
+			delete(r.nodes, id)
+			continue
+		}
+		nodes = append(nodes, *node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/infodiode/sender/config"
+	"github.com/infodiode/shared/binrec"
+)
+
+// generateBinaryRecord формирует двоичную запись фиксированной ширины по раскладке
+// data.binary. Запись передается в payload строкой base64, так как сообщение сериализуется в JSON;
+// получатель декодирует ее и проверяет по раскладке validation.binary
+func (g *DataGenerator) generateBinaryRecord(layout *config.BinaryConfig, id int) json.RawMessage {
+	order := layout.Order()
+
+	g.mu.Lock()
+	record := make([]byte, 0, layout.RecordSize())
+	for i := range layout.Fields {
+		record = g.appendBinaryValue(record, &layout.Fields[i], order, id)
+	}
+	g.mu.Unlock()
+
+	encoded, _ := json.Marshal(base64.StdEncoding.EncodeToString(record))
+	return encoded
+}
+
+// appendBinaryValue дописывает значение поля двоичной записи
+// (вызывается под g.mu, так как rand.Rand не потокобезопасен)
+func (g *DataGenerator) appendBinaryValue(buf []byte, field *config.BinaryField, order binary.AppendByteOrder, id int) []byte {
+	switch field.Type {
+	case config.BinaryTypeUint8:
+		return append(buf, uint8(g.binaryInt(field, id)))
+	case config.BinaryTypeUint16, config.BinaryTypeInt16:
+		return order.AppendUint16(buf, uint16(g.binaryInt(field, id)))
+	case config.BinaryTypeUint32, config.BinaryTypeInt32:
+		return order.AppendUint32(buf, uint32(g.binaryInt(field, id)))
+	case config.BinaryTypeFloat32:
+		value := field.Min + g.random.Float64()*(field.Max-field.Min)
+		return order.AppendUint32(buf, math.Float32bits(float32(value)))
+	case config.BinaryTypeFloat64:
+		value := field.Min + g.random.Float64()*(field.Max-field.Min)
+		return order.AppendUint64(buf, math.Float64bits(value))
+	case config.BinaryTypeBytes:
+		for i := 0; i < field.Length; i++ {
+			buf = append(buf, byte(g.random.Intn(256)))
+		}
+		return buf
+	case config.BinaryTypeConst:
+		value, _ := hex.DecodeString(field.Value)
+		return append(buf, value...)
+	case config.BinaryTypeTimestamp:
+		return order.AppendUint64(buf, uint64(time.Now().UnixMilli()))
+	case config.BinaryTypeCP56Time2a:
+		return appendCP56Time2a(buf, time.Now())
+	case config.BinaryTypeCRC16:
+		// CRC Modbus RTU по предшествующим байтам записи, младший байт первым
+		return binary.LittleEndian.AppendUint16(buf, binrec.CRC16Modbus(buf))
+	default:
+		return buf
+	}
+}
+
+// binaryInt возвращает значение целого поля в диапазоне [min, max]; при rule: sequence
+// номер записи, отсчитываемый от min и повторяющийся по кругу в пределах диапазона
+func (g *DataGenerator) binaryInt(field *config.BinaryField, id int) int64 {
+	min, max := int64(field.Min), int64(field.Max)
+	if field.Rule == "sequence" {
+		return min + int64(id)%(max-min+1)
+	}
+	return min + g.random.Int63n(max-min+1)
+}
+
+// appendCP56Time2a дописывает время в формате CP56Time2a (МЭК 60870-5-104):
+// миллисекунды минуты (2 байта, младший первым), минуты, часы, день месяца с днем недели, месяц, год
+func appendCP56Time2a(buf []byte, t time.Time) []byte {
+	ms := uint16(t.Second()*1000 + t.Nanosecond()/int(time.Millisecond))
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+
+	buf = binary.LittleEndian.AppendUint16(buf, ms)
+	return append(buf,
+		byte(t.Minute()),
+		byte(t.Hour()),
+		byte(t.Day()|weekday<<5),
+		byte(t.Month()),
+		byte(t.Year()%100))
+}
@@ -0,0 +1,103 @@
+package generator
+
+import (
+	"unsafe"
+
+	"github.com/infodiode/shared/models"
+)
+
+// recordOverhead память записи без строк: указатель в срезе и структура
+const recordOverhead = int64(unsafe.Sizeof(uintptr(0)) + unsafe.Sizeof(models.Data{}))
+
+// CacheStats статистика кеша загруженных файлов данных
+type CacheStats struct {
+	Files   int   `json:"files"`
+	Records int   `json:"records"`
+	Bytes   int64 `json:"bytes"` // Оценка памяти записей кеша
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"` // Загрузки файлов с диска
+
+	MappedFiles int   `json:"mapped_files"` // Файлы, отображенные в память на время тестов
+	MappedBytes int64 `json:"mapped_bytes"` // Размер отображенных файлов (не входит в Bytes)
+}
+
+// recordsSize оценивает память, занимаемую записями
+func recordsSize(data []*models.Data) int64 {
+	size := int64(len(data)) * recordOverhead
+	for _, d := range data {
+		size += int64(len(d.Timestamp) + len(d.IndicatorValue) + len(d.Raw))
+	}
+	return size
+}
+
+// cacheStore сохраняет записи файла в кеше (вызывается под cacheMu)
+func (g *DataGenerator) cacheStore(path string, data []*models.Data) {
+	g.cacheDelete(path)
+	size := recordsSize(data)
+	g.dataCache[path] = data
+	g.cacheSizes[path] = size
+	g.cacheBytes += size
+}
+
+// cacheDelete удаляет записи файла из кеша (вызывается под cacheMu)
+func (g *DataGenerator) cacheDelete(path string) {
+	g.cacheBytes -= g.cacheSizes[path]
+	delete(g.dataCache, path)
+	delete(g.cacheSizes, path)
+}
+
+// CacheStats возвращает статистику кеша загруженных файлов данных
+func (g *DataGenerator) CacheStats() CacheStats {
+	g.cacheMu.RLock()
+	defer g.cacheMu.RUnlock()
+
+	stats := CacheStats{
+		Files:  len(g.dataCache),
+		Bytes:  g.cacheBytes,
+		Hits:   g.cacheHits.Load(),
+		Misses: g.cacheMisses.Load(),
+
+		MappedFiles: int(g.mappedFiles.Load()),
+		MappedBytes: g.mappedBytes.Load(),
+	}
+	for _, data := range g.dataCache {
+		stats.Records += len(data)
+	}
+	return stats
+}
+
+// DataPath возвращает директорию файлов данных
+func (g *DataGenerator) DataPath() string {
+	return g.config.DataPath
+}
+
+// DatasetFootprint размер файла набора и оценка памяти его записей
+type DatasetFootprint struct {
+	Bytes   int64 // Содержимое файла без сжатия (записи в JSON)
+	Records int
+	Memory  int64 // Память записей после загрузки
+	Cached  bool  // Записи уже загружены в кеш
+}
+
+// Footprint оценивает память записей файла набора path, не загружая их: для файла
+// из кеша берется его оценка, иначе - размер содержимого и накладные расходы записей
+func (g *DataGenerator) Footprint(path string) (DatasetFootprint, error) {
+	size, records, err := scanDataset(path)
+	if err != nil {
+		return DatasetFootprint{}, err
+	}
+	footprint := DatasetFootprint{
+		Bytes:   size,
+		Records: records,
+		Memory:  size + int64(records)*recordOverhead,
+	}
+
+	g.cacheMu.RLock()
+	if memory, ok := g.cacheSizes[path]; ok {
+		footprint.Memory = memory
+		footprint.Cached = true
+	}
+	g.cacheMu.RUnlock()
+
+	return footprint, nil
+}
@@ -0,0 +1,160 @@
+package generator
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/infodiode/sender/config"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Расширения файлов наборов данных: JSON Lines без сжатия и сжатый zstd
+const (
+	datasetExt           = ".jsonl"
+	compressedDatasetExt = ".jsonl.zst"
+	partSuffix           = ".part" // Файл набора, запись которого не завершена
+)
+
+// datasetFileName возвращает имя файла набора с расширением по уровню сжатия из конфигурации
+func (g *DataGenerator) datasetFileName(base string) string {
+	if g.config.CompressionLevel > 0 {
+		return base + compressedDatasetExt
+	}
+	return base + datasetExt
+}
+
+// findDataset возвращает существующий файл набора base; если есть оба формата,
+// выбирается формат текущей конфигурации
+func (g *DataGenerator) findDataset(base string) string {
+	preferred := g.datasetFileName(base)
+	if _, err := os.Stat(preferred); err == nil {
+		return preferred
+	}
+
+	other := alternateDatasetName(preferred)
+	if _, err := os.Stat(other); err == nil {
+		return other
+	}
+	return preferred
+}
+
+// alternateDatasetName возвращает имя того же файла набора в другом формате
+func alternateDatasetName(filename string) string {
+	if base, ok := strings.CutSuffix(filename, compressedDatasetExt); ok {
+		return base + datasetExt
+	}
+	return strings.TrimSuffix(filename, datasetExt) + compressedDatasetExt
+}
+
+// isDatasetName проверяет расширение файла набора данных
+func isDatasetName(name string) bool {
+	return strings.HasSuffix(name, datasetExt) || strings.HasSuffix(name, compressedDatasetExt)
+}
+
+// datasetFiles возвращает файлы наборов данных директории в обоих форматах, упорядоченные по имени
+func datasetFiles(dir string) ([]string, error) {
+	var files []string
+	for _, ext := range []string{datasetExt, compressedDatasetExt} {
+		matches, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// datasetReader читает файл набора данных, распаковывая его при необходимости
+type datasetReader struct {
+	io.Reader
+	zr   *zstd.Decoder
+	file *os.File
+}
+
+// Close освобождает распаковщик и закрывает файл набора данных
+func (r *datasetReader) Close() error {
+	r.zr.Close()
+	return r.file.Close()
+}
+
+// openDataset открывает файл набора данных; файлы .zst распаковываются при чтении
+func openDataset(filename string) (io.ReadCloser, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(filename, compressedDatasetExt) {
+		return file, nil
+	}
+	// Файл читается последовательно, параллельная распаковка блоков не нужна
+	zr, err := zstd.NewReader(file, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &datasetReader{Reader: zr, zr: zr, file: file}, nil
+}
+
+// datasetWriter записывает файл набора данных во временный файл .part, сжимая его при
+// необходимости; при закрытии файл переименовывается в окончательное имя. Файл, открытый
+// или отображенный в память тестом, не изменяется при повторной генерации набора
+type datasetWriter struct {
+	io.Writer
+	zw   *zstd.Encoder
+	file *os.File
+	path string
+}
+
+// Close завершает сжатый поток, закрывает файл и переименовывает его в окончательное имя
+func (w *datasetWriter) Close() error {
+	var err error
+	if w.zw != nil {
+		err = w.zw.Close()
+	}
+	if err = errors.Join(err, w.file.Close()); err != nil {
+		os.Remove(w.file.Name())
+		return err
+	}
+	return os.Rename(w.file.Name(), w.path)
+}
+
+// Abort закрывает и удаляет незавершенный файл; файл с окончательным именем не изменяется
+func (w *datasetWriter) Abort() {
+	if w.zw != nil {
+		w.zw.Close()
+	}
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+// createDataset создает файл набора данных; файлы .zst сжимаются с уровнем из конфигурации
+func (g *DataGenerator) createDataset(filename string) (*datasetWriter, error) {
+	file, err := os.Create(filename + partSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(filename, compressedDatasetExt) {
+		return &datasetWriter{Writer: file, file: file, path: filename}, nil
+	}
+
+	level := g.config.CompressionLevel
+	if level <= 0 {
+		level = config.DefaultCompressionLevel
+	}
+	zw, err := zstd.NewWriter(file,
+		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+		zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return &datasetWriter{Writer: zw, zw: zw, file: file, path: filename}, nil
+}
@@ -0,0 +1,126 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/infodiode/sender/config"
+	"go.uber.org/zap"
+)
+
+// testDatasetContent возвращает JSON Lines достаточного размера для нескольких блоков zstd
+func testDatasetContent() []byte {
+	var b strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&b, `{"id":%d,"equipment_id":%d,"value":%d.5}`+"\n", i, i%17, i*7)
+	}
+	return []byte(b.String())
+}
+
+func writeTestDataset(t *testing.T, level int, filename string, content []byte) {
+	t.Helper()
+	g := NewDataGenerator(&Config{CompressionLevel: level}, zap.NewNop())
+	w, err := g.createDataset(filename)
+	if err != nil {
+		t.Fatalf("createDataset: %v", err)
+	}
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func readTestDataset(t *testing.T, filename string) []byte {
+	t.Helper()
+	r, err := openDataset(filename)
+	if err != nil {
+		t.Fatalf("openDataset: %v", err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	return data
+}
+
+func TestCompressedDatasetRoundTrip(t *testing.T) {
+	content := testDatasetContent()
+	for level := 1; level <= config.MaxCompressionLevel; level++ {
+		filename := filepath.Join(t.TempDir(), "batch"+compressedDatasetExt)
+		writeTestDataset(t, level, filename, content)
+
+		info, err := os.Stat(filename)
+		if err != nil {
+			t.Fatalf("уровень %d: %v", level, err)
+		}
+		if info.Size() >= int64(len(content)) {
+			t.Errorf("уровень %d: сжатый размер %d не меньше исходного %d", level, info.Size(), len(content))
+		}
+		if _, err := os.Stat(filename + partSuffix); !os.IsNotExist(err) {
+			t.Errorf("уровень %d: временный файл не удален", level)
+		}
+
+		if got := readTestDataset(t, filename); !bytes.Equal(got, content) {
+			t.Fatalf("уровень %d: распакованные данные отличаются от исходных", level)
+		}
+	}
+}
+
+func TestDatasetWriterAbort(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "batch"+compressedDatasetExt)
+	g := NewDataGenerator(&Config{CompressionLevel: 3}, zap.NewNop())
+	w, err := g.createDataset(filename)
+	if err != nil {
+		t.Fatalf("createDataset: %v", err)
+	}
+	w.Write(testDatasetContent())
+	w.Abort()
+
+	for _, name := range []string{filename, filename + partSuffix} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("файл %s остался после Abort", name)
+		}
+	}
+}
+
+// TestCompressedDatasetInterop проверяет совместимость с утилитой zstd в обе стороны
+func TestCompressedDatasetInterop(t *testing.T) {
+	tool, err := exec.LookPath("zstd")
+	if err != nil {
+		t.Skip("утилита zstd не найдена")
+	}
+	content := testDatasetContent()
+	dir := t.TempDir()
+
+	// Файл генератора распаковывается утилитой
+	generated := filepath.Join(dir, "generated"+compressedDatasetExt)
+	writeTestDataset(t, 5, generated, content)
+	out, err := exec.Command(tool, "-d", "-c", generated).Output()
+	if err != nil {
+		t.Fatalf("zstd -d: %v", err)
+	}
+	if !bytes.Equal(out, content) {
+		t.Fatal("данные, распакованные утилитой zstd, отличаются от исходных")
+	}
+
+	// Файл, сжатый утилитой, читается генератором
+	plain := filepath.Join(dir, "external"+datasetExt)
+	if err := os.WriteFile(plain, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command(tool, "-q", "-19", plain).Run(); err != nil {
+		t.Fatalf("zstd: %v", err)
+	}
+	if got := readTestDataset(t, plain+".zst"); !bytes.Equal(got, content) {
+		t.Fatal("данные файла, сжатого утилитой zstd, отличаются от исходных")
+	}
+}
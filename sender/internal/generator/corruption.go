@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CorruptionKind вид искажения записи для негативных тестов валидации
+type CorruptionKind string
+
+const (
+	CorruptIndicatorLength CorruptionKind = "indicator_length" // indicator_value неверной длины
+	CorruptOutOfRange      CorruptionKind = "out_of_range"     // indicator_id/equipment_id вне допустимых диапазонов
+	CorruptTimestamp       CorruptionKind = "bad_timestamp"    // некорректный формат timestamp
+	CorruptTruncatedJSON   CorruptionKind = "truncated_json"   // обрезанный JSON
+)
+
+// CorruptionKinds все поддерживаемые виды искажений
+var CorruptionKinds = []CorruptionKind{
+	CorruptIndicatorLength,
+	CorruptOutOfRange,
+	CorruptTimestamp,
+	CorruptTruncatedJSON,
+}
+
+// malformedTimestamps примеры некорректных временных меток
+var malformedTimestamps = []string{
+	"2024-13-45T25:61:00Z",
+	"20/01/2024 15:30:45",
+	"not-a-timestamp",
+	"1705764645",
+}
+
+// ParseCorruptionKinds разбирает список видов искажений; пустой список означает все виды
+func ParseCorruptionKinds(values []string) ([]CorruptionKind, error) {
+	if len(values) == 0 {
+		return CorruptionKinds, nil
+	}
+
+	kinds := make([]CorruptionKind, 0, len(values))
+	for _, value := range values {
+		kind := CorruptionKind(value)
+		switch kind {
+		case CorruptIndicatorLength, CorruptOutOfRange, CorruptTimestamp, CorruptTruncatedJSON:
+			kinds = append(kinds, kind)
+		default:
+			return nil, fmt.Errorf("неизвестный вид искажения: %s", value)
+		}
+	}
+	return kinds, nil
+}
+
+// GenerateInvalidData генерирует сериализованную запись с указанным искажением
+func (g *DataGenerator) GenerateInvalidData(kind CorruptionKind) ([]byte, error) {
+	data := g.generateStandardData(g.nextID())
+
+	switch kind {
+	case CorruptIndicatorLength:
+		length := g.randomInRange(1, 30)
+		if length == 15 {
+			length = 16
+		}
+		data.IndicatorValue = strings.Repeat("9", length)
+	case CorruptOutOfRange:
+		// Значения за пределами диапазонов, проверяемых recipient
+		data.IndicatorID = g.config.IndicatorIDRange[1] + 1000 + g.randomInRange(0, 1000)
+		data.EquipmentID = g.config.EquipmentIDRange[1] + 100 + g.randomInRange(0, 100)
+	case CorruptTimestamp:
+		data.Timestamp = malformedTimestamps[g.randomInRange(0, len(malformedTimestamps)-1)]
+	case CorruptTruncatedJSON:
+	default:
+		return nil, fmt.Errorf("неизвестный вид искажения: %s", kind)
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации записи: %w", err)
+	}
+
+	if kind == CorruptTruncatedJSON {
+		// Обрезаем запись случайным образом, но не меньше чем до одного байта
+		payload = payload[:g.randomInRange(1, len(payload)-1)]
+	}
+
+	return payload, nil
+}
+
+// GenerateInvalidBatch генерирует пакет искаженных записей, чередуя указанные виды искажений
+func (g *DataGenerator) GenerateInvalidBatch(count int, kinds []CorruptionKind) ([][]byte, error) {
+	if len(kinds) == 0 {
+		kinds = CorruptionKinds
+	}
+
+	batch := make([][]byte, count)
+	for i := range batch {
+		payload, err := g.GenerateInvalidData(kinds[i%len(kinds)])
+		if err != nil {
+			return nil, err
+		}
+		batch[i] = payload
+	}
+	return batch, nil
+}
@@ -0,0 +1,299 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/infodiode/shared/models"
+)
+
+// DatasetSets наборы тестовых данных, каждый в своей поддиректории data_path
+var DatasetSets = []string{"small", "medium", "large"}
+
+// ErrDatasetNotFound файл набора данных не найден
+var ErrDatasetNotFound = errors.New("набор данных не найден")
+
+// Dataset файл сгенерированных тестовых данных
+type Dataset struct {
+	Set        string    `json:"set"`         // Набор (small, medium, large)
+	Name       string    `json:"name"`        // Имя файла в наборе
+	SizeBytes  int64     `json:"size_bytes"`  // Размер файла
+	Compressed bool      `json:"compressed"`  // Файл сжат zstd (.jsonl.zst)
+	Records    int       `json:"records"`     // Количество записей
+	ModifiedAt time.Time `json:"modified_at"` // Время изменения файла
+}
+
+// ListDatasets возвращает файлы всех наборов данных, упорядоченные по набору и имени
+func (g *DataGenerator) ListDatasets() ([]Dataset, error) {
+	datasets, err := g.datasetEntries(DatasetSets)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range datasets {
+		path := g.datasetPath(datasets[i].Set, datasets[i].Name)
+		datasets[i].Records, err = g.countRecords(path)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения %s: %w", path, err)
+		}
+	}
+	return datasets, nil
+}
+
+// datasetEntries возвращает файлы наборов sets без подсчета записей
+func (g *DataGenerator) datasetEntries(sets []string) ([]Dataset, error) {
+	datasets := []Dataset{}
+	for _, set := range sets {
+		files, err := datasetFiles(filepath.Join(g.config.DataPath, set))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			info, err := os.Stat(file)
+			if err != nil {
+				continue
+			}
+
+			datasets = append(datasets, Dataset{
+				Set:        set,
+				Name:       info.Name(),
+				SizeBytes:  info.Size(),
+				Compressed: strings.HasSuffix(info.Name(), compressedDatasetExt),
+				ModifiedAt: info.ModTime(),
+			})
+		}
+	}
+	return datasets, nil
+}
+
+// DeleteDataset удаляет файл name набора set; при пустом name удаляется весь набор.
+// Возвращает количество удаленных файлов
+func (g *DataGenerator) DeleteDataset(set, name string) (int, error) {
+	if err := ValidateDataset(set, name); err != nil {
+		return 0, err
+	}
+
+	var names []string
+	if name != "" {
+		names = []string{name}
+	} else {
+		files, err := datasetFiles(filepath.Join(g.config.DataPath, set))
+		if err != nil {
+			return 0, err
+		}
+		for _, file := range files {
+			names = append(names, filepath.Base(file))
+		}
+	}
+
+	deleted := 0
+	for _, n := range names {
+		if err := g.removeDataset(set, n); err != nil {
+			if errors.Is(err, os.ErrNotExist) && name != "" {
+				return deleted, ErrDatasetNotFound
+			}
+			return deleted, err
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// DatasetFilter отбор файлов наборов для удаления
+type DatasetFilter struct {
+	Sets   []string  // Наборы (пусто - все)
+	Before time.Time // Файлы, измененные раньше (нулевое - любые)
+}
+
+// PruneResult итог удаления файлов наборов по отбору или политике хранения
+type PruneResult struct {
+	Deleted    int      `json:"deleted"`
+	FreedBytes int64    `json:"freed_bytes"`
+	Files      []string `json:"files"` // Удаленные файлы: набор/имя
+}
+
+// add учитывает удаленный файл набора
+func (r *PruneResult) add(d Dataset) {
+	r.Deleted++
+	r.FreedBytes += d.SizeBytes
+	r.Files = append(r.Files, d.Set+"/"+d.Name)
+}
+
+// DeleteDatasets удаляет файлы наборов, подходящие под filter. При ошибке возвращаются
+// файлы, удаленные до нее
+func (g *DataGenerator) DeleteDatasets(filter DatasetFilter) (PruneResult, error) {
+	result := PruneResult{Files: []string{}}
+
+	sets := filter.Sets
+	if len(sets) == 0 {
+		sets = DatasetSets
+	}
+	for _, set := range sets {
+		if err := ValidateDataset(set, ""); err != nil {
+			return result, err
+		}
+	}
+
+	datasets, err := g.datasetEntries(sets)
+	if err != nil {
+		return result, err
+	}
+	for _, d := range datasets {
+		if !filter.Before.IsZero() && !d.ModifiedAt.Before(filter.Before) {
+			continue
+		}
+		if err := g.removeDataset(d.Set, d.Name); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return result, err
+		}
+		result.add(d)
+	}
+	return result, nil
+}
+
+// removeDataset удаляет файл name набора set и его записи из кеша
+func (g *DataGenerator) removeDataset(set, name string) error {
+	path := g.datasetPath(set, name)
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("не удалось удалить %s: %w", path, err)
+	}
+
+	// Загруженные в кеш данные удаленного файла больше не используются тестами
+	g.cacheMu.Lock()
+	g.cacheDelete(path)
+	g.cacheMu.Unlock()
+	return nil
+}
+
+// SampleDataset возвращает первые count записей файла name набора set в формате JSON Lines
+// (сжатый файл распаковывается)
+func (g *DataGenerator) SampleDataset(set, name string, count int) ([]byte, error) {
+	if err := ValidateDataset(set, name); err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, fmt.Errorf("не указано имя файла набора данных")
+	}
+
+	file, err := openDataset(g.datasetPath(set, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrDatasetNotFound
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var sample bytes.Buffer
+	reader := bufio.NewReader(file)
+	for i := 0; i < count; i++ {
+		line, err := reader.ReadBytes('\n')
+		sample.Write(line)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sample.Bytes(), nil
+}
+
+// GetDatasetFile возвращает записи файла name набора set (имя как в списке ListDatasets)
+func (g *DataGenerator) GetDatasetFile(set, name string) ([]*models.Data, error) {
+	path, err := g.DatasetFilePath(set, name)
+	if err != nil {
+		return nil, err
+	}
+	return g.LoadFromFile(path)
+}
+
+// DatasetFilePath проверяет файл name набора set и возвращает путь к нему
+func (g *DataGenerator) DatasetFilePath(set, name string) (string, error) {
+	if err := ValidateDataset(set, name); err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", fmt.Errorf("не указано имя файла набора данных")
+	}
+
+	path := g.datasetPath(set, name)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return "", ErrDatasetNotFound
+	}
+	return path, nil
+}
+
+// countRecords возвращает количество записей файла: из кеша, если файл загружен, иначе по числу строк
+func (g *DataGenerator) countRecords(path string) (int, error) {
+	g.cacheMu.RLock()
+	cached, ok := g.dataCache[path]
+	g.cacheMu.RUnlock()
+	if ok {
+		return len(cached), nil
+	}
+
+	_, records, err := scanDataset(path)
+	return records, err
+}
+
+// scanDataset читает файл набора (со сжатием - распаковывая) и возвращает размер
+// содержимого и число строк, не декодируя записи
+func scanDataset(path string) (int64, int, error) {
+	file, err := openDataset(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	var size int64
+	records := 0
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := file.Read(buf)
+		size += int64(n)
+		records += bytes.Count(buf[:n], []byte{'\n'})
+		if err == io.EOF {
+			return size, records, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+}
+
+// datasetPath возвращает путь к файлу набора в том же виде, что и ключи кеша данных
+func (g *DataGenerator) datasetPath(set, name string) string {
+	return fmt.Sprintf("%s/%s/%s", g.config.DataPath, set, name)
+}
+
+// ValidateDataset проверяет набор и имя файла (без пути)
+func ValidateDataset(set, name string) error {
+	known := false
+	for _, s := range DatasetSets {
+		if s == set {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("неизвестный набор данных: %s (допустимы %s)", set, strings.Join(DatasetSets, ", "))
+	}
+
+	if name != "" && (filepath.Base(name) != name || !isDatasetName(name) || strings.HasPrefix(name, ".")) {
+		return fmt.Errorf("некорректное имя файла набора данных: %s", name)
+	}
+	return nil
+}
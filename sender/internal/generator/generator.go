@@ -1,13 +1,20 @@
 package generator
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/infodiode/sender/config"
 	"github.com/infodiode/shared/models"
 	"github.com/infodiode/shared/utils"
 	"go.uber.org/zap"
@@ -22,6 +29,18 @@ type DataGenerator struct {
 	mu        sync.Mutex
 	dataCache map[string][]*models.Data
 	cacheMu   sync.RWMutex
+	tags      *tagTable    // Выбор импортированных тегов, nil - случайные показатели из диапазонов
+	values    *valueModels // Модели значений показателей, nil - случайные значения
+
+	cacheSizes  map[string]int64 // Оценка памяти записей каждого файла кеша
+	cacheBytes  int64
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+
+	mappedFiles atomic.Int64 // Открытые файлы, отображенные в память (MappedDataset)
+	mappedBytes atomic.Int64
+
+	retention retentionState // Политика хранения наборов и фоновая очистка по ней
 }
 
 // Config конфигурация генератора
@@ -37,6 +56,11 @@ type Config struct {
 	SmallBatchSize   int
 	MediumBatchSize  int
 	LargeBatchSizes  []int
+	CompressionLevel int                  // Уровень сжатия zstd генерируемых файлов (0 - без сжатия)
+	Schema           []config.SchemaField // Пользовательская схема записи (пусто - стандартная запись)
+	Binary           config.BinaryConfig  // Раскладка двоичной записи (без полей - JSON запись)
+	Tags             []Tag                // Импортированные определения тегов (пусто - диапазоны и распределение типов)
+	ValueModels      []config.ValueModel  // Модели значений показателей (пусто - случайные значения)
 }
 
 // NewDataGenerator создает новый генератор данных
@@ -48,24 +72,94 @@ func NewDataGenerator(config *Config, logger *zap.Logger) *DataGenerator {
 		random:    rand.New(source),
 		idCounter: 1,
 		dataCache: make(map[string][]*models.Data),
+
+		cacheSizes: make(map[string]int64),
+		tags:       newTagTable(config.Tags),
+		values:     newValueModels(config.ValueModels),
 	}
 }
 
+// WithSeed создает генератор с той же конфигурацией и собственным источником случайных
+// чисел, инициализированным seed. Используется тестами, чтобы сгенерированные ими данные
+// воспроизводились при повторном запуске с тем же seed независимо от других генераций
+func (g *DataGenerator) WithSeed(seed int64) *DataGenerator {
+	g.mu.Lock()
+	cfg := *g.config
+	g.mu.Unlock()
+
+	cfg.Seed = seed
+	return NewDataGenerator(&cfg, g.logger)
+}
+
+// ConfigHash возвращает хеш параметров генерации (SHA-256, первые 8 байт в hex).
+// Путь к данным не учитывается, чтобы хеши совпадали на разных окружениях
+func (g *DataGenerator) ConfigHash() string {
+	g.mu.Lock()
+	cfg := *g.config
+	g.mu.Unlock()
+
+	cfg.DataPath = ""
+	encoded, _ := json.Marshal(cfg)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:8])
+}
+
 // GenerateData генерирует одну запись данных
 func (g *DataGenerator) GenerateData() *models.Data {
+	id := g.nextID()
+
+	if len(g.config.Binary.Fields) > 0 {
+		return &models.Data{
+			ID:        id,
+			Timestamp: utils.GetCurrentTime(),
+			Raw:       g.generateBinaryRecord(&g.config.Binary, id),
+		}
+	}
+
+	if len(g.config.Schema) > 0 {
+		return &models.Data{
+			ID:        id,
+			Timestamp: utils.GetCurrentTime(),
+			Raw:       g.generateRecord(g.config.Schema, id),
+		}
+	}
+
+	return g.generateStandardData(id)
+}
+
+// nextID возвращает следующий идентификатор записи
+func (g *DataGenerator) nextID() int {
 	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	id := g.idCounter
 	g.idCounter++
-	g.mu.Unlock()
+	return id
+}
+
+// generateStandardData генерирует стандартную запись из 5 полей
+func (g *DataGenerator) generateStandardData(id int) *models.Data {
+	if g.tags != nil {
+		return g.generateTagData(id)
+	}
 
 	indicatorID := g.randomInRange(g.config.IndicatorIDRange[0], g.config.IndicatorIDRange[1])
 	equipmentID := g.randomInRange(g.config.EquipmentIDRange[0], g.config.EquipmentIDRange[1])
 
+	// Показатель с моделью значений получает очередное значение своего ряда
+	// вместо случайного значения по распределению типов
+	var indicatorValue string
+	if value, ok := g.values.next(indicatorID, equipmentID, g.random); ok {
+		indicatorValue = formatModelValue(value, 2)
+	} else {
+		indicatorValue = g.generateIndicatorValue()
+	}
+
 	return &models.Data{
 		ID:             id,
 		Timestamp:      utils.GetCurrentTime(),
 		IndicatorID:    indicatorID,
-		IndicatorValue: g.generateIndicatorValue(),
+		IndicatorValue: indicatorValue,
 		EquipmentID:    equipmentID,
 	}
 }
@@ -75,17 +169,71 @@ func (g *DataGenerator) generateIndicatorValue() string {
 	// Определяем тип значения на основе процентного распределения
 	roll := g.random.Float64() * 100
 
-	if roll < g.config.NullPercent {
-		return "null"
-	} else if roll < g.config.NullPercent+g.config.BoolPercent {
+	g.mu.Lock()
+	nullPercent, boolPercent, floatPercent := g.config.NullPercent, g.config.BoolPercent, g.config.FloatPercent
+	g.mu.Unlock()
+
+	if roll < nullPercent {
+		return padToLength("null", 15)
+	} else if roll < nullPercent+boolPercent {
 		return g.generateBoolValue()
-	} else if roll < g.config.NullPercent+g.config.BoolPercent+g.config.FloatPercent {
+	} else if roll < nullPercent+boolPercent+floatPercent {
 		return g.generateFloatValue()
 	} else {
 		return g.generateStringValue()
 	}
 }
 
+// generateTagData генерирует запись импортированного тега: теги выбираются с частотой,
+// пропорциональной весу, значение формируется по типу тега; числовые теги показателей
+// с моделью значений получают очередное значение ряда
+func (g *DataGenerator) generateTagData(id int) *models.Data {
+	tag := g.tags.pick(g.random)
+
+	var value string
+	modelValue, modeled := 0.0, false
+	if tag.ValueType == TagTypeInt || tag.ValueType == TagTypeFloat {
+		modelValue, modeled = g.values.next(tag.IndicatorID, tag.EquipmentID, g.random)
+	}
+
+	switch {
+	case modeled && tag.ValueType == TagTypeInt:
+		value = formatModelValue(math.Round(modelValue), 0)
+	case modeled:
+		value = formatModelValue(modelValue, 2)
+	case tag.ValueType == TagTypeNull:
+		value = padToLength("null", 15)
+	case tag.ValueType == TagTypeBool:
+		value = g.generateBoolValue()
+	case tag.ValueType == TagTypeInt:
+		value = padToLength(strconv.Itoa(g.randomInRange(-99999, 99999)), 15)
+	case tag.ValueType == TagTypeFloat:
+		value = g.generateFloatValue()
+	default:
+		value = g.generateStringValue()
+	}
+
+	return &models.Data{
+		ID:             id,
+		Timestamp:      utils.GetCurrentTime(),
+		IndicatorID:    tag.IndicatorID,
+		IndicatorValue: value,
+		EquipmentID:    tag.EquipmentID,
+	}
+}
+
+// SetDistribution изменяет процентное распределение типов значений индикатора.
+// Действует на данные, генерируемые после вызова; сохраненные файлы не меняются
+func (g *DataGenerator) SetDistribution(nullPercent, boolPercent, floatPercent, stringPercent float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.config.NullPercent = nullPercent
+	g.config.BoolPercent = boolPercent
+	g.config.FloatPercent = floatPercent
+	g.config.StringPercent = stringPercent
+}
+
 // generateBoolValue генерирует булево значение (15 символов)
 func (g *DataGenerator) generateBoolValue() string {
 	if g.random.Intn(2) == 0 {
@@ -117,7 +265,7 @@ func padToLength(s string, length int) string {
 	if len(s) >= length {
 		return s[:length]
 	}
-	return s + string(make([]byte, length-len(s)))
+	return s + strings.Repeat(" ", length-len(s))
 }
 
 // randomInRange генерирует случайное число в диапазоне [min, max]
@@ -134,7 +282,8 @@ func (g *DataGenerator) GenerateBatch(count int) []*models.Data {
 	return batch
 }
 
-// SaveToFile сохраняет данные в файл в формате JSON Lines
+// SaveToFile сохраняет данные в файл в формате JSON Lines; файл .jsonl.zst сжимается zstd.
+// Файл того же набора в другом формате удаляется, чтобы тесты не использовали устаревшие данные
 func (g *DataGenerator) SaveToFile(filename string, data []*models.Data) error {
 	// Создаем директорию если не существует
 	dir := filepath.Dir(filename)
@@ -143,22 +292,34 @@ func (g *DataGenerator) SaveToFile(filename string, data []*models.Data) error {
 	}
 
 	// Открываем файл для записи
-	file, err := os.Create(filename)
+	file, err := g.createDataset(filename)
 	if err != nil {
 		return fmt.Errorf("не удалось создать файл %s: %w", filename, err)
 	}
-	defer file.Close()
 
 	// Записываем данные в формате JSON Lines
 	encoder := json.NewEncoder(file)
 	for _, item := range data {
 		if err := encoder.Encode(item); err != nil {
+			file.Abort()
 			return fmt.Errorf("ошибка записи в файл: %w", err)
 		}
 	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("ошибка записи в файл: %w", err)
+	}
+
+	if isDatasetName(filename) {
+		other := alternateDatasetName(filename)
+		if err := os.Remove(other); err == nil {
+			g.cacheMu.Lock()
+			g.cacheDelete(other)
+			g.cacheMu.Unlock()
+		}
+	}
 
 	// Получаем информацию о файле
-	fileInfo, err := file.Stat()
+	fileInfo, err := os.Stat(filename)
 	if err != nil {
 		return err
 	}
@@ -177,12 +338,14 @@ func (g *DataGenerator) LoadFromFile(filename string) ([]*models.Data, error) {
 	g.cacheMu.RLock()
 	if cached, ok := g.dataCache[filename]; ok {
 		g.cacheMu.RUnlock()
+		g.cacheHits.Add(1)
 		return cached, nil
 	}
 	g.cacheMu.RUnlock()
+	g.cacheMisses.Add(1)
 
 	// Открываем файл
-	file, err := os.Open(filename)
+	file, err := openDataset(filename)
 	if err != nil {
 		return nil, fmt.Errorf("не удалось открыть файл %s: %w", filename, err)
 	}
@@ -192,16 +355,16 @@ func (g *DataGenerator) LoadFromFile(filename string) ([]*models.Data, error) {
 	var data []*models.Data
 	decoder := json.NewDecoder(file)
 	for decoder.More() {
-		var item models.Data
-		if err := decoder.Decode(&item); err != nil {
+		item, err := g.decodeRecord(decoder)
+		if err != nil {
 			return nil, fmt.Errorf("ошибка чтения из файла: %w", err)
 		}
-		data = append(data, &item)
+		data = append(data, item)
 	}
 
 	// Сохраняем в кеш
 	g.cacheMu.Lock()
-	g.dataCache[filename] = data
+	g.cacheStore(filename, data)
 	g.cacheMu.Unlock()
 
 	g.logger.Info("Данные загружены из файла",
@@ -211,6 +374,24 @@ func (g *DataGenerator) LoadFromFile(filename string) ([]*models.Data, error) {
 	return data, nil
 }
 
+// decodeRecord читает одну запись; при пользовательской схеме или двоичной записи
+// запись сохраняется как есть
+func (g *DataGenerator) decodeRecord(decoder *json.Decoder) (*models.Data, error) {
+	if len(g.config.Schema) > 0 || len(g.config.Binary.Fields) > 0 {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+		return &models.Data{Raw: raw}, nil
+	}
+
+	var item models.Data
+	if err := decoder.Decode(&item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
 // GenerateAllTestData генерирует все тестовые данные
 func (g *DataGenerator) GenerateAllTestData() error {
 	g.logger.Info("Начало генерации всех тестовых данных")
@@ -244,7 +425,7 @@ func (g *DataGenerator) GenerateSmallBatches() error {
 
 	for i := 1; i <= numFiles; i++ {
 		data := g.GenerateBatch(recordsPerFile)
-		filename := fmt.Sprintf("%s/small/batch_%03d.jsonl", g.config.DataPath, i)
+		filename := g.datasetFileName(fmt.Sprintf("%s/small/batch_%03d", g.config.DataPath, i))
 
 		if err := g.SaveToFile(filename, data); err != nil {
 			return fmt.Errorf("ошибка генерации маленького пакета %d: %w", i, err)
@@ -264,7 +445,7 @@ func (g *DataGenerator) GenerateMediumBatches() error {
 
 	for i := 1; i <= numFiles; i++ {
 		data := g.GenerateBatch(recordsPerFile)
-		filename := fmt.Sprintf("%s/medium/batch_%03d.jsonl", g.config.DataPath, i)
+		filename := g.datasetFileName(fmt.Sprintf("%s/medium/batch_%03d", g.config.DataPath, i))
 
 		if err := g.SaveToFile(filename, data); err != nil {
 			return fmt.Errorf("ошибка генерации среднего пакета %d: %w", i, err)
@@ -294,7 +475,7 @@ func (g *DataGenerator) GenerateLargeBatches() error {
 		}
 
 		data := g.GenerateBatch(recordsCount)
-		filename := fmt.Sprintf("%s/large/batch_%dmb.jsonl", g.config.DataPath, sizeMB)
+		filename := g.datasetFileName(fmt.Sprintf("%s/large/batch_%dmb", g.config.DataPath, sizeMB))
 
 		if err := g.SaveToFile(filename, data); err != nil {
 			return fmt.Errorf("ошибка генерации большого пакета %dMB: %w", sizeMB, err)
@@ -304,30 +485,39 @@ func (g *DataGenerator) GenerateLargeBatches() error {
 	return nil
 }
 
-// GetDataForTest возвращает данные для конкретного теста
+// GetDataForTest возвращает данные для конкретного теста (из файла .jsonl или .jsonl.zst)
 func (g *DataGenerator) GetDataForTest(testType string, size int) ([]*models.Data, error) {
-	var filename string
+	path, err := g.TestDataPath(testType, size)
+	if err != nil {
+		return nil, err
+	}
+	return g.LoadFromFile(path)
+}
+
+// TestDataPath возвращает путь к файлу данных для теста (как GetDataForTest)
+func (g *DataGenerator) TestDataPath(testType string, size int) (string, error) {
+	var base string
 
 	switch testType {
 	case "small":
 		// Берем первый файл из маленьких пакетов
-		filename = fmt.Sprintf("%s/small/batch_001.jsonl", g.config.DataPath)
+		base = fmt.Sprintf("%s/small/batch_001", g.config.DataPath)
 	case "medium":
 		// Берем первый файл из средних пакетов
-		filename = fmt.Sprintf("%s/medium/batch_001.jsonl", g.config.DataPath)
+		base = fmt.Sprintf("%s/medium/batch_001", g.config.DataPath)
 	case "large":
 		// Берем файл соответствующего размера
-		filename = fmt.Sprintf("%s/large/batch_%dmb.jsonl", g.config.DataPath, size)
+		base = fmt.Sprintf("%s/large/batch_%dmb", g.config.DataPath, size)
 	default:
-		return nil, fmt.Errorf("неизвестный тип теста: %s", testType)
+		return "", fmt.Errorf("неизвестный тип теста: %s", testType)
 	}
 
-	return g.LoadFromFile(filename)
+	return g.findDataset(base), nil
 }
 
 // StreamDataFromFile читает данные из файла построчно без загрузки в память
 func (g *DataGenerator) StreamDataFromFile(filename string, handler func(*models.Data) error) error {
-	file, err := os.Open(filename)
+	file, err := openDataset(filename)
 	if err != nil {
 		return fmt.Errorf("не удалось открыть файл %s: %w", filename, err)
 	}
@@ -338,8 +528,8 @@ func (g *DataGenerator) StreamDataFromFile(filename string, handler func(*models
 
 	for decoder.More() {
 		lineNum++
-		var item models.Data
-		if err := decoder.Decode(&item); err != nil {
+		item, err := g.decodeRecord(decoder)
+		if err != nil {
 			g.logger.Error("Ошибка декодирования строки",
 				zap.String("файл", filename),
 				zap.Int("строка", lineNum),
@@ -347,7 +537,7 @@ func (g *DataGenerator) StreamDataFromFile(filename string, handler func(*models
 			continue
 		}
 
-		if err := handler(&item); err != nil {
+		if err := handler(item); err != nil {
 			return fmt.Errorf("ошибка обработки данных на строке %d: %w", lineNum, err)
 		}
 	}
@@ -359,61 +549,46 @@ func (g *DataGenerator) StreamDataFromFile(filename string, handler func(*models
 func (g *DataGenerator) ClearCache() {
 	g.cacheMu.Lock()
 	g.dataCache = make(map[string][]*models.Data)
+	g.cacheSizes = make(map[string]int64)
+	g.cacheBytes = 0
 	g.cacheMu.Unlock()
 	g.logger.Info("Кеш данных очищен")
 }
 
 // GetStatistics возвращает статистику по сгенерированным данным
 func (g *DataGenerator) GetStatistics() (*GeneratorStats, error) {
-	stats := &GeneratorStats{
-		SmallBatches:  0,
-		MediumBatches: 0,
-		LargeBatches:  0,
-		TotalRecords:  0,
-		TotalSize:     0,
-	}
-
-	// Подсчет маленьких пакетов
-	smallPath := filepath.Join(g.config.DataPath, "small")
-	if files, err := filepath.Glob(filepath.Join(smallPath, "*.jsonl")); err == nil {
-		stats.SmallBatches = len(files)
-		for _, file := range files {
-			if info, err := os.Stat(file); err == nil {
-				stats.TotalSize += info.Size()
-			}
-		}
+	datasets, err := g.ListDatasets()
+	if err != nil {
+		return nil, err
 	}
 
-	// Подсчет средних пакетов
-	mediumPath := filepath.Join(g.config.DataPath, "medium")
-	if files, err := filepath.Glob(filepath.Join(mediumPath, "*.jsonl")); err == nil {
-		stats.MediumBatches = len(files)
-		for _, file := range files {
-			if info, err := os.Stat(file); err == nil {
-				stats.TotalSize += info.Size()
-			}
-		}
-	}
+	stats := SummarizeDatasets(datasets)
+	return &stats, nil
+}
 
-	// Подсчет больших пакетов
-	largePath := filepath.Join(g.config.DataPath, "large")
-	if files, err := filepath.Glob(filepath.Join(largePath, "*.jsonl")); err == nil {
-		stats.LargeBatches = len(files)
-		for _, file := range files {
-			if info, err := os.Stat(file); err == nil {
-				stats.TotalSize += info.Size()
-			}
+// SummarizeDatasets подсчитывает количество файлов по наборам, записей и общий размер
+func SummarizeDatasets(datasets []Dataset) GeneratorStats {
+	var stats GeneratorStats
+	for _, ds := range datasets {
+		switch ds.Set {
+		case "small":
+			stats.SmallBatches++
+		case "medium":
+			stats.MediumBatches++
+		case "large":
+			stats.LargeBatches++
 		}
+		stats.TotalRecords += ds.Records
+		stats.TotalSize += ds.SizeBytes
 	}
-
-	return stats, nil
+	return stats
 }
 
 // GeneratorStats статистика генератора
 type GeneratorStats struct {
-	SmallBatches  int
-	MediumBatches int
-	LargeBatches  int
-	TotalRecords  int
-	TotalSize     int64
+	SmallBatches  int   `json:"small_batches"`
+	MediumBatches int   `json:"medium_batches"`
+	LargeBatches  int   `json:"large_batches"`
+	TotalRecords  int   `json:"total_records"`
+	TotalSize     int64 `json:"total_size_bytes"`
 }
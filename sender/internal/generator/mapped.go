@@ -0,0 +1,191 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// Records записи набора данных с доступом по индексу
+type Records interface {
+	Len() int
+	At(i int) *models.Data
+}
+
+// RecordSlice записи набора, загруженные в память
+type RecordSlice []*models.Data
+
+// Len возвращает количество записей
+func (s RecordSlice) Len() int {
+	return len(s)
+}
+
+// At возвращает запись i
+func (s RecordSlice) At(i int) *models.Data {
+	return s[i]
+}
+
+// MappedDataset файл набора JSON Lines, отображенный в память: при открытии строятся
+// смещения строк и проверяется каждая запись, а At декодирует запись из отображения
+// при обращении. В памяти процесса остаются только смещения, страницы файла
+// загружаются и вытесняются ядром. Файл должен быть закрыт через Close
+type MappedDataset struct {
+	generator *DataGenerator
+	path      string
+	data      []byte
+	offsets   []int64 // Начала строк; последний элемент - конец данных
+	raw       bool    // Записи пользовательской схемы или двоичные: сохраняются как есть
+}
+
+// IndexEntrySize память индекса отображенного набора на одну запись (смещение строки)
+const IndexEntrySize = 8
+
+// MapsFile сообщает, будет ли OpenRecords отображать файл набора path в память:
+// файл без сжатия размером не меньше mmapThreshold байт (0 - не отображать),
+// записи которого еще не загружены в кеш
+func (g *DataGenerator) MapsFile(path string, mmapThreshold int64) bool {
+	if mmapThreshold <= 0 || strings.HasSuffix(path, compressedDatasetExt) {
+		return false
+	}
+
+	g.cacheMu.RLock()
+	_, cached := g.dataCache[path]
+	g.cacheMu.RUnlock()
+	if cached {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	return err == nil && info.Size() >= mmapThreshold
+}
+
+// OpenRecords возвращает записи файла набора path: файл, выбранный MapsFile,
+// отображается в память (OpenMapped), остальные возвращаются из LoadFromFile
+func (g *DataGenerator) OpenRecords(path string, mmapThreshold int64) (Records, error) {
+	if g.MapsFile(path, mmapThreshold) {
+		return g.OpenMapped(path)
+	}
+
+	data, err := g.LoadFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return RecordSlice(data), nil
+}
+
+// OpenMapped отображает файл набора path в память и индексирует его записи.
+// Сжатые файлы не отображаются
+func (g *DataGenerator) OpenMapped(path string) (*MappedDataset, error) {
+	if strings.HasSuffix(path, compressedDatasetExt) {
+		return nil, fmt.Errorf("сжатый файл %s не отображается в память", path)
+	}
+
+	data, err := mapFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось отобразить файл %s: %w", path, err)
+	}
+
+	d := &MappedDataset{
+		generator: g,
+		path:      path,
+		data:      data,
+		raw:       len(g.config.Schema) > 0 || len(g.config.Binary.Fields) > 0,
+	}
+	if err := d.index(); err != nil {
+		unmapFile(data)
+		return nil, err
+	}
+
+	g.mappedFiles.Add(1)
+	g.mappedBytes.Add(int64(len(data)))
+	g.logger.Info("Файл данных отображен в память",
+		zap.String("файл", path),
+		zap.Int("записей", d.Len()),
+		zap.Int("размер_байт", len(data)))
+
+	return d, nil
+}
+
+// index строит смещения строк и проверяет записи, чтобы At не возвращал ошибок;
+// пустые строки пропускаются, как при LoadFromFile
+func (d *MappedDataset) index() error {
+	// Оценка числа записей по первой строке, чтобы избежать копирований при росте
+	if n := bytes.IndexByte(d.data, '\n'); n > 0 {
+		d.offsets = make([]int64, 0, len(d.data)/(n+1)+2)
+	}
+
+	var item models.Data
+	for start, line := 0, 1; start < len(d.data); line++ {
+		end := bytes.IndexByte(d.data[start:], '\n')
+		if end < 0 {
+			end = len(d.data)
+		} else {
+			end += start
+		}
+
+		record := bytes.TrimSpace(d.data[start:end])
+		if len(record) > 0 {
+			var err error
+			if d.raw {
+				if !json.Valid(record) {
+					err = errors.New("некорректный JSON")
+				}
+			} else {
+				item = models.Data{}
+				err = json.Unmarshal(record, &item)
+			}
+			if err != nil {
+				return fmt.Errorf("ошибка чтения из файла %s, строка %d: %w", d.path, line, err)
+			}
+			d.offsets = append(d.offsets, int64(start))
+		}
+		start = end + 1
+	}
+	d.offsets = append(d.offsets, int64(len(d.data)))
+	return nil
+}
+
+// Len возвращает количество записей
+func (d *MappedDataset) Len() int {
+	return len(d.offsets) - 1
+}
+
+// At декодирует запись i. Запись не ссылается на отображение и остается
+// действительной после Close
+func (d *MappedDataset) At(i int) *models.Data {
+	record := bytes.TrimSpace(d.data[d.offsets[i]:d.offsets[i+1]])
+	if d.raw {
+		return &models.Data{Raw: bytes.Clone(record)}
+	}
+
+	var item models.Data
+	if err := json.Unmarshal(record, &item); err != nil {
+		// Записи проверены при открытии; ошибка возможна только при изменении файла
+		return &models.Data{Raw: bytes.Clone(record)}
+	}
+	return &item
+}
+
+// Path возвращает путь к файлу набора
+func (d *MappedDataset) Path() string {
+	return d.path
+}
+
+// Close снимает отображение; записи, полученные из At, остаются действительными
+func (d *MappedDataset) Close() error {
+	if d.data == nil {
+		return nil
+	}
+	size := len(d.data)
+	err := unmapFile(d.data)
+	d.data = nil
+	d.generator.mappedFiles.Add(-1)
+	d.generator.mappedBytes.Add(-int64(size))
+	return err
+}
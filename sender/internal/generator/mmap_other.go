@@ -0,0 +1,23 @@
+//go:build !unix
+
+package generator
+
+import "os"
+
+// mapFile читает файл path в память: без mmap содержимое файла занимает память
+// процесса, но записи по-прежнему декодируются только при обращении
+func mapFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return data, nil
+}
+
+// unmapFile освобождает содержимое файла, прочитанное mapFile
+func unmapFile(data []byte) error {
+	return nil
+}
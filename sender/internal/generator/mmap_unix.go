@@ -0,0 +1,36 @@
+//go:build unix
+
+package generator
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// mapFile отображает файл path в память только для чтения
+func mapFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	// Отображение остается действительным после закрытия файла
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return []byte{}, nil
+	}
+	return unix.Mmap(int(file.Fd()), 0, int(info.Size()), unix.PROT_READ, unix.MAP_SHARED)
+}
+
+// unmapFile снимает отображение, созданное mapFile
+func unmapFile(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return unix.Munmap(data)
+}
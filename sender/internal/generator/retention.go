@@ -0,0 +1,210 @@
+package generator
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultRetentionInterval период проверки политики хранения, если он не задан
+const defaultRetentionInterval = 10 * time.Minute
+
+// RetentionPolicy политика хранения сгенерированных наборов (изменяется без
+// перезапуска через SetRetention)
+type RetentionPolicy struct {
+	Sets          []string      // Наборы, к которым применяется политика
+	MaxAge        time.Duration // Предельный возраст файла (0 - без ограничения)
+	MaxTotalBytes int64         // Предельный суммарный размер файлов наборов Sets; удаляются самые старые (0 - без ограничения)
+	Interval      time.Duration // Период проверки (0 - defaultRetentionInterval)
+}
+
+// enabled сообщает, задано ли хотя бы одно ограничение
+func (p RetentionPolicy) enabled() bool {
+	return len(p.Sets) > 0 && (p.MaxAge > 0 || p.MaxTotalBytes > 0)
+}
+
+// interval возвращает период проверки политики
+func (p RetentionPolicy) interval() time.Duration {
+	if p.Interval > 0 {
+		return p.Interval
+	}
+	return defaultRetentionInterval
+}
+
+// RetentionStats статистика применения политики хранения
+type RetentionStats struct {
+	Enabled      bool       `json:"enabled"`
+	Runs         int64      `json:"runs"`               // Выполненных проверок
+	Skipped      int64      `json:"skipped"`            // Проверок, пропущенных во время тестов
+	DeletedFiles int64      `json:"deleted_files"`      // Удаленных файлов
+	FreedBytes   int64      `json:"freed_bytes"`        // Освобождено байт
+	LastRun      *time.Time `json:"last_run,omitempty"` // Время последней проверки
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// retentionState политика хранения и фоновая очистка по ней
+type retentionState struct {
+	mu     sync.Mutex
+	policy RetentionPolicy
+	stats  RetentionStats
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// SetRetention изменяет политику хранения; применяется со следующей проверки
+func (g *DataGenerator) SetRetention(policy RetentionPolicy) {
+	g.retention.mu.Lock()
+	g.retention.policy = policy
+	g.retention.mu.Unlock()
+}
+
+// EnforceRetention удаляет файлы наборов policy.Sets старше policy.MaxAge, затем
+// самые старые файлы, пока суммарный размер оставшихся превышает policy.MaxTotalBytes
+func (g *DataGenerator) EnforceRetention(policy RetentionPolicy) (PruneResult, error) {
+	result := PruneResult{Files: []string{}}
+	if !policy.enabled() {
+		return result, nil
+	}
+	for _, set := range policy.Sets {
+		if err := ValidateDataset(set, ""); err != nil {
+			return result, err
+		}
+	}
+
+	datasets, err := g.datasetEntries(policy.Sets)
+	if err != nil {
+		return result, err
+	}
+	sort.Slice(datasets, func(i, j int) bool {
+		return datasets[i].ModifiedAt.Before(datasets[j].ModifiedAt)
+	})
+
+	var total int64
+	for _, d := range datasets {
+		total += d.SizeBytes
+	}
+
+	cutoff := time.Now().Add(-policy.MaxAge)
+	for _, d := range datasets {
+		expired := policy.MaxAge > 0 && d.ModifiedAt.Before(cutoff)
+		oversize := policy.MaxTotalBytes > 0 && total > policy.MaxTotalBytes
+		if !expired && !oversize {
+			// Файлы упорядочены по времени изменения: следующие не старше и не нужны для размера
+			break
+		}
+
+		if err := g.removeDataset(d.Set, d.Name); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				total -= d.SizeBytes
+				continue
+			}
+			return result, err
+		}
+		total -= d.SizeBytes
+		result.add(d)
+	}
+	return result, nil
+}
+
+// StartRetention запускает фоновую проверку политики хранения с периодом
+// RetentionPolicy.Interval; первая проверка выполняется сразу. Пока busy возвращает
+// true (выполняются тесты), проверки пропускаются
+func (g *DataGenerator) StartRetention(busy func() bool) {
+	r := &g.retention
+	r.stopChan = make(chan struct{})
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		for {
+			g.retentionPass(busy)
+
+			r.mu.Lock()
+			interval := r.policy.interval()
+			r.mu.Unlock()
+
+			timer := time.NewTimer(interval)
+			select {
+			case <-timer.C:
+			case <-r.stopChan:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// retentionPass выполняет одну проверку политики хранения
+func (g *DataGenerator) retentionPass(busy func() bool) {
+	r := &g.retention
+	r.mu.Lock()
+	policy := r.policy
+	r.mu.Unlock()
+
+	if !policy.enabled() {
+		return
+	}
+	if busy != nil && busy() {
+		r.mu.Lock()
+		r.stats.Skipped++
+		r.mu.Unlock()
+		g.logger.Debug("Проверка политики хранения пропущена: выполняются тесты")
+		return
+	}
+
+	result, err := g.EnforceRetention(policy)
+
+	now := time.Now()
+	r.mu.Lock()
+	r.stats.Runs++
+	r.stats.DeletedFiles += int64(result.Deleted)
+	r.stats.FreedBytes += result.FreedBytes
+	r.stats.LastRun = &now
+	r.stats.LastError = ""
+	if err != nil {
+		r.stats.LastError = err.Error()
+	}
+	r.mu.Unlock()
+
+	if err != nil {
+		g.logger.Error("Ошибка применения политики хранения наборов данных", zap.Error(err))
+	}
+	if result.Deleted > 0 {
+		g.logger.Info("Удалены наборы данных по политике хранения",
+			zap.Int("files", result.Deleted),
+			zap.Int64("freed_bytes", result.FreedBytes),
+			zap.Strings("names", result.Files))
+	}
+}
+
+// RetentionStats возвращает статистику применения политики хранения
+func (g *DataGenerator) RetentionStats() RetentionStats {
+	r := &g.retention
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	stats.Enabled = r.policy.enabled()
+	if stats.LastRun != nil {
+		lastRun := *stats.LastRun
+		stats.LastRun = &lastRun
+	}
+	return stats
+}
+
+// StopRetention останавливает фоновую проверку политики хранения, запущенную StartRetention
+func (g *DataGenerator) StopRetention() {
+	r := &g.retention
+	if r.stopChan == nil {
+		return
+	}
+	close(r.stopChan)
+	r.wg.Wait()
+	r.stopChan = nil
+}
@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/infodiode/sender/config"
+	"github.com/infodiode/shared/utils"
+)
+
+// Наборы символов для строковых полей схемы
+var schemaCharsets = map[string]string{
+	"":       "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789",
+	"alnum":  "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789",
+	"alpha":  "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"digits": "0123456789",
+	"hex":    "0123456789abcdef",
+}
+
+// generateRecord формирует JSON запись по пользовательской схеме, сохраняя порядок полей
+func (g *DataGenerator) generateRecord(fields []config.SchemaField, id int) json.RawMessage {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.appendObject(nil, fields, id)
+}
+
+// appendObject дописывает JSON объект с указанными полями
+func (g *DataGenerator) appendObject(buf []byte, fields []config.SchemaField, id int) []byte {
+	buf = append(buf, '{')
+	for i := range fields {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendString(buf, fields[i].Name)
+		buf = append(buf, ':')
+		buf = g.appendValue(buf, &fields[i], id)
+	}
+	return append(buf, '}')
+}
+
+// appendValue дописывает значение поля согласно типу и правилу генерации
+// (вызывается под g.mu, так как rand.Rand не потокобезопасен)
+func (g *DataGenerator) appendValue(buf []byte, field *config.SchemaField, id int) []byte {
+	if field.NullRate > 0 && g.random.Float64()*100 < field.NullRate {
+		return append(buf, "null"...)
+	}
+
+	switch field.Type {
+	case config.FieldTypeInt:
+		if field.Rule == "sequence" {
+			return strconv.AppendInt(buf, int64(field.Min)+int64(id), 10)
+		}
+		min, max := int64(field.Min), int64(field.Max)
+		return strconv.AppendInt(buf, min+g.random.Int63n(max-min+1), 10)
+	case config.FieldTypeFloat:
+		value := field.Min + g.random.Float64()*(field.Max-field.Min)
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return append(buf, '0')
+		}
+		return strconv.AppendFloat(buf, value, 'f', field.Precision, 64)
+	case config.FieldTypeBool:
+		return strconv.AppendBool(buf, g.random.Intn(2) == 0)
+	case config.FieldTypeString:
+		charset := schemaCharsets[field.Rule]
+		buf = append(buf, '"')
+		for i := 0; i < field.Length; i++ {
+			buf = append(buf, charset[g.random.Intn(len(charset))])
+		}
+		return append(buf, '"')
+	case config.FieldTypeEnum:
+		return appendString(buf, field.Values[g.random.Intn(len(field.Values))])
+	case config.FieldTypeTimestamp:
+		return appendString(buf, utils.GetCurrentTime())
+	case config.FieldTypeObject:
+		return g.appendObject(buf, field.Fields, id)
+	case config.FieldTypeArray:
+		buf = append(buf, '[')
+		for i := 0; i < field.Length; i++ {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = g.appendValue(buf, field.Items, id)
+		}
+		return append(buf, ']')
+	default:
+		return append(buf, "null"...)
+	}
+}
+
+// appendString дописывает строку в JSON представлении
+func appendString(buf []byte, s string) []byte {
+	value, _ := json.Marshal(s)
+	return append(buf, value...)
+}
@@ -0,0 +1,182 @@
+package generator
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Типы значений тегов
+const (
+	TagTypeNull   = "null"
+	TagTypeBool   = "bool"
+	TagTypeInt    = "int"
+	TagTypeFloat  = "float"
+	TagTypeString = "string"
+)
+
+// tagTypeAliases названия типов значений, встречающиеся в выгрузках историков
+var tagTypeAliases = map[string]string{
+	"null":    TagTypeNull,
+	"bool":    TagTypeBool,
+	"boolean": TagTypeBool,
+	"digital": TagTypeBool,
+	"int":     TagTypeInt,
+	"integer": TagTypeInt,
+	"int32":   TagTypeInt,
+	"int64":   TagTypeInt,
+	"float":   TagTypeFloat,
+	"float32": TagTypeFloat,
+	"float64": TagTypeFloat,
+	"double":  TagTypeFloat,
+	"real":    TagTypeFloat,
+	"analog":  TagTypeFloat,
+	"string":  TagTypeString,
+	"text":    TagTypeString,
+}
+
+// Tag определение тега: показатель оборудования с типом значения и относительной
+// частотой появления в потоке
+type Tag struct {
+	IndicatorID int     `json:"indicator_id"`
+	EquipmentID int     `json:"equipment_id"`
+	ValueType   string  `json:"value_type"`
+	Weight      float64 `json:"weight,omitempty"` // Относительная частота (0 - как у остальных тегов, 1)
+}
+
+// LoadTags загружает определения тегов из файла CSV (.csv) или JSON (массив объектов).
+// CSV должен содержать строку заголовка с колонками indicator_id, equipment_id,
+// value_type и необязательной weight (или count); порядок колонок произвольный
+func LoadTags(path string) ([]Tag, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл тегов %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var tags []Tag
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		tags, err = readTagsCSV(file)
+	} else {
+		err = json.NewDecoder(file).Decode(&tags)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла тегов %s: %w", path, err)
+	}
+
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("файл тегов %s не содержит определений", path)
+	}
+	for i := range tags {
+		if err := normalizeTag(&tags[i]); err != nil {
+			return nil, fmt.Errorf("тег %d файла %s: %w", i+1, path, err)
+		}
+	}
+	return tags, nil
+}
+
+// readTagsCSV читает определения тегов из CSV с заголовком
+func readTagsCSV(r io.Reader) ([]Tag, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать заголовок: %w", err)
+	}
+
+	columns := map[string]int{"weight": -1}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "count" {
+			name = "weight"
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"indicator_id", "equipment_id", "value_type"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("нет колонки %s", required)
+		}
+	}
+
+	var tags []Tag
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return tags, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tag := Tag{ValueType: record[columns["value_type"]]}
+		if tag.IndicatorID, err = strconv.Atoi(strings.TrimSpace(record[columns["indicator_id"]])); err != nil {
+			return nil, fmt.Errorf("строка %d: некорректный indicator_id: %w", line, err)
+		}
+		if tag.EquipmentID, err = strconv.Atoi(strings.TrimSpace(record[columns["equipment_id"]])); err != nil {
+			return nil, fmt.Errorf("строка %d: некорректный equipment_id: %w", line, err)
+		}
+		if i := columns["weight"]; i >= 0 && strings.TrimSpace(record[i]) != "" {
+			if tag.Weight, err = strconv.ParseFloat(strings.TrimSpace(record[i]), 64); err != nil {
+				return nil, fmt.Errorf("строка %d: некорректный weight: %w", line, err)
+			}
+		}
+		tags = append(tags, tag)
+	}
+}
+
+// normalizeTag приводит тип значения к одному из TagType* и проверяет вес
+func normalizeTag(tag *Tag) error {
+	valueType, ok := tagTypeAliases[strings.ToLower(strings.TrimSpace(tag.ValueType))]
+	if !ok {
+		return fmt.Errorf("неизвестный тип значения %q", tag.ValueType)
+	}
+	tag.ValueType = valueType
+
+	if tag.Weight < 0 {
+		return fmt.Errorf("отрицательный weight: %g", tag.Weight)
+	}
+	if tag.Weight == 0 {
+		tag.Weight = 1
+	}
+	return nil
+}
+
+// tagTable выбор тегов с вероятностью, пропорциональной их весу
+type tagTable struct {
+	tags       []Tag
+	cumulative []float64 // Накопленные веса
+}
+
+// newTagTable создает таблицу выбора; nil, если теги не заданы
+func newTagTable(tags []Tag) *tagTable {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	table := &tagTable{tags: tags, cumulative: make([]float64, len(tags))}
+	var total float64
+	for i, tag := range tags {
+		total += max(tag.Weight, 1e-12)
+		table.cumulative[i] = total
+	}
+	return table
+}
+
+// pick выбирает тег с вероятностью, пропорциональной весу
+func (t *tagTable) pick(random *rand.Rand) *Tag {
+	roll := random.Float64() * t.cumulative[len(t.cumulative)-1]
+	i := sort.SearchFloat64s(t.cumulative, roll)
+	if i >= len(t.tags) {
+		i = len(t.tags) - 1
+	}
+	return &t.tags[i]
+}
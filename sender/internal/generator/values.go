@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+
+	"github.com/infodiode/sender/config"
+)
+
+// valueLimit предельное абсолютное значение модели: значение с двумя знаками после
+// запятой помещается в 15 символов indicator_value
+const valueLimit = 999999999.99
+
+// seriesKey ряд значений: показатель оборудования
+type seriesKey struct {
+	indicatorID int
+	equipmentID int
+}
+
+// seriesState состояние ряда: текущий уровень и количество выданных значений
+type seriesState struct {
+	level float64
+	count int64
+}
+
+// valueModels значения показателей по моделям из конфигурации. Состояние ведется
+// отдельно для каждого ряда и создается при первом значении
+type valueModels struct {
+	models []config.ValueModel
+	mu     sync.Mutex
+	series map[seriesKey]*seriesState
+}
+
+// newValueModels создает генератор значений по моделям; nil, если модели не заданы
+func newValueModels(models []config.ValueModel) *valueModels {
+	if len(models) == 0 {
+		return nil
+	}
+	return &valueModels{
+		models: models,
+		series: make(map[seriesKey]*seriesState),
+	}
+}
+
+// find возвращает первую модель, в диапазон которой входит показатель
+func (v *valueModels) find(indicatorID int) *config.ValueModel {
+	for i := range v.models {
+		m := &v.models[i]
+		if indicatorID >= m.IndicatorIDRange[0] && indicatorID <= m.IndicatorIDRange[1] {
+			return m
+		}
+	}
+	return nil
+}
+
+// next возвращает очередное значение ряда; false, если для показателя модель не задана
+func (v *valueModels) next(indicatorID, equipmentID int, random *rand.Rand) (float64, bool) {
+	if v == nil {
+		return 0, false
+	}
+	m := v.find(indicatorID)
+	if m == nil {
+		return 0, false
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key := seriesKey{indicatorID: indicatorID, equipmentID: equipmentID}
+	s, ok := v.series[key]
+	if !ok {
+		s = &seriesState{level: m.Base}
+		v.series[key] = s
+	}
+
+	var value float64
+	switch m.Model {
+	case config.ValueModelRandomWalk:
+		s.level = clampValue(m, s.level+random.NormFloat64()*m.Step)
+		value = s.level
+	case config.ValueModelSine:
+		value = m.Base + m.Amplitude*math.Sin(2*math.Pi*float64(s.count)/float64(m.Period))
+	case config.ValueModelStep:
+		if random.Float64() < m.StepProbability {
+			if random.Intn(2) == 0 {
+				s.level -= m.Step
+			} else {
+				s.level += m.Step
+			}
+			s.level = clampValue(m, s.level)
+		}
+		value = s.level
+	}
+
+	value += m.Drift*float64(s.count) + random.NormFloat64()*m.Noise
+	s.count++
+
+	return clampValue(m, value), true
+}
+
+// formatModelValue форматирует значение модели для indicator_value
+func formatModelValue(value float64, precision int) string {
+	return padToLength(strconv.FormatFloat(value, 'f', precision, 64), 15)
+}
+
+// clampValue ограничивает значение пределами модели и valueLimit
+func clampValue(m *config.ValueModel, value float64) float64 {
+	if m.Min < m.Max {
+		value = min(max(value, m.Min), m.Max)
+	}
+	return min(max(value, -valueLimit), valueLimit)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/infodiode/shared/logging"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -13,6 +14,7 @@ import (
 type Logger struct {
 	*zap.Logger
 	sugar *zap.SugaredLogger
+	level zap.AtomicLevel
 }
 
 // Config конфигурация логгера
@@ -24,34 +26,29 @@ type Config struct {
 	MaxAge     int
 	Compress   bool
 	Console    bool
+
+	Format        string // Формат файла: json, logfmt или console
+	FileLevel     string // Уровень файла (пусто - общий Level)
+	ConsoleFormat string // Формат консоли
+	ConsoleLevel  string // Уровень консоли (пусто - общий Level)
+
+	Syslog      *logging.SyslogConfig // Вывод на сервер syslog (nil - выключен)
+	SyslogLevel string                // Уровень syslog (пусто - общий Level)
 }
 
 // New создает новый экземпляр логгера
 func New(cfg Config) (*Logger, error) {
 	// Парсим уровень логирования
-	level, err := parseLevel(cfg.Level)
+	parsed, err := logging.ParseLevel(cfg.Level)
 	if err != nil {
 		return nil, fmt.Errorf("неверный уровень логирования: %w", err)
 	}
 
-	// Создаем encoder config
-	encoderConfig := zapcore.EncoderConfig{
-		TimeKey:        "timestamp",
-		LevelKey:       "level",
-		NameKey:        "logger",
-		CallerKey:      "caller",
-		FunctionKey:    zapcore.OmitKey,
-		MessageKey:     "message",
-		StacktraceKey:  "stacktrace",
-		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeLevel:    zapcore.LowercaseLevelEncoder,
-		EncodeTime:     zapcore.ISO8601TimeEncoder,
-		EncodeDuration: zapcore.SecondsDurationEncoder,
-		EncodeCaller:   zapcore.ShortCallerEncoder,
-	}
+	// Общий уровень может меняться без пересоздания логгера; выводы с собственным
+	// уровнем от него не зависят
+	level := zap.NewAtomicLevelAt(parsed)
 
-	// Создаем JSON encoder для файла
-	jsonEncoder := zapcore.NewJSONEncoder(encoderConfig)
+	encoderConfig := logging.EncoderConfig()
 
 	// Создаем cores
 	var cores []zapcore.Core
@@ -67,25 +64,35 @@ func New(cfg Config) (*Logger, error) {
 			LocalTime:  true,
 		}
 
-		fileCore := zapcore.NewCore(
-			jsonEncoder,
-			zapcore.AddSync(fileWriter),
-			level,
-		)
+		fileCore, err := newCore(cfg.Format, encoderConfig, zapcore.AddSync(fileWriter), level, cfg.FileLevel)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка создания файлового вывода: %w", err)
+		}
 		cores = append(cores, fileCore)
 	}
 
 	// Консольный core
 	if cfg.Console {
-		consoleEncoder := zapcore.NewConsoleEncoder(encoderConfig)
-		consoleCore := zapcore.NewCore(
-			consoleEncoder,
-			zapcore.AddSync(os.Stdout),
-			level,
-		)
+		consoleCore, err := newCore(cfg.ConsoleFormat, encoderConfig, zapcore.AddSync(os.Stdout), level, cfg.ConsoleLevel)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка создания консольного вывода: %w", err)
+		}
 		cores = append(cores, consoleCore)
 	}
 
+	// Вывод на сервер syslog
+	if cfg.Syslog != nil {
+		enabler, err := logging.SinkLevel(level, cfg.SyslogLevel)
+		if err != nil {
+			return nil, fmt.Errorf("неверный уровень syslog: %w", err)
+		}
+		syslogCore, err := logging.NewSyslogCore(*cfg.Syslog, enabler)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка создания вывода syslog: %w", err)
+		}
+		cores = append(cores, syslogCore)
+	}
+
 	// Создаем tee core
 	core := zapcore.NewTee(cores...)
 
@@ -95,27 +102,39 @@ func New(cfg Config) (*Logger, error) {
 	logger := &Logger{
 		Logger: zapLogger,
 		sugar:  zapLogger.Sugar(),
+		level:  level,
 	}
 
 	return logger, nil
 }
 
-// parseLevel парсит уровень логирования из строки
-func parseLevel(level string) (zapcore.Level, error) {
-	switch level {
-	case "debug":
-		return zapcore.DebugLevel, nil
-	case "info":
-		return zapcore.InfoLevel, nil
-	case "warn", "warning":
-		return zapcore.WarnLevel, nil
-	case "error":
-		return zapcore.ErrorLevel, nil
-	case "fatal":
-		return zapcore.FatalLevel, nil
-	default:
-		return zapcore.InfoLevel, fmt.Errorf("неизвестный уровень: %s", level)
+// newCore создает вывод формата format в writer с уровнем sinkLevel или общим уровнем
+func newCore(format string, encoderConfig zapcore.EncoderConfig, writer zapcore.WriteSyncer,
+	level zap.AtomicLevel, sinkLevel string) (zapcore.Core, error) {
+	encoder, err := logging.NewEncoder(format, encoderConfig)
+	if err != nil {
+		return nil, err
+	}
+	enabler, err := logging.SinkLevel(level, sinkLevel)
+	if err != nil {
+		return nil, err
 	}
+	return zapcore.NewCore(encoder, writer, enabler), nil
+}
+
+// SetLevel изменяет уровень логирования
+func (l *Logger) SetLevel(level string) error {
+	parsed, err := logging.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("неверный уровень логирования: %w", err)
+	}
+	l.level.SetLevel(parsed)
+	return nil
+}
+
+// Level возвращает текущий уровень логирования
+func (l *Logger) Level() string {
+	return l.level.Level().String()
 }
 
 // Sugar возвращает SugaredLogger для удобного использования
@@ -134,6 +153,7 @@ func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
 	return &Logger{
 		Logger: newLogger,
 		sugar:  newLogger.Sugar(),
+		level:  l.level,
 	}
 }
 
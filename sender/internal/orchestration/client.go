@@ -0,0 +1,130 @@
+package orchestration
+
+import (
+	"context"
+	"time"
+
+	"github.com/infodiode/shared/client"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
+)
+
+// Client клиент канала оркестрации для запроса статистики у recipient
+// (используется в лабораторных стендах с обратным каналом)
+type Client struct {
+	recipient *client.Recipient
+}
+
+// Config конфигурация клиента оркестрации
+type Config struct {
+	RecipientURL string        // Базовый адрес HTTP API recipient (http://host:port)
+	Timeout      time.Duration // Таймаут запроса
+	CAFile       string        // Сертификаты удостоверяющих центров для https (пусто - системные)
+}
+
+// RecipientStats статистика обработчика recipient
+type RecipientStats = client.ProcessorStats
+
+// MQTTDeliveries счетчики особых доставок MQTT consumer recipient (retained, DUP, last will)
+type MQTTDeliveries = client.ConsumerStats
+
+// NewClient создает клиент оркестрации; возвращает nil, если адрес recipient не задан
+func NewClient(cfg *Config) (*Client, error) {
+	if cfg.RecipientURL == "" {
+		return nil, nil
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = client.DefaultTimeout
+	}
+
+	httpClient, err := utils.HTTPClient(timeout, cfg.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{recipient: client.NewRecipient(cfg.RecipientURL, httpClient)}, nil
+}
+
+// RecipientStats запрашивает текущую статистику обработчика recipient
+func (c *Client) RecipientStats(ctx context.Context) (*RecipientStats, error) {
+	stats, err := c.recipient.Stats(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats.Processor, nil
+}
+
+// SessionReport запрашивает отчет о полноте доставки сообщений теста;
+// если recipient не получил ни одного сообщения теста, возвращается пустой отчет
+func (c *Client) SessionReport(ctx context.Context, testID string) (*models.SessionReport, error) {
+	report, err := c.recipient.Session(ctx, testID)
+	if client.IsNotFound(err) {
+		return &models.SessionReport{TestID: testID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// FileTransfer запрашивает отчет recipient о приеме файла;
+// если recipient не получил ни одной части файла, возвращается nil
+func (c *Client) FileTransfer(ctx context.Context, transferID string) (*models.FileTransferReport, error) {
+	report, err := c.recipient.FileTransfer(ctx, transferID)
+	if client.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// ReceiveTimeline запрашивает посекундную динамику приема сообщений теста;
+// если recipient не получил ни одного сообщения теста, возвращается пустой список
+func (c *Client) ReceiveTimeline(ctx context.Context, testID string) ([]models.ReceivePoint, error) {
+	points, err := c.recipient.Timeline(ctx, testID)
+	if client.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+// MQTTDeliveries запрашивает счетчики retained, DUP и last will доставок MQTT consumer
+func (c *Client) MQTTDeliveries(ctx context.Context) (*MQTTDeliveries, error) {
+	stats, err := c.recipient.Stats(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats.Consumer, nil
+}
+
+// SubscriptionQoS запрашивает QoS, предоставленный брокером подписке MQTT consumer
+// recipient на основной топик; -1, если подписки нет или recipient его не сообщает
+func (c *Client) SubscriptionQoS(ctx context.Context) (int, error) {
+	stats, err := c.recipient.Stats(ctx, 0)
+	if err != nil {
+		return 0, err
+	}
+	if stats.Consumer.GrantedQoS == nil {
+		return -1, nil
+	}
+	return *stats.Consumer.GrantedQoS, nil
+}
+
+// Resubscribe запрашивает повторную подписку MQTT consumer recipient, при которой
+// брокер доставляет сохраненные retained сообщения
+func (c *Client) Resubscribe(ctx context.Context) error {
+	_, err := c.recipient.Resubscribe(ctx)
+	return err
+}
@@ -0,0 +1,604 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/quicconn"
+	"github.com/infodiode/shared/tcpframe"
+	"github.com/infodiode/shared/utils"
+	quicgo "github.com/quic-go/quic-go"
+	"go.uber.org/zap"
+)
+
+// Категории ошибок отправки
+const (
+	ErrorCategoryTimeout   = "timeout"   // Истек таймаут записи, подключения или простоя соединения
+	ErrorCategoryClosed    = "closed"    // Соединение или поток закрыты сервером
+	ErrorCategoryHandshake = "handshake" // Ошибка подключения или согласования TLS
+	ErrorCategoryOther     = "other"     // Прочие ошибки
+)
+
+// QUICClient клиент для отправки данных по QUIC. Сообщения распределяются по
+// нескольким потокам одного соединения: потеря пакета задерживает только свой
+// поток, а отправка из разных потоков теста выполняется параллельно. При
+// переподключении сессия TLS возобновляется, и отправка начинается без ожидания
+// завершения рукопожатия (0-RTT), если сервер это допускает
+type QUICClient struct {
+	address      string
+	logger       *zap.Logger
+	tlsConfig    *tls.Config
+	quicConfig   *quicgo.Config
+	streamCount  int
+	zeroRTT      bool
+	timeout      time.Duration
+	reconnectInt time.Duration
+	maxRetries   int
+
+	mu          sync.Mutex
+	session     *session  // Текущее соединение, nil без соединения
+	holdUntil   time.Time // До этого момента после принудительного разрыва переподключение не выполняется
+	next        atomic.Uint64
+	connectTime atomic.Int64 // Длительность последнего подключения до готовности к отправке (нс)
+
+	messagesSent    atomic.Int64
+	batchesSent     atomic.Int64
+	bytesSent       atomic.Int64
+	reconnectCount  atomic.Int64
+	handshakes      atomic.Int64
+	resumed         atomic.Int64
+	zeroRTTAccepted atomic.Int64
+	zeroRTTRejected atomic.Int64
+	handshakeTime   atomic.Int64 // Длительность последнего рукопожатия (нс)
+	statsMu         sync.Mutex
+	errorCounts     map[string]int64 // Ошибки отправки по категориям (под statsMu)
+	lastError       string           // Последняя ошибка (под statsMu)
+	lastErrorTime   time.Time
+}
+
+// session соединение QUIC и открытые в нем потоки
+type session struct {
+	conn    *quicgo.Conn
+	streams []*sendStream
+}
+
+// sendStream поток отправки; кадры пишутся в поток целиком под mu
+type sendStream struct {
+	mu     sync.Mutex
+	stream *quicgo.Stream
+}
+
+// Config конфигурация QUIC клиента
+type Config struct {
+	Address         string        `yaml:"address" json:"address"`
+	ServerName      string        `yaml:"server_name" json:"server_name"`                   // Имя сервера для проверки сертификата (пусто - хост из address)
+	CAFile          string        `yaml:"ca_file" json:"ca_file"`                           // Центр сертификации сервера (пусто - системные сертификаты)
+	InsecureSkip    bool          `yaml:"insecure_skip_verify" json:"insecure_skip_verify"` // Не проверять сертификат сервера
+	Streams         int           `yaml:"streams" json:"streams"`                           // Потоков в соединении
+	ZeroRTT         bool          `yaml:"zero_rtt" json:"zero_rtt"`                         // Отправка 0-RTT при возобновлении сессии
+	Timeout         time.Duration `yaml:"timeout" json:"timeout"`                           // Таймаут подключения и записи
+	ReconnectInt    time.Duration `yaml:"reconnect_interval" json:"reconnect_interval"`
+	MaxRetries      int           `yaml:"max_retries" json:"max_retries"`
+	KeepAlivePeriod time.Duration `yaml:"keep_alive_period" json:"keep_alive_period"` // Период keep-alive QUIC (0 - не отправлять)
+	IdleTimeout     time.Duration `yaml:"idle_timeout" json:"idle_timeout"`           // Время простоя до закрытия соединения
+}
+
+// NewQUICClient создает новый QUIC клиент
+func NewQUICClient(config *Config, logger *zap.Logger) (*QUICClient, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("QUIC адрес не указан")
+	}
+
+	tlsConfig, err := quicconn.ClientTLSConfig(config.CAFile, config.ServerName, config.InsecureSkip)
+	if err != nil {
+		return nil, err
+	}
+	if config.InsecureSkip {
+		logger.Warn("Сертификат QUIC сервера не проверяется (quic.insecure_skip_verify): соединение не защищено от подмены сервера",
+			zap.String("address", config.Address))
+	}
+
+	client := &QUICClient{
+		address:      config.Address,
+		logger:       logger,
+		tlsConfig:    tlsConfig,
+		streamCount:  config.Streams,
+		zeroRTT:      config.ZeroRTT,
+		timeout:      config.Timeout,
+		reconnectInt: config.ReconnectInt,
+		maxRetries:   config.MaxRetries,
+		errorCounts:  make(map[string]int64),
+	}
+
+	// Устанавливаем значения по умолчанию
+	if client.streamCount <= 0 {
+		client.streamCount = 4
+	}
+	if client.reconnectInt == 0 {
+		client.reconnectInt = 5 * time.Second
+	}
+	if client.maxRetries == 0 {
+		client.maxRetries = 3
+	}
+	if client.timeout == 0 {
+		client.timeout = 10 * time.Second
+	}
+
+	client.quicConfig = &quicgo.Config{
+		HandshakeIdleTimeout: client.timeout,
+		MaxIdleTimeout:       config.IdleTimeout,
+		KeepAlivePeriod:      config.KeepAlivePeriod,
+	}
+
+	return client, nil
+}
+
+// Connect устанавливает соединение с QUIC сервером и открывает потоки отправки
+func (c *QUICClient) Connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session != nil {
+		return nil
+	}
+
+	c.logger.Info("Подключение к QUIC серверу",
+		zap.String("address", c.address),
+		zap.Int("streams", c.streamCount),
+		zap.Bool("zero_rtt", c.zeroRTT))
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	// Соединение 0-RTT готово к отправке сразу, если в кэше есть билет сессии сервера;
+	// без билета оба способа ждут завершения рукопожатия
+	dial := quicgo.DialAddr
+	if c.zeroRTT {
+		dial = quicgo.DialAddrEarly
+	}
+
+	started := time.Now()
+	conn, err := dial(ctx, c.address, c.tlsConfig, c.quicConfig)
+	if err != nil {
+		return fmt.Errorf("ошибка подключения к QUIC серверу: %w", err)
+	}
+
+	streams, err := openStreams(ctx, conn, c.streamCount)
+	if err != nil {
+		conn.CloseWithError(quicconn.CodeNormal, "")
+		return fmt.Errorf("ошибка открытия потоков QUIC: %w", err)
+	}
+
+	c.session = &session{conn: conn, streams: streams}
+	c.connectTime.Store(int64(time.Since(started)))
+
+	c.logger.Info("Успешное подключение к QUIC серверу",
+		zap.String("address", c.address),
+		zap.Duration("connect_time", time.Since(started)))
+
+	go c.watch(conn, started)
+
+	return nil
+}
+
+// openStreams открывает count потоков отправки; при нехватке разрешенных сервером
+// потоков ожидает их до истечения ctx
+func openStreams(ctx context.Context, conn *quicgo.Conn, count int) ([]*sendStream, error) {
+	streams := make([]*sendStream, count)
+	for i := range streams {
+		stream, err := conn.OpenStreamSync(ctx)
+		if err != nil {
+			return nil, err
+		}
+		streams[i] = &sendStream{stream: stream}
+	}
+	return streams, nil
+}
+
+// watch учитывает завершение рукопожатия соединения conn и его закрытие
+func (c *QUICClient) watch(conn *quicgo.Conn, started time.Time) {
+	select {
+	case <-conn.HandshakeComplete():
+		c.handshakeTime.Store(int64(time.Since(started)))
+		c.handshakes.Add(1)
+
+		state := conn.ConnectionState()
+		if state.TLS.DidResume {
+			c.resumed.Add(1)
+		}
+		if state.Used0RTT {
+			c.zeroRTTAccepted.Add(1)
+		}
+		c.logger.Debug("Рукопожатие QUIC завершено",
+			zap.String("address", c.address),
+			zap.Duration("handshake_time", time.Since(started)),
+			zap.Bool("resumed", state.TLS.DidResume),
+			zap.Bool("zero_rtt", state.Used0RTT))
+	case <-conn.Context().Done():
+	}
+
+	<-conn.Context().Done()
+	c.connectionLost(conn, context.Cause(conn.Context()))
+}
+
+// Disconnect закрывает соединение с QUIC сервером
+func (c *QUICClient) Disconnect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session == nil {
+		return nil
+	}
+
+	err := c.closeSession(quicconn.CodeNormal)
+
+	c.logger.Info("Отключение от QUIC сервера", zap.String("address", c.address))
+
+	return err
+}
+
+// Send отправляет сообщение через QUIC
+func (c *QUICClient) Send(message *models.Message) error {
+	buf := utils.GetBuffer()
+	defer utils.PutBuffer(buf)
+
+	// Кадр протокола v2 TCP: тип, длина и JSON сообщения
+	var header [tcpframe.HeaderSize]byte
+	buf.Write(header[:])
+	if err := buf.EncodeJSON(message); err != nil {
+		err = fmt.Errorf("ошибка сериализации сообщения: %w", err)
+		c.recordError(err)
+		return err
+	}
+	frame := buf.Bytes()
+	tcpframe.PutHeader(frame, tcpframe.TypeMessage)
+
+	if err := c.write(frame, c.timeout); err != nil {
+		err = fmt.Errorf("ошибка отправки сообщения: %w", err)
+		c.recordError(err)
+		return err
+	}
+
+	c.messagesSent.Add(1)
+	c.bytesSent.Add(int64(len(frame)))
+
+	return nil
+}
+
+// SendBatch отправляет пакет сообщений через QUIC
+func (c *QUICClient) SendBatch(messages []*models.Message) error {
+	batch := &models.MessageBatch{
+		Messages:  messages,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Count:     len(messages),
+	}
+
+	buf := utils.GetBuffer()
+	defer utils.PutBuffer(buf)
+
+	var header [tcpframe.HeaderSize]byte
+	buf.Write(header[:])
+	if err := buf.EncodeJSON(batch); err != nil {
+		err = fmt.Errorf("ошибка сериализации пакета: %w", err)
+		c.recordError(err)
+		return err
+	}
+	frame := buf.Bytes()
+	tcpframe.PutHeader(frame, tcpframe.TypeBatch)
+
+	if err := c.write(frame, c.timeout*2); err != nil { // Увеличенный таймаут для пакета
+		err = fmt.Errorf("ошибка отправки пакета: %w", err)
+		c.recordError(err)
+		return err
+	}
+
+	c.batchesSent.Add(1)
+	c.messagesSent.Add(int64(len(messages)))
+	c.bytesSent.Add(int64(len(frame)))
+
+	return nil
+}
+
+// write записывает кадр в следующий по кругу поток, при необходимости переподключаясь.
+// Если сервер отклонил данные 0-RTT, запись повторяется после завершения рукопожатия
+func (c *QUICClient) write(frame []byte, timeout time.Duration) error {
+	sess, err := c.current()
+	if err != nil {
+		return err
+	}
+
+	err = sess.write(int(c.next.Add(1)%uint64(len(sess.streams))), frame, timeout)
+	if errors.Is(err, quicgo.Err0RTTRejected) {
+		if sess, err = c.resume(sess); err != nil {
+			return err
+		}
+		err = sess.write(int(c.next.Add(1)%uint64(len(sess.streams))), frame, timeout)
+	}
+	if err != nil {
+		c.sessionLost(sess, err)
+		return err
+	}
+	return nil
+}
+
+// write записывает кадр в поток index
+func (s *session) write(index int, frame []byte, timeout time.Duration) error {
+	stream := s.streams[index]
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	stream.stream.SetWriteDeadline(time.Now().Add(timeout))
+	_, err := stream.stream.Write(frame)
+	return err
+}
+
+// current возвращает текущее соединение, переподключаясь при его отсутствии
+func (c *QUICClient) current() (*session, error) {
+	c.mu.Lock()
+	sess := c.session
+	if sess != nil {
+		c.mu.Unlock()
+		return sess, nil
+	}
+	if time.Now().Before(c.holdUntil) {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("соединение с QUIC сервером принудительно разорвано")
+	}
+	c.mu.Unlock()
+
+	if err := c.reconnect(); err != nil {
+		return nil, fmt.Errorf("не удалось переподключиться: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.session == nil {
+		return nil, fmt.Errorf("нет соединения с QUIC сервером")
+	}
+	return c.session, nil
+}
+
+// resume продолжает соединение, сервер которого отклонил данные 0-RTT: дожидается
+// завершения рукопожатия и открывает потоки заново. Кадры, записанные в отклоненные
+// потоки, не доставляются и учитываются recipient как потерянные
+func (c *QUICClient) resume(sess *session) (*session, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session != sess {
+		// Соединение уже продолжено или закрыто другой отправкой
+		if c.session == nil {
+			return nil, fmt.Errorf("нет соединения с QUIC сервером")
+		}
+		return c.session, nil
+	}
+
+	c.zeroRTTRejected.Add(1)
+	c.logger.Warn("Сервер отклонил данные 0-RTT, отправка продолжается после рукопожатия",
+		zap.String("address", c.address))
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	conn, err := sess.conn.NextConnection(ctx)
+	if err == nil {
+		var streams []*sendStream
+		if streams, err = openStreams(ctx, conn, c.streamCount); err == nil {
+			c.session = &session{conn: conn, streams: streams}
+			return c.session, nil
+		}
+	}
+
+	c.lose(err)
+	return nil, err
+}
+
+// reconnect пытается переподключиться к серверу
+func (c *QUICClient) reconnect() error {
+	retries := 0
+	for retries < c.maxRetries {
+		c.logger.Info("Попытка переподключения",
+			zap.Int("attempt", retries+1),
+			zap.Int("max_retries", c.maxRetries))
+
+		if err := c.Connect(); err != nil {
+			retries++
+			if retries >= c.maxRetries {
+				return fmt.Errorf("превышено количество попыток переподключения: %w", err)
+			}
+			time.Sleep(c.reconnectInt)
+			continue
+		}
+		c.reconnectCount.Add(1)
+		return nil
+	}
+	return fmt.Errorf("не удалось переподключиться после %d попыток", c.maxRetries)
+}
+
+// Churn принудительно закрывает соединение; следующая отправка после downtime
+// переподключается, до этого отправка завершается ошибкой
+func (c *QUICClient) Churn(downtime time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session == nil {
+		return fmt.Errorf("нет соединения с QUIC сервером")
+	}
+
+	c.logger.Warn("Принудительный разрыв соединения с QUIC сервером",
+		zap.String("address", c.address),
+		zap.Duration("downtime", downtime))
+
+	err := c.closeSession(quicconn.CodeChurn)
+	c.holdUntil = time.Now().Add(downtime)
+	return err
+}
+
+// closeSession закрывает текущее соединение (вызывается под c.mu)
+func (c *QUICClient) closeSession(code quicgo.ApplicationErrorCode) error {
+	if c.session == nil {
+		return nil
+	}
+	err := c.session.conn.CloseWithError(code, "")
+	c.session = nil
+	return err
+}
+
+// sessionLost закрывает соединение sess после ошибки записи, если оно еще текущее
+func (c *QUICClient) sessionLost(sess *session, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session == sess {
+		c.lose(err)
+	}
+}
+
+// connectionLost учитывает закрытие соединения conn, если оно еще текущее
+func (c *QUICClient) connectionLost(conn *quicgo.Conn, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session != nil && c.session.conn == conn {
+		c.lose(err)
+	}
+}
+
+// lose учитывает потерю текущего соединения и закрывает его (вызывается под c.mu);
+// следующая отправка переподключается
+func (c *QUICClient) lose(err error) {
+	c.logger.Warn("Потеря соединения с QUIC сервером", zap.Error(err))
+	c.recordError(err)
+	c.closeSession(quicconn.CodeNormal)
+}
+
+// IsConnected проверяет состояние соединения
+func (c *QUICClient) IsConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.session != nil
+}
+
+// recordError учитывает ошибку отправки в статистике по категориям
+func (c *QUICClient) recordError(err error) {
+	category := classifyError(err)
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.errorCounts[category]++
+	c.lastError = err.Error()
+	c.lastErrorTime = time.Now()
+}
+
+// classifyError определяет категорию ошибки отправки
+func classifyError(err error) string {
+	var (
+		netErr       net.Error
+		appErr       *quicgo.ApplicationError
+		streamErr    *quicgo.StreamError
+		transportErr *quicgo.TransportError
+	)
+
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrorCategoryTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorCategoryTimeout
+	case errors.As(err, &appErr) && appErr.Remote, errors.As(err, &streamErr) && streamErr.Remote:
+		return ErrorCategoryClosed
+	case errors.As(err, &transportErr):
+		return ErrorCategoryHandshake
+	default:
+		return ErrorCategoryOther
+	}
+}
+
+// ClientStats статистика QUIC клиента
+type ClientStats struct {
+	Connected       bool             `json:"connected"`
+	Address         string           `json:"address"`
+	Streams         int              `json:"streams"`              // Потоков в соединении
+	ZeroRTT         bool             `json:"zero_rtt"`             // Включена отправка 0-RTT
+	MaxRetries      int              `json:"max_retries"`          // Попыток переподключения перед ошибкой отправки
+	MessagesSent    int64            `json:"messages_sent"`        // Отправлено сообщений (включая сообщения пакетов)
+	BatchesSent     int64            `json:"batches_sent"`         // Отправлено пакетов
+	BytesSent       int64            `json:"bytes_sent"`           // Отправлено байт с заголовками кадров
+	Errors          int64            `json:"errors"`               // Ошибок отправки
+	ErrorBreakdown  map[string]int64 `json:"error_breakdown"`      // Ошибки по категориям
+	ReconnectCount  int64            `json:"reconnect_count"`      // Успешных переподключений
+	Handshakes      int64            `json:"handshakes"`           // Завершенных рукопожатий
+	Resumed         int64            `json:"resumed"`              // Из них с возобновлением сессии TLS
+	ZeroRTTAccepted int64            `json:"zero_rtt_accepted"`    // Соединений, данные 0-RTT которых приняты сервером
+	ZeroRTTRejected int64            `json:"zero_rtt_rejected"`    // Соединений, данные 0-RTT которых отклонены сервером
+	ConnectMs       float64          `json:"connect_ms"`           // Последнее подключение до готовности к отправке
+	HandshakeMs     float64          `json:"handshake_ms"`         // Последнее рукопожатие до завершения
+	RTTMs           float64          `json:"rtt_ms"`               // Сглаженное время прохождения текущего соединения
+	PacketsSent     uint64           `json:"packets_sent"`         // Отправлено пакетов текущего соединения
+	PacketsLost     uint64           `json:"packets_lost"`         // Потеряно пакетов текущего соединения
+	BytesLost       uint64           `json:"bytes_lost"`           // Потеряно байт текущего соединения
+	LastError       string           `json:"last_error,omitempty"` // Последняя ошибка
+	LastErrorTime   *time.Time       `json:"last_error_time,omitempty"`
+}
+
+// GetStats возвращает статистику QUIC клиента
+func (c *QUICClient) GetStats() ClientStats {
+	c.mu.Lock()
+	sess := c.session
+	c.mu.Unlock()
+
+	stats := ClientStats{
+		Connected:       sess != nil,
+		Address:         c.address,
+		Streams:         c.streamCount,
+		ZeroRTT:         c.zeroRTT,
+		MaxRetries:      c.maxRetries,
+		MessagesSent:    c.messagesSent.Load(),
+		BatchesSent:     c.batchesSent.Load(),
+		BytesSent:       c.bytesSent.Load(),
+		ReconnectCount:  c.reconnectCount.Load(),
+		Handshakes:      c.handshakes.Load(),
+		Resumed:         c.resumed.Load(),
+		ZeroRTTAccepted: c.zeroRTTAccepted.Load(),
+		ZeroRTTRejected: c.zeroRTTRejected.Load(),
+		ConnectMs:       durationMs(c.connectTime.Load()),
+		HandshakeMs:     durationMs(c.handshakeTime.Load()),
+	}
+
+	if sess != nil {
+		connStats := sess.conn.ConnectionStats()
+		stats.RTTMs = durationMs(int64(connStats.SmoothedRTT))
+		stats.PacketsSent = connStats.PacketsSent
+		stats.PacketsLost = connStats.PacketsLost
+		stats.BytesLost = connStats.BytesLost
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	stats.ErrorBreakdown = make(map[string]int64, len(c.errorCounts))
+	for category, count := range c.errorCounts {
+		stats.ErrorBreakdown[category] = count
+		stats.Errors += count
+	}
+	stats.LastError = c.lastError
+	if !c.lastErrorTime.IsZero() {
+		at := c.lastErrorTime
+		stats.LastErrorTime = &at
+	}
+
+	return stats
+}
+
+// durationMs переводит длительность в наносекундах в миллисекунды
+func durationMs(ns int64) float64 {
+	return float64(time.Duration(ns).Microseconds()) / 1000
+}
@@ -0,0 +1,243 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/infodiode/shared/models"
+)
+
+// Размеры SVG диаграмм в HTML отчете
+const (
+	chartWidth  = 800
+	chartHeight = 200
+)
+
+// bar столбец SVG диаграммы
+type bar struct {
+	X, Y, Width, Height float64
+	Title               string
+}
+
+// chart подготовленная к отрисовке диаграмма
+type chart struct {
+	Width, Height int
+	Bars          []bar
+	Max           int64
+}
+
+// htmlData данные для HTML шаблона
+type htmlData struct {
+	Result   *models.TestResult
+	Config   [][]string
+	Timeline chart
+	Received chart // Прием recipient, пустая если динамика приема не получена
+	Latency  chart
+	Errors   []errorCount
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"bucket": bucketLabel,
+	"inc":    func(i int) int { return i + 1 },
+	"optional": func(v *float64) string {
+		if v == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f", *v)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Отчет о тесте {{.Result.ID}}</title>
+<style>
+body { font-family: sans-serif; margin: 24px; color: #222; }
+h1 { font-size: 22px; }
+h2 { font-size: 18px; margin-top: 32px; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+th { background: #f0f0f0; }
+svg { background: #fafafa; border: 1px solid #ddd; }
+rect.sent { fill: #3b82f6; }
+rect.latency { fill: #10b981; }
+rect.received { fill: #f59e0b; }
+.status-completed { color: #15803d; }
+.status-failed, .status-stopped { color: #b91c1c; }
+</style>
+</head>
+<body>
+<h1>Отчет о тесте {{.Result.ID}} <span class="status-{{.Result.Status}}">({{.Result.Status}})</span></h1>
+
+<h2>Конфигурация и итоги</h2>
+<table>
+{{range $i, $row := .Config}}{{if $i}}<tr><td>{{index $row 0}}</td><td>{{index $row 1}}</td></tr>
+{{end}}{{end}}</table>
+
+<h2>Пропускная способность (сообщений в секунду)</h2>
+{{if .Timeline.Bars}}<svg width="{{.Timeline.Width}}" height="{{.Timeline.Height}}" xmlns="http://www.w3.org/2000/svg">
+{{range .Timeline.Bars}}<rect class="sent" x="{{printf "%.2f" .X}}" y="{{printf "%.2f" .Y}}" width="{{printf "%.2f" .Width}}" height="{{printf "%.2f" .Height}}"><title>{{.Title}}</title></rect>
+{{end}}</svg>
+<p>Максимум: {{.Timeline.Max}} msg/s</p>{{else}}<p>Нет данных</p>{{end}}
+{{if .Received.Max}}<h2>Прием recipient (сообщений в секунду)</h2>
+<svg width="{{.Received.Width}}" height="{{.Received.Height}}" xmlns="http://www.w3.org/2000/svg">
+{{range .Received.Bars}}<rect class="received" x="{{printf "%.2f" .X}}" y="{{printf "%.2f" .Y}}" width="{{printf "%.2f" .Width}}" height="{{printf "%.2f" .Height}}"><title>{{.Title}}</title></rect>
+{{end}}</svg>
+<p>Максимум: {{.Received.Max}} msg/s</p>{{end}}
+
+<h2>Гистограмма задержек отправки</h2>
+{{if .Latency.Max}}<svg width="{{.Latency.Width}}" height="{{.Latency.Height}}" xmlns="http://www.w3.org/2000/svg">
+{{range .Latency.Bars}}<rect class="latency" x="{{printf "%.2f" .X}}" y="{{printf "%.2f" .Y}}" width="{{printf "%.2f" .Width}}" height="{{printf "%.2f" .Height}}"><title>{{.Title}}</title></rect>
+{{end}}</svg>{{else}}<p>Нет данных</p>{{end}}
+<table>
+<tr><th>≤ ms</th><th>Количество</th></tr>
+{{range .Result.LatencyHistogram}}<tr><td>{{bucket .}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+
+{{with .Result.Discovery}}<h2>Поиск пропускной способности</h2>
+<p>Максимальная устойчивая скорость: {{.MaxSustainableRate}} msg/s ({{.StopReason}})</p>
+<table>
+<tr><th>Скорость</th><th>Отправлено</th><th>Получено</th><th>Потери, %</th><th>Задержка, ms</th><th>Результат</th></tr>
+{{range .Steps}}<tr><td>{{.Rate}}</td><td>{{.Sent}}</td><td>{{.Received}}</td><td>{{printf "%.2f" .LossPercent}}</td><td>{{printf "%.2f" .AvgLatencyMs}}</td><td>{{if .Passed}}ok{{else}}превышен порог{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+{{with .Result.Sweep}}<h2>Перебор размеров сообщений</h2>
+<table>
+<tr><th>Размер, байт</th><th>Отправлено</th><th>Ошибок</th><th>msg/s</th><th>Мбит/с</th><th>Отправка, ms (ср. / p95)</th><th>Получено</th><th>Потери, %</th><th>Задержка, ms</th></tr>
+{{range .Steps}}<tr><td>{{.Size}}</td><td>{{.Sent}}</td><td>{{.Errors}}</td><td>{{printf "%.1f" .MessagesPerSec}}</td><td>{{printf "%.2f" .MbitPerSec}}</td><td>{{with .Send}}{{printf "%.2f" .AvgMs}} / {{printf "%.2f" .P95Ms}}{{else}}-{{end}}</td><td>{{with .Received}}{{.}}{{else}}-{{end}}</td><td>{{optional .LossPercent}}</td><td>{{optional .AvgLatencyMs}}</td></tr>
+{{end}}</table>
+{{end}}
+{{with .Result.Chaos}}<h2>Разрывы соединений</h2>
+<p>Разрывов: {{.Disconnects}}, восстановлено: {{.Reconnected}}, не выполнено: {{.Failed}}; время восстановления: среднее {{printf "%.1f" .AvgReconnectMs}} ms, максимальное {{printf "%.1f" .MaxReconnectMs}} ms; ошибок отправки: {{.SendErrors}}</p>
+{{end}}
+{{with .Result.Audit}}<h2>Канал аудита</h2>
+<p>{{if .DigestMatch}}Все отправленные сообщения получены{{else}}Номера отправленных и полученных сообщений не совпадают{{end}} (сводка {{.UpdatedAt.Format "15:04:05"}})</p>
+<table>
+<tr><th>Отправлено</th><th>Получено</th><th>Уникальных</th><th>Потеряно</th><th>Потери, %</th></tr>
+<tr><td>{{.Sent}}</td><td>{{.Received}}</td><td>{{.Unique}}</td><td>{{.Lost}}</td><td>{{printf "%.2f" .LossPercent}}</td></tr>
+</table>
+{{end}}
+{{with .Result.Session}}<h2>Восстановление сессии</h2>
+<p>{{if .Passed}}Потерь нет{{else}}Обнаружены потери{{end}}: {{.Verdict}}</p>
+<table>
+<tr><th>Передано клиенту</th><th>Отклонено</th><th>Получено</th><th>Уникальных</th><th>Повторов</th><th>Вне порядка</th><th>Потеряно</th></tr>
+<tr><td>{{.Expected}}</td><td>{{.Rejected}}</td>{{with .Report}}<td>{{.Received}}</td><td>{{.Unique}}</td><td>{{.Duplicates}}</td><td>{{.OutOfOrder}}</td>{{else}}<td>-</td><td>-</td><td>-</td><td>-</td>{{end}}<td>{{.Lost}}</td></tr>
+</table>
+<table>
+<tr><th>Разрыв</th><th>Начало</th><th>Восстановление</th><th>Последний номер</th></tr>
+{{range $i, $e := .Interruptions}}<tr><td>{{inc $i}}</td><td>{{$e.Start.Format "15:04:05.000"}}</td><td>{{$e.End.Format "15:04:05.000"}}</td><td>{{$e.LastSequence}}</td></tr>
+{{end}}</table>
+{{with .Report}}{{if .MissingRanges}}<p>Пропущенные номера: {{range $i, $r := .MissingRanges}}{{if $i}}, {{end}}{{$r.From}}{{if ne $r.From $r.To}}-{{$r.To}}{{end}}{{end}}</p>{{end}}{{end}}
+{{end}}
+{{with .Result.ExactlyOnce}}<h2>Доставка ровно один раз (QoS {{.QoS}})</h2>
+<p>{{if .Passed}}Проверка пройдена{{else}}Проверка не пройдена{{end}}: {{.Verdict}}</p>
+<table>
+<tr><th>Передано клиенту</th><th>Отклонено</th><th>Получено</th><th>Уникальных</th><th>Повторов</th><th>Не получено</th></tr>
+<tr><td>{{.Expected}}</td><td>{{.Rejected}}</td>{{with .Report}}<td>{{.Received}}</td><td>{{.Unique}}</td>{{else}}<td>-</td><td>-</td>{{end}}<td>{{.Duplicates}}</td><td>{{.Missing}}</td></tr>
+</table>
+{{with .Report}}{{if .MissingRanges}}<p>Пропущенные номера: {{range $i, $r := .MissingRanges}}{{if $i}}, {{end}}{{$r.From}}{{if ne $r.From $r.To}}-{{$r.To}}{{end}}{{end}}</p>{{end}}{{end}}
+{{end}}
+{{with .Result.MQTTFeatures}}<h2>Retained и last will</h2>
+<p>{{if .Passed}}Проверка пройдена{{else}}Проверка не пройдена{{end}}: {{.Verdict}}</p>
+<table>
+<tr><th>Отправлено с retain</th><th>Получено</th><th>Доставлено при подписке</th><th>Обрыв соединения</th><th>Получено last will</th><th>Доставок с DUP</th></tr>
+<tr><td>{{.RetainedSent}}</td>{{with .Report}}<td>{{.Unique}}</td>{{else}}<td>-</td>{{end}}<td>{{.RetainedReceived}}</td><td>{{if .WillTriggered}}да{{else}}нет{{end}}</td><td>{{.WillReceived}}</td><td>{{.DuplicateFlagged}}</td></tr>
+</table>
+{{end}}
+{{with .Result.Protocols}}<h2>Статистика по протоколам</h2>
+<table>
+<tr><th>Протокол</th><th>Потоков</th><th>Отправлено</th><th>Байт</th><th>Ошибок</th><th>msg/s</th><th>Задержка ср., ms</th><th>Мин., ms</th><th>Макс., ms</th></tr>
+{{range .}}<tr><td>{{.Protocol}}</td><td>{{.Threads}}</td><td>{{.MessagesSent}}</td><td>{{.BytesSent}}</td><td>{{.Errors}}</td><td>{{printf "%.2f" .AvgThroughput}}</td><td>{{printf "%.2f" .AvgLatency}}</td><td>{{printf "%.2f" .MinLatency}}</td><td>{{printf "%.2f" .MaxLatency}}</td></tr>
+{{end}}</table>
+{{end}}
+{{with .Result.Destinations}}<h2>Статистика по точкам назначения</h2>
+<table>
+<tr><th>Протокол</th><th>Точка назначения</th><th>Отправлено</th><th>Байт</th><th>Ошибок</th><th>msg/s</th><th>Задержка ср., ms</th><th>Мин., ms</th><th>Макс., ms</th></tr>
+{{range .}}<tr><td>{{.Protocol}}</td><td>{{.Target}}</td><td>{{.MessagesSent}}</td><td>{{.BytesSent}}</td><td>{{.Errors}}</td><td>{{printf "%.2f" .AvgThroughput}}</td><td>{{printf "%.2f" .AvgLatency}}</td><td>{{printf "%.2f" .MinLatency}}</td><td>{{printf "%.2f" .MaxLatency}}</td></tr>
+{{end}}</table>
+{{end}}
+{{with .Result.File}}<h2>Передача файла</h2>
+<p>{{if .Passed}}Проверка пройдена{{else}}Проверка не пройдена{{end}}: {{.Verdict}}</p>
+<table>
+<tr><th>Файл</th><th>Размер, байт</th><th>Фрагментов</th><th>Отправлено</th><th>Состояние</th><th>Получено</th><th>Повторов</th><th>Ошибок фрагментов</th></tr>
+<tr><td>{{.Manifest.Name}}</td><td>{{.Manifest.Size}}</td><td>{{.Manifest.Chunks}}</td><td>{{.ChunksSent}}</td>{{with .Report}}<td>{{.Status}}</td><td>{{.ChunksReceived}}</td><td>{{.Duplicates}}</td><td>{{.ChunkErrors}}</td>{{else}}<td>-</td><td>-</td><td>-</td><td>-</td>{{end}}</tr>
+</table>
+{{if .Manifest.SHA256}}<p>SHA256: {{.Manifest.SHA256}}</p>{{end}}
+{{end}}
+<h2>Ошибки по категориям</h2>
+{{if .Errors}}<table>
+<tr><th>Категория</th><th>Количество</th></tr>
+{{range .Errors}}<tr><td>{{.Category}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>{{else}}<p>Ошибок нет</p>{{end}}
+</body>
+</html>
+`))
+
+// RenderHTML записывает самодостаточный HTML отчет с встроенными диаграммами
+func RenderHTML(w io.Writer, result *models.TestResult) error {
+	data := htmlData{
+		Result: result,
+		Config: configRows(result),
+		Errors: sortedErrors(result.ErrorBreakdown),
+	}
+
+	values := make([]int64, len(result.Timeline))
+	titles := make([]string, len(result.Timeline))
+	for i, p := range result.Timeline {
+		values[i] = p.Sent
+		titles[i] = fmt.Sprintf("%d с: %d msg, %d ошибок", p.Second, p.Sent, p.Errors)
+	}
+	data.Timeline = buildChart(values, titles)
+
+	values = make([]int64, len(result.Timeline))
+	titles = make([]string, len(result.Timeline))
+	for i, p := range result.Timeline {
+		values[i] = p.Received
+		titles[i] = fmt.Sprintf("%d с: %d msg, %d с ошибками", p.Second, p.Received, p.ReceiveErrors)
+	}
+	data.Received = buildChart(values, titles)
+
+	values = make([]int64, len(result.LatencyHistogram))
+	titles = make([]string, len(result.LatencyHistogram))
+	for i, b := range result.LatencyHistogram {
+		values[i] = b.Count
+		titles[i] = fmt.Sprintf("≤ %s ms: %d", bucketLabel(b), b.Count)
+	}
+	data.Latency = buildChart(values, titles)
+
+	if err := htmlTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("ошибка формирования HTML отчета: %w", err)
+	}
+	return nil
+}
+
+// buildChart рассчитывает геометрию столбцов диаграммы
+func buildChart(values []int64, titles []string) chart {
+	c := chart{Width: chartWidth, Height: chartHeight}
+	if len(values) == 0 {
+		return c
+	}
+
+	for _, v := range values {
+		if v > c.Max {
+			c.Max = v
+		}
+	}
+
+	step := float64(chartWidth) / float64(len(values))
+	for i, v := range values {
+		height := 0.0
+		if c.Max > 0 {
+			height = float64(v) / float64(c.Max) * float64(chartHeight)
+		}
+		c.Bars = append(c.Bars, bar{
+			X:      float64(i) * step,
+			Y:      float64(chartHeight) - height,
+			Width:  step * 0.9,
+			Height: height,
+			Title:  titles[i],
+		})
+	}
+
+	return c
+}
@@ -0,0 +1,588 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/infodiode/shared/models"
+)
+
+// Format формат отчета
+type Format string
+
+const (
+	FormatHTML Format = "html" // Самодостаточный HTML файл
+	FormatCSV  Format = "csv"  // CSV таблицы
+)
+
+// Таблицы CSV отчета
+const (
+	TableConfig       = "config"
+	TableTimeline     = "timeline"
+	TableLatency      = "latency"
+	TableErrors       = "errors"
+	TableDiscovery    = "discovery"
+	TableSweep        = "sweep"
+	TableSession      = "session"
+	TableExactlyOnce  = "exactly_once"
+	TableMQTTFeatures = "mqtt_features"
+	TableProtocols    = "protocols"
+	TableDestinations = "destinations"
+	TableFile         = "file"
+)
+
+// Tables порядок таблиц в полном CSV отчете
+var Tables = []string{TableConfig, TableTimeline, TableLatency, TableErrors}
+
+// ParseFormat разбирает формат отчета из строки
+func ParseFormat(value string) (Format, error) {
+	switch Format(value) {
+	case "", FormatHTML:
+		return FormatHTML, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("неизвестный формат отчета: %s", value)
+	}
+}
+
+// ContentType возвращает MIME тип для формата
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "text/html; charset=utf-8"
+}
+
+// FileName возвращает имя файла отчета для теста
+func FileName(result *models.TestResult, format Format) string {
+	return fmt.Sprintf("test_%s_report.%s", result.ID, format)
+}
+
+// Render формирует отчет в указанном формате
+func Render(w io.Writer, result *models.TestResult, format Format) error {
+	if format == FormatCSV {
+		tables := Tables
+		if result.Discovery != nil {
+			tables = append(append([]string(nil), Tables...), TableDiscovery)
+		}
+		if result.Sweep != nil {
+			tables = append(append([]string(nil), tables...), TableSweep)
+		}
+		if result.Session != nil {
+			tables = append(append([]string(nil), tables...), TableSession)
+		}
+		if result.ExactlyOnce != nil {
+			tables = append(append([]string(nil), tables...), TableExactlyOnce)
+		}
+		if result.MQTTFeatures != nil {
+			tables = append(append([]string(nil), tables...), TableMQTTFeatures)
+		}
+		if len(result.Protocols) > 0 {
+			tables = append(append([]string(nil), tables...), TableProtocols)
+		}
+		if len(result.Destinations) > 0 {
+			tables = append(append([]string(nil), tables...), TableDestinations)
+		}
+		if result.File != nil {
+			tables = append(append([]string(nil), tables...), TableFile)
+		}
+		return RenderCSV(w, result, tables...)
+	}
+	return RenderHTML(w, result)
+}
+
+// RenderCSV записывает указанные таблицы отчета в CSV, разделяя их пустой строкой
+func RenderCSV(w io.Writer, result *models.TestResult, tables ...string) error {
+	writer := csv.NewWriter(w)
+
+	for i, table := range tables {
+		rows, err := tableRows(result, table)
+		if err != nil {
+			return err
+		}
+
+		if len(tables) > 1 {
+			if i > 0 {
+				if err := writer.Write(nil); err != nil {
+					return err
+				}
+			}
+			if err := writer.Write([]string{"# " + table}); err != nil {
+				return err
+			}
+		}
+
+		if err := writer.WriteAll(rows); err != nil {
+			return fmt.Errorf("ошибка записи таблицы %s: %w", table, err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// tableRows формирует строки таблицы отчета
+func tableRows(result *models.TestResult, table string) ([][]string, error) {
+	switch table {
+	case TableConfig:
+		return configRows(result), nil
+	case TableTimeline:
+		rows := [][]string{{"second", "sent", "bytes", "errors", "received", "receive_errors"}}
+		for _, p := range result.Timeline {
+			rows = append(rows, []string{
+				strconv.Itoa(p.Second),
+				strconv.FormatInt(p.Sent, 10),
+				strconv.FormatInt(p.Bytes, 10),
+				strconv.FormatInt(p.Errors, 10),
+				strconv.FormatInt(p.Received, 10),
+				strconv.FormatInt(p.ReceiveErrors, 10),
+			})
+		}
+		return rows, nil
+	case TableLatency:
+		rows := [][]string{{"le_ms", "count"}}
+		for _, b := range result.LatencyHistogram {
+			rows = append(rows, []string{bucketLabel(b), strconv.FormatInt(b.Count, 10)})
+		}
+		return rows, nil
+	case TableErrors:
+		rows := [][]string{{"category", "count"}}
+		for _, e := range sortedErrors(result.ErrorBreakdown) {
+			rows = append(rows, []string{e.Category, strconv.FormatInt(e.Count, 10)})
+		}
+		return rows, nil
+	case TableDiscovery:
+		rows := [][]string{{"rate", "sent", "received", "loss_percent", "avg_latency_ms", "passed"}}
+		if result.Discovery != nil {
+			for _, step := range result.Discovery.Steps {
+				rows = append(rows, []string{
+					strconv.Itoa(step.Rate),
+					strconv.FormatInt(step.Sent, 10),
+					strconv.FormatInt(step.Received, 10),
+					formatFloat(step.LossPercent),
+					formatFloat(step.AvgLatencyMs),
+					strconv.FormatBool(step.Passed),
+				})
+			}
+		}
+		return rows, nil
+	case TableSweep:
+		rows := [][]string{{"size", "sent", "errors", "messages_per_sec", "mbit_per_sec", "send_avg_ms", "send_p95_ms", "received", "loss_percent", "avg_latency_ms"}}
+		if result.Sweep != nil {
+			for _, step := range result.Sweep.Steps {
+				var sendAvg, sendP95, received, loss, latency string
+				if step.Send != nil {
+					sendAvg, sendP95 = formatFloat(step.Send.AvgMs), formatFloat(step.Send.P95Ms)
+				}
+				if step.Received != nil {
+					received = strconv.FormatInt(*step.Received, 10)
+				}
+				if step.LossPercent != nil {
+					loss = formatFloat(*step.LossPercent)
+				}
+				if step.AvgLatencyMs != nil {
+					latency = formatFloat(*step.AvgLatencyMs)
+				}
+				rows = append(rows, []string{
+					strconv.Itoa(step.Size),
+					strconv.FormatInt(step.Sent, 10),
+					strconv.FormatInt(step.Errors, 10),
+					formatFloat(step.MessagesPerSec),
+					formatFloat(step.MbitPerSec),
+					sendAvg,
+					sendP95,
+					received,
+					loss,
+					latency,
+				})
+			}
+		}
+		return rows, nil
+	case TableSession:
+		rows := [][]string{{"interruption", "start", "end", "last_sequence"}}
+		if result.Session != nil {
+			for i, event := range result.Session.Interruptions {
+				rows = append(rows, []string{
+					strconv.Itoa(i + 1),
+					event.Start.Format(time.RFC3339Nano),
+					event.End.Format(time.RFC3339Nano),
+					strconv.FormatInt(event.LastSequence, 10),
+				})
+			}
+		}
+		return rows, nil
+	case TableExactlyOnce:
+		rows := [][]string{{"qos", "expected", "rejected", "received", "unique", "duplicates", "missing", "passed"}}
+		if e := result.ExactlyOnce; e != nil {
+			var received, unique int64
+			if e.Report != nil {
+				received, unique = e.Report.Received, e.Report.Unique
+			}
+			rows = append(rows, []string{
+				strconv.Itoa(int(e.QoS)),
+				strconv.FormatInt(e.Expected, 10),
+				strconv.FormatInt(e.Rejected, 10),
+				strconv.FormatInt(received, 10),
+				strconv.FormatInt(unique, 10),
+				strconv.FormatInt(e.Duplicates, 10),
+				strconv.FormatInt(e.Missing, 10),
+				strconv.FormatBool(e.Passed),
+			})
+		}
+		return rows, nil
+	case TableMQTTFeatures:
+		rows := [][]string{{"retained_sent", "received", "retained_received", "will_triggered", "will_received", "duplicate_flagged", "passed"}}
+		if f := result.MQTTFeatures; f != nil {
+			var received int64
+			if f.Report != nil {
+				received = f.Report.Unique
+			}
+			rows = append(rows, []string{
+				strconv.FormatInt(f.RetainedSent, 10),
+				strconv.FormatInt(received, 10),
+				strconv.FormatInt(f.RetainedReceived, 10),
+				strconv.FormatBool(f.WillTriggered),
+				strconv.FormatInt(f.WillReceived, 10),
+				strconv.FormatInt(f.DuplicateFlagged, 10),
+				strconv.FormatBool(f.Passed),
+			})
+		}
+		return rows, nil
+	case TableProtocols:
+		rows := [][]string{{"protocol", "threads", "messages_sent", "bytes_sent", "errors", "avg_throughput", "avg_latency_ms", "min_latency_ms", "max_latency_ms"}}
+		for _, ps := range result.Protocols {
+			rows = append(rows, []string{
+				string(ps.Protocol),
+				strconv.Itoa(ps.Threads),
+				strconv.FormatInt(ps.MessagesSent, 10),
+				strconv.FormatInt(ps.BytesSent, 10),
+				strconv.FormatInt(ps.Errors, 10),
+				formatFloat(ps.AvgThroughput),
+				formatFloat(ps.AvgLatency),
+				formatFloat(ps.MinLatency),
+				formatFloat(ps.MaxLatency),
+			})
+		}
+		return rows, nil
+	case TableDestinations:
+		rows := [][]string{{"protocol", "target", "messages_sent", "bytes_sent", "errors", "avg_throughput", "avg_latency_ms", "min_latency_ms", "max_latency_ms"}}
+		for _, ds := range result.Destinations {
+			rows = append(rows, []string{
+				string(ds.Protocol),
+				ds.Target,
+				strconv.FormatInt(ds.MessagesSent, 10),
+				strconv.FormatInt(ds.BytesSent, 10),
+				strconv.FormatInt(ds.Errors, 10),
+				formatFloat(ds.AvgThroughput),
+				formatFloat(ds.AvgLatency),
+				formatFloat(ds.MinLatency),
+				formatFloat(ds.MaxLatency),
+			})
+		}
+		return rows, nil
+	case TableFile:
+		rows := [][]string{{"name", "size", "chunks", "chunks_sent", "sha256", "status", "chunks_received", "duplicates", "chunk_errors", "received_sha256", "passed"}}
+		if f := result.File; f != nil {
+			var status, chunksReceived, duplicates, chunkErrors, receivedSHA256 string
+			if r := f.Report; r != nil {
+				status = string(r.Status)
+				chunksReceived = strconv.Itoa(r.ChunksReceived)
+				duplicates = strconv.FormatInt(r.Duplicates, 10)
+				chunkErrors = strconv.FormatInt(r.ChunkErrors, 10)
+				receivedSHA256 = r.SHA256
+			}
+			rows = append(rows, []string{
+				f.Manifest.Name,
+				strconv.FormatInt(f.Manifest.Size, 10),
+				strconv.Itoa(f.Manifest.Chunks),
+				strconv.Itoa(f.ChunksSent),
+				f.Manifest.SHA256,
+				status,
+				chunksReceived,
+				duplicates,
+				chunkErrors,
+				receivedSHA256,
+				strconv.FormatBool(f.Passed),
+			})
+		}
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("неизвестная таблица отчета: %s", table)
+	}
+}
+
+// configRows формирует таблицу параметров и итогов теста
+func configRows(result *models.TestResult) [][]string {
+	rows := [][]string{{"parameter", "value"}, {"id", result.ID}, {"status", string(result.Status)}}
+
+	if cfg := result.Config; cfg != nil {
+		rows = append(rows,
+			[]string{"type", string(cfg.Type)},
+			[]string{"protocol", string(cfg.Protocol)},
+			[]string{"thread_count", strconv.Itoa(cfg.ThreadCount)},
+			[]string{"packet_size", strconv.Itoa(cfg.PacketSize)},
+			[]string{"messages_per_sec", strconv.Itoa(cfg.MessagesPerSec)},
+			[]string{"duration", strconv.Itoa(cfg.Duration)},
+			[]string{"total_messages", strconv.Itoa(cfg.TotalMessages)},
+		)
+		if cfg.Target != "" {
+			rows = append(rows, []string{"target", cfg.Target})
+		}
+		if cfg.Tenant != "" {
+			rows = append(rows, []string{"tenant", cfg.Tenant})
+		}
+		if cfg.RunLabel != "" {
+			rows = append(rows, []string{"run_label", cfg.RunLabel})
+		}
+		if cfg.StartAt != nil {
+			rows = append(rows, []string{"start_at", cfg.StartAt.UTC().Format(time.RFC3339Nano)})
+		}
+		if result.StartLag != nil {
+			rows = append(rows, []string{"start_lag_ms", formatFloat(*result.StartLag)})
+		}
+		if mo := cfg.MQTT; mo != nil {
+			if mo.QoS != nil {
+				rows = append(rows, []string{"mqtt_qos", strconv.Itoa(int(*mo.QoS))})
+			}
+			if mo.Retained != nil {
+				rows = append(rows, []string{"mqtt_retained", strconv.FormatBool(*mo.Retained)})
+			}
+			if mo.CleanSession != nil {
+				rows = append(rows, []string{"mqtt_clean_session", strconv.FormatBool(*mo.CleanSession)})
+			}
+			if mo.Aggregate != nil {
+				rows = append(rows, []string{"mqtt_aggregate", strconv.FormatBool(*mo.Aggregate)})
+			}
+		}
+		if cfg.WarmupSeconds > 0 {
+			rows = append(rows, []string{"warmup_seconds", strconv.Itoa(cfg.WarmupSeconds)})
+		}
+		if cfg.PadToSize {
+			rows = append(rows, []string{"pad_to_size", "true"})
+		}
+		if cfg.Mixed != nil {
+			rows = append(rows,
+				[]string{"mqtt_percent", formatFloat(cfg.Mixed.MQTTPercent)},
+				[]string{"mqtt_threads", strconv.Itoa(cfg.Mixed.MQTTThreads)},
+				[]string{"tcp_threads", strconv.Itoa(cfg.Mixed.TCPThreads)},
+			)
+		}
+		if cfg.Replay != nil {
+			rows = append(rows,
+				[]string{"replay_capture", cfg.Replay.Capture},
+				[]string{"replay_source_test_id", cfg.Replay.SourceTestID},
+				[]string{"replay_speed", formatFloat(cfg.Replay.Speed)},
+				[]string{"replay_events", strconv.Itoa(cfg.Replay.Events)},
+			)
+		}
+		if cfg.Sweep != nil {
+			sizes := make([]string, len(cfg.Sweep.Sizes))
+			for i, size := range cfg.Sweep.Sizes {
+				sizes[i] = strconv.Itoa(size)
+			}
+			rows = append(rows,
+				[]string{"sweep_sizes", strings.Join(sizes, " ")},
+				[]string{"step_duration", strconv.Itoa(cfg.Sweep.StepDuration)},
+				[]string{"settle_time", strconv.Itoa(cfg.Sweep.SettleTime)},
+			)
+		}
+		if cfg.MQTTFeatures != nil {
+			rows = append(rows,
+				[]string{"retained_markers", strconv.Itoa(cfg.MQTTFeatures.RetainedMarkers)},
+				[]string{"trigger_will", strconv.FormatBool(cfg.MQTTFeatures.TriggerWill)},
+				[]string{"settle_time", strconv.Itoa(cfg.MQTTFeatures.SettleTime)},
+			)
+		}
+		if cfg.Fanout != nil {
+			for i, dest := range cfg.Fanout.Destinations {
+				rows = append(rows, []string{fmt.Sprintf("destination_%d", i+1), dest.String()})
+			}
+		}
+		if cfg.File != nil {
+			rows = append(rows,
+				[]string{"file_name", cfg.File.Name},
+				[]string{"file_size", strconv.FormatInt(cfg.File.Size, 10)},
+				[]string{"chunk_size", strconv.Itoa(cfg.File.ChunkSize)},
+				[]string{"settle_time", strconv.Itoa(cfg.File.SettleTime)},
+			)
+		}
+		if ds := cfg.DataSource; ds != nil {
+			switch {
+			case len(ds.Records) > 0:
+				rows = append(rows, []string{"data_records", strconv.Itoa(len(ds.Records))})
+			case ds.File != "":
+				rows = append(rows, []string{"data_set", ds.Set}, []string{"data_file", ds.File})
+			default:
+				rows = append(rows, []string{"data_set", ds.Set})
+				if ds.Size > 0 {
+					rows = append(rows, []string{"data_size_mb", strconv.Itoa(ds.Size)})
+				}
+			}
+		}
+		if cfg.InvalidPercent > 0 {
+			rows = append(rows, []string{"invalid_percent", formatFloat(cfg.InvalidPercent)})
+		}
+		if cfg.MessageTTL > 0 {
+			rows = append(rows, []string{"message_ttl_ms", strconv.Itoa(cfg.MessageTTL)})
+		}
+		if cfg.SendRetries > 0 {
+			rows = append(rows, []string{"send_retries_limit", strconv.Itoa(cfg.SendRetries)})
+		}
+		rows = append(rows, []string{"seed", strconv.FormatInt(cfg.Seed, 10)})
+	}
+	if result.GeneratorHash != "" {
+		rows = append(rows, []string{"generator_config_hash", result.GeneratorHash})
+	}
+	if result.DataHash != "" {
+		rows = append(rows, []string{"data_hash", result.DataHash})
+	}
+
+	if st := result.Stats; st != nil {
+		rows = append(rows,
+			[]string{"start_time", st.StartTime.Format(time.RFC3339)},
+			[]string{"elapsed_seconds", formatFloat(st.Duration.Seconds())},
+			[]string{"messages_sent", strconv.FormatInt(st.MessagesSent, 10)},
+			[]string{"bytes_sent", strconv.FormatInt(st.BytesSent, 10)},
+			[]string{"errors", strconv.FormatInt(st.Errors, 10)},
+			[]string{"invalid_sent", strconv.FormatInt(st.InvalidSent, 10)},
+			[]string{"avg_throughput", formatFloat(st.AvgThroughput)},
+			[]string{"min_latency_ms", formatFloat(st.MinLatency)},
+			[]string{"max_latency_ms", formatFloat(st.MaxLatency)},
+		)
+		if st.OversizeMessages > 0 || (result.Config != nil && result.Config.PadToSize) {
+			rows = append(rows, []string{"oversize_messages", strconv.FormatInt(st.OversizeMessages, 10)})
+		}
+		if st.SendRetries > 0 || (result.Config != nil && result.Config.SendRetries > 0) {
+			rows = append(rows,
+				[]string{"retried_messages", strconv.FormatInt(st.RetriedMessages, 10)},
+				[]string{"send_retries", strconv.FormatInt(st.SendRetries, 10)},
+			)
+		}
+	}
+
+	rows = append(rows, jitterRows("pacing", result.Pacing)...)
+	rows = append(rows, jitterRows("receive", result.ReceiveJitter)...)
+	if l := result.Latency; l != nil {
+		rows = append(rows, hopRows("send", l.Send)...)
+		rows = append(rows, hopRows("delivery", l.Delivery)...)
+		rows = append(rows, hopRows("processing", l.Processing)...)
+		if l.TransitAvgMs != nil {
+			rows = append(rows, []string{"transit_avg_ms", formatFloat(*l.TransitAvgMs)})
+		}
+	}
+
+	if d := result.Duplicates; d != nil {
+		rows = append(rows,
+			[]string{"duplicates_total", strconv.FormatInt(d.Total, 10)},
+			[]string{"duplicates_retry", strconv.FormatInt(d.Retry, 10)},
+			[]string{"duplicates_broker", strconv.FormatInt(d.Broker, 10)},
+		)
+	}
+
+	if result.MQTTUnacked != nil {
+		rows = append(rows, []string{"mqtt_unacked", strconv.FormatInt(*result.MQTTUnacked, 10)})
+	}
+
+	if a := result.Audit; a != nil {
+		rows = append(rows,
+			[]string{"audit_sent", strconv.FormatInt(a.Sent, 10)},
+			[]string{"audit_unique", strconv.FormatInt(a.Unique, 10)},
+			[]string{"audit_lost", strconv.FormatInt(a.Lost, 10)},
+			[]string{"audit_loss_percent", formatFloat(a.LossPercent)},
+			[]string{"audit_digest_match", strconv.FormatBool(a.DigestMatch)},
+		)
+	}
+
+	if r := result.Raw; r != nil {
+		rows = append(rows,
+			[]string{"raw_frame_size", strconv.Itoa(r.FrameSize)},
+			[]string{"raw_connections", strconv.Itoa(r.Connections)},
+			[]string{"raw_frames_sent", strconv.FormatInt(r.FramesSent, 10)},
+			[]string{"raw_bytes_sent", strconv.FormatInt(r.BytesSent, 10)},
+			[]string{"raw_bandwidth_mbps", formatFloat(r.BandwidthMbps)},
+		)
+	}
+
+	if ch := result.Chaos; ch != nil {
+		rows = append(rows,
+			[]string{"chaos_disconnects", strconv.Itoa(ch.Disconnects)},
+			[]string{"chaos_failed", strconv.Itoa(ch.Failed)},
+			[]string{"chaos_reconnected", strconv.Itoa(ch.Reconnected)},
+			[]string{"chaos_avg_reconnect_ms", formatFloat(ch.AvgReconnectMs)},
+			[]string{"chaos_max_reconnect_ms", formatFloat(ch.MaxReconnectMs)},
+			[]string{"chaos_send_errors", strconv.FormatInt(ch.SendErrors, 10)},
+		)
+	}
+
+	if result.Error != "" {
+		rows = append(rows, []string{"error", result.Error})
+	}
+
+	return rows
+}
+
+// jitterRows формирует строки статистики отклонений с префиксом prefix
+func jitterRows(prefix string, j *models.JitterStats) [][]string {
+	if j == nil {
+		return nil
+	}
+	return [][]string{
+		{prefix + "_jitter_samples", strconv.FormatInt(j.Samples, 10)},
+		{prefix + "_avg_jitter_ms", formatFloat(j.AvgMs)},
+		{prefix + "_p95_jitter_ms", formatFloat(j.P95Ms)},
+		{prefix + "_max_jitter_ms", formatFloat(j.MaxMs)},
+	}
+}
+
+// hopRows формирует строки задержки участка пути с префиксом prefix
+func hopRows(prefix string, h *models.HopLatency) [][]string {
+	if h == nil {
+		return nil
+	}
+	return [][]string{
+		{prefix + "_samples", strconv.FormatInt(h.Samples, 10)},
+		{prefix + "_avg_ms", formatFloat(h.AvgMs)},
+		{prefix + "_p95_ms", formatFloat(h.P95Ms)},
+		{prefix + "_max_ms", formatFloat(h.MaxMs)},
+	}
+}
+
+// errorCount пара категория/количество для сортированного вывода
+type errorCount struct {
+	Category string
+	Count    int64
+}
+
+// sortedErrors упорядочивает категории ошибок по убыванию количества
+func sortedErrors(breakdown map[string]int64) []errorCount {
+	errs := make([]errorCount, 0, len(breakdown))
+	for category, count := range breakdown {
+		errs = append(errs, errorCount{Category: category, Count: count})
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].Count == errs[j].Count {
+			return errs[i].Category < errs[j].Category
+		}
+		return errs[i].Count > errs[j].Count
+	})
+	return errs
+}
+
+// bucketLabel возвращает подпись корзины гистограммы
+func bucketLabel(b models.HistogramBucket) string {
+	if b.UpperBoundMs == 0 {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(b.UpperBoundMs, 'f', -1, 64)
+}
+
+// formatFloat форматирует число с точностью до тысячных
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
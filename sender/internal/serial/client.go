@@ -0,0 +1,234 @@
+package serial
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/serialport"
+	"github.com/infodiode/shared/utils"
+	"go.uber.org/zap"
+)
+
+// Client клиент для отправки сообщений через последовательный порт. Каждое сообщение
+// передается отдельным кадром с длиной и CRC (serialport.PutFrame)
+type Client struct {
+	config  serialport.PortConfig
+	timeout time.Duration
+	logger  *zap.Logger
+	mu      sync.Mutex
+	port    *serialport.Port
+
+	messagesSent atomic.Int64
+	bytesSent    atomic.Int64
+	errors       atomic.Int64
+	reopenCount  atomic.Int64
+	everOpened   atomic.Bool
+	statsMu      sync.Mutex
+	lastError    string // Последняя ошибка (под statsMu)
+	lastErrorAt  time.Time
+}
+
+// Config конфигурация клиента последовательного порта
+type Config struct {
+	Port    serialport.PortConfig
+	Timeout time.Duration // Запас времени записи сверх времени передачи кадра на скорости порта
+}
+
+// NewClient создает клиент последовательного порта; порт открывается при Connect
+// или первой отправке
+func NewClient(config *Config, logger *zap.Logger) (*Client, error) {
+	if err := config.Port.Validate(); err != nil {
+		return nil, err
+	}
+
+	client := &Client{
+		config:  config.Port,
+		timeout: config.Timeout,
+		logger:  logger,
+	}
+	if client.timeout == 0 {
+		client.timeout = 10 * time.Second
+	}
+
+	return client, nil
+}
+
+// Connect открывает последовательный порт
+func (c *Client) Connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.open()
+}
+
+// open открывает порт, если он еще не открыт; вызывается под mu
+func (c *Client) open() error {
+	if c.port != nil {
+		return nil
+	}
+
+	port, err := serialport.Open(c.config)
+	if err != nil {
+		return err
+	}
+	c.port = port
+
+	if c.everOpened.Swap(true) {
+		c.reopenCount.Add(1)
+	}
+	c.logger.Info("Последовательный порт открыт",
+		zap.String("device", c.config.Device),
+		zap.Int("baud_rate", c.config.BaudRate))
+
+	return nil
+}
+
+// Disconnect закрывает последовательный порт
+func (c *Client) Disconnect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.port == nil {
+		return nil
+	}
+
+	err := c.port.Close()
+	c.port = nil
+
+	c.logger.Info("Последовательный порт закрыт", zap.String("device", c.config.Device))
+
+	return err
+}
+
+// Send отправляет сообщение одним кадром
+func (c *Client) Send(message *models.Message) error {
+	buf := utils.GetBuffer()
+	defer utils.PutBuffer(buf)
+
+	// Сериализуем сообщение после места под заголовок кадра
+	var header [serialport.HeaderSize]byte
+	buf.Write(header[:])
+	if err := buf.EncodeJSON(message); err != nil {
+		err = fmt.Errorf("ошибка сериализации сообщения: %w", err)
+		c.recordError(err)
+		return err
+	}
+
+	if err := c.write(serialport.PutFrame(buf.Bytes())); err != nil {
+		return err
+	}
+
+	c.messagesSent.Add(1)
+	return nil
+}
+
+// SendBatch отправляет сообщения пакета последовательными кадрами одной записью;
+// получатель обрабатывает каждое сообщение как отдельное
+func (c *Client) SendBatch(messages []*models.Message) error {
+	buf := utils.GetBuffer()
+	defer utils.PutBuffer(buf)
+
+	var frames []byte
+	var header [serialport.HeaderSize]byte
+	for _, message := range messages {
+		buf.Reset()
+		buf.Write(header[:])
+		if err := buf.EncodeJSON(message); err != nil {
+			err = fmt.Errorf("ошибка сериализации пакета: %w", err)
+			c.recordError(err)
+			return err
+		}
+		frames = append(frames, serialport.PutFrame(buf.Bytes())...)
+	}
+
+	if err := c.write(frames); err != nil {
+		return err
+	}
+
+	c.messagesSent.Add(int64(len(messages)))
+	return nil
+}
+
+// write записывает данные в порт, открывая его при необходимости. После ошибки записи
+// порт закрывается и открывается заново при следующей отправке
+func (c *Client) write(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.open(); err != nil {
+		c.recordError(err)
+		return err
+	}
+
+	// Срок записи учитывает время передачи данных на скорости порта
+	c.port.SetWriteDeadline(time.Now().Add(c.timeout + c.config.TransmitTime(len(data))))
+
+	if _, err := c.port.Write(data); err != nil {
+		c.port.Close()
+		c.port = nil
+		err = fmt.Errorf("ошибка записи в последовательный порт: %w", err)
+		c.recordError(err)
+		return err
+	}
+
+	c.bytesSent.Add(int64(len(data)))
+	return nil
+}
+
+// IsConnected проверяет, открыт ли порт
+func (c *Client) IsConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.port != nil
+}
+
+// recordError учитывает ошибку отправки
+func (c *Client) recordError(err error) {
+	c.errors.Add(1)
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.lastError = err.Error()
+	c.lastErrorAt = time.Now()
+}
+
+// ClientStats статистика клиента последовательного порта
+type ClientStats struct {
+	Connected     bool       `json:"connected"`
+	Device        string     `json:"device"`
+	BaudRate      int        `json:"baud_rate"`
+	MessagesSent  int64      `json:"messages_sent"`        // Отправлено сообщений (кадров)
+	BytesSent     int64      `json:"bytes_sent"`           // Отправлено байт с заголовками и CRC кадров
+	Errors        int64      `json:"errors"`               // Ошибок отправки
+	ReopenCount   int64      `json:"reopen_count"`         // Повторных открытий порта после ошибок
+	LastError     string     `json:"last_error,omitempty"` // Последняя ошибка
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+// GetStats возвращает статистику клиента
+func (c *Client) GetStats() ClientStats {
+	stats := ClientStats{
+		Connected:    c.IsConnected(),
+		Device:       c.config.Device,
+		BaudRate:     c.config.BaudRate,
+		MessagesSent: c.messagesSent.Load(),
+		BytesSent:    c.bytesSent.Load(),
+		Errors:       c.errors.Load(),
+		ReopenCount:  c.reopenCount.Load(),
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	stats.LastError = c.lastError
+	if !c.lastErrorAt.IsZero() {
+		at := c.lastErrorAt
+		stats.LastErrorTime = &at
+	}
+
+	return stats
+}
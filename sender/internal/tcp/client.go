@@ -1,38 +1,119 @@
 package tcp
 
 import (
+	"bufio"
 	"encoding/binary"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/tcpframe"
+	"github.com/infodiode/shared/utils"
 	"go.uber.org/zap"
 )
 
 // TCPClient клиент для отправки данных по TCP
 type TCPClient struct {
-	address      string
-	conn         net.Conn
-	logger       *zap.Logger
-	mu           sync.Mutex
-	isConnected  bool
-	reconnectInt time.Duration
-	maxRetries   int
-	timeout      time.Duration
-	stopChan     chan struct{}
+	address         string
+	conn            net.Conn
+	connDone        chan struct{} // Закрывается при завершении текущего соединения
+	logger          *zap.Logger
+	mu              sync.Mutex
+	isConnected     bool
+	reconnectInt    time.Duration // Пауза после первой неудачной попытки переподключения
+	maxReconnectInt time.Duration // Предельная пауза между попытками переподключения
+	maxRetries      int           // Попыток в серии переподключения
+	timeout         time.Duration
+	keepAlive       bool
+	keepAlivePeriod time.Duration
+	socket          tcpframe.SocketOptions
+	framingMode     string        // Режим согласования формата кадров (auto, legacy)
+	pingInterval    time.Duration // Период отправки ping (0 - не отправлять)
+	pingTimeout     time.Duration // Предельное время без pong, после которого соединение считается потерянным
+	framing         string        // Формат кадров текущего соединения (v2, legacy)
+	ping            bool          // Обмен ping/pong согласован для текущего соединения
+	raw             bool          // Сервер принимает кадры-заполнители в текущем соединении
+	frameChecksum   bool          // Запрашивать контрольную сумму кадров при согласовании протокола v2
+	checksum        bool          // Контрольная сумма кадров согласована для текущего соединения
+	holdUntil       time.Time     // До этого момента после принудительного разрыва переподключение не выполняется
+
+	state         string        // Состояние соединения (StateConnected и др.)
+	changed       chan struct{} // Закрывается и заменяется при смене состояния
+	closed        bool          // Клиент отключен через Disconnect
+	dialing       bool          // Выполняется попытка подключения
+	reconnecting  bool          // Выполняется серия переподключения
+	attempts      int           // Попыток в текущей серии переподключения
+	nextAttempt   time.Time     // Время следующей попытки (в состоянии backoff)
+	lastDialErr   error         // Ошибка последней попытки подключения
+	stopReconnect chan struct{} // Закрывается при отключении клиента
+	stateEvents   []StateEvent
+
+	messagesSent      atomic.Int64
+	batchesSent       atomic.Int64
+	bytesSent         atomic.Int64
+	reconnectCount    atomic.Int64
+	reconnectAttempts atomic.Int64
+	pingsSent         atomic.Int64
+	pongsReceived     atomic.Int64
+	lastPong          atomic.Int64 // Время последнего pong или начала соединения (unix nano)
+	pingRTT           tcpframe.RTT // Время прохождения ping клиента
+	pingsReceived     atomic.Int64 // Получено ping сервера (на каждый отправлен pong)
+	rawFramesSent     atomic.Int64
+	rawBytesSent      atomic.Int64
+	statsMu           sync.Mutex
+	errorCounts       map[string]int64 // Ошибки отправки по категориям (под statsMu)
+	lastError         string           // Последняя ошибка (под statsMu)
+	lastErrorTime     time.Time
 }
 
+// Категории ошибок отправки
+const (
+	ErrorCategoryTimeout = "timeout" // Истек таймаут записи или подключения
+	ErrorCategoryReset   = "reset"   // Соединение сброшено сервером
+	ErrorCategoryRefused = "refused" // Сервер отклонил подключение
+	ErrorCategoryOther   = "other"   // Прочие ошибки
+)
+
+// Режимы согласования формата кадров
+const (
+	FramingAuto   = "auto"   // Согласовать протокол v2, без ответа сервера перейти на исходный формат
+	FramingLegacy = "legacy" // Исходный формат без согласования и ping
+)
+
+// framingV2 формат кадров соединения, согласовавшего протокол v2
+const framingV2 = "v2"
+
+// keepAliveProbes неотвеченных проб TCP keep-alive до разрыва соединения
+const keepAliveProbes = 3
+
+// errNegotiation сервер не ответил на приветствие протокола v2
+var errNegotiation = errors.New("протокол v2 не согласован")
+
+// ErrRawUnsupported сервер не согласовал прием кадров-заполнителей
+var ErrRawUnsupported = errors.New("сервер не принимает кадры-заполнители")
+
 // Config конфигурация TCP клиента
 type Config struct {
 	Address         string        `yaml:"address" json:"address"`
 	ReconnectInt    time.Duration `yaml:"reconnect_interval" json:"reconnect_interval"`
+	MaxReconnectInt time.Duration `yaml:"max_reconnect_interval" json:"max_reconnect_interval"` // Предельная пауза между попытками переподключения
 	MaxRetries      int           `yaml:"max_retries" json:"max_retries"`
 	Timeout         time.Duration `yaml:"timeout" json:"timeout"`
 	KeepAlive       bool          `yaml:"keep_alive" json:"keep_alive"`
 	KeepAlivePeriod time.Duration `yaml:"keep_alive_period" json:"keep_alive_period"`
+	Framing         string        `yaml:"framing" json:"framing"`             // Режим согласования формата кадров (auto, legacy)
+	PingInterval    time.Duration `yaml:"ping_interval" json:"ping_interval"` // Период ping (0 - не отправлять)
+	PingTimeout     time.Duration `yaml:"ping_timeout" json:"ping_timeout"`   // Предельное время без pong (0 - три периода ping)
+
+	FrameChecksum bool                   `yaml:"frame_checksum" json:"frame_checksum"` // Контрольная сумма кадров в протоколе v2
+	Socket        tcpframe.SocketOptions `yaml:"-" json:"socket"`                      // Буферы, TCP_NODELAY и SO_LINGER соединения
 }
 
 // NewTCPClient создает новый TCP клиент
@@ -42,75 +123,217 @@ func NewTCPClient(config *Config, logger *zap.Logger) (*TCPClient, error) {
 	}
 
 	client := &TCPClient{
-		address:      config.Address,
-		logger:       logger,
-		reconnectInt: config.ReconnectInt,
-		maxRetries:   config.MaxRetries,
-		timeout:      config.Timeout,
-		stopChan:     make(chan struct{}),
+		address:         config.Address,
+		logger:          logger,
+		reconnectInt:    config.ReconnectInt,
+		maxReconnectInt: config.MaxReconnectInt,
+		maxRetries:      config.MaxRetries,
+		timeout:         config.Timeout,
+		keepAlive:       config.KeepAlive,
+		keepAlivePeriod: config.KeepAlivePeriod,
+		framingMode:     config.Framing,
+		pingInterval:    config.PingInterval,
+		pingTimeout:     config.PingTimeout,
+		frameChecksum:   config.FrameChecksum,
+		socket:          config.Socket,
+		errorCounts:     make(map[string]int64),
+		state:           StateDisconnected,
+		changed:         make(chan struct{}),
+		stopReconnect:   make(chan struct{}),
 	}
 
 	// Устанавливаем значения по умолчанию
 	if client.reconnectInt == 0 {
 		client.reconnectInt = 5 * time.Second
 	}
+	if client.maxReconnectInt < client.reconnectInt {
+		client.maxReconnectInt = max(time.Minute, client.reconnectInt)
+	}
 	if client.maxRetries == 0 {
 		client.maxRetries = 3
 	}
 	if client.timeout == 0 {
 		client.timeout = 10 * time.Second
 	}
+	if client.keepAlivePeriod == 0 {
+		client.keepAlivePeriod = 30 * time.Second
+	}
+	if client.framingMode == "" {
+		client.framingMode = FramingAuto
+	}
+	if client.pingTimeout == 0 {
+		client.pingTimeout = 3 * client.pingInterval
+	}
 
 	return client, nil
 }
 
-// Connect устанавливает соединение с TCP сервером
+// Connect устанавливает соединение с TCP сервером; отключенный клиент снова
+// переподключается при потере соединения
 func (c *TCPClient) Connect() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.closed {
+		c.closed = false
+		c.stopReconnect = make(chan struct{})
+	}
+
+	err := c.dialLocked()
+	if err != nil && !errors.Is(err, errClosed) {
+		c.setState(StateDisconnected, err)
+	}
+	return err
+}
+
+// dialLocked выполняет попытку подключения, освобождая c.mu на время установления
+// соединения; подключение, начатое другой горутиной, ожидается. При ошибке состояние
+// устанавливает вызывающий (вызывается и возвращается под c.mu)
+func (c *TCPClient) dialLocked() error {
+	for c.dialing {
+		c.waitChange(nil)
+	}
 	if c.isConnected {
 		return nil
 	}
+	if c.closed {
+		return errClosed
+	}
+
+	c.dialing = true
+	c.setState(StateConnecting, nil)
+	c.mu.Unlock()
 
 	c.logger.Info("Подключение к TCP серверу", zap.String("address", c.address))
+	conn, hello, err := c.open()
 
-	conn, err := net.Dial("tcp", c.address)
-	if err != nil {
-		return fmt.Errorf("ошибка подключения к TCP серверу: %w", err)
+	c.mu.Lock()
+	c.dialing = false
+	c.notify()
+	if err == nil && c.closed {
+		conn.Close()
+		err = errClosed
 	}
-
-	// Устанавливаем keep-alive для поддержания соединения
-	if tcpConn, ok := conn.(*net.TCPConn); ok {
-		tcpConn.SetKeepAlive(true)
-		tcpConn.SetKeepAlivePeriod(30 * time.Second)
+	if err != nil {
+		if !errors.Is(err, errClosed) {
+			err = fmt.Errorf("ошибка подключения к TCP серверу: %w", err)
+			c.lastDialErr = err
+		}
+		return err
 	}
 
 	c.conn = conn
+	c.connDone = make(chan struct{})
 	c.isConnected = true
+	c.lastDialErr = nil
+	c.framing = FramingLegacy
+	c.ping = false
+	c.raw = false
+	c.checksum = false
+	if hello != nil {
+		c.framing = framingV2
+		c.ping = hello.Has(tcpframe.FeaturePing)
+		c.raw = hello.Has(tcpframe.FeatureRaw)
+		c.checksum = hello.Has(tcpframe.FeatureChecksum)
+	}
+	c.lastPong.Store(time.Now().UnixNano())
+	c.setState(StateConnected, nil)
 
-	c.logger.Info("Успешное подключение к TCP серверу", zap.String("address", c.address))
+	c.logger.Info("Успешное подключение к TCP серверу",
+		zap.String("address", c.address),
+		zap.String("framing", c.framing),
+		zap.Bool("ping", c.ping),
+		zap.Bool("checksum", c.checksum))
 
-	// Запускаем горутину для проверки соединения
-	go c.monitorConnection()
+	// Наблюдение за соединением завершается вместе с ним
+	go c.monitorConnection(conn, c.connDone, c.ping)
 
 	return nil
 }
 
-// Disconnect закрывает соединение с TCP сервером
+// open устанавливает соединение, согласуя протокол v2, если он не отключен
+func (c *TCPClient) open() (net.Conn, *tcpframe.Hello, error) {
+	conn, hello, err := c.dial(c.framingMode != FramingLegacy)
+	if errors.Is(err, errNegotiation) {
+		// Сервер исходной версии не отвечает на приветствие и разбирает его как кадр,
+		// поэтому соединение устанавливается заново без приветствия
+		c.logger.Warn("Сервер не поддерживает протокол v2, используется исходный формат кадров",
+			zap.String("address", c.address),
+			zap.Error(err))
+		conn, hello, err = c.dial(false)
+	}
+	return conn, hello, err
+}
+
+// dial устанавливает соединение с TCP keep-alive и параметрами сокета и при negotiate
+// согласует протокол v2; nil hello означает исходный формат кадров
+func (c *TCPClient) dial(negotiate bool) (net.Conn, *tcpframe.Hello, error) {
+	dialer := net.Dialer{Timeout: c.timeout, KeepAlive: -1}
+	if c.keepAlive {
+		dialer.KeepAliveConfig = net.KeepAliveConfig{
+			Enable:   true,
+			Idle:     c.keepAlivePeriod,
+			Interval: c.keepAlivePeriod,
+			Count:    keepAliveProbes,
+		}
+	}
+
+	conn, err := dialer.Dial("tcp", c.address)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := c.socket.Apply(conn); err != nil {
+		// Соединение работает и с параметрами ОС, поэтому ошибка не прерывает подключение
+		c.logger.Warn("Не удалось применить параметры сокета",
+			zap.String("address", c.address),
+			zap.Error(err))
+	}
+	if !negotiate {
+		return conn, nil, nil
+	}
+
+	// Прием кадров-заполнителей и ping сервера запрашиваются всегда: без отправки
+	// заполнителей и без ping сервера эти возможности ни на что не влияют
+	features := tcpframe.FeatureRaw | tcpframe.FeatureServerPing
+	if c.pingInterval > 0 {
+		features |= tcpframe.FeaturePing
+	}
+	if c.frameChecksum {
+		features |= tcpframe.FeatureChecksum
+	}
+
+	conn.SetDeadline(time.Now().Add(c.timeout))
+	_, err = conn.Write(tcpframe.AppendHello(nil, tcpframe.Hello{Version: tcpframe.Version, Features: features}))
+	var hello tcpframe.Hello
+	if err == nil {
+		hello, err = tcpframe.ReadHello(conn)
+	}
+	conn.SetDeadline(time.Time{})
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("%w: %v", errNegotiation, err)
+	}
+
+	return conn, &hello, nil
+}
+
+// Disconnect закрывает соединение с TCP сервером и прекращает переподключение
 func (c *TCPClient) Disconnect() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if !c.closed {
+		c.closed = true
+		close(c.stopReconnect)
+	}
+
 	if !c.isConnected || c.conn == nil {
+		c.setState(StateClosed, nil)
 		return nil
 	}
 
-	close(c.stopChan)
-
-	err := c.conn.Close()
-	c.isConnected = false
-	c.conn = nil
+	err := c.closeConn()
+	c.setState(StateClosed, nil)
 
 	c.logger.Info("Отключение от TCP сервера", zap.String("address", c.address))
 
@@ -122,41 +345,51 @@ func (c *TCPClient) Send(message *models.Message) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if !c.isConnected || c.conn == nil {
-		// Пытаемся переподключиться
-		c.mu.Unlock()
-		if err := c.reconnect(); err != nil {
-			return fmt.Errorf("не удалось переподключиться: %w", err)
-		}
-		c.mu.Lock()
+	if err := c.awaitConnection(); err != nil {
+		return err
 	}
 
-	// Сериализуем сообщение в JSON
-	data, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("ошибка сериализации сообщения: %w", err)
+	// Сериализуем сообщение в JSON после места под заголовок: тип и длина в протоколе v2,
+	// только длина (4 байта) в исходном формате
+	buf := utils.GetBuffer()
+	defer utils.PutBuffer(buf)
+
+	headerSize := 4
+	if c.framing == framingV2 {
+		headerSize = tcpframe.HeaderSize
+	}
+	var header [tcpframe.HeaderSize]byte
+	buf.Write(header[:headerSize])
+	if err := buf.EncodeJSON(message); err != nil {
+		err = fmt.Errorf("ошибка сериализации сообщения: %w", err)
+		c.recordError(err)
+		return err
 	}
 
-	// Добавляем длину сообщения в начало (4 байта)
-	// Это позволит получателю корректно читать сообщения
-	length := uint32(len(data))
-	lengthBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(lengthBytes, length)
+	frame := buf.Bytes()
+	if headerSize == tcpframe.HeaderSize {
+		if c.checksum {
+			frame = tcpframe.AppendChecksum(frame)
+		}
+		tcpframe.PutHeader(frame, tcpframe.TypeMessage)
+	} else {
+		binary.BigEndian.PutUint32(frame[:4], uint32(len(frame)-4))
+	}
 
 	// Устанавливаем таймаут на запись
 	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
 
-	// Отправляем длину сообщения
-	if _, err := c.conn.Write(lengthBytes); err != nil {
-		c.isConnected = false
-		return fmt.Errorf("ошибка отправки длины сообщения: %w", err)
+	// Отправляем длину и сообщение одной записью
+	if _, err := c.conn.Write(frame); err != nil {
+		c.closeConn()
+		err = fmt.Errorf("ошибка отправки сообщения: %w", err)
+		c.recordError(err)
+		c.startReconnect(err)
+		return err
 	}
 
-	// Отправляем само сообщение
-	if _, err := c.conn.Write(data); err != nil {
-		c.isConnected = false
-		return fmt.Errorf("ошибка отправки сообщения: %w", err)
-	}
+	c.messagesSent.Add(1)
+	c.bytesSent.Add(int64(len(frame)))
 
 	return nil
 }
@@ -173,89 +406,244 @@ func (c *TCPClient) SendBatch(messages []*models.Message) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if !c.isConnected || c.conn == nil {
-		c.mu.Unlock()
-		if err := c.reconnect(); err != nil {
-			return fmt.Errorf("не удалось переподключиться: %w", err)
-		}
-		c.mu.Lock()
+	if err := c.awaitConnection(); err != nil {
+		return err
 	}
 
-	// Сериализуем пакет в JSON
-	data, err := json.Marshal(batch)
-	if err != nil {
-		return fmt.Errorf("ошибка сериализации пакета: %w", err)
+	// Сериализуем пакет в JSON после заголовка (маркер и длина); кадр пакета
+	// одинаков в протоколе v2 и исходном формате
+	buf := utils.GetBuffer()
+	defer utils.PutBuffer(buf)
+
+	var header [tcpframe.HeaderSize]byte
+	buf.Write(header[:])
+	if err := buf.EncodeJSON(batch); err != nil {
+		err = fmt.Errorf("ошибка сериализации пакета: %w", err)
+		c.recordError(err)
+		return err
 	}
 
-	// Добавляем длину и маркер пакета
-	length := uint32(len(data))
-	header := make([]byte, 5)
-	header[0] = 0x01 // Маркер пакетной отправки
-	binary.BigEndian.PutUint32(header[1:], length)
+	frame := buf.Bytes()
+	if c.checksum {
+		frame = tcpframe.AppendChecksum(frame)
+	}
+	tcpframe.PutHeader(frame, tcpframe.TypeBatch)
 
 	// Устанавливаем таймаут на запись
 	c.conn.SetWriteDeadline(time.Now().Add(c.timeout * 2)) // Увеличенный таймаут для пакета
 
-	// Отправляем заголовок
-	if _, err := c.conn.Write(header); err != nil {
-		c.isConnected = false
-		return fmt.Errorf("ошибка отправки заголовка пакета: %w", err)
+	// Отправляем заголовок и данные одной записью
+	if _, err := c.conn.Write(frame); err != nil {
+		c.closeConn()
+		err = fmt.Errorf("ошибка отправки пакета: %w", err)
+		c.recordError(err)
+		c.startReconnect(err)
+		return err
 	}
 
-	// Отправляем данные
-	if _, err := c.conn.Write(data); err != nil {
-		c.isConnected = false
-		return fmt.Errorf("ошибка отправки пакета: %w", err)
+	c.batchesSent.Add(1)
+	c.messagesSent.Add(int64(len(messages)))
+	c.bytesSent.Add(int64(len(frame)))
+
+	return nil
+}
+
+// SendRaw отправляет готовый кадр-заполнитель (заголовок TypeRaw и тело) без сериализации.
+// Кадр не изменяется и может одновременно отправляться другими клиентами. Требуется
+// протокол v2 с возможностью приема таких кадров, иначе сервер не сможет их пропустить
+func (c *TCPClient) SendRaw(frame []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.awaitConnection(); err != nil {
+		return err
+	}
+
+	if !c.raw {
+		return fmt.Errorf("%w: %s (требуется протокол v2 с их поддержкой)", ErrRawUnsupported, c.address)
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(frame); err != nil {
+		c.closeConn()
+		err = fmt.Errorf("ошибка отправки кадра-заполнителя: %w", err)
+		c.recordError(err)
+		c.startReconnect(err)
+		return err
 	}
 
+	c.rawFramesSent.Add(1)
+	c.rawBytesSent.Add(int64(len(frame)))
+
 	return nil
 }
 
-// reconnect пытается переподключиться к серверу
-func (c *TCPClient) reconnect() error {
-	retries := 0
-	for retries < c.maxRetries {
-		c.logger.Info("Попытка переподключения",
-			zap.Int("attempt", retries+1),
-			zap.Int("max_retries", c.maxRetries))
-
-		if err := c.Connect(); err != nil {
-			retries++
-			if retries >= c.maxRetries {
-				return fmt.Errorf("превышено количество попыток переподключения: %w", err)
-			}
-			time.Sleep(c.reconnectInt)
-			continue
-		}
+// Churn принудительно закрывает соединение; следующая отправка после downtime
+// переподключается, до этого отправка завершается ошибкой
+func (c *TCPClient) Churn(downtime time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.isConnected || c.conn == nil {
+		return fmt.Errorf("нет соединения с TCP сервером")
+	}
+
+	c.logger.Warn("Принудительный разрыв соединения с TCP сервером",
+		zap.String("address", c.address),
+		zap.Duration("downtime", downtime))
+
+	err := c.closeConn()
+	c.holdUntil = time.Now().Add(downtime)
+	c.startReconnect(fmt.Errorf("принудительный разрыв на %s", downtime))
+	return err
+}
+
+// closeConn закрывает текущее соединение и завершает наблюдение за ним (вызывается под c.mu)
+func (c *TCPClient) closeConn() error {
+	c.isConnected = false
+	if c.conn == nil {
 		return nil
 	}
-	return fmt.Errorf("не удалось переподключиться после %d попыток", c.maxRetries)
+
+	err := c.conn.Close()
+	close(c.connDone)
+	c.conn = nil
+	c.connDone = nil
+	c.framing = ""
+	c.ping = false
+	c.raw = false
+	c.checksum = false
+	return err
 }
 
-// monitorConnection мониторит состояние соединения
-func (c *TCPClient) monitorConnection() {
-	ticker := time.NewTicker(30 * time.Second)
+// checkHold возвращает ошибку, если после принудительного разрыва еще не прошло
+// время без соединения (вызывается под c.mu)
+func (c *TCPClient) checkHold() error {
+	if time.Now().Before(c.holdUntil) {
+		err := fmt.Errorf("соединение с TCP сервером принудительно разорвано")
+		c.recordError(err)
+		return err
+	}
+	return nil
+}
+
+// monitorConnection наблюдает за соединением conn до его завершения (закрытия done):
+// читает кадры сервера и при согласованном ping периодически отправляет ping. Разрыв
+// обнаруживается по ошибке чтения, по отсутствию pong дольше ping_timeout и средствами
+// TCP keep-alive; байты вне кадров в соединение не пишутся
+func (c *TCPClient) monitorConnection(conn net.Conn, done chan struct{}, ping bool) {
+	go c.readFrames(conn)
+
+	if !ping || c.pingInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.pingInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-c.stopChan:
+		case <-done:
 			return
 		case <-ticker.C:
-			c.mu.Lock()
-			if c.isConnected && c.conn != nil {
-				// Проверяем соединение отправкой пустого пакета
-				c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-				if _, err := c.conn.Write([]byte{0x00}); err != nil {
-					c.logger.Warn("Потеря соединения с TCP сервером", zap.Error(err))
-					c.isConnected = false
+			c.sendPing(conn)
+		}
+	}
+}
+
+// readFrames читает кадры сервера до ошибки чтения: pong учитывается в статистике,
+// на ping сервера отправляется pong, прочие кадры пропускаются. Сервер исходного
+// формата ничего не пишет, поэтому для него чтение только обнаруживает закрытие соединения
+func (c *TCPClient) readFrames(conn net.Conn) {
+	reader := bufio.NewReaderSize(conn, c.socket.BufferSize())
+	for {
+		frameType, length, err := tcpframe.ReadHeader(reader)
+		if err == nil {
+			var sent time.Time
+			switch frameType {
+			case tcpframe.TypePong:
+				if sent, err = tcpframe.ReadPing(reader, length); err == nil {
+					c.pingRTT.Observe(sent)
+					c.lastPong.Store(time.Now().UnixNano())
+					c.pongsReceived.Add(1)
 				}
+			case tcpframe.TypePing:
+				if sent, err = tcpframe.ReadPing(reader, length); err == nil {
+					c.pingsReceived.Add(1)
+					c.sendPong(conn, sent)
+				}
+			default:
+				_, err = io.CopyN(io.Discard, reader, int64(length))
 			}
-			c.mu.Unlock()
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = fmt.Errorf("сервер закрыл соединение: %w", err)
+			}
+			c.connectionLost(conn, err)
+			return
 		}
 	}
 }
 
+// sendPing отправляет ping или разрывает соединение, если pong не было дольше ping_timeout
+func (c *TCPClient) sendPing(conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != conn {
+		return
+	}
+
+	if silence := time.Since(time.Unix(0, c.lastPong.Load())); silence > c.pingTimeout {
+		c.lose(fmt.Errorf("нет ответа на ping %s: %w", silence.Round(time.Millisecond), os.ErrDeadlineExceeded))
+		return
+	}
+
+	// Кадр пишется под c.mu целиком, поэтому не может оказаться внутри кадра сообщения
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(tcpframe.AppendPing(nil, tcpframe.TypePing, time.Now())); err != nil {
+		c.lose(err)
+		return
+	}
+	c.pingsSent.Add(1)
+}
+
+// sendPong отвечает на ping сервера кадром pong с временем отправки ping sent
+func (c *TCPClient) sendPong(conn net.Conn, sent time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != conn {
+		return
+	}
+
+	// Как и ping, pong пишется под c.mu целиком, между кадрами сообщений
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(tcpframe.AppendPing(nil, tcpframe.TypePong, sent)); err != nil {
+		c.lose(err)
+	}
+}
+
+// connectionLost закрывает соединение conn после ошибки, если оно еще текущее
+func (c *TCPClient) connectionLost(conn net.Conn, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == conn {
+		c.lose(err)
+	}
+}
+
+// lose учитывает потерю текущего соединения, закрывает его и запускает
+// переподключение (вызывается под c.mu)
+func (c *TCPClient) lose(err error) {
+	c.logger.Warn("Потеря соединения с TCP сервером", zap.Error(err))
+	c.recordError(err)
+	c.closeConn()
+	c.startReconnect(err)
+}
+
 // IsConnected проверяет состояние соединения
 func (c *TCPClient) IsConnected() bool {
 	c.mu.Lock()
@@ -263,14 +651,119 @@ func (c *TCPClient) IsConnected() bool {
 	return c.isConnected
 }
 
+// recordError учитывает ошибку отправки в статистике по категориям
+func (c *TCPClient) recordError(err error) {
+	category := classifyError(err)
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.errorCounts[category]++
+	c.lastError = err.Error()
+	c.lastErrorTime = time.Now()
+}
+
+// classifyError определяет категорию ошибки отправки
+func classifyError(err error) string {
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrorCategoryTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorCategoryTimeout
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ErrorCategoryReset
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorCategoryRefused
+	default:
+		return ErrorCategoryOther
+	}
+}
+
+// ClientStats статистика TCP клиента
+type ClientStats struct {
+	Connected      bool              `json:"connected"`
+	Address        string            `json:"address"`
+	State          string            `json:"state"`                // Состояние соединения
+	MaxRetries     int               `json:"max_retries"`          // Попыток в серии переподключения
+	MessagesSent   int64             `json:"messages_sent"`        // Отправлено сообщений (включая сообщения пакетов)
+	BatchesSent    int64             `json:"batches_sent"`         // Отправлено пакетов
+	BytesSent      int64             `json:"bytes_sent"`           // Отправлено байт с заголовками кадров
+	Errors         int64             `json:"errors"`               // Ошибок отправки
+	ErrorBreakdown map[string]int64  `json:"error_breakdown"`      // Ошибки по категориям
+	ReconnectCount int64             `json:"reconnect_count"`      // Успешных переподключений
+	Framing        string            `json:"framing,omitempty"`    // Формат кадров текущего соединения (v2, legacy)
+	FrameChecksum  bool              `json:"frame_checksum"`       // Кадры текущего соединения передаются с контрольной суммой
+	PingsSent      int64             `json:"pings_sent"`           // Отправлено ping
+	PongsReceived  int64             `json:"pongs_received"`       // Получено pong
+	PingRTTMs      float64           `json:"ping_rtt_ms"`          // Время прохождения последнего ping
+	PingRTT        tcpframe.RTTStats `json:"ping_rtt"`             // Время прохождения ping клиента за все соединения
+	PingsReceived  int64             `json:"pings_received"`       // Получено ping сервера (ответ - pong)
+	RawFramesSent  int64             `json:"raw_frames_sent"`      // Отправлено кадров-заполнителей (тест насыщения канала)
+	RawBytesSent   int64             `json:"raw_bytes_sent"`       // Байт кадров-заполнителей с заголовками
+	LastError      string            `json:"last_error,omitempty"` // Последняя ошибка
+	LastErrorTime  *time.Time        `json:"last_error_time,omitempty"`
+
+	ReconnectAttempts int64        `json:"reconnect_attempts"`     // Попыток переподключения
+	NextAttempt       *time.Time   `json:"next_attempt,omitempty"` // Следующая попытка переподключения (backoff)
+	StateEvents       []StateEvent `json:"state_events,omitempty"` // Последние смены состояния соединения
+}
+
 // GetStats возвращает статистику TCP клиента
-func (c *TCPClient) GetStats() map[string]interface{} {
+func (c *TCPClient) GetStats() ClientStats {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	connected := c.isConnected
+	framing := c.framing
+	checksum := c.checksum
+	state := c.state
+	var nextAttempt *time.Time
+	if state == StateBackoff {
+		at := c.nextAttempt
+		nextAttempt = &at
+	}
+	events := make([]StateEvent, len(c.stateEvents))
+	copy(events, c.stateEvents)
+	c.mu.Unlock()
+
+	rtt := c.pingRTT.Stats()
+	stats := ClientStats{
+		Connected:      connected,
+		Address:        c.address,
+		State:          state,
+		MaxRetries:     c.maxRetries,
+		MessagesSent:   c.messagesSent.Load(),
+		BatchesSent:    c.batchesSent.Load(),
+		BytesSent:      c.bytesSent.Load(),
+		ReconnectCount: c.reconnectCount.Load(),
+		Framing:        framing,
+		FrameChecksum:  checksum,
+		PingsSent:      c.pingsSent.Load(),
+		PongsReceived:  c.pongsReceived.Load(),
+		PingRTTMs:      rtt.LastMs,
+		PingRTT:        rtt,
+		PingsReceived:  c.pingsReceived.Load(),
+		RawFramesSent:  c.rawFramesSent.Load(),
+		RawBytesSent:   c.rawBytesSent.Load(),
+
+		ReconnectAttempts: c.reconnectAttempts.Load(),
+		NextAttempt:       nextAttempt,
+		StateEvents:       events,
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
 
-	return map[string]interface{}{
-		"connected": c.isConnected,
-		"address":   c.address,
-		"retries":   c.maxRetries,
+	stats.ErrorBreakdown = make(map[string]int64, len(c.errorCounts))
+	for category, count := range c.errorCounts {
+		stats.ErrorBreakdown[category] = count
+		stats.Errors += count
 	}
+	stats.LastError = c.lastError
+	if !c.lastErrorTime.IsZero() {
+		at := c.lastErrorTime
+		stats.LastErrorTime = &at
+	}
+
+	return stats
 }
@@ -0,0 +1,227 @@
+package tcp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Состояния соединения TCP клиента
+const (
+	StateDisconnected = "disconnected" // Нет соединения, переподключение не выполняется
+	StateConnecting   = "connecting"   // Выполняется попытка подключения
+	StateConnected    = "connected"    // Соединение установлено
+	StateBackoff      = "backoff"      // Пауза перед следующей попыткой переподключения
+	StateHeld         = "held"         // Пауза после принудительного разрыва (тест с разрывами)
+	StateFailed       = "failed"       // Попытки серии исчерпаны; следующая отправка начинает новую серию
+	StateClosed       = "closed"       // Клиент отключен, переподключение не выполняется
+)
+
+// maxStateEvents количество хранимых событий смены состояния соединения
+const maxStateEvents = 50
+
+// ErrNotConnected возвращается при отправке без соединения с TCP сервером
+var ErrNotConnected = errors.New("нет соединения с TCP сервером")
+
+// errClosed попытка подключения прервана отключением клиента
+var errClosed = errors.New("TCP клиент отключен")
+
+// StateEvent событие смены состояния соединения
+type StateEvent struct {
+	Time      time.Time `json:"time"`
+	State     string    `json:"state"`
+	Attempt   int       `json:"attempt,omitempty"`     // Номер попытки в серии переподключения
+	RetryInMs int64     `json:"retry_in_ms,omitempty"` // Пауза до следующей попытки (backoff)
+	Error     string    `json:"error,omitempty"`       // Причина разрыва или ошибка попытки
+}
+
+// Переподключение выполняется фоновой горутиной reconnectLoop, которая запускается при
+// потере соединения или отправке без соединения. Попытки серии разделяются паузами,
+// растущими от reconnect_interval вдвое до max_reconnect_interval, со случайным
+// разбросом; после max_retries неудачных попыток серия завершается (failed).
+// Отправка не подключается сама, а ожидает результата серии не дольше timeout, поэтому
+// c.mu не удерживается на время установления соединения
+
+// setState переводит соединение в состояние state и будит ожидающих смены состояния;
+// cause - причина перехода (вызывается под c.mu)
+func (c *TCPClient) setState(state string, cause error) {
+	c.notify()
+
+	if state == c.state && cause == nil {
+		return
+	}
+	c.state = state
+
+	event := StateEvent{Time: time.Now(), State: state}
+	if c.reconnecting {
+		event.Attempt = c.attempts
+	}
+	if state == StateBackoff {
+		event.RetryInMs = time.Until(c.nextAttempt).Milliseconds()
+	}
+	if cause != nil {
+		event.Error = cause.Error()
+	}
+	c.stateEvents = append(c.stateEvents, event)
+	if len(c.stateEvents) > maxStateEvents {
+		c.stateEvents = c.stateEvents[len(c.stateEvents)-maxStateEvents:]
+	}
+}
+
+// notify будит ожидающих смены состояния или завершения попытки подключения
+// (вызывается под c.mu)
+func (c *TCPClient) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// waitChange освобождает c.mu до смены состояния или срабатывания timeout (nil - без
+// ограничения). Возвращает false по timeout (вызывается под c.mu)
+func (c *TCPClient) waitChange(timeout <-chan time.Time) bool {
+	changed := c.changed
+	c.mu.Unlock()
+	defer c.mu.Lock()
+
+	select {
+	case <-changed:
+		return true
+	case <-timeout:
+		return false
+	}
+}
+
+// sleep освобождает c.mu на время d или до отключения клиента (вызывается под c.mu)
+func (c *TCPClient) sleep(d time.Duration) {
+	stop := c.stopReconnect
+	c.mu.Unlock()
+	defer c.mu.Lock()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-stop:
+	}
+}
+
+// startReconnect запускает серию переподключения, если она еще не выполняется;
+// cause - причина разрыва (вызывается под c.mu)
+func (c *TCPClient) startReconnect(cause error) {
+	if c.closed || c.isConnected || c.reconnecting {
+		return
+	}
+	c.reconnecting = true
+	c.attempts = 0
+	c.setState(StateDisconnected, cause)
+
+	go c.reconnectLoop()
+}
+
+// reconnectLoop выполняет серию попыток переподключения до успеха, исчерпания
+// max_retries или отключения клиента
+func (c *TCPClient) reconnectLoop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer func() { c.reconnecting = false }()
+
+	for {
+		if c.closed || c.isConnected {
+			return
+		}
+
+		// После принудительного разрыва соединение не восстанавливается до holdUntil
+		if hold := time.Until(c.holdUntil); hold > 0 {
+			c.setState(StateHeld, nil)
+			c.sleep(hold)
+			continue
+		}
+
+		c.attempts++
+		c.reconnectAttempts.Add(1)
+		c.logger.Info("Попытка переподключения",
+			zap.String("address", c.address),
+			zap.Int("attempt", c.attempts),
+			zap.Int("max_retries", c.maxRetries))
+
+		err := c.dialLocked()
+		switch {
+		case err == nil:
+			c.reconnectCount.Add(1)
+			return
+		case errors.Is(err, errClosed):
+			return
+		case c.attempts >= c.maxRetries:
+			c.logger.Error("Превышено количество попыток переподключения к TCP серверу",
+				zap.String("address", c.address),
+				zap.Int("attempts", c.attempts),
+				zap.Error(err))
+			c.setState(StateFailed, err)
+			return
+		}
+
+		delay := c.backoff(c.attempts)
+		c.nextAttempt = time.Now().Add(delay)
+		c.logger.Warn("Ошибка переподключения к TCP серверу",
+			zap.String("address", c.address),
+			zap.Int("attempt", c.attempts),
+			zap.Duration("retry_in", delay),
+			zap.Error(err))
+		c.setState(StateBackoff, err)
+		c.sleep(delay)
+	}
+}
+
+// backoff возвращает паузу после неудачной попытки attempt: reconnect_interval,
+// удваиваемый с каждой попыткой до max_reconnect_interval, со случайным разбросом в
+// пределах [d/2, d], чтобы клиенты не переподключались одновременно
+func (c *TCPClient) backoff(attempt int) time.Duration {
+	d := c.reconnectInt
+	for i := 1; i < attempt && d < c.maxReconnectInt; i++ {
+		d *= 2
+	}
+	d = min(d, c.maxReconnectInt)
+
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// awaitConnection проверяет соединение перед отправкой: без соединения запускает серию
+// переподключения и ожидает ее результата не дольше timeout (вызывается под c.mu)
+func (c *TCPClient) awaitConnection() error {
+	if c.isConnected && c.conn != nil {
+		return nil
+	}
+	if err := c.checkHold(); err != nil {
+		return err
+	}
+
+	c.startReconnect(nil)
+
+	timeout := time.NewTimer(c.timeout)
+	defer timeout.Stop()
+
+	for !c.isConnected || c.conn == nil {
+		if c.closed || !c.reconnecting && !c.dialing {
+			return c.notConnected()
+		}
+		if !c.waitChange(timeout.C) {
+			err := fmt.Errorf("%w: переподключение не завершилось за %s", ErrNotConnected, c.timeout)
+			c.recordError(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// notConnected учитывает и возвращает ошибку отправки без соединения (вызывается под c.mu)
+func (c *TCPClient) notConnected() error {
+	err := fmt.Errorf("%w (%s)", ErrNotConnected, c.state)
+	if c.state == StateFailed && c.lastDialErr != nil {
+		err = fmt.Errorf("не удалось переподключиться: %w", c.lastDialErr)
+	}
+	c.recordError(err)
+	return err
+}
@@ -0,0 +1,203 @@
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// fileExt расширение файлов шаблонов
+const fileExt = ".json"
+
+// ErrNotFound шаблон не найден
+var ErrNotFound = errors.New("шаблон теста не найден")
+
+// namePattern допустимое имя шаблона (используется как имя файла)
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// Template именованный шаблон теста: тип теста и тело запроса его запуска
+type Template struct {
+	Name        string          `json:"name"`
+	Type        models.TestType `json:"type"`                  // Тип теста (batch, stream, ...)
+	Description string          `json:"description,omitempty"` // Описание для операторов
+	Request     json.RawMessage `json:"request"`               // Тело запроса запуска теста
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// Store хранилище шаблонов тестов. Каждый шаблон хранится в отдельном файле
+// <name>.json директории хранилища и кешируется в памяти
+type Store struct {
+	directory string
+	logger    *zap.Logger
+	mu        sync.RWMutex
+	templates map[string]*Template
+}
+
+// ValidateName проверяет имя шаблона
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("некорректное имя шаблона %q: допустимы латинские буквы, цифры, '_', '-' и '.', не больше 64 символов", name)
+	}
+	return nil
+}
+
+// NewStore создает хранилище и загружает шаблоны из директории. Отсутствующая
+// директория создается при сохранении первого шаблона; файлы, которые не удалось
+// прочитать, пропускаются с предупреждением в логе
+func NewStore(directory string, logger *zap.Logger) (*Store, error) {
+	s := &Store{
+		directory: directory,
+		logger:    logger,
+		templates: make(map[string]*Template),
+	}
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("ошибка чтения директории шаблонов: %w", err)
+	}
+
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), fileExt)
+		if entry.IsDir() || !ok || ValidateName(name) != nil {
+			continue
+		}
+
+		template, err := s.load(name)
+		if err != nil {
+			logger.Warn("Шаблон теста пропущен", zap.String("name", name), zap.Error(err))
+			continue
+		}
+		s.templates[name] = template
+	}
+
+	logger.Info("Шаблоны тестов загружены",
+		zap.String("directory", directory),
+		zap.Int("count", len(s.templates)))
+
+	return s, nil
+}
+
+// load читает шаблон из файла
+func (s *Store) load(name string) (*Template, error) {
+	data, err := os.ReadFile(s.path(name))
+	if err != nil {
+		return nil, err
+	}
+
+	var template Template
+	if err := json.Unmarshal(data, &template); err != nil {
+		return nil, fmt.Errorf("ошибка разбора шаблона: %w", err)
+	}
+	template.Name = name
+
+	return &template, nil
+}
+
+// path возвращает путь к файлу шаблона
+func (s *Store) path(name string) string {
+	return filepath.Join(s.directory, name+fileExt)
+}
+
+// List возвращает все шаблоны, упорядоченные по имени
+func (s *Store) List() []*Template {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*Template, 0, len(s.templates))
+	for _, template := range s.templates {
+		list = append(list, template)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list
+}
+
+// Get возвращает шаблон по имени
+func (s *Store) Get(name string) (*Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	template, ok := s.templates[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return template, nil
+}
+
+// Put создает или заменяет шаблон и записывает его на диск. Время создания
+// заменяемого шаблона сохраняется. Возвращает сохраненный шаблон и признак создания
+func (s *Store) Put(template Template) (*Template, bool, error) {
+	if err := ValidateName(template.Name); err != nil {
+		return nil, false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	template.CreatedAt, template.UpdatedAt = now, now
+	previous, exists := s.templates[template.Name]
+	if exists {
+		template.CreatedAt = previous.CreatedAt
+	}
+
+	data, err := json.MarshalIndent(&template, "", "  ")
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка сериализации шаблона: %w", err)
+	}
+	if err := s.write(template.Name, data); err != nil {
+		return nil, false, err
+	}
+
+	s.templates[template.Name] = &template
+	return &template, !exists, nil
+}
+
+// write записывает файл шаблона через временный файл, чтобы при сбое на диске
+// не оставался частично записанный шаблон
+func (s *Store) write(name string, data []byte) error {
+	if err := os.MkdirAll(s.directory, 0755); err != nil {
+		return fmt.Errorf("не удалось создать директорию шаблонов: %w", err)
+	}
+
+	tmp := s.path(name) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("ошибка записи шаблона: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(name)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("ошибка записи шаблона: %w", err)
+	}
+
+	return nil
+}
+
+// Delete удаляет шаблон
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.templates[name]; !ok {
+		return ErrNotFound
+	}
+	if err := os.Remove(s.path(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("ошибка удаления шаблона: %w", err)
+	}
+
+	delete(s.templates, name)
+	return nil
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/infodiode/sender/internal/webhook"
+	"go.uber.org/zap"
+)
+
+// AbortPolicy пороги досрочного прерывания теста по ошибкам отправки; нулевой порог
+// не проверяется. Тест прерывается, чтобы не отправлять сообщения в недоступный
+// брокер или сервер до конца заданной длительности
+type AbortPolicy struct {
+	ErrorRate         float64 // Доля сообщений в неудачных отправках с окончания прогрева, %
+	MinAttempts       int64   // Отправленных и неудачных сообщений до проверки доли ошибок
+	ConsecutiveErrors int64   // Ошибок отправки подряд
+}
+
+// enabled проверяет, задан ли хотя бы один порог
+func (p AbortPolicy) enabled() bool {
+	return p.ErrorRate > 0 || p.ConsecutiveErrors > 0
+}
+
+// SetAbortPolicy изменяет пороги прерывания тестов; применяются к тестам, запущенным после изменения
+func (m *Manager) SetAbortPolicy(policy AbortPolicy) {
+	m.abortPolicy.Store(&policy)
+}
+
+// checkAbort проверяет пороги прерывания после ошибки отправки
+func (m *Manager) checkAbort(testCtx *TestContext, consecutive int64) {
+	policy := testCtx.abortPolicy
+	if !policy.enabled() || testCtx.aborted.Load() != nil {
+		return
+	}
+
+	if policy.ConsecutiveErrors > 0 && consecutive >= policy.ConsecutiveErrors {
+		m.abortTest(testCtx, fmt.Sprintf("%d ошибок отправки подряд (порог %d)",
+			consecutive, policy.ConsecutiveErrors))
+		return
+	}
+
+	if policy.ErrorRate > 0 {
+		// Сообщения, а не вызовы отправки: неудачный пакет из 100 сообщений весит как 100 отправленных
+		failed := testCtx.failed.Load()
+		attempts := atomic.LoadInt64(&testCtx.Stats.MessagesSent) + failed
+		if attempts == 0 || attempts < policy.MinAttempts {
+			return
+		}
+		if rate := float64(failed) * 100 / float64(attempts); rate >= policy.ErrorRate {
+			m.abortTest(testCtx, fmt.Sprintf("доля ошибок отправки %.1f%% (%d из %d сообщений) достигла порога %.1f%%",
+				rate, failed, attempts, policy.ErrorRate))
+		}
+	}
+}
+
+// abortTest прерывает тест с указанием причины; итоговый статус - aborted
+func (m *Manager) abortTest(testCtx *TestContext, reason string) {
+	reason = "тест прерван: " + reason
+	if !testCtx.aborted.CompareAndSwap(nil, &reason) {
+		return
+	}
+
+	m.logger.Warn("Тест прерван по порогу ошибок отправки",
+		zap.String("test_id", testCtx.ID),
+		zap.String("reason", reason))
+
+	testCtx.halt()
+	m.notify(testCtx, webhook.EventAssertionBreach, reason)
+}
+
+// halt прекращает выполнение теста: закрывает канал остановки и отменяет контекст
+func (testCtx *TestContext) halt() {
+	testCtx.haltOnce.Do(func() { close(testCtx.stop) })
+	testCtx.Cancel()
+}
+
+// stopErr возвращает ошибку, с которой завершается тест после закрытия канала остановки:
+// причину прерывания по порогу ошибок или остановку через API
+func (testCtx *TestContext) stopErr() error {
+	if reason := testCtx.abortReason(); reason != "" {
+		return errors.New(reason)
+	}
+	return errStoppedByUser
+}
+
+// abortReason возвращает причину прерывания теста, пустую если тест не прерывался
+func (testCtx *TestContext) abortReason() string {
+	if reason := testCtx.aborted.Load(); reason != nil {
+		return *reason
+	}
+	return ""
+}
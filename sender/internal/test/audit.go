@@ -0,0 +1,49 @@
+package test
+
+import (
+	"github.com/infodiode/sender/internal/broker"
+	"github.com/infodiode/shared/models"
+)
+
+// SetAuditListener подключает прием сводок канала аудита; nil - канал аудита не используется
+func (m *Manager) SetAuditListener(listener *broker.AuditListener) {
+	m.audit = listener
+}
+
+// recordAudit учитывает успешно отправленные сообщения с номерами в дайджесте теста
+func recordAudit(testCtx *TestContext, messages []*models.Message) {
+	for _, message := range messages {
+		if message.Sequence <= 0 {
+			continue
+		}
+		testCtx.auditSent.Add(1)
+		testCtx.auditDigest.Add(message.Sequence)
+	}
+}
+
+// auditResult сравнивает отправленные номера теста с последними сводками recipient;
+// nil, если канал аудита не используется или сводок по тесту не поступало
+func (m *Manager) auditResult(testCtx *TestContext) *models.AuditResult {
+	if m.audit == nil {
+		return nil
+	}
+	report, ok := m.audit.Report(testCtx.ID)
+	if !ok {
+		return nil
+	}
+
+	sent := testCtx.auditSent.Load()
+	result := &models.AuditResult{
+		Sent:        sent,
+		Received:    report.Received,
+		Unique:      report.Unique,
+		Lost:        max(sent-report.Unique, 0),
+		DigestMatch: sent == report.Unique && testCtx.auditDigest.Value() == report.Digest,
+		Instances:   report.Instances,
+		UpdatedAt:   report.UpdatedAt,
+	}
+	if sent > 0 {
+		result.LossPercent = float64(result.Lost) / float64(sent) * 100
+	}
+	return result
+}
@@ -0,0 +1,353 @@
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// captureVersion версия формата файла записи
+const captureVersion = 1
+
+// Виды отправок в записи
+const (
+	CaptureKindMessage = "message" // Одно сообщение с записью набора
+	CaptureKindBatch   = "batch"   // Пакет сообщений с последовательными записями набора
+	CaptureKindLarge   = "large"   // Одно сообщение со всеми записями набора
+)
+
+// CaptureHeader первая строка файла записи
+type CaptureHeader struct {
+	Version   int                 `json:"version"`
+	TestID    string              `json:"test_id"`
+	TestType  models.TestType     `json:"test_type"`
+	Protocol  models.TestProtocol `json:"protocol"`
+	StartedAt time.Time           `json:"started_at"`
+
+	DataSource *models.DataSource `json:"data_source,omitempty"` // Тестовые данные, выбранные в запросе теста
+}
+
+// CaptureEvent одна отправка теста. Вместо содержимого сообщений хранится ссылка
+// на записи набора тестовых данных, из которых они сформированы
+type CaptureEvent struct {
+	OffsetUs  int64               `json:"offset_us"`           // Смещение начала отправки от начала записи (мкс)
+	Protocol  models.TestProtocol `json:"protocol"`            // Протокол отправки
+	Kind      string              `json:"kind"`                // Вид отправки (message, batch, large)
+	DataSet   string              `json:"data_set"`            // Набор данных (small, medium, large; inline - записи из запроса)
+	DataSize  int                 `json:"data_size,omitempty"` // Размер набора large (MB)
+	DataFile  string              `json:"data_file,omitempty"` // Файл набора, выбранный в запросе теста
+	DataIndex int                 `json:"data_index"`          // Индекс первой записи в наборе
+	Messages  int                 `json:"messages"`            // Сообщений в отправке
+	Bytes     int64               `json:"bytes"`               // Байт полезной нагрузки
+}
+
+// Capture содержимое файла записи; события упорядочены по смещению
+type Capture struct {
+	Header CaptureHeader
+	Events []CaptureEvent
+}
+
+// Duration возвращает длительность записанного профиля
+func (c *Capture) Duration() time.Duration {
+	if len(c.Events) == 0 {
+		return 0
+	}
+	return time.Duration(c.Events[len(c.Events)-1].OffsetUs) * time.Microsecond
+}
+
+// CaptureStatus состояние записи трафика
+type CaptureStatus struct {
+	Active    bool       `json:"active"`
+	File      string     `json:"file"`
+	TestID    string     `json:"test_id,omitempty"` // Пусто, пока запись ожидает запуска теста
+	Events    int64      `json:"events"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// dataRef набор тестовых данных, из которого формируются сообщения теста
+type dataRef struct {
+	set  string
+	size int
+	file string // Файл набора, выбранный в запросе (пусто - по set и size)
+}
+
+// captureRecorder записывает отправки одного теста в файл.
+// Методы nil *captureRecorder ничего не делают
+type captureRecorder struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	buf     *bufio.Writer
+	encoder *json.Encoder
+	start   time.Time
+	testID  string
+	events  int64
+	closed  bool
+	err     error
+}
+
+// newCaptureRecorder проверяет путь записи; файл создается при подключении к тесту
+func newCaptureRecorder(path string) (*captureRecorder, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("файл записи уже существует: %s", filepath.Base(path))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию записей: %w", err)
+	}
+	return &captureRecorder{path: path}, nil
+}
+
+// attach создает файл записи и записывает заголовок теста; смещения отправок
+// отсчитываются от start
+func (r *captureRecorder) attach(testCtx *TestContext, start time.Time) error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла записи: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.file = file
+	r.buf = bufio.NewWriter(file)
+	r.encoder = json.NewEncoder(r.buf)
+	r.start = start
+	r.testID = testCtx.ID
+
+	return r.encoder.Encode(CaptureHeader{
+		Version:   captureVersion,
+		TestID:    testCtx.ID,
+		TestType:  testCtx.Config.Type,
+		Protocol:  testCtx.Config.Protocol,
+		StartedAt: start,
+
+		DataSource: testCtx.Config.DataSource,
+	})
+}
+
+// record добавляет отправку, начатую в момент at
+func (r *captureRecorder) record(at time.Time, event CaptureEvent) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.encoder == nil || r.closed || r.err != nil {
+		return
+	}
+
+	event.OffsetUs = max(at.Sub(r.start).Microseconds(), 0)
+	if err := r.encoder.Encode(event); err != nil {
+		r.err = err
+		return
+	}
+	r.events++
+}
+
+// close сбрасывает буфер и закрывает файл записи
+func (r *captureRecorder) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return r.err
+	}
+	r.closed = true
+
+	if r.file == nil {
+		return nil
+	}
+
+	flushErr := r.buf.Flush()
+	closeErr := r.file.Close()
+	if err := errors.Join(flushErr, closeErr); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// status возвращает состояние записи
+func (r *captureRecorder) status() *CaptureStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := &CaptureStatus{
+		Active: !r.closed,
+		File:   filepath.Base(r.path),
+		TestID: r.testID,
+		Events: r.events,
+	}
+	if !r.start.IsZero() {
+		start := r.start
+		status.StartedAt = &start
+	}
+	if r.err != nil {
+		status.Error = r.err.Error()
+	}
+	return status
+}
+
+// StartCapture начинает запись отправок в файл path: текущего теста, если он
+// выполняется, иначе следующего запущенного. Запись завершается вместе с тестом
+// или вызовом StopCapture
+func (m *Manager) StartCapture(path string) (*CaptureStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.capture != nil {
+		return nil, fmt.Errorf("запись трафика уже выполняется")
+	}
+
+	recorder, err := newCaptureRecorder(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if testCtx := m.lastTest; testCtx != nil && testCtx.Status == models.TestStatusRunning {
+		if err := recorder.attach(testCtx, time.Now()); err != nil {
+			return nil, err
+		}
+		testCtx.capture.Store(recorder)
+	}
+	m.capture = recorder
+
+	m.logger.Info("Запись трафика начата",
+		zap.String("file", path),
+		zap.String("test_id", recorder.testID))
+
+	return recorder.status(), nil
+}
+
+// StopCapture завершает текущую запись трафика
+func (m *Manager) StopCapture() (*CaptureStatus, error) {
+	m.mu.Lock()
+	recorder := m.capture
+	m.capture = nil
+	m.mu.Unlock()
+
+	if recorder == nil {
+		return nil, fmt.Errorf("запись трафика не выполняется")
+	}
+
+	err := recorder.close()
+	status := recorder.status()
+	if status.TestID == "" {
+		// Тест так и не был запущен, пустой файл не создавался
+		return status, err
+	}
+
+	m.logger.Info("Запись трафика завершена",
+		zap.String("file", status.File),
+		zap.String("test_id", status.TestID),
+		zap.Int64("events", status.Events))
+
+	return status, err
+}
+
+// CaptureStatus возвращает состояние текущей записи трафика (nil, если запись не выполняется)
+func (m *Manager) CaptureStatus() *CaptureStatus {
+	m.mu.RLock()
+	recorder := m.capture
+	m.mu.RUnlock()
+
+	if recorder == nil {
+		return nil
+	}
+	return recorder.status()
+}
+
+// attachCapture подключает ожидающую запись к начинающемуся тесту (вызывается под m.mu)
+func (m *Manager) attachCapture(testCtx *TestContext) {
+	if m.capture == nil || m.capture.status().TestID != "" {
+		return
+	}
+
+	if err := m.capture.attach(testCtx, testCtx.StartTime); err != nil {
+		m.logger.Error("Ошибка начала записи трафика", zap.Error(err))
+		m.capture.close()
+		m.capture = nil
+		return
+	}
+	testCtx.capture.Store(m.capture)
+}
+
+// finishCapture завершает запись, подключенную к завершившемуся тесту
+func (m *Manager) finishCapture(testCtx *TestContext) {
+	recorder := testCtx.capture.Load()
+	if recorder == nil {
+		return
+	}
+
+	m.mu.Lock()
+	if m.capture == recorder {
+		m.capture = nil
+	}
+	m.mu.Unlock()
+
+	if err := recorder.close(); err != nil {
+		m.logger.Error("Ошибка записи трафика", zap.Error(err))
+	}
+
+	status := recorder.status()
+	m.logger.Info("Запись трафика завершена вместе с тестом",
+		zap.String("file", status.File),
+		zap.String("test_id", status.TestID),
+		zap.Int64("events", status.Events))
+}
+
+// LoadCapture читает файл записи трафика
+func LoadCapture(path string) (*Capture, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+
+	capture := &Capture{}
+	if err := decoder.Decode(&capture.Header); err != nil {
+		return nil, fmt.Errorf("ошибка чтения заголовка записи: %w", err)
+	}
+	if capture.Header.Version != captureVersion {
+		return nil, fmt.Errorf("неподдерживаемая версия записи: %d", capture.Header.Version)
+	}
+
+	for {
+		var event CaptureEvent
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("ошибка чтения отправки %d: %w", len(capture.Events)+1, err)
+		}
+		if event.Messages <= 0 {
+			return nil, fmt.Errorf("отправка %d не содержит сообщений", len(capture.Events)+1)
+		}
+		capture.Events = append(capture.Events, event)
+	}
+
+	if len(capture.Events) == 0 {
+		return nil, fmt.Errorf("запись не содержит отправок")
+	}
+
+	// Отправки параллельных потоков записываются не строго по времени
+	sort.SliceStable(capture.Events, func(i, j int) bool {
+		return capture.Events[i].OffsetUs < capture.Events[j].OffsetUs
+	})
+
+	return capture, nil
+}
@@ -0,0 +1,172 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/sender/internal/transport"
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// maxChaosEvents количество разрывов, хранимых в результате теста
+const maxChaosEvents = 100
+
+// chaosPollInterval период проверки восстановления соединения после разрыва
+const chaosPollInterval = 20 * time.Millisecond
+
+// chaosRecorder накапливает итоги принудительных разрывов соединений теста
+type chaosRecorder struct {
+	mu          sync.Mutex
+	result      models.ChaosResult
+	reconnectMs float64 // Суммарное время восстановления
+}
+
+// add учитывает разрыв соединения
+func (r *chaosRecorder) add(event models.ChaosEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event.Error != "" {
+		r.result.Failed++
+	} else {
+		r.result.Disconnects++
+		r.result.SendErrors += event.SendErrors
+	}
+	if event.ReconnectMs > 0 {
+		r.result.Reconnected++
+		r.reconnectMs += event.ReconnectMs
+		r.result.AvgReconnectMs = r.reconnectMs / float64(r.result.Reconnected)
+		r.result.MaxReconnectMs = max(r.result.MaxReconnectMs, event.ReconnectMs)
+	}
+	if len(r.result.Events) < maxChaosEvents {
+		r.result.Events = append(r.result.Events, event)
+	}
+}
+
+// snapshot возвращает копию итогов
+func (r *chaosRecorder) snapshot() *models.ChaosResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := r.result
+	result.Events = append([]models.ChaosEvent{}, r.result.Events...)
+	return &result
+}
+
+// ValidateChaos проверяет параметры принудительных разрывов соединений теста
+func ValidateChaos(config *models.TestConfig) error {
+	chaos := config.Chaos
+	if chaos == nil {
+		return nil
+	}
+	if chaos.Interval < 1 {
+		return fmt.Errorf("chaos.interval должен быть не меньше 1 секунды")
+	}
+	if chaos.DowntimeMs < 0 || chaos.DowntimeMs >= chaos.Interval*1000 {
+		return fmt.Errorf("chaos.downtime_ms должен быть в диапазоне 0-%d", chaos.Interval*1000-1)
+	}
+	for _, protocol := range chaos.Protocols {
+		if protocol != models.ProtocolMQTT && protocol != models.ProtocolTCP && protocol != models.ProtocolQUIC {
+			return fmt.Errorf("разрывы соединений поддерживаются только для %s, %s и %s", models.ProtocolMQTT, models.ProtocolTCP, models.ProtocolQUIC)
+		}
+	}
+	return nil
+}
+
+// chaosProtocols возвращает протоколы, соединения которых разрываются: заданные
+// в запросе или протоколы теста (MQTT и TCP для смешанного теста)
+func chaosProtocols(config *models.TestConfig) []models.TestProtocol {
+	if len(config.Chaos.Protocols) > 0 {
+		return config.Chaos.Protocols
+	}
+	if config.Type == models.TestTypeMixed {
+		return []models.TestProtocol{models.ProtocolMQTT, models.ProtocolTCP}
+	}
+	if config.Protocol == "" {
+		return []models.TestProtocol{models.ProtocolMQTT}
+	}
+	return []models.TestProtocol{config.Protocol}
+}
+
+// startChaos запускает периодические разрывы соединений теста, если они заданы;
+// разрывы прекращаются с завершением или остановкой теста
+func (m *Manager) startChaos(testCtx *TestContext) {
+	if testCtx.Config.Chaos == nil {
+		return
+	}
+
+	testCtx.chaos = &chaosRecorder{}
+	interval := time.Duration(testCtx.Config.Chaos.Interval) * time.Second
+	downtime := time.Duration(testCtx.Config.Chaos.DowntimeMs) * time.Millisecond
+	protocols := chaosProtocols(testCtx.Config)
+
+	m.logger.Info("Включены принудительные разрывы соединений",
+		zap.String("test_id", testCtx.ID),
+		zap.Duration("interval", interval),
+		zap.Duration("downtime", downtime))
+
+	go func() {
+		// Разрывы отсчитываются от начала отправки (start_at), а не от запроса
+		select {
+		case <-time.After(time.Until(testCtx.StartTime)):
+		case <-testCtx.stop:
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-testCtx.ctx.Done():
+				return
+			case <-testCtx.stop:
+				return
+			case <-ticker.C:
+				for _, protocol := range protocols {
+					testCtx.chaos.add(m.churn(testCtx, protocol, downtime, interval))
+				}
+			}
+		}
+	}()
+}
+
+// churn разрывает соединение протокола и ожидает его восстановления не дольше wait
+func (m *Manager) churn(testCtx *TestContext, protocol models.TestProtocol, downtime, wait time.Duration) models.ChaosEvent {
+	event := models.ChaosEvent{Time: time.Now(), Protocol: protocol}
+
+	t, err := m.transport(testCtx, protocol)
+	if err != nil {
+		event.Error = err.Error()
+		return event
+	}
+	churner, ok := t.(transport.Churner)
+	if !ok {
+		event.Error = fmt.Sprintf("транспорт %s не поддерживает разрыв соединения", protocol)
+		return event
+	}
+
+	errorsBefore := atomic.LoadInt64(&testCtx.Stats.Errors)
+	if err := churner.Churn(downtime); err != nil {
+		event.Error = err.Error()
+		return event
+	}
+
+	// TCP переподключается при следующей отправке, MQTT - сразу после downtime
+	deadline := event.Time.Add(wait)
+	for !t.Connected() && time.Now().Before(deadline) {
+		select {
+		case <-testCtx.ctx.Done():
+			deadline = time.Time{}
+		case <-time.After(chaosPollInterval):
+		}
+	}
+	if t.Connected() {
+		event.ReconnectMs = float64(time.Since(event.Time).Microseconds()) / 1000
+	}
+	event.SendErrors = atomic.LoadInt64(&testCtx.Stats.Errors) - errorsBefore
+	return event
+}
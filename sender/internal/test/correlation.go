@@ -0,0 +1,87 @@
+package test
+
+import (
+	"time"
+
+	"github.com/infodiode/shared/correlation"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
+	"go.uber.org/zap"
+)
+
+// SetCorrelationDirectory задает директорию журналов корреляции отправленных сообщений;
+// пусто - журналы не ведутся
+func (m *Manager) SetCorrelationDirectory(dir string) {
+	m.correlationDir = dir
+}
+
+// openCorrelation открывает журнал отправленных сообщений начинающегося теста.
+// Ошибка открытия не мешает тесту: журнал не ведется
+func (m *Manager) openCorrelation(testCtx *TestContext) {
+	if m.correlationDir == "" {
+		return
+	}
+
+	writer, err := correlation.NewWriter(correlation.Config{
+		Directory: m.correlationDir,
+		Prefix:    correlation.PrefixSent + "-" + testCtx.ID,
+	})
+	if err != nil {
+		m.logger.Error("Ошибка открытия журнала корреляции",
+			zap.String("test_id", testCtx.ID),
+			zap.Error(err))
+		return
+	}
+	testCtx.correlation = writer
+}
+
+// recordCorrelation записывает успешно отправленные сообщения в журнал корреляции теста,
+// включая прогрев: recipient записывает в свой журнал все полученные сообщения
+func recordCorrelation(testCtx *TestContext, messages []*models.Message) {
+	if testCtx.correlation == nil {
+		return
+	}
+
+	for _, message := range messages {
+		sent, err := utils.ParseSendTime(message)
+		if err != nil {
+			sent = time.Now()
+		}
+		testCtx.correlation.Write(correlation.Record{
+			TestID:    message.TestID,
+			MessageID: message.MessageID,
+			Sequence:  message.Sequence,
+			Time:      sent,
+			Checksum:  message.Checksum,
+		})
+	}
+}
+
+// closeCorrelation закрывает журнал корреляции завершившегося теста
+func (m *Manager) closeCorrelation(testCtx *TestContext) {
+	if testCtx.correlation == nil {
+		return
+	}
+
+	if err := testCtx.correlation.Close(); err != nil {
+		m.logger.Error("Ошибка записи журнала корреляции",
+			zap.String("test_id", testCtx.ID),
+			zap.Error(err))
+	}
+
+	stats := testCtx.correlation.Stats()
+	m.logger.Info("Журнал корреляции теста закрыт",
+		zap.String("test_id", testCtx.ID),
+		zap.String("file", stats.CurrentFile),
+		zap.Int64("records", stats.RecordsWritten),
+		zap.Int64("errors", stats.Errors))
+}
+
+// correlationStats возвращает статистику журнала корреляции теста (nil, если не ведется)
+func correlationStats(testCtx *TestContext) *correlation.Stats {
+	if testCtx.correlation == nil {
+		return nil
+	}
+	stats := testCtx.correlation.Stats()
+	return &stats
+}
@@ -0,0 +1,175 @@
+package test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/infodiode/sender/internal/generator"
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// inlineDataSet набор данных в записи трафика для записей, переданных в запросе теста
+const inlineDataSet = "inline"
+
+// maxInlineRecords предельное количество записей данных в запросе теста
+const maxInlineRecords = 10000
+
+// ValidateDataSource проверяет тестовые данные, выбранные в запросе теста: задается
+// набор set (с файлом file или размером size для large) либо записи records
+func (m *Manager) ValidateDataSource(config *models.TestConfig) error {
+	source := config.DataSource
+	if source == nil {
+		return nil
+	}
+
+	if len(source.Records) > 0 {
+		if source.Set != "" || source.File != "" || source.Size != 0 {
+			return fmt.Errorf("data_source.records задается без set, file и size")
+		}
+		if len(source.Records) > maxInlineRecords {
+			return fmt.Errorf("data_source.records: записей больше %d", maxInlineRecords)
+		}
+		for i, record := range source.Records {
+			if record == nil {
+				return fmt.Errorf("data_source.records[%d]: пустая запись", i)
+			}
+		}
+		return nil
+	}
+
+	if source.Set == "" {
+		return fmt.Errorf("data_source: не задан набор данных set или записи records")
+	}
+	if err := generator.ValidateDataset(source.Set, source.File); err != nil {
+		return fmt.Errorf("data_source: %w", err)
+	}
+	if source.Size < 0 {
+		return fmt.Errorf("data_source.size не может быть отрицательным")
+	}
+	if source.Size > 0 && (source.Set != "large" || source.File != "") {
+		return fmt.Errorf("data_source.size задается только для набора large без file")
+	}
+	if source.Set == "large" && source.File == "" && source.Size == 0 && config.Type != models.TestTypeLarge {
+		return fmt.Errorf("data_source.size обязателен для набора large")
+	}
+
+	if source.File != "" {
+		path := filepath.Join(m.generator.DataPath(), source.Set, source.File)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("data_source: файл набора данных не найден: %s/%s", source.Set, source.File)
+		}
+	}
+	return nil
+}
+
+// sourceRef возвращает набор данных теста: выбранный в запросе или набор set размера
+// size по умолчанию для типа теста
+func sourceRef(source *models.DataSource, set string, size int) dataRef {
+	switch {
+	case source == nil:
+		return dataRef{set: set, size: size}
+	case len(source.Records) > 0:
+		return dataRef{set: inlineDataSet}
+	case source.File != "":
+		return dataRef{set: source.Set, file: source.File}
+	case source.Size > 0:
+		return dataRef{set: source.Set, size: source.Size}
+	default:
+		return dataRef{set: source.Set, size: size}
+	}
+}
+
+// datasetHash хеш содержимого файла набора с размером и временем изменения, по
+// которым проверяется, что файл не изменился
+type datasetHash struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+// dataHash возвращает хеш содержимого набора ref (SHA-256, первые 8 байт): файла набора
+// или записей, переданных в запросе. Хеш файла вычисляется один раз и обновляется при
+// изменении размера или времени изменения файла
+func (m *Manager) dataHash(ref dataRef, source *models.DataSource) (string, error) {
+	h := sha256.New()
+	if ref.set == inlineDataSet {
+		for _, record := range source.Records {
+			h.Write(record.AppendJSON(nil))
+			h.Write([]byte{'\n'})
+		}
+		return hex.EncodeToString(h.Sum(nil)[:8]), nil
+	}
+
+	path, err := m.dataPath(ref)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения файла данных %s: %w", path, err)
+	}
+	if cached, ok := m.dataHashes.Load(path); ok {
+		if c := cached.(datasetHash); c.size == info.Size() && c.modTime.Equal(info.ModTime()) {
+			return c.hash, nil
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения файла данных %s: %w", path, err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("ошибка чтения файла данных %s: %w", path, err)
+	}
+
+	hash := hex.EncodeToString(h.Sum(nil)[:8])
+	m.dataHashes.Store(path, datasetHash{size: info.Size(), modTime: info.ModTime(), hash: hash})
+	return hash, nil
+}
+
+// testData возвращает записи набора ref; записи набора inline берутся из source.
+// Файл набора не меньше порога MemoryPolicy.MmapThreshold отображается в память:
+// возвращенный *generator.MappedDataset закрывает вызывающий (closeDatasets)
+func (m *Manager) testData(ref dataRef, source *models.DataSource) (generator.Records, error) {
+	if ref.set == inlineDataSet {
+		if source == nil || len(source.Records) == 0 {
+			return nil, fmt.Errorf("записи набора данных %s не заданы", inlineDataSet)
+		}
+		return generator.RecordSlice(source.Records), nil
+	}
+
+	path, err := m.dataPath(ref)
+	if err != nil {
+		return nil, err
+	}
+	return m.generator.OpenRecords(path, m.memoryPolicy.Load().MmapThreshold)
+}
+
+// closeDatasets закрывает наборы данных, отображенные в память для теста
+func (m *Manager) closeDatasets(testCtx *TestContext) {
+	for _, dataset := range testCtx.mapped {
+		if err := dataset.Close(); err != nil {
+			m.logger.Warn("Ошибка закрытия отображенного набора данных",
+				zap.String("test_id", testCtx.ID),
+				zap.String("file", dataset.Path()),
+				zap.Error(err))
+		}
+	}
+	testCtx.mapped = nil
+}
+
+// String возвращает имя набора данных для сообщений об ошибках
+func (r dataRef) String() string {
+	if r.file != "" {
+		return r.set + "/" + r.file
+	}
+	return r.set
+}
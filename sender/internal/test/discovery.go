@@ -0,0 +1,154 @@
+package test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/sender/internal/generator"
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// RunDiscoveryTest пошагово повышает скорость отправки, пока потери или задержка
+// по данным recipient не превысят пороги, и определяет максимальную устойчивую скорость
+func (m *Manager) RunDiscoveryTest(config *models.TestConfig) (err error) {
+	dc := config.Discovery
+	if dc == nil {
+		return fmt.Errorf("не заданы параметры поиска пропускной способности")
+	}
+	if m.orchestrator == nil {
+		return fmt.Errorf("не задан адрес recipient для канала оркестрации")
+	}
+
+	m.logger.Info("Запуск поиска пропускной способности",
+		zap.String("protocol", string(config.Protocol)),
+		zap.Int("start_rate", dc.StartRate),
+		zap.Int("step_rate", dc.StepRate),
+		zap.Int("max_rate", dc.MaxRate),
+		zap.Float64("max_loss_percent", dc.MaxLossPercent),
+		zap.Float64("max_latency_ms", dc.MaxLatencyMs))
+
+	if err := m.ensureTransport(config.Protocol); err != nil {
+		return err
+	}
+
+	testCtx := m.beginTest(config)
+	defer func() { m.endTest(testCtx, err) }()
+
+	result := &models.DiscoveryResult{}
+	m.mu.Lock()
+	testCtx.discovery = result
+	m.mu.Unlock()
+
+	data, err := m.loadTestData(testCtx, "small", 100)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки данных: %w", err)
+	}
+
+	if err := m.waitStart(testCtx); err != nil {
+		return err
+	}
+
+	// Прогрев на начальной скорости; шаги начинаются после доставки прогревочных сообщений
+	if remaining := time.Until(testCtx.warmupEnd); remaining > 0 {
+		if err := m.streamPhase(testCtx, dc.StartRate, remaining, data); err != nil {
+			return err
+		}
+		select {
+		case <-time.After(time.Duration(dc.SettleTime) * time.Second):
+		case <-testCtx.stop:
+			return testCtx.stopErr()
+		}
+	}
+
+	for rate := dc.StartRate; rate <= dc.MaxRate; rate += dc.StepRate {
+		step, err := m.runDiscoveryStep(testCtx, rate, data)
+		if err != nil {
+			m.setDiscoveryStopReason(result, err.Error())
+			return err
+		}
+
+		m.mu.Lock()
+		result.Steps = append(result.Steps, *step)
+		if step.Passed {
+			result.MaxSustainableRate = rate
+		}
+		m.mu.Unlock()
+
+		m.logger.Info("Шаг поиска пропускной способности завершен",
+			zap.Int("rate", rate),
+			zap.Int64("sent", step.Sent),
+			zap.Int64("received", step.Received),
+			zap.Float64("loss_percent", step.LossPercent),
+			zap.Float64("avg_latency_ms", step.AvgLatencyMs),
+			zap.Bool("passed", step.Passed))
+
+		if !step.Passed {
+			m.setDiscoveryStopReason(result, fmt.Sprintf("превышен порог на скорости %d msg/sec", rate))
+			return nil
+		}
+	}
+
+	m.setDiscoveryStopReason(result, "достигнута максимальная проверяемая скорость")
+	return nil
+}
+
+// runDiscoveryStep выполняет один шаг поиска и оценивает его по данным recipient
+func (m *Manager) runDiscoveryStep(testCtx *TestContext, rate int, data generator.Records) (*models.DiscoveryStep, error) {
+	dc := testCtx.Config.Discovery
+
+	before, err := m.orchestrator.RecipientStats(testCtx.ctx)
+	if err != nil {
+		return nil, err
+	}
+	sentBefore := atomic.LoadInt64(&testCtx.Stats.MessagesSent)
+
+	if err := m.streamPhase(testCtx, rate, time.Duration(dc.StepDuration)*time.Second, data); err != nil {
+		return nil, err
+	}
+	if testCtx.ctx.Err() != nil {
+		return nil, fmt.Errorf("истекло время теста")
+	}
+
+	// Ожидаем доставки сообщений, находящихся в пути
+	select {
+	case <-time.After(time.Duration(dc.SettleTime) * time.Second):
+	case <-testCtx.stop:
+		return nil, testCtx.stopErr()
+	}
+
+	after, err := m.orchestrator.RecipientStats(testCtx.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	step := &models.DiscoveryStep{
+		Rate:     rate,
+		Sent:     atomic.LoadInt64(&testCtx.Stats.MessagesSent) - sentBefore,
+		Received: after.MessagesReceived - before.MessagesReceived,
+	}
+
+	if step.Sent > 0 && step.Received < step.Sent {
+		step.LossPercent = float64(step.Sent-step.Received) / float64(step.Sent) * 100
+	}
+
+	// Средняя задержка шага по разнице накопленных сумм
+	if processed := after.MessagesProcessed - before.MessagesProcessed; processed > 0 {
+		totalAfter := after.AvgLatency * float64(after.MessagesProcessed)
+		totalBefore := before.AvgLatency * float64(before.MessagesProcessed)
+		step.AvgLatencyMs = (totalAfter - totalBefore) / float64(processed)
+	}
+
+	step.Passed = step.Sent > 0 && step.LossPercent <= dc.MaxLossPercent &&
+		(dc.MaxLatencyMs == 0 || step.AvgLatencyMs <= dc.MaxLatencyMs)
+
+	return step, nil
+}
+
+// setDiscoveryStopReason фиксирует причину завершения поиска
+func (m *Manager) setDiscoveryStopReason(result *models.DiscoveryResult, reason string) {
+	m.mu.Lock()
+	result.StopReason = reason
+	m.mu.Unlock()
+}
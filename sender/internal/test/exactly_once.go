@@ -0,0 +1,116 @@
+package test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// exactlyOnceQoS уровень QoS, на котором проверяется доставка ровно один раз
+const exactlyOnceQoS byte = 2
+
+// RunExactlyOnceTest отправляет поток сообщений через MQTT с QoS 2 независимо от mqtt.qos
+// и по отчету recipient проверяет, что каждое сообщение теста получено ровно один раз:
+// без повторов и без пропущенных номеров
+func (m *Manager) RunExactlyOnceTest(config *models.TestConfig) (err error) {
+	ec := config.ExactlyOnce
+	if ec == nil {
+		return fmt.Errorf("не заданы параметры проверки доставки ровно один раз")
+	}
+	if config.Protocol != models.ProtocolMQTT {
+		return fmt.Errorf("проверка доставки ровно один раз поддерживается только для MQTT")
+	}
+	if m.orchestrator == nil {
+		return fmt.Errorf("не задан адрес recipient для канала оркестрации")
+	}
+
+	m.logger.Info("Запуск проверки доставки ровно один раз",
+		zap.Int("messages_per_sec", config.MessagesPerSec),
+		zap.Int("send_duration", ec.SendDuration),
+		zap.Int("settle_time", ec.SettleTime))
+
+	testCtx := m.beginTest(config)
+	defer func() { m.endTest(testCtx, err) }()
+
+	result := &models.ExactlyOnceResult{QoS: exactlyOnceQoS}
+	m.mu.Lock()
+	testCtx.exactly = result
+	m.mu.Unlock()
+
+	// Брокер доставляет подписчику сообщения с QoS не выше QoS подписки: при подписке
+	// recipient с QoS 1 повторы допустимы и проверка не имеет смысла
+	qos, err := m.orchestrator.SubscriptionQoS(testCtx.ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса QoS подписки recipient: %w", err)
+	}
+	if qos != int(exactlyOnceQoS) {
+		if qos < 0 {
+			return fmt.Errorf("recipient не подписан на топик MQTT или не сообщает QoS подписки")
+		}
+		return fmt.Errorf("recipient подписан с QoS %d, для проверки доставки ровно один раз требуется mqtt.qos: %d на recipient", qos, exactlyOnceQoS)
+	}
+
+	data, err := m.loadTestData(testCtx, "small", 100)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки данных: %w", err)
+	}
+
+	if err := m.waitStart(testCtx); err != nil {
+		return err
+	}
+
+	// Сообщения прогрева тоже проверяются: им присвоены номера теста
+	if remaining := time.Until(testCtx.warmupEnd); remaining > 0 {
+		if err := m.streamPhase(testCtx, config.MessagesPerSec, remaining, data); err != nil {
+			return err
+		}
+	}
+
+	if err := m.streamPhase(testCtx, config.MessagesPerSec, time.Duration(ec.SendDuration)*time.Second, data); err != nil {
+		return err
+	}
+
+	// Ожидаем завершения обмена QoS 2 и доставки последних сообщений
+	select {
+	case <-time.After(time.Duration(ec.SettleTime) * time.Second):
+	case <-testCtx.stop:
+		return testCtx.stopErr()
+	}
+
+	report, err := m.orchestrator.SessionReport(testCtx.ctx, testCtx.ID)
+	if err != nil {
+		return err
+	}
+
+	sequence := testCtx.sequence.Load()
+
+	m.mu.Lock()
+	result.Report = report
+	result.Rejected = testCtx.rejected.Load()
+	result.Expected = sequence - result.Rejected
+	result.Duplicates = report.Duplicates
+	result.Missing = max(sequence-report.Unique, 0)
+	result.Passed = result.Rejected == 0 && result.Duplicates == 0 && result.Missing == 0
+	switch {
+	case result.Passed:
+		result.Verdict = fmt.Sprintf("все %d сообщений доставлены ровно один раз", sequence)
+	case result.Rejected > 0:
+		result.Verdict = fmt.Sprintf("%d сообщений отклонено без соединения с брокером, доставка не проверена", result.Rejected)
+	default:
+		result.Verdict = fmt.Sprintf("повторов: %d, не получено: %d из %d сообщений",
+			result.Duplicates, result.Missing, sequence)
+	}
+	m.mu.Unlock()
+
+	m.logger.Info("Проверка доставки ровно один раз завершена",
+		zap.Int64("expected", result.Expected),
+		zap.Int64("rejected", result.Rejected),
+		zap.Int64("unique", report.Unique),
+		zap.Int64("duplicates", result.Duplicates),
+		zap.Int64("missing", result.Missing),
+		zap.Bool("passed", result.Passed))
+
+	return nil
+}
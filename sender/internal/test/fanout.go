@@ -0,0 +1,184 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/infodiode/sender/internal/generator"
+	"github.com/infodiode/sender/internal/transport"
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// fanoutDestination точка назначения теста fan-out со своим транспортом и статистикой
+type fanoutDestination struct {
+	dest      models.Destination
+	transport transport.Transport
+	counters  *protocolCounters
+}
+
+// destinationBreakdown статистика по точкам назначения в порядке запроса; nil для остальных тестов
+type destinationBreakdown []*fanoutDestination
+
+// snapshot возвращает статистику по точкам назначения
+func (b destinationBreakdown) snapshot(elapsed time.Duration) []models.ProtocolStats {
+	if len(b) == 0 {
+		return nil
+	}
+
+	stats := make([]models.ProtocolStats, 0, len(b))
+	for _, d := range b {
+		ps := d.counters.snapshot(elapsed)
+		ps.Protocol = d.dest.Protocol
+		ps.Target = d.dest.Target
+		stats = append(stats, ps)
+	}
+	return stats
+}
+
+// RunFanoutTest отправляет поток сообщений одновременно в несколько точек назначения
+// (TCP серверы и топики MQTT). Каждое сообщение с одним порядковым номером передается
+// во все точки, поэтому каждый получатель проверяет полноту доставки независимо;
+// медленная точка назначения не задерживает отправку в остальные
+func (m *Manager) RunFanoutTest(config *models.TestConfig) (err error) {
+	fc := config.Fanout
+	if fc == nil || len(fc.Destinations) == 0 {
+		return fmt.Errorf("не заданы точки назначения")
+	}
+
+	m.logger.Info("Запуск теста fan-out",
+		zap.Int("destinations", len(fc.Destinations)),
+		zap.Int("messages_per_sec", config.MessagesPerSec),
+		zap.Int("duration", config.Duration))
+
+	destinations, err := m.openDestinations(fc.Destinations)
+	if err != nil {
+		return err
+	}
+	defer m.closeDestinations(destinations)
+
+	testCtx := m.beginTest(config)
+	defer func() { m.endTest(testCtx, err) }()
+
+	m.mu.Lock()
+	testCtx.destinations = destinations
+	m.mu.Unlock()
+
+	if err := m.prepareInvalidPayloads(testCtx); err != nil {
+		return err
+	}
+
+	data, err := m.loadTestData(testCtx, "small", 100)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки данных: %w", err)
+	}
+
+	if err := m.waitStart(testCtx); err != nil {
+		return err
+	}
+
+	return m.fanoutPhase(testCtx, destinations, config.MessagesPerSec, data)
+}
+
+// openDestinations создает и подключает транспорты ко всем точкам назначения
+func (m *Manager) openDestinations(dests []models.Destination) (destinationBreakdown, error) {
+	destinations := make(destinationBreakdown, 0, len(dests))
+	for _, dest := range dests {
+		t, err := m.transports.Open(dest.Protocol, dest.Target)
+		if err == nil {
+			if err = t.Connect(); err != nil {
+				closeTransport(t)
+			}
+		}
+		if err != nil {
+			m.closeDestinations(destinations)
+			return nil, fmt.Errorf("точка назначения %s: %w", dest, err)
+		}
+
+		destinations = append(destinations, &fanoutDestination{
+			dest:      dest,
+			transport: t,
+			counters:  &protocolCounters{threads: 1, latencies: newLatencyHistogram()},
+		})
+	}
+	return destinations, nil
+}
+
+// closeDestinations закрывает соединения точек назначения
+func (m *Manager) closeDestinations(destinations destinationBreakdown) {
+	for _, d := range destinations {
+		if err := closeTransport(d.transport); err != nil {
+			m.logger.Warn("Ошибка закрытия точки назначения",
+				zap.Stringer("destination", d.dest),
+				zap.Error(err))
+		}
+	}
+}
+
+// closeTransport закрывает транспорт, если он держит соединение
+func closeTransport(t transport.Transport) error {
+	if c, ok := t.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// fanoutPhase отправляет сообщения с постоянной скоростью rate во все точки назначения
+// до завершения теста
+func (m *Manager) fanoutPhase(testCtx *TestContext, destinations destinationBreakdown, rate int, data generator.Records) error {
+	pace := newPacer(rate)
+	defer pace.Stop()
+	slots := newSendSlots()
+	defer slots.wait()
+
+	dataIndex := 0
+	for {
+		select {
+		case <-testCtx.ctx.Done():
+			return nil
+		case <-testCtx.stop:
+			return testCtx.stopErr()
+		case now := <-pace.C():
+			n, due := pace.take(now)
+			for i := range n {
+				msg := m.newMessage(testCtx, data.At(dataIndex%data.Len()))
+				dataIndex++
+				msgDue := due.Add(time.Duration(i) * pace.step)
+
+				// Отправляем асинхронно в каждую точку, чтобы они не задерживали друг друга
+				for _, d := range destinations {
+					if !slots.acquire(testCtx) {
+						break
+					}
+					go func(d *fanoutDestination) {
+						defer slots.release()
+						m.recordPacing(testCtx, msgDue, time.Now())
+						m.sendToDestination(testCtx, d, msg)
+					}(d)
+				}
+			}
+		}
+	}
+}
+
+// sendToDestination отправляет сообщение в точку назначения и учитывает результат
+func (m *Manager) sendToDestination(testCtx *TestContext, d *fanoutDestination, message *models.Message) {
+	startSend := time.Now()
+	if err := d.transport.Send(message); err != nil {
+		m.recordError(testCtx, err)
+		if !testCtx.warmingUp() {
+			d.counters.recordError()
+		}
+		return
+	}
+
+	bytes := int64(len(message.Payload))
+	latency := float64(time.Since(startSend).Milliseconds())
+	m.recordSendHop(testCtx, message)
+	m.recordSent(testCtx, 1, bytes)
+	m.updateLatencyStats(testCtx, latency)
+	if !testCtx.warmingUp() {
+		d.counters.recordSent(1, bytes, latency)
+	}
+}
@@ -0,0 +1,253 @@
+package test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
+	"go.uber.org/zap"
+)
+
+// fileReportPollInterval период опроса recipient при ожидании проверки файла
+const fileReportPollInterval = 500 * time.Millisecond
+
+// RunFileTest передает файл фрагментами через транспорт протокола теста. Каждый фрагмент
+// сопровождается SHA256, после всех фрагментов отправляется манифест с размером и хешем файла.
+// path - файл из директории файлов sender; при пустом path передается config.File.Size байт,
+// сгенерированных из seed теста. При заданном канале оркестрации результат проверяется
+// по отчету recipient о сборке файла
+func (m *Manager) RunFileTest(config *models.TestConfig, path string) (err error) {
+	fc := config.File
+	if fc == nil {
+		return fmt.Errorf("не заданы параметры теста передачи файла")
+	}
+	if fc.ChunkSize <= 0 {
+		return fmt.Errorf("некорректный размер фрагмента: %d", fc.ChunkSize)
+	}
+
+	m.logger.Info("Запуск теста передачи файла",
+		zap.String("protocol", string(config.Protocol)),
+		zap.String("name", fc.Name),
+		zap.Int64("size", fc.Size),
+		zap.Int("chunk_size", fc.ChunkSize))
+
+	testCtx := m.beginTest(config)
+	defer func() { m.endTest(testCtx, err) }()
+
+	if err := m.connectTransport(testCtx); err != nil {
+		return err
+	}
+
+	var source io.Reader
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("ошибка открытия файла: %w", err)
+		}
+		defer file.Close()
+		source = file
+	} else {
+		source = rand.New(rand.NewSource(config.Seed))
+	}
+
+	name := fc.Name
+	if name == "" {
+		name = testCtx.ID + ".bin"
+	}
+
+	result := &models.FileResult{}
+	m.mu.Lock()
+	testCtx.file = result
+	m.mu.Unlock()
+
+	if err := m.waitStart(testCtx); err != nil {
+		return err
+	}
+
+	// Хеш файла считается по мере отправки; манифест описывает фактически прочитанные данные
+	hasher := sha256.New()
+	size, err := m.sendFileChunks(testCtx, io.LimitReader(source, fc.Size), hasher, result)
+	if err != nil {
+		return err
+	}
+
+	manifest := models.FileManifest{
+		Name:      name,
+		Size:      size,
+		ChunkSize: fc.ChunkSize,
+		Chunks:    result.ChunksSent,
+		SHA256:    hex.EncodeToString(hasher.Sum(nil)),
+	}
+	m.mu.Lock()
+	result.Manifest = manifest
+	m.mu.Unlock()
+
+	payload, _ := json.Marshal(manifest)
+	m.sendFilePart(testCtx, m.newFileMessage(testCtx, string(payload), &models.FilePart{
+		TransferID: testCtx.ID,
+		Index:      models.FileManifestIndex,
+	}))
+
+	if m.orchestrator == nil {
+		m.mu.Lock()
+		result.Verdict = "файл отправлен, проверка не выполнена: не задан адрес recipient для канала оркестрации"
+		m.mu.Unlock()
+		return nil
+	}
+
+	settle := time.Duration(fc.SettleTime) * time.Second
+	report, err := m.waitFileReport(testCtx, settle)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	result.Report = report
+	switch {
+	case report == nil:
+		result.Verdict = "recipient не получил ни одной части файла"
+	case report.Status == models.FileTransferReceiving && report.Manifest == nil:
+		result.Verdict = fmt.Sprintf("манифест не получен за %s, получено %d из %d фрагментов",
+			settle, report.ChunksReceived, manifest.Chunks)
+	case report.Status == models.FileTransferReceiving:
+		result.Verdict = fmt.Sprintf("файл не собран за %s: получено %d из %d фрагментов",
+			settle, report.ChunksReceived, manifest.Chunks)
+	case report.Status == models.FileTransferFailed:
+		result.Verdict = "файл не совпал с манифестом: " + report.Error
+	case report.SHA256 != manifest.SHA256:
+		result.Verdict = "хеш собранного файла не совпал с хешем отправленного"
+	default:
+		result.Passed = true
+		result.Verdict = fmt.Sprintf("файл %s (%d байт, %d фрагментов) собран и совпал с манифестом",
+			manifest.Name, manifest.Size, manifest.Chunks)
+	}
+	m.mu.Unlock()
+
+	m.logger.Info("Тест передачи файла завершен",
+		zap.String("name", manifest.Name),
+		zap.Int64("size", manifest.Size),
+		zap.Int("chunks", manifest.Chunks),
+		zap.Bool("passed", result.Passed),
+		zap.String("verdict", result.Verdict))
+
+	return nil
+}
+
+// sendFileChunks отправляет данные source фрагментами; скорость ограничивается
+// Config.MessagesPerSec (0 - без ограничения). Возвращает количество отправленных байт
+func (m *Manager) sendFileChunks(testCtx *TestContext, source io.Reader, hasher hash.Hash, result *models.FileResult) (int64, error) {
+	var throttle <-chan time.Time
+	if rate := testCtx.Config.MessagesPerSec; rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(rate))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	buf := make([]byte, testCtx.Config.File.ChunkSize)
+	var offset int64
+	for index := 0; ; index++ {
+		n, readErr := io.ReadFull(source, buf)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return offset, fmt.Errorf("ошибка чтения файла: %w", readErr)
+		}
+		if n == 0 {
+			return offset, nil
+		}
+
+		select {
+		case <-testCtx.ctx.Done():
+			return offset, fmt.Errorf("истек таймаут передачи файла")
+		case <-testCtx.stop:
+			return offset, testCtx.stopErr()
+		default:
+		}
+		if throttle != nil {
+			select {
+			case <-throttle:
+			case <-testCtx.ctx.Done():
+				return offset, fmt.Errorf("истек таймаут передачи файла")
+			case <-testCtx.stop:
+				return offset, testCtx.stopErr()
+			}
+		}
+
+		chunk := buf[:n]
+		hasher.Write(chunk)
+		sum := sha256.Sum256(chunk)
+		m.sendFilePart(testCtx, m.newFileMessage(testCtx, base64.StdEncoding.EncodeToString(chunk), &models.FilePart{
+			TransferID: testCtx.ID,
+			Index:      index,
+			Offset:     offset,
+			SHA256:     hex.EncodeToString(sum[:]),
+		}))
+		offset += int64(n)
+
+		m.mu.Lock()
+		result.ChunksSent++
+		m.mu.Unlock()
+
+		if readErr != nil {
+			return offset, nil
+		}
+	}
+}
+
+// sendFilePart отправляет часть файла и учитывает результат в статистике теста
+func (m *Manager) sendFilePart(testCtx *TestContext, message *models.Message) {
+	startSend := time.Now()
+	if err := m.send(testCtx, testCtx.Config.Protocol, message); err != nil {
+		m.recordError(testCtx, err)
+		return
+	}
+
+	m.recordSent(testCtx, 1, int64(len(message.Payload)))
+	m.updateLatencyStats(testCtx, float64(time.Since(startSend).Milliseconds()))
+}
+
+// newFileMessage формирует сообщение с частью файла
+func (m *Manager) newFileMessage(testCtx *TestContext, payload string, part *models.FilePart) *models.Message {
+	return &models.Message{
+		MessageID: int(m.messageIDGen.Add(1)),
+		TestID:    testCtx.ID,
+		Sequence:  testCtx.sequence.Add(1),
+		SendTime:  testCtx.sendTime(),
+		Timestamp: utils.GetCurrentTime(),
+		Payload:   payload,
+		Checksum:  utils.CalculateChecksumString(payload),
+		Schema:    testCtx.schema,
+		TTL:       int64(testCtx.Config.MessageTTL),
+		Tenant:    testCtx.Config.Tenant,
+		RunLabel:  testCtx.Config.RunLabel,
+		File:      part,
+	}
+}
+
+// waitFileReport опрашивает recipient, пока файл не будет проверен или не истечет settle;
+// возвращает последний полученный отчет (nil, если recipient не получил частей файла)
+func (m *Manager) waitFileReport(testCtx *TestContext, settle time.Duration) (*models.FileTransferReport, error) {
+	deadline := time.Now().Add(settle)
+	for {
+		report, err := m.orchestrator.FileTransfer(testCtx.ctx, testCtx.ID)
+		if err != nil {
+			return nil, err
+		}
+		if (report != nil && report.Status != models.FileTransferReceiving) || !time.Now().Before(deadline) {
+			return report, nil
+		}
+
+		select {
+		case <-time.After(fileReportPollInterval):
+		case <-testCtx.stop:
+			return nil, testCtx.stopErr()
+		}
+	}
+}
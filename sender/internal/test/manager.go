@@ -3,7 +3,12 @@ package test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,87 +16,199 @@ import (
 
 	"github.com/infodiode/sender/internal/broker"
 	"github.com/infodiode/sender/internal/generator"
-	"github.com/infodiode/sender/internal/tcp"
+	"github.com/infodiode/sender/internal/orchestration"
+	"github.com/infodiode/sender/internal/transport"
+	"github.com/infodiode/sender/internal/webhook"
+	"github.com/infodiode/shared/correlation"
 	"github.com/infodiode/shared/models"
 	"github.com/infodiode/shared/utils"
 	"go.uber.org/zap"
 )
 
+// errStoppedByUser возвращается, если тест остановлен через API
+var errStoppedByUser = errors.New("тест остановлен пользователем")
+
+// invalidPoolSize количество заранее сгенерированных искаженных записей на тест
+const invalidPoolSize = 1000
+
 // Manager управляет выполнением тестов
 type Manager struct {
 	logger       *zap.Logger
-	producer     *broker.MQTTProducer
-	tcpClient    *tcp.TCPClient
+	producer     *broker.MQTTProducer // Для тестов, использующих возможности MQTT (сессии, QoS 2, retained)
+	transports   *transport.Registry
 	generator    *generator.DataGenerator
-	currentTest  *TestContext
+	orchestrator *orchestration.Client
+	running      map[string]*TestContext // Выполняющиеся тесты
+	lastTest     *TestContext            // Последний запущенный тест
+	results      map[string]*TestContext
+	resultOrder  []string
 	mu           sync.RWMutex
-	stopChan     chan struct{}
 	messageIDGen atomic.Int64
+	capture      *captureRecorder // Ожидающая или выполняющаяся запись трафика
+	abortPolicy  atomic.Pointer[AbortPolicy]
+	memoryPolicy atomic.Pointer[MemoryPolicy]
+	timeFormat   atomic.Pointer[string] // Формат send_time сообщений тестов
+	sendLatency  utils.LatencyHistogram // Задержка отправки сообщений всех тестов для /metrics
+	window       *utils.WindowCounters  // Посекундные показатели отправки всех тестов для /stats?window
+	audit        *broker.AuditListener  // Сводки recipient канала аудита, nil - канал не используется
+	notifier     *webhook.Notifier      // Уведомления о событиях тестов, nil - отключены
+
+	correlationDir string // Директория журналов корреляции отправленных сообщений, пусто - не ведутся
+
+	dataHashes sync.Map // Хеши содержимого файлов наборов: путь -> datasetHash
 }
 
 // TestContext контекст выполнения теста
 type TestContext struct {
+	ID        string
 	Config    *models.TestConfig
 	Stats     *models.TestStats
 	StartTime time.Time
 	Cancel    context.CancelFunc
+	Status    models.TestStatus
+	Err       string
 	ctx       context.Context
 	wg        sync.WaitGroup
+	stop      chan struct{} // Закрывается при остановке теста через API или прерывании по порогу ошибок
+	stopped   atomic.Bool
+	haltOnce  sync.Once
+	timeline  *timelineRecorder
+	latencies *latencyHistogram
+	pacing    *utils.JitterHistogram   // Отклонение отправки от расписания потоковых тестов
+	jitter    *models.JitterStats      // Джиттер приема по данным recipient, полученный по завершении теста
+	sendHop   *utils.JitterHistogram   // Задержка от send_time до завершения отправки сообщения
+	received  *models.LatencyBreakdown // Задержка доставки и обработки по данным recipient
+	errs      errorBreakdown
+	discovery *models.DiscoveryResult
+	sweep     *models.SweepResult
+	session   *models.SessionResult
+	exactly   *models.ExactlyOnceResult
+	features  *models.MQTTFeaturesResult
+	file      *models.FileResult
+	raw       *models.RawResult
+	protocols protocolBreakdown // Статистика по протоколам (только смешанный тест)
+	data      dataRef           // Набор тестовых данных, из которого формируются сообщения
+
+	destinations destinationBreakdown // Статистика по точкам назначения (только тест fan-out)
+	target       transport.Transport  // Отдельный транспорт к Config.Target, nil - общий транспорт протокола
+
+	mapped []*generator.MappedDataset // Наборы данных, отображенные в память на время теста (закрываются в endTest)
+
+	capture     atomic.Pointer[captureRecorder] // Запись отправок теста, nil если не ведется
+	correlation *correlation.Writer             // Журнал отправленных сообщений, nil если не ведется
+
+	warmupEnd time.Time // Окончание прогрева; до него отправка не учитывается в статистике
+	startLag  *float64  // Опоздание начала отправки относительно start_at (ms), nil - start_at не задан
+
+	sequence atomic.Int64 // Порядковый номер последнего сообщения теста
+	rejected atomic.Int64 // Сообщения, отклоненные без соединения с брокером
+	measured atomic.Int64 // Сообщения пакетных тестов, отправленные после прогрева (в счет total_messages)
+
+	chaos *chaosRecorder // Итоги принудительных разрывов соединений, nil - разрывы не заданы
+
+	duplicates  *models.DuplicateStats // Повторы по данным recipient, полученные по завершении теста
+	mqttUnacked *int64                 // Неподтвержденные брокером сообщения в хранилище MQTT клиента по завершении теста
+
+	auditSent   atomic.Int64         // Отправлено сообщений с номерами, включая прогрев
+	auditDigest utils.SequenceDigest // Дайджест отправленных номеров для сравнения со сводками recipient
+
+	abortPolicy AbortPolicy            // Пороги прерывания на момент запуска теста
+	timeFormat  string                 // Формат send_time на момент запуска теста
+	schema      int                    // Версия схемы сообщений, передающая формат send_time
+	consecutive atomic.Int64           // Ошибок отправки подряд
+	failed      atomic.Int64           // Сообщений в неудачных отправках с окончания прогрева (для доли ошибок)
+	aborted     atomic.Pointer[string] // Причина прерывания по порогу ошибок, nil - не прерывался
+
+	invalid [][]byte // Пул искаженных записей для негативных тестов
+
+	fill       atomic.Pointer[string] // Буфер строк заполнения сообщений (pad_to_size)
+	packetSize atomic.Int64           // Размер сообщений с заполнением; меняется по шагам перебора размеров
+
+	random        *rand.Rand               // Источник случайных чисел теста, инициализированный Config.Seed (одна горутина)
+	generator     *generator.DataGenerator // Генератор теста с собственным seed
+	generatorHash string                   // Хеш параметров генератора на момент запуска
+	dataHash      string                   // Хеш содержимого набора данных теста
 }
 
 // NewManager создает новый менеджер тестов
-func NewManager(logger *zap.Logger, producer *broker.MQTTProducer, tcpClient *tcp.TCPClient, generator *generator.DataGenerator) *Manager {
-	return &Manager{
-		logger:    logger,
-		producer:  producer,
-		tcpClient: tcpClient,
-		generator: generator,
+func NewManager(
+	logger *zap.Logger,
+	producer *broker.MQTTProducer,
+	transports *transport.Registry,
+	generator *generator.DataGenerator,
+	orchestrator *orchestration.Client,
+) *Manager {
+	m := &Manager{
+		logger:       logger,
+		producer:     producer,
+		transports:   transports,
+		generator:    generator,
+		orchestrator: orchestrator,
+		running:      make(map[string]*TestContext),
+		results:      make(map[string]*TestContext),
+		window:       utils.NewWindowCounters(),
+	}
+	m.abortPolicy.Store(&AbortPolicy{})
+	m.memoryPolicy.Store(&MemoryPolicy{})
+	m.SetTimeFormat("")
+
+	return m
+}
+
+// lastTestID последний выданный идентификатор теста
+var lastTestID atomic.Int64
+
+// testIDSuffix суффикс идентификаторов тестов sender в кластере
+var testIDSuffix string
+
+// SetTestIDSuffix задает суффикс идентификаторов тестов, чтобы тесты sender кластера,
+// запущенные одновременно, различались у recipient; вызывается до запуска тестов
+func SetTestIDSuffix(suffix string) {
+	testIDSuffix = suffix
+}
+
+// NewTestID генерирует идентификатор для нового теста (время запуска в миллисекундах);
+// тестам, запущенным в одну миллисекунду, выдаются следующие по порядку значения
+func NewTestID() string {
+	for {
+		last := lastTestID.Load()
+		id := max(time.Now().UnixMilli(), last+1)
+		if lastTestID.CompareAndSwap(last, id) {
+			return strconv.FormatInt(id, 10) + testIDSuffix
+		}
 	}
 }
 
 // RunBatchTest запускает пакетный тест
-func (m *Manager) RunBatchTest(config *models.TestConfig) error {
+func (m *Manager) RunBatchTest(config *models.TestConfig) (err error) {
 	m.logger.Info("Запуск пакетного теста",
 		zap.String("protocol", string(config.Protocol)),
 		zap.Int("threads", config.ThreadCount),
 		zap.Int("packet_size", config.PacketSize),
 		zap.Int("total_messages", config.TotalMessages))
 
-	// Проверяем протокол и подключение
-	if config.Protocol == models.ProtocolTCP {
-		if m.tcpClient == nil {
-			return fmt.Errorf("TCP клиент не инициализирован")
-		}
-		if !m.tcpClient.IsConnected() {
-			if err := m.tcpClient.Connect(); err != nil {
-				return fmt.Errorf("ошибка подключения к TCP серверу: %w", err)
-			}
-		}
-	}
-
 	// Создаем контекст теста
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Duration)*time.Second)
-	defer cancel()
+	testCtx := m.beginTest(config)
+	defer func() { m.endTest(testCtx, err) }()
 
-	testCtx := &TestContext{
-		Config:    config,
-		Stats:     &models.TestStats{StartTime: time.Now()},
-		StartTime: time.Now(),
-		Cancel:    cancel,
-		ctx:       ctx,
+	if err := m.connectTransport(testCtx); err != nil {
+		return err
 	}
 
-	m.mu.Lock()
-	m.currentTest = testCtx
-	m.stopChan = make(chan struct{})
-	m.mu.Unlock()
+	if err := m.prepareInvalidPayloads(testCtx); err != nil {
+		return err
+	}
 
 	// Загружаем тестовые данные
-	data, err := m.generator.GetDataForTest("medium", 1)
+	data, err := m.loadTestData(testCtx, "medium", 1)
 	if err != nil {
 		return fmt.Errorf("ошибка загрузки данных для теста: %w", err)
 	}
 
+	if err := m.waitStart(testCtx); err != nil {
+		return err
+	}
+
 	// Запускаем потоки отправки
 	messagesPerThread := config.TotalMessages / config.ThreadCount
 	remainingMessages := config.TotalMessages % config.ThreadCount
@@ -103,20 +220,17 @@ func (m *Manager) RunBatchTest(config *models.TestConfig) error {
 		}
 
 		testCtx.wg.Add(1)
-		go m.batchWorker(testCtx, i, messages, data)
+		go m.batchWorker(testCtx, i, config.Protocol, messages, data)
 	}
 
 	// Ожидаем завершения
 	testCtx.wg.Wait()
 
-	// Финализируем статистику
-	m.finalizeTestStats(testCtx)
-
 	return nil
 }
 
-// batchWorker обработчик для пакетной отправки
-func (m *Manager) batchWorker(testCtx *TestContext, workerID int, messageCount int, data []*models.Data) {
+// batchWorker обработчик для пакетной отправки через указанный протокол
+func (m *Manager) batchWorker(testCtx *TestContext, workerID int, protocol models.TestProtocol, messageCount int, data generator.Records) {
 	defer testCtx.wg.Done()
 
 	m.logger.Info("Запуск batch worker",
@@ -138,7 +252,7 @@ func (m *Manager) batchWorker(testCtx *TestContext, workerID int, messageCount i
 				zap.Int("worker_id", workerID),
 				zap.Int("sent", sent))
 			return
-		case <-m.stopChan:
+		case <-testCtx.stop:
 			m.logger.Info("Worker остановлен пользователем",
 				zap.Int("worker_id", workerID),
 				zap.Int("sent", sent))
@@ -152,48 +266,54 @@ func (m *Manager) batchWorker(testCtx *TestContext, workerID int, messageCount i
 			currentBatch = messageCount - sent
 		}
 
+		firstIndex := dataIndex % data.Len()
 		messages := make([]*models.Message, 0, currentBatch)
 		for i := 0; i < currentBatch; i++ {
 			// Берем данные циклически
-			payload, _ := json.Marshal(data[dataIndex%len(data)])
+			messages = append(messages, m.newMessage(testCtx, data.At(dataIndex%data.Len())))
 			dataIndex++
-
-			msg := &models.Message{
-				MessageID: int(m.messageIDGen.Add(1)),
-				SendTime:  utils.GetCurrentTime(),
-				Timestamp: data[dataIndex%len(data)].Timestamp,
-				Payload:   string(payload),
-				Checksum:  utils.CalculateChecksumString(string(payload)),
-			}
-			messages = append(messages, msg)
 		}
 
 		// Отправляем пакет в зависимости от протокола
 		startSend := time.Now()
-		var err error
-
-		if testCtx.Config.Protocol == models.ProtocolTCP {
-			err = m.tcpClient.SendBatch(messages)
-		} else {
-			err = m.producer.PublishBatch(messages)
-		}
-
+		testCtx.capture.Load().record(startSend, CaptureEvent{
+			Protocol:  protocol,
+			Kind:      CaptureKindBatch,
+			DataSet:   testCtx.data.set,
+			DataSize:  testCtx.data.size,
+			DataFile:  testCtx.data.file,
+			DataIndex: firstIndex,
+			Messages:  currentBatch,
+			Bytes:     int64(len(messages[0].Payload) * currentBatch),
+		})
+		err := m.sendBatch(testCtx, protocol, messages)
 		if err != nil {
-			atomic.AddInt64(&testCtx.Stats.Errors, 1)
+			m.recordFailed(testCtx, err, int64(currentBatch))
+			if !testCtx.warmingUp() {
+				testCtx.protocols.recordError(protocol)
+			}
 			m.logger.Error("Ошибка отправки пакета",
-				zap.String("protocol", string(testCtx.Config.Protocol)),
+				zap.String("protocol", string(protocol)),
 				zap.Int("worker_id", workerID),
 				zap.Error(err))
 		} else {
-			atomic.AddInt64(&testCtx.Stats.MessagesSent, int64(currentBatch))
-			atomic.AddInt64(&testCtx.Stats.BytesSent, int64(len(messages[0].Payload)*currentBatch))
+			bytes := int64(len(messages[0].Payload) * currentBatch)
+			m.recordSent(testCtx, int64(currentBatch), bytes)
 
 			// Обновляем статистику задержки
-			latency := time.Since(startSend).Milliseconds()
-			m.updateLatencyStats(testCtx, float64(latency))
+			latency := float64(time.Since(startSend).Milliseconds())
+			m.updateLatencyStats(testCtx, latency)
+			if !testCtx.warmingUp() {
+				testCtx.protocols.recordSent(protocol, int64(currentBatch), bytes, latency)
+			}
 		}
 
-		sent += currentBatch
+		// Сообщения прогрева не входят в total_messages: после прогрева отправляется
+		// заданное количество сообщений
+		if !testCtx.warmingUp() {
+			sent += currentBatch
+			testCtx.measured.Add(int64(currentBatch))
+		}
 
 		// Логируем прогресс каждые 1000 сообщений
 		if sent%1000 == 0 {
@@ -210,164 +330,160 @@ func (m *Manager) batchWorker(testCtx *TestContext, workerID int, messageCount i
 }
 
 // RunStreamTest запускает потоковый тест
-func (m *Manager) RunStreamTest(config *models.TestConfig) error {
+func (m *Manager) RunStreamTest(config *models.TestConfig) (err error) {
 	m.logger.Info("Запуск потокового теста",
 		zap.String("protocol", string(config.Protocol)),
 		zap.Int("messages_per_sec", config.MessagesPerSec),
 		zap.Int("duration", config.Duration))
 
-	// Проверяем протокол и подключение
-	if config.Protocol == models.ProtocolTCP {
-		if m.tcpClient == nil {
-			return fmt.Errorf("TCP клиент не инициализирован")
-		}
-		if !m.tcpClient.IsConnected() {
-			if err := m.tcpClient.Connect(); err != nil {
-				return fmt.Errorf("ошибка подключения к TCP серверу: %w", err)
-			}
-		}
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Duration)*time.Second)
-	defer cancel()
+	testCtx := m.beginTest(config)
+	defer func() { m.endTest(testCtx, err) }()
 
-	testCtx := &TestContext{
-		Config:    config,
-		Stats:     &models.TestStats{StartTime: time.Now()},
-		StartTime: time.Now(),
-		Cancel:    cancel,
-		ctx:       ctx,
+	if err := m.connectTransport(testCtx); err != nil {
+		return err
 	}
 
-	m.mu.Lock()
-	m.currentTest = testCtx
-	m.stopChan = make(chan struct{})
-	m.mu.Unlock()
+	if err := m.prepareInvalidPayloads(testCtx); err != nil {
+		return err
+	}
 
 	// Загружаем тестовые данные
-	data, err := m.generator.GetDataForTest("small", 100)
+	data, err := m.loadTestData(testCtx, "small", 100)
 	if err != nil {
 		return fmt.Errorf("ошибка загрузки данных: %w", err)
 	}
 
-	// Рассчитываем интервал между сообщениями
-	interval := time.Second / time.Duration(config.MessagesPerSec)
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	if err := m.waitStart(testCtx); err != nil {
+		return err
+	}
+
+	return m.streamPhase(testCtx, config.MessagesPerSec, 0, data)
+}
+
+// streamPhase отправляет сообщения с постоянной скоростью rate в течение duration
+// (до завершения теста, если duration равен нулю)
+func (m *Manager) streamPhase(testCtx *TestContext, rate int, duration time.Duration, data generator.Records) error {
+	pace := newPacer(rate)
+	defer pace.Stop()
+	slots := newSendSlots()
+	defer slots.wait()
+
+	var phaseEnd <-chan time.Time
+	if duration > 0 {
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+		phaseEnd = timer.C
+	}
 
 	dataIndex := 0
 	for {
 		select {
+		case <-phaseEnd:
+			return nil
 		case <-testCtx.ctx.Done():
-			m.finalizeTestStats(testCtx)
 			return nil
-		case <-m.stopChan:
-			m.finalizeTestStats(testCtx)
-			return fmt.Errorf("тест остановлен пользователем")
-		case <-ticker.C:
-			// Отправляем одно сообщение
-			payload, _ := json.Marshal(data[dataIndex%len(data)])
-			dataIndex++
-
-			msg := &models.Message{
-				MessageID: int(m.messageIDGen.Add(1)),
-				SendTime:  utils.GetCurrentTime(),
-				Timestamp: data[dataIndex%len(data)].Timestamp,
-				Payload:   string(payload),
-				Checksum:  utils.CalculateChecksumString(string(payload)),
-			}
-
-			// Отправляем асинхронно чтобы не блокировать ticker
-			go func(message *models.Message) {
-				startSend := time.Now()
-				var err error
-
-				if testCtx.Config.Protocol == models.ProtocolTCP {
-					err = m.tcpClient.Send(message)
-				} else {
-					err = m.producer.Publish(message)
+		case <-testCtx.stop:
+			return testCtx.stopErr()
+		case now := <-pace.C():
+			// Отправляем сообщения, накопленные к этому тику
+			n, due := pace.take(now)
+			for i := range n {
+				if !slots.acquire(testCtx) {
+					break
 				}
 
-				if err != nil {
-					atomic.AddInt64(&testCtx.Stats.Errors, 1)
-				} else {
-					atomic.AddInt64(&testCtx.Stats.MessagesSent, 1)
-					atomic.AddInt64(&testCtx.Stats.BytesSent, int64(len(message.Payload)))
-
-					latency := time.Since(startSend).Milliseconds()
-					m.updateLatencyStats(testCtx, float64(latency))
-				}
-			}(msg)
+				index := dataIndex % data.Len()
+				msg := m.newMessage(testCtx, data.At(index))
+				dataIndex++
+
+				// Отправляем асинхронно чтобы не блокировать темп отправки
+				go func(message *models.Message, index int, due time.Time) {
+					defer slots.release()
+
+					startSend := time.Now()
+					m.recordPacing(testCtx, due, startSend)
+					testCtx.capture.Load().record(startSend, CaptureEvent{
+						Protocol:  testCtx.Config.Protocol,
+						Kind:      CaptureKindMessage,
+						DataSet:   testCtx.data.set,
+						DataSize:  testCtx.data.size,
+						DataFile:  testCtx.data.file,
+						DataIndex: index,
+						Messages:  1,
+						Bytes:     int64(len(message.Payload)),
+					})
+					if err := m.send(testCtx, testCtx.Config.Protocol, message); err != nil {
+						m.recordError(testCtx, err)
+					} else {
+						m.recordSent(testCtx, 1, int64(len(message.Payload)))
+
+						latency := time.Since(startSend).Milliseconds()
+						m.updateLatencyStats(testCtx, float64(latency))
+					}
+				}(msg, index, due.Add(time.Duration(i)*pace.step))
+			}
 		}
 	}
 }
 
 // RunLargeTest запускает тест с большими пакетами
-func (m *Manager) RunLargeTest(config *models.TestConfig) error {
+func (m *Manager) RunLargeTest(config *models.TestConfig) (err error) {
 	m.logger.Info("Запуск теста с большими пакетами",
 		zap.String("protocol", string(config.Protocol)),
 		zap.Int("threads", config.ThreadCount),
 		zap.Int("packet_size", config.PacketSize))
 
-	// Проверяем протокол и подключение
-	if config.Protocol == models.ProtocolTCP {
-		if m.tcpClient == nil {
-			return fmt.Errorf("TCP клиент не инициализирован")
-		}
-		if !m.tcpClient.IsConnected() {
-			if err := m.tcpClient.Connect(); err != nil {
-				return fmt.Errorf("ошибка подключения к TCP серверу: %w", err)
-			}
-		}
-	}
+	testCtx := m.beginTest(config)
+	defer func() { m.endTest(testCtx, err) }()
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Duration)*time.Second)
-	defer cancel()
+	if err := m.connectTransport(testCtx); err != nil {
+		return err
+	}
 
-	testCtx := &TestContext{
-		Config:    config,
-		Stats:     &models.TestStats{StartTime: time.Now()},
-		StartTime: time.Now(),
-		Cancel:    cancel,
-		ctx:       ctx,
+	// Payload сериализуется один раз и используется всеми потоками только для чтения
+	var payload *largePayload
+	if config.Memory != nil && config.Memory.Streaming {
+		payload, err = m.streamLargePayload(testCtx, largeDataSize(config))
+	} else {
+		payload, err = m.loadLargePayload(testCtx, largeDataSize(config))
+	}
+	if err != nil {
+		return err
 	}
 
-	m.mu.Lock()
-	m.currentTest = testCtx
-	m.stopChan = make(chan struct{})
-	m.mu.Unlock()
+	m.logger.Info("Подготовлен большой пакет",
+		zap.Int("records", payload.records),
+		zap.Int("size", len(payload.data)))
 
-	// Определяем размер файла в MB
-	sizeMB := config.PacketSize / (1024 * 1024)
-	if sizeMB < 5 {
-		sizeMB = 5
+	// В потоковом режиме число одновременных отправок ограничено по памяти (tests.memory)
+	var slots chan struct{}
+	if config.Memory != nil && config.Memory.Streaming {
+		slots = make(chan struct{}, config.Memory.InFlight)
 	}
 
-	// Загружаем большой файл данных
-	data, err := m.generator.GetDataForTest("large", sizeMB)
-	if err != nil {
-		return fmt.Errorf("ошибка загрузки больших данных: %w", err)
+	if err := m.waitStart(testCtx); err != nil {
+		return err
 	}
 
 	// Запускаем потоки
 	for i := 0; i < config.ThreadCount; i++ {
 		testCtx.wg.Add(1)
-		go m.largePacketWorker(testCtx, i, data)
+		go m.largePacketWorker(testCtx, i, payload, slots)
 	}
 
 	testCtx.wg.Wait()
-	m.finalizeTestStats(testCtx)
 
 	return nil
 }
 
-// largePacketWorker обработчик для отправки больших пакетов
-func (m *Manager) largePacketWorker(testCtx *TestContext, workerID int, data []*models.Data) {
+// largePacketWorker обработчик для отправки больших пакетов; slots ограничивает
+// одновременные отправки всех потоков (nil - без ограничения)
+func (m *Manager) largePacketWorker(testCtx *TestContext, workerID int, payload *largePayload, slots chan struct{}) {
 	defer testCtx.wg.Done()
 
 	m.logger.Info("Запуск large packet worker",
 		zap.Int("worker_id", workerID),
-		zap.Int("data_size", len(data)))
+		zap.Int("payload_size", len(payload.data)))
 
 	sent := 0
 	for {
@@ -377,7 +493,7 @@ func (m *Manager) largePacketWorker(testCtx *TestContext, workerID int, data []*
 				zap.Int("worker_id", workerID),
 				zap.Int("sent", sent))
 			return
-		case <-m.stopChan:
+		case <-testCtx.stop:
 			m.logger.Info("Large worker остановлен пользователем",
 				zap.Int("worker_id", workerID),
 				zap.Int("sent", sent))
@@ -385,75 +501,142 @@ func (m *Manager) largePacketWorker(testCtx *TestContext, workerID int, data []*
 		default:
 		}
 
-		// Создаем большое сообщение из всех данных
-		payload, _ := json.Marshal(data)
-
-		msg := &models.Message{
-			MessageID: int(m.messageIDGen.Add(1)),
-			SendTime:  utils.GetCurrentTime(),
-			Timestamp: utils.GetCurrentTime(),
-			Payload:   string(payload),
-			Checksum:  utils.CalculateChecksumString(string(payload)),
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-testCtx.ctx.Done():
+				continue
+			case <-testCtx.stop:
+				continue
+			}
 		}
 
-		startSend := time.Now()
-		var err error
-
-		if testCtx.Config.Protocol == models.ProtocolTCP {
-			err = m.tcpClient.Send(msg)
-		} else {
-			err = m.producer.Publish(msg)
-		}
+		// Создаем большое сообщение из всех данных
+		msg := m.newLargeMessage(testCtx, payload)
 
-		if err != nil {
-			atomic.AddInt64(&testCtx.Stats.Errors, 1)
+		startSend := time.Now()
+		testCtx.capture.Load().record(startSend, CaptureEvent{
+			Protocol: testCtx.Config.Protocol,
+			Kind:     CaptureKindLarge,
+			DataSet:  testCtx.data.set,
+			DataSize: testCtx.data.size,
+			DataFile: testCtx.data.file,
+			Messages: 1,
+			Bytes:    int64(len(msg.Payload)),
+		})
+		if err := m.send(testCtx, testCtx.Config.Protocol, msg); err != nil {
+			m.recordError(testCtx, err)
 			m.logger.Error("Ошибка отправки большого пакета",
 				zap.String("protocol", string(testCtx.Config.Protocol)),
 				zap.Int("worker_id", workerID),
-				zap.Int("size", len(payload)),
+				zap.Int("size", len(msg.Payload)),
 				zap.Error(err))
 		} else {
-			atomic.AddInt64(&testCtx.Stats.MessagesSent, 1)
-			atomic.AddInt64(&testCtx.Stats.BytesSent, int64(len(payload)))
+			m.recordSent(testCtx, 1, int64(len(msg.Payload)))
 
 			latency := time.Since(startSend).Milliseconds()
 			m.updateLatencyStats(testCtx, float64(latency))
 			sent++
 		}
+		if slots != nil {
+			<-slots
+		}
 
 		// Задержка между отправками больших пакетов
 		time.Sleep(100 * time.Millisecond)
 	}
 }
 
-// StopCurrentTest останавливает текущий тест
-func (m *Manager) StopCurrentTest() error {
+// StopTest останавливает выполняющийся тест id; при пустом id останавливаются все
+// выполняющиеся тесты. Возвращает идентификаторы остановленных тестов
+func (m *Manager) StopTest(id string) ([]string, error) {
 	m.mu.RLock()
-	if m.currentTest == nil {
-		m.mu.RUnlock()
-		return fmt.Errorf("нет активного теста")
+	var tests []*TestContext
+	if id != "" {
+		if testCtx, ok := m.running[id]; ok {
+			tests = append(tests, testCtx)
+		}
+	} else {
+		for _, testCtx := range m.running {
+			tests = append(tests, testCtx)
+		}
 	}
 	m.mu.RUnlock()
 
-	close(m.stopChan)
-	m.currentTest.Cancel()
+	if len(tests) == 0 {
+		if id != "" {
+			return nil, fmt.Errorf("тест %s не выполняется", id)
+		}
+		return nil, fmt.Errorf("нет активного теста")
+	}
 
-	return nil
+	stopped := make([]string, 0, len(tests))
+	for _, testCtx := range tests {
+		testCtx.stopped.Store(true)
+		testCtx.halt()
+		stopped = append(stopped, testCtx.ID)
+	}
+	sort.Strings(stopped)
+
+	return stopped, nil
+}
+
+// RunningTest сведения о выполняющемся тесте
+type RunningTest struct {
+	ID        string              `json:"id"`
+	Type      models.TestType     `json:"type"`
+	Protocol  models.TestProtocol `json:"protocol,omitempty"`
+	Target    string              `json:"target,omitempty"`
+	Tenant    string              `json:"tenant,omitempty"`
+	RunLabel  string              `json:"run_label,omitempty"`
+	StartTime time.Time           `json:"start_time"`
+	Stats     *models.TestStats   `json:"stats"`
+	Audit     *models.AuditResult `json:"audit,omitempty"`
 }
 
-// GetStats возвращает статистику текущего или последнего теста
+// RunningTests возвращает выполняющиеся тесты в порядке запуска
+func (m *Manager) RunningTests() []RunningTest {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tests := make([]RunningTest, 0, len(m.running))
+	for _, testCtx := range m.running {
+		tests = append(tests, RunningTest{
+			ID:        testCtx.ID,
+			Type:      testCtx.Config.Type,
+			Protocol:  testCtx.Config.Protocol,
+			Target:    testCtx.Config.Target,
+			Tenant:    testCtx.Config.Tenant,
+			RunLabel:  testCtx.Config.RunLabel,
+			StartTime: testCtx.StartTime,
+			Stats:     liveStats(testCtx),
+			Audit:     m.auditResult(testCtx),
+		})
+	}
+	sort.Slice(tests, func(i, j int) bool {
+		return tests[i].StartTime.Before(tests[j].StartTime)
+	})
+	return tests
+}
+
+// GetStats возвращает статистику последнего запущенного теста
 func (m *Manager) GetStats() *models.TestStats {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if m.currentTest == nil || m.currentTest.Stats == nil {
+	if m.lastTest == nil || m.lastTest.Stats == nil {
 		return &models.TestStats{}
 	}
+	return liveStats(m.lastTest)
+}
 
-	stats := *m.currentTest.Stats
+// liveStats возвращает копию статистики теста с длительностью и пропускной
+// способностью на текущий момент, если тест еще выполняется (вызывается под m.mu)
+func liveStats(testCtx *TestContext) *models.TestStats {
+	stats := *testCtx.Stats
 	if stats.EndTime == nil && stats.StartTime.Unix() > 0 {
-		stats.Duration = time.Since(stats.StartTime)
-		if stats.MessagesSent > 0 {
+		stats.Duration = max(time.Since(stats.StartTime), 0)
+		if stats.MessagesSent > 0 && stats.Duration > 0 {
 			stats.AvgThroughput = float64(stats.MessagesSent) / stats.Duration.Seconds()
 		}
 	}
@@ -463,6 +646,11 @@ func (m *Manager) GetStats() *models.TestStats {
 
 // updateLatencyStats обновляет статистику задержек
 func (m *Manager) updateLatencyStats(testCtx *TestContext, latencyMs float64) {
+	if testCtx.warmingUp() {
+		return
+	}
+	testCtx.latencies.observe(latencyMs)
+
 	// Обновляем минимальную задержку
 	for {
 		old := testCtx.Stats.MinLatency
@@ -501,9 +689,9 @@ func (m *Manager) updateLatencyStats(testCtx *TestContext, latencyMs float64) {
 func (m *Manager) finalizeTestStats(testCtx *TestContext) {
 	now := time.Now()
 	testCtx.Stats.EndTime = &now
-	testCtx.Stats.Duration = now.Sub(testCtx.Stats.StartTime)
+	testCtx.Stats.Duration = max(now.Sub(testCtx.Stats.StartTime), 0)
 
-	if testCtx.Stats.MessagesSent > 0 {
+	if testCtx.Stats.MessagesSent > 0 && testCtx.Stats.Duration > 0 {
 		testCtx.Stats.AvgThroughput = float64(testCtx.Stats.MessagesSent) / testCtx.Stats.Duration.Seconds()
 		// Здесь можно добавить расчет перцентилей задержек
 	}
@@ -516,3 +704,756 @@ func (m *Manager) finalizeTestStats(testCtx *TestContext) {
 		zap.Duration("duration", testCtx.Stats.Duration),
 		zap.Float64("throughput", testCtx.Stats.AvgThroughput))
 }
+
+// ensureTransport проверяет готовность транспорта, выбранного для теста
+func (m *Manager) ensureTransport(protocol models.TestProtocol) error {
+	t, err := m.transports.Get(protocol)
+	if err != nil {
+		return err
+	}
+
+	if !t.Connected() {
+		if err := t.Connect(); err != nil {
+			return fmt.Errorf("ошибка подключения транспорта %s: %w", t.Protocol(), err)
+		}
+	}
+
+	return nil
+}
+
+// connectTransport подготавливает транспорт теста: при заданном Config.Target открывает
+// отдельный транспорт к точке назначения (закрывается по завершении теста),
+// иначе проверяет готовность общего транспорта протокола
+func (m *Manager) connectTransport(testCtx *TestContext) error {
+	config := testCtx.Config
+	if config.MQTT != nil {
+		return m.connectMQTT(testCtx)
+	}
+	if config.Target == "" {
+		return m.ensureTransport(config.Protocol)
+	}
+
+	protocol := config.Protocol
+	if protocol == "" {
+		protocol = models.ProtocolMQTT
+	}
+	dest := models.Destination{Protocol: protocol, Target: config.Target}
+
+	t, err := m.transports.Open(protocol, config.Target)
+	if err != nil {
+		return fmt.Errorf("точка назначения %s: %w", dest, err)
+	}
+	if err := t.Connect(); err != nil {
+		closeTransport(t)
+		return fmt.Errorf("точка назначения %s: %w", dest, err)
+	}
+
+	testCtx.target = t
+	return nil
+}
+
+// connectMQTT подготавливает транспорт MQTT теста с параметрами публикации Config.MQTT
+// и топиком Config.Target, если он задан. Для clean_session, отличной от конфигурации,
+// открывается отдельное соединение, которое закрывается по завершении теста
+func (m *Manager) connectMQTT(testCtx *TestContext) error {
+	config := testCtx.Config
+	if m.producer == nil {
+		return fmt.Errorf("транспорт %s не включен", models.ProtocolMQTT)
+	}
+
+	opts := m.producer.PublishOptions()
+	if config.Target != "" {
+		opts.Topic = config.Target
+	}
+	if config.MQTT.QoS != nil {
+		opts.QoS = *config.MQTT.QoS
+	}
+	if config.MQTT.Retained != nil {
+		opts.Retained = *config.MQTT.Retained
+	}
+	if config.MQTT.Aggregate != nil {
+		opts.Aggregate = *config.MQTT.Aggregate
+	}
+
+	clean := config.MQTT.CleanSession
+	if clean == nil || *clean == m.producer.CleanSession() {
+		testCtx.target = transport.NewMQTTOptions(m.producer, opts)
+		return nil
+	}
+
+	producer, err := m.producer.NewSession(testCtx.ID, *clean)
+	if err != nil {
+		return fmt.Errorf("отдельное соединение MQTT теста: %w", err)
+	}
+	testCtx.target = transport.NewMQTTSession(producer, opts)
+	return nil
+}
+
+// transport возвращает транспорт, через который тест отправляет сообщения протокола
+func (m *Manager) transport(testCtx *TestContext, protocol models.TestProtocol) (transport.Transport, error) {
+	if testCtx.target != nil {
+		if protocol == "" {
+			protocol = models.ProtocolMQTT
+		}
+		if protocol == testCtx.target.Protocol() {
+			return testCtx.target, nil
+		}
+	}
+	return m.transports.Get(protocol)
+}
+
+// send отправляет сообщение через транспорт протокола. При ошибке отправка повторяется
+// до send_retries раз с теми же message_id и sequence; повтор помечается номером попытки
+func (m *Manager) send(testCtx *TestContext, protocol models.TestProtocol, message *models.Message) error {
+	err := m.sendOnce(testCtx, protocol, message)
+	for attempt := 1; err != nil && attempt <= testCtx.Config.SendRetries; attempt++ {
+		select {
+		case <-time.After(broker.RetryDelay(attempt)):
+		case <-testCtx.ctx.Done():
+			return err
+		case <-testCtx.stop:
+			return err
+		}
+
+		message.MarkRetry(attempt)
+		m.recordRetry(testCtx, attempt)
+		err = m.sendOnce(testCtx, protocol, message)
+	}
+	return err
+}
+
+// sendOnce выполняет одну попытку отправки сообщения
+func (m *Manager) sendOnce(testCtx *TestContext, protocol models.TestProtocol, message *models.Message) error {
+	var err error
+	if testCtx.Config.Type == models.TestTypeExactlyOnce {
+		// Проверка доставки ровно один раз публикует с QoS 2 вместо mqtt.qos
+		err = m.producer.PublishQoS(message, exactlyOnceQoS)
+	} else {
+		var t transport.Transport
+		if t, err = m.transport(testCtx, protocol); err == nil {
+			err = t.Send(message)
+		}
+	}
+
+	if err == nil {
+		m.recordSendHop(testCtx, message)
+	}
+	return err
+}
+
+// sendBatch отправляет пакет сообщений через транспорт протокола
+func (m *Manager) sendBatch(testCtx *TestContext, protocol models.TestProtocol, messages []*models.Message) error {
+	t, err := m.transport(testCtx, protocol)
+	if err != nil {
+		return err
+	}
+	if err := t.SendBatch(messages); err != nil {
+		return err
+	}
+
+	m.recordSendHop(testCtx, messages...)
+	return nil
+}
+
+// beginTest создает контекст теста и делает его текущим
+func (m *Manager) beginTest(config *models.TestConfig) *TestContext {
+	if config.ID == "" {
+		config.ID = NewTestID()
+	}
+	// Seed фиксируется в конфигурации, чтобы тест можно было повторить
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+
+	// Прогрев добавляется к длительности теста; статистика считается с его окончания.
+	// При заданном start_at время теста отсчитывается от него, а не от запуска
+	warmup := time.Duration(config.WarmupSeconds) * time.Second
+	now := scheduledStart(config)
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(time.Duration(config.Duration)*time.Second+warmup))
+	warmupEnd := now.Add(warmup)
+
+	testCtx := &TestContext{
+		ID:        config.ID,
+		Config:    config,
+		Stats:     &models.TestStats{StartTime: warmupEnd},
+		StartTime: now,
+		Cancel:    cancel,
+		Status:    models.TestStatusRunning,
+		ctx:       ctx,
+		stop:      make(chan struct{}),
+		timeline:  newTimelineRecorder(warmupEnd),
+		latencies: newLatencyHistogram(),
+		pacing:    utils.NewJitterHistogram(),
+		sendHop:   utils.NewJitterHistogram(),
+		warmupEnd: warmupEnd,
+
+		random:        rand.New(rand.NewSource(config.Seed)),
+		generator:     m.generator.WithSeed(config.Seed),
+		generatorHash: m.generator.ConfigHash(),
+
+		abortPolicy: *m.abortPolicy.Load(),
+		timeFormat:  *m.timeFormat.Load(),
+	}
+	testCtx.schema = utils.MessageSchema(testCtx.timeFormat)
+	testCtx.packetSize.Store(int64(config.PacketSize))
+	m.openCorrelation(testCtx)
+
+	m.mu.Lock()
+	m.running[testCtx.ID] = testCtx
+	m.lastTest = testCtx
+	m.storeResult(testCtx)
+	m.attachCapture(testCtx)
+	m.mu.Unlock()
+
+	m.startChaos(testCtx)
+	m.notify(testCtx, webhook.EventTestStarted, "")
+
+	return testCtx
+}
+
+// endTest финализирует тест и фиксирует итоговый статус
+func (m *Manager) endTest(testCtx *TestContext, err error) {
+	testCtx.Cancel()
+	m.finalizeTestStats(testCtx)
+	m.finishCapture(testCtx)
+	m.closeCorrelation(testCtx)
+	m.closeDatasets(testCtx)
+	m.collectReceiveTimeline(testCtx)
+	m.collectReceiveReport(testCtx)
+	m.collectClientStore(testCtx)
+
+	if testCtx.target != nil {
+		if err := closeTransport(testCtx.target); err != nil {
+			m.logger.Warn("Ошибка закрытия транспорта теста",
+				zap.String("test_id", testCtx.ID),
+				zap.Error(err))
+		}
+	}
+
+	m.mu.Lock()
+	delete(m.running, testCtx.ID)
+
+	switch {
+	case testCtx.stopped.Load():
+		testCtx.Status = models.TestStatusStopped
+	case testCtx.aborted.Load() != nil:
+		testCtx.Status = models.TestStatusAborted
+		testCtx.Err = testCtx.abortReason()
+	case err != nil:
+		testCtx.Status = models.TestStatusFailed
+		testCtx.Err = err.Error()
+	default:
+		testCtx.Status = models.TestStatusCompleted
+	}
+	status, reason := testCtx.Status, testCtx.Err
+	m.mu.Unlock()
+
+	m.notify(testCtx, finishEvent(status), reason)
+}
+
+// collectClientStore учитывает сообщения, оставшиеся без подтверждения брокера в
+// хранилище MQTT клиента: тест без ошибок отправки может оставить их в хранилище
+// после таймаута ожидания подтверждения
+func (m *Manager) collectClientStore(testCtx *TestContext) {
+	protocol := testCtx.Config.Protocol
+	if m.producer == nil || (protocol != "" && protocol != models.ProtocolMQTT) {
+		return
+	}
+
+	unacked := m.producer.ClientStats().Store.Outbound
+	if unacked > 0 {
+		m.logger.Warn("В хранилище MQTT клиента остались неподтвержденные сообщения теста",
+			zap.String("test_id", testCtx.ID),
+			zap.Int64("messages", unacked))
+	}
+
+	m.mu.Lock()
+	testCtx.mqttUnacked = &unacked
+	m.mu.Unlock()
+}
+
+// collectReceiveTimeline запрашивает у recipient посекундную динамику приема теста
+// для сопоставления с отправкой; без канала оркестрации ничего не делает
+func (m *Manager) collectReceiveTimeline(testCtx *TestContext) {
+	if m.orchestrator == nil {
+		return
+	}
+
+	points, err := m.orchestrator.ReceiveTimeline(context.Background(), testCtx.ID)
+	if err != nil {
+		m.logger.Warn("Не удалось получить динамику приема recipient",
+			zap.String("test_id", testCtx.ID),
+			zap.Error(err))
+		return
+	}
+	testCtx.timeline.setReceived(points)
+}
+
+// collectReceiveReport запрашивает у recipient задержку по участкам пути и джиттер приема
+// (для теста с отправкой по расписанию); без канала оркестрации ничего не делает
+func (m *Manager) collectReceiveReport(testCtx *TestContext) {
+	if m.orchestrator == nil || testCtx.sendHop.Stats().Samples == 0 {
+		return
+	}
+
+	report, err := m.orchestrator.SessionReport(context.Background(), testCtx.ID)
+	if err != nil {
+		m.logger.Warn("Не удалось получить отчет recipient о приеме",
+			zap.String("test_id", testCtx.ID),
+			zap.Error(err))
+		return
+	}
+
+	m.mu.Lock()
+	if testCtx.pacing.Stats().Samples > 0 {
+		testCtx.jitter = report.Jitter
+	}
+	testCtx.received = report.Latency
+	if report.Duplicates > 0 {
+		testCtx.duplicates = &models.DuplicateStats{
+			Total:  report.Duplicates,
+			Retry:  report.RetryDuplicates,
+			Broker: report.Duplicates - report.RetryDuplicates,
+		}
+	}
+	m.mu.Unlock()
+}
+
+// storeResult сохраняет контекст теста в истории (вызывается под m.mu)
+func (m *Manager) storeResult(testCtx *TestContext) {
+	m.results[testCtx.ID] = testCtx
+	m.resultOrder = append(m.resultOrder, testCtx.ID)
+
+	for len(m.resultOrder) > maxStoredResults {
+		delete(m.results, m.resultOrder[0])
+		m.resultOrder = m.resultOrder[1:]
+	}
+}
+
+// newMessage формирует сообщение с записью; с заданной вероятностью
+// запись подменяется искаженной из пула негативного теста
+func (m *Manager) newMessage(testCtx *TestContext, record *models.Data) *models.Message {
+	sequence := testCtx.sequence.Add(1)
+
+	var payload []byte
+	if invalid, ok := testCtx.invalidPayload(sequence); ok {
+		payload = invalid
+		if !testCtx.warmingUp() {
+			atomic.AddInt64(&testCtx.Stats.InvalidSent, 1)
+		}
+	} else {
+		payload = record.AppendJSON(nil)
+	}
+
+	message := &models.Message{
+		MessageID: int(m.messageIDGen.Add(1)),
+		TestID:    testCtx.ID,
+		Sequence:  sequence,
+		SendTime:  testCtx.sendTime(),
+		Timestamp: record.Timestamp,
+		Payload:   string(payload),
+		Checksum:  utils.CalculateChecksumString(string(payload)),
+		Schema:    testCtx.schema,
+		TTL:       int64(testCtx.Config.MessageTTL),
+		Tenant:    testCtx.Config.Tenant,
+		RunLabel:  testCtx.Config.RunLabel,
+	}
+	if testCtx.Config.PadToSize {
+		m.padMessage(testCtx, message, models.QuotedLen(message.Payload))
+	}
+	return message
+}
+
+// largePayload сериализованный набор данных большого пакета с контрольной суммой.
+// Строка не изменяется после создания и разделяется всеми сообщениями теста
+type largePayload struct {
+	data     string
+	checksum string
+	quoted   int // Длина payload в JSON; вычисляется при pad_to_size
+	records  int // Записей в payload
+}
+
+// loadLargePayload загружает набор данных большого пакета и сериализует его в payload
+func (m *Manager) loadLargePayload(testCtx *TestContext, sizeMB int) (*largePayload, error) {
+	data, err := m.loadTestData(testCtx, "large", sizeMB)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки больших данных: %w", err)
+	}
+
+	payload, err := encodeLargePayload(data, data.Len())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации больших данных: %w", err)
+	}
+	if testCtx.Config.PadToSize {
+		return fitLargePayload(testCtx, payload, func(n int) (*largePayload, error) {
+			return encodeLargePayload(data, n)
+		})
+	}
+	return payload, nil
+}
+
+// encodeLargePayload сериализует первые count записей набора в JSON-массив по одной
+// записи, не держа в памяти промежуточную копию всего массива, и вычисляет контрольную сумму
+func encodeLargePayload(data generator.Records, count int) (*largePayload, error) {
+	var builder strings.Builder
+	builder.WriteByte('[')
+	for i := 0; i < count; i++ {
+		encoded, err := json.Marshal(data.At(i))
+		if err != nil {
+			return nil, err
+		}
+		// Размер массива оценивается по первой записи, чтобы избежать копирований при росте
+		if i == 0 {
+			builder.Grow((len(encoded) + 1) * count)
+		} else {
+			builder.WriteByte(',')
+		}
+		builder.Write(encoded)
+	}
+	builder.WriteByte(']')
+
+	payload := builder.String()
+	return &largePayload{
+		data:     payload,
+		checksum: utils.CalculateChecksumString(payload),
+		records:  count,
+	}, nil
+}
+
+// newLargeMessage формирует сообщение, payload которого содержит все записи набора
+func (m *Manager) newLargeMessage(testCtx *TestContext, payload *largePayload) *models.Message {
+	message := &models.Message{
+		MessageID: int(m.messageIDGen.Add(1)),
+		TestID:    testCtx.ID,
+		Sequence:  testCtx.sequence.Add(1),
+		SendTime:  testCtx.sendTime(),
+		Timestamp: utils.GetCurrentTime(),
+		Payload:   payload.data,
+		Checksum:  payload.checksum,
+		Schema:    testCtx.schema,
+		TTL:       int64(testCtx.Config.MessageTTL),
+		Tenant:    testCtx.Config.Tenant,
+		RunLabel:  testCtx.Config.RunLabel,
+	}
+	if testCtx.Config.PadToSize && payload.quoted > 0 {
+		m.padMessage(testCtx, message, payload.quoted)
+	}
+	return message
+}
+
+// loadTestData загружает набор тестовых данных и запоминает его для записи трафика.
+// set и size - набор по умолчанию для типа теста, если в запросе не выбраны другие данные
+func (m *Manager) loadTestData(testCtx *TestContext, set string, size int) (generator.Records, error) {
+	ref := sourceRef(testCtx.Config.DataSource, set, size)
+	data, err := m.testData(ref, testCtx.Config.DataSource)
+	if err != nil {
+		return nil, err
+	}
+	if mapped, ok := data.(*generator.MappedDataset); ok {
+		testCtx.mapped = append(testCtx.mapped, mapped)
+	}
+	if data.Len() == 0 {
+		return nil, fmt.Errorf("набор данных %s пуст", ref)
+	}
+
+	testCtx.data = ref
+	if testCtx.dataHash, err = m.dataHash(ref, testCtx.Config.DataSource); err != nil {
+		return nil, err
+	}
+
+	// Большой пакет проверяется и сокращается при сериализации (fitLargePayload)
+	if testCtx.Config.Type != models.TestTypeLarge {
+		if err := checkPadSize(testCtx, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// prepareInvalidPayloads генерирует пул искаженных записей, если тест их подмешивает
+func (m *Manager) prepareInvalidPayloads(testCtx *TestContext) error {
+	if testCtx.Config.InvalidPercent <= 0 {
+		return nil
+	}
+
+	kinds, err := generator.ParseCorruptionKinds(testCtx.Config.CorruptionKinds)
+	if err != nil {
+		return err
+	}
+
+	invalid, err := testCtx.generator.GenerateInvalidBatch(invalidPoolSize, kinds)
+	if err != nil {
+		return fmt.Errorf("ошибка генерации искаженных записей: %w", err)
+	}
+	testCtx.invalid = invalid
+
+	m.logger.Info("Подготовлены искаженные записи",
+		zap.Float64("invalid_percent", testCtx.Config.InvalidPercent),
+		zap.Int("count", len(invalid)))
+
+	return nil
+}
+
+// invalidPayload возвращает искаженную запись для сообщения с номером sequence, если
+// оно выбрано с вероятностью InvalidPercent. Выбор и запись пула определяются seed
+// теста и номером сообщения, а не порядком вызовов, поэтому совпадают при повторе
+// теста с тем же seed независимо от распределения сообщений по потокам
+func (testCtx *TestContext) invalidPayload(sequence int64) ([]byte, bool) {
+	if len(testCtx.invalid) == 0 {
+		return nil, false
+	}
+	h := mix64(uint64(testCtx.Config.Seed) + uint64(sequence)*0x9e3779b97f4a7c15)
+	if float64(h>>11)/(1<<53)*100 >= testCtx.Config.InvalidPercent {
+		return nil, false
+	}
+	return testCtx.invalid[mix64(h)%uint64(len(testCtx.invalid))], true
+}
+
+// mix64 перемешивает биты x (финализатор splitmix64)
+func mix64(x uint64) uint64 {
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// warmingUp проверяет, идет ли прогрев теста
+func (testCtx *TestContext) warmingUp() bool {
+	return time.Now().Before(testCtx.warmupEnd)
+}
+
+// recordSent учитывает успешно отправленные сообщения
+func (m *Manager) recordSent(testCtx *TestContext, messages, bytes int64) {
+	testCtx.consecutive.Store(0)
+	m.window.Add(time.Now(), messages, bytes, 0)
+	if testCtx.warmingUp() {
+		return
+	}
+	atomic.AddInt64(&testCtx.Stats.MessagesSent, messages)
+	atomic.AddInt64(&testCtx.Stats.BytesSent, bytes)
+	testCtx.timeline.add(messages, bytes, 0)
+}
+
+// recordRetry учитывает повторную попытку attempt отправки сообщения
+func (m *Manager) recordRetry(testCtx *TestContext, attempt int) {
+	if testCtx.warmingUp() {
+		return
+	}
+	atomic.AddInt64(&testCtx.Stats.SendRetries, 1)
+	if attempt == 1 {
+		atomic.AddInt64(&testCtx.Stats.RetriedMessages, 1)
+	}
+}
+
+// recordPacing учитывает отклонение момента отправки at от срока сообщения по расписанию
+func (m *Manager) recordPacing(testCtx *TestContext, due, at time.Time) {
+	if testCtx.warmingUp() {
+		return
+	}
+	testCtx.pacing.Observe(at.Sub(due))
+}
+
+// recordSendHop учитывает задержку от send_time сообщений до завершения их отправки:
+// подтверждения брокера, записи в сокет или порт. Номера сообщений учитываются для
+// канала аудита и во время прогрева, так как recipient получает все сообщения теста
+func (m *Manager) recordSendHop(testCtx *TestContext, messages ...*models.Message) {
+	recordAudit(testCtx, messages)
+	recordCorrelation(testCtx, messages)
+
+	// Окно показателей /stats отражает текущую отправку, включая прогрев
+	done := time.Now()
+	warmingUp := testCtx.warmingUp()
+	for _, message := range messages {
+		if sent, err := utils.ParseSendTime(message); err == nil {
+			m.window.ObserveLatency(done, done.Sub(sent))
+			if !warmingUp {
+				testCtx.sendHop.Observe(done.Sub(sent))
+				m.sendLatency.Observe(float64(done.Sub(sent).Microseconds()) / 1000)
+			}
+		}
+	}
+}
+
+// WindowStats возвращает показатели отправки всех тестов за последние window,
+// включая прогрев: сообщения, байты payload, ошибки и задержку отправки
+func (m *Manager) WindowStats(window time.Duration) models.WindowStats {
+	return m.window.Stats(window)
+}
+
+// SendLatency возвращает гистограмму задержки отправки сообщений всех тестов
+// (от send_time до завершения отправки) без учета прогрева
+func (m *Manager) SendLatency() *utils.LatencyHistogram {
+	return &m.sendLatency
+}
+
+// recordError учитывает ошибку отправки
+func (m *Manager) recordError(testCtx *TestContext, err error) {
+	m.recordFailed(testCtx, err, 1)
+}
+
+// recordFailed учитывает ошибку отправки пакета из messages сообщений: Stats.Errors
+// считает неудачные отправки, а доля ошибок для прерывания теста - сообщения
+func (m *Manager) recordFailed(testCtx *TestContext, err error, messages int64) {
+	// Отклоненные сообщения учитываются и при прогреве, так как им присвоены номера теста
+	if errors.Is(err, broker.ErrNotConnected) {
+		testCtx.rejected.Add(messages)
+	}
+	// Ошибки подряд учитываются и при прогреве: недоступный брокер прерывает тест сразу
+	consecutive := testCtx.consecutive.Add(1)
+	m.window.Add(time.Now(), 0, 0, 1)
+	if testCtx.warmingUp() {
+		m.checkAbort(testCtx, consecutive)
+		return
+	}
+
+	atomic.AddInt64(&testCtx.Stats.Errors, 1)
+	testCtx.failed.Add(messages)
+	testCtx.errs.add(classifyError(err))
+	testCtx.timeline.add(0, 0, 1)
+	m.checkAbort(testCtx, consecutive)
+}
+
+// GetResult возвращает результат теста по идентификатору
+func (m *Manager) GetResult(id string) (*models.TestResult, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	testCtx, ok := m.results[id]
+	if !ok {
+		return nil, false
+	}
+
+	stats := *testCtx.Stats
+	if stats.EndTime == nil {
+		stats.Duration = max(time.Since(stats.StartTime), 0)
+	}
+
+	var discovery *models.DiscoveryResult
+	if testCtx.discovery != nil {
+		d := *testCtx.discovery
+		d.Steps = append([]models.DiscoveryStep(nil), testCtx.discovery.Steps...)
+		discovery = &d
+	}
+
+	var sweep *models.SweepResult
+	if testCtx.sweep != nil {
+		sweep = &models.SweepResult{Steps: append([]models.SweepStep(nil), testCtx.sweep.Steps...)}
+	}
+
+	var session *models.SessionResult
+	if testCtx.session != nil {
+		s := *testCtx.session
+		s.Interruptions = append([]models.SessionInterruption(nil), testCtx.session.Interruptions...)
+		if s.Report != nil {
+			report := *s.Report
+			report.MissingRanges = append([]models.SequenceRange(nil), s.Report.MissingRanges...)
+			s.Report = &report
+		}
+		session = &s
+	}
+
+	var exactly *models.ExactlyOnceResult
+	if testCtx.exactly != nil {
+		e := *testCtx.exactly
+		if e.Report != nil {
+			report := *e.Report
+			report.MissingRanges = append([]models.SequenceRange(nil), e.Report.MissingRanges...)
+			e.Report = &report
+		}
+		exactly = &e
+	}
+
+	var features *models.MQTTFeaturesResult
+	if testCtx.features != nil {
+		f := *testCtx.features
+		if f.Report != nil {
+			report := *f.Report
+			report.MissingRanges = append([]models.SequenceRange(nil), f.Report.MissingRanges...)
+			f.Report = &report
+		}
+		features = &f
+	}
+
+	var file *models.FileResult
+	if testCtx.file != nil {
+		f := *testCtx.file
+		if f.Report != nil {
+			report := *f.Report
+			if report.Manifest != nil {
+				manifest := *report.Manifest
+				report.Manifest = &manifest
+			}
+			f.Report = &report
+		}
+		file = &f
+	}
+
+	var raw *models.RawResult
+	if testCtx.raw != nil {
+		r := *testCtx.raw
+		r.FramesSent = stats.MessagesSent
+		r.BytesSent = stats.BytesSent
+		r.BandwidthMbps = rawBandwidth(stats.BytesSent, stats.Duration)
+		raw = &r
+	}
+
+	var pacing *models.JitterStats
+	if p := testCtx.pacing.Stats(); p.Samples > 0 {
+		pacing = &p
+	}
+
+	var chaos *models.ChaosResult
+	if testCtx.chaos != nil {
+		chaos = testCtx.chaos.snapshot()
+	}
+
+	return &models.TestResult{
+		ID:               testCtx.ID,
+		Status:           testCtx.Status,
+		Config:           testCtx.Config,
+		Stats:            &stats,
+		Timeline:         testCtx.timeline.snapshot(),
+		LatencyHistogram: testCtx.latencies.snapshot(),
+		ErrorBreakdown:   testCtx.errs.snapshot(),
+		Error:            testCtx.Err,
+		Discovery:        discovery,
+		Sweep:            sweep,
+		Session:          session,
+		ExactlyOnce:      exactly,
+		MQTTFeatures:     features,
+		Protocols:        testCtx.protocols.snapshot(stats.Duration),
+		Destinations:     testCtx.destinations.snapshot(stats.Duration),
+		GeneratorHash:    testCtx.generatorHash,
+		DataHash:         testCtx.dataHash,
+		File:             file,
+		Raw:              raw,
+		Pacing:           pacing,
+		ReceiveJitter:    testCtx.jitter,
+		Duplicates:       testCtx.duplicates,
+		MQTTUnacked:      testCtx.mqttUnacked,
+		Latency:          latencyBreakdown(testCtx.sendHop.HopLatency(), testCtx.received),
+		Audit:            m.auditResult(testCtx),
+		Chaos:            chaos,
+		Correlation:      correlationStats(testCtx),
+		StartLag:         testCtx.startLag,
+	}, true
+}
+
+// latencyBreakdown объединяет задержку отправки sender с задержкой доставки и обработки
+// recipient; средняя задержка передачи - разность средних доставки и отправки
+func latencyBreakdown(send *models.HopLatency, received *models.LatencyBreakdown) *models.LatencyBreakdown {
+	if send == nil && received == nil {
+		return nil
+	}
+
+	breakdown := &models.LatencyBreakdown{Send: send}
+	if received != nil {
+		breakdown.Delivery = received.Delivery
+		breakdown.Processing = received.Processing
+	}
+	if send != nil && breakdown.Delivery != nil {
+		transit := breakdown.Delivery.AvgMs - send.AvgMs
+		breakdown.TransitAvgMs = &transit
+	}
+	return breakdown
+}
@@ -0,0 +1,232 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"strings"
+
+	"github.com/infodiode/sender/internal/generator"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
+)
+
+// ErrMemoryBudget тест с большими пакетами не помещается в ограничение памяти
+var ErrMemoryBudget = errors.New("превышено ограничение памяти")
+
+// sendOverhead память одной отправки относительно payload: сообщение сериализуется
+// в JSON с экранированием payload, транспорт формирует пакет из сериализованного сообщения
+const sendOverhead = 2.5
+
+// MemoryPolicy ограничение памяти тестов с большими пакетами
+type MemoryPolicy struct {
+	Budget int64 // Ограничение в байтах (0 - GOMEMLIMIT, если задан, иначе не проверяется)
+	Stream bool  // При превышении переходить в потоковый режим вместо отказа
+
+	MmapThreshold int64 // Размер файла набора, начиная с которого он отображается в память вместо загрузки, байт (0 - не отображать)
+}
+
+// SetMemoryPolicy изменяет ограничение памяти; применяется к тестам, запущенным после изменения
+func (m *Manager) SetMemoryPolicy(policy MemoryPolicy) {
+	m.memoryPolicy.Store(&policy)
+}
+
+// budget возвращает ограничение памяти в байтах; 0 - ограничение не задано
+func (p MemoryPolicy) budget() int64 {
+	if p.Budget > 0 {
+		return p.Budget
+	}
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		return limit
+	}
+	return 0
+}
+
+// PlanMemory оценивает память теста с большими пакетами до запуска: записи набора,
+// общий payload и одновременные отправки всех потоков. Если оценка превышает
+// ограничение, тест переводится в потоковый режим или отклоняется с ErrMemoryBudget.
+// Оценка сохраняется в config.Memory, в том числе при отказе
+func (m *Manager) PlanMemory(config *models.TestConfig) error {
+	config.Memory = nil
+	if config.Type != models.TestTypeLarge {
+		return nil
+	}
+	policy := *m.memoryPolicy.Load()
+	budget := policy.budget()
+	if budget == 0 {
+		return nil
+	}
+
+	plan, err := m.estimateLarge(config, policy)
+	if err != nil {
+		return err
+	}
+	plan.BudgetBytes = budget
+	config.Memory = plan
+	if plan.EstimatedBytes <= budget {
+		return nil
+	}
+
+	if !policy.Stream {
+		return fmt.Errorf("%w: оценка памяти теста %d MB, ограничение %d MB",
+			ErrMemoryBudget, megabytes(plan.EstimatedBytes), megabytes(budget))
+	}
+
+	// Потоковый режим: записи набора не загружаются, число одновременных
+	// отправок ограничивается остатком памяти после payload
+	plan.Streaming = true
+	plan.Mapped = false
+	plan.RecordsBytes = 0
+	available := budget - plan.PayloadBytes
+	if plan.SendBytes > 0 {
+		plan.InFlight = int(min(int64(plan.Threads), available/plan.SendBytes))
+	}
+	if plan.InFlight < 1 {
+		plan.InFlight = 1
+		plan.EstimatedBytes = plan.PayloadBytes + plan.SendBytes
+		return fmt.Errorf("%w: в потоковом режиме одна отправка требует %d MB, ограничение %d MB",
+			ErrMemoryBudget, megabytes(plan.EstimatedBytes), megabytes(budget))
+	}
+	plan.EstimatedBytes = plan.PayloadBytes + int64(plan.InFlight)*plan.SendBytes
+	return nil
+}
+
+// estimateLarge оценивает память теста с большими пакетами без ограничений; набор,
+// отображаемый в память по порогу policy, занимает только индекс строк
+func (m *Manager) estimateLarge(config *models.TestConfig, policy MemoryPolicy) (*models.MemoryPlan, error) {
+	plan := &models.MemoryPlan{
+		Threads:  max(config.ThreadCount, 1),
+		InFlight: max(config.ThreadCount, 1),
+	}
+
+	ref := sourceRef(config.DataSource, "large", largeDataSize(config))
+	if ref.set == inlineDataSet {
+		// Записи запроса уже в памяти, учитывается только их сериализация
+		for _, record := range config.DataSource.Records {
+			plan.DatasetBytes += int64(len(record.AppendJSON(nil))) + 1
+		}
+	} else {
+		path, err := m.dataPath(ref)
+		if err != nil {
+			return nil, err
+		}
+		footprint, err := m.generator.Footprint(path)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка оценки набора данных %s: %w", ref, err)
+		}
+		plan.DatasetBytes = footprint.Bytes
+		plan.RecordsBytes = footprint.Memory
+		if m.generator.MapsFile(path, policy.MmapThreshold) {
+			plan.Mapped = true
+			plan.RecordsBytes = int64(footprint.Records) * generator.IndexEntrySize
+		}
+	}
+
+	plan.PayloadBytes = plan.DatasetBytes
+	if config.PadToSize && config.PacketSize > 0 {
+		plan.PayloadBytes = min(plan.PayloadBytes, int64(config.PacketSize))
+	}
+	plan.SendBytes = int64(float64(plan.PayloadBytes) * sendOverhead)
+	plan.EstimatedBytes = plan.RecordsBytes + plan.PayloadBytes + int64(plan.InFlight)*plan.SendBytes
+	return plan, nil
+}
+
+// dataPath возвращает путь к файлу набора ref
+func (m *Manager) dataPath(ref dataRef) (string, error) {
+	if ref.file != "" {
+		return m.generator.DatasetFilePath(ref.set, ref.file)
+	}
+	return m.generator.TestDataPath(ref.set, ref.size)
+}
+
+// largeDataSize возвращает размер файла набора large в MB для теста с большими пакетами
+func largeDataSize(config *models.TestConfig) int {
+	return max(config.PacketSize/(1024*1024), 5)
+}
+
+// megabytes переводит байты в MB с округлением вверх
+func megabytes(bytes int64) int64 {
+	return (bytes + 1<<20 - 1) >> 20
+}
+
+// errStreamLimit останавливает чтение набора после нужного числа записей
+var errStreamLimit = errors.New("достигнуто количество записей")
+
+// streamLargePayload сериализует набор данных большого пакета, читая файл построчно:
+// записи не загружаются в память и не сохраняются в кеше генератора
+func (m *Manager) streamLargePayload(testCtx *TestContext, sizeMB int) (*largePayload, error) {
+	ref := sourceRef(testCtx.Config.DataSource, "large", sizeMB)
+	if ref.set == inlineDataSet {
+		// Записи запроса уже в памяти
+		return m.loadLargePayload(testCtx, sizeMB)
+	}
+	path, err := m.dataPath(ref)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки больших данных: %w", err)
+	}
+	testCtx.data = ref
+
+	// При pad_to_size записи сверх packet_size не поместятся в сообщение и не читаются
+	size, limit := testCtx.Config.Memory.PayloadBytes, int64(0)
+	if testCtx.Config.PadToSize {
+		limit = size
+	}
+	encode := func(n int) (*largePayload, error) {
+		payload, err := m.streamEncode(path, n, size, limit)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сериализации больших данных: %w", err)
+		}
+		return payload, nil
+	}
+
+	payload, err := encode(0)
+	if err != nil {
+		return nil, err
+	}
+	if payload.records == 0 {
+		return nil, fmt.Errorf("набор данных %s пуст", ref)
+	}
+	if testCtx.Config.PadToSize {
+		return fitLargePayload(testCtx, payload, encode)
+	}
+	return payload, nil
+}
+
+// streamEncode сериализует первые count записей файла path (0 - все) в JSON-массив,
+// останавливаясь, когда массив превысит limit байт (0 - без ограничения);
+// size - оценка размера массива для выделения буфера
+func (m *Manager) streamEncode(path string, count int, size, limit int64) (*largePayload, error) {
+	var builder strings.Builder
+	builder.Grow(int(size) + 2)
+	builder.WriteByte('[')
+
+	records := 0
+	err := m.generator.StreamDataFromFile(path, func(record *models.Data) error {
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if records > 0 {
+			builder.WriteByte(',')
+		}
+		builder.Write(encoded)
+		records++
+		if (count > 0 && records >= count) || (limit > 0 && int64(builder.Len()) > limit) {
+			return errStreamLimit
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStreamLimit) {
+		return nil, err
+	}
+	builder.WriteByte(']')
+
+	payload := builder.String()
+	return &largePayload{
+		data:     payload,
+		checksum: utils.CalculateChecksumString(payload),
+		records:  records,
+	}, nil
+}
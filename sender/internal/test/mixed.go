@@ -0,0 +1,184 @@
+package test
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// RunMixedTest запускает пакетный тест, в котором часть потоков отправляет через MQTT,
+// а остальные через TCP, чтобы оценить взаимное влияние протоколов на общем канале
+func (m *Manager) RunMixedTest(config *models.TestConfig) (err error) {
+	mc := config.Mixed
+	if mc == nil {
+		return fmt.Errorf("не заданы параметры смешанного теста")
+	}
+
+	if mc.MQTTThreads <= 0 || mc.TCPThreads <= 0 || mc.MQTTThreads+mc.TCPThreads != config.ThreadCount {
+		return fmt.Errorf("смешанный тест требует хотя бы одного потока каждого протокола (mqtt: %d, tcp: %d)",
+			mc.MQTTThreads, mc.TCPThreads)
+	}
+
+	m.logger.Info("Запуск смешанного теста",
+		zap.Int("mqtt_threads", mc.MQTTThreads),
+		zap.Int("tcp_threads", mc.TCPThreads),
+		zap.Int("packet_size", config.PacketSize),
+		zap.Int("total_messages", config.TotalMessages))
+
+	if err := m.ensureTransport(models.ProtocolTCP); err != nil {
+		return err
+	}
+
+	testCtx := m.beginTest(config)
+	defer func() { m.endTest(testCtx, err) }()
+
+	m.mu.Lock()
+	testCtx.protocols = protocolBreakdown{
+		models.ProtocolMQTT: {threads: mc.MQTTThreads, latencies: newLatencyHistogram()},
+		models.ProtocolTCP:  {threads: mc.TCPThreads, latencies: newLatencyHistogram()},
+	}
+	m.mu.Unlock()
+
+	if err := m.prepareInvalidPayloads(testCtx); err != nil {
+		return err
+	}
+
+	data, err := m.loadTestData(testCtx, "medium", 1)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки данных для теста: %w", err)
+	}
+
+	if err := m.waitStart(testCtx); err != nil {
+		return err
+	}
+
+	// Сообщения распределяются между потоками поровну независимо от протокола
+	messagesPerThread := config.TotalMessages / config.ThreadCount
+	remainingMessages := config.TotalMessages % config.ThreadCount
+
+	for i := 0; i < config.ThreadCount; i++ {
+		messages := messagesPerThread
+		if i == 0 {
+			messages += remainingMessages
+		}
+
+		protocol := models.ProtocolMQTT
+		if i >= mc.MQTTThreads {
+			protocol = models.ProtocolTCP
+		}
+
+		testCtx.wg.Add(1)
+		go m.batchWorker(testCtx, i, protocol, messages, data)
+	}
+
+	testCtx.wg.Wait()
+
+	return nil
+}
+
+// SplitThreads делит потоки смешанного теста между MQTT и TCP по доле MQTT в процентах
+func SplitThreads(threads int, mqttPercent float64) (mqttThreads, tcpThreads int) {
+	mqttThreads = int(math.Round(float64(threads) * mqttPercent / 100))
+	return mqttThreads, threads - mqttThreads
+}
+
+// protocolCounters накопленная статистика отправки через один протокол
+type protocolCounters struct {
+	mu         sync.Mutex
+	threads    int
+	sent       int64
+	bytes      int64
+	errors     int64
+	batches    int64
+	latencySum float64
+	minLatency float64
+	maxLatency float64
+	latencies  *latencyHistogram
+}
+
+// protocolBreakdown статистика по протоколам; nil для тестов с одним протоколом
+type protocolBreakdown map[models.TestProtocol]*protocolCounters
+
+// recordSent учитывает успешно отправленный пакет
+func (b protocolBreakdown) recordSent(protocol models.TestProtocol, messages, bytes int64, latencyMs float64) {
+	if c, ok := b[protocol]; ok {
+		c.recordSent(messages, bytes, latencyMs)
+	}
+}
+
+// recordError учитывает ошибку отправки
+func (b protocolBreakdown) recordError(protocol models.TestProtocol) {
+	if c, ok := b[protocol]; ok {
+		c.recordError()
+	}
+}
+
+// snapshot возвращает статистику по протоколам, упорядоченную по имени протокола
+func (b protocolBreakdown) snapshot(elapsed time.Duration) []models.ProtocolStats {
+	if len(b) == 0 {
+		return nil
+	}
+
+	stats := make([]models.ProtocolStats, 0, len(b))
+	for protocol, c := range b {
+		ps := c.snapshot(elapsed)
+		ps.Protocol = protocol
+		stats = append(stats, ps)
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Protocol < stats[j].Protocol })
+	return stats
+}
+
+// recordSent учитывает успешно отправленный пакет
+func (c *protocolCounters) recordSent(messages, bytes int64, latencyMs float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sent += messages
+	c.bytes += bytes
+	c.batches++
+	c.latencySum += latencyMs
+	if c.batches == 1 || latencyMs < c.minLatency {
+		c.minLatency = latencyMs
+	}
+	if latencyMs > c.maxLatency {
+		c.maxLatency = latencyMs
+	}
+	c.latencies.observe(latencyMs)
+}
+
+// recordError учитывает ошибку отправки
+func (c *protocolCounters) recordError() {
+	c.mu.Lock()
+	c.errors++
+	c.mu.Unlock()
+}
+
+// snapshot возвращает накопленную статистику без протокола и точки назначения
+func (c *protocolCounters) snapshot(elapsed time.Duration) models.ProtocolStats {
+	c.mu.Lock()
+	ps := models.ProtocolStats{
+		Threads:      c.threads,
+		MessagesSent: c.sent,
+		BytesSent:    c.bytes,
+		Errors:       c.errors,
+		MinLatency:   c.minLatency,
+		MaxLatency:   c.maxLatency,
+	}
+	if c.batches > 0 {
+		ps.AvgLatency = c.latencySum / float64(c.batches)
+	}
+	c.mu.Unlock()
+
+	ps.LatencyHistogram = c.latencies.snapshot()
+	if elapsed > 0 {
+		ps.AvgThroughput = float64(ps.MessagesSent) / elapsed.Seconds()
+	}
+	return ps
+}
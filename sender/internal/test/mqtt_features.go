@@ -0,0 +1,174 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// willReconnectTimeout максимальное ожидание восстановления соединения после обрыва
+const willReconnectTimeout = 30 * time.Second
+
+// RunMQTTFeaturesTest проверяет прохождение через MQTT прокси диода сохраненных (retained)
+// сообщений и last will: отправляет сообщения с флагом retain, запрашивает у recipient
+// повторную подписку, обрывает соединение producer и по счетчикам recipient проверяет,
+// что каждая особая доставка получена и учтена отдельно от тестового потока
+func (m *Manager) RunMQTTFeaturesTest(config *models.TestConfig) (err error) {
+	fc := config.MQTTFeatures
+	if fc == nil {
+		return fmt.Errorf("не заданы параметры проверки retained и last will")
+	}
+	if config.Protocol != models.ProtocolMQTT {
+		return fmt.Errorf("проверка retained и last will поддерживается только для MQTT")
+	}
+	if m.orchestrator == nil {
+		return fmt.Errorf("не задан адрес recipient для канала оркестрации")
+	}
+	if fc.RetainedMarkers == 0 && !fc.TriggerWill {
+		return fmt.Errorf("не выбрана ни одна проверка: задайте retained_markers или trigger_will")
+	}
+	if fc.TriggerWill && !m.producer.WillConfigured() {
+		return fmt.Errorf("проверка last will требует mqtt.will_topic в конфигурации sender")
+	}
+
+	m.logger.Info("Запуск проверки retained и last will",
+		zap.Int("retained_markers", fc.RetainedMarkers),
+		zap.Bool("trigger_will", fc.TriggerWill),
+		zap.Int("settle_time", fc.SettleTime))
+
+	testCtx := m.beginTest(config)
+	defer func() { m.endTest(testCtx, err) }()
+
+	result := &models.MQTTFeaturesResult{WillTriggered: fc.TriggerWill}
+	m.mu.Lock()
+	testCtx.features = result
+	m.mu.Unlock()
+
+	settle := time.Duration(fc.SettleTime) * time.Second
+
+	before, err := m.orchestrator.MQTTDeliveries(testCtx.ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.waitStart(testCtx); err != nil {
+		return err
+	}
+
+	if fc.RetainedMarkers > 0 {
+		data, err := m.loadTestData(testCtx, "small", 100)
+		if err != nil {
+			return fmt.Errorf("ошибка загрузки данных: %w", err)
+		}
+
+		// Сохраненное сообщение удаляется с брокера, чтобы не доставляться следующим подписчикам
+		defer func() {
+			if err := m.producer.ClearRetained(); err != nil {
+				m.logger.Warn("Ошибка удаления сохраненного сообщения", zap.Error(err))
+			}
+		}()
+
+		for i := 0; i < fc.RetainedMarkers; i++ {
+			msg := m.newMessage(testCtx, data.At(i%data.Len()))
+			startSend := time.Now()
+			if err := m.producer.PublishRetained(msg); err != nil {
+				m.recordError(testCtx, err)
+				continue
+			}
+			m.recordSent(testCtx, 1, int64(len(msg.Payload)))
+			m.updateLatencyStats(testCtx, float64(time.Since(startSend).Milliseconds()))
+
+			m.mu.Lock()
+			result.RetainedSent++
+			m.mu.Unlock()
+		}
+
+		if err := m.waitSettle(testCtx, settle); err != nil {
+			return err
+		}
+
+		// Брокер recipient доставляет последнее сохраненное сообщение новой подписке
+		if err := m.orchestrator.Resubscribe(testCtx.ctx); err != nil {
+			return err
+		}
+	}
+
+	if fc.TriggerWill {
+		if err := m.producer.Abort(); err != nil {
+			return err
+		}
+		if err := m.producer.Reconnect(willReconnectTimeout); err != nil {
+			return err
+		}
+	}
+
+	if err := m.waitSettle(testCtx, settle); err != nil {
+		return err
+	}
+
+	after, err := m.orchestrator.MQTTDeliveries(testCtx.ctx)
+	if err != nil {
+		return err
+	}
+
+	var report *models.SessionReport
+	if fc.RetainedMarkers > 0 {
+		if report, err = m.orchestrator.SessionReport(testCtx.ctx, testCtx.ID); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	result.Report = report
+	result.RetainedReceived = max(after.Retained-before.Retained, 0)
+	result.WillReceived = max(after.Will-before.Will, 0)
+	result.DuplicateFlagged = max(after.Duplicates-before.Duplicates, 0)
+
+	var problems []string
+	if fc.RetainedMarkers > 0 {
+		if report.Unique < result.RetainedSent {
+			problems = append(problems, fmt.Sprintf("получено %d из %d сообщений с флагом retain",
+				report.Unique, result.RetainedSent))
+		}
+		switch {
+		case result.RetainedReceived == 0:
+			problems = append(problems, "сохраненное сообщение не доставлено при повторной подписке")
+		case result.RetainedReceived > 1:
+			problems = append(problems, fmt.Sprintf("при повторной подписке доставлено %d сохраненных сообщений вместо одного",
+				result.RetainedReceived))
+		}
+	}
+	if fc.TriggerWill && result.WillReceived == 0 {
+		problems = append(problems, "last will не получен после обрыва соединения")
+	}
+
+	result.Passed = len(problems) == 0
+	if result.Passed {
+		result.Verdict = "retained и last will доставлены в соответствии с MQTT"
+	} else {
+		result.Verdict = strings.Join(problems, "; ")
+	}
+	m.mu.Unlock()
+
+	m.logger.Info("Проверка retained и last will завершена",
+		zap.Int64("retained_sent", result.RetainedSent),
+		zap.Int64("retained_received", result.RetainedReceived),
+		zap.Int64("will_received", result.WillReceived),
+		zap.Int64("duplicate_flagged", result.DuplicateFlagged),
+		zap.Bool("passed", result.Passed))
+
+	return nil
+}
+
+// waitSettle ожидает доставки отправленного; прерывается остановкой теста
+func (m *Manager) waitSettle(testCtx *TestContext, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-testCtx.stop:
+		return testCtx.stopErr()
+	}
+}
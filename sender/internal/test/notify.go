@@ -0,0 +1,34 @@
+package test
+
+import (
+	"github.com/infodiode/sender/internal/webhook"
+	"github.com/infodiode/shared/models"
+)
+
+// SetNotifier подключает уведомления о событиях тестов; nil - уведомления не отправляются
+func (m *Manager) SetNotifier(notifier *webhook.Notifier) {
+	m.notifier = notifier
+}
+
+// notify отправляет уведомление о событии теста с текущим результатом
+// (вызывается без удержания m.mu)
+func (m *Manager) notify(testCtx *TestContext, event, reason string) {
+	if m.notifier == nil {
+		return
+	}
+	if result, ok := m.GetResult(testCtx.ID); ok {
+		m.notifier.Notify(event, reason, result)
+	}
+}
+
+// finishEvent возвращает событие завершения теста по итоговому статусу
+func finishEvent(status models.TestStatus) string {
+	switch status {
+	case models.TestStatusCompleted:
+		return webhook.EventTestCompleted
+	case models.TestStatusStopped:
+		return webhook.EventTestStopped
+	default:
+		return webhook.EventTestFailed
+	}
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"time"
+)
+
+const (
+	// minPacerInterval минимальный период пополнения корзины: при более частых тиках
+	// time.Ticker не успевает и пропускает срабатывания
+	minPacerInterval = time.Millisecond
+	// pacerBurstTicks допустимое отставание от расписания в тиках: после задержки цикла
+	// отправки досылается не больше стольких тиков сообщений, чтобы не отправлять их лавиной
+	pacerBurstTicks = 4
+	// maxInFlightSends предел одновременно выполняющихся асинхронных отправок потока
+	maxInFlightSends = 1024
+)
+
+// pacer задает темп отправки потока по расписанию (корзина токенов, выраженная через
+// сроки сообщений): сообщение k должно быть отправлено
+// через k/rate от начала. На каждом тике отправляются все сообщения, срок которых наступил,
+// поэтому при высоких скоростях за тик отправляется несколько сообщений вместо одного
+// сообщения за тик с интервалом 1s/rate
+type pacer struct {
+	ticker *time.Ticker
+	step   time.Duration // Интервал между сообщениями по расписанию
+	lag    time.Duration // Допустимое отставание от расписания
+	next   time.Time     // Срок следующего сообщения
+}
+
+// newPacer создает темп отправки rate сообщений в секунду
+func newPacer(rate int) *pacer {
+	step := time.Second / time.Duration(rate)
+	interval := max(step, minPacerInterval)
+
+	return &pacer{
+		ticker: time.NewTicker(interval),
+		step:   step,
+		lag:    interval * pacerBurstTicks,
+		next:   time.Now().Add(step),
+	}
+}
+
+// C канал тиков; на каждом тике вызывается take
+func (p *pacer) C() <-chan time.Time {
+	return p.ticker.C
+}
+
+// take возвращает количество сообщений, срок которых наступил к моменту now, и срок
+// первого из них; срок i-го сообщения - due + i*step. При отставании больше lag
+// пропущенная часть расписания отбрасывается
+func (p *pacer) take(now time.Time) (n int, due time.Time) {
+	if now.Sub(p.next) > p.lag {
+		p.next = now.Add(-p.lag)
+	}
+	if now.Before(p.next) {
+		return 0, p.next
+	}
+
+	n = int(now.Sub(p.next)/p.step) + 1
+	due = p.next
+	p.next = p.next.Add(time.Duration(n) * p.step)
+	return n, due
+}
+
+// Stop останавливает тики
+func (p *pacer) Stop() {
+	p.ticker.Stop()
+}
+
+// sendSlots ограничивает количество одновременно выполняющихся асинхронных отправок
+type sendSlots chan struct{}
+
+// newSendSlots создает ограничение maxInFlightSends отправок
+func newSendSlots() sendSlots {
+	return make(sendSlots, maxInFlightSends)
+}
+
+// acquire занимает слот отправки; ожидание прерывается завершением или остановкой теста.
+// Возвращает false, если слот не получен
+func (s sendSlots) acquire(testCtx *TestContext) bool {
+	select {
+	case s <- struct{}{}:
+		return true
+	case <-testCtx.ctx.Done():
+		return false
+	case <-testCtx.stop:
+		return false
+	}
+}
+
+// release освобождает слот отправки
+func (s sendSlots) release() {
+	<-s
+}
+
+// wait ожидает завершения всех занятых отправок: занимает все слоты и освобождает
+// их. Вызывается перед выходом из фазы отправки, чтобы отправки не изменяли
+// статистику завершенного теста
+func (s sendSlots) wait() {
+	for range cap(s) {
+		s <- struct{}{}
+	}
+	for range cap(s) {
+		<-s
+	}
+}
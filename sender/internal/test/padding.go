@@ -0,0 +1,122 @@
+package test
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+
+	"github.com/infodiode/sender/internal/generator"
+	"github.com/infodiode/shared/models"
+)
+
+// paddingField поле заполнения с пустым значением; заполнение не короче одного символа,
+// иначе поле не выводится (omitempty)
+const paddingField = `,"padding":""`
+
+// maxTimeLength наибольшая длина времени в формате RFC3339Nano; числовые форматы
+// send_time (utils.TimeFormatEpoch*, монотонные часы) короче
+const maxTimeLength = len("2006-01-02T15:04:05.999999999-07:00")
+
+// paddedSize возвращает текущий размер сообщений с заполнением: packet_size или
+// размер шага перебора размеров
+func (testCtx *TestContext) paddedSize() int {
+	return int(testCtx.packetSize.Load())
+}
+
+// padMessage дополняет сообщение полем padding так, чтобы его JSON занимал ровно
+// packet_size байт (вызывается при pad_to_size); quotedPayload - длина payload в JSON
+// (models.QuotedLen). Сообщение, не помещающееся в packet_size, отправляется без
+// заполнения и учитывается в oversize_messages
+func (m *Manager) padMessage(testCtx *TestContext, message *models.Message, quotedPayload int) {
+	envelope := *message
+	envelope.Payload = ""
+	size := len(envelope.AppendJSON(nil)) - len(`""`) + quotedPayload
+
+	need := testCtx.paddedSize() - size - len(paddingField)
+	if need < 1 {
+		if !testCtx.warmingUp() {
+			atomic.AddInt64(&testCtx.Stats.OversizeMessages, 1)
+		}
+		return
+	}
+	message.Padding = testCtx.filler(need)
+}
+
+// filler возвращает строку заполнения длины n; строки разделяют общий буфер теста
+func (testCtx *TestContext) filler(n int) string {
+	current := testCtx.fill.Load()
+	if current == nil || len(*current) < n {
+		// Буфер растет с запасом: размер сообщений меняется с ростом номеров
+		grown := strings.Repeat("x", n+n/8+64)
+		testCtx.fill.Store(&grown)
+		current = &grown
+	}
+	return (*current)[:n]
+}
+
+// paddedMinimum возвращает наименьший packet_size, при котором сообщение с payload
+// (длина в JSON quotedPayload) дополняется заполнением до точного размера. Номера и время
+// отправки берутся наибольшей длины, чтобы размер не был превышен в ходе теста
+func paddedMinimum(testCtx *TestContext, quotedPayload int, timestamp string) int {
+	envelope := models.Message{
+		MessageID: math.MaxInt,
+		TestID:    testCtx.ID,
+		Sequence:  math.MaxInt64,
+		SendTime:  strings.Repeat("0", maxTimeLength),
+		Timestamp: timestamp,
+		Checksum:  strings.Repeat("0", 64),
+		Schema:    testCtx.schema,
+		TTL:       int64(testCtx.Config.MessageTTL),
+		Tenant:    testCtx.Config.Tenant,
+		RunLabel:  testCtx.Config.RunLabel,
+	}
+	return len(envelope.AppendJSON(nil)) - len(`""`) + quotedPayload + len(paddingField) + 1
+}
+
+// checkPadSize проверяет, что сообщения с записями data и искаженными записями теста
+// помещаются в packet_size вместе с заполнением
+func checkPadSize(testCtx *TestContext, data generator.Records) error {
+	if !testCtx.Config.PadToSize {
+		return nil
+	}
+
+	minimum := 0
+	for i := 0; i < data.Len(); i++ {
+		record := data.At(i)
+		minimum = max(minimum, paddedMinimum(testCtx, models.QuotedLen(string(record.AppendJSON(nil))), record.Timestamp))
+	}
+	for _, payload := range testCtx.invalid {
+		minimum = max(minimum, paddedMinimum(testCtx, models.QuotedLen(string(payload)), strings.Repeat("0", maxTimeLength)))
+	}
+
+	if minimum > testCtx.paddedSize() {
+		return fmt.Errorf("pad_to_size: для записей набора %s packet_size должен быть не меньше %d байт",
+			testCtx.data.set, minimum)
+	}
+	return nil
+}
+
+// fitLargePayload сокращает набор записей большого пакета, пока сообщение с ним не
+// поместится в packet_size вместе с заполнением (pad_to_size), и запоминает длину payload в JSON.
+// encode сериализует первые n записей набора
+func fitLargePayload(testCtx *TestContext, payload *largePayload, encode func(n int) (*largePayload, error)) (*largePayload, error) {
+	for {
+		payload.quoted = models.QuotedLen(payload.data)
+		minimum := paddedMinimum(testCtx, payload.quoted, strings.Repeat("0", maxTimeLength))
+		if minimum <= testCtx.paddedSize() {
+			return payload, nil
+		}
+		if payload.records <= 1 {
+			return nil, fmt.Errorf("pad_to_size: для сообщения с одной записью packet_size должен быть не меньше %d байт", minimum)
+		}
+
+		// Число записей уменьшается пропорционально превышению
+		n := min(payload.records-1, int(float64(payload.records)*float64(testCtx.paddedSize())/float64(minimum)))
+
+		var err error
+		if payload, err = encode(max(n, 1)); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/shared/models"
+)
+
+// progressRateWindow интервал оценки текущей скорости отправки (полных секунд)
+const progressRateWindow = 5
+
+// Progress ход выполняющегося теста. Тесты с заданным количеством сообщений (batch,
+// mixed) оцениваются по сформированным сообщениям, остальные - по времени выполнения
+type Progress struct {
+	ID        string              `json:"id"`
+	Type      models.TestType     `json:"type"`
+	Protocol  models.TestProtocol `json:"protocol,omitempty"`
+	StartTime time.Time           `json:"start_time"`
+	WarmingUp bool                `json:"warming_up"` // Идет прогрев; отправка не учитывается в статистике
+
+	MessagesSent  int64 `json:"messages_sent"`            // Отправлено без учета прогрева, как в /stats
+	Messages      int64 `json:"messages,omitempty"`       // Сформировано сообщений, включая прогрев и ошибки отправки
+	MessagesTotal int64 `json:"messages_total,omitempty"` // Всего сообщений теста (total_messages)
+
+	ElapsedSec  float64 `json:"elapsed_sec"`  // Прошло с запуска, включая прогрев
+	DurationSec float64 `json:"duration_sec"` // Предельная длительность: duration и прогрев
+
+	CurrentRate float64 `json:"current_rate"` // Сообщений в секунду за последние полные секунды
+	Percent     float64 `json:"percent"`      // Выполнено, %
+
+	ETASec       *float64   `json:"eta_sec,omitempty"`       // Оценка оставшегося времени
+	EstimatedEnd *time.Time `json:"estimated_end,omitempty"` // Оценка момента завершения
+}
+
+// Progress возвращает ход выполняющегося теста id; при пустом id - последнего
+// запущенного из выполняющихся
+func (m *Manager) Progress(id string) (*Progress, error) {
+	m.mu.RLock()
+	testCtx := m.running[id]
+	if id == "" {
+		for _, running := range m.running {
+			if testCtx == nil || running.StartTime.After(testCtx.StartTime) {
+				testCtx = running
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	if testCtx == nil {
+		if id != "" {
+			return nil, fmt.Errorf("тест %s не выполняется", id)
+		}
+		return nil, fmt.Errorf("нет активного теста")
+	}
+	return testProgress(testCtx, time.Now()), nil
+}
+
+// testProgress оценивает ход теста на момент now
+func testProgress(testCtx *TestContext, now time.Time) *Progress {
+	config := testCtx.Config
+	limit := time.Duration(config.Duration+config.WarmupSeconds) * time.Second
+	elapsed := max(now.Sub(testCtx.StartTime), 0)
+
+	progress := &Progress{
+		ID:           testCtx.ID,
+		Type:         config.Type,
+		Protocol:     config.Protocol,
+		StartTime:    testCtx.StartTime,
+		WarmingUp:    now.Before(testCtx.warmupEnd),
+		MessagesSent: atomic.LoadInt64(&testCtx.Stats.MessagesSent),
+		ElapsedSec:   elapsed.Seconds(),
+		DurationSec:  limit.Seconds(),
+		CurrentRate:  testCtx.timeline.rate(progressRateWindow),
+	}
+
+	// Оставшееся время до предельной длительности теста
+	var remaining time.Duration
+	if limit > 0 {
+		remaining = max(limit-elapsed, 0)
+		progress.Percent = math.Min(elapsed.Seconds()/limit.Seconds()*100, 100)
+	}
+
+	if total := int64(config.TotalMessages); total > 0 && (config.Type == models.TestTypeBatch || config.Type == models.TestTypeMixed) {
+		done := min(testCtx.measured.Load(), total)
+		progress.Messages = done
+		progress.MessagesTotal = total
+		// Тест завершается по количеству сообщений или по длительности, если она истечет раньше
+		progress.Percent = max(progress.Percent, float64(done)/float64(total)*100)
+		if progress.CurrentRate > 0 {
+			byRate := time.Duration(float64(total-done) / progress.CurrentRate * float64(time.Second))
+			if limit <= 0 || byRate < remaining {
+				remaining = byRate
+			}
+		}
+	}
+
+	if limit > 0 || progress.MessagesTotal > 0 && progress.CurrentRate > 0 {
+		eta := remaining.Seconds()
+		end := now.Add(remaining)
+		progress.ETASec = &eta
+		progress.EstimatedEnd = &end
+	}
+	return progress
+}
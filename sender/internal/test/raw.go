@@ -0,0 +1,200 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/infodiode/sender/internal/tcp"
+	"github.com/infodiode/sender/internal/transport"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/tcpframe"
+	"go.uber.org/zap"
+)
+
+const (
+	// rawPoolFrames количество кадров пула теста насыщения канала; кадры различаются
+	// содержимым, чтобы сжатие на пути не завышало результат
+	rawPoolFrames = 16
+	// rawPoolBytes предельный объем пула кадров
+	rawPoolBytes = 64 * 1024 * 1024
+)
+
+// RunRawTest измеряет предельную пропускную способность пути через диод: кадры-заполнители
+// размера Config.PacketSize отправляются по Config.ThreadCount отдельным TCP соединениям
+// из заранее подготовленного пула, без сериализации сообщений и контрольных сумм.
+// Recipient пропускает такие кадры, не разбирая их. Скорость ограничивается
+// Config.MessagesPerSec кадров в секунду на все соединения (0 - без ограничения)
+func (m *Manager) RunRawTest(config *models.TestConfig) (err error) {
+	if config.PacketSize <= tcpframe.HeaderSize {
+		return fmt.Errorf("размер кадра должен быть больше заголовка (%d байт): %d", tcpframe.HeaderSize, config.PacketSize)
+	}
+	connections := max(config.ThreadCount, 1)
+
+	m.logger.Info("Запуск теста насыщения канала",
+		zap.String("target", config.Target),
+		zap.Int("frame_size", config.PacketSize),
+		zap.Int("connections", connections),
+		zap.Int("frames_per_sec", config.MessagesPerSec),
+		zap.Int("duration", config.Duration))
+
+	senders, closeAll, err := m.openRawConnections(config.Target, connections)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	testCtx := m.beginTest(config)
+	defer func() { m.endTest(testCtx, err) }()
+
+	pool := newRawPool(testCtx.random, config.PacketSize)
+
+	m.mu.Lock()
+	testCtx.raw = &models.RawResult{
+		FrameSize:   config.PacketSize,
+		Connections: connections,
+		PoolFrames:  len(pool),
+	}
+	m.mu.Unlock()
+
+	// Интервал между кадрами одного соединения при ограничении скорости
+	var interval time.Duration
+	if config.MessagesPerSec > 0 {
+		interval = time.Second * time.Duration(connections) / time.Duration(config.MessagesPerSec)
+	}
+
+	if err := m.waitStart(testCtx); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	var failMu sync.Mutex
+	var failure error
+	for i, sender := range senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Соединения начинают с разных кадров пула
+			if err := m.rawWorker(testCtx, sender, pool, i*len(pool)/len(senders), interval); err != nil {
+				failMu.Lock()
+				failure = err
+				failMu.Unlock()
+				testCtx.Cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if failure != nil {
+		return failure
+	}
+
+	stats := testCtx.Stats
+	m.logger.Info("Тест насыщения канала завершен",
+		zap.Int64("frames_sent", stats.MessagesSent),
+		zap.Int64("bytes_sent", stats.BytesSent),
+		zap.Float64("bandwidth_mbps", rawBandwidth(stats.BytesSent, time.Since(stats.StartTime))))
+
+	return nil
+}
+
+// openRawConnections открывает отдельные TCP соединения теста к address
+// (пустой - адрес из конфигурации); возвращает функцию их закрытия
+func (m *Manager) openRawConnections(address string, count int) ([]transport.RawSender, func(), error) {
+	opened := make([]transport.Transport, 0, count)
+	closeAll := func() {
+		for _, t := range opened {
+			if err := closeTransport(t); err != nil {
+				m.logger.Warn("Ошибка закрытия соединения теста насыщения канала", zap.Error(err))
+			}
+		}
+	}
+
+	senders := make([]transport.RawSender, 0, count)
+	for i := 0; i < count; i++ {
+		t, err := m.transports.Open(models.ProtocolTCP, address)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		opened = append(opened, t)
+
+		sender, ok := t.(transport.RawSender)
+		if !ok {
+			closeAll()
+			return nil, nil, fmt.Errorf("транспорт %s не поддерживает отправку кадров-заполнителей", t.Protocol())
+		}
+		if err := t.Connect(); err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("соединение %d: %w", i+1, err)
+		}
+		senders = append(senders, sender)
+	}
+
+	return senders, closeAll, nil
+}
+
+// newRawPool формирует пул кадров-заполнителей размера size (с заголовком) со случайным
+// телом из источника теста; объем пула ограничен rawPoolBytes
+func newRawPool(random *rand.Rand, size int) [][]byte {
+	count := min(rawPoolFrames, max(1, rawPoolBytes/size))
+
+	pool := make([][]byte, count)
+	body := make([]byte, size-tcpframe.HeaderSize)
+	for i := range pool {
+		random.Read(body)
+		pool[i] = tcpframe.AppendRaw(make([]byte, 0, size), body)
+	}
+	return pool
+}
+
+// rawWorker отправляет кадры пула по кругу, начиная с кадра first, до окончания теста.
+// Ошибки отправки учитываются в статистике; отсутствие поддержки кадров-заполнителей
+// на recipient завершает тест
+func (m *Manager) rawWorker(testCtx *TestContext, sender transport.RawSender, pool [][]byte, first int, interval time.Duration) error {
+	var throttle <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	for next := first; ; next++ {
+		select {
+		case <-testCtx.ctx.Done():
+			return nil
+		case <-testCtx.stop:
+			return nil
+		default:
+		}
+		if throttle != nil {
+			select {
+			case <-throttle:
+			case <-testCtx.ctx.Done():
+				return nil
+			case <-testCtx.stop:
+				return nil
+			}
+		}
+
+		frame := pool[next%len(pool)]
+		if err := sender.SendRaw(frame); err != nil {
+			if errors.Is(err, tcp.ErrRawUnsupported) {
+				return err
+			}
+			m.recordError(testCtx, err)
+			continue
+		}
+		m.recordSent(testCtx, 1, int64(len(frame)))
+	}
+}
+
+// rawBandwidth возвращает пропускную способность в Мбит/с
+func rawBandwidth(bytes int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / elapsed.Seconds() / 1e6
+}
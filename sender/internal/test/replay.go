@@ -0,0 +1,207 @@
+package test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/infodiode/sender/internal/generator"
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// RunReplayTest воспроизводит отправки записанного теста с исходными интервалами
+// (с учетом множителя скорости) и теми же записями тестовых данных, чтобы сравнить
+// поведение диода на одинаковом трафике, например до и после обновления прошивки.
+// Если в конфигурации задан протокол, все отправки идут через него, иначе через записанные
+func (m *Manager) RunReplayTest(config *models.TestConfig, capture *Capture) (err error) {
+	rc := config.Replay
+	if rc == nil || capture == nil {
+		return fmt.Errorf("не заданы параметры воспроизведения")
+	}
+	if rc.Speed <= 0 {
+		return fmt.Errorf("некорректный множитель скорости воспроизведения: %g", rc.Speed)
+	}
+
+	m.logger.Info("Запуск воспроизведения записанного трафика",
+		zap.String("capture", rc.Capture),
+		zap.String("source_test_id", capture.Header.TestID),
+		zap.Int("events", len(capture.Events)),
+		zap.Duration("duration", capture.Duration()),
+		zap.Float64("speed", rc.Speed))
+
+	threads := make(map[models.TestProtocol]int)
+	for _, event := range capture.Events {
+		threads[m.replayProtocol(config, event)]++
+	}
+	for protocol := range threads {
+		if err := m.ensureTransport(protocol); err != nil {
+			return err
+		}
+	}
+
+	// Наборы загружаются до создания контекста теста, поэтому отображенные в память
+	// наборы закрываются по завершении воспроизведения, а не в endTest
+	data := make(map[dataRef]generator.Records)
+	var mapped []*generator.MappedDataset
+	defer func() {
+		for _, dataset := range mapped {
+			dataset.Close()
+		}
+	}()
+	for _, event := range capture.Events {
+		ref := dataRef{set: event.DataSet, size: event.DataSize, file: event.DataFile}
+		if _, ok := data[ref]; ok {
+			continue
+		}
+		records, err := m.testData(ref, capture.Header.DataSource)
+		if err != nil {
+			return fmt.Errorf("ошибка загрузки данных записи: %w", err)
+		}
+		if dataset, ok := records.(*generator.MappedDataset); ok {
+			mapped = append(mapped, dataset)
+		}
+		if records.Len() == 0 {
+			return fmt.Errorf("набор данных %s пуст", ref)
+		}
+		data[ref] = records
+	}
+
+	// Большие пакеты сериализуются один раз на набор данных
+	large := make(map[dataRef]*largePayload)
+	for _, event := range capture.Events {
+		ref := dataRef{set: event.DataSet, size: event.DataSize, file: event.DataFile}
+		if event.Kind != CaptureKindLarge || large[ref] != nil {
+			continue
+		}
+		payload, err := encodeLargePayload(data[ref], data[ref].Len())
+		if err != nil {
+			return fmt.Errorf("ошибка сериализации данных записи: %w", err)
+		}
+		large[ref] = payload
+	}
+
+	// Записи из запроса исходного теста сохраняются в записи нового воспроизведения
+	config.DataSource = capture.Header.DataSource
+
+	testCtx := m.beginTest(config)
+	defer func() { m.endTest(testCtx, err) }()
+
+	// Для записей смешанного теста статистика ведется по каждому протоколу
+	if len(threads) > 1 {
+		m.mu.Lock()
+		testCtx.protocols = make(protocolBreakdown, len(threads))
+		for protocol := range threads {
+			testCtx.protocols[protocol] = &protocolCounters{latencies: newLatencyHistogram()}
+		}
+		m.mu.Unlock()
+	}
+
+	if err := m.waitStart(testCtx); err != nil {
+		return err
+	}
+
+	// Отправки выполняются асинхронно, их число ограничено, как в потоковом тесте;
+	// перед завершением теста ожидаются выполняющиеся отправки
+	slots := newSendSlots()
+	defer slots.wait()
+
+	start := time.Now()
+	for _, event := range capture.Events {
+		due := start.Add(time.Duration(float64(event.OffsetUs)/rc.Speed) * time.Microsecond)
+		if wait := time.Until(due); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-testCtx.ctx.Done():
+				timer.Stop()
+				return nil
+			case <-testCtx.stop:
+				timer.Stop()
+				return testCtx.stopErr()
+			}
+		}
+
+		// Отправка асинхронная, чтобы медленная отправка не сдвигала следующие;
+		// при занятых слотах воспроизведение отстает от записи
+		if !slots.acquire(testCtx) {
+			select {
+			case <-testCtx.stop:
+				return testCtx.stopErr()
+			default:
+				return nil
+			}
+		}
+		ref := dataRef{set: event.DataSet, size: event.DataSize, file: event.DataFile}
+		go m.replayEvent(testCtx, slots, m.replayProtocol(config, event), event, data[ref], large[ref])
+	}
+
+	return nil
+}
+
+// replayProtocol возвращает протокол воспроизведения отправки
+func (m *Manager) replayProtocol(config *models.TestConfig, event CaptureEvent) models.TestProtocol {
+	if config.Protocol != "" {
+		return config.Protocol
+	}
+	if event.Protocol == "" {
+		return models.ProtocolMQTT
+	}
+	return event.Protocol
+}
+
+// replayEvent формирует сообщения отправки из записей набора данных и отправляет их;
+// large - подготовленный payload набора для событий с большим пакетом. По завершении
+// освобождает слот отправки
+func (m *Manager) replayEvent(testCtx *TestContext, slots sendSlots, protocol models.TestProtocol, event CaptureEvent, data generator.Records, large *largePayload) {
+	defer slots.release()
+
+	var messages []*models.Message
+	var bytes int64
+	switch event.Kind {
+	case CaptureKindLarge:
+		msg := m.newLargeMessage(testCtx, large)
+		messages = []*models.Message{msg}
+		bytes = int64(len(msg.Payload))
+	default:
+		messages = make([]*models.Message, 0, event.Messages)
+		for i := 0; i < event.Messages; i++ {
+			msg := m.newMessage(testCtx, data.At((event.DataIndex+i)%data.Len()))
+			messages = append(messages, msg)
+			bytes += int64(len(msg.Payload))
+		}
+	}
+
+	startSend := time.Now()
+	testCtx.capture.Load().record(startSend, CaptureEvent{
+		Protocol:  protocol,
+		Kind:      event.Kind,
+		DataSet:   event.DataSet,
+		DataSize:  event.DataSize,
+		DataFile:  event.DataFile,
+		DataIndex: event.DataIndex,
+		Messages:  len(messages),
+		Bytes:     bytes,
+	})
+
+	var err error
+	if event.Kind == CaptureKindBatch {
+		err = m.sendBatch(testCtx, protocol, messages)
+	} else {
+		err = m.send(testCtx, protocol, messages[0])
+	}
+
+	if err != nil {
+		m.recordFailed(testCtx, err, int64(len(messages)))
+		testCtx.protocols.recordError(protocol)
+		m.logger.Debug("Ошибка отправки при воспроизведении",
+			zap.String("protocol", string(protocol)),
+			zap.String("kind", event.Kind),
+			zap.Error(err))
+		return
+	}
+
+	latency := float64(time.Since(startSend).Milliseconds())
+	m.recordSent(testCtx, int64(len(messages)), bytes)
+	m.updateLatencyStats(testCtx, latency)
+	testCtx.protocols.recordSent(protocol, int64(len(messages)), bytes, latency)
+}
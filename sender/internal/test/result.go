@@ -0,0 +1,225 @@
+package test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/infodiode/sender/internal/broker"
+	"github.com/infodiode/shared/models"
+)
+
+// maxStoredResults максимальное количество хранимых результатов тестов
+const maxStoredResults = 100
+
+// maxTimelinePoints глубина посекундной динамики теста (кольцевой буфер)
+const maxTimelinePoints = 3600
+
+// latencyBucketsMs верхние границы корзин гистограммы задержек (ms)
+var latencyBucketsMs = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// Категории ошибок отправки
+const (
+	ErrorCategoryTimeout      = "timeout"
+	ErrorCategoryDisconnected = "disconnected"
+	ErrorCategoryReset        = "reset"
+	ErrorCategoryRefused      = "refused"
+	ErrorCategoryOther        = "other"
+)
+
+// timelineRecorder накапливает посекундную динамику теста в кольцевом буфере:
+// хранятся последние maxTimelinePoints секунд
+type timelineRecorder struct {
+	mu       sync.Mutex
+	start    time.Time
+	next     int // Секунда, следующая за последней записанной
+	points   []models.TimelinePoint
+	received []models.ReceivePoint // Динамика приема recipient, полученная по завершении теста
+}
+
+// newTimelineRecorder создает новый регистратор динамики
+func newTimelineRecorder(start time.Time) *timelineRecorder {
+	return &timelineRecorder{start: start}
+}
+
+// add добавляет показатели в точку текущей секунды
+func (t *timelineRecorder) add(sent, bytes, errs int64) {
+	second := int(time.Since(t.start) / time.Second)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.advance(second)
+	if second < 0 || second < t.next-maxTimelinePoints {
+		return
+	}
+
+	point := &t.points[second%maxTimelinePoints]
+	point.Sent += sent
+	point.Bytes += bytes
+	point.Errors += errs
+}
+
+// advance добавляет пустые точки до секунды second включительно (вызывается под t.mu)
+func (t *timelineRecorder) advance(second int) {
+	for t.next <= second {
+		point := models.TimelinePoint{Second: t.next}
+		if len(t.points) < maxTimelinePoints {
+			t.points = append(t.points, point)
+		} else {
+			t.points[t.next%maxTimelinePoints] = point
+		}
+		t.next++
+	}
+}
+
+// rate возвращает среднюю скорость отправки (сообщений в секунду) за последние window
+// полных секунд; текущая неполная секунда не учитывается
+func (t *timelineRecorder) rate(window int) float64 {
+	second := int(time.Since(t.start) / time.Second)
+	if second <= 0 || window <= 0 {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.advance(second)
+	first := max(second-window, 0, t.next-len(t.points))
+	var sent int64
+	for s := first; s < second; s++ {
+		sent += t.points[s%maxTimelinePoints].Sent
+	}
+	if second == first {
+		return 0
+	}
+	return float64(sent) / float64(second-first)
+}
+
+// setReceived сохраняет посекундную динамику приема recipient
+func (t *timelineRecorder) setReceived(points []models.ReceivePoint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.received = points
+}
+
+// snapshot возвращает копию накопленных точек в хронологическом порядке.
+// Прием recipient сопоставляется с секундами теста по часам хостов, поэтому
+// точность сопоставления зависит от их синхронизации
+func (t *timelineRecorder) snapshot() []models.TimelinePoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var points []models.TimelinePoint
+	if t.next <= maxTimelinePoints {
+		points = append(points, t.points...)
+	} else {
+		start := t.next % maxTimelinePoints
+		points = append(append(points, t.points[start:]...), t.points[:start]...)
+	}
+
+	first := t.next - len(t.points)
+	for _, rp := range t.received {
+		second := int(rp.Time.Sub(t.start).Round(time.Second) / time.Second)
+		if second < first {
+			continue
+		}
+		// Recipient может принимать сообщения и после окончания отправки
+		for first+len(points) <= second {
+			points = append(points, models.TimelinePoint{Second: first + len(points)})
+		}
+
+		point := &points[second-first]
+		point.Received += rp.Received
+		point.ReceiveErrors += rp.Invalid
+	}
+
+	return points
+}
+
+// latencyHistogram гистограмма задержек с фиксированными корзинами
+type latencyHistogram struct {
+	counts []atomic.Int64
+}
+
+// newLatencyHistogram создает пустую гистограмму
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]atomic.Int64, len(latencyBucketsMs)+1)}
+}
+
+// observe учитывает одно измерение задержки
+func (h *latencyHistogram) observe(latencyMs float64) {
+	for i, bound := range latencyBucketsMs {
+		if latencyMs <= bound {
+			h.counts[i].Add(1)
+			return
+		}
+	}
+	h.counts[len(latencyBucketsMs)].Add(1)
+}
+
+// snapshot возвращает содержимое корзин
+func (h *latencyHistogram) snapshot() []models.HistogramBucket {
+	buckets := make([]models.HistogramBucket, 0, len(h.counts))
+	for i := range h.counts {
+		bucket := models.HistogramBucket{Count: h.counts[i].Load()}
+		if i < len(latencyBucketsMs) {
+			bucket.UpperBoundMs = latencyBucketsMs[i]
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+// errorBreakdown счетчики ошибок по категориям
+type errorBreakdown struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// add учитывает ошибку указанной категории
+func (e *errorBreakdown) add(category string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.counts == nil {
+		e.counts = make(map[string]int64)
+	}
+	e.counts[category]++
+}
+
+// snapshot возвращает копию счетчиков
+func (e *errorBreakdown) snapshot() map[string]int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	counts := make(map[string]int64, len(e.counts))
+	for k, v := range e.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+// classifyError определяет категорию ошибки отправки
+func classifyError(err error) string {
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, broker.ErrPublishTimeout), errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrorCategoryTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorCategoryTimeout
+	case errors.Is(err, broker.ErrNotConnected), errors.Is(err, broker.ErrNATSNotConnected):
+		return ErrorCategoryDisconnected
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ErrorCategoryReset
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorCategoryRefused
+	default:
+		return ErrorCategoryOther
+	}
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// maxStartAhead предельное время от запроса до начала отправки по start_at
+const maxStartAhead = time.Hour
+
+// startAtTolerance опоздание запроса относительно start_at, при котором тест еще
+// запускается (сразу после подготовки); при большем опоздании запрос отклоняется
+const startAtTolerance = time.Second
+
+// ValidateStartAt проверяет время начала отправки теста
+func ValidateStartAt(config *models.TestConfig) error {
+	if config.StartAt == nil {
+		return nil
+	}
+	wait := time.Until(*config.StartAt)
+	if wait < -startAtTolerance {
+		return fmt.Errorf("время начала start_at уже прошло (%s назад); проверьте синхронизацию часов", (-wait).Round(time.Millisecond))
+	}
+	if wait > maxStartAhead {
+		return fmt.Errorf("время начала start_at должно быть не позже чем через %s", maxStartAhead)
+	}
+	return nil
+}
+
+// scheduledStart возвращает начало отправки теста: start_at или текущее время,
+// если start_at не задан или уже прошел
+func scheduledStart(config *models.TestConfig) time.Time {
+	now := time.Now()
+	if config.StartAt != nil && config.StartAt.After(now) {
+		return *config.StartAt
+	}
+	return now
+}
+
+// waitStart ожидает начала отправки теста по start_at после подготовки (подключения
+// и загрузки данных), чтобы тесты нескольких sender начинали отправку одновременно.
+// Возвращает ошибку остановки, если тест остановлен во время ожидания
+func (m *Manager) waitStart(testCtx *TestContext) error {
+	if testCtx.Config.StartAt == nil {
+		return nil
+	}
+
+	if wait := time.Until(testCtx.StartTime); wait > 0 {
+		m.logger.Info("Ожидание начала отправки теста",
+			zap.String("test_id", testCtx.ID),
+			zap.Time("start_at", testCtx.StartTime),
+			zap.Duration("wait", wait))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-testCtx.stop:
+			timer.Stop()
+			return testCtx.stopErr()
+		}
+	}
+
+	// Опоздание: подготовка заняла больше времени до start_at или запрос пришел позже него
+	lag := time.Since(*testCtx.Config.StartAt)
+	lagMs := float64(max(lag, 0).Microseconds()) / 1000
+	m.mu.Lock()
+	testCtx.startLag = &lagMs
+	m.mu.Unlock()
+	if lag > 10*time.Millisecond {
+		m.logger.Warn("Отправка теста начата позже start_at",
+			zap.String("test_id", testCtx.ID),
+			zap.Duration("lag", lag))
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// RunSessionResumeTest отправляет поток сообщений с разрывами MQTT соединения
+// и по отчету recipient проверяет, что сохраненные QoS 1/2 сообщения не потеряны
+func (m *Manager) RunSessionResumeTest(config *models.TestConfig) (err error) {
+	sc := config.Session
+	if sc == nil {
+		return fmt.Errorf("не заданы параметры теста восстановления сессии")
+	}
+	if config.Protocol != models.ProtocolMQTT {
+		return fmt.Errorf("тест восстановления сессии поддерживается только для MQTT")
+	}
+	if m.orchestrator == nil {
+		return fmt.Errorf("не задан адрес recipient для канала оркестрации")
+	}
+	if !m.producer.SessionPersistent() {
+		return fmt.Errorf("тест восстановления сессии требует qos 1/2, clean_session=false и store_directory")
+	}
+
+	m.logger.Info("Запуск теста восстановления сессии",
+		zap.Int("messages_per_sec", config.MessagesPerSec),
+		zap.Int("interruptions", sc.Interruptions),
+		zap.Int("interval", sc.Interval),
+		zap.Int("pause_duration", sc.PauseDuration))
+
+	testCtx := m.beginTest(config)
+	defer func() { m.endTest(testCtx, err) }()
+
+	result := &models.SessionResult{}
+	m.mu.Lock()
+	testCtx.session = result
+	m.mu.Unlock()
+
+	data, err := m.loadTestData(testCtx, "small", 100)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки данных: %w", err)
+	}
+
+	if err := m.waitStart(testCtx); err != nil {
+		return err
+	}
+
+	// Прогрев без разрывов; его сообщения входят в проверку полноты доставки
+	if remaining := time.Until(testCtx.warmupEnd); remaining > 0 {
+		if err := m.streamPhase(testCtx, config.MessagesPerSec, remaining, data); err != nil {
+			return err
+		}
+	}
+
+	interval := time.Duration(sc.Interval) * time.Second
+	for i := 0; i < sc.Interruptions; i++ {
+		if err := m.streamPhase(testCtx, config.MessagesPerSec, interval, data); err != nil {
+			return err
+		}
+
+		event := models.SessionInterruption{
+			Start:        time.Now(),
+			LastSequence: testCtx.sequence.Load(),
+		}
+		if err := m.producer.Interrupt(); err != nil {
+			return err
+		}
+
+		select {
+		case <-time.After(time.Duration(sc.PauseDuration) * time.Second):
+		case <-testCtx.stop:
+			// Соединение восстанавливаем и при остановке, чтобы не оставить producer отключенным
+			if err := m.producer.Resume(); err != nil {
+				m.logger.Error("Ошибка восстановления соединения", zap.Error(err))
+			}
+			return testCtx.stopErr()
+		}
+
+		if err := m.producer.Resume(); err != nil {
+			return err
+		}
+		event.End = time.Now()
+
+		m.mu.Lock()
+		result.Interruptions = append(result.Interruptions, event)
+		m.mu.Unlock()
+
+		m.logger.Info("Соединение восстановлено после разрыва",
+			zap.Int("interruption", i+1),
+			zap.Int64("last_sequence", event.LastSequence),
+			zap.Duration("pause", event.End.Sub(event.Start)))
+	}
+
+	if err := m.streamPhase(testCtx, config.MessagesPerSec, interval, data); err != nil {
+		return err
+	}
+
+	// Ожидаем повторной отправки сохраненных сообщений и их доставки
+	select {
+	case <-time.After(time.Duration(sc.SettleTime) * time.Second):
+	case <-testCtx.stop:
+		return testCtx.stopErr()
+	}
+
+	report, err := m.orchestrator.SessionReport(testCtx.ctx, testCtx.ID)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	result.Report = report
+	result.Expected = testCtx.sequence.Load() - testCtx.rejected.Load()
+	result.Rejected = testCtx.rejected.Load()
+	if result.Expected > report.Unique {
+		result.Lost = result.Expected - report.Unique
+	}
+	result.Passed = result.Lost == 0
+	if result.Passed {
+		result.Verdict = fmt.Sprintf("все %d сообщений доставлены после %d разрывов", result.Expected, len(result.Interruptions))
+	} else {
+		result.Verdict = fmt.Sprintf("потеряно %d из %d сообщений", result.Lost, result.Expected)
+	}
+	m.mu.Unlock()
+
+	m.logger.Info("Тест восстановления сессии завершен",
+		zap.Int64("expected", result.Expected),
+		zap.Int64("rejected", result.Rejected),
+		zap.Int64("unique", report.Unique),
+		zap.Int64("duplicates", report.Duplicates),
+		zap.Int64("lost", result.Lost),
+		zap.Bool("passed", result.Passed))
+
+	return nil
+}
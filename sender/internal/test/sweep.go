@@ -0,0 +1,223 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/sender/internal/generator"
+	"github.com/infodiode/sender/internal/orchestration"
+	"github.com/infodiode/shared/models"
+	"github.com/infodiode/shared/utils"
+	"go.uber.org/zap"
+)
+
+// RunSweepTest перебирает размеры сообщений: на каждом шаге сообщения дополняются
+// заполнением до размера шага и отправляются потоками без ограничения темпа в течение
+// step_duration. Результат - зависимость пропускной способности и задержки от размера
+func (m *Manager) RunSweepTest(config *models.TestConfig) (err error) {
+	sc := config.Sweep
+	if sc == nil || len(sc.Sizes) == 0 {
+		return fmt.Errorf("не заданы размеры сообщений перебора")
+	}
+
+	m.logger.Info("Запуск перебора размеров сообщений",
+		zap.String("protocol", string(config.Protocol)),
+		zap.Int("threads", config.ThreadCount),
+		zap.Ints("sizes", sc.Sizes),
+		zap.Int("step_duration", sc.StepDuration))
+
+	// Шаги начинаются с наименьшего размера: набор данных проверяется по нему
+	config.PadToSize = true
+	config.PacketSize = sc.Sizes[0]
+
+	testCtx := m.beginTest(config)
+	defer func() { m.endTest(testCtx, err) }()
+
+	if err := m.connectTransport(testCtx); err != nil {
+		return err
+	}
+
+	result := &models.SweepResult{}
+	m.mu.Lock()
+	testCtx.sweep = result
+	m.mu.Unlock()
+
+	data, err := m.loadTestData(testCtx, "small", 100)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки данных: %w", err)
+	}
+
+	if err := m.waitStart(testCtx); err != nil {
+		return err
+	}
+
+	// Прогрев на наименьшем размере; шаги начинаются после доставки прогревочных сообщений
+	if remaining := time.Until(testCtx.warmupEnd); remaining > 0 {
+		if stopped := m.sweepPhase(testCtx, remaining, data, nil); stopped {
+			return testCtx.stopErr()
+		}
+		if err := m.sweepSettle(testCtx); err != nil {
+			return err
+		}
+	}
+
+	for _, size := range sc.Sizes {
+		step, err := m.runSweepStep(testCtx, size, data)
+		if err != nil {
+			return err
+		}
+
+		m.mu.Lock()
+		result.Steps = append(result.Steps, *step)
+		m.mu.Unlock()
+
+		m.logger.Info("Шаг перебора размеров завершен",
+			zap.Int("size", size),
+			zap.Int64("sent", step.Sent),
+			zap.Int64("errors", step.Errors),
+			zap.Float64("messages_per_sec", step.MessagesPerSec),
+			zap.Float64("mbit_per_sec", step.MbitPerSec))
+	}
+
+	return nil
+}
+
+// runSweepStep отправляет сообщения размера size в течение шага и оценивает прием по
+// данным recipient, если задан канал оркестрации
+func (m *Manager) runSweepStep(testCtx *TestContext, size int, data generator.Records) (*models.SweepStep, error) {
+	sc := testCtx.Config.Sweep
+	testCtx.packetSize.Store(int64(size))
+
+	var before *orchestration.RecipientStats
+	if m.orchestrator != nil {
+		var err error
+		if before, err = m.orchestrator.RecipientStats(testCtx.ctx); err != nil {
+			return nil, err
+		}
+	}
+	sentBefore := atomic.LoadInt64(&testCtx.Stats.MessagesSent)
+	errorsBefore := atomic.LoadInt64(&testCtx.Stats.Errors)
+
+	send := utils.NewJitterHistogram()
+	start := time.Now()
+	if stopped := m.sweepPhase(testCtx, time.Duration(sc.StepDuration)*time.Second, data, send); stopped {
+		return nil, testCtx.stopErr()
+	}
+	elapsed := time.Since(start).Seconds()
+	if testCtx.ctx.Err() != nil {
+		return nil, fmt.Errorf("истекло время теста")
+	}
+
+	step := &models.SweepStep{
+		Size:   size,
+		Sent:   atomic.LoadInt64(&testCtx.Stats.MessagesSent) - sentBefore,
+		Errors: atomic.LoadInt64(&testCtx.Stats.Errors) - errorsBefore,
+		Send:   send.HopLatency(),
+	}
+	if elapsed > 0 {
+		step.MessagesPerSec = float64(step.Sent) / elapsed
+		step.MbitPerSec = float64(step.Sent) * float64(size) * 8 / 1e6 / elapsed
+	}
+
+	if m.orchestrator == nil {
+		return step, nil
+	}
+
+	// Ожидаем доставки сообщений, находящихся в пути
+	if err := m.sweepSettle(testCtx); err != nil {
+		return nil, err
+	}
+	after, err := m.orchestrator.RecipientStats(testCtx.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	received := after.MessagesReceived - before.MessagesReceived
+	loss := 0.0
+	if step.Sent > 0 && received < step.Sent {
+		loss = float64(step.Sent-received) / float64(step.Sent) * 100
+	}
+	step.Received = &received
+	step.LossPercent = &loss
+
+	// Средняя задержка шага по разнице накопленных сумм
+	if processed := after.MessagesProcessed - before.MessagesProcessed; processed > 0 {
+		totalAfter := after.AvgLatency * float64(after.MessagesProcessed)
+		totalBefore := before.AvgLatency * float64(before.MessagesProcessed)
+		latency := (totalAfter - totalBefore) / float64(processed)
+		step.AvgLatencyMs = &latency
+	}
+
+	return step, nil
+}
+
+// sweepPhase отправляет сообщения в thread_count потоков без ограничения темпа в течение
+// duration; длительность отправки каждого сообщения учитывается в send, если он задан.
+// Возвращает true, если тест остановлен
+func (m *Manager) sweepPhase(testCtx *TestContext, duration time.Duration, data generator.Records, send *utils.JitterHistogram) bool {
+	phaseEnd := time.Now().Add(duration)
+	var next atomic.Int64
+
+	var wg sync.WaitGroup
+	for range max(testCtx.Config.ThreadCount, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(phaseEnd) {
+				select {
+				case <-testCtx.ctx.Done():
+					return
+				case <-testCtx.stop:
+					return
+				default:
+				}
+
+				index := int(next.Add(1)-1) % data.Len()
+				msg := m.newMessage(testCtx, data.At(index))
+
+				startSend := time.Now()
+				testCtx.capture.Load().record(startSend, CaptureEvent{
+					Protocol:  testCtx.Config.Protocol,
+					Kind:      CaptureKindMessage,
+					DataSet:   testCtx.data.set,
+					DataSize:  testCtx.data.size,
+					DataFile:  testCtx.data.file,
+					DataIndex: index,
+					Messages:  1,
+					Bytes:     int64(len(msg.Payload)),
+				})
+				if err := m.send(testCtx, testCtx.Config.Protocol, msg); err != nil {
+					m.recordError(testCtx, err)
+					continue
+				}
+				m.recordSent(testCtx, 1, int64(len(msg.Payload)))
+
+				duration := time.Since(startSend)
+				m.updateLatencyStats(testCtx, float64(duration.Milliseconds()))
+				if send != nil {
+					send.Observe(duration)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-testCtx.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// sweepSettle ожидает settle_time для доставки сообщений, находящихся в пути
+func (m *Manager) sweepSettle(testCtx *TestContext) error {
+	select {
+	case <-time.After(time.Duration(testCtx.Config.Sweep.SettleTime) * time.Second):
+		return nil
+	case <-testCtx.stop:
+		return testCtx.stopErr()
+	}
+}
@@ -0,0 +1,21 @@
+package test
+
+import (
+	"time"
+
+	"github.com/infodiode/shared/utils"
+)
+
+// SetTimeFormat изменяет формат send_time сообщений (utils.TimeFormat*); применяется
+// к тестам, запущенным после изменения
+func (m *Manager) SetTimeFormat(format string) {
+	if format == "" {
+		format = utils.TimeFormatRFC3339Nano
+	}
+	m.timeFormat.Store(&format)
+}
+
+// sendTime возвращает текущее время в формате send_time теста
+func (testCtx *TestContext) sendTime() string {
+	return utils.FormatTime(time.Now(), testCtx.timeFormat)
+}
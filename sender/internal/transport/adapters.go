@@ -0,0 +1,193 @@
+package transport
+
+import (
+	"time"
+
+	"github.com/infodiode/sender/internal/broker"
+	"github.com/infodiode/sender/internal/quic"
+	"github.com/infodiode/sender/internal/serial"
+	"github.com/infodiode/sender/internal/tcp"
+	"github.com/infodiode/shared/models"
+)
+
+// mqttTransport отправка через MQTT producer
+type mqttTransport struct {
+	producer *broker.MQTTProducer
+	opts     *broker.PublishOptions // Параметры публикации; nil - из конфигурации producer
+}
+
+// NewMQTT создает транспорт MQTT
+func NewMQTT(producer *broker.MQTTProducer) Transport {
+	return &mqttTransport{producer: producer}
+}
+
+// NewMQTTTopic создает транспорт MQTT, отправляющий в топик topic через общий producer
+func NewMQTTTopic(producer *broker.MQTTProducer, topic string) Transport {
+	opts := producer.PublishOptions()
+	opts.Topic = topic
+	return NewMQTTOptions(producer, opts)
+}
+
+// NewMQTTOptions создает транспорт MQTT, публикующий с параметрами opts через общий producer
+func NewMQTTOptions(producer *broker.MQTTProducer, opts broker.PublishOptions) Transport {
+	return &mqttTransport{producer: producer, opts: &opts}
+}
+
+// NewMQTTSession создает транспорт MQTT через собственный producer (отдельное соединение),
+// который закрывается вместе с транспортом
+func NewMQTTSession(producer *broker.MQTTProducer, opts broker.PublishOptions) Transport {
+	return &mqttSessionTransport{mqttTransport{producer: producer, opts: &opts}}
+}
+
+func (t *mqttTransport) Protocol() models.TestProtocol { return models.ProtocolMQTT }
+
+// Connect ничего не делает: переподключение выполняет клиент paho
+func (t *mqttTransport) Connect() error { return nil }
+
+func (t *mqttTransport) Connected() bool { return t.producer.IsConnected() }
+
+func (t *mqttTransport) Send(message *models.Message) error {
+	if t.opts != nil {
+		return t.producer.PublishWith(*t.opts, message)
+	}
+	return t.producer.Publish(message)
+}
+
+func (t *mqttTransport) SendBatch(messages []*models.Message) error {
+	if t.opts != nil {
+		return t.producer.PublishBatchWith(*t.opts, messages)
+	}
+	return t.producer.PublishBatch(messages)
+}
+
+func (t *mqttTransport) Stats() interface{} { return t.producer.GetStats() }
+
+func (t *mqttTransport) Churn(downtime time.Duration) error { return t.producer.Churn(downtime) }
+
+// mqttSessionTransport транспорт MQTT с собственным соединением
+type mqttSessionTransport struct {
+	mqttTransport
+}
+
+// Close закрывает соединение producer
+func (t *mqttSessionTransport) Close() error { return t.producer.Close() }
+
+// tcpTransport отправка через TCP клиент
+type tcpTransport struct {
+	client *tcp.TCPClient
+}
+
+// NewTCP создает транспорт TCP
+func NewTCP(client *tcp.TCPClient) Transport {
+	return &tcpTransport{client: client}
+}
+
+func (t *tcpTransport) Protocol() models.TestProtocol { return models.ProtocolTCP }
+
+func (t *tcpTransport) Connect() error { return t.client.Connect() }
+
+func (t *tcpTransport) Connected() bool { return t.client.IsConnected() }
+
+func (t *tcpTransport) Send(message *models.Message) error {
+	return t.client.Send(message)
+}
+
+func (t *tcpTransport) SendBatch(messages []*models.Message) error {
+	return t.client.SendBatch(messages)
+}
+
+func (t *tcpTransport) SendRaw(frame []byte) error { return t.client.SendRaw(frame) }
+
+func (t *tcpTransport) Stats() interface{} { return t.client.GetStats() }
+
+func (t *tcpTransport) Churn(downtime time.Duration) error { return t.client.Churn(downtime) }
+
+// Close закрывает соединение клиента
+func (t *tcpTransport) Close() error { return t.client.Disconnect() }
+
+// quicTransport отправка через QUIC клиент
+type quicTransport struct {
+	client *quic.QUICClient
+}
+
+// NewQUIC создает транспорт QUIC
+func NewQUIC(client *quic.QUICClient) Transport {
+	return &quicTransport{client: client}
+}
+
+func (t *quicTransport) Protocol() models.TestProtocol { return models.ProtocolQUIC }
+
+func (t *quicTransport) Connect() error { return t.client.Connect() }
+
+func (t *quicTransport) Connected() bool { return t.client.IsConnected() }
+
+func (t *quicTransport) Send(message *models.Message) error {
+	return t.client.Send(message)
+}
+
+func (t *quicTransport) SendBatch(messages []*models.Message) error {
+	return t.client.SendBatch(messages)
+}
+
+func (t *quicTransport) Stats() interface{} { return t.client.GetStats() }
+
+func (t *quicTransport) Churn(downtime time.Duration) error { return t.client.Churn(downtime) }
+
+// Close закрывает соединение клиента
+func (t *quicTransport) Close() error { return t.client.Disconnect() }
+
+// natsTransport отправка через NATS JetStream producer
+type natsTransport struct {
+	producer *broker.NATSProducer
+}
+
+// NewNATS создает транспорт NATS JetStream
+func NewNATS(producer *broker.NATSProducer) Transport {
+	return &natsTransport{producer: producer}
+}
+
+func (t *natsTransport) Protocol() models.TestProtocol { return models.ProtocolNATS }
+
+// Connect ничего не делает: producer переподключается при отправке
+func (t *natsTransport) Connect() error { return nil }
+
+func (t *natsTransport) Connected() bool { return t.producer.IsConnected() }
+
+func (t *natsTransport) Send(message *models.Message) error {
+	return t.producer.Publish(message)
+}
+
+func (t *natsTransport) SendBatch(messages []*models.Message) error {
+	return t.producer.PublishBatch(messages)
+}
+
+func (t *natsTransport) Stats() interface{} { return t.producer.GetStats() }
+
+// serialTransport отправка через последовательный порт
+type serialTransport struct {
+	client *serial.Client
+}
+
+// NewSerial создает транспорт последовательного порта
+func NewSerial(client *serial.Client) Transport {
+	return &serialTransport{client: client}
+}
+
+func (t *serialTransport) Protocol() models.TestProtocol { return models.ProtocolSerial }
+
+func (t *serialTransport) Connect() error { return t.client.Connect() }
+
+func (t *serialTransport) Connected() bool { return t.client.IsConnected() }
+
+func (t *serialTransport) Send(message *models.Message) error {
+	return t.client.Send(message)
+}
+
+func (t *serialTransport) SendBatch(messages []*models.Message) error {
+	return t.client.SendBatch(messages)
+}
+
+func (t *serialTransport) Stats() interface{} { return t.client.GetStats() }
+
+// Close закрывает порт клиента
+func (t *serialTransport) Close() error { return t.client.Disconnect() }
@@ -0,0 +1,124 @@
+package transport
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/infodiode/shared/models"
+)
+
+// Transport канал отправки сообщений теста через один протокол
+type Transport interface {
+	// Protocol возвращает протокол транспорта
+	Protocol() models.TestProtocol
+	// Connect подготавливает транспорт к отправке (подключается, если требуется)
+	Connect() error
+	// Connected сообщает, готов ли транспорт к отправке
+	Connected() bool
+	// Send отправляет одно сообщение
+	Send(message *models.Message) error
+	// SendBatch отправляет пакет сообщений
+	SendBatch(messages []*models.Message) error
+	// Stats возвращает статистику клиента протокола для /stats
+	Stats() interface{}
+}
+
+// Churner транспорт, соединение которого можно принудительно разорвать для проверки
+// переподключения; соединение восстанавливается не раньше чем через downtime
+type Churner interface {
+	Churn(downtime time.Duration) error
+}
+
+// RawSender транспорт, отправляющий готовые кадры без сериализации сообщений
+// (тест насыщения канала)
+type RawSender interface {
+	SendRaw(frame []byte) error
+}
+
+// Factory создает транспорт протокола к точке назначения target
+// (топик или адрес сервера); если транспорт держит соединение, он реализует io.Closer
+type Factory func(target string) (Transport, error)
+
+// Registry набор транспортов, доступных тестам
+type Registry struct {
+	mu         sync.RWMutex
+	transports map[models.TestProtocol]Transport
+	factories  map[models.TestProtocol]Factory
+}
+
+// NewRegistry создает пустой набор транспортов
+func NewRegistry() *Registry {
+	return &Registry{
+		transports: make(map[models.TestProtocol]Transport),
+		factories:  make(map[models.TestProtocol]Factory),
+	}
+}
+
+// Register добавляет транспорт, заменяя ранее зарегистрированный для того же протокола
+func (r *Registry) Register(t Transport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transports[t.Protocol()] = t
+}
+
+// Get возвращает транспорт протокола; пустой протокол означает MQTT
+func (r *Registry) Get(protocol models.TestProtocol) (Transport, error) {
+	if protocol == "" {
+		protocol = models.ProtocolMQTT
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.transports[protocol]
+	if !ok {
+		return nil, fmt.Errorf("транспорт %s не включен", protocol)
+	}
+	return t, nil
+}
+
+// RegisterFactory добавляет создание транспортов протокола к произвольной точке назначения
+func (r *Registry) RegisterFactory(protocol models.TestProtocol, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[protocol] = factory
+}
+
+// Open создает транспорт протокола к точке назначения target; закрывает его вызывающий
+func (r *Registry) Open(protocol models.TestProtocol, target string) (Transport, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[protocol]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("транспорт %s не поддерживает выбор точки назначения", protocol)
+	}
+	return factory(target)
+}
+
+// Protocols возвращает зарегистрированные протоколы в алфавитном порядке
+func (r *Registry) Protocols() []models.TestProtocol {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	protocols := make([]models.TestProtocol, 0, len(r.transports))
+	for protocol := range r.transports {
+		protocols = append(protocols, protocol)
+	}
+	sort.Slice(protocols, func(i, j int) bool { return protocols[i] < protocols[j] })
+	return protocols
+}
+
+// Stats возвращает статистику всех транспортов по протоколам
+func (r *Registry) Stats() map[models.TestProtocol]interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := make(map[models.TestProtocol]interface{}, len(r.transports))
+	for protocol, t := range r.transports {
+		stats[protocol] = t.Stats()
+	}
+	return stats
+}
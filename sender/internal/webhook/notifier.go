@@ -0,0 +1,250 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/shared/models"
+	"go.uber.org/zap"
+)
+
+// queueSize очередь событий каждого получателя; при переполнении события отбрасываются
+const queueSize = 100
+
+// События жизненного цикла теста
+const (
+	EventTestStarted     = "test.started"          // Тест запущен
+	EventTestCompleted   = "test.completed"        // Тест завершился по окончании длительности
+	EventTestStopped     = "test.stopped"          // Тест остановлен через API
+	EventTestFailed      = "test.failed"           // Тест завершился с ошибкой или прерван
+	EventAssertionBreach = "test.assertion_breach" // Нарушен порог прерывания теста
+)
+
+// Events все события, на которые можно подписать получателя
+var Events = []string{
+	EventTestStarted,
+	EventTestCompleted,
+	EventTestStopped,
+	EventTestFailed,
+	EventAssertionBreach,
+}
+
+// Target получатель уведомлений
+type Target struct {
+	URL        string
+	Headers    map[string]string
+	Events     []string      // События получателя (пусто - все)
+	Timeout    time.Duration // Таймаут одного запроса
+	Retries    int           // Повторов после неудачного запроса
+	RetryDelay time.Duration // Пауза перед повтором, удваивается с каждой попыткой
+}
+
+// Payload тело уведомления
+type Payload struct {
+	Event  string             `json:"event"`
+	Time   time.Time          `json:"time"`
+	TestID string             `json:"test_id"`
+	Reason string             `json:"reason,omitempty"` // Причина нарушения порога
+	Result *models.TestResult `json:"result"`
+}
+
+// TargetStats статистика отправки уведомлений получателю
+type TargetStats struct {
+	URL       string `json:"url"`
+	Delivered int64  `json:"delivered"`
+	Failed    int64  `json:"failed"`  // Уведомлений, не доставленных после всех повторов
+	Dropped   int64  `json:"dropped"` // Уведомлений, отброшенных при переполнении очереди
+	Retries   int64  `json:"retries"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// target получатель с очередью уведомлений
+type target struct {
+	config    Target
+	events    map[string]bool // nil - все события
+	client    *http.Client
+	queue     chan []byte
+	delivered atomic.Int64
+	failed    atomic.Int64
+	dropped   atomic.Int64
+	retries   atomic.Int64
+	lastError atomic.Value // string
+}
+
+// Notifier отправляет уведомления о событиях тестов получателям в фоне: каждый
+// получатель обрабатывает свою очередь, поэтому медленный получатель не задерживает
+// ни тесты, ни остальных получателей
+type Notifier struct {
+	targets []*target
+	logger  *zap.Logger
+	mu      sync.RWMutex
+	closed  bool // Очереди закрыты (под mu)
+	wg      sync.WaitGroup
+}
+
+// NewNotifier создает отправку уведомлений получателям targets
+func NewNotifier(targets []Target, logger *zap.Logger) *Notifier {
+	n := &Notifier{logger: logger.With(zap.String("component", "webhook"))}
+	for _, cfg := range targets {
+		t := &target{
+			config: cfg,
+			client: &http.Client{Timeout: cfg.Timeout},
+			queue:  make(chan []byte, queueSize),
+		}
+		if len(cfg.Events) > 0 {
+			t.events = make(map[string]bool, len(cfg.Events))
+			for _, event := range cfg.Events {
+				t.events[event] = true
+			}
+		}
+		n.targets = append(n.targets, t)
+	}
+	return n
+}
+
+// Start запускает отправку уведомлений
+func (n *Notifier) Start() {
+	for _, t := range n.targets {
+		n.logger.Info("Уведомления о тестах включены",
+			zap.String("url", t.config.URL),
+			zap.Strings("events", t.config.Events))
+
+		n.wg.Add(1)
+		go n.run(t)
+	}
+}
+
+// Notify ставит уведомление о событии теста в очереди получателей, подписанных на событие
+func (n *Notifier) Notify(event, reason string, result *models.TestResult) {
+	data, err := json.Marshal(Payload{
+		Event:  event,
+		Time:   time.Now(),
+		TestID: result.ID,
+		Reason: reason,
+		Result: result,
+	})
+	if err != nil {
+		n.logger.Error("Ошибка сериализации уведомления", zap.String("event", event), zap.Error(err))
+		return
+	}
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if n.closed {
+		return
+	}
+
+	for _, t := range n.targets {
+		if t.events != nil && !t.events[event] {
+			continue
+		}
+		select {
+		case t.queue <- data:
+		default:
+			t.dropped.Add(1)
+			n.logger.Warn("Очередь уведомлений переполнена, уведомление отброшено",
+				zap.String("url", t.config.URL),
+				zap.String("event", event),
+				zap.String("test_id", result.ID))
+		}
+	}
+}
+
+// run отправляет уведомления из очереди получателя до ее закрытия
+func (n *Notifier) run(t *target) {
+	defer n.wg.Done()
+
+	for data := range t.queue {
+		if err := n.deliver(t, data); err != nil {
+			t.failed.Add(1)
+			t.lastError.Store(err.Error())
+			n.logger.Warn("Уведомление не доставлено",
+				zap.String("url", t.config.URL),
+				zap.Int("retries", t.config.Retries),
+				zap.Error(err))
+			continue
+		}
+		t.delivered.Add(1)
+	}
+}
+
+// deliver отправляет уведомление с повторами
+func (n *Notifier) deliver(t *target, data []byte) error {
+	delay := t.config.RetryDelay
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = t.post(data); err == nil {
+			return nil
+		}
+		if attempt >= t.config.Retries {
+			return err
+		}
+
+		t.retries.Add(1)
+		n.logger.Debug("Повтор отправки уведомления",
+			zap.String("url", t.config.URL),
+			zap.Int("attempt", attempt+1),
+			zap.Error(err))
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// post выполняет запрос POST с уведомлением
+func (t *target) post(data []byte) error {
+	req, err := http.NewRequest(http.MethodPost, t.config.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("получатель ответил статусом %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Stats возвращает статистику отправки по получателям
+func (n *Notifier) Stats() []TargetStats {
+	stats := make([]TargetStats, 0, len(n.targets))
+	for _, t := range n.targets {
+		lastError, _ := t.lastError.Load().(string)
+		stats = append(stats, TargetStats{
+			URL:       t.config.URL,
+			Delivered: t.delivered.Load(),
+			Failed:    t.failed.Load(),
+			Dropped:   t.dropped.Load(),
+			Retries:   t.retries.Load(),
+			LastError: lastError,
+		})
+	}
+	return stats
+}
+
+// Close отправляет уведомления, оставшиеся в очередях, и останавливает отправку
+func (n *Notifier) Close() {
+	n.mu.Lock()
+	n.closed = true
+	for _, t := range n.targets {
+		close(t.queue)
+	}
+	n.mu.Unlock()
+
+	n.wg.Wait()
+}
@@ -0,0 +1,290 @@
+package actionlog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Формат журнала: JSON строки, по одной на действие, только дописываются в конец файла.
+// Каждая запись содержит hash - sha256 от hash предыдущей записи и самой записи без hash,
+// поэтому изменение или удаление записи в середине журнала обнаруживается при открытии
+const maxLineSize = 1024 * 1024
+
+// ActorSystem исполнитель действий, выполняемых сервисом без запроса API
+const ActorSystem = "system"
+
+// Event запись журнала действий
+type Event struct {
+	Seq        int64             `json:"seq"`                   // Номер записи (продолжается после перезапуска)
+	Time       time.Time         `json:"time"`                  // Время действия (UTC)
+	Actor      string            `json:"actor"`                 // Заголовок X-Actor, адрес клиента или system
+	RemoteAddr string            `json:"remote_addr,omitempty"` // Адрес клиента API
+	Action     string            `json:"action"`                // Метод и путь запроса или действие сервиса (config.reload)
+	Query      string            `json:"query,omitempty"`       // Параметры строки запроса
+	Params     json.RawMessage   `json:"params,omitempty"`      // Тело запроса JSON
+	ParamsSize int64             `json:"params_size,omitempty"` // Размер тела, не сохраненного в params (не JSON или больше предела)
+	Status     int               `json:"status,omitempty"`      // HTTP статус ответа
+	Error      string            `json:"error,omitempty"`       // Ошибка из ответа или выполнения действия
+	Details    map[string]string `json:"details,omitempty"`     // Сведения о результате (test_id, измененные разделы)
+	Hash       string            `json:"hash,omitempty"`        // sha256 hash предыдущей записи и этой записи без hash
+}
+
+// Status состояние журнала
+type Status struct {
+	File        string `json:"file"`
+	Events      int64  `json:"events"`                // Записей в журнале
+	ChainValid  bool   `json:"chain_valid"`           // Цепочка hash записей не нарушена
+	ChainError  string `json:"chain_error,omitempty"` // Первое нарушение цепочки, найденное при открытии
+	WriteErrors int64  `json:"write_errors"`          // Действий, которые не удалось записать
+}
+
+// Query отбор записей журнала
+type Query struct {
+	Since  time.Time // Не раньше момента (нулевое - без ограничения)
+	Actor  string    // Исполнитель (пусто - любой)
+	Action string    // Начало действия, например "POST /test" (пусто - любое)
+	TestID string    // Тест из details.test_id (пусто - любой)
+	Limit  int       // Последних записей (0 - все)
+}
+
+// Log журнал действий в файле
+type Log struct {
+	path string
+
+	mu          sync.Mutex
+	file        *os.File
+	seq         int64
+	last        string // hash последней записи
+	chainErr    string
+	writeErrors int64
+}
+
+// Open открывает журнал path для дописывания, создавая файл и директорию при
+// отсутствии. Существующие записи проверяются: номер и цепочка hash продолжаются
+// с последней записи, нарушение цепочки выводится в Status
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("ошибка создания директории журнала действий: %w", err)
+	}
+
+	l := &Log{path: path}
+	if err := l.recover(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия журнала действий: %w", err)
+	}
+	l.file = file
+	return l, nil
+}
+
+// recover читает существующие записи журнала и проверяет цепочку hash
+func (l *Log) recover() error {
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка чтения журнала действий: %w", err)
+	}
+	defer file.Close()
+
+	line := 0
+	err = scanLines(file, func(data []byte) {
+		line++
+		var event Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			l.breakChain(fmt.Sprintf("строка %d: некорректная запись: %v", line, err))
+			return
+		}
+
+		hash, err := eventHash(l.last, event)
+		switch {
+		case err != nil:
+			l.breakChain(fmt.Sprintf("строка %d: %v", line, err))
+		case hash != event.Hash:
+			l.breakChain(fmt.Sprintf("строка %d (seq %d): hash не совпадает с цепочкой", line, event.Seq))
+		case event.Seq != l.seq+1:
+			l.breakChain(fmt.Sprintf("строка %d: seq %d после %d", line, event.Seq, l.seq))
+		}
+		// Цепочка продолжается от записи, даже если она нарушена
+		l.seq = max(l.seq, event.Seq)
+		l.last = event.Hash
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка чтения журнала действий: %w", err)
+	}
+	return nil
+}
+
+// breakChain запоминает первое нарушение цепочки
+func (l *Log) breakChain(reason string) {
+	if l.chainErr == "" {
+		l.chainErr = reason
+	}
+}
+
+// Record дописывает действие event в журнал, заполняя номер, время и hash.
+// Для nil журнала ничего не делает
+func (l *Log) Record(event Event) error {
+	if l == nil {
+		return nil
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.Time = event.Time.UTC()
+	if len(event.Params) > 0 {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, event.Params); err != nil {
+			event.ParamsSize = int64(len(event.Params))
+			event.Params = nil
+		} else {
+			event.Params = compact.Bytes()
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	event.Seq = l.seq + 1
+	hash, err := eventHash(l.last, event)
+	if err != nil {
+		l.writeErrors++
+		return fmt.Errorf("ошибка записи журнала действий: %w", err)
+	}
+	event.Hash = hash
+
+	data, err := json.Marshal(event)
+	if err == nil {
+		data = append(data, '\n')
+		if _, err = l.file.Write(data); err == nil {
+			err = l.file.Sync()
+		}
+	}
+	if err != nil {
+		l.writeErrors++
+		return fmt.Errorf("ошибка записи журнала действий: %w", err)
+	}
+
+	l.seq = event.Seq
+	l.last = hash
+	return nil
+}
+
+// Query возвращает записи журнала, удовлетворяющие q, в порядке записи
+func (l *Log) Query(q Query) ([]Event, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения журнала действий: %w", err)
+	}
+	defer file.Close()
+
+	events := []Event{}
+	err = scanLines(file, func(data []byte) {
+		var event Event
+		if json.Unmarshal(data, &event) != nil || !q.match(event) {
+			return
+		}
+		events = append(events, event)
+		if q.Limit > 0 && len(events) > 2*q.Limit {
+			events = append(events[:0], events[len(events)-q.Limit:]...)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения журнала действий: %w", err)
+	}
+
+	if q.Limit > 0 && len(events) > q.Limit {
+		events = events[len(events)-q.Limit:]
+	}
+	return events, nil
+}
+
+// match проверяет, удовлетворяет ли запись отбору
+func (q Query) match(event Event) bool {
+	switch {
+	case !q.Since.IsZero() && event.Time.Before(q.Since):
+		return false
+	case q.Actor != "" && event.Actor != q.Actor:
+		return false
+	case q.Action != "" && !strings.HasPrefix(event.Action, q.Action):
+		return false
+	case q.TestID != "" && !hasTestID(event.Details["test_id"], q.TestID):
+		return false
+	}
+	return true
+}
+
+// hasTestID проверяет, входит ли тест в список тестов действия через запятую
+func hasTestID(list, testID string) bool {
+	for id := range strings.SplitSeq(list, ",") {
+		if id == testID {
+			return true
+		}
+	}
+	return false
+}
+
+// Status возвращает состояние журнала
+func (l *Log) Status() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return Status{
+		File:        l.path,
+		Events:      l.seq,
+		ChainValid:  l.chainErr == "",
+		ChainError:  l.chainErr,
+		WriteErrors: l.writeErrors,
+	}
+}
+
+// Close закрывает файл журнала
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// eventHash вычисляет hash записи: sha256 от hash предыдущей записи и JSON записи без hash
+func eventHash(prev string, event Event) (string, error) {
+	event.Hash = ""
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(prev))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// scanLines вызывает handle для каждой непустой строки файла
+func scanLines(file *os.File, handle func([]byte)) error {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) > 0 {
+			handle(scanner.Bytes())
+		}
+	}
+	return scanner.Err()
+}
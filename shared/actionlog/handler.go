@@ -0,0 +1,218 @@
+package actionlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode"
+
+	"go.uber.org/zap"
+)
+
+// ActorHeader заголовок запроса с именем исполнителя действия (оператор или система
+// автоматизации); без него исполнителем считается адрес клиента
+const ActorHeader = "X-Actor"
+
+// Пределы сохраняемых данных запроса и ответа
+const (
+	maxParamsSize = 64 * 1024 // Тело запроса в params
+	maxErrorBody  = 4096      // Тело ответа с ошибкой
+	maxActorLen   = 128
+)
+
+// details сведения о результате действия, заполняемые обработчиком запроса
+type details struct {
+	mu     sync.Mutex
+	values map[string]string
+	skip   bool // Запрос не записывается в журнал
+}
+
+type detailsKey struct{}
+
+// Annotate добавляет к записи журнала о запросе с контекстом ctx сведение key (например,
+// test_id запущенного теста). Вне запроса, записываемого в журнал, ничего не делает
+func Annotate(ctx context.Context, key, value string) {
+	d, ok := ctx.Value(detailsKey{}).(*details)
+	if !ok {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.values == nil {
+		d.values = make(map[string]string)
+	}
+	d.values[key] = value
+}
+
+// Skip исключает из журнала запрос с контекстом ctx (например, периодическое
+// подтверждение регистрации). Вне запроса, записываемого в журнал, ничего не делает
+func Skip(ctx context.Context) {
+	d, ok := ctx.Value(detailsKey{}).(*details)
+	if !ok {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.skip = true
+}
+
+// Handler записывает в журнал log запросы next, изменяющие состояние (POST, PUT, PATCH,
+// DELETE): исполнителя, путь, тело запроса, статус и ошибку ответа. Для nil журнала
+// возвращает next без изменений
+func Handler(log *Log, logger *zap.Logger, next http.Handler) http.Handler {
+	if log == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		event := Event{
+			Actor:      requestActor(r),
+			RemoteAddr: remoteHost(r),
+			Action:     r.Method + " " + r.URL.Path,
+			Query:      r.URL.RawQuery,
+		}
+		event.Params, event.ParamsSize = readParams(r)
+
+		d := &details{}
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), detailsKey{}, d)))
+
+		event.Status = recorder.status
+		if recorder.status >= http.StatusBadRequest {
+			event.Error = responseError(recorder.body.Bytes())
+		}
+		d.mu.Lock()
+		event.Details = d.values
+		skip := d.skip
+		d.mu.Unlock()
+		if skip {
+			return
+		}
+
+		if err := log.Record(event); err != nil {
+			logger.Error("Ошибка записи действия в журнал",
+				zap.String("action", event.Action),
+				zap.String("actor", event.Actor),
+				zap.Error(err))
+		}
+	})
+}
+
+// readParams читает тело запроса для журнала и восстанавливает его для обработчика.
+// Тело сохраняется, если это JSON не больше maxParamsSize; иначе возвращается его размер
+func readParams(r *http.Request) (json.RawMessage, int64) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, 0
+	}
+
+	head, err := io.ReadAll(io.LimitReader(r.Body, maxParamsSize+1))
+	body := io.MultiReader(bytes.NewReader(head), r.Body)
+	r.Body = readCloser{Reader: body, Closer: r.Body}
+	if err != nil || len(head) == 0 {
+		return nil, 0
+	}
+
+	if len(head) > maxParamsSize || !json.Valid(head) {
+		size := r.ContentLength
+		if size < 0 {
+			size = int64(len(head))
+		}
+		return nil, size
+	}
+	return head, 0
+}
+
+// readCloser тело запроса, восстановленное после чтения для журнала
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// requestActor возвращает исполнителя действия: X-Actor или адрес клиента
+func requestActor(r *http.Request) string {
+	actor := strings.Map(func(c rune) rune {
+		if unicode.IsControl(c) {
+			return -1
+		}
+		return c
+	}, strings.TrimSpace(r.Header.Get(ActorHeader)))
+	if actor == "" {
+		return remoteHost(r)
+	}
+	if runes := []rune(actor); len(runes) > maxActorLen {
+		actor = string(runes[:maxActorLen])
+	}
+	return actor
+}
+
+// remoteHost возвращает адрес клиента без порта
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// responseError извлекает поле error из JSON ответа с ошибкой
+func responseError(body []byte) string {
+	var response struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &response) == nil && response.Error != "" {
+		return response.Error
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// responseRecorder запоминает статус ответа и начало тела для извлечения ошибки
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+// WriteHeader запоминает статус ответа
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write запоминает начало тела ответа
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	if remaining := maxErrorBody - r.body.Len(); remaining > 0 {
+		r.body.Write(data[:min(len(data), remaining)])
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+// Flush передает буферизованные данные клиенту, если это поддерживает ResponseWriter
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
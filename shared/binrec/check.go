@@ -0,0 +1,91 @@
+package binrec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+)
+
+// Check проверяет запись по раскладке: размер, значения const, диапазоны числовых
+// полей, корректность CP56Time2a и контрольную сумму crc16. Поля bytes и timestamp
+// не проверяются - их значения произвольны
+func (l *Layout) Check(record []byte) error {
+	if size := l.RecordSize(); len(record) != size {
+		return fmt.Errorf("размер записи %d байт, ожидалось %d", len(record), size)
+	}
+
+	order := l.Order()
+	offset := 0
+	for i := range l.Fields {
+		field := &l.Fields[i]
+		value := record[offset : offset+field.Size()]
+		if err := checkField(field, value, record[:offset], order); err != nil {
+			return fmt.Errorf("поле %s (смещение %d): %w", field.Name, offset, err)
+		}
+		offset += len(value)
+	}
+
+	return nil
+}
+
+// checkField проверяет значение поля; preceding - байты записи перед полем (для crc16)
+func checkField(field *Field, value, preceding []byte, order ByteOrder) error {
+	switch field.Type {
+	case TypeUint8:
+		return checkInt(field, int64(value[0]))
+	case TypeUint16:
+		return checkInt(field, int64(order.Uint16(value)))
+	case TypeUint32:
+		return checkInt(field, int64(order.Uint32(value)))
+	case TypeInt16:
+		return checkInt(field, int64(int16(order.Uint16(value))))
+	case TypeInt32:
+		return checkInt(field, int64(int32(order.Uint32(value))))
+	case TypeFloat32:
+		// Границы приводятся к float32, так как отправитель округляет значение до float32
+		v := math.Float32frombits(order.Uint32(value))
+		if v != v || v < float32(field.Min) || v > float32(field.Max) {
+			return fmt.Errorf("значение %g вне диапазона [%g, %g]", v, field.Min, field.Max)
+		}
+	case TypeFloat64:
+		v := math.Float64frombits(order.Uint64(value))
+		if v != v || v < field.Min || v > field.Max {
+			return fmt.Errorf("значение %g вне диапазона [%g, %g]", v, field.Min, field.Max)
+		}
+	case TypeConst:
+		expected, _ := hex.DecodeString(field.Value)
+		if !bytes.Equal(value, expected) {
+			return fmt.Errorf("значение %x, ожидалось %s", value, field.Value)
+		}
+	case TypeCP56Time2a:
+		return checkCP56Time2a(value)
+	case TypeCRC16:
+		if crc, expected := binary.LittleEndian.Uint16(value), CRC16Modbus(preceding); crc != expected {
+			return fmt.Errorf("контрольная сумма %04x, ожидалось %04x", crc, expected)
+		}
+	}
+
+	return nil
+}
+
+// checkInt проверяет попадание целого значения в диапазон [min, max]
+func checkInt(field *Field, v int64) error {
+	if v < int64(field.Min) || v > int64(field.Max) {
+		return fmt.Errorf("значение %d вне диапазона [%g, %g]", v, field.Min, field.Max)
+	}
+	return nil
+}
+
+// checkCP56Time2a проверяет допустимость составляющих времени CP56Time2a
+// (служебные биты IV, SU и день недели не проверяются)
+func checkCP56Time2a(value []byte) error {
+	ms := binary.LittleEndian.Uint16(value)
+	minute, hour := value[2]&0x3F, value[3]&0x1F
+	day, month, year := value[4]&0x1F, value[5]&0x0F, value[6]&0x7F
+	if ms >= 60000 || minute > 59 || hour > 23 || day < 1 || month < 1 || month > 12 || year > 99 {
+		return fmt.Errorf("некорректное время CP56Time2a %x", value)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package binrec
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+)
+
+// Раскладка двоичной записи фиксированной ширины (кадры Modbus, IEC-104).
+// Отправитель формирует записи по раскладке data.binary, получатель проверяет
+// их по той же раскладке validation.binary
+
+// Типы полей двоичной записи
+const (
+	TypeUint8      = "uint8"
+	TypeUint16     = "uint16"
+	TypeUint32     = "uint32"
+	TypeInt16      = "int16"
+	TypeInt32      = "int32"
+	TypeFloat32    = "float32"
+	TypeFloat64    = "float64"
+	TypeBytes      = "bytes"
+	TypeConst      = "const"
+	TypeTimestamp  = "timestamp"
+	TypeCP56Time2a = "cp56time2a"
+	TypeCRC16      = "crc16"
+)
+
+// Layout раскладка двоичной записи
+type Layout struct {
+	ByteOrder string  `mapstructure:"byte_order" json:"byte_order"` // Порядок байтов числовых полей: big или little
+	Fields    []Field `mapstructure:"fields" json:"fields"`         // Поля записи в порядке следования
+}
+
+// Field описание поля двоичной записи
+type Field struct {
+	Name   string  `mapstructure:"name" json:"name"`               // Имя поля (для сообщений об ошибках)
+	Type   string  `mapstructure:"type" json:"type"`               // Тип поля (uint8, uint16, uint32, int16, int32, float32, float64, bytes, const, timestamp, cp56time2a, crc16)
+	Rule   string  `mapstructure:"rule" json:"rule,omitempty"`     // Правило генерации целых: random (по умолчанию) или sequence
+	Min    float64 `mapstructure:"min" json:"min,omitempty"`       // Минимальное значение числового поля
+	Max    float64 `mapstructure:"max" json:"max,omitempty"`       // Максимальное значение числового поля
+	Length int     `mapstructure:"length" json:"length,omitempty"` // Длина поля bytes, байт
+	Value  string  `mapstructure:"value" json:"value,omitempty"`   // Значение поля const в hex, например 0103
+}
+
+// fieldSizes размеры полей фиксированной ширины, байт
+var fieldSizes = map[string]int{
+	TypeUint8:      1,
+	TypeUint16:     2,
+	TypeUint32:     4,
+	TypeInt16:      2,
+	TypeInt32:      4,
+	TypeFloat32:    4,
+	TypeFloat64:    8,
+	TypeTimestamp:  8,
+	TypeCP56Time2a: 7,
+	TypeCRC16:      2,
+}
+
+// ByteOrder порядок байтов для чтения и записи числовых полей
+type ByteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// Size возвращает размер поля, байт
+func (f *Field) Size() int {
+	switch f.Type {
+	case TypeBytes:
+		return f.Length
+	case TypeConst:
+		return len(f.Value) / 2
+	default:
+		return fieldSizes[f.Type]
+	}
+}
+
+// Enabled сообщает, задана ли раскладка
+func (l *Layout) Enabled() bool {
+	return len(l.Fields) > 0
+}
+
+// RecordSize возвращает размер записи, байт
+func (l *Layout) RecordSize() int {
+	size := 0
+	for i := range l.Fields {
+		size += l.Fields[i].Size()
+	}
+	return size
+}
+
+// Order возвращает порядок байтов числовых полей
+func (l *Layout) Order() ByteOrder {
+	if l.ByteOrder == "little" {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// Validate проверяет раскладку; prefix - ключ конфигурации для сообщений об ошибках
+func (l *Layout) Validate(prefix string) error {
+	if !l.Enabled() {
+		return nil
+	}
+
+	switch l.ByteOrder {
+	case "big", "little":
+	default:
+		return fmt.Errorf("некорректный порядок байтов %s.byte_order: %s (допустимо big или little)", prefix, l.ByteOrder)
+	}
+
+	for i := range l.Fields {
+		field := &l.Fields[i]
+		if field.Name == "" {
+			return fmt.Errorf("%s.fields[%d]: не указано имя поля", prefix, i)
+		}
+		path := prefix + "." + field.Name
+
+		switch field.Type {
+		case TypeUint8, TypeUint16, TypeUint32, TypeInt16, TypeInt32:
+			if field.Rule != "" && field.Rule != "random" && field.Rule != "sequence" {
+				return fmt.Errorf("%s: неизвестное правило генерации %s", path, field.Rule)
+			}
+			if field.Min > field.Max {
+				return fmt.Errorf("%s: min больше max", path)
+			}
+			if min, max := intRange(field.Type); field.Min < min || field.Max > max {
+				return fmt.Errorf("%s: диапазон [%g, %g] выходит за пределы типа %s", path, field.Min, field.Max, field.Type)
+			}
+		case TypeFloat32, TypeFloat64:
+			if field.Min > field.Max {
+				return fmt.Errorf("%s: min больше max", path)
+			}
+		case TypeBytes:
+			if field.Length <= 0 {
+				return fmt.Errorf("%s: для bytes необходимо указать length", path)
+			}
+		case TypeConst:
+			if _, err := hex.DecodeString(field.Value); err != nil || field.Value == "" {
+				return fmt.Errorf("%s: value должно быть непустой hex строкой", path)
+			}
+		case TypeTimestamp, TypeCP56Time2a, TypeCRC16:
+		default:
+			return fmt.Errorf("%s: неизвестный тип поля %s", path, field.Type)
+		}
+	}
+
+	return nil
+}
+
+// intRange возвращает диапазон значений целого типа
+func intRange(fieldType string) (float64, float64) {
+	switch fieldType {
+	case TypeUint8:
+		return 0, math.MaxUint8
+	case TypeUint16:
+		return 0, math.MaxUint16
+	case TypeUint32:
+		return 0, math.MaxUint32
+	case TypeInt16:
+		return math.MinInt16, math.MaxInt16
+	default:
+		return math.MinInt32, math.MaxInt32
+	}
+}
+
+// CRC16Modbus вычисляет CRC-16/MODBUS (полином 0xA001, начальное значение 0xFFFF)
+func CRC16Modbus(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,155 @@
+// Package client типизированный клиент HTTP API sender и recipient. Описание API
+// выдается сервисами в /openapi.json; клиент покрывает операции, которые используют
+// оркестрация тестов, согласованные тесты нескольких sender и интеграционные проверки
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/infodiode/shared/models"
+)
+
+// DefaultTimeout таймаут запросов клиента, созданного без http.Client
+const DefaultTimeout = 5 * time.Second
+
+// StatusError ответ API с кодом, отличным от ожидаемого
+type StatusError struct {
+	Code    int
+	Message string // Поле error ответа
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("статус %d: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("статус %d", e.Code)
+}
+
+// StatusCode возвращает код ответа API из ошибки запроса (0 - запрос не выполнен)
+func StatusCode(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code
+	}
+	return 0
+}
+
+// IsNotFound проверяет, что сервис ответил 404: ресурс не найден или функция отключена
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// base общая часть клиентов: адрес сервиса и выполнение JSON запросов
+type base struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// newBase создает общую часть клиента; без httpClient используется клиент с DefaultTimeout
+func newBase(baseURL string, httpClient *http.Client) base {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	return base{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// BaseURL возвращает адрес сервиса
+func (b *base) BaseURL() string {
+	return b.baseURL
+}
+
+// OpenAPI запрашивает описание API сервиса (OpenAPI 3)
+func (b *base) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	var document json.RawMessage
+	if err := b.do(ctx, http.MethodGet, "/openapi.json", nil, nil, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// do выполняет запрос к API с параметрами query и JSON телом body (nil - без тела)
+// и декодирует JSON ответ в target (nil - ответ не разбирается). Ответ с кодом,
+// отличным от 2xx, возвращается как *StatusError; ответ с кодом из decodeOn при
+// этом также декодируется в target (например, 503 /health с состоянием проверок)
+func (b *base) do(ctx context.Context, method, path string, query url.Values, body, target any, decodeOn ...int) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("ошибка сериализации запроса: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	requestURL := b.baseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, reader)
+	if err != nil {
+		return fmt.Errorf("ошибка формирования запроса: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса %s: %w", requestURL, err)
+	}
+	defer resp.Body.Close()
+
+	var statusErr error
+	if slices.Contains(decodeOn, resp.StatusCode) {
+		statusErr = &StatusError{Code: resp.StatusCode}
+	} else if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Ошибки API возвращаются в поле error
+		var failure struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		_ = json.Unmarshal(data, &failure)
+		return &StatusError{Code: resp.StatusCode, Message: failure.Error}
+	}
+
+	if target == nil {
+		return statusErr
+	}
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("ошибка разбора ответа %s: %w", requestURL, err)
+	}
+	return statusErr
+}
+
+// health запрашивает состояние сервиса (/health или /healthz); при неработоспособном
+// сервисе (503) возвращает состояние проверок вместе с *StatusError
+func health(ctx context.Context, b *base, path string) (*models.HealthStatus, error) {
+	status := &models.HealthStatus{}
+	err := b.do(ctx, http.MethodGet, path, nil, nil, status, http.StatusServiceUnavailable)
+	if err != nil && StatusCode(err) != http.StatusServiceUnavailable {
+		return nil, err
+	}
+	return status, err
+}
+
+// windowQuery возвращает параметры запроса /stats с окном показателей (0 - без окна)
+func windowQuery(window time.Duration) url.Values {
+	if window <= 0 {
+		return nil
+	}
+	return url.Values{"window": {window.String()}}
+}
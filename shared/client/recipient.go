@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/infodiode/shared/models"
+)
+
+// Recipient клиент HTTP API recipient
+type Recipient struct {
+	base
+}
+
+// NewRecipient создает клиент API recipient по адресу baseURL (http://host:port);
+// httpClient задает таймаут и TLS (nil - клиент с DefaultTimeout)
+func NewRecipient(baseURL string, httpClient *http.Client) *Recipient {
+	return &Recipient{base: newBase(baseURL, httpClient)}
+}
+
+// ProcessorStats статистика обработчика recipient
+type ProcessorStats struct {
+	MessagesReceived   int64     `json:"messages_received"`
+	MessagesProcessed  int64     `json:"messages_processed"`
+	MessagesValid      int64     `json:"messages_valid"`
+	MessagesInvalid    int64     `json:"messages_invalid"`
+	ChecksumErrors     int64     `json:"checksum_errors"`
+	ProcessingErrors   int64     `json:"processing_errors"`
+	PayloadErrors      int64     `json:"payload_errors"`
+	IntegrityErrors    int64     `json:"integrity_errors"`
+	MessagesStale      int64     `json:"messages_stale"`
+	TotalBytesReceived int64     `json:"total_bytes_received"`
+	AvgMessageSize     int64     `json:"avg_message_size"`
+	MinLatency         float64   `json:"min_latency_ms"`
+	MaxLatency         float64   `json:"max_latency_ms"`
+	AvgLatency         float64   `json:"avg_latency_ms"`
+	Throughput         float64   `json:"throughput_msg_per_sec"`
+	FirstMessageTime   time.Time `json:"first_message_time"`
+	LastMessageTime    time.Time `json:"last_message_time"`
+}
+
+// ConsumerStats статистика consumer брокера recipient
+type ConsumerStats struct {
+	MessagesReceived int64   `json:"messages_received"`
+	BytesReceived    int64   `json:"bytes_received"`
+	Errors           int64   `json:"errors"`
+	ReconnectCount   int32   `json:"reconnect_count"`
+	Connected        bool    `json:"connected"`
+	Subscribed       bool    `json:"subscribed"`
+	SubscribeErrors  int64   `json:"subscribe_errors"`
+	GrantedQoS       *int    `json:"granted_qos,omitempty"` // QoS подписки на основной топик (нет - подписки нет или consumer не MQTT)
+	UptimeSeconds    float64 `json:"uptime_seconds"`
+	AvgMessageSize   int64   `json:"avg_message_size"`
+	InFlight         int64   `json:"inflight"`
+	InFlightLimit    int     `json:"inflight_limit"`
+	Throttled        int64   `json:"throttled"`
+	Retained         int64   `json:"retained"`          // Сохраненных брокером сообщений (флаг retain)
+	Duplicates       int64   `json:"duplicate_flagged"` // Повторных доставок (флаг DUP)
+	Will             int64   `json:"will"`              // Сообщений last will
+	Batches          int64   `json:"batches"`           // Пакетов сообщений в одном сообщении брокера
+}
+
+// RecipientStats основные разделы ответа GET /stats recipient; полный состав - в /openapi.json
+type RecipientStats struct {
+	Processor ProcessorStats      `json:"processor"`        // С запуска процесса или сброса статистики
+	Consumer  ConsumerStats       `json:"consumer"`         // MQTT consumer
+	Window    *models.WindowStats `json:"window,omitempty"` // Показатели за окно (при запросе с окном)
+}
+
+// Health запрашивает состояние recipient; неработоспособный recipient отвечает 503 с тем же
+// телом, поэтому состояние возвращается вместе с *StatusError
+func (r *Recipient) Health(ctx context.Context) (*models.HealthStatus, error) {
+	return health(ctx, &r.base, "/health")
+}
+
+// DeepHealth запрашивает состояние recipient с проверкой получения пробных сообщений
+// sender (/healthz); при отключенных пробах возвращается ошибка с кодом 404 (IsNotFound)
+func (r *Recipient) DeepHealth(ctx context.Context) (*models.HealthStatus, error) {
+	return health(ctx, &r.base, "/healthz")
+}
+
+// Version запрашивает сведения о сборке и конфигурации recipient
+func (r *Recipient) Version(ctx context.Context) (*models.VersionInfo, error) {
+	info := &models.VersionInfo{}
+	if err := r.do(ctx, http.MethodGet, "/version", nil, nil, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Stats запрашивает статистику recipient; window > 0 добавляет показатели за последние window
+func (r *Recipient) Stats(ctx context.Context, window time.Duration) (*RecipientStats, error) {
+	stats := &RecipientStats{}
+	if err := r.do(ctx, http.MethodGet, "/stats", windowQuery(window), nil, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ResetStats сбрасывает статистику recipient и возвращает статистику после сброса
+func (r *Recipient) ResetStats(ctx context.Context) (*RecipientStats, error) {
+	stats := &RecipientStats{}
+	if err := r.do(ctx, http.MethodPost, "/admin/reset-stats", nil, nil, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Sessions запрашивает отчеты тестов с отбором по меткам tenant и runLabel (пусто - без отбора)
+func (r *Recipient) Sessions(ctx context.Context, tenant, runLabel string) ([]*models.SessionReport, error) {
+	query := url.Values{}
+	if tenant != "" {
+		query.Set("tenant", tenant)
+	}
+	if runLabel != "" {
+		query.Set("run_label", runLabel)
+	}
+
+	var reports []*models.SessionReport
+	if err := r.do(ctx, http.MethodGet, "/sessions", query, nil, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// Session запрашивает отчет о полноте доставки сообщений теста; если recipient
+// не получил ни одного сообщения теста, возвращается ошибка с кодом 404 (IsNotFound)
+func (r *Recipient) Session(ctx context.Context, testID string) (*models.SessionReport, error) {
+	report := &models.SessionReport{}
+	if err := r.do(ctx, http.MethodGet, "/sessions/"+url.PathEscape(testID), nil, nil, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Timeline запрашивает посекундную динамику приема сообщений теста (404 - сообщения не получены)
+func (r *Recipient) Timeline(ctx context.Context, testID string) ([]models.ReceivePoint, error) {
+	var points []models.ReceivePoint
+	if err := r.do(ctx, http.MethodGet, "/sessions/"+url.PathEscape(testID)+"/timeline", nil, nil, &points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// FileTransfer запрашивает отчет о приеме файла (404 - не получено ни одной части файла)
+func (r *Recipient) FileTransfer(ctx context.Context, transferID string) (*models.FileTransferReport, error) {
+	report := &models.FileTransferReport{}
+	if err := r.do(ctx, http.MethodGet, "/files/"+url.PathEscape(transferID), nil, nil, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Resubscribe запрашивает повторную подписку MQTT consumer, при которой брокер
+// доставляет сохраненные retained сообщения; возвращает статистику consumer
+func (r *Recipient) Resubscribe(ctx context.Context) (*ConsumerStats, error) {
+	stats := &ConsumerStats{}
+	if err := r.do(ctx, http.MethodPost, "/mqtt/resubscribe", nil, nil, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/infodiode/shared/models"
+)
+
+// Sender клиент HTTP API sender
+type Sender struct {
+	base
+}
+
+// NewSender создает клиент API sender по адресу baseURL (http://host:port);
+// httpClient задает таймаут и TLS (nil - клиент с DefaultTimeout)
+func NewSender(baseURL string, httpClient *http.Client) *Sender {
+	return &Sender{base: newBase(baseURL, httpClient)}
+}
+
+// TestStarted ответ на запуск теста
+type TestStarted struct {
+	Status string             `json:"status"`
+	TestID string             `json:"test_id"`
+	Config *models.TestConfig `json:"config"`
+}
+
+// TestStopped ответ на остановку тестов
+type TestStopped struct {
+	Status  string   `json:"status"`
+	Stopped []string `json:"stopped"` // Идентификаторы остановленных тестов
+}
+
+// SenderStats основные разделы ответа GET /stats sender; полный состав - в /openapi.json
+type SenderStats struct {
+	Test        *models.TestStats   `json:"test"`             // Статистика последнего запущенного теста
+	Active      bool                `json:"active"`           // Выполняется ли хотя бы один тест
+	CurrentTest string              `json:"current_test"`     // Тип последнего запущенного из выполняющихся тестов
+	Window      *models.WindowStats `json:"window,omitempty"` // Показатели за окно (при запросе с окном)
+}
+
+// Health запрашивает состояние sender; неработоспособный sender отвечает 503 с тем же
+// телом, поэтому состояние возвращается вместе с *StatusError
+func (s *Sender) Health(ctx context.Context) (*models.HealthStatus, error) {
+	return health(ctx, &s.base, "/health")
+}
+
+// DeepHealth запрашивает состояние sender с проверкой публикации пробных сообщений
+// (/healthz); при отключенных пробах возвращается ошибка с кодом 404 (IsNotFound)
+func (s *Sender) DeepHealth(ctx context.Context) (*models.HealthStatus, error) {
+	return health(ctx, &s.base, "/healthz")
+}
+
+// Version запрашивает сведения о сборке и конфигурации sender
+func (s *Sender) Version(ctx context.Context) (*models.VersionInfo, error) {
+	info := &models.VersionInfo{}
+	if err := s.do(ctx, http.MethodGet, "/version", nil, nil, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Stats запрашивает статистику sender; window > 0 добавляет показатели за последние window
+func (s *Sender) Stats(ctx context.Context, window time.Duration) (*SenderStats, error) {
+	stats := &SenderStats{}
+	if err := s.do(ctx, http.MethodGet, "/stats", windowQuery(window), nil, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// StartTest запускает тест: kind - путь операции после /test/ (batch, stream,
+// exactly-once, ...), request - тело запроса теста (структура или map полей)
+func (s *Sender) StartTest(ctx context.Context, kind string, request any) (*TestStarted, error) {
+	started := &TestStarted{}
+	if err := s.do(ctx, http.MethodPost, "/test/"+kind, nil, request, started); err != nil {
+		return nil, err
+	}
+	return started, nil
+}
+
+// StopTest останавливает выполняющийся тест testID (пусто - все выполняющиеся тесты).
+// Если тест не выполняется, sender отвечает 400 или 404
+func (s *Sender) StopTest(ctx context.Context, testID string) (*TestStopped, error) {
+	stopped := &TestStopped{}
+	if err := s.do(ctx, http.MethodPost, "/test/stop", nil, map[string]string{"test_id": testID}, stopped); err != nil {
+		return nil, err
+	}
+	return stopped, nil
+}
+
+// TestResult запрашивает результат выполняющегося или завершенного теста
+func (s *Sender) TestResult(ctx context.Context, testID string) (*models.TestResult, error) {
+	result := &models.TestResult{}
+	if err := s.do(ctx, http.MethodGet, "/test/"+url.PathEscape(testID), nil, nil, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RegisterNode регистрирует sender у ведущего согласованных тестов или подтверждает
+// регистрацию; registration - тело запроса POST /cluster/nodes
+func (s *Sender) RegisterNode(ctx context.Context, registration any) error {
+	return s.do(ctx, http.MethodPost, "/cluster/nodes", nil, registration, nil)
+}
+
+// RemoveNode снимает регистрацию sender id у ведущего
+func (s *Sender) RemoveNode(ctx context.Context, id string) error {
+	return s.do(ctx, http.MethodDelete, "/cluster/nodes/"+url.PathEscape(id), nil, nil, nil)
+}
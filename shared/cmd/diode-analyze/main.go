@@ -0,0 +1,106 @@
+// diode-analyze сопоставляет журналы корреляции sender и recipient и выводит
+// потерянные, повторно полученные, опоздавшие и искаженные сообщения
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/infodiode/shared/correlation"
+)
+
+// timeFormat формат времени в текстовом отчете
+const timeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+func main() {
+	var (
+		sentPath     = flag.String("sent", "", "журнал отправки sender: файл, директория или шаблон имени")
+		receivedPath = flag.String("received", "", "журнал получения recipient: файл, директория или шаблон имени")
+		testID       = flag.String("test", "", "идентификатор теста (по умолчанию все тесты журнала отправки)")
+		lateAfter    = flag.Duration("late", time.Second, "задержка, начиная с которой сообщение считается опоздавшим (0 - не проверять)")
+		limit        = flag.Int("limit", 100, "максимум строк в каждом разделе текстового отчета (0 - без ограничения)")
+		asJSON       = flag.Bool("json", false, "вывести отчет в формате JSON")
+	)
+	flag.Parse()
+
+	if *sentPath == "" || *receivedPath == "" {
+		fmt.Fprintln(os.Stderr, "Необходимо указать -sent и -received")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	analyzer := correlation.NewAnalyzer(correlation.Options{TestID: *testID, LateAfter: *lateAfter})
+	if err := correlation.Read(*sentPath, correlation.PrefixSent, analyzer.AddSent); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка чтения журнала отправки: %v\n", err)
+		os.Exit(1)
+	}
+	if err := correlation.Read(*receivedPath, correlation.PrefixReceived, analyzer.AddReceived); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка чтения журнала получения: %v\n", err)
+		os.Exit(1)
+	}
+
+	report := analyzer.Report()
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка вывода отчета: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	printReport(os.Stdout, report, *limit)
+}
+
+// printReport выводит текстовый отчет; в каждом разделе не больше limit строк
+func printReport(w io.Writer, r *correlation.Report, limit int) {
+	fmt.Fprintf(w, "Тесты:          %v\n", r.Tests)
+	fmt.Fprintf(w, "Отправлено:     %d\n", r.Sent)
+	fmt.Fprintf(w, "Получено:       %d (уникальных %d, неизвестных %d)\n", r.Received, r.Unique, r.Unexpected)
+	fmt.Fprintf(w, "Потеряно:       %d (%.4f%%)\n", len(r.Lost), r.LossPercent)
+	fmt.Fprintf(w, "Повторы:        %d\n", len(r.Duplicated))
+	fmt.Fprintf(w, "Опоздавшие:     %d\n", len(r.Late))
+	fmt.Fprintf(w, "Искаженные:     %d\n", len(r.Mismatched))
+	fmt.Fprintf(w, "Задержка, мс:   средняя %.3f, максимальная %.3f\n", r.AvgLatencyMs, r.MaxLatencyMs)
+
+	section(w, "Потерянные сообщения (test_id message_id sequence sent_at)", len(r.Lost), limit, func(i int) {
+		m := r.Lost[i]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", m.TestID, m.MessageID, m.Sequence, m.SentAt.Format(timeFormat))
+	})
+	section(w, "Повторно полученные (test_id message_id sequence count first_received_at repeat_gap_ms)", len(r.Duplicated), limit, func(i int) {
+		m := r.Duplicated[i]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%.3f\n", m.TestID, m.MessageID, m.Sequence, m.Count, m.FirstAt.Format(timeFormat), m.RepeatGapMs)
+	})
+	section(w, "Опоздавшие (test_id message_id sequence sent_at received_at latency_ms)", len(r.Late), limit, func(i int) {
+		m := r.Late[i]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%.3f\n", m.TestID, m.MessageID, m.Sequence,
+			m.SentAt.Format(timeFormat), m.ReceivedAt.Format(timeFormat), m.LatencyMs)
+	})
+	section(w, "Искаженные (test_id message_id sequence sent_checksum received_checksum)", len(r.Mismatched), limit, func(i int) {
+		m := r.Mismatched[i]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", m.TestID, m.MessageID, m.Sequence, m.Sent, m.Received)
+	})
+}
+
+// section выводит раздел отчета из n строк, не больше limit
+func section(w io.Writer, title string, n, limit int, row func(i int)) {
+	if n == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s:\n", title)
+
+	shown := n
+	if limit > 0 {
+		shown = min(n, limit)
+	}
+	for i := range shown {
+		row(i)
+	}
+	if shown < n {
+		fmt.Fprintf(w, "... и еще %d\n", n-shown)
+	}
+}
@@ -0,0 +1,37 @@
+package configwatch
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ChangedSections возвращает разделы конфигурации (поля структуры current и next
+// верхнего уровня, по имени из тега mapstructure), значения которых различаются.
+// Вызывается после переноса в next изменяемых без перезапуска параметров, поэтому
+// результат - разделы, изменения которых вступят в силу после перезапуска
+func ChangedSections[T any](current, next *T) []string {
+	oldValue := reflect.ValueOf(current).Elem()
+	newValue := reflect.ValueOf(next).Elem()
+	configType := oldValue.Type()
+
+	var changed []string
+	for i := range configType.NumField() {
+		field := configType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			changed = append(changed, sectionName(field))
+		}
+	}
+	return changed
+}
+
+// sectionName возвращает имя раздела конфигурации по тегу mapstructure поля
+func sectionName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
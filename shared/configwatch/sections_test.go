@@ -0,0 +1,37 @@
+package configwatch
+
+import (
+	"slices"
+	"testing"
+)
+
+type testConfig struct {
+	Service struct {
+		Name string `mapstructure:"name"`
+	} `mapstructure:"service"`
+	HealthProbe struct {
+		Enabled bool `mapstructure:"enabled"`
+	} `mapstructure:"health_probe"`
+	Webhooks []string `mapstructure:"webhooks"`
+	Untagged int
+}
+
+func TestChangedSections(t *testing.T) {
+	var current testConfig
+	current.Service.Name = "sender"
+	current.Webhooks = []string{"http://a"}
+
+	next := current
+	next.Webhooks = []string{"http://a"}
+	if changed := ChangedSections(&current, &next); len(changed) != 0 {
+		t.Fatalf("без изменений получены разделы %v", changed)
+	}
+
+	next.HealthProbe.Enabled = true
+	next.Webhooks = append(next.Webhooks, "http://b")
+	next.Untagged = 1
+	changed := ChangedSections(&current, &next)
+	if expected := []string{"health_probe", "webhooks", "untagged"}; !slices.Equal(changed, expected) {
+		t.Errorf("ChangedSections = %v, ожидалось %v", changed, expected)
+	}
+}
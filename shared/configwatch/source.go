@@ -0,0 +1,163 @@
+package configwatch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// includeKey ключ списка подключаемых файлов конфигурации
+	includeKey = "include"
+	// maxIncludeDepth предельная вложенность подключаемых файлов
+	maxIncludeDepth = 8
+)
+
+// envReference подстановка переменной окружения: ${NAME} или ${NAME:-значение по умолчанию};
+// $${ - экранированный символ $
+var envReference = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// ReadFiles читает файл конфигурации path в v: сначала подключаемые файлы из списка
+// include (пути относительно директории включающего файла) в порядке перечисления,
+// затем сам файл, значения которого переопределяют подключенные. В строковых значениях
+// выполняется подстановка переменных окружения. Возвращает прочитанные файлы
+func ReadFiles(v *viper.Viper, path string) ([]string, error) {
+	var files []string
+	if err := mergeConfigFile(v, path, nil, &files); err != nil {
+		return files, err
+	}
+	return files, nil
+}
+
+// mergeConfigFile объединяет в v файл path и подключаемые им файлы; chain - цепочка
+// включающих файлов для обнаружения циклов
+func mergeConfigFile(v *viper.Viper, path string, chain []string, files *[]string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("некорректный путь %s: %w", path, err)
+	}
+	for _, parent := range chain {
+		if parent == abs {
+			return fmt.Errorf("циклическое подключение файла %s", path)
+		}
+	}
+	if len(chain) >= maxIncludeDepth {
+		return fmt.Errorf("превышена вложенность подключаемых файлов (%d): %s", maxIncludeDepth, path)
+	}
+	*files = append(*files, abs)
+
+	file := viper.New()
+	file.SetConfigFile(abs)
+	if err := file.ReadInConfig(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	expanded, err := expandEnv(file.AllSettings())
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	settings := expanded.(map[string]any)
+
+	includes, err := includeList(settings[includeKey])
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	delete(settings, includeKey)
+
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(abs), include)
+		}
+		if err := mergeConfigFile(v, include, append(chain, abs), files); err != nil {
+			return err
+		}
+	}
+
+	return v.MergeConfigMap(settings)
+}
+
+// includeList возвращает список подключаемых файлов: строку или список строк
+func includeList(value any) ([]string, error) {
+	switch list := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{list}, nil
+	case []any:
+		includes := make([]string, 0, len(list))
+		for _, item := range list {
+			path, ok := item.(string)
+			if !ok || path == "" {
+				return nil, fmt.Errorf("%s: ожидается путь к файлу, получено %v", includeKey, item)
+			}
+			includes = append(includes, path)
+		}
+		return includes, nil
+	default:
+		return nil, fmt.Errorf("%s: ожидается путь или список путей к файлам", includeKey)
+	}
+}
+
+// expandEnv выполняет подстановку переменных окружения в строковых значениях
+// конфигурации. Переменная без значения по умолчанию должна быть задана
+func expandEnv(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return expandEnvString(v)
+	case map[string]any:
+		for key, item := range v {
+			expanded, err := expandEnv(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+		return v, nil
+	case []any:
+		for i, item := range v {
+			expanded, err := expandEnv(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// expandEnvString выполняет подстановку переменных окружения в строке
+func expandEnvString(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var missing []string
+	expanded := envReference.ReplaceAllStringFunc(s, func(ref string) string {
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+		match := envReference.FindStringSubmatch(ref)
+		value, ok := os.LookupEnv(match[1])
+		if match[2] != "" {
+			// Как в shell: значение по умолчанию и для пустой переменной
+			if value == "" {
+				value = strings.TrimPrefix(match[2], ":-")
+			}
+			return value
+		}
+		if !ok {
+			missing = append(missing, match[1])
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("не заданы переменные окружения: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
@@ -0,0 +1,83 @@
+package configwatch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadFilesInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "base.yaml", "mqtt:\n  broker: tcp://base:1883\n  qos: 1\n")
+	main := writeFile(t, dir, "config.yaml", "include: base.yaml\nmqtt:\n  qos: 2\n")
+
+	v := viper.New()
+	files, err := ReadFiles(v, main)
+	if err != nil {
+		t.Fatalf("ReadFiles: %v", err)
+	}
+	if len(files) != 2 {
+		t.Errorf("прочитано файлов %d, ожидалось 2: %v", len(files), files)
+	}
+	if broker := v.GetString("mqtt.broker"); broker != "tcp://base:1883" {
+		t.Errorf("mqtt.broker = %q, ожидалось значение из подключенного файла", broker)
+	}
+	if qos := v.GetInt("mqtt.qos"); qos != 2 {
+		t.Errorf("mqtt.qos = %d, ожидалось значение основного файла 2", qos)
+	}
+	if v.IsSet(includeKey) {
+		t.Error("ключ include не должен попадать в конфигурацию")
+	}
+}
+
+func TestReadFilesEnv(t *testing.T) {
+	t.Setenv("CONFIGWATCH_BROKER", "tcp://env:1883")
+	t.Setenv("CONFIGWATCH_EMPTY", "")
+	dir := t.TempDir()
+	main := writeFile(t, dir, "config.yaml", strings.Join([]string{
+		"broker: ${CONFIGWATCH_BROKER}",
+		"topic: ${CONFIGWATCH_EMPTY:-test/data}",
+		"literal: $${CONFIGWATCH_BROKER}",
+	}, "\n"))
+
+	v := viper.New()
+	if _, err := ReadFiles(v, main); err != nil {
+		t.Fatalf("ReadFiles: %v", err)
+	}
+	expected := map[string]string{
+		"broker":  "tcp://env:1883",
+		"topic":   "test/data",
+		"literal": "${CONFIGWATCH_BROKER}",
+	}
+	for key, value := range expected {
+		if actual := v.GetString(key); actual != value {
+			t.Errorf("%s = %q, ожидалось %q", key, actual, value)
+		}
+	}
+}
+
+func TestReadFilesErrors(t *testing.T) {
+	dir := t.TempDir()
+	cycle := writeFile(t, dir, "a.yaml", "include: b.yaml\n")
+	writeFile(t, dir, "b.yaml", "include: a.yaml\n")
+	missing := writeFile(t, dir, "env.yaml", "broker: ${CONFIGWATCH_MISSING}\n")
+
+	if _, err := ReadFiles(viper.New(), cycle); err == nil || !strings.Contains(err.Error(), "циклическое") {
+		t.Errorf("ожидалась ошибка циклического подключения, получено %v", err)
+	}
+	if _, err := ReadFiles(viper.New(), missing); err == nil || !strings.Contains(err.Error(), "CONFIGWATCH_MISSING") {
+		t.Errorf("ожидалась ошибка незаданной переменной, получено %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package configwatch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce время ожидания завершения серии событий записи файла
+const reloadDebounce = 500 * time.Millisecond
+
+// Watch перечитывает конфигурацию при изменении файла configPath или подключаемых им
+// файлов (files - список, прочитанный при запуске) или получении SIGHUP и передает
+// результат в onReload до отмены ctx. load читает конфигурацию и возвращает также
+// прочитанные файлы, в том числе при ошибке. Ошибки чтения и валидации передаются
+// в onReload, текущая конфигурация при этом не меняется
+func Watch[T any](ctx context.Context, configPath string, files []string, load func(string) (*T, []string, error), onReload func(*T, error)) error {
+	if configPath == "" {
+		return fmt.Errorf("не указан путь к файлу конфигурации")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("ошибка создания наблюдателя файлов: %w", err)
+	}
+
+	// Наблюдаем за директориями: редакторы и ConfigMap в Kubernetes заменяют файл
+	// переименованием, после чего наблюдение за самим файлом прекращается
+	mainFile, err := filepath.Abs(configPath)
+	if err != nil {
+		watcher.Close()
+		return fmt.Errorf("некорректный путь к файлу конфигурации: %w", err)
+	}
+	targets := make(map[string]bool)
+	dirs := make(map[string]bool)
+	watchFiles := func(files []string) error {
+		clear(targets)
+		targets[mainFile] = true
+		for _, file := range files {
+			targets[filepath.Clean(file)] = true
+		}
+		for file := range targets {
+			dir := filepath.Dir(file)
+			if dirs[dir] {
+				continue
+			}
+			if err := watcher.Add(dir); err != nil {
+				return fmt.Errorf("ошибка наблюдения за директорией конфигурации: %w", err)
+			}
+			dirs[dir] = true
+		}
+		return nil
+	}
+	if err := watchFiles(files); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	// reload перечитывает конфигурацию; список подключаемых файлов мог измениться
+	reload := func() {
+		config, files, err := load(configPath)
+		if watchErr := watchFiles(files); watchErr != nil && err == nil {
+			err = watchErr
+			config = nil
+		}
+		onReload(config, err)
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		defer watcher.Close()
+		defer signal.Stop(hangup)
+
+		debounce := time.NewTimer(reloadDebounce)
+		debounce.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangup:
+				reload()
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !targets[filepath.Clean(event.Name)] && filepath.Base(event.Name) != "..data" {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					debounce.Reset(reloadDebounce)
+				}
+			case <-debounce.C:
+				reload()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				onReload(nil, fmt.Errorf("ошибка наблюдения за файлом конфигурации: %w", err))
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package correlation
+
+import (
+	"sort"
+	"time"
+)
+
+// Options параметры сопоставления журналов
+type Options struct {
+	TestID    string        // Анализируемый тест; пусто - все тесты журнала отправки
+	LateAfter time.Duration // Задержка, начиная с которой сообщение считается опоздавшим (0 - не проверять)
+}
+
+// Lost отправленное, но не полученное сообщение
+type Lost struct {
+	TestID    string    `json:"test_id"`
+	MessageID int       `json:"message_id"`
+	Sequence  int64     `json:"sequence"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// Duplicated сообщение, полученное больше одного раза
+type Duplicated struct {
+	TestID      string    `json:"test_id"`
+	MessageID   int       `json:"message_id"`
+	Sequence    int64     `json:"sequence"`
+	Count       int       `json:"count"`
+	SentAt      time.Time `json:"sent_at"`
+	FirstAt     time.Time `json:"first_received_at"`
+	LastAt      time.Time `json:"last_received_at"`
+	RepeatGapMs float64   `json:"repeat_gap_ms"` // Интервал между первым и последним получением
+}
+
+// Late сообщение, полученное с задержкой не меньше Options.LateAfter
+type Late struct {
+	TestID     string    `json:"test_id"`
+	MessageID  int       `json:"message_id"`
+	Sequence   int64     `json:"sequence"`
+	SentAt     time.Time `json:"sent_at"`
+	ReceivedAt time.Time `json:"received_at"`
+	LatencyMs  float64   `json:"latency_ms"`
+}
+
+// Mismatch сообщение, контрольная сумма которого при получении отличается от отправленной
+type Mismatch struct {
+	TestID    string `json:"test_id"`
+	MessageID int    `json:"message_id"`
+	Sequence  int64  `json:"sequence"`
+	Sent      string `json:"sent_checksum"`
+	Received  string `json:"received_checksum"`
+}
+
+// Report результат сопоставления журналов отправки и получения
+type Report struct {
+	Tests        []string `json:"tests"`
+	Sent         int64    `json:"sent"`
+	Received     int64    `json:"received"`   // Получено записей анализируемых тестов, включая повторы
+	Unique       int64    `json:"unique"`     // Получено отправленных сообщений без учета повторов
+	Unexpected   int64    `json:"unexpected"` // Получены, но отсутствуют в журнале отправки
+	LossPercent  float64  `json:"loss_percent"`
+	AvgLatencyMs float64  `json:"avg_latency_ms"` // По первому получению; включает расхождение часов sender и recipient
+	MaxLatencyMs float64  `json:"max_latency_ms"`
+
+	Lost       []Lost       `json:"lost"`
+	Duplicated []Duplicated `json:"duplicated"`
+	Late       []Late       `json:"late"`
+	Mismatched []Mismatch   `json:"mismatched"`
+}
+
+// key идентификатор сообщения в журналах
+type key struct {
+	testID    string
+	messageID int
+}
+
+// entry отправленное сообщение и его получения
+type entry struct {
+	sent     Record
+	count    int
+	first    time.Time
+	last     time.Time
+	checksum string // Первая контрольная сумма получения, отличная от отправленной
+}
+
+// Analyzer сопоставляет журналы: сначала добавляются все записи отправки, затем
+// записи получения
+type Analyzer struct {
+	options  Options
+	messages map[key]*entry
+	tests    map[string]bool
+	received int64
+	unknown  int64
+}
+
+// NewAnalyzer создает сопоставление с параметрами options
+func NewAnalyzer(options Options) *Analyzer {
+	return &Analyzer{
+		options:  options,
+		messages: make(map[key]*entry),
+		tests:    make(map[string]bool),
+	}
+}
+
+// AddSent добавляет запись журнала отправки
+func (a *Analyzer) AddSent(r Record) error {
+	if a.options.TestID != "" && r.TestID != a.options.TestID {
+		return nil
+	}
+	a.tests[r.TestID] = true
+	a.messages[key{r.TestID, r.MessageID}] = &entry{sent: r}
+	return nil
+}
+
+// AddReceived добавляет запись журнала получения; записи тестов, отсутствующих
+// в журнале отправки, не учитываются
+func (a *Analyzer) AddReceived(r Record) error {
+	if !a.tests[r.TestID] {
+		return nil
+	}
+	a.received++
+
+	e, ok := a.messages[key{r.TestID, r.MessageID}]
+	if !ok {
+		a.unknown++
+		return nil
+	}
+
+	e.count++
+	if e.count == 1 || r.Time.Before(e.first) {
+		e.first = r.Time
+	}
+	if r.Time.After(e.last) {
+		e.last = r.Time
+	}
+	if r.Checksum != e.sent.Checksum && e.checksum == "" {
+		e.checksum = r.Checksum
+	}
+	return nil
+}
+
+// Report возвращает результат сопоставления; списки упорядочены по тесту и номеру сообщения
+func (a *Analyzer) Report() *Report {
+	report := &Report{
+		Sent:       int64(len(a.messages)),
+		Received:   a.received,
+		Unexpected: a.unknown,
+		Lost:       []Lost{},
+		Duplicated: []Duplicated{},
+		Late:       []Late{},
+		Mismatched: []Mismatch{},
+	}
+	for test := range a.tests {
+		report.Tests = append(report.Tests, test)
+	}
+	sort.Strings(report.Tests)
+
+	entries := make([]*entry, 0, len(a.messages))
+	for _, e := range a.messages {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].sent.TestID != entries[j].sent.TestID {
+			return entries[i].sent.TestID < entries[j].sent.TestID
+		}
+		if entries[i].sent.Sequence != entries[j].sent.Sequence {
+			return entries[i].sent.Sequence < entries[j].sent.Sequence
+		}
+		return entries[i].sent.MessageID < entries[j].sent.MessageID
+	})
+
+	var totalLatency time.Duration
+	for _, e := range entries {
+		s := e.sent
+		if e.count == 0 {
+			report.Lost = append(report.Lost, Lost{
+				TestID: s.TestID, MessageID: s.MessageID, Sequence: s.Sequence, SentAt: s.Time,
+			})
+			continue
+		}
+
+		report.Unique++
+		latency := e.first.Sub(s.Time)
+		totalLatency += latency
+		report.MaxLatencyMs = max(report.MaxLatencyMs, durationMs(latency))
+
+		if e.count > 1 {
+			report.Duplicated = append(report.Duplicated, Duplicated{
+				TestID: s.TestID, MessageID: s.MessageID, Sequence: s.Sequence, Count: e.count,
+				SentAt: s.Time, FirstAt: e.first, LastAt: e.last, RepeatGapMs: durationMs(e.last.Sub(e.first)),
+			})
+		}
+		if a.options.LateAfter > 0 && latency >= a.options.LateAfter {
+			report.Late = append(report.Late, Late{
+				TestID: s.TestID, MessageID: s.MessageID, Sequence: s.Sequence,
+				SentAt: s.Time, ReceivedAt: e.first, LatencyMs: durationMs(latency),
+			})
+		}
+		if e.checksum != "" {
+			report.Mismatched = append(report.Mismatched, Mismatch{
+				TestID: s.TestID, MessageID: s.MessageID, Sequence: s.Sequence, Sent: s.Checksum, Received: e.checksum,
+			})
+		}
+	}
+
+	if report.Sent > 0 {
+		report.LossPercent = float64(len(report.Lost)) / float64(report.Sent) * 100
+	}
+	if report.Unique > 0 {
+		report.AvgLatencyMs = durationMs(totalLatency) / float64(report.Unique)
+	}
+	return report
+}
+
+// durationMs переводит длительность в миллисекунды
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
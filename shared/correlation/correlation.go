@@ -0,0 +1,346 @@
+package correlation
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Формат журнала: текстовые строки, по одной на сообщение, с полями через табуляцию:
+// test_id, message_id, sequence, время (нс Unix) и контрольная сумма. Sender записывает
+// время отправки (send_time), recipient - время получения. Строки, начинающиеся с '#',
+// содержат заголовок файла и пропускаются при чтении
+const (
+	fileHeader = "# infodiode correlation v1 test_id message_id sequence time_unix_ns checksum\n"
+	fileExt    = ".tsv"
+
+	flushInterval = time.Second
+)
+
+// Начало имен файлов журналов sender и recipient
+const (
+	PrefixSent     = "sent"
+	PrefixReceived = "received"
+)
+
+// Record запись журнала об отправленном или полученном сообщении
+type Record struct {
+	TestID    string
+	MessageID int
+	Sequence  int64
+	Time      time.Time
+	Checksum  string
+}
+
+// AppendRecord дописывает строку записи в dst
+func AppendRecord(dst []byte, r Record) []byte {
+	dst = append(dst, r.TestID...)
+	dst = append(dst, '\t')
+	dst = strconv.AppendInt(dst, int64(r.MessageID), 10)
+	dst = append(dst, '\t')
+	dst = strconv.AppendInt(dst, r.Sequence, 10)
+	dst = append(dst, '\t')
+	dst = strconv.AppendInt(dst, r.Time.UnixNano(), 10)
+	dst = append(dst, '\t')
+	dst = append(dst, r.Checksum...)
+	return append(dst, '\n')
+}
+
+// ParseRecord разбирает строку записи без завершающего перевода строки
+func ParseRecord(line []byte) (Record, error) {
+	var fields [5][]byte
+	for i := range len(fields) - 1 {
+		field, rest, ok := bytes.Cut(line, []byte{'\t'})
+		if !ok {
+			return Record{}, fmt.Errorf("ожидается %d полей", len(fields))
+		}
+		fields[i], line = field, rest
+	}
+	fields[len(fields)-1] = line
+
+	messageID, err := strconv.Atoi(string(fields[1]))
+	if err != nil {
+		return Record{}, fmt.Errorf("некорректный message_id: %w", err)
+	}
+	sequence, err := strconv.ParseInt(string(fields[2]), 10, 64)
+	if err != nil {
+		return Record{}, fmt.Errorf("некорректный sequence: %w", err)
+	}
+	nanos, err := strconv.ParseInt(string(fields[3]), 10, 64)
+	if err != nil {
+		return Record{}, fmt.Errorf("некорректное время: %w", err)
+	}
+
+	return Record{
+		TestID:    string(fields[0]),
+		MessageID: messageID,
+		Sequence:  sequence,
+		Time:      time.Unix(0, nanos),
+		Checksum:  string(fields[4]),
+	}, nil
+}
+
+// Config конфигурация журнала
+type Config struct {
+	Directory   string // Директория файлов журнала
+	Prefix      string // Начало имени файлов; к нему добавляется номер файла
+	MaxFileSize int64  // Размер файла в байтах, после которого начинается новый файл (0 - без ограничения)
+}
+
+// Stats статистика записи журнала
+type Stats struct {
+	CurrentFile    string `json:"current_file"`
+	FilesCreated   int64  `json:"files_created"`
+	RecordsWritten int64  `json:"records_written"`
+	Errors         int64  `json:"errors"`
+	LastError      string `json:"last_error,omitempty"`
+}
+
+// Writer записывает журнал сообщений в файлы с ротацией по размеру.
+// Методы nil *Writer ничего не делают, поэтому журнал можно не проверять на nil
+type Writer struct {
+	config   Config
+	mu       sync.Mutex
+	file     *os.File
+	buf      *bufio.Writer
+	line     []byte
+	size     int64
+	seq      int
+	stats    Stats
+	closed   bool
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewWriter создает журнал и открывает первый файл
+func NewWriter(cfg Config) (*Writer, error) {
+	if cfg.Directory == "" {
+		return nil, fmt.Errorf("не указана директория журнала корреляции")
+	}
+	if cfg.MaxFileSize < 0 {
+		return nil, fmt.Errorf("некорректный размер файла журнала корреляции: %d", cfg.MaxFileSize)
+	}
+	if err := os.MkdirAll(cfg.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию журнала корреляции: %w", err)
+	}
+
+	w := &Writer{
+		config:   cfg,
+		stopChan: make(chan struct{}),
+	}
+
+	w.mu.Lock()
+	err := w.rotate()
+	w.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	go w.flushLoop()
+
+	return w, nil
+}
+
+// Write добавляет запись в журнал. Ошибки записи не прерывают отправку и прием:
+// они учитываются в статистике
+func (w *Writer) Write(r Record) {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buf == nil {
+		return // журнал закрыт
+	}
+
+	w.line = AppendRecord(w.line[:0], r)
+	if w.config.MaxFileSize > 0 && w.size > int64(len(fileHeader)) && w.size+int64(len(w.line)) > w.config.MaxFileSize {
+		if err := w.rotate(); err != nil {
+			w.recordError(err)
+			return
+		}
+	}
+
+	if _, err := w.buf.Write(w.line); err != nil {
+		w.recordError(err)
+		return
+	}
+	w.size += int64(len(w.line))
+	w.stats.RecordsWritten++
+}
+
+// rotate закрывает текущий файл и открывает новый (вызывается под w.mu)
+func (w *Writer) rotate() error {
+	if err := w.closeFile(); err != nil {
+		w.recordError(err)
+	}
+
+	w.seq++
+	path := filepath.Join(w.config.Directory, fmt.Sprintf("%s-%04d%s", w.config.Prefix, w.seq, fileExt))
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла журнала корреляции: %w", err)
+	}
+
+	w.file = file
+	w.buf = bufio.NewWriterSize(file, 64*1024)
+	if _, err := w.buf.WriteString(fileHeader); err != nil {
+		return fmt.Errorf("ошибка записи файла журнала корреляции: %w", err)
+	}
+	w.size = int64(len(fileHeader))
+	w.stats.CurrentFile = path
+	w.stats.FilesCreated++
+	return nil
+}
+
+// closeFile сбрасывает буфер и закрывает текущий файл (вызывается под w.mu)
+func (w *Writer) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+
+	flushErr := w.buf.Flush()
+	closeErr := w.file.Close()
+	w.file = nil
+	w.buf = nil
+
+	return errors.Join(flushErr, closeErr)
+}
+
+// recordError учитывает ошибку записи (вызывается под w.mu)
+func (w *Writer) recordError(err error) {
+	w.stats.Errors++
+	w.stats.LastError = err.Error()
+}
+
+// flushLoop периодически сбрасывает буфер на диск
+func (w *Writer) flushLoop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.buf != nil {
+				if err := w.buf.Flush(); err != nil {
+					w.recordError(err)
+				}
+			}
+			w.mu.Unlock()
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+// Stats возвращает статистику записи журнала
+func (w *Writer) Stats() Stats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
+
+// Close сбрасывает буфер и закрывает файл журнала
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	err := w.closeFile()
+	w.mu.Unlock()
+
+	close(w.stopChan)
+	w.wg.Wait()
+	return err
+}
+
+// Files возвращает файлы журнала по пути к файлу, шаблону имени или директории
+// (в ней выбираются файлы, имена которых начинаются с prefix) в порядке записи
+func Files(path, prefix string) ([]string, error) {
+	if info, err := os.Stat(path); err == nil {
+		if !info.IsDir() {
+			return []string{path}, nil
+		}
+		path = filepath.Join(path, prefix+"-*"+fileExt)
+	}
+
+	files, err := filepath.Glob(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("файлы журнала не найдены: %s", path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ReadFile последовательно читает записи файла журнала и передает их в handler.
+// Обрезанная последняя строка (например, после аварийного завершения) пропускается
+func ReadFile(path string, handler func(Record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	for number := 1; ; number++ {
+		line, err := reader.ReadSlice('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line = line[:len(line)-1]
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		record, err := ParseRecord(line)
+		if err != nil {
+			return fmt.Errorf("строка %d: %w", number, err)
+		}
+		if err := handler(record); err != nil {
+			return err
+		}
+	}
+}
+
+// Read читает записи всех файлов журнала по пути (см. Files)
+func Read(path, prefix string, handler func(Record) error) error {
+	files, err := Files(path, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := ReadFile(file, handler); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return nil
+}
@@ -1,3 +1,30 @@
 module github.com/infodiode/shared
 
 go 1.25.0
+
+require (
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/mailru/easyjson v0.9.2
+	github.com/prometheus/client_model v0.6.2
+	github.com/spf13/viper v1.21.0
+	go.bug.st/serial v1.6.4
+	go.uber.org/zap v1.27.0
+	golang.org/x/sys v0.38.0
+	google.golang.org/protobuf v1.36.10
+)
+
+require (
+	github.com/creack/goselect v0.1.2 // indirect
+	github.com/go-viper/mapstructure/v2 v2.4.0 // indirect
+	github.com/josharian/intern v1.0.0 // indirect
+	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
+	github.com/sagikazarmark/locafero v0.11.0 // indirect
+	github.com/sourcegraph/conc v0.3.1-0.20240121214520-5f936abd7ae8 // indirect
+	github.com/spf13/afero v1.15.0 // indirect
+	github.com/spf13/cast v1.10.0 // indirect
+	github.com/spf13/pflag v1.0.10 // indirect
+	github.com/subosito/gotenv v1.6.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	go.yaml.in/yaml/v3 v3.0.4 // indirect
+	golang.org/x/text v0.28.0 // indirect
+)
@@ -0,0 +1,244 @@
+package logging
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var bufferPool = buffer.NewPool()
+
+// logfmtTimeLayout формат времени записи (как zapcore.ISO8601TimeEncoder)
+const logfmtTimeLayout = "2006-01-02T15:04:05.000Z0700"
+
+// logfmtEncoder записывает поля парами key=value через пробел. Значения с пробелами,
+// кавычками, '=' и управляющими символами заключаются в кавычки; вложенные объекты
+// и массивы записываются в JSON. Имена полей пространства имен получают префикс "namespace."
+type logfmtEncoder struct {
+	cfg       *zapcore.EncoderConfig
+	buf       *buffer.Buffer
+	namespace string
+}
+
+// NewLogfmtEncoder создает encoder формата logfmt
+func NewLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{cfg: &cfg, buf: bufferPool.Get()}
+}
+
+// Clone копирует encoder вместе с накопленными полями
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := &logfmtEncoder{cfg: e.cfg, buf: bufferPool.Get(), namespace: e.namespace}
+	clone.buf.Write(e.buf.Bytes())
+	return clone
+}
+
+// EncodeEntry записывает строку записи: служебные поля, сообщение, поля логгера и записи
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	line := &logfmtEncoder{cfg: e.cfg, buf: bufferPool.Get()}
+
+	if e.cfg.TimeKey != "" && e.cfg.TimeKey != zapcore.OmitKey {
+		line.AddString(e.cfg.TimeKey, ent.Time.Format(logfmtTimeLayout))
+	}
+	if e.cfg.LevelKey != "" && e.cfg.LevelKey != zapcore.OmitKey {
+		line.AddString(e.cfg.LevelKey, ent.Level.String())
+	}
+	if ent.LoggerName != "" && e.cfg.NameKey != "" && e.cfg.NameKey != zapcore.OmitKey {
+		line.AddString(e.cfg.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined && e.cfg.CallerKey != "" && e.cfg.CallerKey != zapcore.OmitKey {
+		line.AddString(e.cfg.CallerKey, ent.Caller.TrimmedPath())
+	}
+	if e.cfg.MessageKey != "" && e.cfg.MessageKey != zapcore.OmitKey {
+		line.AddString(e.cfg.MessageKey, ent.Message)
+	}
+
+	if e.buf.Len() > 0 {
+		line.separate()
+		line.buf.Write(e.buf.Bytes())
+	}
+	line.namespace = e.namespace
+	for _, field := range fields {
+		field.AddTo(line)
+	}
+
+	if ent.Stack != "" && e.cfg.StacktraceKey != "" && e.cfg.StacktraceKey != zapcore.OmitKey {
+		line.namespace = ""
+		line.AddString(e.cfg.StacktraceKey, ent.Stack)
+	}
+
+	lineEnding := e.cfg.LineEnding
+	if lineEnding == "" {
+		lineEnding = zapcore.DefaultLineEnding
+	}
+	line.buf.AppendString(lineEnding)
+	return line.buf, nil
+}
+
+// separate отделяет следующее поле пробелом
+func (e *logfmtEncoder) separate() {
+	if e.buf.Len() > 0 {
+		e.buf.AppendByte(' ')
+	}
+}
+
+// addKey записывает имя поля и знак '='
+func (e *logfmtEncoder) addKey(key string) {
+	e.separate()
+	if e.namespace != "" {
+		e.buf.AppendString(e.namespace)
+		e.buf.AppendByte('.')
+	}
+	e.buf.AppendString(key)
+	e.buf.AppendByte('=')
+}
+
+// appendValue записывает строковое значение, при необходимости в кавычках
+func (e *logfmtEncoder) appendValue(value string) {
+	if needsQuoting(value) {
+		e.buf.AppendString(strconv.Quote(value))
+		return
+	}
+	e.buf.AppendString(value)
+}
+
+// needsQuoting проверяет, нужно ли заключать значение в кавычки
+func needsQuoting(value string) bool {
+	if value == "" {
+		return true
+	}
+	for i := 0; i < len(value); {
+		r, size := utf8.DecodeRuneInString(value[i:])
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// addJSON записывает значение в виде JSON
+func (e *logfmtEncoder) addJSON(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	e.addKey(key)
+	e.appendValue(string(data))
+	return nil
+}
+
+// AddArray записывает массив в JSON
+func (e *logfmtEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	fields := zapcore.NewMapObjectEncoder()
+	if err := fields.AddArray(key, marshaler); err != nil {
+		return err
+	}
+	return e.addJSON(key, fields.Fields[key])
+}
+
+// AddObject записывает объект в JSON
+func (e *logfmtEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	fields := zapcore.NewMapObjectEncoder()
+	if err := marshaler.MarshalLogObject(fields); err != nil {
+		return err
+	}
+	return e.addJSON(key, fields.Fields)
+}
+
+// AddReflected записывает произвольное значение в JSON
+func (e *logfmtEncoder) AddReflected(key string, value interface{}) error {
+	return e.addJSON(key, value)
+}
+
+// OpenNamespace добавляет префикс к именам следующих полей
+func (e *logfmtEncoder) OpenNamespace(key string) {
+	if e.namespace != "" {
+		e.namespace += "." + key
+		return
+	}
+	e.namespace = key
+}
+
+func (e *logfmtEncoder) AddBinary(key string, value []byte) {
+	e.AddString(key, base64.StdEncoding.EncodeToString(value))
+}
+
+func (e *logfmtEncoder) AddByteString(key string, value []byte) {
+	e.AddString(key, string(value))
+}
+
+func (e *logfmtEncoder) AddBool(key string, value bool) {
+	e.addKey(key)
+	e.buf.AppendBool(value)
+}
+
+func (e *logfmtEncoder) AddComplex128(key string, value complex128) {
+	e.AddString(key, strconv.FormatComplex(value, 'g', -1, 128))
+}
+
+func (e *logfmtEncoder) AddComplex64(key string, value complex64) {
+	e.AddString(key, strconv.FormatComplex(complex128(value), 'g', -1, 64))
+}
+
+// AddDuration записывает длительность в секундах (как zapcore.SecondsDurationEncoder)
+func (e *logfmtEncoder) AddDuration(key string, value time.Duration) {
+	e.AddFloat64(key, value.Seconds())
+}
+
+func (e *logfmtEncoder) AddFloat64(key string, value float64) {
+	e.addFloat(key, value, 64)
+}
+
+func (e *logfmtEncoder) AddFloat32(key string, value float32) {
+	e.addFloat(key, float64(value), 32)
+}
+
+func (e *logfmtEncoder) addFloat(key string, value float64, bitSize int) {
+	e.addKey(key)
+	switch {
+	case math.IsNaN(value):
+		e.buf.AppendString("NaN")
+	case math.IsInf(value, 1):
+		e.buf.AppendString("+Inf")
+	case math.IsInf(value, -1):
+		e.buf.AppendString("-Inf")
+	default:
+		e.buf.AppendFloat(value, bitSize)
+	}
+}
+
+func (e *logfmtEncoder) AddInt(key string, value int)     { e.AddInt64(key, int64(value)) }
+func (e *logfmtEncoder) AddInt32(key string, value int32) { e.AddInt64(key, int64(value)) }
+func (e *logfmtEncoder) AddInt16(key string, value int16) { e.AddInt64(key, int64(value)) }
+func (e *logfmtEncoder) AddInt8(key string, value int8)   { e.AddInt64(key, int64(value)) }
+
+func (e *logfmtEncoder) AddInt64(key string, value int64) {
+	e.addKey(key)
+	e.buf.AppendInt(value)
+}
+
+func (e *logfmtEncoder) AddString(key, value string) {
+	e.addKey(key)
+	e.appendValue(value)
+}
+
+func (e *logfmtEncoder) AddTime(key string, value time.Time) {
+	e.AddString(key, value.Format(logfmtTimeLayout))
+}
+
+func (e *logfmtEncoder) AddUint(key string, value uint)       { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUint32(key string, value uint32)   { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUint16(key string, value uint16)   { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUint8(key string, value uint8)     { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUintptr(key string, value uintptr) { e.AddUint64(key, uint64(value)) }
+
+func (e *logfmtEncoder) AddUint64(key string, value uint64) {
+	e.addKey(key)
+	e.buf.AppendUint(value)
+}
@@ -0,0 +1,179 @@
+package logging
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// encodeFields кодирует поля записи без служебных полей и возвращает строку без перевода строки
+func encodeFields(t *testing.T, enc zapcore.Encoder, fields ...zap.Field) string {
+	t.Helper()
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, fields)
+	if err != nil {
+		t.Fatalf("EncodeEntry: %v", err)
+	}
+	defer buf.Free()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// fieldsEncoder возвращает encoder без служебных полей
+func fieldsEncoder() zapcore.Encoder {
+	return NewLogfmtEncoder(zapcore.EncoderConfig{LineEnding: "\n"})
+}
+
+func TestLogfmtQuoting(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"plain", `k=plain`},
+		{"подключение", `k=подключение`},
+		{"", `k=""`},
+		{"two words", `k="two words"`},
+		{"a=b", `k="a=b"`},
+		{`say "hi"`, `k="say \"hi\""`},
+		{`C:\path`, `k="C:\\path"`},
+		{"line\nbreak", `k="line\nbreak"`},
+		{"tab\there", `k="tab\there"`},
+		{"del\x7f", `k="del\x7f"`},
+		{"bad\xffutf8", `k="bad\xffutf8"`},
+	}
+
+	for _, tt := range tests {
+		if actual := encodeFields(t, fieldsEncoder(), zap.String("k", tt.value)); actual != tt.expected {
+			t.Errorf("значение %q: получено %s, ожидалось %s", tt.value, actual, tt.expected)
+		}
+	}
+}
+
+func TestLogfmtScalars(t *testing.T) {
+	actual := encodeFields(t, fieldsEncoder(),
+		zap.Int("int", -5),
+		zap.Uint64("uint", 7),
+		zap.Bool("ok", true),
+		zap.Float64("f", 1.5),
+		zap.Float64("nan", math.NaN()),
+		zap.Float64("inf", math.Inf(1)),
+		zap.Float32("ninf", float32(math.Inf(-1))),
+		zap.Duration("d", 1500*time.Millisecond),
+		zap.Binary("bin", []byte{0xff, 0x00}),
+		zap.ByteString("bs", []byte("a b")),
+		zap.Time("at", time.Date(2024, 5, 6, 7, 8, 9, 10_000_000, time.UTC)),
+		zap.Error(errors.New("нет связи")),
+	)
+	expected := `int=-5 uint=7 ok=true f=1.5 nan=NaN inf=+Inf ninf=-Inf d=1.5 bin="/wA=" bs="a b" ` +
+		`at=2024-05-06T07:08:09.010Z error="нет связи"`
+	if actual != expected {
+		t.Errorf("получено\n%s\nожидалось\n%s", actual, expected)
+	}
+}
+
+func TestLogfmtNested(t *testing.T) {
+	object := zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("name", "two words")
+		enc.AddInt("count", 2)
+		return enc.AddObject("inner", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddBool("ok", true)
+			return nil
+		}))
+	})
+	array := zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+		enc.AppendString("a")
+		enc.AppendInt(1)
+		return enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("k", "v")
+			return nil
+		}))
+	})
+
+	actual := encodeFields(t, fieldsEncoder(),
+		zap.Object("obj", object),
+		zap.Array("arr", array),
+		zap.Strings("list", []string{"x", "y"}),
+		zap.Any("map", map[string]int{"a": 1}),
+	)
+	expected := `obj="{\"count\":2,\"inner\":{\"ok\":true},\"name\":\"two words\"}" ` +
+		`arr="[\"a\",1,{\"k\":\"v\"}]" list="[\"x\",\"y\"]" map="{\"a\":1}"`
+	if actual != expected {
+		t.Errorf("получено\n%s\nожидалось\n%s", actual, expected)
+	}
+}
+
+func TestLogfmtNamespace(t *testing.T) {
+	actual := encodeFields(t, fieldsEncoder(),
+		zap.String("before", "1"),
+		zap.Namespace("mqtt"),
+		zap.String("broker", "tcp://b"),
+		zap.Namespace("tls"),
+		zap.Bool("enabled", false),
+	)
+	expected := `before=1 mqtt.broker=tcp://b mqtt.tls.enabled=false`
+	if actual != expected {
+		t.Errorf("получено %s, ожидалось %s", actual, expected)
+	}
+}
+
+func TestLogfmtClone(t *testing.T) {
+	parent := fieldsEncoder()
+	parent.AddString("service", "sender")
+
+	child := parent.Clone()
+	child.OpenNamespace("test")
+	child.AddInt("id", 1)
+
+	if actual := encodeFields(t, parent, zap.Int("n", 1)); actual != `service=sender n=1` {
+		t.Errorf("поля клона попали в исходный encoder: %s", actual)
+	}
+	if actual := encodeFields(t, child, zap.Int("n", 2)); actual != `service=sender test.id=1 test.n=2` {
+		t.Errorf("клон: %s", actual)
+	}
+	// Повторное кодирование не изменяет накопленные поля
+	if actual := encodeFields(t, child); actual != `service=sender test.id=1` {
+		t.Errorf("повторное кодирование клона: %s", actual)
+	}
+}
+
+func TestLogfmtEncodeEntry(t *testing.T) {
+	enc := NewLogfmtEncoder(EncoderConfig())
+	enc.AddString("component", "api")
+
+	entry := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2024, 1, 2, 3, 4, 5, 6_000_000, time.UTC),
+		LoggerName: "sender",
+		Message:    "Тест остановлен",
+		Caller:     zapcore.NewEntryCaller(0, "/src/internal/test/manager.go", 42, true),
+		Stack:      "goroutine 1",
+	}
+	buf, err := enc.EncodeEntry(entry, []zapcore.Field{zap.Namespace("test"), zap.String("id", "t 1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Free()
+
+	expected := `timestamp=2024-01-02T03:04:05.006Z level=warn logger=sender caller=test/manager.go:42 ` +
+		`message="Тест остановлен" component=api test.id="t 1" stacktrace="goroutine 1"` + "\n"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("получено\n%s\nожидалось\n%s", actual, expected)
+	}
+}
+
+func TestLogfmtLogger(t *testing.T) {
+	var out strings.Builder
+	core := zapcore.NewCore(fieldsEncoder(), zapcore.AddSync(&out), zapcore.DebugLevel)
+	logger := zap.New(core).With(zap.String("service", "recipient"))
+
+	logger.Info("первая", zap.Int("n", 1))
+	logger.With(zap.Namespace("tcp")).Info("вторая", zap.String("client", "127.0.0.1:5000"))
+
+	expected := "service=recipient n=1\nservice=recipient tcp.client=127.0.0.1:5000\n"
+	if actual := out.String(); actual != expected {
+		t.Errorf("получено\n%s\nожидалось\n%s", actual, expected)
+	}
+}
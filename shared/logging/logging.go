@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Форматы записей логов
+const (
+	FormatJSON    = "json"    // JSON объект на строку
+	FormatLogfmt  = "logfmt"  // Пары key=value на строку
+	FormatConsole = "console" // Человекочитаемый формат zap
+)
+
+// EncoderConfig возвращает общую для sender и recipient конфигурацию полей записей
+func EncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "timestamp",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "message",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
+
+// NewEncoder создает encoder формата format (json, logfmt или console)
+func NewEncoder(format string, cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	switch format {
+	case FormatJSON:
+		return zapcore.NewJSONEncoder(cfg), nil
+	case FormatLogfmt:
+		return NewLogfmtEncoder(cfg), nil
+	case FormatConsole:
+		return zapcore.NewConsoleEncoder(cfg), nil
+	default:
+		return nil, fmt.Errorf("неизвестный формат логов: %s (допустимы json, logfmt, console)", format)
+	}
+}
+
+// ValidateFormat проверяет название формата записей
+func ValidateFormat(format string) error {
+	_, err := NewEncoder(format, EncoderConfig())
+	return err
+}
+
+// ParseLevel разбирает уровень логирования (debug, info, warn, error, fatal)
+func ParseLevel(level string) (zapcore.Level, error) {
+	switch level {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "warn", "warning":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	case "fatal":
+		return zapcore.FatalLevel, nil
+	default:
+		return zapcore.InfoLevel, fmt.Errorf("неизвестный уровень: %s", level)
+	}
+}
+
+// sinkLevel уровень отдельного вывода: собственный, если задан, иначе общий
+// уровень логгера, изменяемый без перезапуска
+type sinkLevel struct {
+	global zap.AtomicLevel
+	own    *zapcore.Level
+}
+
+// Enabled проверяет, записывается ли уровень в вывод
+func (s sinkLevel) Enabled(level zapcore.Level) bool {
+	if s.own != nil {
+		return level >= *s.own
+	}
+	return s.global.Enabled(level)
+}
+
+// SinkLevel возвращает уровень вывода: level, если он задан, иначе global
+func SinkLevel(global zap.AtomicLevel, level string) (zapcore.LevelEnabler, error) {
+	if level == "" {
+		return sinkLevel{global: global}, nil
+	}
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	return sinkLevel{global: global, own: &parsed}, nil
+}
@@ -0,0 +1,352 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Параметры соединения с сервером syslog
+const (
+	syslogDialTimeout  = 2 * time.Second
+	syslogWriteTimeout = 2 * time.Second
+	syslogRetryDelay   = 5 * time.Second // Пауза между попытками подключения после ошибки
+	syslogQueueSize    = 4096            // Записей в очереди отправки; при переполнении новые отбрасываются
+	syslogFlushTimeout = 5 * time.Second // Предельное ожидание отправки очереди в Sync
+)
+
+// Предельная длина полей заголовка RFC5424 (раздел 6)
+const (
+	syslogMaxHostname = 255
+	syslogMaxAppName  = 48
+	syslogMaxMsgID    = 32
+)
+
+// syslogTimeLayout формат времени RFC5424 (RFC3339 с микросекундами)
+const syslogTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// errSyslogUnavailable запись отброшена до следующей попытки подключения
+var errSyslogUnavailable = errors.New("сервер syslog недоступен")
+
+// facilities коды источников syslog (RFC5424, раздел 6.2.1)
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// SyslogConfig параметры вывода логов на сервер syslog
+type SyslogConfig struct {
+	Network  string // udp, tcp, unix (поток) или unixgram (датаграммы, например /dev/log journald)
+	Address  string // Адрес сервера (host:port или путь к сокету)
+	Facility string // Источник: kern, user, daemon, local0-local7 и др.
+	AppName  string // APP-NAME заголовка
+	Format   string // Формат текста сообщения: logfmt или json
+}
+
+// ValidateSyslog проверяет параметры вывода на сервер syslog
+func ValidateSyslog(cfg SyslogConfig) error {
+	switch cfg.Network {
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return fmt.Errorf("неизвестный тип соединения syslog: %s (допустимы udp, tcp, unix, unixgram)", cfg.Network)
+	}
+	if cfg.Address == "" {
+		return fmt.Errorf("не указан адрес сервера syslog")
+	}
+	if _, ok := facilities[cfg.Facility]; !ok {
+		return fmt.Errorf("неизвестный источник syslog: %s", cfg.Facility)
+	}
+	if cfg.Format != FormatLogfmt && cfg.Format != FormatJSON {
+		return fmt.Errorf("неизвестный формат сообщений syslog: %s (допустимы logfmt, json)", cfg.Format)
+	}
+	return nil
+}
+
+// severity возвращает важность syslog для уровня zap
+func severity(level zapcore.Level) int {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return 7 // debug
+	case level == zapcore.InfoLevel:
+		return 6 // informational
+	case level == zapcore.WarnLevel:
+		return 4 // warning
+	case level == zapcore.ErrorLevel:
+		return 3 // error
+	default:
+		return 2 // critical: dpanic, panic, fatal
+	}
+}
+
+// syslogItem элемент очереди отправки: сообщение или отметка Sync
+type syslogItem struct {
+	msg     []byte
+	flushed chan struct{} // Закрывается, когда все предшествующие сообщения обработаны
+}
+
+// syslogConn соединение с сервером syslog, восстанавливаемое после ошибок.
+// Сообщения отправляются из отдельной горутины через очередь, поэтому медленный
+// или недоступный сервер не задерживает запись логов
+type syslogConn struct {
+	network string
+	address string
+	stream  bool      // Потоковое соединение: сообщения с префиксом длины (RFC6587)
+	errOut  io.Writer // Вывод ошибок отправки (ErrorOutput логгера недоступен горутине)
+
+	queue    chan syslogItem
+	dropped  atomic.Int64 // Отброшено при переполнении очереди с последнего сообщения о потерях
+	overflow atomic.Bool  // Переполнение уже передано в ErrorOutput логгера
+
+	// Используются только горутиной отправки
+	conn     net.Conn
+	failedAt time.Time
+	failed   int64 // Отброшено из-за ошибок отправки с последнего сообщения о потерях
+}
+
+// newSyslogConn создает соединение и запускает горутину отправки
+func newSyslogConn(network, address string, errOut io.Writer) *syslogConn {
+	c := &syslogConn{
+		network: network,
+		address: address,
+		stream:  network == "tcp" || network == "unix",
+		errOut:  errOut,
+		queue:   make(chan syslogItem, syslogQueueSize),
+	}
+	go c.run()
+	return c
+}
+
+// enqueue ставит копию сообщения в очередь без ожидания. При переполнении
+// сообщение отбрасывается; ошибка возвращается только для первого отброшенного
+// сообщения, пока горутина отправки не сообщит о потерях
+func (c *syslogConn) enqueue(msg []byte) error {
+	select {
+	case c.queue <- syslogItem{msg: bytes.Clone(msg)}:
+		return nil
+	default:
+	}
+	c.dropped.Add(1)
+	if c.overflow.CompareAndSwap(false, true) {
+		return fmt.Errorf("очередь отправки на сервер syslog переполнена (%d записей), записи отбрасываются", syslogQueueSize)
+	}
+	return nil
+}
+
+// flush ожидает отправки сообщений, поставленных в очередь до вызова, но не
+// дольше timeout
+func (c *syslogConn) flush(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	flushed := make(chan struct{})
+	select {
+	case c.queue <- syslogItem{flushed: flushed}:
+	case <-timer.C:
+		return errors.New("истекло время ожидания отправки логов на сервер syslog")
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-timer.C:
+		return errors.New("истекло время ожидания отправки логов на сервер syslog")
+	}
+}
+
+// run отправляет сообщения из очереди. Ошибки подключения и отправки выводятся
+// в errOut; пока сервер недоступен, сообщения отбрасываются без вывода ошибок
+func (c *syslogConn) run() {
+	for item := range c.queue {
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+
+		err := c.write(item.msg)
+		switch {
+		case err == nil:
+			c.reportLoss()
+		case errors.Is(err, errSyslogUnavailable):
+			c.failed++
+		default:
+			c.failed++
+			c.report("%v", err)
+		}
+	}
+}
+
+// reportLoss после успешной отправки сообщает количество записей, отброшенных
+// с прошлого сообщения о потерях
+func (c *syslogConn) reportLoss() {
+	dropped := c.dropped.Swap(0)
+	c.overflow.Store(false)
+	if dropped > 0 || c.failed > 0 {
+		c.report("отправка на сервер syslog восстановлена, отброшено записей: %d при переполнении очереди, %d при недоступности сервера",
+			dropped, c.failed)
+		c.failed = 0
+	}
+}
+
+// report выводит ошибку отправки в errOut в формате ErrorOutput zap
+func (c *syslogConn) report(format string, args ...any) {
+	fmt.Fprintf(c.errOut, "%s syslog: %s\n", time.Now().UTC().Format(time.RFC3339Nano), fmt.Sprintf(format, args...))
+}
+
+// write отправляет сообщение; при ошибке соединение закрывается, новая попытка
+// подключения выполняется не раньше чем через syslogRetryDelay
+func (c *syslogConn) write(msg []byte) error {
+	if c.conn == nil {
+		if time.Since(c.failedAt) < syslogRetryDelay {
+			return errSyslogUnavailable
+		}
+		conn, err := net.DialTimeout(c.network, c.address, syslogDialTimeout)
+		if err != nil {
+			c.failedAt = time.Now()
+			return fmt.Errorf("ошибка подключения к серверу syslog: %w", err)
+		}
+		c.conn = conn
+	}
+
+	if c.stream {
+		framed := strconv.AppendInt(make([]byte, 0, len(msg)+12), int64(len(msg)), 10)
+		framed = append(framed, ' ')
+		msg = append(framed, msg...)
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+	if _, err := c.conn.Write(msg); err != nil {
+		c.conn.Close()
+		c.conn = nil
+		c.failedAt = time.Now()
+		return fmt.Errorf("ошибка отправки на сервер syslog: %w", err)
+	}
+	return nil
+}
+
+// headerField приводит значение поля заголовка RFC5424 к допустимому виду:
+// символы вне PRINTUSASCII (33-126) заменяются на '_', длина ограничивается max;
+// пустое значение заменяется на NILVALUE "-"
+func headerField(value string, max int) string {
+	if value == "" {
+		return "-"
+	}
+	if len(value) > max {
+		value = value[:max]
+	}
+	field := []byte(value)
+	for i, b := range field {
+		if b < 33 || b > 126 {
+			field[i] = '_'
+		}
+	}
+	return string(field)
+}
+
+// syslogCore отправляет записи на сервер syslog в формате RFC5424: важность
+// определяется уровнем записи, текст сообщения содержит сообщение и поля
+type syslogCore struct {
+	zapcore.LevelEnabler
+	encoder  zapcore.Encoder
+	conn     *syslogConn
+	facility int
+	hostname string
+	appName  string
+	procID   string
+}
+
+// NewSyslogCore создает вывод логов на сервер syslog с уровнем enabler.
+// Записи отправляются из отдельной горутины через очередь; подключение
+// выполняется при первой записи. Пока сервер недоступен или очередь
+// переполнена, записи отбрасываются, ошибки отправки выводятся в stderr
+func NewSyslogCore(cfg SyslogConfig, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+	if err := ValidateSyslog(cfg); err != nil {
+		return nil, err
+	}
+
+	// Время и уровень передаются в заголовке RFC5424
+	encoderConfig := EncoderConfig()
+	encoderConfig.TimeKey = zapcore.OmitKey
+	encoderConfig.LevelKey = zapcore.OmitKey
+	encoderConfig.LineEnding = ""
+	encoder, err := NewEncoder(cfg.Format, encoderConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = ""
+	}
+
+	return &syslogCore{
+		LevelEnabler: enabler,
+		encoder:      encoder,
+		conn:         newSyslogConn(cfg.Network, cfg.Address, os.Stderr),
+		facility:     facilities[cfg.Facility],
+		hostname:     headerField(hostname, syslogMaxHostname),
+		appName:      headerField(cfg.AppName, syslogMaxAppName),
+		procID:       strconv.Itoa(os.Getpid()),
+	}, nil
+}
+
+// With добавляет поля ко всем следующим записям
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.encoder = c.encoder.Clone()
+	for _, field := range fields {
+		field.AddTo(clone.encoder)
+	}
+	return &clone
+}
+
+// Check добавляет вывод к записи, если ее уровень включен
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write отправляет запись: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID - MSG
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	body, err := c.encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer body.Free()
+
+	msgID := headerField(ent.LoggerName, syslogMaxMsgID)
+
+	var msg bytes.Buffer
+	msg.Grow(body.Len() + 128)
+	msg.WriteByte('<')
+	msg.WriteString(strconv.Itoa(c.facility*8 + severity(ent.Level)))
+	msg.WriteString(">1 ")
+	msg.WriteString(ent.Time.Format(syslogTimeLayout))
+	msg.WriteByte(' ')
+	msg.WriteString(c.hostname)
+	msg.WriteByte(' ')
+	msg.WriteString(c.appName)
+	msg.WriteByte(' ')
+	msg.WriteString(c.procID)
+	msg.WriteByte(' ')
+	msg.WriteString(msgID)
+	msg.WriteString(" - ")
+	msg.Write(bytes.TrimRight(body.Bytes(), "\n"))
+
+	return c.conn.enqueue(msg.Bytes())
+}
+
+// Sync ожидает отправки записей, поставленных в очередь, не дольше syslogFlushTimeout
+func (c *syslogCore) Sync() error {
+	return c.conn.flush(syslogFlushTimeout)
+}
@@ -0,0 +1,175 @@
+package logging
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// readFramed читает сообщение с префиксом длины (RFC6587)
+func readFramed(r *bufio.Reader) (string, error) {
+	prefix, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return "", err
+	}
+	return string(msg), nil
+}
+
+func TestSyslogCoreHeaderLimits(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if msg, err := readFramed(bufio.NewReader(conn)); err == nil {
+			received <- msg
+		}
+	}()
+
+	core, err := NewSyslogCore(SyslogConfig{
+		Network:  "tcp",
+		Address:  listener.Addr().String(),
+		Facility: "local0",
+		AppName:  strings.Repeat("a", 60),
+		Format:   FormatLogfmt,
+	}, zapcore.DebugLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(core).Named(strings.Repeat("m", 20) + " " + strings.Repeat("n", 20))
+	logger.Warn("проверка")
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	var msg string
+	select {
+	case msg = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("сообщение не получено сервером")
+	}
+
+	fields := strings.SplitN(msg, " ", 7)
+	if len(fields) < 7 {
+		t.Fatalf("некорректный заголовок: %q", msg)
+	}
+	if fields[0] != "<132>1" {
+		t.Errorf("PRI и версия %q, ожидалось <132>1", fields[0])
+	}
+	if want := strings.Repeat("a", syslogMaxAppName); fields[3] != want {
+		t.Errorf("APP-NAME %q, ожидалось %q", fields[3], want)
+	}
+	if want := strings.Repeat("m", 20) + "_" + strings.Repeat("n", 11); fields[5] != want {
+		t.Errorf("MSGID %q, ожидалось %q", fields[5], want)
+	}
+	if !strings.Contains(fields[6], "проверка") {
+		t.Errorf("текст сообщения %q не содержит запись", fields[6])
+	}
+}
+
+func TestHeaderField(t *testing.T) {
+	tests := []struct {
+		value string
+		max   int
+		want  string
+	}{
+		{"", 48, "-"},
+		{"sender", 48, "sender"},
+		{"abcdef", 4, "abcd"},
+		{"a b\tc", 48, "a_b_c"},
+		{"модуль", 48, "____________"},
+	}
+	for _, tt := range tests {
+		if got := headerField(tt.value, tt.max); got != tt.want {
+			t.Errorf("headerField(%q, %d) = %q, ожидалось %q", tt.value, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestSyslogConnEnqueueDoesNotBlock(t *testing.T) {
+	// Горутина отправки не запущена: очередь не разбирается, как при зависшем сервере
+	c := &syslogConn{queue: make(chan syslogItem, 2)}
+
+	done := make(chan struct{})
+	var errs int
+	go func() {
+		defer close(done)
+		for range 10 {
+			if c.enqueue([]byte("msg")) != nil {
+				errs++
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("запись заблокирована при переполнении очереди")
+	}
+
+	if dropped := c.dropped.Load(); dropped != 8 {
+		t.Errorf("отброшено %d записей, ожидалось 8", dropped)
+	}
+	if errs != 1 {
+		t.Errorf("ошибок переполнения %d, ожидалась 1", errs)
+	}
+	if err := c.flush(50 * time.Millisecond); err == nil {
+		t.Error("flush завершился без горутины отправки")
+	}
+}
+
+func TestSyslogConnReportsLossAfterRecovery(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	}()
+
+	var errOut strings.Builder
+	c := newSyslogConn("tcp", listener.Addr().String(), &errOut)
+	c.dropped.Store(3)
+	c.overflow.Store(true)
+	if err := c.enqueue([]byte("msg")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.flush(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.overflow.Load() || c.dropped.Load() != 0 {
+		t.Error("счетчик потерь не сброшен после успешной отправки")
+	}
+	if !strings.Contains(errOut.String(), "отброшено записей: 3") {
+		t.Errorf("потери не сообщены: %q", errOut.String())
+	}
+}
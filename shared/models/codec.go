@@ -0,0 +1,176 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/mailru/easyjson"
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+//go:generate easyjson -no_std_marshalers models.go
+
+// Сериализация Message, MessageBatch и Data без отражения кодом, сгенерированным
+// easyjson (models_easyjson.go): encoding/json тратит большую часть времени пакетных
+// тестов на обход полей через reflect. Результат сериализации совпадает с json.Marshal,
+// включая экранирование HTML символов; некорректный UTF-8 заменяется на \ufffd, а не на
+// сам символ U+FFFD. При разборе неизвестные поля пропускаются, null не изменяет поле;
+// имена полей, в отличие от json.Unmarshal, сравниваются с учетом регистра.
+// После изменения структур models.go код обновляется командой go generate
+
+// appendEasyJSON дописывает JSON представление v в dst
+func appendEasyJSON(dst []byte, v easyjson.Marshaler) []byte {
+	w := jwriter.Writer{}
+	v.MarshalEasyJSON(&w)
+	return append(dst, w.Buffer.BuildBytes()...)
+}
+
+// AppendJSON дописывает JSON представление сообщения в dst
+func (m Message) AppendJSON(dst []byte) []byte {
+	return appendEasyJSON(dst, m)
+}
+
+// MarshalJSON сериализует сообщение без отражения
+func (m Message) MarshalJSON() ([]byte, error) {
+	return m.AppendJSON(nil), nil
+}
+
+// UnmarshalJSON разбирает сообщение без отражения
+func (m *Message) UnmarshalJSON(data []byte) error {
+	return easyjson.Unmarshal(data, m)
+}
+
+// PeekSequence извлекает test_id и sequence сообщения, не разбирая остальные поля;
+// для проверки порядка до полного разбора. Пустые значения, если полей нет или JSON некорректен
+func PeekSequence(data []byte) (testID string, sequence int64) {
+	l := jlexer.Lexer{Data: data}
+	testID, sequence = peekSequence(&l)
+	if l.Consumed(); l.Error() != nil {
+		return "", 0
+	}
+	return testID, sequence
+}
+
+// peekSequence читает из l объект сообщения, извлекая только test_id и sequence
+func peekSequence(l *jlexer.Lexer) (testID string, sequence int64) {
+	l.Delim('{')
+	for !l.IsDelim('}') {
+		key := l.UnsafeFieldName(false)
+		l.WantColon()
+		if l.IsNull() {
+			l.Skip()
+			l.WantComma()
+			continue
+		}
+		switch key {
+		case "test_id":
+			testID = l.String()
+		case "sequence":
+			sequence = l.Int64()
+		default:
+			l.SkipRecursive()
+		}
+		l.WantComma()
+	}
+	l.Delim('}')
+	return testID, sequence
+}
+
+// batchPrefix начало JSON представления пакета сообщений (MessageBatch.AppendJSON);
+// сообщение начинается с поля send_time, поэтому пакет отличается без разбора
+var batchPrefix = []byte(`{"messages":`)
+
+// IsBatchPayload сообщает, что data - пакет сообщений MessageBatch, а не одно сообщение
+func IsBatchPayload(data []byte) bool {
+	return bytes.HasPrefix(data, batchPrefix)
+}
+
+// PeekBatchSequences передает в observe test_id и sequence каждого сообщения пакета
+// data по порядку, не разбирая остальные поля; возвращает количество сообщений
+func PeekBatchSequences(data []byte, observe func(testID string, sequence int64)) (int, error) {
+	count := 0
+	l := jlexer.Lexer{Data: data}
+	l.Delim('{')
+	for !l.IsDelim('}') {
+		key := l.UnsafeFieldName(false)
+		l.WantColon()
+		if key != "messages" || l.IsNull() {
+			l.SkipRecursive()
+			l.WantComma()
+			continue
+		}
+
+		l.Delim('[')
+		for !l.IsDelim(']') {
+			if l.IsNull() {
+				l.Skip()
+				l.WantComma()
+				continue
+			}
+			testID, sequence := peekSequence(&l)
+			if !l.Ok() {
+				break
+			}
+			count++
+			if observe != nil {
+				observe(testID, sequence)
+			}
+			l.WantComma()
+		}
+		l.Delim(']')
+		l.WantComma()
+	}
+	l.Delim('}')
+	l.Consumed()
+	return count, l.Error()
+}
+
+// AppendJSON дописывает JSON представление пакета в dst
+func (b MessageBatch) AppendJSON(dst []byte) []byte {
+	return appendEasyJSON(dst, b)
+}
+
+// MarshalJSON сериализует пакет без отражения
+func (b MessageBatch) MarshalJSON() ([]byte, error) {
+	return b.AppendJSON(nil), nil
+}
+
+// UnmarshalJSON разбирает пакет без отражения
+func (b *MessageBatch) UnmarshalJSON(data []byte) error {
+	return easyjson.Unmarshal(data, b)
+}
+
+// AppendJSON дописывает JSON представление записи в dst; запись по пользовательской
+// схеме (Raw) сжимается и экранируется так же, как json.Marshal
+func (d Data) AppendJSON(dst []byte) []byte {
+	if len(d.Raw) > 0 {
+		if raw, err := json.Marshal(d.Raw); err == nil {
+			return append(dst, raw...)
+		}
+		return append(dst, d.Raw...)
+	}
+	return appendEasyJSON(dst, d)
+}
+
+// UnmarshalJSON разбирает стандартную запись без отражения; Raw не заполняется
+func (d *Data) UnmarshalJSON(data []byte) error {
+	return easyjson.Unmarshal(data, d)
+}
+
+// UnmarshalDataArray разбирает JSON-массив записей без отражения; null в массиве
+// дает nil элемент, как у json.Unmarshal в []*Data
+func UnmarshalDataArray(data []byte) ([]*Data, error) {
+	var records dataList
+	if err := easyjson.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// QuotedLen возвращает длину строки s в JSON представлении (с кавычками и экранированием)
+func QuotedLen(s string) int {
+	w := jwriter.Writer{}
+	w.String(s)
+	return w.Size()
+}
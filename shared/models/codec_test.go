@@ -0,0 +1,311 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+// Типы без методов сериализации: эталонный результат encoding/json через reflect
+type (
+	plainMessage Message
+	plainData    Data
+	plainBatch   struct {
+		Messages  []*plainMessage `json:"messages"`
+		Timestamp string          `json:"timestamp"`
+		Count     int             `json:"count"`
+	}
+)
+
+// codecStrings строки, проверяющие экранирование: управляющие и HTML символы,
+// некорректный UTF-8, U+2028/U+2029 и символы вне BMP
+var codecStrings = []string{
+	"",
+	"2024-01-02T03:04:05.123456789Z",
+	`{"id":1,"value":"a\"b"}`,
+	"<script>&amp;</script>",
+	"\x00\x01\x1f\t\n\r\\/",
+	"\xff\xfe некорректный \xc3",
+	"  ",
+	"Привет, мир 😀",
+}
+
+// assertSameJSON сравнивает результат сериализации с json.Marshal: побайтно, а для
+// строк с некорректным UTF-8 (U+FFFD записывается как \ufffd) - по разобранному значению
+func assertSameJSON(t *testing.T, got, want []byte, validUTF8 bool) {
+	t.Helper()
+	if validUTF8 {
+		if !bytes.Equal(got, want) {
+			t.Errorf("\n получено  %s\n ожидалось %s", got, want)
+		}
+		return
+	}
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("%s: %v", got, err)
+	}
+	if err := json.Unmarshal(want, &wantValue); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("\n получено  %s\n ожидалось %s", got, want)
+	}
+}
+
+func testMessages() []Message {
+	var messages []Message
+	for i, s := range codecStrings {
+		messages = append(messages, Message{
+			SendTime:  s,
+			MessageID: i - 3,
+			Timestamp: s,
+			Payload:   s,
+			Checksum:  s,
+		})
+	}
+	messages = append(messages, Message{
+		SendTime:  "1700000000000000",
+		MessageID: 1 << 40,
+		Timestamp: "2024-01-02T03:04:05Z",
+		Payload:   "[]",
+		Checksum:  "abc",
+		Schema:    SchemaEpochMicros,
+		TestID:    "test-<1>",
+		Sequence:  -9223372036854775808,
+		TTL:       9223372036854775807,
+		Attempt:   2,
+		Tenant:    "team&co",
+		RunLabel:  "run",
+		File:      &FilePart{TransferID: "t", Index: FileManifestIndex, Offset: 1 << 33, SHA256: "ff"},
+		Padding:   "0000",
+	}, Message{File: &FilePart{}})
+	return messages
+}
+
+func TestMessageMarshalMatchesEncodingJSON(t *testing.T) {
+	for _, m := range testMessages() {
+		want, err := json.Marshal(plainMessage(m))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertSameJSON(t, got, want, utf8.ValidString(m.Payload))
+		if prefix := []byte("prefix"); !bytes.Equal(m.AppendJSON(prefix), append(prefix, got...)) {
+			t.Errorf("AppendJSON не сохраняет содержимое dst")
+		}
+	}
+}
+
+func TestMessageBatchMarshalMatchesEncodingJSON(t *testing.T) {
+	messages := testMessages()
+	batches := []MessageBatch{
+		{},
+		{Messages: []*Message{}, Timestamp: "<t>", Count: 0},
+		{Messages: []*Message{&messages[0], nil, &messages[len(messages)-2]}, Timestamp: "t", Count: 3},
+	}
+	for _, b := range batches {
+		plain := plainBatch{Timestamp: b.Timestamp, Count: b.Count}
+		if b.Messages != nil {
+			plain.Messages = []*plainMessage{}
+			for _, m := range b.Messages {
+				plain.Messages = append(plain.Messages, (*plainMessage)(m))
+			}
+		}
+		want, err := json.Marshal(plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertSameJSON(t, got, want, true)
+		if !IsBatchPayload(got) {
+			t.Errorf("IsBatchPayload(%s) = false", got)
+		}
+	}
+}
+
+func TestDataMarshalMatchesEncodingJSON(t *testing.T) {
+	for i, s := range codecStrings {
+		d := Data{ID: i, Timestamp: s, IndicatorID: -i, IndicatorValue: s, EquipmentID: i * 1000}
+		want, err := json.Marshal(plainData(d))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := json.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertSameJSON(t, got, want, utf8.ValidString(s))
+	}
+
+	raw := Data{ID: 1, Raw: json.RawMessage(`{ "tag" : "<a>" }`)}
+	if got := string(raw.AppendJSON(nil)); got != `{"tag":"\u003ca\u003e"}` {
+		t.Errorf("Data.Raw: получено %s", got)
+	}
+}
+
+// codecInputs документы для сравнения разбора с json.Unmarshal
+var codecInputs = []string{
+	`{}`,
+	` { "send_time" : "t" , "message_id" : 7 } `,
+	`{"send_time":"aé😀\n\"","payload":"\/x","checksum":null,"message_id":-1}`,
+	`{"unknown":{"a":[1,2,{"b":null}],"c":"}"},"test_id":"x","sequence":42,"ttl_ms":1000,"attempt":3}`,
+	`{"file":{"transfer_id":"t","index":-1,"offset":123,"sha256":"ff","extra":true},"padding":"00"}`,
+	`{"file":null,"schema":2,"tenant":"a","run_label":"b"}`,
+	`{"id":5,"timestamp":"2024","indicator_id":1,"indicator_value":"v","equipment_id":9,"more":1.5e3}`,
+}
+
+// codecInvalid документы, которые не разбирает ни один из декодеров
+var codecInvalid = []string{
+	``,
+	`{`,
+	`{"message_id":"1"}`,
+	`{"message_id":1.5}`,
+	`{"send_time":1}`,
+	`{"send_time":"t"} x`,
+	`{"send_time":"t",}`,
+	`[]`,
+}
+
+func TestMessageUnmarshalMatchesEncodingJSON(t *testing.T) {
+	for _, input := range codecInputs {
+		var want plainMessage
+		if err := json.Unmarshal([]byte(input), &want); err != nil {
+			t.Fatalf("json.Unmarshal(%s): %v", input, err)
+		}
+		var got Message
+		if err := got.UnmarshalJSON([]byte(input)); err != nil {
+			t.Fatalf("UnmarshalJSON(%s): %v", input, err)
+		}
+		if !reflect.DeepEqual(plainMessage(got), want) {
+			t.Errorf("Message(%s):\n получено  %+v\n ожидалось %+v", input, got, want)
+		}
+	}
+
+	// null не изменяет уже заполненное поле
+	m := Message{SendTime: "t", MessageID: 1}
+	if err := m.UnmarshalJSON([]byte(`{"send_time":null,"message_id":null}`)); err != nil || m.SendTime != "t" || m.MessageID != 1 {
+		t.Errorf("null изменил поля: %+v, %v", m, err)
+	}
+
+	for _, input := range codecInvalid {
+		var plain plainMessage
+		if json.Unmarshal([]byte(input), &plain) == nil {
+			t.Fatalf("json.Unmarshal(%s) без ошибки", input)
+		}
+		var m Message
+		if err := m.UnmarshalJSON([]byte(input)); err == nil {
+			t.Errorf("UnmarshalJSON(%s) без ошибки", input)
+		}
+	}
+}
+
+func TestMessageBatchUnmarshalMatchesEncodingJSON(t *testing.T) {
+	inputs := []string{
+		`{"messages":null,"timestamp":"t","count":0}`,
+		`{"messages":[],"count":0}`,
+		`{"messages":[` + codecInputs[3] + `,null,` + codecInputs[4] + `],"timestamp":"t","count":3}`,
+	}
+	for _, input := range inputs {
+		var want plainBatch
+		if err := json.Unmarshal([]byte(input), &want); err != nil {
+			t.Fatalf("json.Unmarshal(%s): %v", input, err)
+		}
+		var got MessageBatch
+		if err := got.UnmarshalJSON([]byte(input)); err != nil {
+			t.Fatalf("UnmarshalJSON(%s): %v", input, err)
+		}
+
+		if (got.Messages == nil) != (want.Messages == nil) || len(got.Messages) != len(want.Messages) {
+			t.Fatalf("MessageBatch(%s): messages %v, ожидалось %v", input, got.Messages, want.Messages)
+		}
+		for i := range got.Messages {
+			if !reflect.DeepEqual((*plainMessage)(got.Messages[i]), want.Messages[i]) {
+				t.Errorf("MessageBatch(%s): сообщение %d отличается", input, i)
+			}
+		}
+		if got.Timestamp != want.Timestamp || got.Count != want.Count {
+			t.Errorf("MessageBatch(%s): %+v, ожидалось %+v", input, got, want)
+		}
+	}
+}
+
+func TestDataUnmarshalMatchesEncodingJSON(t *testing.T) {
+	for _, input := range codecInputs {
+		var want plainData
+		if err := json.Unmarshal([]byte(input), &want); err != nil {
+			t.Fatalf("json.Unmarshal(%s): %v", input, err)
+		}
+		var got Data
+		if err := got.UnmarshalJSON([]byte(input)); err != nil {
+			t.Fatalf("UnmarshalJSON(%s): %v", input, err)
+		}
+		if !reflect.DeepEqual(plainData(got), want) {
+			t.Errorf("Data(%s):\n получено  %+v\n ожидалось %+v", input, got, want)
+		}
+	}
+
+	array := `[` + codecInputs[6] + `,null,{}]`
+	var want []*plainData
+	if err := json.Unmarshal([]byte(array), &want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalDataArray([]byte(array))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) || got[1] != nil ||
+		!reflect.DeepEqual((*plainData)(got[0]), want[0]) || !reflect.DeepEqual((*plainData)(got[2]), want[2]) {
+		t.Errorf("UnmarshalDataArray: %v, ожидалось %v", got, want)
+	}
+	if records, err := UnmarshalDataArray([]byte("null")); err != nil || records != nil {
+		t.Errorf("UnmarshalDataArray(null) = %v, %v", records, err)
+	}
+	if records, err := UnmarshalDataArray([]byte("[]")); err != nil || records == nil || len(records) != 0 {
+		t.Errorf("UnmarshalDataArray([]) = %v, %v", records, err)
+	}
+}
+
+func TestPeekSequence(t *testing.T) {
+	m := Message{SendTime: "t", Payload: `{"test_id":"вложенный","sequence":1}`, TestID: "test-1", Sequence: 17}
+	data := m.AppendJSON(nil)
+	if testID, sequence := PeekSequence(data); testID != "test-1" || sequence != 17 {
+		t.Errorf("PeekSequence = %q, %d", testID, sequence)
+	}
+	if testID, sequence := PeekSequence(data[:len(data)-1]); testID != "" || sequence != 0 {
+		t.Errorf("PeekSequence некорректного JSON = %q, %d", testID, sequence)
+	}
+
+	batch := MessageBatch{Messages: []*Message{&m, nil, {TestID: "test-2", Sequence: 18}}, Count: 2}
+	data = batch.AppendJSON(nil)
+	var sequences []int64
+	n, err := PeekBatchSequences(data, func(testID string, sequence int64) {
+		sequences = append(sequences, sequence)
+	})
+	if err != nil || n != 2 || !reflect.DeepEqual(sequences, []int64{17, 18}) {
+		t.Errorf("PeekBatchSequences = %d, %v, %v", n, sequences, err)
+	}
+	if _, err := PeekBatchSequences(data[:len(data)-1], nil); err == nil {
+		t.Error("PeekBatchSequences некорректного JSON без ошибки")
+	}
+}
+
+func TestQuotedLen(t *testing.T) {
+	for _, s := range codecStrings {
+		// Длина должна совпадать с фактической сериализацией сообщения
+		quoted := Message{Payload: s}.AppendJSON(nil)
+		quoted = quoted[bytes.Index(quoted, []byte(`"payload":`))+len(`"payload":`):]
+		quoted = quoted[:bytes.Index(quoted, []byte(`,"checksum"`))]
+		if got := QuotedLen(s); got != len(quoted) {
+			t.Errorf("QuotedLen(%q) = %d, ожидалось %d", s, got, len(quoted))
+		}
+	}
+}
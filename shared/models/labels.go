@@ -0,0 +1,26 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MaxLabelLength наибольшая длина метки теста (tenant, run_label)
+const MaxLabelLength = 64
+
+// labelPattern допустимое значение метки теста: метки выводятся в метках метрик
+// Prometheus и используются как уровень топика MQTT, поэтому не содержат кавычек,
+// пробелов, '/', '+' и '#'
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidateLabel проверяет значение метки теста field; пустое значение допустимо
+func ValidateLabel(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > MaxLabelLength || !labelPattern.MatchString(value) {
+		return fmt.Errorf("некорректное значение %s %q: допустимы латинские буквы, цифры, '_', '-' и '.', не больше %d символов",
+			field, value, MaxLabelLength)
+	}
+	return nil
+}
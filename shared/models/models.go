@@ -1,27 +1,138 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/infodiode/shared/correlation"
 )
 
 // Message представляет структуру сообщения в брокере
+//
+//easyjson:json
 type Message struct {
 	SendTime  string `json:"send_time"`  // Время отправки сообщения
 	MessageID int    `json:"message_id"` // Уникальный идентификатор сообщения
 	Timestamp string `json:"timestamp"`  // Временная метка создания данных
 	Payload   string `json:"payload"`    // Полезная нагрузка в виде JSON строки
 	Checksum  string `json:"checksum"`   // Контрольная сумма payload (SHA256 hex)
+
+	Schema int `json:"schema,omitempty"` // Версия схемы сообщения: формат send_time (0 - RFC3339Nano)
+
+	TestID   string `json:"test_id,omitempty"`  // Идентификатор теста, к которому относится сообщение
+	Sequence int64  `json:"sequence,omitempty"` // Порядковый номер сообщения в тесте (с 1)
+	TTL      int64  `json:"ttl_ms,omitempty"`   // Срок актуальности от send_time в миллисекундах (0 - по настройке recipient)
+	Attempt  int    `json:"attempt,omitempty"`  // Номер повторной попытки отправки (0 - первая); повтор сохраняет message_id и sequence
+
+	Tenant   string `json:"tenant,omitempty"`    // Команда, запустившая тест (метка для разделения результатов)
+	RunLabel string `json:"run_label,omitempty"` // Метка прогона (серия тестов, сборка, стенд)
+
+	File *FilePart `json:"file,omitempty"` // Часть передаваемого файла; payload содержит данные фрагмента в base64 или манифест
+
+	Padding string `json:"padding,omitempty"` // Заполнение до размера сообщения, заданного в тесте (pad_to_size); не проверяется
+}
+
+// Версии схемы сообщения (поле schema). Версия определяет формат send_time; сообщения
+// без поля schema передают время в RFC3339Nano, как версия 1. Recipient, не знающий
+// версию, не может вычислить задержку сообщения
+const (
+	SchemaRFC3339     = 1 // send_time - строка RFC3339Nano
+	SchemaEpochMicros = 2 // send_time - микросекунды от начала эпохи Unix
+	SchemaEpochNanos  = 3 // send_time - наносекунды от начала эпохи Unix
+	SchemaMonotonic   = 4 // send_time - наносекунды монотонных часов хоста sender
+)
+
+// MarkRetry помечает сообщение как повторную попытку отправки attempt. Заполнение
+// сокращается на длину поля attempt, чтобы повтор сохранил размер сообщения (pad_to_size)
+func (m *Message) MarkRetry(attempt int) {
+	if m.Padding == "" {
+		m.Attempt = attempt
+		return
+	}
+
+	size := len(m.AppendJSON(nil))
+	m.Attempt = attempt
+	if excess := len(m.AppendJSON(nil)) - size; excess > 0 {
+		m.Padding = m.Padding[:max(len(m.Padding)-excess, 1)]
+	}
+}
+
+// FileManifestIndex номер части файла, содержащей манифест
+const FileManifestIndex = -1
+
+// FilePart описание части файла в тесте передачи файлов
+//
+//easyjson:json
+type FilePart struct {
+	TransferID string `json:"transfer_id"`      // Идентификатор передачи (идентификатор теста)
+	Index      int    `json:"index"`            // Номер фрагмента с 0 (FileManifestIndex - манифест в JSON)
+	Offset     int64  `json:"offset"`           // Смещение фрагмента в файле
+	SHA256     string `json:"sha256,omitempty"` // Хеш данных фрагмента (SHA256 hex)
+}
+
+// FileManifest манифест передаваемого файла; передается после всех фрагментов
+type FileManifest struct {
+	Name      string `json:"name"`       // Имя файла
+	Size      int64  `json:"size"`       // Размер файла в байтах
+	ChunkSize int    `json:"chunk_size"` // Размер фрагмента в байтах (последний может быть меньше)
+	Chunks    int    `json:"chunks"`     // Количество фрагментов
+	SHA256    string `json:"sha256"`     // Хеш всего файла (SHA256 hex)
+}
+
+// FileTransferStatus состояние приема файла на recipient
+type FileTransferStatus string
+
+const (
+	FileTransferReceiving FileTransferStatus = "receiving" // Фрагменты или манифест еще не получены
+	FileTransferVerified  FileTransferStatus = "verified"  // Файл собран, размер и хеш совпали с манифестом
+	FileTransferFailed    FileTransferStatus = "failed"    // Файл собран, но не совпал с манифестом
+)
+
+// FileTransferReport отчет recipient о приеме файла
+type FileTransferReport struct {
+	TransferID     string             `json:"transfer_id"`
+	Status         FileTransferStatus `json:"status"`
+	Manifest       *FileManifest      `json:"manifest,omitempty"` // Полученный манифест
+	ChunksReceived int                `json:"chunks_received"`    // Уникальных фрагментов
+	BytesReceived  int64              `json:"bytes_received"`     // Данных в уникальных фрагментах
+	Duplicates     int64              `json:"duplicates"`         // Повторно полученных фрагментов
+	ChunkErrors    int64              `json:"chunk_errors"`       // Фрагментов с неверным хешем или смещением
+	SHA256         string             `json:"sha256,omitempty"`   // Хеш собранного файла
+	Path           string             `json:"path,omitempty"`     // Путь к собранному файлу
+	Error          string             `json:"error,omitempty"`    // Причина несовпадения с манифестом
+	StartedAt      time.Time          `json:"started_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+	VerifyDuration float64            `json:"verify_duration_ms,omitempty"` // Время проверки собранного файла
 }
 
 // Data представляет структуру генерируемых данных
+//
+//easyjson:json
 type Data struct {
 	ID             int    `json:"id"`              // Уникальный идентификатор записи
 	Timestamp      string `json:"timestamp"`       // Временная метка создания
 	IndicatorID    int    `json:"indicator_id"`    // Идентификатор индикатора
 	IndicatorValue string `json:"indicator_value"` // Значение индикатора (15 символов)
 	EquipmentID    int    `json:"equipment_id"`    // Идентификатор оборудования
+
+	// Raw запись, сформированная по пользовательской схеме генератора;
+	// если задана, сериализуется вместо стандартных полей
+	Raw json.RawMessage `json:"-"`
+}
+
+// MarshalJSON сериализует запись, отдавая приоритет записи по пользовательской схеме
+func (d Data) MarshalJSON() ([]byte, error) {
+	if len(d.Raw) > 0 {
+		return d.Raw, nil
+	}
+	return d.AppendJSON(nil), nil
 }
 
+// dataList массив записей для разбора UnmarshalDataArray
+//
+//easyjson:json
+type dataList []*Data
+
 // LogEntry представляет структуру записи в лог файле
 type LogEntry struct {
 	Timestamp     time.Time `json:"timestamp"`                // Время события
@@ -37,53 +148,531 @@ type LogEntry struct {
 
 // TestConfig представляет конфигурацию теста
 type TestConfig struct {
-	Type           TestType     `json:"type"`             // Тип теста
-	Protocol       TestProtocol `json:"protocol"`         // Протокол передачи (MQTT или TCP)
-	ThreadCount    int          `json:"thread_count"`     // Количество потоков
-	PacketSize     int          `json:"packet_size"`      // Размер пакета в байтах
-	MessagesPerSec int          `json:"messages_per_sec"` // Сообщений в секунду
-	Duration       int          `json:"duration"`         // Продолжительность теста в секундах
-	TotalMessages  int          `json:"total_messages"`   // Общее количество сообщений
+	ID             string       `json:"id,omitempty"`          // Идентификатор теста
+	Type           TestType     `json:"type"`                  // Тип теста
+	Protocol       TestProtocol `json:"protocol"`              // Протокол передачи (MQTT, TCP, QUIC, NATS или serial)
+	Target         string       `json:"target,omitempty"`      // Отдельная точка назначения теста: топик MQTT или адрес TCP или QUIC (пусто - из конфигурации)
+	ThreadCount    int          `json:"thread_count"`          // Количество потоков
+	PacketSize     int          `json:"packet_size"`           // Размер пакета в байтах
+	MessagesPerSec int          `json:"messages_per_sec"`      // Сообщений в секунду
+	Duration       int          `json:"duration"`              // Продолжительность теста в секундах
+	TotalMessages  int          `json:"total_messages"`        // Общее количество сообщений
+	WarmupSeconds  int          `json:"warmup_seconds"`        // Прогрев в начале теста, не учитываемый в статистике (сек)
+	PadToSize      bool         `json:"pad_to_size,omitempty"` // Дополнять каждое сообщение до packet_size байт в JSON
+
+	InvalidPercent  float64  `json:"invalid_percent,omitempty"`  // Доля искаженных записей в потоке (%)
+	CorruptionKinds []string `json:"corruption_kinds,omitempty"` // Виды искажений (пусто - все)
+	MessageTTL      int      `json:"message_ttl_ms,omitempty"`   // Срок актуальности сообщений в миллисекундах (0 - не задавать)
+	SendRetries     int      `json:"send_retries,omitempty"`     // Повторных попыток отправки сообщения при ошибке (0 - без повторов)
+	Seed            int64    `json:"seed"`                       // Seed генератора случайных чисел теста (0 - выбрать случайно)
+
+	StartAt *time.Time `json:"start_at,omitempty"` // Время начала отправки (RFC3339); sender готовит тест заранее и ожидает его
+
+	Tenant   string `json:"tenant,omitempty"`    // Команда, запустившая тест; передается в сообщениях
+	RunLabel string `json:"run_label,omitempty"` // Метка прогона; передается в сообщениях
+
+	Discovery *DiscoveryConfig `json:"discovery,omitempty"` // Параметры поиска пропускной способности
+	Sweep     *SweepConfig     `json:"sweep,omitempty"`     // Параметры перебора размеров сообщений
+	Session   *SessionConfig   `json:"session,omitempty"`   // Параметры теста восстановления сессии
+	Mixed     *MixedConfig     `json:"mixed,omitempty"`     // Параметры смешанного теста MQTT и TCP
+	Replay    *ReplayConfig    `json:"replay,omitempty"`    // Параметры воспроизведения записанного трафика
+
+	ExactlyOnce  *ExactlyOnceConfig  `json:"exactly_once,omitempty"`  // Параметры проверки доставки ровно один раз
+	MQTTFeatures *MQTTFeaturesConfig `json:"mqtt_features,omitempty"` // Параметры проверки retained и last will
+	Fanout       *FanoutConfig       `json:"fanout,omitempty"`        // Точки назначения теста fan-out
+	File         *FileConfig         `json:"file,omitempty"`          // Параметры теста передачи файла
+	MQTT         *MQTTOptions        `json:"mqtt,omitempty"`          // Параметры публикации MQTT теста вместо конфигурации
+	Chaos        *ChaosConfig        `json:"chaos,omitempty"`         // Принудительные разрывы соединений во время теста
+	DataSource   *DataSource         `json:"data_source,omitempty"`   // Тестовые данные вместо набора по умолчанию для типа теста
+
+	Memory *MemoryPlan `json:"memory,omitempty"` // Оценка памяти теста с большими пакетами (заполняет sender при запуске)
+}
+
+// DataSource тестовые данные, из которых формируются сообщения теста: набор данных
+// генератора, конкретный файл набора или записи, переданные в самом запросе
+type DataSource struct {
+	Set     string  `json:"set,omitempty"`     // Набор данных (small, medium, large)
+	Size    int     `json:"size,omitempty"`    // Размер файла набора large в MB (0 - по типу теста)
+	File    string  `json:"file,omitempty"`    // Имя файла в наборе set, как в списке GET /data
+	Records []*Data `json:"records,omitempty"` // Записи данных в запросе вместо набора генератора
+}
+
+// MemoryPlan оценка памяти теста с большими пакетами перед запуском и решение по
+// ограничению памяти sender (tests.memory)
+type MemoryPlan struct {
+	DatasetBytes   int64 `json:"dataset_bytes"`   // Записи набора в JSON без сжатия
+	RecordsBytes   int64 `json:"records_bytes"`   // Память загруженных записей набора (0 - записи не загружаются)
+	PayloadBytes   int64 `json:"payload_bytes"`   // Сериализованный payload, общий для всех потоков
+	SendBytes      int64 `json:"send_bytes"`      // Память одной выполняющейся отправки
+	Threads        int   `json:"threads"`         // Потоков теста
+	InFlight       int   `json:"in_flight"`       // Одновременных отправок
+	EstimatedBytes int64 `json:"estimated_bytes"` // Итоговая оценка
+	BudgetBytes    int64 `json:"budget_bytes"`    // Ограничение памяти (0 - не задано)
+	Streaming      bool  `json:"streaming"`       // Потоковый режим: записи читаются из файла без загрузки, отправки ограничены in_flight
+	Mapped         bool  `json:"mapped"`          // Набор отображается в память: записи не загружаются, records_bytes - индекс строк
+}
+
+// ChaosConfig параметры принудительных разрывов соединений во время теста
+// для проверки переподключения и измерения потерь при нестабильной связи
+type ChaosConfig struct {
+	Interval   int            `json:"interval"`              // Период разрывов в секундах
+	DowntimeMs int            `json:"downtime_ms,omitempty"` // Время без соединения после разрыва в миллисекундах
+	Protocols  []TestProtocol `json:"protocols,omitempty"`   // Протоколы, соединения которых разрываются (пусто - протоколы теста)
+}
+
+// MQTTOptions параметры публикации MQTT одного теста; незаданные берутся из конфигурации sender
+type MQTTOptions struct {
+	QoS          *byte `json:"qos,omitempty"`           // Уровень QoS (0, 1 или 2)
+	Retained     *bool `json:"retained,omitempty"`      // Флаг retain публикуемых сообщений
+	CleanSession *bool `json:"clean_session,omitempty"` // Чистая сессия; отличная от конфигурации открывает отдельное соединение
+	Aggregate    *bool `json:"aggregate,omitempty"`     // Публиковать пакет сообщений одним сообщением брокера
+}
+
+// FileConfig параметры теста передачи файла
+type FileConfig struct {
+	Name       string `json:"name"`        // Имя файла в директории файлов sender (пусто - сгенерированный)
+	Size       int64  `json:"size"`        // Размер файла в байтах
+	ChunkSize  int    `json:"chunk_size"`  // Размер фрагмента в байтах
+	SettleTime int    `json:"settle_time"` // Максимальное ожидание проверки на recipient в секундах
+}
+
+// FanoutConfig параметры отправки одного потока в несколько точек назначения
+// (режим репликации диода, когда один источник питает несколько получателей)
+type FanoutConfig struct {
+	Destinations []Destination `json:"destinations"` // Точки назначения
+}
+
+// Destination точка назначения теста fan-out
+type Destination struct {
+	Protocol TestProtocol `json:"protocol"` // Протокол (mqtt, tcp или quic)
+	Target   string       `json:"target"`   // Топик MQTT или адрес TCP или QUIC сервера (host:port)
+}
+
+// String возвращает имя точки назначения вида protocol:target
+func (d Destination) String() string {
+	return string(d.Protocol) + ":" + d.Target
+}
+
+// MQTTFeaturesConfig параметры проверки сохраненных (retained) сообщений и last will
+// при прохождении через MQTT прокси диода
+type MQTTFeaturesConfig struct {
+	RetainedMarkers int  `json:"retained_markers"` // Сообщений с флагом retain (0 - не проверять)
+	TriggerWill     bool `json:"trigger_will"`     // Оборвать соединение для публикации last will
+	SettleTime      int  `json:"settle_time"`      // Ожидание доставки после каждого шага в секундах
+}
+
+// ExactlyOnceConfig параметры проверки доставки ровно один раз через MQTT QoS 2
+type ExactlyOnceConfig struct {
+	SendDuration int `json:"send_duration"` // Длительность отправки в секундах
+	SettleTime   int `json:"settle_time"`   // Ожидание доставки после отправки в секундах
+}
+
+// ReplayConfig параметры воспроизведения трафика, записанного через /capture
+type ReplayConfig struct {
+	Capture      string  `json:"capture"`        // Имя файла записи
+	SourceTestID string  `json:"source_test_id"` // Тест, трафик которого записан
+	Speed        float64 `json:"speed"`          // Множитель темпа воспроизведения (1 - исходный темп)
+	Events       int     `json:"events"`         // Отправок в записи
+}
+
+// MixedConfig параметры смешанного теста: часть потоков отправляет через MQTT, остальные через TCP
+type MixedConfig struct {
+	MQTTPercent float64 `json:"mqtt_percent"` // Доля потоков, отправляющих через MQTT (%)
+	MQTTThreads int     `json:"mqtt_threads"` // Потоков MQTT (рассчитывается при запуске)
+	TCPThreads  int     `json:"tcp_threads"`  // Потоков TCP (рассчитывается при запуске)
+}
+
+// SessionConfig параметры теста восстановления MQTT сессии
+type SessionConfig struct {
+	Interruptions int `json:"interruptions"`  // Количество разрывов соединения
+	Interval      int `json:"interval"`       // Длительность отправки между разрывами в секундах
+	PauseDuration int `json:"pause_duration"` // Длительность разрыва в секундах
+	SettleTime    int `json:"settle_time"`    // Ожидание доставки после отправки в секундах
+}
+
+// DiscoveryConfig параметры теста поиска максимальной пропускной способности
+type DiscoveryConfig struct {
+	StartRate      int     `json:"start_rate"`       // Начальная скорость (msg/sec)
+	StepRate       int     `json:"step_rate"`        // Прирост скорости на каждом шаге (msg/sec)
+	MaxRate        int     `json:"max_rate"`         // Максимальная проверяемая скорость (msg/sec)
+	StepDuration   int     `json:"step_duration"`    // Длительность шага в секундах
+	SettleTime     int     `json:"settle_time"`      // Ожидание доставки после шага в секундах
+	MaxLossPercent float64 `json:"max_loss_percent"` // Допустимая доля потерь (%)
+	MaxLatencyMs   float64 `json:"max_latency_ms"`   // Допустимая средняя задержка (ms)
+}
+
+// SweepConfig параметры теста зависимости пропускной способности и задержки от размера сообщения
+type SweepConfig struct {
+	Sizes        []int `json:"sizes"`         // Размеры сообщений шагов в байтах
+	StepDuration int   `json:"step_duration"` // Длительность шага в секундах
+	SettleTime   int   `json:"settle_time"`   // Ожидание доставки после шага в секундах
 }
 
 // TestType определяет тип теста
 type TestType string
 
 const (
-	TestTypeBatch  TestType = "batch"  // Пакетная отправка
-	TestTypeStream TestType = "stream" // Потоковая отправка
-	TestTypeLarge  TestType = "large"  // Большие пакеты
-	TestTypeBulk   TestType = "bulk"   // Большие пакеты в несколько потоков
+	TestTypeBatch        TestType = "batch"         // Пакетная отправка
+	TestTypeStream       TestType = "stream"        // Потоковая отправка
+	TestTypeLarge        TestType = "large"         // Большие пакеты
+	TestTypeBulk         TestType = "bulk"          // Большие пакеты в несколько потоков
+	TestTypeDiscovery    TestType = "discovery"     // Поиск максимальной устойчивой пропускной способности
+	TestTypeSession      TestType = "session"       // Проверка восстановления MQTT сессии после разрывов
+	TestTypeMixed        TestType = "mixed"         // Одновременная отправка через MQTT и TCP
+	TestTypeReplay       TestType = "replay"        // Воспроизведение записанного профиля отправки
+	TestTypeExactlyOnce  TestType = "exactly_once"  // Проверка доставки ровно один раз (MQTT QoS 2)
+	TestTypeMQTTFeatures TestType = "mqtt_features" // Проверка retained сообщений и last will
+	TestTypeFanout       TestType = "fanout"        // Одновременная отправка потока в несколько точек назначения
+	TestTypeFile         TestType = "file"          // Передача файла фрагментами с проверкой по манифесту
+	TestTypeRaw          TestType = "raw"           // Насыщение канала TCP готовыми кадрами без сериализации
+	TestTypeSweep        TestType = "sweep"         // Перебор размеров сообщений с замером пропускной способности и задержки
 )
 
 // TestProtocol определяет протокол передачи данных
 type TestProtocol string
 
 const (
-	ProtocolMQTT TestProtocol = "mqtt" // Передача через MQTT брокер
-	ProtocolTCP  TestProtocol = "tcp"  // Передача через TCP соединение
+	ProtocolMQTT   TestProtocol = "mqtt"   // Передача через MQTT брокер
+	ProtocolTCP    TestProtocol = "tcp"    // Передача через TCP соединение
+	ProtocolNATS   TestProtocol = "nats"   // Передача через поток NATS JetStream
+	ProtocolSerial TestProtocol = "serial" // Передача через последовательный порт (RS-232/RS-485)
+	ProtocolQUIC   TestProtocol = "quic"   // Передача через соединение QUIC с несколькими потоками
 )
 
 // TestStats представляет статистику теста
 type TestStats struct {
-	StartTime        time.Time     `json:"start_time"`         // Время начала теста
-	EndTime          *time.Time    `json:"end_time,omitempty"` // Время окончания теста
-	Duration         time.Duration `json:"duration"`           // Продолжительность
-	MessagesSent     int64         `json:"messages_sent"`      // Отправлено сообщений
-	MessagesReceived int64         `json:"messages_received"`  // Получено сообщений
-	BytesSent        int64         `json:"bytes_sent"`         // Отправлено байт
-	BytesReceived    int64         `json:"bytes_received"`     // Получено байт
-	Errors           int64         `json:"errors"`             // Количество ошибок
-	AvgThroughput    float64       `json:"avg_throughput"`     // Средняя пропускная способность (msg/sec)
-	AvgLatency       float64       `json:"avg_latency_ms"`     // Средняя задержка (ms)
-	MinLatency       float64       `json:"min_latency_ms"`     // Минимальная задержка (ms)
-	MaxLatency       float64       `json:"max_latency_ms"`     // Максимальная задержка (ms)
-	P50Latency       float64       `json:"p50_latency_ms"`     // 50-й перцентиль задержки
-	P95Latency       float64       `json:"p95_latency_ms"`     // 95-й перцентиль задержки
-	P99Latency       float64       `json:"p99_latency_ms"`     // 99-й перцентиль задержки
+	StartTime        time.Time     `json:"start_time"`                  // Время начала теста
+	EndTime          *time.Time    `json:"end_time,omitempty"`          // Время окончания теста
+	Duration         time.Duration `json:"duration"`                    // Продолжительность
+	MessagesSent     int64         `json:"messages_sent"`               // Отправлено сообщений
+	MessagesReceived int64         `json:"messages_received"`           // Получено сообщений
+	BytesSent        int64         `json:"bytes_sent"`                  // Отправлено байт
+	BytesReceived    int64         `json:"bytes_received"`              // Получено байт
+	Errors           int64         `json:"errors"`                      // Количество ошибок
+	InvalidSent      int64         `json:"invalid_sent"`                // Отправлено искаженных записей
+	OversizeMessages int64         `json:"oversize_messages,omitempty"` // Сообщений больше packet_size при pad_to_size
+	RetriedMessages  int64         `json:"retried_messages,omitempty"`  // Сообщений, отправленных повторно (send_retries)
+	SendRetries      int64         `json:"send_retries,omitempty"`      // Повторных попыток отправки
+	AvgThroughput    float64       `json:"avg_throughput"`              // Средняя пропускная способность (msg/sec)
+	AvgLatency       float64       `json:"avg_latency_ms"`              // Средняя задержка (ms)
+	MinLatency       float64       `json:"min_latency_ms"`              // Минимальная задержка (ms)
+	MaxLatency       float64       `json:"max_latency_ms"`              // Максимальная задержка (ms)
+	P50Latency       float64       `json:"p50_latency_ms"`              // 50-й перцентиль задержки
+	P95Latency       float64       `json:"p95_latency_ms"`              // 95-й перцентиль задержки
+	P99Latency       float64       `json:"p99_latency_ms"`              // 99-й перцентиль задержки
+}
+
+// TestStatus определяет состояние выполнения теста
+type TestStatus string
+
+const (
+	TestStatusRunning   TestStatus = "running"   // Тест выполняется
+	TestStatusCompleted TestStatus = "completed" // Тест завершен штатно
+	TestStatusStopped   TestStatus = "stopped"   // Тест остановлен пользователем
+	TestStatusFailed    TestStatus = "failed"    // Тест завершен с ошибкой
+	TestStatusAborted   TestStatus = "aborted"   // Тест прерван по порогу ошибок отправки
+)
+
+// TestResult представляет результат выполнения теста
+type TestResult struct {
+	ID               string              `json:"id"`                          // Идентификатор теста
+	Status           TestStatus          `json:"status"`                      // Состояние теста
+	Config           *TestConfig         `json:"config"`                      // Конфигурация теста
+	Stats            *TestStats          `json:"stats"`                       // Итоговая статистика
+	Timeline         []TimelinePoint     `json:"timeline,omitempty"`          // Посекундная динамика отправки
+	LatencyHistogram []HistogramBucket   `json:"latency_histogram,omitempty"` // Распределение задержек
+	ErrorBreakdown   map[string]int64    `json:"error_breakdown,omitempty"`   // Ошибки по категориям
+	Error            string              `json:"error,omitempty"`             // Причина неуспешного завершения
+	Discovery        *DiscoveryResult    `json:"discovery,omitempty"`         // Результат поиска пропускной способности
+	Sweep            *SweepResult        `json:"sweep,omitempty"`             // Результат перебора размеров сообщений
+	Session          *SessionResult      `json:"session,omitempty"`           // Результат проверки восстановления сессии
+	ExactlyOnce      *ExactlyOnceResult  `json:"exactly_once,omitempty"`      // Результат проверки доставки ровно один раз
+	MQTTFeatures     *MQTTFeaturesResult `json:"mqtt_features,omitempty"`     // Результат проверки retained и last will
+	Protocols        []ProtocolStats     `json:"protocols,omitempty"`         // Статистика по протоколам (смешанный тест)
+	Destinations     []ProtocolStats     `json:"destinations,omitempty"`      // Статистика по точкам назначения (тест fan-out)
+	GeneratorHash    string              `json:"generator_config_hash"`       // Хеш параметров генератора данных на момент теста
+	DataHash         string              `json:"data_hash,omitempty"`         // Хеш содержимого набора данных теста
+	File             *FileResult         `json:"file,omitempty"`              // Результат передачи файла
+	Raw              *RawResult          `json:"raw,omitempty"`               // Результат насыщения канала кадрами-заполнителями
+	Pacing           *JitterStats        `json:"pacing,omitempty"`            // Ошибка темпа отправки относительно расписания (потоковые тесты)
+	ReceiveJitter    *JitterStats        `json:"receive_jitter,omitempty"`    // Джиттер приема по данным recipient
+	Duplicates       *DuplicateStats     `json:"duplicates,omitempty"`        // Повторы по данным recipient с разделением по причине
+	MQTTUnacked      *int64              `json:"mqtt_unacked,omitempty"`      // Сообщений без подтверждения брокера в хранилище MQTT клиента по завершении теста
+	Latency          *LatencyBreakdown   `json:"latency_breakdown,omitempty"` // Задержка по участкам пути сообщения
+	Audit            *AuditResult        `json:"audit,omitempty"`             // Потери по сводкам канала аудита recipient
+	Chaos            *ChaosResult        `json:"chaos,omitempty"`             // Принудительные разрывы соединений
+	Correlation      *correlation.Stats  `json:"correlation,omitempty"`       // Журнал корреляции отправленных сообщений
+	StartLag         *float64            `json:"start_lag_ms,omitempty"`      // Опоздание начала отправки относительно start_at (ms)
+}
+
+// DuplicateStats повторно полученные recipient сообщения теста. Повторы сообщений,
+// отправленных повторно (attempt > 0), отделяются от повторных доставок брокера или транспорта
+type DuplicateStats struct {
+	Total  int64 `json:"total"`  // Повторно полученных сообщений
+	Retry  int64 `json:"retry"`  // Повторов из-за повторной отправки sender
+	Broker int64 `json:"broker"` // Остальных повторов: повторная доставка брокером или транспортом
+}
+
+// ProtocolStats статистика отправки через один протокол в смешанном тесте
+// или в одну точку назначения в тесте fan-out
+type ProtocolStats struct {
+	Protocol         TestProtocol      `json:"protocol"`          // Протокол
+	Target           string            `json:"target,omitempty"`  // Точка назначения (тест fan-out)
+	Threads          int               `json:"threads"`           // Количество потоков
+	MessagesSent     int64             `json:"messages_sent"`     // Отправлено сообщений
+	BytesSent        int64             `json:"bytes_sent"`        // Отправлено байт
+	Errors           int64             `json:"errors"`            // Количество ошибок
+	AvgThroughput    float64           `json:"avg_throughput"`    // Средняя пропускная способность (msg/sec)
+	AvgLatency       float64           `json:"avg_latency_ms"`    // Средняя задержка отправки пакета (ms)
+	MinLatency       float64           `json:"min_latency_ms"`    // Минимальная задержка (ms)
+	MaxLatency       float64           `json:"max_latency_ms"`    // Максимальная задержка (ms)
+	LatencyHistogram []HistogramBucket `json:"latency_histogram"` // Распределение задержек
+}
+
+// SessionResult результат теста восстановления MQTT сессии
+type SessionResult struct {
+	Interruptions []SessionInterruption `json:"interruptions"`     // Выполненные разрывы соединения
+	Expected      int64                 `json:"expected"`          // Сообщений, переданных клиенту MQTT
+	Rejected      int64                 `json:"rejected"`          // Сообщений, отклоненных без соединения
+	Report        *SessionReport        `json:"report,omitempty"`  // Отчет recipient о полученных сообщениях
+	Lost          int64                 `json:"lost"`              // Потеряно сообщений
+	Passed        bool                  `json:"passed"`            // Потерь нет
+	Verdict       string                `json:"verdict,omitempty"` // Пояснение результата
+}
+
+// ExactlyOnceResult результат проверки доставки ровно один раз
+type ExactlyOnceResult struct {
+	QoS        byte           `json:"qos"`               // QoS публикации
+	Expected   int64          `json:"expected"`          // Сообщений, переданных клиенту MQTT
+	Rejected   int64          `json:"rejected"`          // Сообщений, отклоненных без соединения
+	Report     *SessionReport `json:"report,omitempty"`  // Отчет recipient о полученных сообщениях
+	Duplicates int64          `json:"duplicates"`        // Повторно доставленных сообщений
+	Missing    int64          `json:"missing"`           // Номеров теста, не полученных recipient
+	Passed     bool           `json:"passed"`            // Каждое сообщение доставлено ровно один раз
+	Verdict    string         `json:"verdict,omitempty"` // Пояснение результата
+}
+
+// FileResult результат теста передачи файла
+type FileResult struct {
+	Manifest   FileManifest        `json:"manifest"`          // Манифест переданного файла
+	ChunksSent int                 `json:"chunks_sent"`       // Отправлено фрагментов
+	Report     *FileTransferReport `json:"report,omitempty"`  // Отчет recipient о приеме файла
+	Passed     bool                `json:"passed"`            // Файл собран и совпал с манифестом
+	Verdict    string              `json:"verdict,omitempty"` // Пояснение результата
+}
+
+// RawResult результат теста насыщения канала: пропускная способность пути без
+// сериализации сообщений, с которой сравниваются тесты на уровне сообщений
+type RawResult struct {
+	FrameSize     int     `json:"frame_size"`     // Размер кадра с заголовком (байт)
+	Connections   int     `json:"connections"`    // Количество TCP соединений
+	PoolFrames    int     `json:"pool_frames"`    // Кадров в пуле, из которого выполняется отправка
+	FramesSent    int64   `json:"frames_sent"`    // Отправлено кадров (без прогрева)
+	BytesSent     int64   `json:"bytes_sent"`     // Отправлено байт с заголовками кадров (без прогрева)
+	BandwidthMbps float64 `json:"bandwidth_mbps"` // Средняя пропускная способность (Мбит/с)
+}
+
+// MQTTFeaturesResult результат проверки retained сообщений и last will.
+// Счетчики recipient приводятся как прирост за время теста
+type MQTTFeaturesResult struct {
+	RetainedSent     int64          `json:"retained_sent"`     // Отправлено сообщений с флагом retain
+	Report           *SessionReport `json:"report,omitempty"`  // Отчет recipient о полученных сообщениях
+	RetainedReceived int64          `json:"retained_received"` // Сохраненных сообщений, доставленных при повторной подписке
+	WillTriggered    bool           `json:"will_triggered"`    // Соединение оборвано для публикации last will
+	WillReceived     int64          `json:"will_received"`     // Получено сообщений last will
+	DuplicateFlagged int64          `json:"duplicate_flagged"` // Повторных доставок с флагом DUP
+	Passed           bool           `json:"passed"`            // Поведение брокера соответствует MQTT
+	Verdict          string         `json:"verdict,omitempty"` // Пояснение результата
+}
+
+// ChaosResult итоги принудительных разрывов соединений во время теста
+type ChaosResult struct {
+	Disconnects    int          `json:"disconnects"`      // Выполнено разрывов
+	Failed         int          `json:"failed"`           // Разрывы, которые не удалось выполнить
+	Reconnected    int          `json:"reconnected"`      // Разрывы, после которых соединение восстановилось
+	AvgReconnectMs float64      `json:"avg_reconnect_ms"` // Среднее время от разрыва до восстановления
+	MaxReconnectMs float64      `json:"max_reconnect_ms"` // Максимальное время от разрыва до восстановления
+	SendErrors     int64        `json:"send_errors"`      // Ошибок отправки от разрывов до восстановления
+	Events         []ChaosEvent `json:"events"`           // Разрывы (первые 100)
+}
+
+// ChaosEvent принудительный разрыв соединения
+type ChaosEvent struct {
+	Time        time.Time    `json:"time"`                   // Момент разрыва
+	Protocol    TestProtocol `json:"protocol"`               // Протокол соединения
+	ReconnectMs float64      `json:"reconnect_ms,omitempty"` // Время до восстановления соединения (0 - не восстановлено)
+	SendErrors  int64        `json:"send_errors"`            // Ошибок отправки до восстановления
+	Error       string       `json:"error,omitempty"`        // Причина, по которой разрыв не выполнен
+}
+
+// SessionInterruption разрыв соединения во время теста
+type SessionInterruption struct {
+	Start        time.Time `json:"start"`         // Момент разрыва
+	End          time.Time `json:"end"`           // Момент восстановления соединения
+	LastSequence int64     `json:"last_sequence"` // Последний номер сообщения перед разрывом
+}
+
+// SessionReport отчет recipient о сообщениях одного теста
+type SessionReport struct {
+	TestID          string            `json:"test_id"`             // Идентификатор теста
+	Tenant          string            `json:"tenant,omitempty"`    // Команда, запустившая тест
+	RunLabel        string            `json:"run_label,omitempty"` // Метка прогона
+	Received        int64             `json:"received"`            // Получено сообщений (с повторами)
+	Unique          int64             `json:"unique"`              // Уникальных номеров
+	Duplicates      int64             `json:"duplicates"`          // Повторно полученных сообщений
+	RetryDuplicates int64             `json:"retry_duplicates"`    // Из них повторов из-за повторной отправки sender (attempt > 0)
+	MaxSequence     int64             `json:"max_sequence"`        // Максимальный полученный номер
+	Missing         int64             `json:"missing"`             // Пропущенных номеров до max_sequence
+	MissingRanges   []SequenceRange   `json:"missing_ranges"`      // Диапазоны пропущенных номеров (первые 100)
+	OutOfOrder      int64             `json:"out_of_order"`        // Сообщений, пришедших после большего номера
+	Stale           int64             `json:"stale"`               // Сообщений, полученных позже срока актуальности
+	FirstSeen       time.Time         `json:"first_seen"`          // Время первого сообщения
+	LastSeen        time.Time         `json:"last_seen"`           // Время последнего сообщения
+	Jitter          *JitterStats      `json:"jitter,omitempty"`    // Джиттер интервалов прихода относительно интервалов отправки
+	Latency         *LatencyBreakdown `json:"latency,omitempty"`   // Задержка доставки и обработки (участки delivery и processing)
+}
+
+// AuditDigest сводка recipient о принятых сообщениях тестов, периодически публикуемая
+// в канал аудита (обратный канал стенда)
+type AuditDigest struct {
+	Instance string            `json:"instance"` // Экземпляр recipient
+	Time     time.Time         `json:"time"`     // Время формирования сводки
+	Tests    []AuditTestDigest `json:"tests"`    // Тесты, сообщения которых получены недавно
+}
+
+// AuditTestDigest сводка о принятых сообщениях одного теста
+type AuditTestDigest struct {
+	TestID      string    `json:"test_id"`         // Идентификатор теста
+	Received    int64     `json:"received"`        // Получено сообщений (с повторами)
+	Unique      int64     `json:"unique"`          // Уникальных номеров
+	MaxSequence int64     `json:"max_sequence"`    // Максимальный полученный номер
+	Digest      string    `json:"sequence_digest"` // Дайджест уникальных номеров (XOR перемешанных номеров, hex)
+	LastSeen    time.Time `json:"last_seen"`       // Время последнего сообщения
+}
+
+// AuditResult потери теста, вычисленные sender по сводкам канала аудита
+type AuditResult struct {
+	Sent        int64     `json:"sent"`         // Отправлено сообщений с номерами теста (включая прогрев)
+	Received    int64     `json:"received"`     // Получено recipient (с повторами)
+	Unique      int64     `json:"unique"`       // Уникальных номеров, полученных recipient
+	Lost        int64     `json:"lost"`         // Отправлено, но не получено
+	LossPercent float64   `json:"loss_percent"` // Доля потерь (%)
+	DigestMatch bool      `json:"digest_match"` // Множества отправленных и полученных номеров совпадают
+	Instances   []string  `json:"instances"`    // Экземпляры recipient, приславшие сводки
+	UpdatedAt   time.Time `json:"updated_at"`   // Время последней сводки
+}
+
+// JitterStats статистика отклонений времени: джиттера приема на recipient
+// или ошибки темпа отправки на sender
+type JitterStats struct {
+	Samples int64   `json:"samples"`       // Количество измерений
+	AvgMs   float64 `json:"avg_jitter_ms"` // Среднее отклонение (ms)
+	P95Ms   float64 `json:"p95_jitter_ms"` // 95-й перцентиль отклонения (ms)
+	MaxMs   float64 `json:"max_jitter_ms"` // Максимальное отклонение (ms)
+}
+
+// HopLatency статистика задержки на одном участке пути сообщения
+type HopLatency struct {
+	Samples int64   `json:"samples"` // Количество измерений
+	AvgMs   float64 `json:"avg_ms"`  // Средняя задержка (ms)
+	P95Ms   float64 `json:"p95_ms"`  // 95-й перцентиль задержки (ms)
+	MaxMs   float64 `json:"max_ms"`  // Максимальная задержка (ms)
+}
+
+// LatencyBreakdown задержка сообщения по участкам пути. Участок send измеряет sender,
+// delivery и processing - recipient; transit вычисляется при объединении отчетов
+type LatencyBreakdown struct {
+	Send       *HopLatency `json:"send,omitempty"`       // От send_time до завершения отправки (подтверждение брокера, запись в сокет или порт)
+	Delivery   *HopLatency `json:"delivery,omitempty"`   // От send_time до получения recipient
+	Processing *HopLatency `json:"processing,omitempty"` // Обработка recipient от получения до учета в статистике
+
+	// Средняя задержка передачи через брокер или диод после завершения отправки: delivery.avg - send.avg
+	TransitAvgMs *float64 `json:"transit_avg_ms,omitempty"`
+}
+
+// WindowStats показатели сервиса за последний интервал (/stats?window=1m): скользящее
+// окно по секундам, не зависящее от накопленных счетчиков и их сброса
+type WindowStats struct {
+	Window         string      `json:"window"`            // Запрошенное окно, например 1m
+	Seconds        float64     `json:"seconds"`           // Охваченное время: меньше окна, если сервис запущен недавно
+	Messages       int64       `json:"messages"`          // Сообщений за окно
+	Bytes          int64       `json:"bytes"`             // Байт payload за окно
+	Errors         int64       `json:"errors"`            // Ошибок за окно: отправки на sender, некорректных сообщений на recipient
+	MessagesPerSec float64     `json:"messages_per_sec"`  // Средняя скорость за окно
+	BytesPerSec    float64     `json:"bytes_per_sec"`     // Средняя пропускная способность за окно
+	Latency        *HopLatency `json:"latency,omitempty"` // Задержка сообщений за окно (nil - нет измерений)
+}
+
+// SequenceRange диапазон номеров сообщений [From, To]
+type SequenceRange struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// DiscoveryResult результат поиска максимальной пропускной способности
+type DiscoveryResult struct {
+	MaxSustainableRate int             `json:"max_sustainable_rate"` // Максимальная устойчивая скорость (msg/sec)
+	StopReason         string          `json:"stop_reason"`          // Причина завершения поиска
+	Steps              []DiscoveryStep `json:"steps"`                // Результаты шагов
+}
+
+// DiscoveryStep результат одного шага поиска пропускной способности
+type DiscoveryStep struct {
+	Rate         int     `json:"rate"`           // Заданная скорость (msg/sec)
+	Sent         int64   `json:"sent"`           // Отправлено сообщений
+	Received     int64   `json:"received"`       // Получено recipient
+	LossPercent  float64 `json:"loss_percent"`   // Доля потерь (%)
+	AvgLatencyMs float64 `json:"avg_latency_ms"` // Средняя задержка по данным recipient (ms)
+	Passed       bool    `json:"passed"`         // Шаг уложился в пороги
+}
+
+// SweepResult результат перебора размеров сообщений
+type SweepResult struct {
+	Steps []SweepStep `json:"steps"` // Результаты шагов по возрастанию размера
+}
+
+// SweepStep показатели отправки сообщений одного размера. Показатели приема заполняются,
+// если задан канал оркестрации с recipient
+type SweepStep struct {
+	Size           int         `json:"size"`             // Размер сообщения в байтах
+	Sent           int64       `json:"sent"`             // Отправлено сообщений
+	Errors         int64       `json:"errors"`           // Ошибок отправки
+	MessagesPerSec float64     `json:"messages_per_sec"` // Скорость отправки (msg/sec)
+	MbitPerSec     float64     `json:"mbit_per_sec"`     // Скорость отправки по размеру сообщений (Мбит/с)
+	Send           *HopLatency `json:"send,omitempty"`   // Длительность отправки сообщения
+
+	Received     *int64   `json:"received,omitempty"`       // Получено recipient
+	LossPercent  *float64 `json:"loss_percent,omitempty"`   // Доля потерь (%)
+	AvgLatencyMs *float64 `json:"avg_latency_ms,omitempty"` // Средняя задержка по данным recipient (ms)
+}
+
+// TimelinePoint представляет показатели теста за одну секунду
+type TimelinePoint struct {
+	Second int   `json:"second"` // Секунда от начала теста
+	Sent   int64 `json:"sent"`   // Отправлено сообщений
+	Bytes  int64 `json:"bytes"`  // Отправлено байт
+	Errors int64 `json:"errors"` // Количество ошибок
+
+	Received      int64 `json:"received,omitempty"`       // Получено recipient (по каналу оркестрации)
+	ReceiveErrors int64 `json:"receive_errors,omitempty"` // Получено recipient с ошибками проверки
+}
+
+// ReceivePoint показатели приема сообщений теста на recipient за одну секунду
+type ReceivePoint struct {
+	Time     time.Time `json:"time"`     // Начало секунды по часам recipient
+	Received int64     `json:"received"` // Получено сообщений
+	Invalid  int64     `json:"invalid"`  // Из них с ошибками проверки
+}
+
+// HistogramBucket представляет корзину гистограммы задержек
+type HistogramBucket struct {
+	UpperBoundMs float64 `json:"le_ms"` // Верхняя граница корзины (ms), 0 - без границы
+	Count        int64   `json:"count"` // Количество измерений в корзине
 }
 
 // MessageBatch представляет пакет сообщений для отправки
+//
+//easyjson:json
 type MessageBatch struct {
 	Messages  []*Message `json:"messages"`  // Массив сообщений
 	Timestamp string     `json:"timestamp"` // Временная метка пакета
@@ -97,6 +686,32 @@ type HealthStatus struct {
 	Version   string    `json:"version"`   // Версия сервиса
 	Timestamp time.Time `json:"timestamp"` // Время проверки
 	Checks    []Check   `json:"checks"`    // Детальные проверки
+
+	BuildTime     string  `json:"build_time,omitempty"`     // Время сборки
+	UptimeSeconds float64 `json:"uptime_seconds,omitempty"` // Время работы сервиса
+}
+
+// HealthProbe пробное сообщение проверки пути sender -> брокер -> диод -> recipient,
+// периодически публикуемое sender в топик health_probe.topic (GET /healthz)
+type HealthProbe struct {
+	Instance string    `json:"instance"`  // Отправитель (mqtt.client_id sender)
+	Sequence int64     `json:"sequence"`  // Номер пробы с запуска sender, начиная с 1
+	SendTime time.Time `json:"send_time"` // Время публикации
+}
+
+// VersionInfo сведения о сборке и конфигурации сервиса (GET /version). Позволяет
+// перед запуском тестов убедиться, что обе стороны диода собраны и настроены совместимо
+type VersionInfo struct {
+	Service    string   `json:"service"`     // Имя сервиса
+	Version    string   `json:"version"`     // Версия сборки
+	BuildTime  string   `json:"build_time"`  // Время сборки
+	GitCommit  string   `json:"git_commit"`  // Коммит сборки
+	GoVersion  string   `json:"go_version"`  // Версия Go
+	Transports []string `json:"transports"`  // Включенные транспорты
+	Features   []string `json:"features"`    // Включенные возможности
+	ConfigHash string   `json:"config_hash"` // Хеш конфигурации, загруженной при запуске
+
+	GeneratorConfigHash string `json:"generator_config_hash,omitempty"` // Хеш параметров генератора данных (sender)
 }
 
 // Check представляет результат проверки компонента
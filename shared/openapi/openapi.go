@@ -0,0 +1,254 @@
+// Package openapi формирует документ OpenAPI 3 для HTTP API сервисов по описанию
+// маршрутов. Схемы тел запросов и ответов строятся по Go типам (теги json), поэтому
+// документ не расходится с моделями при их изменении
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version версия спецификации OpenAPI документа
+const Version = "3.0.3"
+
+// Document документ OpenAPI
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info сведения об API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag группа операций
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem операции одного пути по методам HTTP в нижнем регистре
+type PathItem map[string]*Operation
+
+// Operation операция API
+type Operation struct {
+	Tags        []string            `json:"tags,omitempty"`
+	Summary     string              `json:"summary"`
+	OperationID string              `json:"operationId"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter параметр пути или запроса
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path или query
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody тело запроса
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response ответ операции
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType содержимое тела
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components общие схемы документа
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema схема JSON значения (подмножество OpenAPI 3.0)
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// Param параметр запроса (query) операции
+type Param struct {
+	Name        string
+	Type        string // string, integer, number или boolean (пусто - string)
+	Description string
+	Required    bool
+}
+
+// Route описание операции для документа
+type Route struct {
+	Method      string  // Метод HTTP
+	Path        string  // Путь: /test/{id} или в формате gin /test/:id
+	Tag         string  // Группа операций
+	Summary     string  // Краткое описание
+	Query       []Param // Параметры запроса
+	Request     any     // Значение типа тела запроса (nil - без тела)
+	Response    any     // Значение типа ответа (nil - ответ без схемы)
+	Status      int     // Код успешного ответа (0 - 200)
+	ContentType string  // Тип содержимого ответа (пусто - application/json)
+	Errors      []int   // Коды ответов с ошибкой {"error": "..."}
+}
+
+// Builder формирует документ по описаниям маршрутов
+type Builder struct {
+	doc   Document
+	names map[reflect.Type]string // Имена схем components по типам
+}
+
+// ginParam параметр пути в формате gin
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z_][A-Za-z0-9_]*)`)
+
+// pathParam параметр пути в формате OpenAPI
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// errorSchema имя схемы ответа с ошибкой
+const errorSchema = "Error"
+
+// New создает построитель документа
+func New(info Info, tags ...Tag) *Builder {
+	b := &Builder{
+		doc: Document{
+			OpenAPI:    Version,
+			Info:       info,
+			Tags:       tags,
+			Paths:      make(map[string]PathItem),
+			Components: Components{Schemas: make(map[string]*Schema)},
+		},
+		names: make(map[reflect.Type]string),
+	}
+	b.doc.Components.Schemas[errorSchema] = &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"error": {Type: "string", Description: "Описание ошибки"}},
+		Required:   []string{"error"},
+	}
+	return b
+}
+
+// Add добавляет операции в документ
+func (b *Builder) Add(routes ...Route) {
+	for _, route := range routes {
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+
+		op := &Operation{
+			Summary:     route.Summary,
+			OperationID: operationID(method, path),
+			Responses:   make(map[string]Response),
+		}
+		if route.Tag != "" {
+			op.Tags = []string{route.Tag}
+		}
+
+		for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     match[1],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+		for _, param := range route.Query {
+			kind := param.Type
+			if kind == "" {
+				kind = "string"
+			}
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:        param.Name,
+				In:          "query",
+				Description: param.Description,
+				Required:    param.Required,
+				Schema:      &Schema{Type: kind},
+			})
+		}
+
+		if route.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{"application/json": {Schema: b.schema(reflect.TypeOf(route.Request))}},
+			}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		response := Response{Description: http.StatusText(status)}
+		switch {
+		case route.ContentType != "":
+			response.Content = map[string]MediaType{route.ContentType: {Schema: &Schema{Type: "string"}}}
+		case route.Response != nil:
+			response.Content = map[string]MediaType{"application/json": {Schema: b.schema(reflect.TypeOf(route.Response))}}
+		}
+		op.Responses[strconv.Itoa(status)] = response
+
+		for _, code := range route.Errors {
+			op.Responses[strconv.Itoa(code)] = Response{
+				Description: http.StatusText(code),
+				Content:     map[string]MediaType{"application/json": {Schema: &Schema{Ref: componentRef(errorSchema)}}},
+			}
+		}
+
+		item := b.doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			b.doc.Paths[path] = item
+		}
+		item[method] = op
+	}
+}
+
+// Document возвращает сформированный документ
+func (b *Builder) Document() *Document {
+	return &b.doc
+}
+
+// JSON возвращает документ в JSON
+func (b *Builder) JSON() ([]byte, error) {
+	return json.Marshal(b.doc)
+}
+
+// operationID формирует идентификатор операции из метода и пути: get /test/{id}/report -
+// getTestIdReport
+func operationID(method, path string) string {
+	var id strings.Builder
+	id.WriteString(method)
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '-' || r == '_'
+	}) {
+		id.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return id.String()
+}
+
+// componentRef ссылка на схему components
+func componentRef(name string) string {
+	return "#/components/schemas/" + name
+}
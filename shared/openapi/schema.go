@@ -0,0 +1,156 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// schema возвращает схему значения типа t. Именованные структуры выносятся в
+// components и возвращаются ссылкой, поэтому рекурсивные типы не зацикливаются
+func (b *Builder) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer"}
+	case reflect.Int32, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		if t == reflect.TypeFor[time.Duration]() {
+			return &Schema{Type: "integer", Format: "int64", Description: "Длительность в наносекундах"}
+		}
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return &Schema{Ref: componentRef(b.component(t))}
+	default:
+		// interface{} и прочие типы: произвольное значение
+		return &Schema{}
+	}
+}
+
+// component регистрирует схему именованной структуры t и возвращает ее имя
+func (b *Builder) component(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+
+	name := schemaName(t)
+	if _, taken := b.doc.Components.Schemas[name]; taken {
+		// Одноименные типы разных пакетов различаются именем пакета
+		name = exportedName(path.Base(t.PkgPath())) + name
+	}
+	b.names[t] = name
+	b.doc.Components.Schemas[name] = &Schema{} // Заполняется ниже; ссылки внутри типа уже разрешаются
+	*b.doc.Components.Schemas[name] = *b.structSchema(t)
+	return name
+}
+
+// structSchema возвращает схему полей структуры t по тегам json: поля без omitempty
+// обязательны, поля встроенных структур без имени в теге переносятся в схему структуры
+func (b *Builder) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	b.addFields(schema, t)
+	return schema
+}
+
+// addFields добавляет поля структуры t в schema
+func (b *Builder) addFields(schema *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.addFields(schema, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		var property *Schema
+		if hasOption(options, "string") {
+			property = &Schema{Type: "string"}
+		} else {
+			property = b.schema(field.Type)
+		}
+		schema.Properties[name] = property
+		if !hasOption(options, "omitempty") && !hasOption(options, "omitzero") && field.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// hasOption проверяет наличие параметра тега json
+func hasOption(options, option string) bool {
+	for _, value := range strings.Split(options, ",") {
+		if value == option {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaName имя схемы типа: имя Go типа, для обобщенных типов без параметров
+func schemaName(t reflect.Type) string {
+	name, _, _ := strings.Cut(t.Name(), "[")
+	return exportedName(name)
+}
+
+// exportedName возвращает имя с заглавной буквы
+func exportedName(name string) string {
+	if name == "" {
+		return name
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
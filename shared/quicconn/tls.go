@@ -0,0 +1,132 @@
+package quicconn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+// ALPN протокол приложения, согласуемый при установлении соединения QUIC
+const ALPN = "infodiode"
+
+// Коды закрытия соединения QUIC (ApplicationErrorCode)
+const (
+	CodeNormal   = 0x00 // Штатное закрытие
+	CodeShutdown = 0x01 // Остановка сервера
+	CodeChurn    = 0x02 // Принудительный разрыв для проверки переподключения
+)
+
+// sessionCacheSize количество сохраняемых билетов сессий TLS (по одному на сервер)
+const sessionCacheSize = 64
+
+// ServerTLSConfig возвращает параметры TLS сервера: сертификат из certFile и keyFile
+// или, если они не заданы, самоподписанный сертификат, созданный при запуске.
+// Возвращает также признак самоподписанного сертификата
+func ServerTLSConfig(certFile, keyFile string) (*tls.Config, bool, error) {
+	var (
+		cert       tls.Certificate
+		selfSigned bool
+		err        error
+	)
+
+	switch {
+	case certFile != "" && keyFile != "":
+		cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, false, fmt.Errorf("ошибка загрузки сертификата QUIC: %w", err)
+		}
+	case certFile == "" && keyFile == "":
+		cert, err = selfSignedCertificate()
+		if err != nil {
+			return nil, false, err
+		}
+		selfSigned = true
+	default:
+		return nil, false, fmt.Errorf("сертификат и ключ QUIC задаются вместе")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{ALPN},
+		MinVersion:   tls.VersionTLS13,
+	}, selfSigned, nil
+}
+
+// ClientTLSConfig возвращает параметры TLS клиента. Сертификат сервера проверяется
+// по центру сертификации из caFile, без caFile - по системным сертификатам. Проверка
+// отключается только явно (insecureSkipVerify, стенд с самоподписанным сертификатом
+// recipient). Билеты сессий сохраняются в кэше, что позволяет возобновлять сессию
+// и отправлять данные 0-RTT при переподключении
+func ClientTLSConfig(caFile, serverName string, insecureSkipVerify bool) (*tls.Config, error) {
+	if caFile != "" && insecureSkipVerify {
+		return nil, fmt.Errorf("центр сертификации QUIC не используется при отключенной проверке сертификата")
+	}
+
+	config := &tls.Config{
+		ServerName:         serverName,
+		NextProtos:         []string{ALPN},
+		MinVersion:         tls.VersionTLS13,
+		ClientSessionCache: tls.NewLRUClientSessionCache(sessionCacheSize),
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	if caFile == "" {
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения сертификата центра сертификации QUIC: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("файл %s не содержит сертификатов", caFile)
+	}
+	config.RootCAs = pool
+
+	return config, nil
+}
+
+// selfSignedCertificate создает самоподписанный сертификат для имени хоста и localhost
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("ошибка создания ключа QUIC: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("ошибка создания сертификата QUIC: %w", err)
+	}
+
+	names := []string{"localhost"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" && hostname != "localhost" {
+		names = append(names, hostname)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: names[len(names)-1]},
+		DNSNames:     names,
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("ошибка создания сертификата QUIC: %w", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,87 @@
+package quicconn
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// handshake выполняет рукопожатие TLS клиента с сервером через TCP на loopback
+func handshake(t *testing.T, server, client *tls.Config) error {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tls.Server(conn, server).Handshake()
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tls.Client(conn, client).Handshake()
+	conn.Close()
+	<-done
+	return err
+}
+
+func TestClientTLSConfigVerification(t *testing.T) {
+	server, selfSigned, err := ServerTLSConfig("", "")
+	if err != nil || !selfSigned {
+		t.Fatalf("ServerTLSConfig: %v, самоподписанный %v", err, selfSigned)
+	}
+
+	// Без центра сертификации и явного отключения проверки самоподписанный сертификат отклоняется
+	client, err := ClientTLSConfig("", "localhost", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.InsecureSkipVerify {
+		t.Fatal("проверка сертификата отключена без insecureSkipVerify")
+	}
+	if err := handshake(t, server, client); err == nil {
+		t.Fatal("самоподписанный сертификат принят без проверки")
+	}
+
+	client, err = ClientTLSConfig("", "localhost", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handshake(t, server, client); err != nil {
+		t.Fatalf("рукопожатие без проверки сертификата: %v", err)
+	}
+
+	// Сертификат сервера как центр сертификации
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificates[0].Certificate[0]})
+	if err := os.WriteFile(caFile, block, 0644); err != nil {
+		t.Fatal(err)
+	}
+	client, err = ClientTLSConfig(caFile, "localhost", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handshake(t, server, client); err != nil {
+		t.Fatalf("рукопожатие с центром сертификации: %v", err)
+	}
+}
+
+func TestClientTLSConfigRejectsCAWithInsecure(t *testing.T) {
+	if _, err := ClientTLSConfig("ca.pem", "", true); err == nil {
+		t.Fatal("центр сертификации принят вместе с отключенной проверкой")
+	}
+}
@@ -0,0 +1,167 @@
+package serialport
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+)
+
+// Формат кадра: маркер начала (2 байта), длина данных (4 байта, big-endian), данные
+// и CRC-32 (IEEE) длины и данных (4 байта, big-endian). Маркер позволяет найти начало
+// следующего кадра после потери или искажения байтов на линии
+const (
+	frameMagic0 = 0xA5
+	frameMagic1 = 0x5A
+
+	// HeaderSize размер заголовка кадра (маркер и длина)
+	HeaderSize = 6
+	// TrailerSize размер контрольной суммы в конце кадра
+	TrailerSize = 4
+)
+
+var (
+	// ErrFrameCRC контрольная сумма кадра не совпала
+	ErrFrameCRC = errors.New("несовпадение CRC кадра")
+	// ErrFrameSize длина кадра превышает допустимую
+	ErrFrameSize = errors.New("слишком большой кадр")
+	// ErrFrameTimeout кадр не был получен целиком за отведенное время
+	ErrFrameTimeout = errors.New("кадр получен не полностью")
+)
+
+// PutFrame оформляет кадр: frame содержит HeaderSize зарезервированных байтов и данные;
+// заполняет заголовок и возвращает кадр с добавленной контрольной суммой
+func PutFrame(frame []byte) []byte {
+	frame[0] = frameMagic0
+	frame[1] = frameMagic1
+	binary.BigEndian.PutUint32(frame[2:HeaderSize], uint32(len(frame)-HeaderSize))
+
+	return binary.BigEndian.AppendUint32(frame, crc32.ChecksumIEEE(frame[2:]))
+}
+
+// deadlineReader источник данных, поддерживающий срок ожидания чтения
+type deadlineReader interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// FrameReader читает кадры из потока байтов последовательной линии. Кадр с неверной
+// длиной, контрольной суммой или не полученный целиком пропускается: поиск следующего
+// маркера продолжается со второго байта пропущенного кадра
+type FrameReader struct {
+	r        io.Reader
+	buf      *bufio.Reader
+	maxSize  int
+	timeout  time.Duration
+	byteTime time.Duration
+
+	skipped int64 // Байтов, пропущенных при поиске начала кадра
+}
+
+// NewFrameReader создает читатель кадров с данными не больше maxSize байтов. Если r
+// поддерживает SetReadDeadline, кадр должен быть получен целиком за timeout после маркера
+// плюс время передачи его байтов (byteTime на байт)
+func NewFrameReader(r io.Reader, maxSize int, timeout, byteTime time.Duration) *FrameReader {
+	return &FrameReader{
+		r:        r,
+		buf:      bufio.NewReaderSize(r, HeaderSize+maxSize+TrailerSize),
+		maxSize:  maxSize,
+		timeout:  timeout,
+		byteTime: byteTime,
+	}
+}
+
+// Skipped возвращает количество байтов, пропущенных при поиске начала кадра
+func (f *FrameReader) Skipped() int64 {
+	return f.skipped
+}
+
+// ReadFrame возвращает данные следующего кадра. Ошибки ErrFrameCRC, ErrFrameSize
+// и ErrFrameTimeout относятся к пропущенному кадру, чтение можно продолжать
+func (f *FrameReader) ReadFrame() ([]byte, error) {
+	for {
+		// Начало кадра ожидается без ограничения времени
+		f.setDeadline(time.Time{})
+
+		b, err := f.buf.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != frameMagic0 {
+			f.skipped++
+			continue
+		}
+
+		if f.timeout > 0 {
+			f.setDeadline(time.Now().Add(f.timeout))
+		}
+		payload, err := f.readBody()
+		if err == nil {
+			return payload, nil
+		}
+
+		// Первый байт маркера пропущен, поиск продолжается со следующего
+		f.skipped++
+		if errors.Is(err, errNoMagic) {
+			continue
+		}
+		return nil, err
+	}
+}
+
+// errNoMagic за первым байтом маркера не следует второй
+var errNoMagic = errors.New("нет маркера кадра")
+
+// readBody читает оставшуюся часть кадра после первого байта маркера, не извлекая ее
+// из буфера до успешной проверки
+func (f *FrameReader) readBody() ([]byte, error) {
+	head, err := f.peek(HeaderSize - 1)
+	if err != nil {
+		return nil, err
+	}
+	if head[0] != frameMagic1 {
+		return nil, errNoMagic
+	}
+
+	length := binary.BigEndian.Uint32(head[1:])
+	if length > uint32(f.maxSize) {
+		return nil, ErrFrameSize
+	}
+
+	// Срок ожидания продлевается на время передачи объявленной длины кадра
+	if f.timeout > 0 && f.byteTime > 0 {
+		f.setDeadline(time.Now().Add(f.timeout + time.Duration(length+TrailerSize)*f.byteTime))
+	}
+
+	frame, err := f.peek(HeaderSize - 1 + int(length) + TrailerSize)
+	if err != nil {
+		return nil, err
+	}
+	body := frame[1 : len(frame)-TrailerSize]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(frame[len(frame)-TrailerSize:]) {
+		return nil, ErrFrameCRC
+	}
+
+	payload := append([]byte(nil), body[4:]...)
+	f.buf.Discard(len(frame))
+	return payload, nil
+}
+
+// peek возвращает n следующих байтов без извлечения; истечение срока ожидания
+// означает, что кадр не получен целиком
+func (f *FrameReader) peek(n int) ([]byte, error) {
+	data, err := f.buf.Peek(n)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil, ErrFrameTimeout
+	}
+	return data, err
+}
+
+// setDeadline задает срок ожидания чтения, если источник его поддерживает
+func (f *FrameReader) setDeadline(t time.Time) {
+	if d, ok := f.r.(deadlineReader); ok {
+		d.SetReadDeadline(t)
+	}
+}
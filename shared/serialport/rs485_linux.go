@@ -0,0 +1,38 @@
+package serialport
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Флаги struct serial_rs485 (linux/serial.h)
+const (
+	serRS485Enabled   = 1 << 0 // Режим RS-485 включен
+	serRS485RTSOnSend = 1 << 1 // RTS активен на время передачи
+)
+
+// serialRS485 структура ioctl TIOCSRS485
+type serialRS485 struct {
+	Flags              uint32
+	DelayRTSBeforeSend uint32
+	DelayRTSAfterSend  uint32
+	padding            [5]uint32
+}
+
+// enableRS485 включает режим RS-485 драйвера порта (направление передачи через RTS).
+// Настройка сохраняется драйвером после закрытия дескриптора и действует для порта,
+// открытого затем go.bug.st/serial
+func enableRS485(device string) error {
+	fd, err := unix.Open(device, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	rs485 := serialRS485{Flags: serRS485Enabled | serRS485RTSOnSend}
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), uintptr(unix.TIOCSRS485), uintptr(unsafe.Pointer(&rs485))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package serialport
+
+import "errors"
+
+// enableRS485 не поддерживается на этой платформе: драйверы Windows и macOS
+// управляют направлением RS-485 адаптера сами или не позволяют его настроить
+func enableRS485(device string) error {
+	return errors.New("режим RS-485 поддерживается только в Linux")
+}
@@ -0,0 +1,203 @@
+// Package serialport последовательный порт (RS-232/RS-485) и кадрирование сообщений
+// для проверки диодов с последовательным интерфейсом. Порт открывается библиотекой
+// go.bug.st/serial (Linux, Windows, macOS, BSD); режим RS-485 поддерживается только в Linux
+package serialport
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// Контроль четности
+const (
+	ParityNone = "none"
+	ParityEven = "even"
+	ParityOdd  = "odd"
+)
+
+// baudRates стандартные скорости порта
+var baudRates = map[int]struct{}{
+	1200: {}, 2400: {}, 4800: {}, 9600: {}, 19200: {}, 38400: {}, 57600: {}, 115200: {},
+	230400: {}, 460800: {}, 500000: {}, 576000: {}, 921600: {}, 1000000: {}, 1152000: {},
+	1500000: {}, 2000000: {}, 2500000: {}, 3000000: {}, 3500000: {}, 4000000: {},
+}
+
+// PortConfig параметры последовательного порта
+type PortConfig struct {
+	Device   string // Устройство (/dev/ttyS0, /dev/ttyUSB0)
+	BaudRate int    // Скорость, бод
+	DataBits int    // Битов данных (5-8)
+	Parity   string // Контроль четности: none, even или odd
+	StopBits int    // Стоповых битов (1 или 2)
+	RS485    bool   // Включить режим RS-485 драйвера (управление направлением передачи через RTS)
+}
+
+// Validate проверяет параметры порта
+func (c PortConfig) Validate() error {
+	if c.Device == "" {
+		return fmt.Errorf("не указано устройство последовательного порта")
+	}
+	if _, ok := baudRates[c.BaudRate]; !ok {
+		return fmt.Errorf("неподдерживаемая скорость последовательного порта: %d", c.BaudRate)
+	}
+	if c.DataBits < 5 || c.DataBits > 8 {
+		return fmt.Errorf("некорректное количество битов данных: %d (допустимо 5-8)", c.DataBits)
+	}
+	switch c.Parity {
+	case ParityNone, ParityEven, ParityOdd:
+	default:
+		return fmt.Errorf("некорректный контроль четности: %s (none, even или odd)", c.Parity)
+	}
+	if c.StopBits != 1 && c.StopBits != 2 {
+		return fmt.Errorf("некорректное количество стоповых битов: %d (1 или 2)", c.StopBits)
+	}
+	return nil
+}
+
+// ByteTime возвращает время передачи одного байта с учетом стартового, стопового
+// битов и бита четности
+func (c PortConfig) ByteTime() time.Duration {
+	bits := 1 + c.DataBits + c.StopBits
+	if c.Parity != ParityNone {
+		bits++
+	}
+	return time.Duration(bits) * time.Second / time.Duration(c.BaudRate)
+}
+
+// TransmitTime возвращает время передачи n байтов на скорости порта
+func (c PortConfig) TransmitTime(n int) time.Duration {
+	return time.Duration(n) * c.ByteTime()
+}
+
+// mode возвращает скорость и формат символа порта для go.bug.st/serial
+func (c PortConfig) mode() *serial.Mode {
+	mode := &serial.Mode{
+		BaudRate: c.BaudRate,
+		DataBits: c.DataBits,
+		Parity:   serial.NoParity,
+		StopBits: serial.OneStopBit,
+	}
+	switch c.Parity {
+	case ParityEven:
+		mode.Parity = serial.EvenParity
+	case ParityOdd:
+		mode.Parity = serial.OddParity
+	}
+	if c.StopBits == 2 {
+		mode.StopBits = serial.TwoStopBits
+	}
+	return mode
+}
+
+// Port открытый последовательный порт. Сроки ожидания чтения и записи задаются как
+// у net.Conn; чтение и запись из нескольких горутин одновременно не поддерживаются
+type Port struct {
+	port          serial.Port
+	config        PortConfig
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+// Open открывает и настраивает последовательный порт: обмен без преобразования
+// символов (raw), без аппаратного и программного управления потоком
+func Open(config PortConfig) (*Port, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Режим RS-485 хранится драйвером порта, поэтому включается до открытия
+	if config.RS485 {
+		if err := enableRS485(config.Device); err != nil {
+			return nil, fmt.Errorf("ошибка включения режима RS-485 порта %s: %w", config.Device, err)
+		}
+	}
+
+	port, err := serial.Open(config.Device, config.mode())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия порта %s: %w", config.Device, err)
+	}
+
+	// Данные, полученные до настройки порта, отбрасываются
+	if err := port.ResetInputBuffer(); err != nil {
+		port.Close()
+		return nil, fmt.Errorf("ошибка настройки порта %s: %w", config.Device, err)
+	}
+
+	return &Port{port: port, config: config}, nil
+}
+
+// Read читает данные из порта; по истечении срока ожидания возвращает os.ErrDeadlineExceeded
+func (p *Port) Read(b []byte) (int, error) {
+	for {
+		timeout := serial.NoTimeout
+		if !p.readDeadline.IsZero() {
+			if timeout = time.Until(p.readDeadline); timeout <= 0 {
+				return 0, os.ErrDeadlineExceeded
+			}
+		}
+		if err := p.port.SetReadTimeout(timeout); err != nil {
+			return 0, err
+		}
+
+		// Библиотека сообщает об истечении таймаута пустым чтением без ошибки
+		n, err := p.port.Read(b)
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// Write записывает данные в порт. Если срок записи истекает, порт закрывается, что
+// прерывает ожидающую запись, и Write возвращает os.ErrDeadlineExceeded
+func (p *Port) Write(b []byte) (int, error) {
+	if p.writeDeadline.IsZero() {
+		return p.port.Write(b)
+	}
+	timeout := time.Until(p.writeDeadline)
+	if timeout <= 0 {
+		return 0, os.ErrDeadlineExceeded
+	}
+
+	var expired atomic.Bool
+	timer := time.AfterFunc(timeout, func() {
+		expired.Store(true)
+		p.port.Close()
+	})
+	n, err := p.port.Write(b)
+	timer.Stop()
+	if expired.Load() {
+		return n, os.ErrDeadlineExceeded
+	}
+	return n, err
+}
+
+// SetReadDeadline задает срок ожидания чтения (нулевое время - без ограничения)
+func (p *Port) SetReadDeadline(t time.Time) error {
+	p.readDeadline = t
+	return nil
+}
+
+// SetWriteDeadline задает срок ожидания записи (нулевое время - без ограничения)
+func (p *Port) SetWriteDeadline(t time.Time) error {
+	p.writeDeadline = t
+	return nil
+}
+
+// Close закрывает порт; прерывает ожидающее чтение
+func (p *Port) Close() error {
+	return p.port.Close()
+}
+
+// Device возвращает устройство порта
+func (p *Port) Device() string {
+	return p.config.Device
+}
+
+// Config возвращает параметры порта
+func (p *Port) Config() PortConfig {
+	return p.config
+}
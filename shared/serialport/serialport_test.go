@@ -0,0 +1,184 @@
+package serialport
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// fakeSerial порт go.bug.st/serial с данными в памяти: Read возвращает данные data,
+// затем ожидает таймаут чтения и возвращает пустое чтение; Write ожидает закрытия
+type fakeSerial struct {
+	serial.Port
+
+	mu          sync.Mutex
+	data        []byte
+	readTimeout time.Duration
+	closed      chan struct{}
+	closeOnce   sync.Once
+	blockWrite  bool
+	written     bytes.Buffer
+}
+
+func newFakeSerial(data []byte) *fakeSerial {
+	return &fakeSerial{data: data, closed: make(chan struct{})}
+}
+
+func (f *fakeSerial) SetReadTimeout(t time.Duration) error {
+	f.readTimeout = t
+	return nil
+}
+
+func (f *fakeSerial) Read(b []byte) (int, error) {
+	f.mu.Lock()
+	if len(f.data) > 0 {
+		n := copy(b, f.data)
+		f.data = f.data[n:]
+		f.mu.Unlock()
+		return n, nil
+	}
+	f.mu.Unlock()
+
+	if f.readTimeout == serial.NoTimeout {
+		<-f.closed
+		return 0, errors.New("порт закрыт")
+	}
+	select {
+	case <-time.After(f.readTimeout):
+		return 0, nil
+	case <-f.closed:
+		return 0, errors.New("порт закрыт")
+	}
+}
+
+func (f *fakeSerial) Write(b []byte) (int, error) {
+	if f.blockWrite {
+		<-f.closed
+		return 0, errors.New("порт закрыт")
+	}
+	return f.written.Write(b)
+}
+
+func (f *fakeSerial) Close() error {
+	f.closeOnce.Do(func() { close(f.closed) })
+	return nil
+}
+
+func TestPortConfigMode(t *testing.T) {
+	tests := []struct {
+		config PortConfig
+		want   serial.Mode
+	}{
+		{
+			PortConfig{BaudRate: 115200, DataBits: 8, Parity: ParityNone, StopBits: 1},
+			serial.Mode{BaudRate: 115200, DataBits: 8, Parity: serial.NoParity, StopBits: serial.OneStopBit},
+		},
+		{
+			PortConfig{BaudRate: 9600, DataBits: 7, Parity: ParityEven, StopBits: 2},
+			serial.Mode{BaudRate: 9600, DataBits: 7, Parity: serial.EvenParity, StopBits: serial.TwoStopBits},
+		},
+		{
+			PortConfig{BaudRate: 4000000, DataBits: 5, Parity: ParityOdd, StopBits: 1},
+			serial.Mode{BaudRate: 4000000, DataBits: 5, Parity: serial.OddParity, StopBits: serial.OneStopBit},
+		},
+	}
+	for _, tt := range tests {
+		if got := *tt.config.mode(); got != tt.want {
+			t.Errorf("mode(%+v) = %+v, ожидалось %+v", tt.config, got, tt.want)
+		}
+	}
+}
+
+func TestPortConfigValidate(t *testing.T) {
+	valid := PortConfig{Device: "/dev/ttyS0", BaudRate: 115200, DataBits: 8, Parity: ParityNone, StopBits: 1}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	invalid := []func(c *PortConfig){
+		func(c *PortConfig) { c.Device = "" },
+		func(c *PortConfig) { c.BaudRate = 12345 },
+		func(c *PortConfig) { c.DataBits = 9 },
+		func(c *PortConfig) { c.Parity = "mark" },
+		func(c *PortConfig) { c.StopBits = 3 },
+	}
+	for i, modify := range invalid {
+		c := valid
+		modify(&c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("вариант %d: Validate без ошибки для %+v", i, c)
+		}
+	}
+
+	// 8N1: 10 битов на байт
+	if got := valid.ByteTime(); got != time.Second*10/115200 {
+		t.Errorf("ByteTime = %v", got)
+	}
+}
+
+func TestPortReadDeadline(t *testing.T) {
+	p := &Port{port: newFakeSerial([]byte("ab"))}
+
+	p.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	buf := make([]byte, 8)
+	if n, err := p.Read(buf); n != 2 || err != nil {
+		t.Fatalf("Read = %d, %v", n, err)
+	}
+
+	start := time.Now()
+	if _, err := p.Read(buf); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Read после срока: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Read ожидал %v", elapsed)
+	}
+}
+
+func TestFrameReaderIncompleteFrameOverPort(t *testing.T) {
+	frame := PutFrame(append(make([]byte, HeaderSize), "payload"...))
+	data := append(bytes.Clone(frame), frame[:len(frame)-3]...)
+	p := &Port{port: newFakeSerial(data)}
+
+	reader := NewFrameReader(p, 64, 20*time.Millisecond, 0)
+	payload, err := reader.ReadFrame()
+	if err != nil || string(payload) != "payload" {
+		t.Fatalf("ReadFrame = %q, %v", payload, err)
+	}
+
+	// Второй кадр не получен целиком: срок ожидания истекает
+	done := make(chan error, 1)
+	go func() {
+		_, err := reader.ReadFrame()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrFrameTimeout) {
+			t.Fatalf("ReadFrame: %v, ожидалось ErrFrameTimeout", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ReadFrame не завершился по сроку ожидания")
+	}
+	p.Close()
+}
+
+func TestPortWriteDeadline(t *testing.T) {
+	fake := newFakeSerial(nil)
+	p := &Port{port: fake}
+
+	p.SetWriteDeadline(time.Now().Add(time.Second))
+	if n, err := p.Write([]byte("data")); n != 4 || err != nil || fake.written.String() != "data" {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+
+	fake.blockWrite = true
+	p.SetWriteDeadline(time.Now().Add(20 * time.Millisecond))
+	if _, err := p.Write([]byte("data")); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Write после срока: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package supervisor
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Состояния, передаваемые systemd через sd_notify
+const (
+	stateReady    = "READY=1"
+	stateStopping = "STOPPING=1"
+	stateWatchdog = "WATCHDOG=1"
+)
+
+// notify отправляет состояние сервиса в сокет NOTIFY_SOCKET (протокол sd_notify).
+// Возвращает false без ошибки, если процесс запущен не под systemd
+func notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	// Имя абстрактного сокета начинается с '@', net заменяет его на нулевой байт
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// watchdogInterval возвращает интервал watchdog из WATCHDOG_USEC (WatchdogSec в unit файле);
+// 0, если watchdog не включен или предназначен другому процессу
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond
+}
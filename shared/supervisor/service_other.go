@@ -0,0 +1,8 @@
+//go:build !windows
+
+package supervisor
+
+// startPlatform вне Windows не требуется: systemd получает уведомления через NOTIFY_SOCKET
+func (s *Service) startPlatform() error {
+	return nil
+}
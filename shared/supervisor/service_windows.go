@@ -0,0 +1,69 @@
+//go:build windows
+
+package supervisor
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// startPlatform при запуске диспетчером служб Windows запускает обработчик команд службы
+func (s *Service) startPlatform() error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return fmt.Errorf("ошибка определения режима службы Windows: %w", err)
+	}
+	if !isService {
+		return nil
+	}
+
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+
+		if err := svc.Run(s.name, &handler{service: s}); err != nil {
+			select {
+			case s.errs <- fmt.Errorf("ошибка службы Windows: %w", err):
+			default:
+			}
+		}
+	}()
+
+	return nil
+}
+
+// handler обработчик команд диспетчера служб Windows
+type handler struct {
+	service *Service
+}
+
+// Execute передает диспетчеру состояние сервиса и команды остановки сервису
+func (h *handler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	s := h.service
+	status <- svc.Status{State: svc.StartPending}
+
+	ready, stopping := s.ready, s.stopping
+	for {
+		select {
+		case <-ready:
+			ready = nil
+			status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+		case <-stopping:
+			ready, stopping = nil, nil
+			status <- svc.Status{State: svc.StopPending}
+		case <-s.stopped:
+			if s.failed() != nil {
+				return false, 1
+			}
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				s.requestStop()
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package supervisor связывает сервис с супервизором процессов: уведомления systemd
+// (sd_notify: READY, WATCHDOG, STOPPING) и диспетчер служб Windows
+package supervisor
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// stoppedTimeout время ожидания передачи диспетчеру служб состояния остановки
+const stoppedTimeout = 5 * time.Second
+
+// Service состояние сервиса для супервизора. Вне systemd и диспетчера служб Windows
+// все методы ничего не делают
+type Service struct {
+	name     string
+	shutdown chan<- os.Signal
+	errs     chan<- error
+
+	ready        chan struct{}
+	stopping     chan struct{}
+	stopped      chan struct{}
+	readyOnce    sync.Once
+	stoppingOnce sync.Once
+	stoppedOnce  sync.Once
+
+	mu   sync.Mutex
+	err  error         // Ошибка, с которой завершается сервис (под mu)
+	done chan struct{} // Закрывается после завершения обработчика службы Windows, nil вне службы
+}
+
+// Start подключает сервис к супервизору. При запуске службой Windows команда остановки
+// от диспетчера служб передается в shutdown как os.Interrupt, а ошибка диспетчера - в errs
+func Start(name string, shutdown chan<- os.Signal, errs chan<- error) (*Service, error) {
+	s := &Service{
+		name:     name,
+		shutdown: shutdown,
+		errs:     errs,
+		ready:    make(chan struct{}),
+		stopping: make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	if err := s.startPlatform(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Ready сообщает супервизору, что сервис запущен и готов к работе
+func (s *Service) Ready() error {
+	s.readyOnce.Do(func() { close(s.ready) })
+
+	_, err := notify(stateReady)
+	return err
+}
+
+// Watchdog запускает отправку WATCHDOG=1 systemd с интервалом в половину WatchdogSec,
+// пока healthy (nil - всегда) возвращает true. Если сервис перестает отвечать, systemd
+// перезапускает его. Возвращает интервал watchdog; 0 - watchdog не включен
+func (s *Service) Watchdog(healthy func() bool) time.Duration {
+	interval := watchdogInterval()
+	if interval == 0 {
+		return 0
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stopping:
+				return
+			case <-ticker.C:
+				if healthy == nil || healthy() {
+					notify(stateWatchdog)
+				}
+			}
+		}
+	}()
+
+	return interval
+}
+
+// Stopping сообщает супервизору о начале остановки сервиса
+func (s *Service) Stopping() error {
+	s.stoppingOnce.Do(func() { close(s.stopping) })
+
+	_, err := notify(stateStopping)
+	return err
+}
+
+// Stopped сообщает диспетчеру служб Windows о завершении сервиса; ошибка err
+// передается как код завершения службы, чтобы сработали действия при сбое
+func (s *Service) Stopped(err error) {
+	s.Stopping()
+
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+
+	s.stoppedOnce.Do(func() { close(s.stopped) })
+
+	if s.done != nil {
+		select {
+		case <-s.done:
+		case <-time.After(stoppedTimeout):
+		}
+	}
+}
+
+// requestStop передает сервису команду остановки от диспетчера служб
+func (s *Service) requestStop() {
+	select {
+	case s.shutdown <- os.Interrupt:
+	default:
+	}
+}
+
+// failed возвращает ошибку, с которой завершается сервис
+func (s *Service) failed() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
@@ -0,0 +1,168 @@
+package tcpframe
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// Формат кадра протокола v2: тип (1 байт), длина тела (4 байта, big-endian) и тело.
+// Протокол согласуется в начале соединения: клиент отправляет кадр приветствия,
+// сервер отвечает таким же кадром с поддерживаемыми возможностями. Без согласования
+// используется исходный формат: пакет - маркер 0x01 и длина, одиночное сообщение -
+// только длина; в нем нет служебных кадров, поэтому он не допускает байтов вне кадров
+const (
+	TypeBatch   byte = 0x01 // Пакет сообщений (MessageBatch); совпадает с маркером исходного формата
+	TypeMessage byte = 0x02 // Одиночное сообщение
+	TypePing    byte = 0x03 // Проверка соединения (в обе стороны); тело - время отправки (unix nano)
+	TypePong    byte = 0x04 // Ответ на проверку; тело ping без изменений
+	TypeHello   byte = 0x05 // Приветствие: сигнатура, версия и возможности
+	TypeRaw     byte = 0x06 // Заполнитель для измерения пропускной способности; тело не разбирается
+
+	// HeaderSize размер заголовка кадра (тип и длина)
+	HeaderSize = 5
+	// Version версия протокола
+	Version = 2
+)
+
+// Возможности, согласуемые в приветствии
+const (
+	FeaturePing       byte = 1 << iota // Обмен ping/pong
+	FeatureRaw                         // Прием кадров-заполнителей TypeRaw
+	FeatureChecksum                    // Контрольная сумма кадров сообщений и пакетов
+	FeatureServerPing                  // Ping сервера: клиент отвечает pong, сервер измеряет время прохождения
+)
+
+// ChecksumSize размер контрольной суммы кадра. При согласованной FeatureChecksum тело
+// кадров TypeMessage и TypeBatch завершается CRC-32C (big-endian) предшествующих байтов
+// тела, а длина в заголовке включает контрольную сумму. Сумма считается по байтам,
+// переданным в соединение, и дополняет контрольную сумму payload в сообщении: payload
+// проверяется до сериализации кадра, кадр - в том виде, в котором он передан
+const ChecksumSize = 4
+
+// checksumTable таблица CRC-32C (Castagnoli); вычисление ускоряется инструкциями процессора
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// helloMagic сигнатура приветствия; отличает его от кадра исходного формата
+var helloMagic = [4]byte{'I', 'D', 'T', 'P'}
+
+// helloSize размер тела приветствия: сигнатура (4 байта), версия и возможности
+const helloSize = 6
+
+// pingSize размер тела ping и pong
+const pingSize = 8
+
+var (
+	// ErrNotHello кадр не является приветствием протокола v2
+	ErrNotHello = errors.New("ответ не является приветствием протокола")
+	// ErrPingSize неверная длина кадра ping или pong
+	ErrPingSize = errors.New("неверная длина кадра ping")
+)
+
+// Hello параметры приветствия
+type Hello struct {
+	Version  byte
+	Features byte
+}
+
+// Has проверяет, поддерживает ли сторона возможность feature
+func (h Hello) Has(feature byte) bool {
+	return h.Features&feature != 0
+}
+
+// PutHeader заполняет заголовок кадра: frame содержит HeaderSize зарезервированных
+// байтов и тело
+func PutHeader(frame []byte, frameType byte) {
+	frame[0] = frameType
+	binary.BigEndian.PutUint32(frame[1:HeaderSize], uint32(len(frame)-HeaderSize))
+}
+
+// AppendChecksum дописывает в кадр frame (зарезервированный заголовок и тело) контрольную
+// сумму тела; заголовок заполняется после, чтобы длина включала контрольную сумму
+func AppendChecksum(frame []byte) []byte {
+	return binary.BigEndian.AppendUint32(frame, crc32.Checksum(frame[HeaderSize:], checksumTable))
+}
+
+// NewChecksum создает вычислитель контрольной суммы тела кадра для потокового чтения
+func NewChecksum() hash.Hash32 {
+	return crc32.New(checksumTable)
+}
+
+// ReadChecksum читает контрольную сумму, завершающую тело кадра
+func ReadChecksum(r io.Reader) (uint32, error) {
+	var sum [ChecksumSize]byte
+	if _, err := io.ReadFull(r, sum[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(sum[:]), nil
+}
+
+// AppendRaw дописывает в dst кадр-заполнитель с телом body
+func AppendRaw(dst []byte, body []byte) []byte {
+	dst = append(dst, TypeRaw)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(body)))
+	return append(dst, body...)
+}
+
+// ReadHeader читает заголовок кадра и возвращает тип и длину тела
+func ReadHeader(r io.Reader) (byte, uint32, error) {
+	var header [HeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, 0, err
+	}
+	return header[0], binary.BigEndian.Uint32(header[1:]), nil
+}
+
+// AppendHello дописывает кадр приветствия в dst
+func AppendHello(dst []byte, hello Hello) []byte {
+	dst = append(dst, TypeHello, 0, 0, 0, helloSize)
+	dst = append(dst, helloMagic[:]...)
+	return append(dst, hello.Version, hello.Features)
+}
+
+// IsHello проверяет, начинается ли поток с кадра приветствия, не извлекая данных
+func IsHello(reader *bufio.Reader) bool {
+	header, err := reader.Peek(HeaderSize + len(helloMagic))
+	if err != nil {
+		return false
+	}
+	return header[0] == TypeHello &&
+		binary.BigEndian.Uint32(header[1:HeaderSize]) == helloSize &&
+		bytes.Equal(header[HeaderSize:], helloMagic[:])
+}
+
+// ReadHello читает кадр приветствия целиком
+func ReadHello(r io.Reader) (Hello, error) {
+	var frame [HeaderSize + helloSize]byte
+	if _, err := io.ReadFull(r, frame[:]); err != nil {
+		return Hello{}, err
+	}
+	if frame[0] != TypeHello || binary.BigEndian.Uint32(frame[1:HeaderSize]) != helloSize ||
+		!bytes.Equal(frame[HeaderSize:HeaderSize+len(helloMagic)], helloMagic[:]) {
+		return Hello{}, ErrNotHello
+	}
+	return Hello{Version: frame[HeaderSize+len(helloMagic)], Features: frame[HeaderSize+len(helloMagic)+1]}, nil
+}
+
+// AppendPing дописывает кадр frameType (TypePing или TypePong) с временем sent
+func AppendPing(dst []byte, frameType byte, sent time.Time) []byte {
+	dst = append(dst, frameType, 0, 0, 0, pingSize)
+	return binary.BigEndian.AppendUint64(dst, uint64(sent.UnixNano()))
+}
+
+// ReadPing читает тело кадра ping или pong длины length и возвращает время отправки ping
+func ReadPing(r io.Reader, length uint32) (time.Time, error) {
+	if length != pingSize {
+		return time.Time{}, ErrPingSize
+	}
+	var body [pingSize]byte
+	if _, err := io.ReadFull(r, body[:]); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(body[:]))), nil
+}
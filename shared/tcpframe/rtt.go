@@ -0,0 +1,62 @@
+package tcpframe
+
+import (
+	"sync"
+	"time"
+)
+
+// RTTStats время прохождения ping (от отправки ping до получения pong)
+type RTTStats struct {
+	Samples int64   `json:"samples"` // Получено pong
+	LastMs  float64 `json:"last_ms"`
+	MinMs   float64 `json:"min_ms"`
+	AvgMs   float64 `json:"avg_ms"`
+	MaxMs   float64 `json:"max_ms"`
+}
+
+// RTT накапливает время прохождения ping; используется клиентом и сервером
+type RTT struct {
+	mu      sync.Mutex
+	samples int64
+	last    time.Duration
+	min     time.Duration
+	max     time.Duration
+	sum     time.Duration
+}
+
+// Observe учитывает время прохождения ping по времени его отправки sent
+func (r *RTT) Observe(sent time.Time) {
+	rtt := max(time.Since(sent), 0)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.samples == 0 || rtt < r.min {
+		r.min = rtt
+	}
+	r.max = max(r.max, rtt)
+	r.last = rtt
+	r.sum += rtt
+	r.samples++
+}
+
+// Stats возвращает накопленную статистику
+func (r *RTT) Stats() RTTStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := RTTStats{Samples: r.samples}
+	if r.samples == 0 {
+		return stats
+	}
+	stats.LastMs = milliseconds(r.last)
+	stats.MinMs = milliseconds(r.min)
+	stats.AvgMs = milliseconds(r.sum / time.Duration(r.samples))
+	stats.MaxMs = milliseconds(r.max)
+	return stats
+}
+
+// milliseconds переводит длительность в миллисекунды с точностью до микросекунды
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
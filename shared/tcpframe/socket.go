@@ -0,0 +1,59 @@
+package tcpframe
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// DefaultReadBufferSize размер буфера чтения кадров по умолчанию: кадр пакета
+// в десятки и сотни килобайт читается меньшим числом системных вызовов, чем
+// с буфером bufio по умолчанию (4 КБ)
+const DefaultReadBufferSize = 64 * 1024
+
+// SocketOptions параметры сокета TCP соединения, общие для клиента и сервера
+type SocketOptions struct {
+	ReadBufferSize int  // Буфер чтения кадров (bufio), байт (0 - DefaultReadBufferSize)
+	RecvBuffer     int  // SO_RCVBUF, байт (0 - по умолчанию ОС)
+	SendBuffer     int  // SO_SNDBUF, байт (0 - по умолчанию ОС)
+	NoDelay        bool // TCP_NODELAY: отправлять кадры без ожидания заполнения сегмента (алгоритм Нейгла отключен)
+	Linger         int  // SO_LINGER, секунд: -1 - по умолчанию ОС, 0 - сброс соединения (RST) при закрытии, >0 - ожидание отправки данных
+}
+
+// BufferSize возвращает размер буфера чтения кадров
+func (o SocketOptions) BufferSize() int {
+	if o.ReadBufferSize > 0 {
+		return o.ReadBufferSize
+	}
+	return DefaultReadBufferSize
+}
+
+// Apply применяет параметры к соединению conn; соединения, отличные от TCP, не
+// изменяются. Ошибки отдельных параметров объединяются, остальные параметры применяются
+func (o SocketOptions) Apply(conn net.Conn) error {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+
+	var errs []error
+	if o.RecvBuffer > 0 {
+		if err := tcpConn.SetReadBuffer(o.RecvBuffer); err != nil {
+			errs = append(errs, fmt.Errorf("SO_RCVBUF: %w", err))
+		}
+	}
+	if o.SendBuffer > 0 {
+		if err := tcpConn.SetWriteBuffer(o.SendBuffer); err != nil {
+			errs = append(errs, fmt.Errorf("SO_SNDBUF: %w", err))
+		}
+	}
+	if err := tcpConn.SetNoDelay(o.NoDelay); err != nil {
+		errs = append(errs, fmt.Errorf("TCP_NODELAY: %w", err))
+	}
+	if o.Linger >= 0 {
+		if err := tcpConn.SetLinger(o.Linger); err != nil {
+			errs = append(errs, fmt.Errorf("SO_LINGER: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledBufferSize буферы больше этого размера не возвращаются в пул,
+// чтобы единичные большие пакеты не удерживали память
+const maxPooledBufferSize = 4 * 1024 * 1024
+
+// Buffer буфер сериализации с переиспользуемым JSON энкодером
+type Buffer struct {
+	bytes.Buffer
+	encoder *json.Encoder
+}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := &Buffer{}
+		buf.encoder = json.NewEncoder(&buf.Buffer)
+		return buf
+	},
+}
+
+// GetBuffer возвращает пустой буфер из пула
+func GetBuffer() *Buffer {
+	buf := bufferPool.Get().(*Buffer)
+	buf.Reset()
+	return buf
+}
+
+// PutBuffer возвращает буфер в пул; после вызова буфер использовать нельзя
+func PutBuffer(buf *Buffer) {
+	if buf == nil || buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// jsonAppender значение с сериализацией без отражения (models.Message, MessageBatch, Data)
+type jsonAppender interface {
+	AppendJSON(dst []byte) []byte
+}
+
+// EncodeJSON дописывает JSON представление v в буфер (результат совпадает с json.Marshal)
+func (b *Buffer) EncodeJSON(v interface{}) error {
+	if appender, ok := v.(jsonAppender); ok {
+		b.Write(appender.AppendJSON(b.AvailableBuffer()))
+		return nil
+	}
+	if err := b.encoder.Encode(v); err != nil {
+		return err
+	}
+	// Encoder добавляет перевод строки, которого нет у json.Marshal
+	b.Truncate(b.Len() - 1)
+	return nil
+}
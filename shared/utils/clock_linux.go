@@ -0,0 +1,20 @@
+//go:build linux
+
+package utils
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// MonotonicNow возвращает показание монотонных часов хоста (CLOCK_MONOTONIC) в
+// наносекундах. В отличие от монотонного времени time.Now часы общие для всех
+// процессов хоста, включая контейнеры без отдельного пространства имен времени
+func MonotonicNow() (int64, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return 0, fmt.Errorf("ошибка чтения монотонных часов: %w", err)
+	}
+	return ts.Nano(), nil
+}
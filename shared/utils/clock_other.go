@@ -0,0 +1,11 @@
+//go:build !linux
+
+package utils
+
+import "errors"
+
+// MonotonicNow возвращает показание монотонных часов хоста; общие для процессов
+// монотонные часы поддерживаются только в Linux
+func MonotonicNow() (int64, error) {
+	return 0, errors.New("монотонные часы хоста поддерживаются только в Linux")
+}
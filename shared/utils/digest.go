@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// SequenceHash перемешивает номер сообщения (финализатор SplitMix64), чтобы
+// XOR номеров соседних сообщений не сокращался
+func SequenceHash(sequence int64) uint64 {
+	z := uint64(sequence) + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// FormatDigest возвращает дайджест номеров в виде hex строки фиксированной длины
+func FormatDigest(digest uint64) string {
+	return fmt.Sprintf("%016x", digest)
+}
+
+// SequenceDigest дайджест множества номеров сообщений: XOR перемешанных номеров.
+// Не зависит от порядка добавления, поэтому sender и recipient получают одинаковое
+// значение для одного и того же множества номеров. Безопасен для одновременного использования
+type SequenceDigest struct {
+	value atomic.Uint64
+}
+
+// Add добавляет номер в дайджест; каждый номер должен добавляться один раз
+func (d *SequenceDigest) Add(sequence int64) {
+	hash := SequenceHash(sequence)
+	for {
+		old := d.value.Load()
+		if d.value.CompareAndSwap(old, old^hash) {
+			return
+		}
+	}
+}
+
+// Value возвращает текущее значение дайджеста
+func (d *SequenceDigest) Value() uint64 {
+	return d.value.Load()
+}
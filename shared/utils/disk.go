@@ -0,0 +1,27 @@
+package utils
+
+// DiskUsage заполнение файловой системы, на которой находится путь
+type DiskUsage struct {
+	Path        string  `json:"path"`
+	TotalBytes  uint64  `json:"total_bytes"`
+	FreeBytes   uint64  `json:"free_bytes"` // Доступно для записи непривилегированному процессу
+	UsedBytes   uint64  `json:"used_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// newDiskUsage вычисляет занятое место по размеру и свободному месту
+func newDiskUsage(path string, total, free, available uint64) DiskUsage {
+	usage := DiskUsage{
+		Path:       path,
+		TotalBytes: total,
+		FreeBytes:  available,
+	}
+	if total > free {
+		usage.UsedBytes = total - free
+	}
+	// Процент считается от места, доступного процессу, как в df
+	if capacity := usage.UsedBytes + available; capacity > 0 {
+		usage.UsedPercent = float64(usage.UsedBytes) * 100 / float64(capacity)
+	}
+	return usage
+}
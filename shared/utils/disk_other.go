@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package utils
+
+import "fmt"
+
+// GetDiskUsage не поддерживается на этой платформе
+func GetDiskUsage(path string) (DiskUsage, error) {
+	return DiskUsage{}, fmt.Errorf("заполнение диска не поддерживается на этой платформе")
+}
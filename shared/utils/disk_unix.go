@@ -0,0 +1,20 @@
+//go:build unix
+
+package utils
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// GetDiskUsage возвращает заполнение файловой системы, на которой находится path
+func GetDiskUsage(path string) (DiskUsage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return DiskUsage{}, fmt.Errorf("ошибка получения заполнения диска %s: %w", path, err)
+	}
+
+	blockSize := uint64(st.Bsize)
+	return newDiskUsage(path, uint64(st.Blocks)*blockSize, uint64(st.Bfree)*blockSize, uint64(st.Bavail)*blockSize), nil
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package utils
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// GetDiskUsage возвращает заполнение тома, на котором находится path
+func GetDiskUsage(path string) (DiskUsage, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return DiskUsage{}, fmt.Errorf("некорректный путь %s: %w", path, err)
+	}
+
+	var available, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(name, &available, &total, &free); err != nil {
+		return DiskUsage{}, fmt.Errorf("ошибка получения заполнения диска %s: %w", path, err)
+	}
+	return newDiskUsage(path, total, free, available), nil
+}
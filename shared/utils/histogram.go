@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtoContentType тип содержимого метрик Prometheus в формате protobuf с разделителями
+const ProtoContentType = "application/vnd.google.protobuf; proto=io.prometheus.client.MetricFamily; encoding=delimited"
+
+// LatencyBucketsMs верхние границы классических корзин гистограммы задержек (ms)
+var LatencyBucketsMs = [...]float64{0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+const (
+	// nativeSchema схема нативной гистограммы Prometheus: границы корзин - степени 2^(1/8)
+	nativeSchema = 3
+	// nativeZeroThreshold значения не больше порога (ms) учитываются в нулевой корзине
+	nativeZeroThreshold = 1.0 / 1024
+	// nativeMinIndex, nativeMaxIndex индексы корзин для значений от порога до 2^20 ms (около 17 минут);
+	// корзины больших значений хранятся в LatencyHistogram.overflow
+	nativeMinIndex = -10 << nativeSchema
+	nativeMaxIndex = 20 << nativeSchema
+)
+
+// nativeBounds границы долей мантиссы для схемы nativeSchema: 2^(k/8) / 2
+var nativeBounds = func() [1 << nativeSchema]float64 {
+	var bounds [1 << nativeSchema]float64
+	for k := range bounds {
+		bounds[k] = math.Exp2(float64(k)/(1<<nativeSchema)) / 2
+	}
+	return bounds
+}()
+
+// LatencyHistogram гистограмма задержек в миллисекундах для экспорта в Prometheus:
+// классические корзины LatencyBucketsMs и разреженные корзины нативной гистограммы.
+// Нулевое значение готово к использованию; безопасна для одновременного использования
+type LatencyHistogram struct {
+	classic [len(LatencyBucketsMs) + 1]atomic.Int64 // Последняя корзина - +Inf
+	native  [nativeMaxIndex - nativeMinIndex + 1]atomic.Int64
+	zero    atomic.Int64
+	sum     atomic.Uint64 // math.Float64bits суммы
+
+	overflowMu sync.Mutex
+	overflow   map[int]int64 // Корзины нативной гистограммы с индексом больше nativeMaxIndex
+}
+
+// Observe учитывает задержку latencyMs; отрицательные значения (расхождение часов) учитываются как 0
+func (h *LatencyHistogram) Observe(latencyMs float64) {
+	latencyMs = max(latencyMs, 0)
+
+	h.classic[sort.SearchFloat64s(LatencyBucketsMs[:], latencyMs)].Add(1)
+	if latencyMs <= nativeZeroThreshold {
+		h.zero.Add(1)
+	} else if index := nativeIndex(latencyMs); index <= nativeMaxIndex {
+		h.native[index-nativeMinIndex].Add(1)
+	} else {
+		h.overflowMu.Lock()
+		if h.overflow == nil {
+			h.overflow = make(map[int]int64)
+		}
+		h.overflow[index]++
+		h.overflowMu.Unlock()
+	}
+
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+latencyMs)) {
+			break
+		}
+	}
+}
+
+// nativeIndex возвращает индекс корзины нативной гистограммы: корзина i содержит
+// значения в диапазоне (2^((i-1)/8), 2^(i/8)]
+func nativeIndex(v float64) int {
+	frac, exp := math.Frexp(v)
+	index := sort.SearchFloat64s(nativeBounds[:], frac) + (exp-1)<<nativeSchema
+	return max(index, nativeMinIndex)
+}
+
+// WriteText выводит гистограмму name в текстовом формате Prometheus (классические корзины)
+func (h *LatencyHistogram) WriteText(w io.Writer, name, help string) {
+	fmt.Fprintf(w, "\n# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+
+	var cumulative int64
+	for i := range h.classic {
+		cumulative += h.classic[i].Load()
+		le := "+Inf"
+		if i < len(LatencyBucketsMs) {
+			le = strconv.FormatFloat(LatencyBucketsMs[i], 'g', -1, 64)
+		}
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, le, cumulative)
+	}
+
+	fmt.Fprintf(w, "%s_sum %.3f\n", name, math.Float64frombits(h.sum.Load()))
+	fmt.Fprintf(w, "%s_count %d\n", name, cumulative)
+}
+
+// WriteProto выводит гистограмму name как MetricFamily в формате protobuf с разделителями
+// (ProtoContentType): классические корзины и разреженные корзины нативной гистограммы
+func (h *LatencyHistogram) WriteProto(w io.Writer, name, help string) error {
+	histogram := &dto.Histogram{
+		Schema:        proto.Int32(nativeSchema),
+		ZeroThreshold: proto.Float64(nativeZeroThreshold),
+		ZeroCount:     proto.Uint64(uint64(h.zero.Load())),
+	}
+
+	var cumulative uint64
+	for i := range h.classic {
+		cumulative += uint64(h.classic[i].Load())
+		if i == len(LatencyBucketsMs) {
+			break
+		}
+		histogram.Bucket = append(histogram.Bucket, &dto.Bucket{
+			CumulativeCount: proto.Uint64(cumulative),
+			UpperBound:      proto.Float64(LatencyBucketsMs[i]),
+		})
+	}
+
+	// Количество берется по корзинам, чтобы совпадать с ними при одновременных измерениях
+	histogram.SampleCount = proto.Uint64(cumulative)
+	histogram.SampleSum = proto.Float64(math.Float64frombits(h.sum.Load()))
+	h.appendNativeBuckets(histogram)
+
+	family := &dto.MetricFamily{
+		Name:   proto.String(name),
+		Help:   proto.String(help),
+		Type:   dto.MetricType_HISTOGRAM.Enum(),
+		Metric: []*dto.Metric{{Histogram: histogram}},
+	}
+	_, err := protodelim.MarshalTo(w, family)
+	return err
+}
+
+// appendNativeBuckets добавляет заполненные корзины нативной гистограммы интервалами
+// (positive_span) и разностями соседних значений (positive_delta)
+func (h *LatencyHistogram) appendNativeBuckets(histogram *dto.Histogram) {
+	var previous int64
+	last := 0
+	add := func(index int, count int64) {
+		// Смещение первого интервала - индекс корзины, следующих - пропуск после предыдущего
+		spans := histogram.PositiveSpan
+		if len(spans) == 0 {
+			spans = append(spans, &dto.BucketSpan{Offset: proto.Int32(int32(index)), Length: proto.Uint32(0)})
+		} else if index != last+1 {
+			spans = append(spans, &dto.BucketSpan{Offset: proto.Int32(int32(index - last - 1)), Length: proto.Uint32(0)})
+		}
+		*spans[len(spans)-1].Length++
+		histogram.PositiveSpan = spans
+		last = index
+
+		histogram.PositiveDelta = append(histogram.PositiveDelta, count-previous)
+		previous = count
+	}
+
+	for i := range h.native {
+		if count := h.native[i].Load(); count > 0 {
+			add(i+nativeMinIndex, count)
+		}
+	}
+
+	h.overflowMu.Lock()
+	indexes := slices.Sorted(maps.Keys(h.overflow))
+	for _, index := range indexes {
+		add(index, h.overflow[index])
+	}
+	h.overflowMu.Unlock()
+
+	if len(histogram.PositiveSpan) == 0 {
+		// Пустой интервал отличает пустую нативную гистограмму от классической
+		histogram.PositiveSpan = []*dto.BucketSpan{{Offset: proto.Int32(0), Length: proto.Uint32(0)}}
+	}
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// nativeCounts восстанавливает количества по индексам корзин из интервалов и разностей
+func nativeCounts(t *testing.T, h *dto.Histogram) map[int]int64 {
+	t.Helper()
+	counts := map[int]int64{}
+	index, delta, count := 0, 0, int64(0)
+	for i, span := range h.GetPositiveSpan() {
+		if i == 0 {
+			index = int(span.GetOffset())
+		} else {
+			index += int(span.GetOffset()) + 1
+		}
+		for j := range int(span.GetLength()) {
+			if j > 0 {
+				index++
+			}
+			count += h.GetPositiveDelta()[delta]
+			delta++
+			counts[index] = count
+		}
+	}
+	if delta != len(h.GetPositiveDelta()) {
+		t.Fatalf("разностей %d, корзин в интервалах %d", len(h.GetPositiveDelta()), delta)
+	}
+	return counts
+}
+
+func TestLatencyHistogramWriteProtoRoundTrip(t *testing.T) {
+	var h LatencyHistogram
+	values := []float64{-1, 0.0001, 0.7, 0.7, 3, 120, 4000, 4000, 4000, 1 << 22, 1 << 30}
+	want := map[int]int64{}
+	var sum float64
+	for _, v := range values {
+		h.Observe(v)
+		if v = max(v, 0); v > nativeZeroThreshold {
+			want[nativeIndex(v)]++
+		}
+		sum += max(v, 0)
+	}
+
+	var buf bytes.Buffer
+	if err := h.WriteProto(&buf, "latency_ms", "Задержка"); err != nil {
+		t.Fatal(err)
+	}
+
+	var family dto.MetricFamily
+	if err := protodelim.UnmarshalFrom(bufio.NewReader(&buf), &family); err != nil {
+		t.Fatalf("разбор MetricFamily: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("после сообщения остались %d байт", buf.Len())
+	}
+	if family.GetName() != "latency_ms" || family.GetHelp() != "Задержка" || family.GetType() != dto.MetricType_HISTOGRAM {
+		t.Fatalf("заголовок MetricFamily: %q %q %v", family.GetName(), family.GetHelp(), family.GetType())
+	}
+	if len(family.GetMetric()) != 1 {
+		t.Fatalf("метрик %d, ожидалась 1", len(family.GetMetric()))
+	}
+	histogram := family.GetMetric()[0].GetHistogram()
+
+	if histogram.GetSampleCount() != uint64(len(values)) {
+		t.Errorf("sample_count %d, ожидалось %d", histogram.GetSampleCount(), len(values))
+	}
+	if math.Abs(histogram.GetSampleSum()-sum) > 1e-6 {
+		t.Errorf("sample_sum %g, ожидалось %g", histogram.GetSampleSum(), sum)
+	}
+	if len(histogram.GetBucket()) != len(LatencyBucketsMs) {
+		t.Fatalf("классических корзин %d, ожидалось %d", len(histogram.GetBucket()), len(LatencyBucketsMs))
+	}
+	for i, bucket := range histogram.GetBucket() {
+		var expected uint64
+		for _, v := range values {
+			if max(v, 0) <= LatencyBucketsMs[i] {
+				expected++
+			}
+		}
+		if bucket.GetUpperBound() != LatencyBucketsMs[i] || bucket.GetCumulativeCount() != expected {
+			t.Errorf("корзина le=%g: %d, ожидалось le=%g: %d",
+				bucket.GetUpperBound(), bucket.GetCumulativeCount(), LatencyBucketsMs[i], expected)
+		}
+	}
+
+	if histogram.GetSchema() != nativeSchema || histogram.GetZeroThreshold() != nativeZeroThreshold {
+		t.Errorf("схема %d, порог %g", histogram.GetSchema(), histogram.GetZeroThreshold())
+	}
+	if histogram.GetZeroCount() != 2 {
+		t.Errorf("zero_count %d, ожидалось 2", histogram.GetZeroCount())
+	}
+	got := nativeCounts(t, histogram)
+	if len(got) != len(want) {
+		t.Errorf("нативных корзин %d, ожидалось %d: %v", len(got), len(want), got)
+	}
+	for index, count := range want {
+		if got[index] != count {
+			t.Errorf("нативная корзина %d: %d, ожидалось %d", index, got[index], count)
+		}
+	}
+}
+
+func TestNativeIndexBeyondRange(t *testing.T) {
+	// Значения больше 2^20 ms не сводятся в последнюю корзину
+	if index := nativeIndex(1 << 30); index != 30<<nativeSchema {
+		t.Errorf("индекс 2^30 ms %d, ожидалось %d", index, 30<<nativeSchema)
+	}
+	if index := nativeIndex(1 << 20); index != nativeMaxIndex {
+		t.Errorf("индекс 2^20 ms %d, ожидалось %d", index, nativeMaxIndex)
+	}
+}
+
+func TestLatencyHistogramWriteProtoEmpty(t *testing.T) {
+	var h LatencyHistogram
+	var buf bytes.Buffer
+	if err := h.WriteProto(&buf, "empty_ms", "Пусто"); err != nil {
+		t.Fatal(err)
+	}
+	var family dto.MetricFamily
+	if err := protodelim.UnmarshalFrom(bufio.NewReader(&buf), &family); err != nil {
+		t.Fatal(err)
+	}
+	histogram := family.GetMetric()[0].GetHistogram()
+	if len(histogram.GetPositiveSpan()) != 1 || histogram.GetPositiveSpan()[0].GetLength() != 0 {
+		t.Errorf("пустая нативная гистограмма должна содержать один пустой интервал: %v", histogram.GetPositiveSpan())
+	}
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+
+	"github.com/infodiode/shared/models"
+)
+
+const (
+	// jitterSubBuckets количество корзин на каждую степень двойки (погрешность перцентиля до 1/8)
+	jitterSubBuckets = 8
+	// jitterBuckets корзины для значений до 2^40 мкс (около 12 суток)
+	jitterBuckets = 41 * jitterSubBuckets
+)
+
+// JitterHistogram логарифмическая гистограмма отклонений времени с шагом в микросекундах.
+// Безопасна для одновременного использования; перцентили вычисляются с точностью до корзины
+type JitterHistogram struct {
+	counts [jitterBuckets]atomic.Int64
+	count  atomic.Int64
+	sum    atomic.Int64 // микросекунды
+	max    atomic.Int64 // микросекунды
+}
+
+// NewJitterHistogram создает пустую гистограмму
+func NewJitterHistogram() *JitterHistogram {
+	return &JitterHistogram{}
+}
+
+// Observe учитывает отклонение d; отрицательные отклонения учитываются по модулю
+func (h *JitterHistogram) Observe(d time.Duration) {
+	micros := d.Microseconds()
+	if micros < 0 {
+		micros = -micros
+	}
+
+	h.counts[jitterBucket(micros)].Add(1)
+	h.count.Add(1)
+	h.sum.Add(micros)
+	for {
+		old := h.max.Load()
+		if micros <= old || h.max.CompareAndSwap(old, micros) {
+			break
+		}
+	}
+}
+
+// Stats возвращает количество измерений, среднее, 95-й перцентиль и максимум отклонения
+func (h *JitterHistogram) Stats() models.JitterStats {
+	count := h.count.Load()
+	if count == 0 {
+		return models.JitterStats{}
+	}
+
+	return models.JitterStats{
+		Samples: count,
+		AvgMs:   float64(h.sum.Load()) / float64(count) / 1000.0,
+		P95Ms:   float64(h.quantile(0.95, count)) / 1000.0,
+		MaxMs:   float64(h.max.Load()) / 1000.0,
+	}
+}
+
+// HopLatency возвращает статистику как задержку участка пути; nil без измерений
+func (h *JitterHistogram) HopLatency() *models.HopLatency {
+	stats := h.Stats()
+	if stats.Samples == 0 {
+		return nil
+	}
+	return &models.HopLatency{
+		Samples: stats.Samples,
+		AvgMs:   stats.AvgMs,
+		P95Ms:   stats.P95Ms,
+		MaxMs:   stats.MaxMs,
+	}
+}
+
+// quantile возвращает верхнюю границу корзины, содержащей перцентиль q (в микросекундах),
+// но не больше наибольшего измерения
+func (h *JitterHistogram) quantile(q float64, count int64) int64 {
+	rank := int64(math.Ceil(q * float64(count)))
+	var seen int64
+	for i := range h.counts {
+		seen += h.counts[i].Load()
+		if seen >= rank {
+			return min(jitterBucketBound(i), h.max.Load())
+		}
+	}
+	return h.max.Load()
+}
+
+// jitterBucket возвращает индекс корзины для значения в микросекундах: значения меньше
+// jitterSubBuckets учитываются точно, дальше каждая степень двойки делится на jitterSubBuckets корзин
+func jitterBucket(micros int64) int {
+	if micros < jitterSubBuckets {
+		return int(micros)
+	}
+
+	exp := bits.Len64(uint64(micros)) - 4 // сдвиг, оставляющий 4 старших бита
+	sub := int(micros>>uint(exp)) - jitterSubBuckets
+	return min((exp+1)*jitterSubBuckets+sub, jitterBuckets-1)
+}
+
+// jitterBucketBound возвращает наибольшее значение (в микросекундах), попадающее в корзину i
+func jitterBucketBound(i int) int64 {
+	if i < jitterSubBuckets {
+		return int64(i)
+	}
+
+	exp := i/jitterSubBuckets - 1
+	sub := int64(i%jitterSubBuckets + jitterSubBuckets)
+	return (sub+1)<<uint(exp) - 1
+}
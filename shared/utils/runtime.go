@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+)
+
+// WriteRuntimeMetrics выводит метрики среды выполнения Go в формате Prometheus:
+// число горутин, использование кучи и паузы сборщика мусора
+func WriteRuntimeMetrics(w io.Writer) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	// Последняя пауза хранится в кольцевом буфере по индексу (NumGC+255)%256
+	var lastPause time.Duration
+	if ms.NumGC > 0 {
+		lastPause = time.Duration(ms.PauseNs[(ms.NumGC+255)%256])
+	}
+
+	fmt.Fprintf(w, "\n# HELP go_goroutines Number of goroutines that currently exist\n")
+	fmt.Fprintf(w, "# TYPE go_goroutines gauge\n")
+	fmt.Fprintf(w, "go_goroutines %d\n", runtime.NumGoroutine())
+
+	fmt.Fprintf(w, "\n# HELP go_memstats_heap_alloc_bytes Heap bytes allocated and still in use\n")
+	fmt.Fprintf(w, "# TYPE go_memstats_heap_alloc_bytes gauge\n")
+	fmt.Fprintf(w, "go_memstats_heap_alloc_bytes %d\n", ms.HeapAlloc)
+
+	fmt.Fprintf(w, "\n# HELP go_memstats_heap_inuse_bytes Heap bytes in in-use spans\n")
+	fmt.Fprintf(w, "# TYPE go_memstats_heap_inuse_bytes gauge\n")
+	fmt.Fprintf(w, "go_memstats_heap_inuse_bytes %d\n", ms.HeapInuse)
+
+	fmt.Fprintf(w, "\n# HELP go_memstats_heap_objects Number of allocated heap objects\n")
+	fmt.Fprintf(w, "# TYPE go_memstats_heap_objects gauge\n")
+	fmt.Fprintf(w, "go_memstats_heap_objects %d\n", ms.HeapObjects)
+
+	fmt.Fprintf(w, "\n# HELP go_memstats_sys_bytes Bytes obtained from the OS\n")
+	fmt.Fprintf(w, "# TYPE go_memstats_sys_bytes gauge\n")
+	fmt.Fprintf(w, "go_memstats_sys_bytes %d\n", ms.Sys)
+
+	fmt.Fprintf(w, "\n# HELP go_gc_cycles_total Number of completed GC cycles\n")
+	fmt.Fprintf(w, "# TYPE go_gc_cycles_total counter\n")
+	fmt.Fprintf(w, "go_gc_cycles_total %d\n", ms.NumGC)
+
+	fmt.Fprintf(w, "\n# HELP go_gc_pause_last_seconds Duration of the last GC stop-the-world pause\n")
+	fmt.Fprintf(w, "# TYPE go_gc_pause_last_seconds gauge\n")
+	fmt.Fprintf(w, "go_gc_pause_last_seconds %.6f\n", lastPause.Seconds())
+
+	fmt.Fprintf(w, "\n# HELP go_gc_pause_seconds_total Cumulative GC stop-the-world pause time\n")
+	fmt.Fprintf(w, "# TYPE go_gc_pause_seconds_total counter\n")
+	fmt.Fprintf(w, "go_gc_pause_seconds_total %.6f\n", time.Duration(ms.PauseTotalNs).Seconds())
+}
@@ -1,7 +1,11 @@
 package utils
 
 import (
+	"fmt"
+	"strconv"
 	"time"
+
+	"github.com/infodiode/shared/models"
 )
 
 const (
@@ -9,6 +13,15 @@ const (
 	TimeFormat = time.RFC3339Nano
 )
 
+// Форматы времени отправки сообщений (send_time). Числовые форматы разбираются
+// без разбора строки даты, что заметно дешевле на recipient при высокой скорости
+const (
+	TimeFormatRFC3339Nano = "rfc3339nano"  // Строка RFC3339 с наносекундами (по умолчанию)
+	TimeFormatEpochMicros = "epoch_micros" // Микросекунды от начала эпохи Unix
+	TimeFormatEpochNanos  = "epoch_nanos"  // Наносекунды от начала эпохи Unix
+	TimeFormatMonotonic   = "monotonic"    // Наносекунды монотонных часов хоста; sender и recipient на одном хосте
+)
+
 // GetCurrentTime возвращает текущее время в формате RFC3339Nano
 func GetCurrentTime() string {
 	return time.Now().Format(TimeFormat)
@@ -19,6 +32,108 @@ func ParseTime(timeStr string) (time.Time, error) {
 	return time.Parse(TimeFormat, timeStr)
 }
 
+// ValidateTimeFormat проверяет формат времени отправки; пустой формат - RFC3339Nano
+func ValidateTimeFormat(format string) error {
+	switch format {
+	case "", TimeFormatRFC3339Nano, TimeFormatEpochMicros, TimeFormatEpochNanos:
+		return nil
+	case TimeFormatMonotonic:
+		_, err := MonotonicNow()
+		return err
+	default:
+		return fmt.Errorf("неизвестный формат времени: %s (допустимо %s, %s, %s, %s)", format,
+			TimeFormatRFC3339Nano, TimeFormatEpochMicros, TimeFormatEpochNanos, TimeFormatMonotonic)
+	}
+}
+
+// MessageSchema возвращает версию схемы сообщения, передающую время в формате format.
+// Для RFC3339Nano возвращается 0: поле schema не передается, и сообщения принимают
+// recipient прежних версий
+func MessageSchema(format string) int {
+	switch format {
+	case TimeFormatEpochMicros:
+		return models.SchemaEpochMicros
+	case TimeFormatEpochNanos:
+		return models.SchemaEpochNanos
+	case TimeFormatMonotonic:
+		return models.SchemaMonotonic
+	default:
+		return 0
+	}
+}
+
+// SchemaTimeFormat возвращает формат времени сообщения версии схемы schema;
+// сообщения без версии (0) передают время в RFC3339Nano
+func SchemaTimeFormat(schema int) (string, error) {
+	switch schema {
+	case 0, models.SchemaRFC3339:
+		return TimeFormatRFC3339Nano, nil
+	case models.SchemaEpochMicros:
+		return TimeFormatEpochMicros, nil
+	case models.SchemaEpochNanos:
+		return TimeFormatEpochNanos, nil
+	case models.SchemaMonotonic:
+		return TimeFormatMonotonic, nil
+	default:
+		return "", fmt.Errorf("неподдерживаемая версия схемы сообщения: %d", schema)
+	}
+}
+
+// FormatTime форматирует время t в формате format. Для монотонных часов время
+// переводится в их показание через монотонное время процесса
+func FormatTime(t time.Time, format string) string {
+	switch format {
+	case TimeFormatEpochMicros:
+		return strconv.FormatInt(t.UnixMicro(), 10)
+	case TimeFormatEpochNanos:
+		return strconv.FormatInt(t.UnixNano(), 10)
+	case TimeFormatMonotonic:
+		if now, err := MonotonicNow(); err == nil {
+			return strconv.FormatInt(now-int64(time.Since(t)), 10)
+		}
+	}
+	return t.Format(TimeFormat)
+}
+
+// ParseTimeFormat разбирает время в формате format. Показание монотонных часов
+// переводится в текущее время за вычетом прошедшего с него интервала: разность с
+// time.Now() этого процесса точна, пока оба процесса на одном хосте
+func ParseTimeFormat(value, format string) (time.Time, error) {
+	switch format {
+	case "", TimeFormatRFC3339Nano:
+		return ParseTime(value)
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("некорректное время %q в формате %s", value, format)
+	}
+	switch format {
+	case TimeFormatEpochMicros:
+		return time.UnixMicro(n), nil
+	case TimeFormatEpochNanos:
+		return time.Unix(0, n), nil
+	case TimeFormatMonotonic:
+		now := time.Now()
+		mono, err := MonotonicNow()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-time.Duration(mono - n)), nil
+	default:
+		return time.Time{}, fmt.Errorf("неизвестный формат времени: %s", format)
+	}
+}
+
+// ParseSendTime разбирает время отправки сообщения в формате его версии схемы
+func ParseSendTime(message *models.Message) (time.Time, error) {
+	format, err := SchemaTimeFormat(message.Schema)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ParseTimeFormat(message.SendTime, format)
+}
+
 // CalculateLatency вычисляет задержку между двумя временными метками в миллисекундах
 func CalculateLatency(sendTime, receiveTime string) (float64, error) {
 	sent, err := ParseTime(sendTime)
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// HTTPClient создает HTTP клиента с таймаутом запроса timeout. caFile - файл PEM
+// сертификатов удостоверяющих центров для проверки серверов HTTPS (пусто - системные)
+func HTTPClient(timeout time.Duration, caFile string) (*http.Client, error) {
+	client := &http.Client{Timeout: timeout}
+	if caFile == "" {
+		return client, nil
+	}
+
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения сертификатов удостоверяющих центров: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("файл %s не содержит сертификатов PEM", caFile)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	client.Transport = transport
+	return client, nil
+}
+
+// ListenAndServe запускает HTTP сервер; при заданных certFile и keyFile - по HTTPS
+// с TLS не ниже 1.2
+func ListenAndServe(server *http.Server, certFile, keyFile string) error {
+	if certFile == "" {
+		return server.ListenAndServe()
+	}
+
+	if server.TLSConfig == nil {
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return server.ListenAndServeTLS(certFile, keyFile)
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"runtime/debug"
+)
+
+// VCSRevision возвращает коммит, из которого собран бинарный файл, по данным,
+// встроенным go build (с суффиксом -dirty при незафиксированных изменениях).
+// Пустая строка, если сборка выполнена вне репозитория git
+func VCSRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// ConfigHash возвращает хеш конфигурации (SHA-256 ее JSON представления, первые 8 байт в hex)
+func ConfigHash(config interface{}) string {
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/infodiode/shared/models"
+)
+
+// MaxStatsWindow наибольшее окно показателей WindowCounters
+const MaxStatsWindow = 5 * time.Minute
+
+// windowSlots секунд в кольцевом буфере WindowCounters
+const windowSlots = int(MaxStatsWindow / time.Second)
+
+// ParseStatsWindow разбирает окно показателей /stats: длительность от 1s до
+// MaxStatsWindow (обычно 1m или 5m). Пустое значение и all означают накопленные
+// показатели без окна (0)
+func ParseStatsWindow(value string) (time.Duration, error) {
+	if value == "" || value == "all" {
+		return 0, nil
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window < time.Second || window > MaxStatsWindow {
+		return 0, fmt.Errorf("некорректное окно %q: допустимо all или длительность от 1s до %s, например 1m", value, MaxStatsWindow)
+	}
+	return window.Truncate(time.Second), nil
+}
+
+// windowSlot показатели одной секунды
+type windowSlot struct {
+	second   int64 // Секунда Unix, к которой относятся показатели
+	messages int64
+	bytes    int64
+	errors   int64
+
+	latencyCount int64
+	latencySum   int64 // микросекунды
+	latencyMax   int64 // микросекунды
+	latency      [jitterBuckets]int64
+}
+
+// WindowCounters кольцевой буфер посекундных показателей за последние MaxStatsWindow:
+// сообщения, байты, ошибки и гистограмма задержки. Позволяет получить показатели
+// за последнюю минуту, не сбрасывая накопленные счетчики. Безопасен для одновременного использования
+type WindowCounters struct {
+	start time.Time
+
+	mu    sync.Mutex
+	slots [windowSlots]windowSlot
+}
+
+// NewWindowCounters создает пустой буфер; охваченное время отсчитывается от создания
+func NewWindowCounters() *WindowCounters {
+	return &WindowCounters{start: time.Now()}
+}
+
+// slot возвращает показатели секунды at, очищая ячейку вытесненной секунды;
+// nil, если секунда уже вытеснена из буфера (вызывается под mu)
+func (w *WindowCounters) slot(at time.Time) *windowSlot {
+	second := at.Unix()
+	slot := &w.slots[second%int64(windowSlots)]
+	switch {
+	case slot.second == second:
+		return slot
+	case slot.second > second:
+		return nil
+	}
+	*slot = windowSlot{second: second}
+	return slot
+}
+
+// Add учитывает сообщения, байты и ошибки в момент at
+func (w *WindowCounters) Add(at time.Time, messages, bytes, errors int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if slot := w.slot(at); slot != nil {
+		slot.messages += messages
+		slot.bytes += bytes
+		slot.errors += errors
+	}
+}
+
+// ObserveLatency учитывает задержку d сообщения в момент at; отрицательные значения
+// (расхождение часов) учитываются как 0
+func (w *WindowCounters) ObserveLatency(at time.Time, d time.Duration) {
+	micros := max(d.Microseconds(), 0)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if slot := w.slot(at); slot != nil {
+		slot.latency[jitterBucket(micros)]++
+		slot.latencyCount++
+		slot.latencySum += micros
+		slot.latencyMax = max(slot.latencyMax, micros)
+	}
+}
+
+// Stats возвращает показатели за последние window (не больше MaxStatsWindow),
+// включая текущую неполную секунду
+func (w *WindowCounters) Stats(window time.Duration) models.WindowStats {
+	window = min(window, MaxStatsWindow)
+	now := time.Now()
+	from := now.Unix() - int64(window/time.Second)
+
+	stats := models.WindowStats{
+		Window:  window.String(),
+		Seconds: min(window, max(now.Sub(w.start), time.Second)).Seconds(),
+	}
+
+	var latency [jitterBuckets]int64
+	var count, sum, maxLatency int64
+
+	w.mu.Lock()
+	for i := range w.slots {
+		slot := &w.slots[i]
+		if slot.second <= from || slot.second > now.Unix() {
+			continue
+		}
+		stats.Messages += slot.messages
+		stats.Bytes += slot.bytes
+		stats.Errors += slot.errors
+		if slot.latencyCount > 0 {
+			for j, n := range slot.latency {
+				latency[j] += n
+			}
+			count += slot.latencyCount
+			sum += slot.latencySum
+			maxLatency = max(maxLatency, slot.latencyMax)
+		}
+	}
+	w.mu.Unlock()
+
+	if stats.Seconds > 0 {
+		stats.MessagesPerSec = float64(stats.Messages) / stats.Seconds
+		stats.BytesPerSec = float64(stats.Bytes) / stats.Seconds
+	}
+	if count > 0 {
+		stats.Latency = &models.HopLatency{
+			Samples: count,
+			AvgMs:   float64(sum) / float64(count) / 1000.0,
+			P95Ms:   float64(windowQuantile(latency[:], 0.95, count, maxLatency)) / 1000.0,
+			MaxMs:   float64(maxLatency) / 1000.0,
+		}
+	}
+	return stats
+}
+
+// windowQuantile возвращает верхнюю границу корзины, содержащей перцентиль q
+// (в микросекундах), но не больше наибольшего измерения
+func windowQuantile(counts []int64, q float64, count, maxValue int64) int64 {
+	rank := int64(math.Ceil(q * float64(count)))
+	var seen int64
+	for i, n := range counts {
+		seen += n
+		if seen >= rank {
+			return min(jitterBucketBound(i), maxValue)
+		}
+	}
+	return maxValue
+}